@@ -6,6 +6,7 @@ import (
 	"os"
 	"path/filepath"
 	"time"
+	_ "time/tzdata" // server.display_timezone must resolve where the OS has no timezone database (Windows)
 
 	"github.com/matthieu/mcp-server-prtg/internal/cliargs"
 	"github.com/matthieu/mcp-server-prtg/internal/services/configuration"
@@ -29,6 +30,8 @@ const (
 	cmdStop      = "stop"
 	cmdStatus    = "status"
 	cmdConfig    = "config"
+	cmdSelfTest  = "selftest"
+	cmdDoctor    = "doctor" // Alias of selftest
 )
 
 func main() {
@@ -116,8 +119,11 @@ func executeCommand(args *cliargs.ParsedArgs) error {
 	case cmdConfig:
 		return handleConfigCommand(args)
 
+	case cmdSelfTest, cmdDoctor:
+		return runSelfTestCommand(args)
+
 	default:
-		return fmt.Errorf("unknown command: %s\n\nAvailable commands: run, install, uninstall, start, stop, status, config", args.Command)
+		return fmt.Errorf("unknown command: %s\n\nAvailable commands: run, install, uninstall, start, stop, status, config, selftest", args.Command)
 	}
 }
 
@@ -189,6 +195,7 @@ func showHelp() error {
 	fmt.Println("  stop        Stop the system service")
 	fmt.Println("  status      Show service status")
 	fmt.Println("  config      Show configuration information")
+	fmt.Println("  selftest    Check configuration, database, PRTG API and TLS certificate (alias: doctor)")
 	fmt.Println()
 	fmt.Println("OPTIONS:")
 	fmt.Println("  --config PATH       Path to configuration file (default: ./config.yaml)")
@@ -203,6 +210,7 @@ func showHelp() error {
 	fmt.Printf("  %s run --config /etc/mcp-server-prtg/config.yaml\n", os.Args[0])
 	fmt.Printf("  %s install --config /etc/mcp-server-prtg/config.yaml\n", os.Args[0])
 	fmt.Printf("  %s start\n", os.Args[0])
+	fmt.Printf("  %s selftest --config /etc/mcp-server-prtg/config.yaml\n", os.Args[0])
 	fmt.Println()
 	fmt.Println("For more information, see: https://github.com/matthieu/mcp-server-prtg")
 
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/matthieu/mcp-server-prtg/internal/cliargs"
+	"github.com/matthieu/mcp-server-prtg/internal/selftest"
+)
+
+func TestExecuteCommand_SelfTestDispatch(t *testing.T) {
+	original := runSelfTestCommand
+	t.Cleanup(func() { runSelfTestCommand = original })
+
+	var called []string
+
+	runSelfTestCommand = func(args *cliargs.ParsedArgs) error {
+		called = append(called, args.Command)
+		return errors.New("self-test failed: 1 of 5 checks failed")
+	}
+
+	for _, command := range []string{cmdSelfTest, cmdDoctor} {
+		err := executeCommand(&cliargs.ParsedArgs{Command: command})
+		assert.ErrorContains(t, err, "self-test failed")
+	}
+
+	assert.Equal(t, []string{"selftest", "doctor"}, called)
+
+	err := executeCommand(&cliargs.ParsedArgs{Command: "selftests"})
+	assert.ErrorContains(t, err, "unknown command")
+	assert.ErrorContains(t, err, "selftest")
+	assert.Len(t, called, 2)
+}
+
+func TestSelfTestChecks_MissingConfiguration(t *testing.T) {
+	configPath := filepath.Join(t.TempDir(), "config.yaml")
+
+	checks, cleanup := selfTestChecks(&cliargs.ParsedArgs{Command: cmdSelfTest, ConfigPath: configPath})
+	defer cleanup()
+
+	report := selftest.Run(context.Background(), checks, selftest.DefaultCheckTimeout)
+
+	statuses := map[string]string{}
+	for _, result := range report.Results {
+		statuses[result.Name] = result.Status
+	}
+
+	assert.Equal(t, map[string]string{
+		"version":         selftest.StatusPass,
+		"configuration":   selftest.StatusFail,
+		"database":        selftest.StatusSkip,
+		"prtg_api":        selftest.StatusSkip,
+		"tls_certificate": selftest.StatusSkip,
+	}, statuses)
+	assert.False(t, report.Passed())
+
+	// The self-test never creates a default configuration
+	_, err := os.Stat(configPath)
+	require.True(t, os.IsNotExist(err))
+}
@@ -0,0 +1,167 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/matthieu/mcp-server-prtg/internal/cliargs"
+	"github.com/matthieu/mcp-server-prtg/internal/database"
+	"github.com/matthieu/mcp-server-prtg/internal/prtg"
+	"github.com/matthieu/mcp-server-prtg/internal/selftest"
+	"github.com/matthieu/mcp-server-prtg/internal/services/configuration"
+	"github.com/matthieu/mcp-server-prtg/internal/services/logger"
+)
+
+// runSelfTestCommand runs the selftest command; tests replace it to check the dispatch.
+var runSelfTestCommand = runSelfTest
+
+// runSelfTest checks the installation (configuration, database, PRTG API, TLS certificate)
+// and prints a report suitable for bug reports. It fails when any check fails.
+func runSelfTest(args *cliargs.ParsedArgs) error {
+	fmt.Printf("MCP Server PRTG self-test (%s)\n\n", time.Now().UTC().Format(time.RFC3339))
+
+	checks, cleanup := selfTestChecks(args)
+	defer cleanup()
+
+	report := selftest.Run(context.Background(), checks, selftest.DefaultCheckTimeout)
+	if err := report.Write(os.Stdout); err != nil {
+		return fmt.Errorf("failed to write self-test report: %w", err)
+	}
+
+	if !report.Passed() {
+		return fmt.Errorf("self-test failed: %d of %d checks failed", report.Failed(), len(report.Results))
+	}
+
+	return nil
+}
+
+// selfTestChecks returns the checks for the configuration at args.ConfigPath. Unlike run,
+// a missing configuration file is reported instead of being created. The returned cleanup
+// releases the loaded configuration.
+func selfTestChecks(args *cliargs.ParsedArgs) ([]selftest.Check, func()) {
+	configPath, err := filepath.Abs(args.ConfigPath)
+	if err != nil {
+		configPath = args.ConfigPath
+	}
+
+	var config *configuration.Configuration
+
+	checks := []selftest.Check{
+		{Name: "version", Run: func(context.Context) (string, error) {
+			return getVersionString(), nil
+		}},
+		{Name: "configuration", Run: func(context.Context) (string, error) {
+			if _, err := os.Stat(configPath); err != nil {
+				return "", fmt.Errorf("%s: %w", configPath, err)
+			}
+
+			loaded, err := configuration.NewConfiguration(args, logger.NewSilentLogger())
+			if err != nil {
+				return "", err
+			}
+
+			config = loaded
+
+			if err := config.Validate(); err != nil {
+				return "", fmt.Errorf("%s: %w", configPath, err)
+			}
+
+			return configPath, nil
+		}},
+		{Name: "database", Run: func(ctx context.Context) (string, error) {
+			if config == nil {
+				return "", selftest.Skip("configuration not loaded")
+			}
+
+			return checkDatabase(ctx, config)
+		}},
+		{Name: "prtg_api", Run: func(ctx context.Context) (string, error) {
+			if config == nil {
+				return "", selftest.Skip("configuration not loaded")
+			}
+
+			return checkPRTGAPI(ctx, config)
+		}},
+		{Name: "tls_certificate", Run: func(context.Context) (string, error) {
+			if config == nil {
+				return "", selftest.Skip("configuration not loaded")
+			}
+
+			return checkTLSCertificate(config)
+		}},
+	}
+
+	cleanup := func() {
+		if config != nil {
+			_ = config.Shutdown(context.Background())
+		}
+	}
+
+	return checks, cleanup
+}
+
+// checkDatabase connects once to the configured database and pings it.
+func checkDatabase(ctx context.Context, config *configuration.Configuration) (string, error) {
+	db, err := database.Connect(ctx, database.NewPostgresConnector(config.GetDatabaseConnectionString()),
+		database.RetryPolicy{Attempts: 1}, logger.NewSilentLogger())
+	if err != nil {
+		return "", err
+	}
+	defer db.Close()
+
+	if err := db.Health(ctx); err != nil {
+		return "", err
+	}
+
+	searchPath, err := db.CheckSchema(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	if config.UsesDatabaseDSN() {
+		return fmt.Sprintf("connected using database.dsn (search_path=%s)", searchPath), nil
+	}
+
+	return fmt.Sprintf("connected to %s:%d/%s as %s (sslmode=%s, search_path=%s)",
+		config.GetDatabaseHost(), config.GetDatabasePort(), config.GetDatabaseName(),
+		config.GetDatabaseUser(), config.GetDatabaseSSLMode(), searchPath), nil
+}
+
+// checkPRTGAPI pings the PRTG API when it is enabled.
+func checkPRTGAPI(ctx context.Context, config *configuration.Configuration) (string, error) {
+	if !config.IsPRTGEnabled() {
+		return "", selftest.Skip("disabled (prtg.enabled: false)")
+	}
+
+	client, err := prtg.NewClient(prtg.ClientConfig{
+		BaseURL:   config.GetPRTGBaseURL(),
+		Token:     config.GetPRTGAPIToken(),
+		Timeout:   config.GetPRTGTimeout(),
+		VerifySSL: config.IsPRTGSSLVerifyEnabled(),
+		Logger:    logger.NewSilentLogger(),
+	})
+	if err != nil {
+		return "", err
+	}
+
+	if err := client.Ping(ctx); err != nil {
+		return "", err
+	}
+
+	return fmt.Sprintf("%s reachable (verify_ssl=%t)", config.GetPRTGBaseURL(), config.IsPRTGSSLVerifyEnabled()), nil
+}
+
+// checkTLSCertificate verifies the configured server certificate when TLS is enabled.
+func checkTLSCertificate(config *configuration.Configuration) (string, error) {
+	switch {
+	case !config.IsTLSEnabled():
+		return "", selftest.Skip("TLS disabled (server.enable_tls: false)")
+	case config.IsACMEEnabled():
+		return "", selftest.Skip("certificates managed by ACME")
+	}
+
+	return selftest.CheckCertificate(config.GetTLSCertFile(), config.GetTLSKeyFile(), time.Now())
+}
@@ -6,8 +6,8 @@ import (
 	"os"
 	"os/signal"
 	"path/filepath"
+	"strings"
 	"syscall"
-	"time"
 
 	"github.com/kardianos/service"
 
@@ -68,11 +68,11 @@ func (p *program) run() {
 
 // Stop is called when the service stops.
 func (p *program) Stop(_ service.Service) error {
-	// Graceful shutdown with timeout
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-	defer cancel()
-
 	if p.agent != nil {
+		// Graceful shutdown bounded by the configured grace period
+		ctx, cancel := context.WithTimeout(context.Background(), p.agent.ShutdownTimeout())
+		defer cancel()
+
 		if err := p.agent.Shutdown(ctx); err != nil {
 			return fmt.Errorf("failed to shutdown agent: %w", err)
 		}
@@ -397,7 +397,9 @@ func getServiceStatus(args *cliargs.ParsedArgs) error {
 			fmt.Printf("  Address:      %s\n", config.GetServerAddress())
 			fmt.Printf("  TLS Enabled:  %v\n", config.IsTLSEnabled())
 
-			if config.IsTLSEnabled() {
+			if config.IsACMEEnabled() {
+				fmt.Printf("  Certificate:  ACME (%s)\n", strings.Join(config.GetACMEConfig().Domains, ", "))
+			} else if config.IsTLSEnabled() {
 				fmt.Printf("  Certificate:  %s\n", config.GetTLSCertFile())
 			}
 
@@ -405,10 +407,14 @@ func getServiceStatus(args *cliargs.ParsedArgs) error {
 
 			// Database information
 			fmt.Println("Database:")
-			fmt.Printf("  Host:     %s:%d\n", config.GetDatabaseHost(), config.GetDatabasePort())
-			fmt.Printf("  Database: %s\n", config.GetDatabaseName())
-			fmt.Printf("  User:     %s\n", config.GetDatabaseUser())
-			fmt.Printf("  SSL Mode: %s\n", config.GetDatabaseSSLMode())
+			if config.UsesDatabaseDSN() {
+				fmt.Println("  Source:   database.dsn")
+			} else {
+				fmt.Printf("  Host:     %s:%d\n", config.GetDatabaseHost(), config.GetDatabasePort())
+				fmt.Printf("  Database: %s\n", config.GetDatabaseName())
+				fmt.Printf("  User:     %s\n", config.GetDatabaseUser())
+				fmt.Printf("  SSL Mode: %s\n", config.GetDatabaseSSLMode())
+			}
 
 			// Try to test database connection
 			connStr := config.GetDatabaseConnectionString()
@@ -21,7 +21,8 @@ type Agent struct {
 	config     *configuration.Configuration
 	logger     *logger.Logger
 	db         *database.DB
-	httpServer *server.StreamableHTTPServer
+	httpServer *server.TransportServers
+	auditLog   *server.AuditLog // nil when logging.audit_file is not set
 	args       *cliargs.ParsedArgs
 	shutdownCh chan struct{} // Channel to signal shutdown
 }
@@ -40,47 +41,151 @@ func NewAgent(args *cliargs.ParsedArgs) (*Agent, error) {
 		return nil, fmt.Errorf("failed to initialize configuration: %w", err)
 	}
 
+	// server.require_tls: never expose API keys over plaintext by accident
+	if err := config.CheckRequireTLS(); err != nil {
+		return nil, fmt.Errorf("refusing to start: %w", err)
+	}
+
 	moduleLogger.Info().
 		Str("config_path", args.ConfigPath).
 		Str("api_key_preview", maskKey(config.GetAPIKey())).
+		Int("api_keys", len(config.GetAPIKeys())).
 		Msg("Configuration loaded")
 
+	// Apply configured log masking patterns and sampling, now and on every reload
+	applyLogSettings := func() {
+		if err := logger.SetMaskPatterns(config.GetLogMaskPatterns()); err != nil {
+			moduleLogger.Warn().Err(err).Msg("Invalid log mask patterns, keeping previous ones")
+		}
+
+		logger.SetSampling(config.GetLogSampling())
+	}
+	applyLogSettings()
+	config.OnConfigChanged(applyLogSettings)
+
 	// Initialize database (optional - server can start without database)
 	dbLogger := logger.NewModuleLogger(baseLogger, logger.ModuleDatabase)
 	connStr := config.GetDatabaseConnectionString()
 
 	// Log connection attempt (with masked password)
-	moduleLogger.Debug().
-		Str("host", config.GetDatabaseHost()).
-		Int("port", config.GetDatabasePort()).
-		Str("database", config.GetDatabaseName()).
-		Str("user", config.GetDatabaseUser()).
-		Str("sslmode", config.GetDatabaseSSLMode()).
-		Msg("Attempting database connection")
-
-	db, err := database.New(connStr, dbLogger.Logger)
+	if config.UsesDatabaseDSN() {
+		moduleLogger.Debug().Msg("Attempting database connection using database.dsn")
+	} else {
+		moduleLogger.Debug().
+			Str("host", config.GetDatabaseHost()).
+			Int("port", config.GetDatabasePort()).
+			Str("database", config.GetDatabaseName()).
+			Str("user", config.GetDatabaseUser()).
+			Str("sslmode", config.GetDatabaseSSLMode()).
+			Msg("Attempting database connection")
+	}
+
+	retryPolicy := database.RetryPolicy{
+		Attempts: config.GetDatabaseConnectAttempts(),
+		Interval: config.GetDatabaseConnectRetryInterval(),
+	}
+
+	// On failure db is still returned, disconnected: it reconnects on the next tool call
+	db, err := database.Connect(context.Background(), database.NewPostgresConnector(connStr), retryPolicy, dbLogger.Logger)
 	if err != nil {
 		moduleLogger.Warn().
 			Err(err).
 			Str("sslmode", config.GetDatabaseSSLMode()).
-			Msg("Failed to initialize database - server will start but tools will not work")
-
-		db = nil
+			Msg("Failed to initialize database - server will start and retry the connection on the next tool call")
 	} else {
 		moduleLogger.Info().Msg("Database connection established")
+
+		if searchPath, err := db.CheckSchema(context.Background()); err != nil {
+			moduleLogger.Warn().Err(err).Msg("Exporter tables not found - set database.schema to the schema holding them")
+		} else {
+			moduleLogger.Debug().Str("search_path", searchPath).Msg("Exporter tables found")
+		}
+	}
+
+	// Server-side statement timeout, history table and custom query tables, updated on every reload
+	applyStatementTimeout := func() {
+		db.SetStatementTimeout(config.GetDatabaseStatementTimeout())
+		db.SetHistoryTable(config.HistoryTable())
+		db.SetCustomQueryAllowedTables(config.CustomQueryAllowedTables())
+	}
+	applyStatementTimeout()
+	config.OnConfigChanged(applyStatementTimeout)
+
+	// Reconnect when the connection settings change, e.g. a rotated database.password_file
+	config.OnConfigChanged(func() {
+		newConnStr := config.GetDatabaseConnectionString()
+		if newConnStr == connStr {
+			return
+		}
+
+		connStr = newConnStr
+
+		if err := db.Reconfigure(context.Background(), database.NewPostgresConnector(connStr)); err != nil {
+			moduleLogger.Warn().Err(err).Msg("Failed to reconnect with the new database settings - keeping the current connection")
+			return
+		}
+
+		moduleLogger.Info().Msg("Database reconnected with the new settings")
+	})
+
+	// Track in-flight tool calls so shutdown can drain them
+	inFlight := server.NewInFlightTracker()
+
+	serverOptions := []mcpserver.ServerOption{
+		mcpserver.WithToolHandlerMiddleware(inFlight.Middleware),
 	}
 
+	// Optional audit trail of tool calls, in its own rotated file
+	var auditLog *server.AuditLog
+
+	if auditConfig := config.GetAuditLogConfig(); auditConfig.File != "" {
+		auditLog, err = server.NewAuditLog(auditConfig)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open audit log: %w", err)
+		}
+
+		serverOptions = append(serverOptions, mcpserver.WithToolHandlerMiddleware(auditLog.Middleware))
+
+		moduleLogger.Info().
+			Str("file", auditConfig.File).
+			Bool("redact_queries", auditConfig.RedactQueries).
+			Msg("Tool call audit log enabled")
+	}
+
+	// Maintenance mode answers tool calls without running them, /health is not affected
+	maintenance := server.NewMaintenanceGate(config.MaintenanceMode)
+	serverOptions = append(serverOptions, mcpserver.WithToolHandlerMiddleware(maintenance.Middleware))
+
+	maintenanceMode := config.MaintenanceMode()
+	if maintenanceMode {
+		moduleLogger.Warn().Msg("Maintenance mode is on: tool calls return a maintenance message")
+	}
+
+	config.OnConfigChanged(func() {
+		if enabled := config.MaintenanceMode(); enabled != maintenanceMode {
+			maintenanceMode = enabled
+			moduleLogger.Warn().Bool("maintenance_mode", enabled).Msg("Maintenance mode changed")
+		}
+	})
+
+	// Bound concurrent tool calls so a burst of clients cannot exhaust the database pool.
+	// Registered last so that rejected calls are still audited.
+	limiter := server.NewConcurrencyLimiter(config.GetMaxConcurrentRequests(), server.DefaultConcurrencyWait)
+	serverOptions = append(serverOptions, mcpserver.WithToolHandlerMiddleware(limiter.Middleware))
+
 	// Create MCP server
 	mcpServer := mcpserver.NewMCPServer(
 		"prtg-server",
 		"1.0.0",
+		serverOptions...,
 	)
 
 	// Register MCP tools (database-based)
 	toolHandler := handlers.NewToolHandler(db, config, baseLogger)
 	toolHandler.RegisterTools(mcpServer)
 
-	toolsCount := 12 // Base tools from database
+	toolsCount := 25 // Base tools from database
+	historyToolsRegistered := false
 
 	// Initialize PRTG API client if enabled
 	if config.IsPRTGEnabled() {
@@ -120,25 +225,48 @@ func NewAgent(args *cliargs.ParsedArgs) (*Agent, error) {
 			metricsHandler := handlers.NewMetricsToolHandler(prtgClient, toolHandler)
 			metricsHandler.RegisterMetricsTools(mcpServer)
 
-			toolsCount += 3 // Add 3 metrics tools
+			// Enable live channel values in prtg_device_overview
+			toolHandler.SetPRTGClient(prtgClient)
+
+			toolsCount += 6 // Add 6 metrics tools
+			historyToolsRegistered = true
 			moduleLogger.Info().Msg("PRTG metrics tools registered")
+
+			// Register write tools (pause/resume) only when explicitly allowed
+			writeHandler := handlers.NewWriteToolHandler(prtgClient, toolHandler)
+			if n := writeHandler.RegisterWriteTools(mcpServer); n > 0 {
+				toolsCount += n
+				moduleLogger.Warn().Int("tools", n).Msg("PRTG write tools registered (allow_write_operations: true)")
+			}
 		}
 	} else {
 		moduleLogger.Info().Msg("PRTG API client disabled in configuration")
 	}
 
+	// Without the PRTG API, the time series tools can still read the database history table
+	if !historyToolsRegistered && config.HistoryTable() != "" {
+		metricsHandler := handlers.NewMetricsToolHandler(nil, toolHandler)
+		metricsHandler.RegisterHistoryTools(mcpServer)
+
+		toolsCount += 2
+		moduleLogger.Info().
+			Str("history_table", config.HistoryTable()).
+			Msg("Time series tools registered (database history only)")
+	}
+
 	moduleLogger.Info().
 		Int("tools_count", toolsCount).
 		Msg("MCP tools registered")
 
-	// Create Streamable HTTP server (modern MCP transport)
-	httpServer := server.NewStreamableHTTPServer(mcpServer, db, config, baseLogger)
+	// Create the HTTP listeners of the configured transports (Streamable HTTP by default)
+	httpServer := server.NewTransportServers(mcpServer, db, config, inFlight, baseLogger)
 
 	return &Agent{
 		config:     config,
 		logger:     baseLogger,
 		db:         db,
 		httpServer: httpServer,
+		auditLog:   auditLog,
 		args:       args,
 		shutdownCh: make(chan struct{}),
 	}, nil
@@ -149,7 +277,7 @@ func (a *Agent) Start() error {
 	moduleLogger := logger.NewModuleLogger(a.logger, "agent")
 	moduleLogger.Info().Msg("Starting agent")
 
-	// Start Streamable HTTP server
+	// Start the HTTP listeners
 	ctx := context.Background()
 	if err := a.httpServer.Start(ctx); err != nil {
 		return fmt.Errorf("failed to start HTTP server: %w", err)
@@ -175,8 +303,8 @@ func (a *Agent) Shutdown(ctx context.Context) error {
 		close(a.shutdownCh)
 	}
 
-	// Create shutdown context with timeout
-	shutdownCtx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	// Create shutdown context bounded by the configured grace period
+	shutdownCtx, cancel := context.WithTimeout(ctx, a.ShutdownTimeout())
 	defer cancel()
 
 	// Shutdown HTTP server
@@ -186,6 +314,13 @@ func (a *Agent) Shutdown(ctx context.Context) error {
 		}
 	}
 
+	// Close audit log after the HTTP server has drained the tool calls
+	if a.auditLog != nil {
+		if err := a.auditLog.Close(); err != nil {
+			moduleLogger.Error().Err(err).Msg("Error closing audit log")
+		}
+	}
+
 	// Close database
 	if a.db != nil {
 		if err := a.db.Close(); err != nil {
@@ -205,6 +340,11 @@ func (a *Agent) Shutdown(ctx context.Context) error {
 	return nil
 }
 
+// ShutdownTimeout returns the grace period allowed for a graceful shutdown.
+func (a *Agent) ShutdownTimeout() time.Duration {
+	return a.config.GetShutdownTimeout()
+}
+
 // maskKey masks an API key for logging.
 func maskKey(key string) string {
 	if len(key) <= 8 {
@@ -5,7 +5,7 @@ import "github.com/alexflint/go-arg"
 // ParsedArgs contains all command-line arguments for the MCP Server.
 type ParsedArgs struct {
 	// Command to execute
-	Command string `arg:"positional" help:"Command to execute (run, install, start, stop, uninstall, config)"`
+	Command string `arg:"positional" help:"Command to execute (run, install, start, stop, uninstall, config, selftest)"`
 
 	// Configuration
 	ConfigPath string `arg:"--config,-c" help:"Path to configuration file" default:"./config.yaml"`
@@ -0,0 +1,359 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"net"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+// Connection states reported by State.
+const (
+	StateConnected    = "connected"
+	StateDisconnected = "disconnected"
+)
+
+const (
+	// connectTimeout bounds a single connection attempt (open + ping).
+	connectTimeout = 10 * time.Second
+
+	// defaultMaxRetryInterval caps the exponential backoff between startup attempts.
+	defaultMaxRetryInterval = 30 * time.Second
+
+	// reconnectInterval is the minimum delay between runtime reconnection attempts,
+	// so that a down database is not hammered by every incoming query.
+	reconnectInterval = 5 * time.Second
+)
+
+// Connector opens a database connection pool and checks that it is reachable.
+// Tests inject their own Connector to simulate failing connections.
+type Connector func(ctx context.Context) (*sql.DB, error)
+
+// NewPostgresConnector returns a Connector opening PostgreSQL pools with optimized settings.
+func NewPostgresConnector(connStr string) Connector {
+	return func(ctx context.Context) (*sql.DB, error) {
+		conn, err := sql.Open("postgres", connStr)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open database: %w", err)
+		}
+
+		// Configure connection pool with optimized settings
+		// Higher limits for better concurrency while maintaining resource efficiency
+		conn.SetMaxOpenConns(50)                  // Increased from 25 for better concurrency
+		conn.SetMaxIdleConns(10)                  // 20% of MaxOpen (recommended ratio)
+		conn.SetConnMaxLifetime(15 * time.Minute) // Longer lifetime to avoid frequent reconnections
+		conn.SetConnMaxIdleTime(5 * time.Minute)  // Close idle connections after 5 minutes to free resources
+
+		if err := conn.PingContext(ctx); err != nil {
+			_ = conn.Close()
+			return nil, fmt.Errorf("failed to ping database: %w", err)
+		}
+
+		return conn, nil
+	}
+}
+
+// RetryPolicy controls the connection attempts made by Connect.
+type RetryPolicy struct {
+	Attempts    int           // Total number of attempts (values below 1 mean a single attempt)
+	Interval    time.Duration // Delay before the second attempt, doubled after each failure
+	MaxInterval time.Duration // Upper bound of the delay (0 = 30 seconds)
+}
+
+// backoff returns the delay to wait after the given failed attempt (1-based).
+func (p RetryPolicy) backoff(attempt int) time.Duration {
+	maxInterval := p.MaxInterval
+	if maxInterval <= 0 {
+		maxInterval = defaultMaxRetryInterval
+	}
+
+	delay := p.Interval
+	for i := 1; i < attempt && delay < maxInterval; i++ {
+		delay *= 2
+	}
+
+	if delay > maxInterval {
+		return maxInterval
+	}
+
+	return delay
+}
+
+// Connect establishes a database connection, retrying with exponential backoff.
+// When every attempt fails, Connect returns the last error together with a disconnected DB
+// that retries the connection on its next query, so callers may keep serving without a database.
+func Connect(ctx context.Context, connector Connector, policy RetryPolicy, logger *zerolog.Logger) (*DB, error) {
+	db := &DB{
+		logger:    logger,
+		connector: connector,
+	}
+
+	attempts := policy.Attempts
+	if attempts < 1 {
+		attempts = 1
+	}
+
+	for attempt := 1; ; attempt++ {
+		conn, err := db.connect(ctx, connector)
+		if err == nil {
+			db.conn = conn
+			db.broken = false
+			db.lastErr = nil
+			logger.Info().Int("attempt", attempt).Msg("database connection established")
+
+			return db, nil
+		}
+
+		db.lastErr = err
+		db.broken = true
+
+		if attempt >= attempts {
+			return db, fmt.Errorf("database connection failed after %d attempt(s): %w", attempt, err)
+		}
+
+		delay := policy.backoff(attempt)
+		logger.Warn().
+			Err(err).
+			Int("attempt", attempt).
+			Int("max_attempts", attempts).
+			Dur("retry_in", delay).
+			Msg("database connection failed, retrying")
+
+		select {
+		case <-ctx.Done():
+			return db, fmt.Errorf("database connection aborted: %w", ctx.Err())
+		case <-time.After(delay):
+		}
+	}
+}
+
+// connect runs connector with the per-attempt timeout.
+func (db *DB) connect(ctx context.Context, connector Connector) (*sql.DB, error) {
+	ctx, cancel := context.WithTimeout(ctx, connectTimeout)
+	defer cancel()
+
+	return connector(ctx)
+}
+
+// State returns the connection state (StateConnected or StateDisconnected)
+// and the last connection error while disconnected.
+func (db *DB) State() (string, error) {
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+
+	if db.conn == nil || db.broken {
+		return StateDisconnected, db.lastErr
+	}
+
+	return StateConnected, nil
+}
+
+// pool returns the connection pool to use, reconnecting first when the
+// connection was lost and a connector is available.
+func (db *DB) pool(ctx context.Context) (*sql.DB, error) {
+	db.mu.RLock()
+	conn, broken := db.conn, db.broken
+	db.mu.RUnlock()
+
+	if conn != nil && (!broken || db.connector == nil) {
+		return conn, nil
+	}
+
+	if db.connector == nil {
+		return nil, errors.New("database not connected")
+	}
+
+	return db.reconnect(ctx)
+}
+
+// reconnect replaces the connection pool, at most once per reconnectInterval. The new pool is
+// dialed without holding the lock, so that State and queries on the current pool don't wait
+// for a slow or unreachable database.
+func (db *DB) reconnect(ctx context.Context) (*sql.DB, error) {
+	connector, generation, current, err := db.beginReconnect()
+	if connector == nil {
+		return current, err
+	}
+
+	conn, err := db.connect(ctx, connector)
+	if err != nil {
+		db.mu.Lock()
+		defer db.mu.Unlock()
+
+		db.lastErr = err
+		db.logger.Warn().Err(err).Msg("database reconnection failed")
+
+		if db.conn != nil {
+			return db.conn, nil
+		}
+
+		return nil, fmt.Errorf("database unavailable: %w", err)
+	}
+
+	current, swapped := db.swapPool(conn, generation, false)
+	if !swapped {
+		if current == nil {
+			return nil, errors.New("database unavailable: settings changed during reconnection")
+		}
+
+		return current, nil
+	}
+
+	db.logger.Info().Msg("database connection re-established")
+
+	return current, nil
+}
+
+// beginReconnect reserves a reconnection attempt and returns the connector to dial and its
+// generation. When no attempt is due (the connection is healthy again, or an attempt started
+// less than reconnectInterval ago, possibly still dialing), the connector is nil and the pool
+// or error to use instead is returned.
+func (db *DB) beginReconnect() (Connector, uint64, *sql.DB, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	// Another query may have reconnected while we waited for the lock
+	if db.conn != nil && !db.broken {
+		return nil, 0, db.conn, nil
+	}
+
+	if time.Since(db.lastAttempt) < reconnectInterval {
+		if db.conn != nil {
+			// Keep using the old pool: database/sql re-dials on its own
+			return nil, 0, db.conn, nil
+		}
+
+		return nil, 0, nil, fmt.Errorf("database unavailable: %w", db.lastErr)
+	}
+
+	db.lastAttempt = time.Now()
+
+	return db.connector, db.generation, nil, nil
+}
+
+// swapPool installs conn, dialed with the connector of the given generation, as the connection
+// pool and closes the previous one. conn is closed instead when Reconfigure switched connectors
+// since, or when the current pool is healthy again and replaceHealthy is false. Returns the pool
+// in use and whether conn was installed.
+func (db *DB) swapPool(conn *sql.DB, generation uint64, replaceHealthy bool) (*sql.DB, bool) {
+	db.mu.Lock()
+
+	if db.generation != generation || (!replaceHealthy && db.conn != nil && !db.broken) {
+		current := db.conn
+		db.mu.Unlock()
+
+		if closeErr := conn.Close(); closeErr != nil {
+			db.logger.Warn().Err(closeErr).Msg("failed to close unused database pool")
+		}
+
+		return current, false
+	}
+
+	previous := db.conn
+	db.conn = conn
+	db.broken = false
+	db.lastErr = nil
+	db.mu.Unlock()
+
+	// Close waits for running queries, so it must not hold the lock
+	if previous != nil {
+		if closeErr := previous.Close(); closeErr != nil {
+			db.logger.Warn().Err(closeErr).Msg("failed to close previous database pool")
+		}
+	}
+
+	return conn, true
+}
+
+// Reconfigure switches to a new connector, e.g. after the credentials were rotated, and
+// opens a pool with it right away. On failure the current pool keeps serving queries and
+// the new connector is used by the next reconnection.
+func (db *DB) Reconfigure(ctx context.Context, connector Connector) error {
+	db.mu.Lock()
+	db.connector = connector
+	db.generation++
+	generation := db.generation
+	db.lastAttempt = time.Now()
+	db.mu.Unlock()
+
+	conn, err := db.connect(ctx, connector)
+	if err != nil {
+		db.mu.Lock()
+		defer db.mu.Unlock()
+
+		db.lastErr = err
+		db.logger.Warn().Err(err).Msg("database reconnection with new settings failed")
+
+		return fmt.Errorf("database unavailable: %w", err)
+	}
+
+	// A later Reconfigure takes precedence
+	if _, swapped := db.swapPool(conn, generation, true); swapped {
+		db.logger.Info().Msg("database connection re-established with new settings")
+	}
+
+	return nil
+}
+
+// observe marks the connection as broken when err indicates a lost connection,
+// so that the next query reconnects. A successful query marks it healthy again.
+func (db *DB) observe(err error) {
+	if !isConnectionError(err) {
+		if err == nil {
+			db.markHealthy()
+		}
+
+		return
+	}
+
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	if !db.broken {
+		db.logger.Warn().Err(err).Msg("database connection lost")
+	}
+
+	db.broken = true
+	db.lastErr = err
+}
+
+// markHealthy clears the broken flag after the current pool served a query,
+// as database/sql transparently re-dials dropped connections.
+func (db *DB) markHealthy() {
+	db.mu.RLock()
+	broken := db.broken
+	db.mu.RUnlock()
+
+	if !broken {
+		return
+	}
+
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	if db.conn != nil {
+		db.broken = false
+		db.lastErr = nil
+	}
+}
+
+// isConnectionError reports whether err is caused by the database connection
+// rather than by the query itself.
+func isConnectionError(err error) bool {
+	if err == nil {
+		return false
+	}
+
+	if errors.Is(err, driver.ErrBadConn) || errors.Is(err, sql.ErrConnDone) {
+		return true
+	}
+
+	var netErr net.Error
+
+	return errors.As(err, &netErr)
+}
@@ -0,0 +1,349 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/lib/pq"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// flakyConnector fails until failures reaches zero, then hands out sqlmock pools
+// prepared by setup (if set).
+type flakyConnector struct {
+	failures int
+	calls    int
+	mocks    []sqlmock.Sqlmock
+	setup    func(sqlmock.Sqlmock)
+	t        *testing.T
+}
+
+func (c *flakyConnector) connect(_ context.Context) (*sql.DB, error) {
+	c.calls++
+
+	if c.failures > 0 {
+		c.failures--
+		return nil, errors.New("connection refused")
+	}
+
+	conn, mock, err := sqlmock.New(sqlmock.MonitorPingsOption(true))
+	require.NoError(c.t, err)
+	c.t.Cleanup(func() { conn.Close() })
+	c.mocks = append(c.mocks, mock)
+
+	if c.setup != nil {
+		c.setup(mock)
+	}
+
+	return conn, nil
+}
+
+func TestConnect_RetriesUntilConnected(t *testing.T) {
+	logger := zerolog.Nop()
+	connector := &flakyConnector{failures: 2, t: t}
+
+	db, err := Connect(context.Background(), connector.connect, RetryPolicy{Attempts: 3, Interval: time.Millisecond}, &logger)
+	require.NoError(t, err)
+
+	assert.Equal(t, 3, connector.calls)
+
+	state, lastErr := db.State()
+	assert.Equal(t, StateConnected, state)
+	assert.NoError(t, lastErr)
+}
+
+func TestConnect_GivesUpAfterAttempts(t *testing.T) {
+	logger := zerolog.Nop()
+	connector := &flakyConnector{failures: 5, t: t}
+
+	db, err := Connect(context.Background(), connector.connect, RetryPolicy{Attempts: 2, Interval: time.Millisecond}, &logger)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "after 2 attempt(s)")
+	assert.Equal(t, 2, connector.calls)
+
+	require.NotNil(t, db, "a disconnected DB is returned so the server can keep running")
+
+	state, lastErr := db.State()
+	assert.Equal(t, StateDisconnected, state)
+	assert.EqualError(t, lastErr, "connection refused")
+}
+
+func TestDB_ReconnectsOnNextQuery(t *testing.T) {
+	logger := zerolog.Nop()
+	connector := &flakyConnector{failures: 1, t: t}
+
+	db, err := Connect(context.Background(), connector.connect, RetryPolicy{Attempts: 1}, &logger)
+	require.Error(t, err)
+
+	// The database is back: the next query re-establishes the connection
+	connector.setup = func(mock sqlmock.Sqlmock) {
+		mock.ExpectExec("SET application_name").WillReturnResult(sqlmock.NewResult(0, 0))
+	}
+
+	_, err = db.Exec(context.Background(), "SET application_name = 'test'")
+	require.NoError(t, err)
+	assert.Equal(t, 2, connector.calls)
+
+	state, _ := db.State()
+	assert.Equal(t, StateConnected, state)
+}
+
+func TestDB_ReconnectsAfterConnectionLost(t *testing.T) {
+	logger := zerolog.Nop()
+	connector := &flakyConnector{t: t}
+
+	db, err := Connect(context.Background(), connector.connect, RetryPolicy{Attempts: 1}, &logger)
+	require.NoError(t, err)
+
+	connReset := &net.OpError{Op: "read", Net: "tcp", Err: errors.New("connection reset by peer")}
+	connector.mocks[0].ExpectQuery("SELECT 1").WillReturnError(connReset)
+
+	var one int
+	require.Error(t, db.QueryRow(context.Background(), "SELECT 1").Scan(&one))
+
+	state, lastErr := db.State()
+	assert.Equal(t, StateDisconnected, state)
+	assert.ErrorIs(t, lastErr, connReset)
+
+	t.Run("throttled while recently attempted", func(t *testing.T) {
+		db.lastAttempt = time.Now()
+		connector.failures = 1
+
+		// The old pool is kept until the next reconnection attempt is allowed
+		connector.mocks[0].ExpectPing()
+		require.NoError(t, db.Health(context.Background()))
+		assert.Equal(t, 1, connector.calls)
+		connector.failures = 0
+	})
+
+	db.broken = true
+	db.lastAttempt = time.Time{}
+
+	connector.setup = func(mock sqlmock.Sqlmock) {
+		mock.ExpectQuery("SELECT 1").WillReturnRows(sqlmock.NewRows([]string{"one"}).AddRow(1))
+	}
+	connector.mocks[0].ExpectClose()
+
+	require.NoError(t, db.QueryRow(context.Background(), "SELECT 1").Scan(&one))
+	assert.Equal(t, 1, one)
+	assert.Equal(t, 2, connector.calls, "a new pool replaces the broken one")
+	assert.NoError(t, connector.mocks[0].ExpectationsWereMet(), "the broken pool is closed")
+
+	state, lastErr = db.State()
+	assert.Equal(t, StateConnected, state)
+	assert.NoError(t, lastErr)
+}
+
+func TestDB_Reconfigure(t *testing.T) {
+	logger := zerolog.Nop()
+	connector := &flakyConnector{t: t}
+
+	db, err := Connect(context.Background(), connector.connect, RetryPolicy{Attempts: 1}, &logger)
+	require.NoError(t, err)
+
+	// New settings that do not work yet: the current pool is kept
+	rotated := &flakyConnector{failures: 1, t: t}
+	require.Error(t, db.Reconfigure(context.Background(), rotated.connect))
+
+	connector.mocks[0].ExpectPing()
+	require.NoError(t, db.Health(context.Background()))
+	assert.Equal(t, 1, connector.calls)
+
+	// Once they work, the new pool replaces the old one
+	connector.mocks[0].ExpectClose()
+	require.NoError(t, db.Reconfigure(context.Background(), rotated.connect))
+	assert.Equal(t, 2, rotated.calls)
+	assert.NoError(t, connector.mocks[0].ExpectationsWereMet(), "the previous pool is closed")
+
+	state, lastErr := db.State()
+	assert.Equal(t, StateConnected, state)
+	assert.NoError(t, lastErr)
+}
+
+// blockingConnector wraps a connector so that each dial waits for release, after signaling
+// on dialing.
+func blockingConnector(connect Connector, dialing chan<- struct{}, release <-chan struct{}) Connector {
+	return func(ctx context.Context) (*sql.DB, error) {
+		dialing <- struct{}{}
+		<-release
+
+		return connect(ctx)
+	}
+}
+
+func TestDB_ReconnectDialsWithoutLock(t *testing.T) {
+	logger := zerolog.Nop()
+	connector := &flakyConnector{failures: 1, t: t}
+
+	db, err := Connect(context.Background(), connector.connect, RetryPolicy{Attempts: 1}, &logger)
+	require.Error(t, err)
+
+	dialing := make(chan struct{}, 2)
+	release := make(chan struct{})
+	db.connector = blockingConnector(connector.connect, dialing, release)
+
+	done := make(chan error, 1)
+
+	go func() {
+		_, err := db.pool(context.Background())
+		done <- err
+	}()
+
+	<-dialing
+
+	// State does not wait for the dial
+	stateDone := make(chan string, 1)
+
+	go func() {
+		state, _ := db.State()
+		stateDone <- state
+	}()
+
+	select {
+	case state := <-stateDone:
+		assert.Equal(t, StateDisconnected, state)
+	case <-time.After(time.Second):
+		t.Fatal("State blocked by the reconnection")
+	}
+
+	// A concurrent query does not dial a second pool
+	_, err = db.pool(context.Background())
+	assert.ErrorContains(t, err, "database unavailable")
+	assert.Empty(t, dialing)
+
+	close(release)
+	require.NoError(t, <-done)
+
+	state, _ := db.State()
+	assert.Equal(t, StateConnected, state)
+	assert.Equal(t, 2, connector.calls)
+}
+
+func TestDB_ReconnectDropsPoolOfPreviousSettings(t *testing.T) {
+	logger := zerolog.Nop()
+	connector := &flakyConnector{failures: 1, t: t}
+
+	db, err := Connect(context.Background(), connector.connect, RetryPolicy{Attempts: 1}, &logger)
+	require.Error(t, err)
+
+	dialing := make(chan struct{}, 1)
+	release := make(chan struct{})
+	db.connector = blockingConnector(connector.connect, dialing, release)
+
+	type result struct {
+		conn *sql.DB
+		err  error
+	}
+
+	done := make(chan result, 1)
+
+	go func() {
+		conn, err := db.pool(context.Background())
+		done <- result{conn, err}
+	}()
+
+	<-dialing
+
+	// The credentials are rotated while the old ones are still dialing
+	rotated := &flakyConnector{t: t}
+	require.NoError(t, db.Reconfigure(context.Background(), rotated.connect))
+
+	connector.setup = func(mock sqlmock.Sqlmock) { mock.ExpectClose() }
+	close(release)
+
+	got := <-done
+	require.NoError(t, got.err)
+
+	db.mu.RLock()
+	current := db.conn
+	db.mu.RUnlock()
+
+	assert.Same(t, current, got.conn, "the query uses the pool of the new settings")
+	assert.Equal(t, 1, rotated.calls)
+	require.Len(t, connector.mocks, 1)
+	assert.NoError(t, connector.mocks[0].ExpectationsWereMet(), "the pool of the previous settings is closed")
+}
+
+func TestRetryPolicy_Backoff(t *testing.T) {
+	policy := RetryPolicy{Interval: time.Second, MaxInterval: 5 * time.Second}
+
+	assert.Equal(t, time.Second, policy.backoff(1))
+	assert.Equal(t, 2*time.Second, policy.backoff(2))
+	assert.Equal(t, 4*time.Second, policy.backoff(3))
+	assert.Equal(t, 5*time.Second, policy.backoff(4), "capped at MaxInterval")
+}
+
+func TestHealth_ClassifiesErrors(t *testing.T) {
+	logger := zerolog.Nop()
+
+	tests := []struct {
+		name   string
+		err    error
+		reason string
+	}{
+		{"refused", &net.OpError{Op: "dial", Net: "tcp", Err: errors.New("connect: connection refused")}, HealthUnreachable},
+		{"bad password", &pq.Error{Code: "28P01", Message: `password authentication failed for user "prtg_reader"`}, HealthAuthFailed},
+		{"deadline", fmt.Errorf("failed to ping database: %w", context.DeadlineExceeded), HealthTimeout},
+		{"missing database", &pq.Error{Code: "3D000", Message: `database "prtg" does not exist`}, HealthUnavailable},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			failing := func(context.Context) (*sql.DB, error) { return nil, tt.err }
+
+			db, _ := Connect(context.Background(), failing, RetryPolicy{Attempts: 1}, &logger)
+
+			err := db.Health(context.Background())
+
+			var healthErr *HealthError
+			require.ErrorAs(t, err, &healthErr)
+			assert.Equal(t, tt.reason, healthErr.Reason)
+			assert.ErrorIs(t, err, tt.err, "the driver error is kept for logs")
+		})
+	}
+}
+
+func TestDB_CheckSchema(t *testing.T) {
+	logger := zerolog.Nop()
+
+	tests := []struct {
+		name    string
+		found   bool
+		wantErr string
+	}{
+		{"tables in the configured schema", true, ""},
+		{"tables not in the search path", false, `table prtg_sensor not found with search_path "prtg_exporter, public"`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conn, mock, err := sqlmock.New()
+			require.NoError(t, err)
+			defer conn.Close()
+
+			mock.ExpectQuery(`SELECT current_setting\('search_path'\), to_regclass\('prtg_sensor'\) IS NOT NULL`).
+				WillReturnRows(sqlmock.NewRows([]string{"search_path", "found"}).AddRow("prtg_exporter, public", tt.found))
+
+			db := &DB{conn: conn, logger: &logger}
+
+			searchPath, err := db.CheckSchema(context.Background())
+			assert.Equal(t, "prtg_exporter, public", searchPath)
+
+			if tt.wantErr == "" {
+				assert.NoError(t, err)
+			} else {
+				assert.ErrorContains(t, err, tt.wantErr)
+			}
+
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
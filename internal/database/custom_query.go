@@ -0,0 +1,297 @@
+package database
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// fromClauseEnd lists the keywords ending the table list of a FROM clause.
+var fromClauseEnd = map[string]bool{
+	"WHERE": true, "GROUP": true, "HAVING": true, "ORDER": true, "LIMIT": true, "OFFSET": true,
+	"UNION": true, "INTERSECT": true, "EXCEPT": true, "WINDOW": true, "FETCH": true, "FOR": true,
+}
+
+// subqueryStart lists the keywords starting a subquery in parentheses.
+var subqueryStart = map[string]bool{"SELECT": true, "WITH": true, "VALUES": true, "TABLE": true}
+
+// sqlTextFunctions lists functions that run SQL text or read a table given by name, which
+// would escape the allowed tables: query_to_xml('SELECT * FROM secret', ...) references no table.
+// Functions whose name starts with "dblink" or "crosstab" are rejected as well.
+var sqlTextFunctions = map[string]bool{
+	"query_to_xml": true, "query_to_xmlschema": true, "query_to_xml_and_xmlschema": true,
+	"cursor_to_xml": true, "cursor_to_xmlschema": true,
+	"table_to_xml": true, "table_to_xmlschema": true, "table_to_xml_and_xmlschema": true,
+	"schema_to_xml": true, "schema_to_xmlschema": true, "schema_to_xml_and_xmlschema": true,
+	"database_to_xml": true, "database_to_xmlschema": true, "database_to_xml_and_xmlschema": true,
+	"ts_stat": true, "xpath_table": true, "connectby": true,
+}
+
+// runsSQLText reports whether the function name is one of sqlTextFunctions.
+func runsSQLText(name string) bool {
+	return sqlTextFunctions[name] || strings.HasPrefix(name, "dblink") || strings.HasPrefix(name, "crosstab")
+}
+
+// SetCustomQueryAllowedTables restricts custom queries to the given tables, optionally
+// schema-qualified. An empty list allows every table the database user can read.
+// The names must have been validated as identifiers by the configuration.
+func (db *DB) SetCustomQueryAllowedTables(tables []string) {
+	allowed := make(map[string]bool, len(tables))
+
+	for _, table := range tables {
+		allowed[strings.ToLower(table)] = true
+	}
+
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	db.allowedTables = allowed
+}
+
+// checkCustomQueryTables rejects a custom query referencing tables outside the allowed tables.
+func (db *DB) checkCustomQueryTables(query string) error {
+	db.mu.RLock()
+	allowed := db.allowedTables
+	db.mu.RUnlock()
+
+	if len(allowed) == 0 {
+		return nil
+	}
+
+	tables, err := customQueryTables(query)
+	if err != nil {
+		return err
+	}
+
+	var denied []string
+
+	for _, table := range tables {
+		if !allowed[table] {
+			denied = append(denied, table)
+		}
+	}
+
+	if len(denied) > 0 {
+		return fmt.Errorf("query references tables that are not allowed: %s", strings.Join(denied, ", "))
+	}
+
+	return nil
+}
+
+// customQueryTables returns the tables referenced in the FROM and JOIN clauses of a SELECT
+// query, sorted and without duplicates. Unquoted names are lowercased like PostgreSQL does,
+// schema-qualified names are kept qualified (e.g. "public.prtg_sensor"). Subqueries and
+// parenthesized joins are scanned as well; function calls in FROM (e.g. generate_series) are
+// not tables. An error is returned when a FROM item is not recognized, or when the query
+// calls one of sqlTextFunctions, as the tables it reads could not be checked.
+//
+// This is a lightweight tokenizer, not a SQL parser: it relies on prepareCustomQuery having
+// rejected comments and multiple statements. It is given the query as written, before
+// prepareCustomQuery wraps it.
+func customQueryTables(query string) ([]string, error) {
+	tokens := sqlTokens(query)
+	found := map[string]bool{}
+
+	// Per parenthesis depth: whether a SELECT was seen (so FROM is a table clause rather than
+	// e.g. EXTRACT(YEAR FROM ts)) and whether the tokens are in a FROM clause. A parenthesis
+	// opening a FROM item, e.g. FROM (a JOIN b), continues the FROM clause of its parent.
+	type scope struct{ selectSeen, inFrom bool }
+
+	scopes := []scope{{}}
+	fromParen := -1 // Index of the parenthesis opening the current FROM item, if any
+
+	// table records the table referenced by the FROM item at tokens[i], skipping subqueries
+	// and function calls
+	table := func(i int) error {
+		for i < len(tokens) && (tokens[i].keyword == "ONLY" || tokens[i].keyword == "LATERAL") {
+			i++
+		}
+
+		if i >= len(tokens) {
+			return fmt.Errorf("cannot determine the tables of the query: FROM clause without table")
+		}
+
+		if tokens[i].text == "(" {
+			fromParen = i
+			return nil
+		}
+
+		name := tokens[i].name
+
+		for i+2 < len(tokens) && tokens[i+1].text == "." && tokens[i+2].name != "" {
+			name += "." + tokens[i+2].name
+			i += 2
+		}
+
+		if name == "" {
+			return fmt.Errorf("cannot determine the tables of the query near %q", tokens[i].text)
+		}
+
+		// A function call, not a table
+		if i+1 < len(tokens) && tokens[i+1].text == "(" {
+			return nil
+		}
+
+		found[name] = true
+
+		return nil
+	}
+
+	for i, token := range tokens {
+		current := &scopes[len(scopes)-1]
+
+		var err error
+
+		switch {
+		case token.name != "" && i+1 < len(tokens) && tokens[i+1].text == "(" && runsSQLText(token.name):
+			return nil, fmt.Errorf("function %s is not allowed in custom queries", token.name)
+		case token.text == "(" && i == fromParen:
+			scopes = append(scopes, scope{selectSeen: true, inFrom: true})
+
+			// A parenthesized join starts with a FROM item, a subquery with SELECT
+			if next := i + 1; next < len(tokens) && !subqueryStart[tokens[next].keyword] {
+				err = table(next)
+			}
+		case token.text == "(":
+			scopes = append(scopes, scope{})
+		case token.text == ")":
+			if len(scopes) > 1 {
+				scopes = scopes[:len(scopes)-1]
+			}
+		case token.keyword == "SELECT":
+			current.selectSeen = true
+			current.inFrom = false
+		case token.keyword == "FROM" && current.selectSeen && (i == 0 || tokens[i-1].keyword != "DISTINCT"):
+			current.inFrom = true
+			err = table(i + 1)
+		case token.keyword == "JOIN" && current.selectSeen:
+			err = table(i + 1)
+		case token.text == "," && current.inFrom:
+			err = table(i + 1)
+		case token.keyword == "TABLE":
+			// TABLE name is short for SELECT * FROM name
+			err = table(i + 1)
+		case fromClauseEnd[token.keyword]:
+			current.inFrom = false
+		}
+
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	tables := make([]string, 0, len(found))
+	for name := range found {
+		tables = append(tables, name)
+	}
+
+	sort.Strings(tables)
+
+	return tables, nil
+}
+
+// sqlToken is a token of a SQL query. name is set for identifiers (lowercased unless quoted)
+// and keyword for unquoted words (uppercased).
+type sqlToken struct {
+	text    string
+	name    string
+	keyword string
+}
+
+// sqlTokens splits a query into identifiers, string literals and single-character symbols.
+// String literals (including E'...' and dollar-quoted strings) are returned as one token,
+// so their content is never taken for a keyword.
+func sqlTokens(query string) []sqlToken {
+	var tokens []sqlToken
+
+	for i := 0; i < len(query); {
+		c := query[i]
+
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			i++
+		case c == '$' && dollarQuoteTag(query[i:]) != "":
+			tag := dollarQuoteTag(query[i:])
+
+			end := len(query)
+			if j := strings.Index(query[i+len(tag):], tag); j != -1 {
+				end = i + len(tag) + j + len(tag)
+			}
+
+			tokens = append(tokens, sqlToken{text: query[i:end]})
+			i = end
+		case c == '\'' || c == '"':
+			// Quoted literal or identifier, a doubled quote escapes the quote. In E'...'
+			// strings a backslash escapes the next character as well.
+			escapes := c == '\'' && i > 0 && (query[i-1] == 'E' || query[i-1] == 'e') &&
+				(i < 2 || !isIdentifierChar(query[i-2]))
+			j := i + 1
+
+			for j < len(query) {
+				if escapes && query[j] == '\\' {
+					j += 2
+					continue
+				}
+
+				if query[j] == c {
+					if j+1 < len(query) && query[j+1] == c {
+						j += 2
+						continue
+					}
+
+					break
+				}
+
+				j++
+			}
+
+			text := query[i:min(j+1, len(query))]
+			token := sqlToken{text: text}
+
+			if c == '"' {
+				token.name = strings.ReplaceAll(strings.Trim(text, `"`), `""`, `"`)
+			}
+
+			tokens = append(tokens, token)
+			i = j + 1
+		case isIdentifierChar(c):
+			j := i
+			for j < len(query) && isIdentifierChar(query[j]) {
+				j++
+			}
+
+			word := query[i:j]
+			tokens = append(tokens, sqlToken{text: word, name: strings.ToLower(word), keyword: strings.ToUpper(word)})
+			i = j
+		default:
+			tokens = append(tokens, sqlToken{text: string(c)})
+			i++
+		}
+	}
+
+	return tokens
+}
+
+// dollarQuoteTag returns the opening delimiter ($$ or $tag$) of a dollar-quoted string at the
+// start of s, or "" when s does not start with one (e.g. a $1 parameter).
+func dollarQuoteTag(s string) string {
+	for j := 1; j < len(s); j++ {
+		switch c := s[j]; {
+		case c == '$':
+			return s[:j+1]
+		case c == '_' || c >= 0x80 || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || (j > 1 && c >= '0' && c <= '9'):
+			continue
+		default:
+			return ""
+		}
+	}
+
+	return ""
+}
+
+// isIdentifierChar reports whether c can be part of an unquoted identifier or number.
+// Non-ASCII bytes are accepted, as PostgreSQL allows letters beyond ASCII in identifiers.
+func isIdentifierChar(c byte) bool {
+	return c == '_' || c == '$' || c >= 0x80 ||
+		(c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || (c >= '0' && c <= '9')
+}
@@ -0,0 +1,187 @@
+package database
+
+import (
+	"context"
+	"regexp"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCustomQueryTables(t *testing.T) {
+	tests := []struct {
+		name  string
+		query string
+		want  []string
+	}{
+		{
+			name:  "single table with alias",
+			query: "SELECT s.name FROM prtg_sensor s WHERE s.status = 5",
+			want:  []string{"prtg_sensor"},
+		},
+		{
+			name:  "joins and comma list",
+			query: "SELECT * FROM prtg_sensor s JOIN prtg_device d ON d.id = s.prtg_device_id LEFT JOIN prtg_group AS g ON g.id = d.prtg_group_id, prtg_tag t",
+			want:  []string{"prtg_device", "prtg_group", "prtg_sensor", "prtg_tag"},
+		},
+		{
+			name:  "subqueries",
+			query: "SELECT id FROM (SELECT id FROM prtg_sensor) x WHERE id IN (SELECT prtg_sensor_id FROM prtg_sensor_tag)",
+			want:  []string{"prtg_sensor", "prtg_sensor_tag"},
+		},
+		{
+			name:  "schema-qualified and quoted names",
+			query: `SELECT * FROM Public.PRTG_Sensor, "public"."Mixed_Case"`,
+			want:  []string{"public.Mixed_Case", "public.prtg_sensor"},
+		},
+		{
+			name:  "FROM inside functions and IS DISTINCT FROM",
+			query: "SELECT EXTRACT(YEAR FROM last_check), SUBSTRING(name FROM 1 FOR 3) FROM prtg_sensor WHERE status IS DISTINCT FROM priority",
+			want:  []string{"prtg_sensor"},
+		},
+		{
+			name:  "table functions are not tables",
+			query: "SELECT * FROM generate_series(1, 10) n, prtg_sensor",
+			want:  []string{"prtg_sensor"},
+		},
+		{
+			name:  "keywords inside string literals",
+			query: "SELECT * FROM prtg_sensor WHERE name = 'x FROM secrets' OR message = E'it\\'s FROM audit'",
+			want:  []string{"prtg_sensor"},
+		},
+		{
+			name:  "literals cannot hide a subquery",
+			query: "SELECT E'\\'', (SELECT 1 FROM secrets), $$'$$, (SELECT 1 FROM tokens), '$$'",
+			want:  []string{"secrets", "tokens"},
+		},
+		{
+			name:  "parenthesized cross join",
+			query: "SELECT * FROM (secret CROSS JOIN prtg_sensor)",
+			want:  []string{"prtg_sensor", "secret"},
+		},
+		{
+			name:  "parenthesized join with aliases",
+			query: "SELECT * FROM (secret s JOIN prtg_sensor p ON true)",
+			want:  []string{"prtg_sensor", "secret"},
+		},
+		{
+			name:  "nested parenthesized joins after a comma",
+			query: "SELECT * FROM prtg_device d, ((secret JOIN prtg_sensor ON true) JOIN (SELECT 1 FROM tokens) t ON true)",
+			want:  []string{"prtg_device", "prtg_sensor", "secret", "tokens"},
+		},
+		{
+			name:  "join on a parenthesized join",
+			query: "SELECT * FROM prtg_device d JOIN (secret JOIN prtg_sensor ON true) ON true",
+			want:  []string{"prtg_device", "prtg_sensor", "secret"},
+		},
+		{
+			name:  "TABLE command in a subquery",
+			query: "SELECT * FROM prtg_sensor WHERE id IN (TABLE secret)",
+			want:  []string{"prtg_sensor", "secret"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tables, err := customQueryTables(tt.query)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, tables)
+		})
+	}
+}
+
+func TestCustomQueryTables_Rejected(t *testing.T) {
+	tests := []struct {
+		name    string
+		query   string
+		wantErr string
+	}{
+		{
+			name:    "query_to_xml runs SQL text",
+			query:   "SELECT query_to_xml('select * from secret', true, false, '')",
+			wantErr: "function query_to_xml is not allowed in custom queries",
+		},
+		{
+			name:    "schema-qualified query_to_xml_and_xmlschema",
+			query:   "SELECT pg_catalog.query_to_xml_and_xmlschema('select * from secret', true, false, '')",
+			wantErr: "function query_to_xml_and_xmlschema is not allowed in custom queries",
+		},
+		{
+			name:    "table_to_xml reads a table by name",
+			query:   "SELECT table_to_xml('secret', true, false, '') FROM prtg_sensor",
+			wantErr: "function table_to_xml is not allowed in custom queries",
+		},
+		{
+			name:    "dblink functions",
+			query:   "SELECT * FROM dblink_exec('dbname=prtg', 'select 1')",
+			wantErr: "function dblink_exec is not allowed in custom queries",
+		},
+		{
+			name:    "FROM item that is not a table",
+			query:   "SELECT * FROM 'secret'",
+			wantErr: `cannot determine the tables of the query near "'secret'"`,
+		},
+		{
+			name:    "FROM without table",
+			query:   "SELECT 1 FROM",
+			wantErr: "cannot determine the tables of the query: FROM clause without table",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := customQueryTables(tt.query)
+			assert.EqualError(t, err, tt.wantErr)
+		})
+	}
+}
+
+func TestExecuteCustomQuery_AllowedTables(t *testing.T) {
+	mockDB, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer mockDB.Close()
+
+	logger := zerolog.Nop()
+	db := &DB{conn: mockDB, logger: &logger}
+	db.SetCustomQueryAllowedTables([]string{"prtg_sensor", "PRTG_Device"})
+
+	t.Run("allowed tables", func(t *testing.T) {
+		mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM (SELECT s.name FROM prtg_sensor s JOIN prtg_device d ON d.id = s.prtg_device_id) q LIMIT $1")).
+			WithArgs(10).
+			WillReturnRows(sqlmock.NewRows([]string{"name"}).AddRow("Ping"))
+
+		results, err := db.ExecuteCustomQuery(context.Background(), "SELECT s.name FROM prtg_sensor s JOIN prtg_device d ON d.id = s.prtg_device_id", 10)
+		require.NoError(t, err)
+		assert.Len(t, results, 1)
+	})
+
+	t.Run("disallowed table", func(t *testing.T) {
+		_, err := db.ExecuteCustomQuery(context.Background(), "SELECT s.name FROM prtg_sensor s WHERE s.id IN (SELECT id FROM app_users)", 10)
+		assert.EqualError(t, err, "query references tables that are not allowed: app_users")
+
+		_, _, err = db.StreamCustomQuery(context.Background(), "SELECT * FROM public.prtg_sensor", 10, func([]string, []interface{}) error { return nil })
+		assert.EqualError(t, err, "query references tables that are not allowed: public.prtg_sensor")
+
+		_, err = db.ExecuteCustomQuery(context.Background(), "SELECT * FROM (app_users CROSS JOIN prtg_sensor)", 10)
+		assert.EqualError(t, err, "query references tables that are not allowed: app_users")
+
+		_, err = db.ExecuteCustomQuery(context.Background(), "SELECT query_to_xml('select * from app_users', true, false, '')", 10)
+		assert.EqualError(t, err, "function query_to_xml is not allowed in custom queries")
+	})
+
+	t.Run("empty list allows every table", func(t *testing.T) {
+		db.SetCustomQueryAllowedTables(nil)
+
+		mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM (SELECT id FROM app_users) q LIMIT $1")).
+			WithArgs(10).
+			WillReturnRows(sqlmock.NewRows([]string{"id"}))
+
+		_, err := db.ExecuteCustomQuery(context.Background(), "SELECT id FROM app_users", 10)
+		require.NoError(t, err)
+	})
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
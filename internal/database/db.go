@@ -3,58 +3,62 @@ package database
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"fmt"
+	"net"
+	"strings"
+	"sync"
 	"time"
 
+	"github.com/lib/pq"
 	"github.com/rs/zerolog"
-
-	// PostgreSQL driver.
-	_ "github.com/lib/pq"
 )
 
 // DB wraps the database connection and provides query methods.
+// When created with a Connector, a DB whose connection is lost (or was never established)
+// reconnects on the next query instead of failing permanently.
 type DB struct {
 	conn   *sql.DB
 	logger *zerolog.Logger
+
+	mu          sync.RWMutex
+	connector   Connector // nil: fixed connection, no reconnection
+	broken      bool      // A connection error was seen, reconnect before the next query
+	lastErr     error     // Last connection error, reported by State
+	lastAttempt time.Time // Last reconnection attempt, for throttling
+	generation  uint64    // Bumped by Reconfigure, so that pools dialed with a previous connector are dropped
+
+	statementTimeout time.Duration   // Server-side limit for heavy queries (0 = none), see withStatementTimeout
+	historyTable     string          // Table of historical channel values ("" = none), see GetSensorHistoryFromDB
+	allowedTables    map[string]bool // Tables custom queries may read (empty = any), see SetCustomQueryAllowedTables
 }
 
+// queryer is the query interface shared by the connection pool and a transaction.
+type queryer interface {
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+}
+
+// txKey is the context key of the transaction opened by withStatementTimeout.
+type txKey struct{}
+
 // New creates a PostgreSQL database connection with optimized pool settings.
 // The connection is validated with a ping before returning.
 func New(connStr string, logger *zerolog.Logger) (*DB, error) {
-	conn, err := sql.Open("postgres", connStr)
+	db, err := Connect(context.Background(), NewPostgresConnector(connStr), RetryPolicy{Attempts: 1}, logger)
 	if err != nil {
-		return nil, fmt.Errorf("failed to open database: %w", err)
-	}
-
-	// Configure connection pool with optimized settings
-	// Higher limits for better concurrency while maintaining resource efficiency
-	conn.SetMaxOpenConns(50)                  // Increased from 25 for better concurrency
-	conn.SetMaxIdleConns(10)                  // 20% of MaxOpen (recommended ratio)
-	conn.SetConnMaxLifetime(15 * time.Minute) // Longer lifetime to avoid frequent reconnections
-	conn.SetConnMaxIdleTime(5 * time.Minute)  // Close idle connections after 5 minutes to free resources
-
-	// Test connection
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
-	defer cancel()
-
-	if err := conn.PingContext(ctx); err != nil {
-		if closeErr := conn.Close(); closeErr != nil {
-			logger.Error().Err(closeErr).Msg("Failed to close connection after ping error")
-		}
-
-		return nil, fmt.Errorf("failed to ping database: %w", err)
+		return nil, err
 	}
 
-	logger.Info().Msg("database connection established")
-
-	return &DB{
-		conn:   conn,
-		logger: logger,
-	}, nil
+	return db, nil
 }
 
 // Close closes the database connection.
 func (db *DB) Close() error {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
 	if db.conn != nil {
 		return db.conn.Close()
 	}
@@ -65,9 +69,76 @@ func (db *DB) Close() error {
 // Conn returns the underlying database connection.
 // Use with caution - prefer using DB methods for proper context handling.
 func (db *DB) Conn() *sql.DB {
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+
 	return db.conn
 }
 
+// SetStatementTimeout sets the PostgreSQL statement_timeout applied to custom, hierarchy
+// and statistics queries. Zero or a negative value disables it.
+func (db *DB) SetStatementTimeout(timeout time.Duration) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	db.statementTimeout = timeout
+}
+
+// SetHistoryTable sets the table read by GetSensorHistoryFromDB, optionally schema-qualified
+// (e.g. "metrics.prtg_channel_history"). An empty name disables database history.
+// The name must have been validated as an identifier by the configuration.
+func (db *DB) SetHistoryTable(table string) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	db.historyTable = table
+}
+
+// withStatementTimeout runs fn in a transaction where PostgreSQL itself cancels any statement
+// running longer than the statement timeout (SET LOCAL statement_timeout), so a query does not
+// keep a backend busy after the client gave up. Query, QueryRow and Exec calls made with the
+// context passed to fn join the transaction. Without a timeout fn runs directly on the pool.
+func (db *DB) withStatementTimeout(ctx context.Context, fn func(ctx context.Context) error) error {
+	db.mu.RLock()
+	timeout := db.statementTimeout
+	db.mu.RUnlock()
+
+	if timeout <= 0 || ctx.Value(txKey{}) != nil {
+		return fn(ctx)
+	}
+
+	conn, err := db.pool(ctx)
+	if err != nil {
+		return err
+	}
+
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		db.observe(err)
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+
+	// Only reads run in the transaction: rolling back is equivalent to committing
+	defer func() { _ = tx.Rollback() }()
+
+	// SET does not accept bind parameters; the value is an integer
+	if _, err := tx.ExecContext(ctx, fmt.Sprintf("SET LOCAL statement_timeout = %d", timeout.Milliseconds())); err != nil {
+		db.observe(err)
+		return fmt.Errorf("failed to set statement timeout: %w", err)
+	}
+
+	return fn(context.WithValue(ctx, txKey{}, tx))
+}
+
+// runner returns the transaction bound to ctx by withStatementTimeout, or else the connection pool.
+func (db *DB) runner(ctx context.Context) (queryer, error) {
+	if tx, ok := ctx.Value(txKey{}).(*sql.Tx); ok {
+		return tx, nil
+	}
+
+	return db.pool(ctx)
+}
+
 // Query executes a query using the provided context
 // IMPORTANT: The context must remain valid while scanning rows.
 // The caller is responsible for context lifetime management.
@@ -77,20 +148,55 @@ func (db *DB) Query(ctx context.Context, query string, args ...interface{}) (*sq
 		Interface("args", args).
 		Msg("executing query")
 
-	return db.conn.QueryContext(ctx, query, args...)
+	conn, err := db.runner(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := conn.QueryContext(ctx, query, args...)
+	db.observe(err)
+
+	return rows, err
+}
+
+// Row is the result of QueryRow. Like *sql.Row, errors are deferred until Scan.
+type Row struct {
+	row    *sql.Row
+	err    error
+	cancel context.CancelFunc
+	db     *DB
+}
+
+// Scan copies the columns of the row into dest. It returns sql.ErrNoRows if the query selected no rows.
+func (r *Row) Scan(dest ...interface{}) error {
+	defer r.cancel()
+
+	if r.err != nil {
+		return r.err
+	}
+
+	err := r.row.Scan(dest...)
+	r.db.observe(err)
+
+	return err
 }
 
 // QueryRow executes a query expected to return at most one row.
-func (db *DB) QueryRow(ctx context.Context, query string, args ...interface{}) *sql.Row {
+// The 30 second query timeout lasts until Scan returns.
+func (db *DB) QueryRow(ctx context.Context, query string, args ...interface{}) *Row {
 	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
-	defer cancel()
 
 	db.logger.Debug().
 		Str("query", query).
 		Interface("args", args).
 		Msg("executing query row")
 
-	return db.conn.QueryRowContext(ctx, query, args...)
+	conn, err := db.runner(ctx)
+	if err != nil {
+		return &Row{err: err, cancel: cancel, db: db}
+	}
+
+	return &Row{row: conn.QueryRowContext(ctx, query, args...), cancel: cancel, db: db}
 }
 
 // Exec executes a query that doesn't return rows.
@@ -103,16 +209,100 @@ func (db *DB) Exec(ctx context.Context, query string, args ...interface{}) (sql.
 		Interface("args", args).
 		Msg("executing statement")
 
-	return db.conn.ExecContext(ctx, query, args...)
+	conn, err := db.runner(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := conn.ExecContext(ctx, query, args...)
+	db.observe(err)
+
+	return result, err
+}
+
+// CheckSchema verifies that the exporter tables resolve with the session search_path
+// (see database.schema) and returns that search path.
+func (db *DB) CheckSchema(ctx context.Context) (string, error) {
+	var (
+		searchPath string
+		found      bool
+	)
+
+	err := db.QueryRow(ctx, `SELECT current_setting('search_path'), to_regclass('prtg_sensor') IS NOT NULL`).
+		Scan(&searchPath, &found)
+	if err != nil {
+		return "", fmt.Errorf("failed to read search_path: %w", err)
+	}
+
+	if !found {
+		return searchPath, fmt.Errorf("table prtg_sensor not found with search_path %q, check database.schema", searchPath)
+	}
+
+	return searchPath, nil
+}
+
+// Reasons reported by HealthError. They describe the failure without any detail from the
+// driver (host names, user names, connection settings), so they are safe to expose to clients.
+const (
+	HealthUnreachable = "unreachable" // The server could not be reached or dropped the connection
+	HealthAuthFailed  = "auth_failed" // The server rejected the credentials
+	HealthTimeout     = "timeout"     // The server did not answer in time
+	HealthUnavailable = "unavailable" // Any other failure (missing database, server shutting down...)
+)
+
+// HealthError is returned by Health when the database cannot be used.
+// Reason is one of the Health* constants; Err keeps the driver error for logs.
+type HealthError struct {
+	Reason string
+	Err    error
+}
+
+func (e *HealthError) Error() string {
+	return fmt.Sprintf("database %s: %v", e.Reason, e.Err)
+}
+
+func (e *HealthError) Unwrap() error {
+	return e.Err
+}
+
+// classifyHealthError returns the HealthError reason matching a connection or ping error.
+func classifyHealthError(err error) string {
+	var (
+		pqErr  *pq.Error
+		netErr net.Error
+	)
+
+	switch {
+	case errors.Is(err, context.DeadlineExceeded), errors.As(err, &netErr) && netErr.Timeout():
+		return HealthTimeout
+	case errors.As(err, &pqErr):
+		// Class 28: invalid authorization specification (e.g. 28P01 invalid_password)
+		if strings.HasPrefix(string(pqErr.Code), "28") {
+			return HealthAuthFailed
+		}
+
+		return HealthUnavailable
+	case isConnectionError(err):
+		return HealthUnreachable
+	default:
+		return HealthUnavailable
+	}
 }
 
 // Health checks the database connection health.
+// Failures are returned as a *HealthError.
 func (db *DB) Health(ctx context.Context) error {
 	ctx, cancel := context.WithTimeout(ctx, 2*time.Second)
 	defer cancel()
 
-	if err := db.conn.PingContext(ctx); err != nil {
-		return fmt.Errorf("database ping failed: %w", err)
+	conn, err := db.pool(ctx)
+	if err != nil {
+		return &HealthError{Reason: classifyHealthError(err), Err: err}
+	}
+
+	if err := conn.PingContext(ctx); err != nil {
+		db.observe(err)
+		return &HealthError{Reason: classifyHealthError(err), Err: fmt.Errorf("database ping failed: %w", err)}
 	}
 
 	return nil
@@ -0,0 +1,49 @@
+package database
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// sensorFilterBuilder accumulates the conditions of a WHERE clause and their positional
+// arguments. Placeholders are numbered in the order values are bound, so filters can be
+// combined freely without tracking $n positions by hand. The zero value is ready to use.
+type sensorFilterBuilder struct {
+	conditions []string
+	args       []interface{}
+}
+
+// arg binds a value and returns its placeholder, e.g. "$3". The placeholder can be used
+// several times in a query, and for values bound outside the WHERE clause (HAVING, LIMIT).
+func (b *sensorFilterBuilder) arg(value interface{}) string {
+	b.args = append(b.args, value)
+
+	return "$" + strconv.Itoa(len(b.args))
+}
+
+// where adds a condition. Each %s in the condition is replaced by the placeholder of the value
+// at the same position, e.g. where("s.priority BETWEEN %s AND %s", 2, 4). A condition without
+// values is added as is, so it may contain % (e.g. an inlined LIKE pattern).
+func (b *sensorFilterBuilder) where(condition string, values ...interface{}) {
+	if len(values) > 0 {
+		placeholders := make([]interface{}, len(values))
+		for i, value := range values {
+			placeholders[i] = b.arg(value)
+		}
+
+		condition = fmt.Sprintf(condition, placeholders...)
+	}
+
+	b.conditions = append(b.conditions, condition)
+}
+
+// whereClause renders the conditions, e.g. "WHERE d.name ILIKE $1 AND s.status = $2", or ""
+// when there are none.
+func (b *sensorFilterBuilder) whereClause() string {
+	if len(b.conditions) == 0 {
+		return ""
+	}
+
+	return "WHERE " + strings.Join(b.conditions, " AND ")
+}
@@ -0,0 +1,29 @@
+package database
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSensorFilterBuilder_PlaceholderNumbering(t *testing.T) {
+	var b sensorFilterBuilder
+
+	assert.Empty(t, b.whereClause(), "no conditions")
+
+	b.where("d.name ILIKE %s", "%web%")
+	b.where("s.status NOT IN (7,8,9)") // Inlined, binds nothing
+	b.where("s.priority BETWEEN %s AND %s", 2, 4)
+	b.where("s.sensor_type ILIKE '%ping%'") // No values: % is kept as is
+
+	// A placeholder bound once can be used several times
+	window := b.arg(6)
+	b.where("(s.last_up_utc >= " + window + " OR s.last_down_utc >= " + window + ")")
+
+	assert.Equal(t, "WHERE d.name ILIKE $1 AND s.status NOT IN (7,8,9) AND s.priority BETWEEN $2 AND $3"+
+		" AND s.sensor_type ILIKE '%ping%' AND (s.last_up_utc >= $4 OR s.last_down_utc >= $4)", b.whereClause())
+
+	// Arguments bound after the WHERE clause continue the numbering
+	assert.Equal(t, "$5", b.arg(50))
+	assert.Equal(t, []interface{}{"%web%", 2, 4, 6, 50}, b.args)
+}
@@ -3,22 +3,77 @@ package database
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"fmt"
+	"slices"
+	"sort"
+	"strconv"
 	"strings"
 	"time"
 
+	"github.com/lib/pq"
+
+	"github.com/matthieu/mcp-server-prtg/internal/prtg"
 	"github.com/matthieu/mcp-server-prtg/internal/types"
 )
 
 // GetSensors retrieves sensors matching the given filters.
 // Results are ordered by sensor name. The limit parameter controls the maximum number of results.
 func (db *DB) GetSensors(ctx context.Context, deviceName, sensorName string, status *int, tags string, limit int) ([]types.Sensor, error) {
-	return db.GetSensorsExtended(ctx, deviceName, sensorName, "", "", status, tags, "name", limit)
+	filter := types.SensorFilter{
+		DeviceName: deviceName,
+		SensorName: sensorName,
+		Status:     status,
+		Tags:       tags,
+	}
+
+	return db.GetSensorsExtended(ctx, filter, "name", limit)
 }
 
+// statusSeverityOrder ranks sensor statuses for ORDER BY, most critical first: Down(5), DownPartial(14),
+// DownAcknowledged(13), Warning(4), Unusual(10), NoProbe(6), Unknown(1), Collecting(2), then the others.
+const statusSeverityOrder = `CASE s.status
+			WHEN 5 THEN 1   -- Down (most critical)
+			WHEN 14 THEN 2  -- Down Partial
+			WHEN 13 THEN 3  -- Down Acknowledged
+			WHEN 4 THEN 4   -- Warning
+			WHEN 10 THEN 5  -- Unusual
+			WHEN 6 THEN 6   -- No Probe
+			WHEN 1 THEN 7   -- Unknown
+			WHEN 2 THEN 8   -- Collecting
+			ELSE 9          -- Up and paused statuses (3,7,8,9,11,12)
+		END`
+
+// alertSeverityScoreSQL computes the 0-100 alert severity score of a sensor: a status weight plus
+// 2 per priority level (1-5), 0 for statuses that are not alerts. It must match
+// alertSeverityScore of the handlers package.
+const alertSeverityScoreSQL = `COALESCE(CASE s.status
+			WHEN 5 THEN 90   -- Down
+			WHEN 14 THEN 78  -- Down Partial
+			WHEN 6 THEN 66   -- No Probe
+			WHEN 4 THEN 54   -- Warning
+			WHEN 10 THEN 42  -- Unusual
+			WHEN 13 THEN 30  -- Down Acknowledged
+			WHEN 1 THEN 18   -- Unknown
+		END + LEAST(GREATEST(s.priority, 1), 5) * 2, 0)`
+
+// ipv4Pattern matches the IPv4 addresses PostgreSQL can cast to inet, and nothing else.
+const ipv4Pattern = `^((25[0-5]|2[0-4][0-9]|1?[0-9]?[0-9])\.){3}(25[0-5]|2[0-4][0-9]|1?[0-9]?[0-9])$`
+
+// hostIPOrder sorts devices whose host is an IPv4 address in numeric order, e.g. 10.0.0.9 before
+// 10.0.0.10, followed by the other hosts (DNS names, IPv6) in lexical order. Casting a host that is
+// not an address to inet would fail the query, so only hosts matching ipv4Pattern are cast.
+const hostIPOrder = `CASE WHEN d.host ~ '` + ipv4Pattern + `' THEN d.host::inet END NULLS LAST, d.host`
+
 // GetSensorsExtended retrieves sensors matching the given filters with additional options.
-// Supports filtering by sensor_type, group_name, and custom ordering.
-func (db *DB) GetSensorsExtended(ctx context.Context, deviceName, sensorName, sensorType, groupName string, status *int, tags, orderBy string, limit int) ([]types.Sensor, error) {
+// Supports filtering by sensor_type, group_name, priority range, and custom ordering.
+func (db *DB) GetSensorsExtended(ctx context.Context, filter types.SensorFilter, orderBy string, limit int) ([]types.Sensor, error) {
+	if err := filter.Validate(); err != nil {
+		return nil, err
+	}
+
+	conditions := sensorFilterConditions(filter)
+
 	// Query with group join for group_name filter
 	query := `
 		SELECT
@@ -28,6 +83,7 @@ func (db *DB) GetSensorsExtended(ctx context.Context, deviceName, sensorName, se
 			s.sensor_type,
 			s.prtg_device_id,
 			d.name AS device_name,
+			d.host AS device_host,
 			s.scanning_interval_seconds,
 			s.status,
 			s.last_check_utc,
@@ -39,53 +95,7 @@ func (db *DB) GetSensorsExtended(ctx context.Context, deviceName, sensorName, se
 			s.downtime_since_seconds,
 			sp.path AS full_path,
 			'' AS tags
-		FROM prtg_sensor s
-		INNER JOIN prtg_device d ON s.prtg_device_id = d.id
-			AND s.prtg_server_address_id = d.prtg_server_address_id
-		INNER JOIN prtg_sensor_path sp ON s.id = sp.sensor_id
-			AND s.prtg_server_address_id = sp.prtg_server_address_id
-		INNER JOIN prtg_group g ON d.prtg_group_id = g.id
-			AND d.prtg_server_address_id = g.prtg_server_address_id
-		WHERE 1=1
-	`
-
-	args := []interface{}{}
-	argPos := 1
-
-	// Add filters
-	if deviceName != "" {
-		query += fmt.Sprintf(" AND d.name ILIKE $%d", argPos)
-		args = append(args, "%"+deviceName+"%")
-		argPos++
-	}
-
-	if sensorName != "" {
-		query += fmt.Sprintf(" AND s.name ILIKE $%d", argPos)
-		args = append(args, "%"+sensorName+"%")
-		argPos++
-	}
-
-	if sensorType != "" {
-		query += fmt.Sprintf(" AND s.sensor_type ILIKE $%d", argPos)
-		args = append(args, "%"+sensorType+"%")
-		argPos++
-	}
-
-	if groupName != "" {
-		query += fmt.Sprintf(" AND g.name ILIKE $%d", argPos)
-		args = append(args, "%"+groupName+"%")
-		argPos++
-	}
-
-	if status != nil {
-		query += fmt.Sprintf(" AND s.status = $%d", argPos)
-		args = append(args, *status)
-		argPos++
-	}
-
-	// Tags filter temporarily disabled for performance
-	// TODO: Re-enable with proper indexing
-	_ = tags
+	` + sensorFromClause + conditions.whereClause()
 
 	// Add ordering
 	orderClause := " ORDER BY s.name" // Default
@@ -96,6 +106,12 @@ func (db *DB) GetSensorsExtended(ctx context.Context, deviceName, sensorName, se
 		orderClause = " ORDER BY s.priority DESC, s.name"
 	case "device":
 		orderClause = " ORDER BY d.name, s.name"
+	case "device_status":
+		orderClause = " ORDER BY d.name, " + statusSeverityOrder + ", s.name"
+	case "host":
+		orderClause = " ORDER BY d.host, s.name" // Lexical: 10.0.0.10 sorts before 10.0.0.9
+	case "host_ip":
+		orderClause = " ORDER BY " + hostIPOrder + ", s.name"
 	case "type":
 		orderClause = " ORDER BY s.sensor_type, s.name"
 	case "last_check":
@@ -104,17 +120,16 @@ func (db *DB) GetSensorsExtended(ctx context.Context, deviceName, sensorName, se
 	query += orderClause
 
 	if limit > 0 {
-		query += fmt.Sprintf(" LIMIT $%d", argPos)
-		args = append(args, limit)
+		query += " LIMIT " + conditions.arg(limit)
 	}
 
 	db.logger.Debug().
 		Str("query", query).
-		Interface("args", args).
+		Interface("args", conditions.args).
 		Msg("executing GetSensors query")
 
 	startTime := time.Now()
-	rows, err := db.Query(ctx, query, args...)
+	rows, err := db.Query(ctx, query, conditions.args...)
 	queryDuration := time.Since(startTime)
 
 	if err != nil {
@@ -150,112 +165,229 @@ func (db *DB) GetSensorsExtended(ctx context.Context, deviceName, sensorName, se
 	return sensors, nil
 }
 
-// GetSensorByID retrieves a single sensor by ID.
-// Returns sql.ErrNoRows if the sensor is not found.
-func (db *DB) GetSensorByID(ctx context.Context, sensorID int) (*types.Sensor, error) {
-	query := `
-		SELECT
-			s.id,
-			s.prtg_server_address_id,
-			s.name,
-			s.sensor_type,
-			s.prtg_device_id,
-			d.name AS device_name,
-			s.scanning_interval_seconds,
-			s.status,
-			s.last_check_utc,
-			s.last_up_utc,
-			s.last_down_utc,
-			s.priority,
-			s.message,
-			s.uptime_since_seconds,
-			s.downtime_since_seconds,
-			sp.path AS full_path,
-			COALESCE(
-				(SELECT string_agg(t.name, ',')
-				 FROM prtg_sensor_tag st
-				 JOIN prtg_tag t ON st.prtg_tag_id = t.id
-				 WHERE st.prtg_sensor_id = s.id
-				 AND st.prtg_server_address_id = s.prtg_server_address_id),
-				''
-			) AS tags
+// CountSensors returns the number of sensors matching the given filters.
+// Uses the same joins and WHERE clause as GetSensorsExtended so counts stay in sync with listings.
+func (db *DB) CountSensors(ctx context.Context, filter types.SensorFilter) (int, error) {
+	if err := filter.Validate(); err != nil {
+		return 0, err
+	}
+
+	whereClause, args := buildSensorWhereClause(filter)
+	query := "SELECT COUNT(*)" + sensorFromClause + whereClause
+
+	db.logger.Debug().
+		Str("query", query).
+		Interface("args", args).
+		Msg("executing CountSensors query")
+
+	var count int
+	if err := db.QueryRow(ctx, query, args...).Scan(&count); err != nil {
+		return 0, fmt.Errorf("count query failed: %w", err)
+	}
+
+	return count, nil
+}
+
+// groupCountDimensions maps the dimensions accepted by GroupCounts to their SQL expression.
+// Only these expressions are ever interpolated into the query.
+//
+//nolint:gochecknoglobals // Read-only whitelist.
+var groupCountDimensions = map[string]string{
+	"status":      "s.status::text",
+	"sensor_type": "COALESCE(s.sensor_type, '')",
+	"device":      "d.name",
+	"group":       "g.name",
+}
+
+// GroupCounts returns the number of sensors matching the filter for each value of a dimension
+// (status, sensor_type, device or group), largest groups first.
+func (db *DB) GroupCounts(ctx context.Context, dimension string, filter types.SensorFilter, limit int) ([]types.DimensionCount, error) {
+	expr, ok := groupCountDimensions[dimension]
+	if !ok {
+		return nil, fmt.Errorf("invalid dimension %q (allowed: status, sensor_type, device, group)", dimension)
+	}
+
+	if err := filter.Validate(); err != nil {
+		return nil, err
+	}
+
+	conditions := sensorFilterConditions(filter)
+	query := "SELECT " + expr + " AS value, COUNT(*) AS count" + sensorFromClause + conditions.whereClause() +
+		" GROUP BY 1 ORDER BY count DESC, value"
+
+	if limit > 0 {
+		query += " LIMIT " + conditions.arg(limit)
+	}
+
+	db.logger.Debug().
+		Str("query", query).
+		Interface("args", conditions.args).
+		Msg("executing GroupCounts query")
+
+	rows, err := db.Query(ctx, query, conditions.args...)
+	if err != nil {
+		return nil, fmt.Errorf("group counts query failed: %w", err)
+	}
+	defer rows.Close()
+
+	counts := []types.DimensionCount{}
+
+	for rows.Next() {
+		var count types.DimensionCount
+		if err := rows.Scan(&count.Value, &count.Count); err != nil {
+			return nil, fmt.Errorf("group counts scan failed: %w", err)
+		}
+
+		counts = append(counts, count)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("group counts rows failed: %w", err)
+	}
+
+	return counts, nil
+}
+
+// sensorFromClause is the FROM clause shared by sensor listing and count queries.
+// The group join is required for the group_name filter.
+const sensorFromClause = `
 		FROM prtg_sensor s
 		INNER JOIN prtg_device d ON s.prtg_device_id = d.id
 			AND s.prtg_server_address_id = d.prtg_server_address_id
 		INNER JOIN prtg_sensor_path sp ON s.id = sp.sensor_id
 			AND s.prtg_server_address_id = sp.prtg_server_address_id
-		WHERE s.id = $1
-	`
+		INNER JOIN prtg_group g ON d.prtg_group_id = g.id
+			AND d.prtg_server_address_id = g.prtg_server_address_id
+`
 
-	var sensor types.Sensor
-
-	var lastCheckUTC, lastDownUTC sql.NullTime
-
-	var uptimeSecs, downtimeSecs sql.NullFloat64
-
-	var message, tags sql.NullString
-
-	err := db.QueryRow(ctx, query, sensorID).Scan(
-		&sensor.ID,
-		&sensor.ServerID,
-		&sensor.Name,
-		&sensor.SensorType,
-		&sensor.DeviceID,
-		&sensor.DeviceName,
-		&sensor.ScanningIntervalSecs,
-		&sensor.Status,
-		&lastCheckUTC,
-		&sensor.LastUpUTC,
-		&lastDownUTC,
-		&sensor.Priority,
-		&message,
-		&uptimeSecs,
-		&downtimeSecs,
-		&sensor.FullPath,
-		&tags,
-	)
+// buildSensorWhereClause builds the WHERE clause and positional arguments for a sensor filter.
+// Placeholders start at $1.
+func buildSensorWhereClause(filter types.SensorFilter) (string, []interface{}) {
+	b := sensorFilterConditions(filter)
 
-	if err != nil {
-		if err == sql.ErrNoRows {
-			return nil, fmt.Errorf("sensor not found")
+	return b.whereClause(), b.args
+}
+
+// sensorFilterConditions returns the conditions of a sensor filter, on the tables of
+// sensorFromClause. Callers bind further arguments (e.g. the LIMIT) with the returned builder.
+func sensorFilterConditions(filter types.SensorFilter) *sensorFilterBuilder {
+	var b sensorFilterBuilder
+
+	if filter.DeviceName != "" {
+		b.where("d.name ILIKE %s", "%"+filter.DeviceName+"%")
+	}
+
+	if filter.SensorName != "" {
+		b.where("s.name ILIKE %s", "%"+filter.SensorName+"%")
+	}
+
+	if filter.SensorType != "" {
+		b.where("s.sensor_type ILIKE %s", "%"+filter.SensorType+"%")
+	}
+
+	if filter.GroupName != "" {
+		b.where("g.name ILIKE %s", "%"+filter.GroupName+"%")
+	}
+
+	if filter.Status != nil {
+		b.where("s.status = %s", *filter.Status)
+	}
+
+	if len(filter.Statuses) > 0 {
+		b.where("s.status = ANY(%s)", pq.Array(filter.Statuses))
+	}
+
+	if filter.MinPriority != nil || filter.MaxPriority != nil {
+		minPriority, maxPriority := types.MinSensorPriority, types.MaxSensorPriority
+		if filter.MinPriority != nil {
+			minPriority = *filter.MinPriority
 		}
 
-		return nil, fmt.Errorf("query failed: %w", err)
+		if filter.MaxPriority != nil {
+			maxPriority = *filter.MaxPriority
+		}
+
+		b.where("s.priority BETWEEN %s AND %s", minPriority, maxPriority)
 	}
 
-	// Handle nullable fields
-	if lastCheckUTC.Valid {
-		sensor.LastCheckUTC = &lastCheckUTC.Time
+	switch {
+	case filter.MinInterval != nil && filter.MaxInterval != nil:
+		b.where("s.scanning_interval_seconds BETWEEN %s AND %s", *filter.MinInterval, *filter.MaxInterval)
+	case filter.MinInterval != nil:
+		b.where("s.scanning_interval_seconds >= %s", *filter.MinInterval)
+	case filter.MaxInterval != nil:
+		b.where("s.scanning_interval_seconds <= %s", *filter.MaxInterval)
 	}
 
-	if lastDownUTC.Valid {
-		sensor.LastDownUTC = &lastDownUTC.Time
+	// A sensor that was never checked is as suspect as one checked long ago
+	if filter.StaleMinutes > 0 {
+		b.where("(s.last_check_utc IS NULL OR s.last_check_utc < NOW() - (%s || ' minutes')::interval)", filter.StaleMinutes)
 	}
 
-	if uptimeSecs.Valid {
-		sensor.UptimeSinceSecs = &uptimeSecs.Float64
+	if filter.PathPrefix != "" {
+		b.where("sp.path LIKE %s", escapeLike(strings.TrimSuffix(filter.PathPrefix, "/"))+"/%")
 	}
 
-	if downtimeSecs.Valid {
-		sensor.DowntimeSinceSecs = &downtimeSecs.Float64
+	// Status codes are package constants, so they are inlined rather than bound
+	switch {
+	case filter.ActiveOnly:
+		b.where("s.status NOT IN (" + joinInts(types.InactiveStatuses) + ")")
+	case filter.ExcludePaused:
+		b.where("s.status NOT IN (" + joinInts(types.PausedStatuses) + ")")
 	}
 
-	if message.Valid {
-		sensor.Message = message.String
+	if filter.ProblemOnly {
+		b.where("s.status IN (" + joinInts(types.ProblemStatuses) + ")")
 	}
 
-	if tags.Valid {
-		sensor.Tags = tags.String
+	// Tags filter temporarily disabled for performance
+	// TODO: Re-enable with proper indexing
+	_ = filter.Tags
+
+	return &b
+}
+
+// likeEscaper escapes the LIKE wildcards, with the default backslash escape character.
+var likeEscaper = strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`)
+
+// escapeLike escapes s so that it matches literally in a LIKE pattern.
+func escapeLike(s string) string {
+	return likeEscaper.Replace(s)
+}
+
+// joinInts renders integers as a comma-separated SQL list.
+func joinInts(values []int) string {
+	parts := make([]string, len(values))
+	for i, v := range values {
+		parts[i] = strconv.Itoa(v)
 	}
 
-	sensor.StatusText = types.GetStatusText(sensor.Status)
+	return strings.Join(parts, ",")
+}
+
+// GetSensorByID retrieves a single sensor by ID, on the given PRTG server if serverID is set.
+// Sensor IDs are only unique per server: without serverID, an ID found on several servers
+// returns a *types.AmbiguousSensorError listing them instead of one of the sensors.
+func (db *DB) GetSensorByID(ctx context.Context, sensorID int, serverID *int) (*types.Sensor, error) {
+	sensor, err := db.getSensorByID(ctx, sensorID, serverID)
+	if err != nil {
+		var ambiguous *types.AmbiguousSensorError
+
+		switch {
+		case err == sql.ErrNoRows:
+			return nil, fmt.Errorf("sensor not found")
+		case errors.As(err, &ambiguous):
+			return nil, err
+		}
+
+		return nil, fmt.Errorf("query failed: %w", err)
+	}
 
-	return &sensor, nil
+	return sensor, nil
 }
 
-// GetAlerts retrieves sensors in alert state (non-UP status).
-// Results are sorted by priority and severity (Down first, then Warning, etc.), limited to 100 results.
-func (db *DB) GetAlerts(ctx context.Context, hours int, statusFilter *int, deviceName string) ([]types.Sensor, error) {
+// getSensorByID implements GetSensorByID. Returns sql.ErrNoRows if the sensor is not found.
+func (db *DB) getSensorByID(ctx context.Context, sensorID int, serverID *int) (*types.Sensor, error) {
 	query := `
 		SELECT
 			s.id,
@@ -264,6 +396,7 @@ func (db *DB) GetAlerts(ctx context.Context, hours int, statusFilter *int, devic
 			s.sensor_type,
 			s.prtg_device_id,
 			d.name AS device_name,
+			d.host AS device_host,
 			s.scanning_interval_seconds,
 			s.status,
 			s.last_check_utc,
@@ -287,119 +420,54 @@ func (db *DB) GetAlerts(ctx context.Context, hours int, statusFilter *int, devic
 			AND s.prtg_server_address_id = d.prtg_server_address_id
 		INNER JOIN prtg_sensor_path sp ON s.id = sp.sensor_id
 			AND s.prtg_server_address_id = sp.prtg_server_address_id
-		WHERE s.status != $1
+		WHERE s.id = $1
 	`
 
-	args := []interface{}{types.StatusUp}
-	argPos := 2
-
-	if hours > 0 {
-		query += fmt.Sprintf(" AND s.last_check_utc >= NOW() - ($%d || ' hours')::interval", argPos)
-
-		args = append(args, hours)
-		argPos++
-	}
-
-	if statusFilter != nil {
-		query += fmt.Sprintf(" AND s.status = $%d", argPos)
-
-		args = append(args, *statusFilter)
-		argPos++
-	}
-
-	if deviceName != "" {
-		query += fmt.Sprintf(" AND d.name ILIKE $%d", argPos)
-
-		args = append(args, "%"+deviceName+"%")
+	args := []interface{}{sensorID}
+	if serverID != nil {
+		query += " AND s.prtg_server_address_id = $2"
+		args = append(args, *serverID)
 	}
 
-	// Order by severity: Down statuses first, then Warning, then others
-	// Severity order: Down(5), DownPartial(14), DownAcknowledged(13), Warning(4), Unusual(10),
-	//                 NoProbe(6), Unknown(1), Collecting(2), then Paused statuses
-	query += ` ORDER BY
-		s.priority DESC,
-		CASE s.status
-			WHEN 5 THEN 1   -- Down (most critical)
-			WHEN 14 THEN 2  -- Down Partial
-			WHEN 13 THEN 3  -- Down Acknowledged
-			WHEN 4 THEN 4   -- Warning
-			WHEN 10 THEN 5  -- Unusual
-			WHEN 6 THEN 6   -- No Probe
-			WHEN 1 THEN 7   -- Unknown
-			WHEN 2 THEN 8   -- Collecting
-			ELSE 9          -- Paused statuses (7,8,9,11,12)
-		END,
-		s.name
-		LIMIT 100`
+	query += " ORDER BY s.prtg_server_address_id"
 
 	rows, err := db.Query(ctx, query, args...)
 	if err != nil {
-		return nil, fmt.Errorf("query failed: %w", err)
+		return nil, err
 	}
 	defer rows.Close()
 
-	return scanSensors(rows)
-}
-
-// GetDeviceOverview retrieves a device with all its sensors and aggregated statistics.
-// Returns sql.ErrNoRows if no device matches the given name.
-func (db *DB) GetDeviceOverview(ctx context.Context, deviceName string) (*types.DeviceOverview, error) {
-	// Get device info
-	deviceQuery := `
-		SELECT
-			d.id,
-			d.prtg_server_address_id,
-			d.name,
-			d.host,
-			d.prtg_group_id,
-			g.name AS group_name,
-			dp.path AS full_path,
-			d.tree_depth,
-			COALESCE(
-				(SELECT COUNT(*) FROM prtg_sensor s
-				 WHERE s.prtg_device_id = d.id
-				 AND s.prtg_server_address_id = d.prtg_server_address_id),
-				0
-			) AS sensor_count
-		FROM prtg_device d
-		INNER JOIN prtg_group g ON d.prtg_group_id = g.id
-			AND d.prtg_server_address_id = g.prtg_server_address_id
-		INNER JOIN prtg_device_path dp ON d.id = dp.device_id
-			AND d.prtg_server_address_id = dp.prtg_server_address_id
-		WHERE d.name ILIKE $1
-		LIMIT 1
-	`
+	sensors, err := scanSensors(rows)
+	if err != nil {
+		return nil, err
+	}
 
-	var device types.Device
-	err := db.QueryRow(ctx, deviceQuery, "%"+deviceName+"%").Scan(
-		&device.ID,
-		&device.ServerID,
-		&device.Name,
-		&device.Host,
-		&device.GroupID,
-		&device.GroupName,
-		&device.FullPath,
-		&device.TreeDepth,
-		&device.SensorCount,
-	)
+	switch len(sensors) {
+	case 0:
+		return nil, sql.ErrNoRows
+	case 1:
+		return &sensors[0], nil
+	}
 
-	if err != nil {
-		if err == sql.ErrNoRows {
-			return nil, fmt.Errorf("device not found")
-		}
+	return nil, &types.AmbiguousSensorError{SensorID: sensorID, Sensors: sensors}
+}
 
-		return nil, fmt.Errorf("query failed: %w", err)
+// GetSensorsByIDs retrieves the sensors of PRTG server serverID matching the given IDs.
+// Non-UP sensors are returned first, then by priority and name. Unknown IDs are ignored.
+func (db *DB) GetSensorsByIDs(ctx context.Context, serverID int, sensorIDs []int) ([]types.Sensor, error) {
+	if len(sensorIDs) == 0 {
+		return []types.Sensor{}, nil
 	}
 
-	// Get all sensors for this device
-	sensorsQuery := `
+	query := `
 		SELECT
 			s.id,
 			s.prtg_server_address_id,
 			s.name,
 			s.sensor_type,
 			s.prtg_device_id,
-			$2 AS device_name,
+			d.name AS device_name,
+			d.host AS device_host,
 			s.scanning_interval_seconds,
 			s.status,
 			s.last_check_utc,
@@ -410,62 +478,36 @@ func (db *DB) GetDeviceOverview(ctx context.Context, deviceName string) (*types.
 			s.uptime_since_seconds,
 			s.downtime_since_seconds,
 			sp.path AS full_path,
-			COALESCE(
-				(SELECT string_agg(t.name, ',')
-				 FROM prtg_sensor_tag st
-				 JOIN prtg_tag t ON st.prtg_tag_id = t.id
-				 WHERE st.prtg_sensor_id = s.id
-				 AND st.prtg_server_address_id = s.prtg_server_address_id),
-				''
-			) AS tags
+			'' AS tags
 		FROM prtg_sensor s
+		INNER JOIN prtg_device d ON s.prtg_device_id = d.id
+			AND s.prtg_server_address_id = d.prtg_server_address_id
 		INNER JOIN prtg_sensor_path sp ON s.id = sp.sensor_id
 			AND s.prtg_server_address_id = sp.prtg_server_address_id
-		WHERE s.prtg_device_id = $1
-		AND s.prtg_server_address_id = $3
-		ORDER BY s.status, s.name
+		WHERE s.prtg_server_address_id = $1 AND s.id = ANY($2)
+		ORDER BY
+			CASE WHEN s.status = $3 THEN 1 ELSE 0 END,
+			s.priority DESC,
+			s.name
 	`
 
-	rows, err := db.Query(ctx, sensorsQuery, device.ID, device.Name, device.ServerID)
-	if err != nil {
-		return nil, fmt.Errorf("failed to get sensors: %w", err)
+	ids := make([]int64, len(sensorIDs))
+	for i, id := range sensorIDs {
+		ids[i] = int64(id)
 	}
-	defer rows.Close()
 
-	sensors, err := scanSensors(rows)
+	rows, err := db.Query(ctx, query, serverID, pq.Array(ids), types.StatusUp)
 	if err != nil {
-		return nil, err
+		return nil, fmt.Errorf("query failed: %w", err)
 	}
+	defer rows.Close()
 
-	// Calculate statistics
-	upCount := 0
-	downCount := 0
-	warnCount := 0
-
-	for i := range sensors {
-		switch sensors[i].Status {
-		case types.StatusUp:
-			upCount++
-		case types.StatusDown:
-			downCount++
-		case types.StatusWarning:
-			warnCount++
-		}
-	}
-
-	return &types.DeviceOverview{
-		Device:       device,
-		Sensors:      sensors,
-		TotalSensors: len(sensors),
-		UpSensors:    upCount,
-		DownSensors:  downCount,
-		WarnSensors:  warnCount,
-	}, nil
+	return scanSensors(rows)
 }
 
-// GetTopSensors retrieves top sensors ranked by the given metric.
-// Valid metrics: "uptime", "downtime", "alerts". Results are limited by the limit parameter.
-func (db *DB) GetTopSensors(ctx context.Context, metric, sensorType string, limit, _ int) ([]types.Sensor, error) {
+// GetSensorsByDeviceID retrieves the sensors of a device, ordered by name (limit 0 = all).
+// Returns an error if no device has this ID; a device without sensors returns an empty slice.
+func (db *DB) GetSensorsByDeviceID(ctx context.Context, deviceID, limit int) ([]types.Sensor, error) {
 	query := `
 		SELECT
 			s.id,
@@ -474,6 +516,7 @@ func (db *DB) GetTopSensors(ctx context.Context, metric, sensorType string, limi
 			s.sensor_type,
 			s.prtg_device_id,
 			d.name AS device_name,
+			d.host AS device_host,
 			s.scanning_interval_seconds,
 			s.status,
 			s.last_check_utc,
@@ -484,53 +527,98 @@ func (db *DB) GetTopSensors(ctx context.Context, metric, sensorType string, limi
 			s.uptime_since_seconds,
 			s.downtime_since_seconds,
 			sp.path AS full_path,
-			COALESCE(
-				(SELECT string_agg(t.name, ',')
-				 FROM prtg_sensor_tag st
-				 JOIN prtg_tag t ON st.prtg_tag_id = t.id
-				 WHERE st.prtg_sensor_id = s.id
-				 AND st.prtg_server_address_id = s.prtg_server_address_id),
-				''
-			) AS tags
+			'' AS tags
 		FROM prtg_sensor s
 		INNER JOIN prtg_device d ON s.prtg_device_id = d.id
 			AND s.prtg_server_address_id = d.prtg_server_address_id
 		INNER JOIN prtg_sensor_path sp ON s.id = sp.sensor_id
 			AND s.prtg_server_address_id = sp.prtg_server_address_id
-		WHERE 1=1
+		WHERE s.prtg_device_id = $1
+		ORDER BY s.name
 	`
 
-	args := []interface{}{}
-	argPos := 1
+	args := []interface{}{deviceID}
+	if limit > 0 {
+		query += " LIMIT $2"
+		args = append(args, limit)
+	}
 
-	if sensorType != "" {
-		query += fmt.Sprintf(" AND s.sensor_type ILIKE $%d", argPos)
+	rows, err := db.Query(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("query failed: %w", err)
+	}
+	defer rows.Close()
 
-		args = append(args, "%"+sensorType+"%")
-		argPos++
+	sensors, err := scanSensors(rows)
+	if err != nil || len(sensors) > 0 {
+		return sensors, err
 	}
 
-	// Add ordering based on metric
-	switch metric {
-	case "downtime":
-		query += " ORDER BY s.downtime_since_seconds DESC NULLS LAST"
-	case "alerts":
-		// Order by non-UP status, then by priority
-		query += fmt.Sprintf(" AND s.status != $%d ORDER BY s.priority DESC, s.status", argPos)
+	// No sensors: tell an empty device from an unknown one
+	if _, err := db.getDeviceByID(ctx, deviceID); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("device not found")
+		}
 
-		args = append(args, types.StatusUp)
-		argPos++
-	default: // "uptime" or default
-		query += " ORDER BY s.uptime_since_seconds DESC NULLS LAST"
+		return nil, fmt.Errorf("device lookup failed: %w", err)
 	}
 
-	if limit > 0 {
-		query += fmt.Sprintf(" LIMIT $%d", argPos)
+	return sensors, nil
+}
 
-		args = append(args, limit)
+// GetAlerts retrieves one page of sensors in alert state (non-UP status).
+// Results are sorted by priority and severity (Down first, then Warning, etc.), with the sensor ID
+// as final tiebreaker so that pages do not overlap. limit <= 0 defaults to types.AlertsLimit.
+func (db *DB) GetAlerts(ctx context.Context, filter types.AlertFilter, limit, offset int) ([]types.Sensor, error) {
+	if limit <= 0 {
+		limit = types.AlertsLimit
 	}
 
-	rows, err := db.Query(ctx, query, args...)
+	conditions := alertFilterConditions(filter)
+
+	query := `
+		SELECT
+			s.id,
+			s.prtg_server_address_id,
+			s.name,
+			s.sensor_type,
+			s.prtg_device_id,
+			d.name AS device_name,
+			d.host AS device_host,
+			s.scanning_interval_seconds,
+			s.status,
+			s.last_check_utc,
+			s.last_up_utc,
+			s.last_down_utc,
+			s.priority,
+			s.message,
+			s.uptime_since_seconds,
+			s.downtime_since_seconds,
+			sp.path AS full_path,
+			COALESCE(
+				(SELECT string_agg(t.name, ',')
+				 FROM prtg_sensor_tag st
+				 JOIN prtg_tag t ON st.prtg_tag_id = t.id
+				 WHERE st.prtg_sensor_id = s.id
+				 AND st.prtg_server_address_id = s.prtg_server_address_id),
+				''
+			) AS tags` + alertFromClause + conditions.whereClause()
+
+	// Order by severity: Down statuses first, then Warning, then others. Ordering by score in
+	// SQL keeps the pages of a score-ordered listing in sequence.
+	orderBy := ""
+	if filter.OrderBy == types.AlertOrderSeverity {
+		orderBy = alertSeverityScoreSQL + " DESC,"
+	}
+
+	query += fmt.Sprintf(` ORDER BY %s
+		s.priority DESC,
+		%s,
+		s.name,
+		s.id
+		LIMIT %s OFFSET %s`, orderBy, statusSeverityOrder, conditions.arg(limit), conditions.arg(max(offset, 0)))
+
+	rows, err := db.Query(ctx, query, conditions.args...)
 	if err != nil {
 		return nil, fmt.Errorf("query failed: %w", err)
 	}
@@ -539,227 +627,1049 @@ func (db *DB) GetTopSensors(ctx context.Context, metric, sensorType string, limi
 	return scanSensors(rows)
 }
 
-// ExecuteCustomQuery executes a custom SQL SELECT query with security validation.
-// Only SELECT queries are allowed - INSERT/UPDATE/DELETE/DROP are rejected.
-// This function should be disabled in production (set allow_custom_queries: false in config).
-func (db *DB) ExecuteCustomQuery(ctx context.Context, query string, limit int) ([]map[string]interface{}, error) {
-	// Security: Validate query is SELECT only
-	queryUpper := strings.ToUpper(strings.TrimSpace(query))
-	if !strings.HasPrefix(queryUpper, "SELECT") {
-		return nil, fmt.Errorf("only SELECT queries are allowed")
-	}
+// CountAlerts returns the number of sensors in alert state matching the filter, across all pages.
+func (db *DB) CountAlerts(ctx context.Context, filter types.AlertFilter) (int, error) {
+	whereClause, args := buildAlertWhereClause(filter)
+	query := "SELECT COUNT(*)" + alertFromClause + whereClause
 
-	// Check for dangerous keywords (including comments to prevent bypass)
-	dangerous := []string{"DROP", "DELETE", "UPDATE", "INSERT", "ALTER", "CREATE", "TRUNCATE", "EXEC", "EXECUTE", "/*", "--", ";"}
-	for _, keyword := range dangerous {
-		if strings.Contains(queryUpper, keyword) || strings.Contains(query, keyword) {
-			return nil, fmt.Errorf("query contains forbidden keyword: %s", keyword)
-		}
+	var count int
+	if err := db.QueryRow(ctx, query, args...).Scan(&count); err != nil {
+		return 0, fmt.Errorf("count query failed: %w", err)
 	}
 
-	// Enforce maximum limit
-	maxLimit := 1000
+	return count, nil
+}
 
-	if limit <= 0 {
-		limit = 100
-	}
+// alertFromClause is the FROM clause shared by the alerts listing and count queries.
+const alertFromClause = `
+		FROM prtg_sensor s
+		INNER JOIN prtg_device d ON s.prtg_device_id = d.id
+			AND s.prtg_server_address_id = d.prtg_server_address_id
+		INNER JOIN prtg_sensor_path sp ON s.id = sp.sensor_id
+			AND s.prtg_server_address_id = sp.prtg_server_address_id
+`
+
+// buildAlertWhereClause builds the WHERE clause and positional arguments of an alerts query.
+// Placeholders start at $1 with the excluded Up status.
+func buildAlertWhereClause(filter types.AlertFilter) (string, []interface{}) {
+	b := alertFilterConditions(filter)
+
+	return b.whereClause(), b.args
+}
+
+// alertFilterConditions returns the conditions of an alerts query, on the tables of alertFromClause.
+func alertFilterConditions(filter types.AlertFilter) *sensorFilterBuilder {
+	var b sensorFilterBuilder
+
+	b.where("s.status != %s", types.StatusUp)
 
-	if limit > maxLimit {
-		limit = maxLimit
+	if filter.Hours > 0 {
+		b.where("s.last_check_utc >= NOW() - (%s || ' hours')::interval", filter.Hours)
 	}
 
-	// Add limit if not present using parameterized query
-	if !strings.Contains(queryUpper, "LIMIT") {
-		query += " LIMIT $1"
+	if filter.Status != nil {
+		b.where("s.status = %s", *filter.Status)
+	}
 
-		rows, err := db.conn.QueryContext(ctx, query, limit)
-		if err != nil {
-			return nil, fmt.Errorf("query failed: %w", err)
-		}
-		defer rows.Close()
+	if filter.MinPriority != nil {
+		b.where("s.priority >= %s", *filter.MinPriority)
+	}
 
-		return scanGenericResults(rows)
+	if filter.DeviceName != "" {
+		b.where("d.name ILIKE %s", "%"+filter.DeviceName+"%")
 	}
 
-	rows, err := db.conn.QueryContext(ctx, query)
+	return &b
+}
+
+// GetRecentChanges retrieves sensors whose last_down_utc or last_up_utc falls within the last N minutes,
+// across all devices. Each result is labeled with the direction of its most recent transition.
+// Results are sorted newest change first.
+func (db *DB) GetRecentChanges(ctx context.Context, minutes, limit int) ([]types.StatusChange, error) {
+	query := `
+		SELECT
+			s.id,
+			s.prtg_server_address_id,
+			s.name,
+			s.sensor_type,
+			s.prtg_device_id,
+			d.name AS device_name,
+			d.host AS device_host,
+			s.scanning_interval_seconds,
+			s.status,
+			s.last_check_utc,
+			s.last_up_utc,
+			s.last_down_utc,
+			s.priority,
+			s.message,
+			s.uptime_since_seconds,
+			s.downtime_since_seconds,
+			sp.path AS full_path,
+			'' AS tags
+		FROM prtg_sensor s
+		INNER JOIN prtg_device d ON s.prtg_device_id = d.id
+			AND s.prtg_server_address_id = d.prtg_server_address_id
+		INNER JOIN prtg_sensor_path sp ON s.id = sp.sensor_id
+			AND s.prtg_server_address_id = sp.prtg_server_address_id
+		WHERE s.last_down_utc >= NOW() - ($1 || ' minutes')::interval
+			OR s.last_up_utc >= NOW() - ($1 || ' minutes')::interval
+		ORDER BY GREATEST(s.last_down_utc, s.last_up_utc) DESC, s.name
+		LIMIT $2
+	`
+
+	rows, err := db.Query(ctx, query, minutes, limit)
 	if err != nil {
 		return nil, fmt.Errorf("query failed: %w", err)
 	}
 	defer rows.Close()
 
-	return scanGenericResults(rows)
-}
-
-// scanGenericResults scans generic SQL query results into maps.
-func scanGenericResults(rows *sql.Rows) ([]map[string]interface{}, error) {
-	// Get column names
-	columns, err := rows.Columns()
+	sensors, err := scanSensors(rows)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get columns: %w", err)
+		return nil, err
 	}
 
-	results := []map[string]interface{}{}
-
-	for rows.Next() {
-		// Create a slice of interface{}'s to represent each column
-		values := make([]interface{}, len(columns))
-		valuePtrs := make([]interface{}, len(columns))
-
-		for i := range values {
-			valuePtrs[i] = &values[i]
-		}
+	changes := make([]types.StatusChange, 0, len(sensors))
+	for _, sensor := range sensors {
+		changes = append(changes, labelStatusChange(sensor))
+	}
 
-		if err := rows.Scan(valuePtrs...); err != nil {
-			return nil, fmt.Errorf("scan failed: %w", err)
-		}
+	return changes, nil
+}
 
-		// Create map for this row
-		row := make(map[string]interface{})
-		for i, col := range columns {
-			row[col] = values[i]
-		}
+// labelStatusChange labels a sensor with its most recent transition (see Sensor.LastTransition).
+func labelStatusChange(sensor types.Sensor) types.StatusChange {
+	transition, changedAt := sensor.LastTransition()
 
-		results = append(results, row)
+	return types.StatusChange{
+		Sensor:     sensor,
+		Transition: transition,
+		ChangedAt:  changedAt,
 	}
-
-	return results, rows.Err()
 }
 
-// GetGroups retrieves all PRTG groups matching the given filters.
-func (db *DB) GetGroups(ctx context.Context, groupName string, parentID *int, limit int) ([]types.Group, error) {
-	query := `
+// GetDeviceOverview retrieves a device with all its sensors and aggregated statistics.
+// Returns sql.ErrNoRows if no device matches the given name.
+func (db *DB) GetDeviceOverview(ctx context.Context, deviceName string) (*types.DeviceOverview, error) {
+	// Get device info
+	deviceQuery := `
 		SELECT
-			g.id,
-			g.prtg_server_address_id,
-			g.name,
-			g.is_probe_node,
-			g.self_group_id,
-			gp.path AS full_path,
-			g.tree_depth
-		FROM prtg_group g
-		INNER JOIN prtg_group_path gp ON g.id = gp.group_id
-			AND g.prtg_server_address_id = gp.prtg_server_address_id
-		WHERE 1=1
+			d.id,
+			d.prtg_server_address_id,
+			d.name,
+			d.host,
+			d.prtg_group_id,
+			g.name AS group_name,
+			dp.path AS full_path,
+			d.tree_depth,
+			COALESCE(
+				(SELECT COUNT(*) FROM prtg_sensor s
+				 WHERE s.prtg_device_id = d.id
+				 AND s.prtg_server_address_id = d.prtg_server_address_id),
+				0
+			) AS sensor_count
+		FROM prtg_device d
+		INNER JOIN prtg_group g ON d.prtg_group_id = g.id
+			AND d.prtg_server_address_id = g.prtg_server_address_id
+		INNER JOIN prtg_device_path dp ON d.id = dp.device_id
+			AND d.prtg_server_address_id = dp.prtg_server_address_id
+		WHERE d.name ILIKE $1
+		LIMIT 1
 	`
 
-	args := []interface{}{}
-	argPos := 1
+	var device types.Device
+	err := db.QueryRow(ctx, deviceQuery, "%"+deviceName+"%").Scan(
+		&device.ID,
+		&device.ServerID,
+		&device.Name,
+		&device.Host,
+		&device.GroupID,
+		&device.GroupName,
+		&device.FullPath,
+		&device.TreeDepth,
+		&device.SensorCount,
+	)
+
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("device not found")
+		}
+
+		return nil, fmt.Errorf("query failed: %w", err)
+	}
+
+	// Get all sensors for this device
+	sensorsQuery := `
+		SELECT
+			s.id,
+			s.prtg_server_address_id,
+			s.name,
+			s.sensor_type,
+			s.prtg_device_id,
+			$2 AS device_name,
+			$4 AS device_host,
+			s.scanning_interval_seconds,
+			s.status,
+			s.last_check_utc,
+			s.last_up_utc,
+			s.last_down_utc,
+			s.priority,
+			s.message,
+			s.uptime_since_seconds,
+			s.downtime_since_seconds,
+			sp.path AS full_path,
+			COALESCE(
+				(SELECT string_agg(t.name, ',')
+				 FROM prtg_sensor_tag st
+				 JOIN prtg_tag t ON st.prtg_tag_id = t.id
+				 WHERE st.prtg_sensor_id = s.id
+				 AND st.prtg_server_address_id = s.prtg_server_address_id),
+				''
+			) AS tags
+		FROM prtg_sensor s
+		INNER JOIN prtg_sensor_path sp ON s.id = sp.sensor_id
+			AND s.prtg_server_address_id = sp.prtg_server_address_id
+		WHERE s.prtg_device_id = $1
+		AND s.prtg_server_address_id = $3
+		ORDER BY s.status, s.name
+	`
+
+	rows, err := db.Query(ctx, sensorsQuery, device.ID, device.Name, device.ServerID, device.Host)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get sensors: %w", err)
+	}
+	defer rows.Close()
+
+	sensors, err := scanSensors(rows)
+	if err != nil {
+		return nil, err
+	}
+
+	// Calculate statistics
+	upCount := 0
+	downCount := 0
+	warnCount := 0
+
+	for i := range sensors {
+		switch sensors[i].Status {
+		case types.StatusUp:
+			upCount++
+		case types.StatusDown:
+			downCount++
+		case types.StatusWarning:
+			warnCount++
+		}
+	}
+
+	return &types.DeviceOverview{
+		Device:       device,
+		Sensors:      sensors,
+		TotalSensors: len(sensors),
+		UpSensors:    upCount,
+		DownSensors:  downCount,
+		WarnSensors:  warnCount,
+	}, nil
+}
+
+// GetTopSensors retrieves top sensors ranked by the given metric.
+// Valid metrics: "uptime", "downtime", "alerts", "recent_changes". Results are limited by the limit parameter.
+func (db *DB) GetTopSensors(ctx context.Context, metric, sensorType string, limit, _ int) ([]types.Sensor, error) {
+	query := `
+		SELECT
+			s.id,
+			s.prtg_server_address_id,
+			s.name,
+			s.sensor_type,
+			s.prtg_device_id,
+			d.name AS device_name,
+			d.host AS device_host,
+			s.scanning_interval_seconds,
+			s.status,
+			s.last_check_utc,
+			s.last_up_utc,
+			s.last_down_utc,
+			s.priority,
+			s.message,
+			s.uptime_since_seconds,
+			s.downtime_since_seconds,
+			sp.path AS full_path,
+			COALESCE(
+				(SELECT string_agg(t.name, ',')
+				 FROM prtg_sensor_tag st
+				 JOIN prtg_tag t ON st.prtg_tag_id = t.id
+				 WHERE st.prtg_sensor_id = s.id
+				 AND st.prtg_server_address_id = s.prtg_server_address_id),
+				''
+			) AS tags
+		FROM prtg_sensor s
+		INNER JOIN prtg_device d ON s.prtg_device_id = d.id
+			AND s.prtg_server_address_id = d.prtg_server_address_id
+		INNER JOIN prtg_sensor_path sp ON s.id = sp.sensor_id
+			AND s.prtg_server_address_id = sp.prtg_server_address_id
+	`
+
+	var conditions sensorFilterBuilder
+
+	if sensorType != "" {
+		conditions.where("s.sensor_type ILIKE %s", "%"+sensorType+"%")
+	}
+
+	// Add ordering based on metric
+	var orderClause string
+
+	switch metric {
+	case "downtime":
+		orderClause = " ORDER BY s.downtime_since_seconds DESC NULLS LAST"
+	case "alerts":
+		// Order by non-UP status, then by priority
+		conditions.where("s.status != %s", types.StatusUp)
+		orderClause = " ORDER BY s.priority DESC, s.status"
+	case "recent_changes":
+		// Problem sensors, most recently gone down first. Those without a recorded down
+		// transition (e.g. warnings raised while up) come last.
+		conditions.where("s.status IN (" + joinInts(types.ProblemStatuses) + ")")
+		orderClause = " ORDER BY s.last_down_utc DESC NULLS LAST, s.name"
+	default: // "uptime" or default
+		orderClause = " ORDER BY s.uptime_since_seconds DESC NULLS LAST"
+	}
+
+	query += conditions.whereClause() + orderClause
+
+	if limit > 0 {
+		query += " LIMIT " + conditions.arg(limit)
+	}
+
+	rows, err := db.Query(ctx, query, conditions.args...)
+	if err != nil {
+		return nil, fmt.Errorf("query failed: %w", err)
+	}
+	defer rows.Close()
+
+	return scanSensors(rows)
+}
+
+// ExecuteCustomQuery executes a custom SQL SELECT query with security validation.
+// Only SELECT queries are allowed - INSERT/UPDATE/DELETE/DROP are rejected, and so are
+// tables outside the list set with SetCustomQueryAllowedTables.
+// This function should be disabled in production (set allow_custom_queries: false in config).
+// At most limit rows are returned (see customQueryLimit), all at once; use StreamCustomQuery for
+// large result sets.
+func (db *DB) ExecuteCustomQuery(ctx context.Context, query string, limit int) ([]map[string]interface{}, error) {
+	wrapped, args, err := prepareCustomQuery(query, customQueryLimit(limit))
+	if err != nil {
+		return nil, err
+	}
+
+	if err := db.checkCustomQueryTables(query); err != nil {
+		return nil, err
+	}
+
+	query = wrapped
+
+	// Rows are scanned inside the statement timeout transaction
+	var results []map[string]interface{}
+
+	err = db.withStatementTimeout(ctx, func(ctx context.Context) error {
+		rows, err := db.Query(ctx, query, args...)
+		if err != nil {
+			return fmt.Errorf("query failed: %w", err)
+		}
+		defer rows.Close()
+
+		results, err = scanGenericResults(rows)
+
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return results, nil
+}
+
+// StreamCustomQuery executes a custom SQL SELECT query with the validation of ExecuteCustomQuery,
+// passing the first limit rows (see customQueryLimit) to fn one at a time instead of materializing
+// them. The values slice is reused for every row, so fn must not retain it. It returns the number
+// of rows passed to fn, and whether the query has more rows than that.
+func (db *DB) StreamCustomQuery(ctx context.Context, query string, limit int,
+	fn func(columns []string, values []interface{}) error) (int, bool, error) {
+	limit = customQueryLimit(limit)
+
+	// One row past the limit tells whether rows were left out
+	wrapped, args, err := prepareCustomQuery(query, limit+1)
+	if err != nil {
+		return 0, false, err
+	}
+
+	if err := db.checkCustomQueryTables(query); err != nil {
+		return 0, false, err
+	}
+
+	query = wrapped
+	count := 0
+	truncated := false
+
+	err = db.withStatementTimeout(ctx, func(ctx context.Context) error {
+		rows, err := db.Query(ctx, query, args...)
+		if err != nil {
+			return fmt.Errorf("query failed: %w", err)
+		}
+		defer rows.Close()
+
+		columns, err := rows.Columns()
+		if err != nil {
+			return fmt.Errorf("failed to get columns: %w", err)
+		}
+
+		// One scan buffer for the whole result set
+		values := make([]interface{}, len(columns))
+		valuePtrs := make([]interface{}, len(columns))
+
+		for i := range values {
+			valuePtrs[i] = &values[i]
+		}
+
+		for rows.Next() {
+			if count == limit {
+				truncated = true
+				break
+			}
+
+			if err := rows.Scan(valuePtrs...); err != nil {
+				return fmt.Errorf("scan failed: %w", err)
+			}
+
+			count++
+
+			if err := fn(columns, values); err != nil {
+				return err
+			}
+		}
+
+		return rows.Err()
+	})
+	if err != nil {
+		return 0, false, err
+	}
+
+	return count, truncated, nil
+}
+
+// customQueryLimit returns the number of rows a custom query called with limit returns:
+// types.CustomQueryLimit when limit is not set, at most types.MaxCustomQueryRows.
+func customQueryLimit(limit int) int {
+	if limit <= 0 {
+		return types.CustomQueryLimit
+	}
+
+	return min(limit, types.MaxCustomQueryRows)
+}
+
+// prepareCustomQuery validates a custom query and wraps it so that at most rows rows are read,
+// whatever LIMIT the query itself has: SELECT * FROM (query) q LIMIT $1.
+func prepareCustomQuery(query string, rows int) (string, []interface{}, error) {
+	// Security: Validate query is SELECT only
+	queryUpper := strings.ToUpper(strings.TrimSpace(query))
+	if !strings.HasPrefix(queryUpper, "SELECT") {
+		return "", nil, fmt.Errorf("only SELECT queries are allowed")
+	}
+
+	// Check for dangerous keywords (including comments to prevent bypass)
+	dangerous := []string{"DROP", "DELETE", "UPDATE", "INSERT", "ALTER", "CREATE", "TRUNCATE", "EXEC", "EXECUTE", "/*", "--", ";"}
+	for _, keyword := range dangerous {
+		if strings.Contains(queryUpper, keyword) || strings.Contains(query, keyword) {
+			return "", nil, fmt.Errorf("query contains forbidden keyword: %s", keyword)
+		}
+	}
+
+	// A LIMIT in the query only lowers the row count, it can't raise it past rows
+	return "SELECT * FROM (" + query + ") q LIMIT $1", []interface{}{rows}, nil
+}
+
+// scanGenericResults scans generic SQL query results into maps.
+func scanGenericResults(rows *sql.Rows) ([]map[string]interface{}, error) {
+	// Get column names
+	columns, err := rows.Columns()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get columns: %w", err)
+	}
+
+	results := []map[string]interface{}{}
+
+	for rows.Next() {
+		// Create a slice of interface{}'s to represent each column
+		values := make([]interface{}, len(columns))
+		valuePtrs := make([]interface{}, len(columns))
+
+		for i := range values {
+			valuePtrs[i] = &values[i]
+		}
+
+		if err := rows.Scan(valuePtrs...); err != nil {
+			return nil, fmt.Errorf("scan failed: %w", err)
+		}
+
+		// Create map for this row
+		row := make(map[string]interface{})
+		for i, col := range columns {
+			row[col] = values[i]
+		}
+
+		results = append(results, row)
+	}
+
+	return results, rows.Err()
+}
+
+// GetGroups retrieves all PRTG groups matching the given filters.
+func (db *DB) GetGroups(ctx context.Context, groupName string, parentID *int, limit int) ([]types.Group, error) {
+	query := `
+		SELECT
+			g.id,
+			g.prtg_server_address_id,
+			g.name,
+			g.is_probe_node,
+			g.self_group_id,
+			gp.path AS full_path,
+			g.tree_depth
+		FROM prtg_group g
+		INNER JOIN prtg_group_path gp ON g.id = gp.group_id
+			AND g.prtg_server_address_id = gp.prtg_server_address_id
+	`
+
+	var conditions sensorFilterBuilder
+
+	if groupName != "" {
+		conditions.where("g.name ILIKE %s", "%"+groupName+"%")
+	}
+
+	if parentID != nil {
+		conditions.where("g.self_group_id = %s", *parentID)
+	}
+
+	query += conditions.whereClause() + " ORDER BY g.tree_depth, g.name"
+
+	if limit > 0 {
+		query += " LIMIT " + conditions.arg(limit)
+	}
+
+	rows, err := db.Query(ctx, query, conditions.args...)
+	if err != nil {
+		return nil, fmt.Errorf("query failed: %w", err)
+	}
+	defer rows.Close()
+
+	return scanGroups(rows)
+}
+
+// scanGroups scans group rows selected as id, server, name, is_probe_node, self_group_id, path, depth.
+func scanGroups(rows *sql.Rows) ([]types.Group, error) {
+	groups := []types.Group{}
+	for rows.Next() {
+		var group types.Group
+		var parentID sql.NullInt32
+
+		err := rows.Scan(
+			&group.ID,
+			&group.ServerID,
+			&group.Name,
+			&group.IsProbeNode,
+			&parentID,
+			&group.FullPath,
+			&group.TreeDepth,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("scan failed: %w", err)
+		}
+
+		if parentID.Valid {
+			parentIDInt := int(parentID.Int32)
+			group.ParentID = &parentIDInt
+		}
+
+		groups = append(groups, group)
+	}
+
+	return groups, rows.Err()
+}
+
+// GetDevicesByGroupID retrieves the devices in a given group, ordered by name (limit 0 = all).
+func (db *DB) GetDevicesByGroupID(ctx context.Context, groupID, limit int) ([]types.Device, error) {
+	query := `
+		SELECT
+			d.id,
+			d.prtg_server_address_id,
+			d.name,
+			d.host,
+			d.prtg_group_id,
+			g.name AS group_name,
+			dp.path AS full_path,
+			COALESCE(
+				(SELECT COUNT(*) FROM prtg_sensor s
+				 WHERE s.prtg_device_id = d.id
+				 AND s.prtg_server_address_id = d.prtg_server_address_id),
+				0
+			) AS sensor_count,
+			d.tree_depth
+		FROM prtg_device d
+		INNER JOIN prtg_group g ON d.prtg_group_id = g.id
+			AND d.prtg_server_address_id = g.prtg_server_address_id
+		INNER JOIN prtg_device_path dp ON d.id = dp.device_id
+			AND d.prtg_server_address_id = dp.prtg_server_address_id
+		WHERE d.prtg_group_id = $1
+		ORDER BY d.name
+	`
+
+	args := []interface{}{groupID}
+	if limit > 0 {
+		query += " LIMIT $2"
+		args = append(args, limit)
+	}
+
+	rows, err := db.Query(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("query failed: %w", err)
+	}
+	defer rows.Close()
+
+	return scanDevices(rows)
+}
+
+// scanDevices scans device rows selected as id, server, name, host, group ID, group name,
+// path, sensor count, depth.
+func scanDevices(rows *sql.Rows) ([]types.Device, error) {
+	devices := []types.Device{}
+	for rows.Next() {
+		var device types.Device
+
+		err := rows.Scan(
+			&device.ID,
+			&device.ServerID,
+			&device.Name,
+			&device.Host,
+			&device.GroupID,
+			&device.GroupName,
+			&device.FullPath,
+			&device.SensorCount,
+			&device.TreeDepth,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("scan failed: %w", err)
+		}
+
+		devices = append(devices, device)
+	}
+
+	return devices, rows.Err()
+}
+
+// GetEmptyDevices retrieves devices without any sensor, ordered by path (limit 0 = all).
+// Such devices are usually leftovers of removed monitoring.
+func (db *DB) GetEmptyDevices(ctx context.Context, limit int) ([]types.Device, error) {
+	query := `
+		SELECT
+			d.id,
+			d.prtg_server_address_id,
+			d.name,
+			d.host,
+			d.prtg_group_id,
+			g.name AS group_name,
+			dp.path AS full_path,
+			0 AS sensor_count,
+			d.tree_depth
+		FROM prtg_device d
+		INNER JOIN prtg_group g ON d.prtg_group_id = g.id
+			AND d.prtg_server_address_id = g.prtg_server_address_id
+		INNER JOIN prtg_device_path dp ON d.id = dp.device_id
+			AND d.prtg_server_address_id = dp.prtg_server_address_id
+		WHERE NOT EXISTS (
+			SELECT 1 FROM prtg_sensor s
+			WHERE s.prtg_device_id = d.id
+			AND s.prtg_server_address_id = d.prtg_server_address_id
+		)
+		ORDER BY dp.path, d.id
+	`
+
+	args := []interface{}{}
+	if limit > 0 {
+		query += " LIMIT $1"
+		args = append(args, limit)
+	}
+
+	rows, err := db.Query(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("query failed: %w", err)
+	}
+	defer rows.Close()
+
+	return scanDevices(rows)
+}
+
+// GetEmptyGroups retrieves groups without devices and without child groups, ordered by path
+// (limit 0 = all). Probes are included: an empty probe is as much a leftover as an empty group.
+func (db *DB) GetEmptyGroups(ctx context.Context, limit int) ([]types.Group, error) {
+	query := `
+		SELECT
+			g.id,
+			g.prtg_server_address_id,
+			g.name,
+			g.is_probe_node,
+			g.self_group_id,
+			gp.path AS full_path,
+			g.tree_depth
+		FROM prtg_group g
+		INNER JOIN prtg_group_path gp ON g.id = gp.group_id
+			AND g.prtg_server_address_id = gp.prtg_server_address_id
+		WHERE NOT EXISTS (
+			SELECT 1 FROM prtg_device d
+			WHERE d.prtg_group_id = g.id
+			AND d.prtg_server_address_id = g.prtg_server_address_id
+		)
+		AND NOT EXISTS (
+			SELECT 1 FROM prtg_group c
+			WHERE c.self_group_id = g.id
+			AND c.prtg_server_address_id = g.prtg_server_address_id
+		)
+		ORDER BY gp.path, g.id
+	`
+
+	args := []interface{}{}
+	if limit > 0 {
+		query += " LIMIT $1"
+		args = append(args, limit)
+	}
+
+	rows, err := db.Query(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("query failed: %w", err)
+	}
+	defer rows.Close()
+
+	return scanGroups(rows)
+}
+
+// taggedSensorsJoin left-joins each sensor of s to its tag assignments, one row per tagged sensor,
+// so st.prtg_sensor_id IS NULL identifies untagged sensors without duplicating tagged ones.
+const taggedSensorsJoin = `
+		LEFT JOIN (
+			SELECT DISTINCT prtg_sensor_id, prtg_server_address_id FROM prtg_sensor_tag
+		) st ON st.prtg_sensor_id = s.id
+			AND st.prtg_server_address_id = s.prtg_server_address_id`
+
+// GetTagCoverage counts the sensors with and without tags, and returns the devices, or the
+// groups directly holding the devices (groupBy "group"), with the most untagged sensors.
+// Objects whose sensors are all tagged are not returned.
+func (db *DB) GetTagCoverage(ctx context.Context, groupBy string, limit int) (*types.TagCoverage, error) {
+	var coverage *types.TagCoverage
+
+	err := db.withStatementTimeout(ctx, func(ctx context.Context) error {
+		var err error
+		coverage, err = db.getTagCoverage(ctx, groupBy, limit)
+
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return coverage, nil
+}
+
+// getTagCoverage implements GetTagCoverage.
+func (db *DB) getTagCoverage(ctx context.Context, groupBy string, limit int) (*types.TagCoverage, error) {
+	coverage := &types.TagCoverage{GroupBy: groupBy, Objects: []types.UntaggedSensors{}}
+
+	totalsQuery := `
+		SELECT
+			COUNT(*) AS total_sensors,
+			COUNT(*) FILTER (WHERE st.prtg_sensor_id IS NULL) AS untagged_sensors
+		FROM prtg_sensor s` + taggedSensorsJoin
+
+	if err := db.QueryRow(ctx, totalsQuery).Scan(&coverage.TotalSensors, &coverage.UntaggedSensors); err != nil {
+		return nil, fmt.Errorf("tag coverage query failed: %w", err)
+	}
+
+	coverage.CoveragePercent = 100
+	if coverage.TotalSensors > 0 {
+		tagged := coverage.TotalSensors - coverage.UntaggedSensors
+		coverage.CoveragePercent = float64(tagged) * 100 / float64(coverage.TotalSensors)
+	}
+
+	// Objects are devices, or the groups holding them
+	object, path := `d.id, d.prtg_server_address_id, d.name`, `dp.path`
+	objectJoin := `
+		INNER JOIN prtg_device_path dp ON dp.device_id = d.id
+			AND dp.prtg_server_address_id = d.prtg_server_address_id`
+
+	if groupBy == "group" {
+		object, path = `g.id, g.prtg_server_address_id, g.name`, `gp.path`
+		objectJoin = `
+		INNER JOIN prtg_group g ON g.id = d.prtg_group_id
+			AND g.prtg_server_address_id = d.prtg_server_address_id
+		INNER JOIN prtg_group_path gp ON gp.group_id = g.id
+			AND gp.prtg_server_address_id = g.prtg_server_address_id`
+	}
+
+	query := `
+		SELECT
+			` + object + `, ` + path + `,
+			COUNT(*) FILTER (WHERE st.prtg_sensor_id IS NULL) AS untagged_sensors,
+			COUNT(*) AS total_sensors
+		FROM prtg_sensor s` + taggedSensorsJoin + `
+		INNER JOIN prtg_device d ON d.id = s.prtg_device_id
+			AND d.prtg_server_address_id = s.prtg_server_address_id` + objectJoin + `
+		GROUP BY ` + object + `, ` + path + `
+		HAVING COUNT(*) FILTER (WHERE st.prtg_sensor_id IS NULL) > 0
+		ORDER BY untagged_sensors DESC, ` + path + `
+		LIMIT $1
+	`
+
+	rows, err := db.Query(ctx, query, limit)
+	if err != nil {
+		return nil, fmt.Errorf("untagged sensors query failed: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var untagged types.UntaggedSensors
+		if err := rows.Scan(&untagged.ID, &untagged.ServerID, &untagged.Name, &untagged.FullPath,
+			&untagged.UntaggedSensors, &untagged.TotalSensors); err != nil {
+			return nil, fmt.Errorf("untagged sensors scan failed: %w", err)
+		}
+
+		coverage.Objects = append(coverage.Objects, untagged)
+	}
+
+	return coverage, rows.Err()
+}
+
+// ResolveObjectByID looks up a PRTG object ID as a sensor, then a device, then a group,
+// and returns the first match. Returns nil, nil if no object has this ID.
+func (db *DB) ResolveObjectByID(ctx context.Context, objectID int) (*types.PRTGObject, error) {
+	sensor, err := db.getSensorByID(ctx, objectID, nil)
+	if err == nil {
+		return &types.PRTGObject{Type: types.ObjectTypeSensor, Sensor: sensor}, nil
+	}
+
+	if err != sql.ErrNoRows {
+		return nil, fmt.Errorf("sensor lookup failed: %w", err)
+	}
+
+	device, err := db.getDeviceByID(ctx, objectID)
+	if err == nil {
+		return &types.PRTGObject{Type: types.ObjectTypeDevice, Device: device}, nil
+	}
+
+	if err != sql.ErrNoRows {
+		return nil, fmt.Errorf("device lookup failed: %w", err)
+	}
+
+	group, err := db.getGroupByID(ctx, objectID, nil)
+	if err == nil {
+		return &types.PRTGObject{Type: types.ObjectTypeGroup, Group: group}, nil
+	}
+
+	if err != sql.ErrNoRows {
+		return nil, fmt.Errorf("group lookup failed: %w", err)
+	}
+
+	return nil, nil
+}
+
+// getDeviceByID retrieves a single device by ID. Returns sql.ErrNoRows if it is not found.
+func (db *DB) getDeviceByID(ctx context.Context, deviceID int) (*types.Device, error) {
+	query := `
+		SELECT
+			d.id,
+			d.prtg_server_address_id,
+			d.name,
+			d.host,
+			d.prtg_group_id,
+			g.name AS group_name,
+			dp.path AS full_path,
+			COALESCE(
+				(SELECT COUNT(*) FROM prtg_sensor s
+				 WHERE s.prtg_device_id = d.id
+				 AND s.prtg_server_address_id = d.prtg_server_address_id),
+				0
+			) AS sensor_count,
+			d.tree_depth
+		FROM prtg_device d
+		INNER JOIN prtg_group g ON d.prtg_group_id = g.id
+			AND d.prtg_server_address_id = g.prtg_server_address_id
+		INNER JOIN prtg_device_path dp ON d.id = dp.device_id
+			AND d.prtg_server_address_id = dp.prtg_server_address_id
+		WHERE d.id = $1
+		LIMIT 1
+	`
+
+	var device types.Device
+
+	err := db.QueryRow(ctx, query, deviceID).Scan(
+		&device.ID,
+		&device.ServerID,
+		&device.Name,
+		&device.Host,
+		&device.GroupID,
+		&device.GroupName,
+		&device.FullPath,
+		&device.SensorCount,
+		&device.TreeDepth,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return &device, nil
+}
+
+// getGroupByID retrieves a single group or probe by ID, on the given server if serverID is not nil.
+// Returns sql.ErrNoRows if it is not found.
+func (db *DB) getGroupByID(ctx context.Context, groupID int, serverID *int) (*types.Group, error) {
+	query := `
+		SELECT
+			g.id,
+			g.prtg_server_address_id,
+			g.name,
+			g.is_probe_node,
+			g.self_group_id,
+			gp.path AS full_path,
+			g.tree_depth
+		FROM prtg_group g
+		INNER JOIN prtg_group_path gp ON g.id = gp.group_id
+			AND g.prtg_server_address_id = gp.prtg_server_address_id
+		WHERE g.id = $1`
+
+	args := []interface{}{groupID}
+
+	if serverID != nil {
+		query += " AND g.prtg_server_address_id = $2"
+		args = append(args, *serverID)
+	}
+
+	query += " LIMIT 1"
+
+	var group types.Group
+	var parentID sql.NullInt32
+
+	err := db.QueryRow(ctx, query, args...).Scan(
+		&group.ID,
+		&group.ServerID,
+		&group.Name,
+		&group.IsProbeNode,
+		&parentID,
+		&group.FullPath,
+		&group.TreeDepth,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	if parentID.Valid {
+		parentIDInt := int(parentID.Int32)
+		group.ParentID = &parentIDInt
+	}
+
+	return &group, nil
+}
+
+// maxDevicePathDepth bounds the walk up the group tree, so corrupted parent links can't loop forever.
+const maxDevicePathDepth = 64
+
+// GetDevicePath retrieves a device and its ancestor groups, following the parent links of the
+// stored groups up to the probe. Ancestors are ordered from the probe down to the device's group.
+// All queries of the walk run under the statement timeout, if configured.
+func (db *DB) GetDevicePath(ctx context.Context, deviceID int) (*types.DevicePath, error) {
+	var path *types.DevicePath
+
+	err := db.withStatementTimeout(ctx, func(ctx context.Context) error {
+		var err error
+		path, err = db.getDevicePath(ctx, deviceID)
+
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return path, nil
+}
 
-	if groupName != "" {
-		query += fmt.Sprintf(" AND g.name ILIKE $%d", argPos)
-		args = append(args, "%"+groupName+"%")
-		argPos++
-	}
+// getDevicePath implements GetDevicePath.
+func (db *DB) getDevicePath(ctx context.Context, deviceID int) (*types.DevicePath, error) {
+	device, err := db.getDeviceByID(ctx, deviceID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("device not found")
+		}
 
-	if parentID != nil {
-		query += fmt.Sprintf(" AND g.self_group_id = $%d", argPos)
-		args = append(args, *parentID)
-		argPos++
+		return nil, fmt.Errorf("device lookup failed: %w", err)
 	}
 
-	query += " ORDER BY g.tree_depth, g.name"
+	var ancestors []types.Group
 
-	if limit > 0 {
-		query += fmt.Sprintf(" LIMIT $%d", argPos)
-		args = append(args, limit)
-	}
+	visited := make(map[int]bool)
 
-	rows, err := db.Query(ctx, query, args...)
-	if err != nil {
-		return nil, fmt.Errorf("query failed: %w", err)
-	}
-	defer rows.Close()
+	for groupID := &device.GroupID; groupID != nil; {
+		if visited[*groupID] || len(ancestors) == maxDevicePathDepth {
+			return nil, fmt.Errorf("group hierarchy of device %d loops or is too deep at group %d", deviceID, *groupID)
+		}
 
-	groups := []types.Group{}
-	for rows.Next() {
-		var group types.Group
-		var parentID sql.NullInt32
+		visited[*groupID] = true
 
-		err := rows.Scan(
-			&group.ID,
-			&group.ServerID,
-			&group.Name,
-			&group.IsProbeNode,
-			&parentID,
-			&group.FullPath,
-			&group.TreeDepth,
-		)
+		// Group IDs are only unique per server: the ancestors are on the device's server
+		group, err := db.getGroupByID(ctx, *groupID, &device.ServerID)
 		if err != nil {
-			return nil, fmt.Errorf("scan failed: %w", err)
-		}
+			if err == sql.ErrNoRows {
+				return nil, fmt.Errorf("group %d not found in the path of device %d", *groupID, deviceID)
+			}
 
-		if parentID.Valid {
-			parentIDInt := int(parentID.Int32)
-			group.ParentID = &parentIDInt
+			return nil, fmt.Errorf("group lookup failed: %w", err)
 		}
 
-		groups = append(groups, group)
+		ancestors = append(ancestors, *group)
+		groupID = group.ParentID
 	}
 
-	return groups, rows.Err()
+	// Walked from the device's group upwards
+	slices.Reverse(ancestors)
+
+	return &types.DevicePath{Ancestors: ancestors, Device: *device}, nil
 }
 
-// GetDevicesByGroupID retrieves all devices in a given group.
-func (db *DB) GetDevicesByGroupID(ctx context.Context, groupID int) ([]types.Device, error) {
-	query := `
-		SELECT
-			d.id,
-			d.prtg_server_address_id,
-			d.name,
-			d.host,
-			d.prtg_group_id,
-			g.name AS group_name,
-			dp.path AS full_path,
-			COALESCE(
-				(SELECT COUNT(*) FROM prtg_sensor s
-				 WHERE s.prtg_device_id = d.id
-				 AND s.prtg_server_address_id = d.prtg_server_address_id),
-				0
-			) AS sensor_count,
-			d.tree_depth
-		FROM prtg_device d
-		INNER JOIN prtg_group g ON d.prtg_group_id = g.id
-			AND d.prtg_server_address_id = g.prtg_server_address_id
-		INNER JOIN prtg_device_path dp ON d.id = dp.device_id
-			AND d.prtg_server_address_id = dp.prtg_server_address_id
-		WHERE d.prtg_group_id = $1
-		ORDER BY d.name
-	`
+// GetHierarchy retrieves the PRTG hierarchy starting from a group.
+// If groupName is empty, returns root groups. Includes devices and optionally sensors.
+// Nodes whose devices, child groups or sensors exceed the breadth limits are annotated with their totals.
+// All queries of the walk run under the statement timeout, if configured.
+func (db *DB) GetHierarchy(ctx context.Context, groupName string, opts types.HierarchyOptions) (*types.HierarchyNode, error) {
+	var node *types.HierarchyNode
 
-	rows, err := db.Query(ctx, query, groupID)
+	err := db.withStatementTimeout(ctx, func(ctx context.Context) error {
+		var err error
+		node, err = db.getHierarchy(ctx, groupName, opts)
+
+		return err
+	})
 	if err != nil {
-		return nil, fmt.Errorf("query failed: %w", err)
+		return nil, err
 	}
-	defer rows.Close()
 
-	devices := []types.Device{}
-	for rows.Next() {
-		var device types.Device
+	return node, nil
+}
 
-		err := rows.Scan(
-			&device.ID,
-			&device.ServerID,
-			&device.Name,
-			&device.Host,
-			&device.GroupID,
-			&device.GroupName,
-			&device.FullPath,
-			&device.SensorCount,
-			&device.TreeDepth,
-		)
-		if err != nil {
-			return nil, fmt.Errorf("scan failed: %w", err)
-		}
+// getHierarchy implements GetHierarchy.
+func (db *DB) getHierarchy(ctx context.Context, groupName string, opts types.HierarchyOptions) (*types.HierarchyNode, error) {
+	if opts.MaxChildren <= 0 {
+		opts.MaxChildren = types.DefaultHierarchyMaxChildren
+	}
 
-		devices = append(devices, device)
+	if opts.MaxSensorsPerDevice <= 0 {
+		opts.MaxSensorsPerDevice = types.DefaultHierarchyMaxSensorsPerDevice
 	}
 
-	return devices, rows.Err()
-}
+	if opts.MaxNodes <= 0 {
+		opts.MaxNodes = types.DefaultHierarchyMaxNodes
+	}
 
-// GetHierarchy retrieves the PRTG hierarchy starting from a group.
-// If groupName is empty, returns root groups. Includes devices and optionally sensors.
-func (db *DB) GetHierarchy(ctx context.Context, groupName string, includeSensors bool, maxDepth int) (*types.HierarchyNode, error) {
 	// Get the starting group(s)
 	var groups []types.Group
 	var err error
@@ -832,11 +1742,59 @@ func (db *DB) GetHierarchy(ctx context.Context, groupName string, includeSensors
 	}
 
 	// Build hierarchy starting from first group
-	return db.buildHierarchyNode(ctx, &groups[0], includeSensors, maxDepth, 0)
+	budget := &hierarchyBudget{remaining: opts.MaxNodes - 1} // The starting group is the first node
+
+	node, err := db.buildHierarchyNode(ctx, &groups[0], opts, 0, budget)
+	if err != nil {
+		return nil, err
+	}
+
+	node.NodeLimitReached = budget.exhausted
+
+	return node, nil
+}
+
+// hierarchyBudget is the number of nodes (groups, devices, sensors) a hierarchy traversal may
+// still add, shared by the whole recursion so an unlimited depth cannot exhaust memory.
+type hierarchyBudget struct {
+	remaining int
+	exhausted bool // A node was left out because the budget was used up
+}
+
+// take reserves up to n nodes and returns how many were granted.
+func (b *hierarchyBudget) take(n int) int {
+	if n > b.remaining {
+		n = b.remaining
+		b.exhausted = true
+	}
+
+	b.remaining -= n
+
+	return n
+}
+
+// hierarchyError returns the context error once ctx is cancelled or expired, so an interrupted
+// traversal reports context.Canceled or context.DeadlineExceeded rather than the driver's
+// cancellation error, and otherwise err wrapped with msg.
+func hierarchyError(ctx context.Context, msg string, err error) error {
+	if ctxErr := ctx.Err(); ctxErr != nil {
+		return ctxErr
+	}
+
+	return fmt.Errorf("%s: %w", msg, err)
 }
 
 // buildHierarchyNode recursively builds a hierarchy node.
-func (db *DB) buildHierarchyNode(ctx context.Context, group *types.Group, includeSensors bool, maxDepth, currentDepth int) (*types.HierarchyNode, error) {
+// One extra device and child group are fetched to detect truncation; totals are only counted when truncated.
+// Each group, device and sensor uses one node of the budget; once it is used up, the remaining
+// devices, sensors and child groups are left out.
+// The context is checked before each query, so a client disconnect or timeout stops the traversal.
+func (db *DB) buildHierarchyNode(ctx context.Context, group *types.Group, opts types.HierarchyOptions,
+	currentDepth int, budget *hierarchyBudget) (*types.HierarchyNode, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
 	node := &types.HierarchyNode{
 		Group:   *group,
 		Devices: []types.HierarchyDevice{},
@@ -844,92 +1802,326 @@ func (db *DB) buildHierarchyNode(ctx context.Context, group *types.Group, includ
 	}
 
 	// Stop if we've reached max depth
-	if maxDepth > 0 && currentDepth >= maxDepth {
+	if opts.MaxDepth > 0 && currentDepth >= opts.MaxDepth {
 		return node, nil
 	}
 
-	// Get devices in this group
-	devices, err := db.GetDevicesByGroupID(ctx, group.ID)
+	// Get devices in this group
+	devices, err := db.GetDevicesByGroupID(ctx, group.ID, opts.MaxChildren+1)
+	if err != nil {
+		return nil, hierarchyError(ctx, "failed to get devices", err)
+	}
+
+	if len(devices) > opts.MaxChildren {
+		devices = devices[:opts.MaxChildren]
+
+		if node.TotalDevices, err = db.countChildren(ctx, "SELECT COUNT(*) FROM prtg_device WHERE prtg_group_id = $1", group.ID); err != nil {
+			return nil, hierarchyError(ctx, "failed to count devices", err)
+		}
+	}
+
+	// Build device nodes
+	for _, device := range devices {
+		if budget.take(1) == 0 {
+			return node, nil
+		}
+
+		deviceNode := types.HierarchyDevice{
+			Device:  device,
+			Sensors: []types.Sensor{},
+		}
+
+		// Get sensors if requested, within the remaining budget
+		sensorLimit := opts.MaxSensorsPerDevice
+		if opts.IncludeSensors && device.SensorCount > 0 {
+			sensorLimit = budget.take(min(sensorLimit, device.SensorCount))
+		}
+
+		if opts.IncludeSensors && sensorLimit > 0 {
+			if err := ctx.Err(); err != nil {
+				return nil, err
+			}
+
+			sensorsQuery := `
+				SELECT
+					s.id,
+					s.prtg_server_address_id,
+					s.name,
+					s.sensor_type,
+					s.prtg_device_id,
+					$2 AS device_name,
+					$5 AS device_host,
+					s.scanning_interval_seconds,
+					s.status,
+					s.last_check_utc,
+					s.last_up_utc,
+					s.last_down_utc,
+					s.priority,
+					s.message,
+					s.uptime_since_seconds,
+					s.downtime_since_seconds,
+					sp.path AS full_path,
+					'' AS tags
+				FROM prtg_sensor s
+				INNER JOIN prtg_sensor_path sp ON s.id = sp.sensor_id
+					AND s.prtg_server_address_id = sp.prtg_server_address_id
+				WHERE s.prtg_device_id = $1
+				AND s.prtg_server_address_id = $3
+				ORDER BY s.name
+				LIMIT $4
+			`
+
+			rows, err := db.Query(ctx, sensorsQuery, device.ID, device.Name, device.ServerID, sensorLimit, device.Host)
+			if err != nil {
+				return nil, hierarchyError(ctx, "failed to get sensors", err)
+			}
+
+			sensors, err := scanSensors(rows)
+			rows.Close()
+
+			if err != nil {
+				return nil, hierarchyError(ctx, "failed to scan sensors", err)
+			}
+
+			deviceNode.Sensors = sensors
+
+			// The device sensor count is already known, no extra query needed
+			if device.SensorCount > len(sensors) && len(sensors) == sensorLimit {
+				deviceNode.TotalSensors = device.SensorCount
+			}
+		}
+
+		if opts.IncludeSensors && sensorLimit == 0 {
+			deviceNode.TotalSensors = device.SensorCount // Budget used up: none of the sensors is listed
+		}
+
+		node.Devices = append(node.Devices, deviceNode)
+	}
+
+	// Get child groups
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	childGroups, err := db.GetGroups(ctx, "", &group.ID, opts.MaxChildren+1)
+	if err != nil {
+		return nil, hierarchyError(ctx, "failed to get child groups", err)
+	}
+
+	if len(childGroups) > opts.MaxChildren {
+		childGroups = childGroups[:opts.MaxChildren]
+
+		if node.TotalGroups, err = db.countChildren(ctx, "SELECT COUNT(*) FROM prtg_group WHERE self_group_id = $1", group.ID); err != nil {
+			return nil, hierarchyError(ctx, "failed to count child groups", err)
+		}
+	}
+
+	// Recursively build child nodes
+	for i := range childGroups {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
+		if budget.take(1) == 0 {
+			break
+		}
+
+		childNode, err := db.buildHierarchyNode(ctx, &childGroups[i], opts, currentDepth+1, budget)
+		if err != nil {
+			return nil, err
+		}
+		node.Groups = append(node.Groups, childNode)
+	}
+
+	return node, nil
+}
+
+// countChildren runs a COUNT(*) query taking a group ID.
+func (db *DB) countChildren(ctx context.Context, query string, groupID int) (int, error) {
+	var count int
+	if err := db.QueryRow(ctx, query, groupID).Scan(&count); err != nil {
+		return 0, err
+	}
+
+	return count, nil
+}
+
+// Search performs a universal search across groups, devices, and sensors.
+// Returns matching results organized by type.
+// Sensors also match on their status message; name and type matches are listed first.
+func (db *DB) Search(ctx context.Context, searchTerm string, limit int) (*types.SearchResults, error) {
+	var params sensorFilterBuilder
+
+	pattern := params.arg("%" + searchTerm + "%")
+
+	clauses := searchClauses{
+		groupWhere:  fmt.Sprintf("g.name ILIKE %s", pattern),
+		groupOrder:  "g.name",
+		deviceWhere: fmt.Sprintf("d.name ILIKE %[1]s OR d.host ILIKE %[1]s", pattern),
+		deviceOrder: "d.name",
+		sensorWhere: fmt.Sprintf("s.name ILIKE %[1]s OR s.sensor_type ILIKE %[1]s OR s.message ILIKE %[1]s", pattern),
+		sensorOrder: fmt.Sprintf("(s.name ILIKE %[1]s OR s.sensor_type ILIKE %[1]s) DESC, s.name", pattern), // Name/type matches before message-only matches
+		params:      params,
+	}
+
+	return db.runSearch(ctx, clauses, limit)
+}
+
+// SearchFuzzy performs a typo-tolerant universal search using PostgreSQL trigram similarity.
+// Objects match when they contain the search term or their similarity reaches the threshold (0-1).
+// Results are ordered by similarity, best match first.
+// Requires the pg_trgm extension (CREATE EXTENSION IF NOT EXISTS pg_trgm).
+func (db *DB) SearchFuzzy(ctx context.Context, searchTerm string, threshold float64, limit int) (*types.SearchResults, error) {
+	if threshold <= 0 || threshold > 1 {
+		threshold = 0.3 // pg_trgm default similarity threshold
+	}
+
+	var params sensorFilterBuilder
+
+	// %[1]s is the ILIKE pattern, %[2]s the term compared by similarity, %[3]s the threshold
+	placeholders := []interface{}{params.arg("%" + searchTerm + "%"), params.arg(searchTerm), params.arg(threshold)}
+	clause := func(format string) string { return fmt.Sprintf(format, placeholders...) }
+
+	clauses := searchClauses{
+		groupWhere:  clause("g.name ILIKE %[1]s OR similarity(g.name, %[2]s) >= %[3]s"),
+		groupOrder:  clause("similarity(g.name, %[2]s) DESC, g.name"),
+		deviceWhere: clause("d.name ILIKE %[1]s OR d.host ILIKE %[1]s OR GREATEST(similarity(d.name, %[2]s), similarity(d.host, %[2]s)) >= %[3]s"),
+		deviceOrder: clause("GREATEST(similarity(d.name, %[2]s), similarity(d.host, %[2]s)) DESC, d.name"),
+		sensorWhere: clause("s.name ILIKE %[1]s OR s.sensor_type ILIKE %[1]s OR s.message ILIKE %[1]s OR " +
+			"GREATEST(similarity(s.name, %[2]s), similarity(s.sensor_type, %[2]s)) >= %[3]s"),
+		sensorOrder: clause("GREATEST(similarity(s.name, %[2]s), similarity(s.sensor_type, %[2]s)) DESC, s.name"),
+		params:      params,
+	}
+
+	results, err := db.runSearch(ctx, clauses, limit)
+	if err != nil && strings.Contains(err.Error(), "similarity") && strings.Contains(err.Error(), "does not exist") {
+		return nil, fmt.Errorf("fuzzy search requires the pg_trgm extension (CREATE EXTENSION IF NOT EXISTS pg_trgm): %w", err)
+	}
+
+	return results, err
+}
+
+// SearchMessages retrieves sensors whose status message contains the search term (case-insensitive).
+// Most recently checked sensors are listed first.
+// On large installations a trigram index speeds this up:
+// CREATE INDEX ON prtg_sensor USING gin (message gin_trgm_ops).
+func (db *DB) SearchMessages(ctx context.Context, searchTerm string, limit int) ([]types.Sensor, error) {
+	if limit <= 0 {
+		limit = 50
+	}
+
+	query := `
+		SELECT
+			s.id,
+			s.prtg_server_address_id,
+			s.name,
+			s.sensor_type,
+			s.prtg_device_id,
+			d.name AS device_name,
+			d.host AS device_host,
+			s.scanning_interval_seconds,
+			s.status,
+			s.last_check_utc,
+			s.last_up_utc,
+			s.last_down_utc,
+			s.priority,
+			s.message,
+			s.uptime_since_seconds,
+			s.downtime_since_seconds,
+			sp.path AS full_path,
+			'' AS tags
+		FROM prtg_sensor s
+		INNER JOIN prtg_device d ON s.prtg_device_id = d.id
+			AND s.prtg_server_address_id = d.prtg_server_address_id
+		INNER JOIN prtg_sensor_path sp ON s.id = sp.sensor_id
+			AND s.prtg_server_address_id = sp.prtg_server_address_id
+		WHERE s.message ILIKE $1
+		ORDER BY s.last_check_utc DESC NULLS LAST, s.name
+		LIMIT $2
+	`
+
+	rows, err := db.Query(ctx, query, "%"+searchTerm+"%", limit)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get devices: %w", err)
+		return nil, fmt.Errorf("query failed: %w", err)
 	}
+	defer rows.Close()
 
-	// Build device nodes
-	for _, device := range devices {
-		deviceNode := types.HierarchyDevice{
-			Device:  device,
-			Sensors: []types.Sensor{},
-		}
-
-		// Get sensors if requested
-		if includeSensors {
-			sensorsQuery := `
-				SELECT
-					s.id,
-					s.prtg_server_address_id,
-					s.name,
-					s.sensor_type,
-					s.prtg_device_id,
-					$2 AS device_name,
-					s.scanning_interval_seconds,
-					s.status,
-					s.last_check_utc,
-					s.last_up_utc,
-					s.last_down_utc,
-					s.priority,
-					s.message,
-					s.uptime_since_seconds,
-					s.downtime_since_seconds,
-					sp.path AS full_path,
-					'' AS tags
-				FROM prtg_sensor s
-				INNER JOIN prtg_sensor_path sp ON s.id = sp.sensor_id
-					AND s.prtg_server_address_id = sp.prtg_server_address_id
-				WHERE s.prtg_device_id = $1
-				AND s.prtg_server_address_id = $3
-				ORDER BY s.name
-				LIMIT 50
-			`
+	return scanSensors(rows)
+}
 
-			rows, err := db.Query(ctx, sensorsQuery, device.ID, device.Name, device.ServerID)
-			if err != nil {
-				return nil, fmt.Errorf("failed to get sensors: %w", err)
-			}
+// maxMessageSamples is the number of sensor names listed with each message by GetMessageFrequency.
+const maxMessageSamples = 3
 
-			sensors, err := scanSensors(rows)
-			rows.Close()
+// GetMessageFrequency groups the sensors in a problem status (see types.ProblemStatuses) by
+// status message, most common message
+// first, so that one underlying error shared by many sensors shows as a single line. Messages are
+// compared after trimming whitespace; sensors without message are grouped under an empty message.
+// With hours > 0, only sensors checked within the last hours are counted.
+func (db *DB) GetMessageFrequency(ctx context.Context, hours, limit int) ([]types.MessageCount, error) {
+	if limit <= 0 {
+		limit = 20
+	}
 
-			if err != nil {
-				return nil, fmt.Errorf("failed to scan sensors: %w", err)
-			}
+	var conditions sensorFilterBuilder
 
-			deviceNode.Sensors = sensors
-		}
+	// Paused and unknown sensors are not failing, their messages would drown the real errors
+	conditions.where("s.status IN (" + joinInts(types.ProblemStatuses) + ")")
 
-		node.Devices = append(node.Devices, deviceNode)
+	if hours > 0 {
+		conditions.where("s.last_check_utc >= NOW() - (%s || ' hours')::interval", hours)
 	}
 
-	// Get child groups
-	childGroups, err := db.GetGroups(ctx, "", &group.ID, 50)
+	query := fmt.Sprintf(`
+		SELECT
+			BTRIM(COALESCE(s.message, '')) AS message,
+			COUNT(*) AS sensor_count,
+			COUNT(DISTINCT (s.prtg_server_address_id, s.prtg_device_id)) AS device_count,
+			(ARRAY_AGG(d.name || ' / ' || s.name ORDER BY s.priority DESC, d.name, s.name))[1:%d] AS sample_sensors
+		FROM prtg_sensor s
+		INNER JOIN prtg_device d ON s.prtg_device_id = d.id
+			AND s.prtg_server_address_id = d.prtg_server_address_id
+		%s
+		GROUP BY 1
+		ORDER BY sensor_count DESC, message
+		LIMIT %s
+	`, maxMessageSamples, conditions.whereClause(), conditions.arg(limit))
+
+	rows, err := db.Query(ctx, query, conditions.args...)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get child groups: %w", err)
+		return nil, fmt.Errorf("message frequency query failed: %w", err)
 	}
+	defer rows.Close()
 
-	// Recursively build child nodes
-	for i := range childGroups {
-		childNode, err := db.buildHierarchyNode(ctx, &childGroups[i], includeSensors, maxDepth, currentDepth+1)
-		if err != nil {
-			return nil, err
+	messages := []types.MessageCount{}
+
+	for rows.Next() {
+		var message types.MessageCount
+		if err := rows.Scan(&message.Message, &message.SensorCount, &message.DeviceCount, pq.Array(&message.SampleSensors)); err != nil {
+			return nil, fmt.Errorf("message frequency scan failed: %w", err)
 		}
-		node.Groups = append(node.Groups, childNode)
+
+		messages = append(messages, message)
 	}
 
-	return node, nil
+	return messages, rows.Err()
 }
 
-// Search performs a universal search across groups, devices, and sensors.
-// Returns matching results organized by type.
-func (db *DB) Search(ctx context.Context, searchTerm string, limit int) (*types.SearchResults, error) {
+// searchClauses holds the per-category WHERE and ORDER BY clauses of a universal search, and
+// the arguments they refer to. The limit is bound after them as the last query parameter.
+type searchClauses struct {
+	groupWhere  string
+	groupOrder  string
+	deviceWhere string
+	deviceOrder string
+	sensorWhere string
+	sensorOrder string
+	params      sensorFilterBuilder
+}
+
+// runSearch executes a universal search across groups, devices, and sensors with the given clauses.
+// A category whose query fails is reported in the results' Errors and the other categories are
+// still returned; an error is only returned when every category failed.
+func (db *DB) runSearch(ctx context.Context, clauses searchClauses, limit int) (*types.SearchResults, error) {
 	if limit <= 0 {
 		limit = 50
 	}
@@ -940,8 +2132,47 @@ func (db *DB) Search(ctx context.Context, searchTerm string, limit int) (*types.
 		Sensors: []types.Sensor{},
 	}
 
-	// Search in groups
-	groupQuery := `
+	params := clauses.params
+	limitPlaceholder := params.arg(limit)
+	args := params.args
+
+	var errs []error
+
+	fail := func(category string, err error) {
+		db.logger.Warn().Err(err).Str("category", category).Msg("search category failed, returning partial results")
+
+		errs = append(errs, err)
+		results.Errors = append(results.Errors, types.SearchError{Category: category, Error: err.Error()})
+	}
+
+	if groups, err := db.searchGroups(ctx, clauses, limitPlaceholder, args); err != nil {
+		fail("groups", err)
+	} else {
+		results.Groups = groups
+	}
+
+	if devices, err := db.searchDevices(ctx, clauses, limitPlaceholder, args); err != nil {
+		fail("devices", err)
+	} else {
+		results.Devices = devices
+	}
+
+	if sensors, err := db.searchSensors(ctx, clauses, limitPlaceholder, args); err != nil {
+		fail("sensors", err)
+	} else {
+		results.Sensors = sensors
+	}
+
+	if len(errs) == 3 {
+		return nil, errors.Join(errs...)
+	}
+
+	return results, nil
+}
+
+// searchGroups runs the group query of a universal search.
+func (db *DB) searchGroups(ctx context.Context, clauses searchClauses, limitPlaceholder string, args []interface{}) ([]types.Group, error) {
+	groupQuery := fmt.Sprintf(`
 		SELECT
 			g.id,
 			g.prtg_server_address_id,
@@ -953,17 +2184,19 @@ func (db *DB) Search(ctx context.Context, searchTerm string, limit int) (*types.
 		FROM prtg_group g
 		INNER JOIN prtg_group_path gp ON g.id = gp.group_id
 			AND g.prtg_server_address_id = gp.prtg_server_address_id
-		WHERE g.name ILIKE $1
-		ORDER BY g.name
-		LIMIT $2
-	`
+		WHERE %s
+		ORDER BY %s
+		LIMIT %s
+	`, clauses.groupWhere, clauses.groupOrder, limitPlaceholder)
 
-	groupRows, err := db.Query(ctx, groupQuery, "%"+searchTerm+"%", limit)
+	groupRows, err := db.Query(ctx, groupQuery, args...)
 	if err != nil {
 		return nil, fmt.Errorf("group search failed: %w", err)
 	}
 	defer groupRows.Close()
 
+	groups := []types.Group{}
+
 	for groupRows.Next() {
 		var group types.Group
 		var parentID sql.NullInt32
@@ -986,15 +2219,15 @@ func (db *DB) Search(ctx context.Context, searchTerm string, limit int) (*types.
 			group.ParentID = &parentIDInt
 		}
 
-		results.Groups = append(results.Groups, group)
+		groups = append(groups, group)
 	}
 
-	if err := groupRows.Err(); err != nil {
-		return nil, err
-	}
+	return groups, groupRows.Err()
+}
 
-	// Search in devices
-	deviceQuery := `
+// searchDevices runs the device query of a universal search.
+func (db *DB) searchDevices(ctx context.Context, clauses searchClauses, limitPlaceholder string, args []interface{}) ([]types.Device, error) {
+	deviceQuery := fmt.Sprintf(`
 		SELECT
 			d.id,
 			d.prtg_server_address_id,
@@ -1015,17 +2248,19 @@ func (db *DB) Search(ctx context.Context, searchTerm string, limit int) (*types.
 			AND d.prtg_server_address_id = g.prtg_server_address_id
 		INNER JOIN prtg_device_path dp ON d.id = dp.device_id
 			AND d.prtg_server_address_id = dp.prtg_server_address_id
-		WHERE d.name ILIKE $1 OR d.host ILIKE $1
-		ORDER BY d.name
-		LIMIT $2
-	`
+		WHERE %s
+		ORDER BY %s
+		LIMIT %s
+	`, clauses.deviceWhere, clauses.deviceOrder, limitPlaceholder)
 
-	deviceRows, err := db.Query(ctx, deviceQuery, "%"+searchTerm+"%", limit)
+	deviceRows, err := db.Query(ctx, deviceQuery, args...)
 	if err != nil {
 		return nil, fmt.Errorf("device search failed: %w", err)
 	}
 	defer deviceRows.Close()
 
+	devices := []types.Device{}
+
 	for deviceRows.Next() {
 		var device types.Device
 
@@ -1044,15 +2279,15 @@ func (db *DB) Search(ctx context.Context, searchTerm string, limit int) (*types.
 			return nil, fmt.Errorf("device scan failed: %w", err)
 		}
 
-		results.Devices = append(results.Devices, device)
+		devices = append(devices, device)
 	}
 
-	if err := deviceRows.Err(); err != nil {
-		return nil, err
-	}
+	return devices, deviceRows.Err()
+}
 
-	// Search in sensors
-	sensorQuery := `
+// searchSensors runs the sensor query of a universal search.
+func (db *DB) searchSensors(ctx context.Context, clauses searchClauses, limitPlaceholder string, args []interface{}) ([]types.Sensor, error) {
+	sensorQuery := fmt.Sprintf(`
 		SELECT
 			s.id,
 			s.prtg_server_address_id,
@@ -1060,6 +2295,7 @@ func (db *DB) Search(ctx context.Context, searchTerm string, limit int) (*types.
 			s.sensor_type,
 			s.prtg_device_id,
 			d.name AS device_name,
+			d.host AS device_host,
 			s.scanning_interval_seconds,
 			s.status,
 			s.last_check_utc,
@@ -1076,29 +2312,26 @@ func (db *DB) Search(ctx context.Context, searchTerm string, limit int) (*types.
 			AND s.prtg_server_address_id = d.prtg_server_address_id
 		INNER JOIN prtg_sensor_path sp ON s.id = sp.sensor_id
 			AND s.prtg_server_address_id = sp.prtg_server_address_id
-		WHERE s.name ILIKE $1 OR s.sensor_type ILIKE $1
-		ORDER BY s.name
-		LIMIT $2
-	`
+		WHERE %s
+		ORDER BY %s
+		LIMIT %s
+	`, clauses.sensorWhere, clauses.sensorOrder, limitPlaceholder)
 
-	sensorRows, err := db.Query(ctx, sensorQuery, "%"+searchTerm+"%", limit)
+	sensorRows, err := db.Query(ctx, sensorQuery, args...)
 	if err != nil {
 		return nil, fmt.Errorf("sensor search failed: %w", err)
 	}
 	defer sensorRows.Close()
 
-	sensors, err := scanSensors(sensorRows)
-	if err != nil {
-		return nil, err
-	}
-
-	results.Sensors = sensors
-
-	return results, nil
+	return scanSensors(sensorRows)
 }
 
 // GetTags retrieves all PRTG tags matching the given filters.
-func (db *DB) GetTags(ctx context.Context, tagName string, limit int) ([]types.Tag, error) {
+func (db *DB) GetTags(ctx context.Context, filter types.TagFilter, limit int) ([]types.Tag, error) {
+	if err := filter.Validate(); err != nil {
+		return nil, err
+	}
+
 	if limit <= 0 {
 		limit = 100
 	}
@@ -1112,25 +2345,40 @@ func (db *DB) GetTags(ctx context.Context, tagName string, limit int) ([]types.T
 		FROM prtg_tag t
 		LEFT JOIN prtg_sensor_tag st ON t.id = st.prtg_tag_id
 			AND t.prtg_server_address_id = st.prtg_server_address_id
-		WHERE 1=1
 	`
 
-	args := []interface{}{}
-	argPos := 1
+	var conditions sensorFilterBuilder
 
-	if tagName != "" {
-		query += fmt.Sprintf(" AND t.name ILIKE $%d", argPos)
-		args = append(args, "%"+tagName+"%")
-		argPos++
+	if filter.Name != "" {
+		conditions.where("t.name ILIKE %s", "%"+filter.Name+"%")
 	}
 
-	query += ` GROUP BY t.id, t.prtg_server_address_id, t.name
-		ORDER BY t.name`
+	query += conditions.whereClause() + ` GROUP BY t.id, t.prtg_server_address_id, t.name`
 
-	query += fmt.Sprintf(" LIMIT $%d", argPos)
-	args = append(args, limit)
+	// Sensor count range, applied to the aggregate
+	having := []string{}
 
-	rows, err := db.Query(ctx, query, args...)
+	if filter.MinSensorCount != nil {
+		having = append(having, "COUNT(DISTINCT st.prtg_sensor_id) >= "+conditions.arg(*filter.MinSensorCount))
+	}
+
+	if filter.MaxSensorCount != nil {
+		having = append(having, "COUNT(DISTINCT st.prtg_sensor_id) <= "+conditions.arg(*filter.MaxSensorCount))
+	}
+
+	if len(having) > 0 {
+		query += " HAVING " + strings.Join(having, " AND ")
+	}
+
+	if filter.OrderBy == types.TagOrderUsage {
+		query += " ORDER BY sensor_count DESC, t.name, t.id"
+	} else {
+		query += " ORDER BY t.name, t.id"
+	}
+
+	query += " LIMIT " + conditions.arg(limit)
+
+	rows, err := db.Query(ctx, query, conditions.args...)
 	if err != nil {
 		return nil, fmt.Errorf("query failed: %w", err)
 	}
@@ -1171,6 +2419,7 @@ func (db *DB) GetBusinessProcesses(ctx context.Context, processName string, stat
 			s.sensor_type,
 			s.prtg_device_id,
 			d.name as device_name,
+			COALESCE(d.host, '') AS device_host,
 			s.scanning_interval_seconds,
 			s.status,
 			s.last_check_utc,
@@ -1194,30 +2443,23 @@ func (db *DB) GetBusinessProcesses(ctx context.Context, processName string, stat
 			AND s.prtg_server_address_id = sp.prtg_server_address_id
 		LEFT JOIN prtg_device d ON s.prtg_device_id = d.id
 			AND s.prtg_server_address_id = d.prtg_server_address_id
-		WHERE s.sensor_type ILIKE '%business%process%'
 	`
 
-	args := []interface{}{}
-	argPos := 1
+	var conditions sensorFilterBuilder
+
+	conditions.where("s.sensor_type ILIKE '%business%process%'")
 
 	if processName != "" {
-		query += fmt.Sprintf(" AND s.name ILIKE $%d", argPos)
-		args = append(args, "%"+processName+"%")
-		argPos++
+		conditions.where("s.name ILIKE %s", "%"+processName+"%")
 	}
 
 	if status != nil {
-		query += fmt.Sprintf(" AND s.status = $%d", argPos)
-		args = append(args, *status)
-		argPos++
+		conditions.where("s.status = %s", *status)
 	}
 
-	query += ` ORDER BY s.priority DESC, s.name`
-
-	query += fmt.Sprintf(" LIMIT $%d", argPos)
-	args = append(args, limit)
+	query += conditions.whereClause() + ` ORDER BY s.priority DESC, s.name LIMIT ` + conditions.arg(limit)
 
-	rows, err := db.Query(ctx, query, args...)
+	rows, err := db.Query(ctx, query, conditions.args...)
 	if err != nil {
 		return nil, fmt.Errorf("query failed: %w", err)
 	}
@@ -1230,10 +2472,29 @@ func (db *DB) GetBusinessProcesses(ctx context.Context, processName string, stat
 // Uses PostgreSQL table statistics (pg_class.reltuples) for fast row count estimates
 // instead of exact COUNT(*) to prevent timeouts on large databases (100k+ rows).
 // The estimates are updated by ANALYZE/VACUUM and are accurate enough for dashboard statistics.
+// All queries run under the statement timeout, if configured.
 func (db *DB) GetStatistics(ctx context.Context) (*types.Statistics, error) {
+	var stats *types.Statistics
+
+	err := db.withStatementTimeout(ctx, func(ctx context.Context) error {
+		var err error
+		stats, err = db.getStatistics(ctx)
+
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return stats, nil
+}
+
+// getStatistics implements GetStatistics.
+func (db *DB) getStatistics(ctx context.Context) (*types.Statistics, error) {
 	stats := &types.Statistics{
-		SensorsByStatus: make(map[string]int),
-		TopSensorTypes:  []types.SensorTypeCount{},
+		SensorsByStatus:    make(map[string]int),
+		TopSensorTypes:     []types.SensorTypeCount{},
+		ProblemSensorTypes: []types.SensorTypeStatusCount{},
 	}
 
 	// Get total counts - optimized query using table statistics
@@ -1282,39 +2543,302 @@ func (db *DB) GetStatistics(ctx context.Context) (*types.Statistics, error) {
 		if err := statusRows.Scan(&status, &count); err != nil {
 			return nil, fmt.Errorf("status scan failed: %w", err)
 		}
-		statusText := types.GetStatusText(status)
-		stats.SensorsByStatus[statusText] = count
+		// Undocumented codes are summed under "Other" rather than reported as Unknown (1)
+		statusText := "Other"
+		if types.IsKnownStatus(status) {
+			statusText = types.GetStatusText(status)
+		}
+		stats.SensorsByStatus[statusText] += count
 	}
 
 	// Get top sensor types
-	typeQuery := `
-		SELECT sensor_type, COUNT(*) as count
+	topTypes, err := db.GetSensorTypes(ctx, topSensorTypesLimit)
+	if err != nil {
+		return nil, err
+	}
+	stats.TopSensorTypes = topTypes
+
+	// Get sensor types with the most problems
+	problemTypes, err := db.getSensorTypeStatusBreakdown(ctx, problemSensorTypesLimit)
+	if err != nil {
+		return nil, err
+	}
+	stats.ProblemSensorTypes = problemTypes
+
+	return stats, nil
+}
+
+// topSensorTypesLimit is the number of sensor types returned in the statistics.
+const topSensorTypesLimit = 15
+
+// problemSensorTypesLimit is the number of sensor types returned in the problem breakdown.
+const problemSensorTypesLimit = 10
+
+// GetSensorTypes returns the distinct sensor types stored by the exporter with their sensor counts,
+// most common first. The values are exact, so they can be reused as sensor_type filters.
+func (db *DB) GetSensorTypes(ctx context.Context, limit int) ([]types.SensorTypeCount, error) {
+	if limit <= 0 {
+		limit = 100
+	}
+
+	query := `
+		SELECT sensor_type, COUNT(*) AS count
 		FROM prtg_sensor
 		WHERE sensor_type IS NOT NULL AND sensor_type != ''
 		GROUP BY sensor_type
-		ORDER BY count DESC
-		LIMIT 15
+		ORDER BY count DESC, sensor_type
+		LIMIT $1
 	`
 
-	typeRows, err := db.Query(ctx, typeQuery)
+	rows, err := db.Query(ctx, query, limit)
 	if err != nil {
 		return nil, fmt.Errorf("sensor type query failed: %w", err)
 	}
-	defer typeRows.Close()
+	defer rows.Close()
 
-	for typeRows.Next() {
-		var sensorType string
-		var count int
-		if err := typeRows.Scan(&sensorType, &count); err != nil {
+	sensorTypes := []types.SensorTypeCount{}
+
+	for rows.Next() {
+		var sensorType types.SensorTypeCount
+		if err := rows.Scan(&sensorType.Type, &sensorType.Count); err != nil {
 			return nil, fmt.Errorf("sensor type scan failed: %w", err)
 		}
-		stats.TopSensorTypes = append(stats.TopSensorTypes, types.SensorTypeCount{
-			Type:  sensorType,
-			Count: count,
-		})
+
+		sensorTypes = append(sensorTypes, sensorType)
 	}
 
-	return stats, nil
+	return sensorTypes, rows.Err()
+}
+
+// getSensorTypeStatusBreakdown returns per sensor type the count of down, warning and up sensors,
+// limited to the top N types by problem count (down + warning). Types without problems are omitted.
+func (db *DB) getSensorTypeStatusBreakdown(ctx context.Context, limit int) ([]types.SensorTypeStatusCount, error) {
+	downStatuses := []int{types.StatusDown, types.StatusDownPartial, types.StatusDownAcknowledged}
+	warningStatuses := []int{types.StatusWarning, types.StatusUnusual}
+
+	query := `
+		SELECT
+			sensor_type,
+			COUNT(*) FILTER (WHERE status = ANY($1)) as down_count,
+			COUNT(*) FILTER (WHERE status = ANY($2)) as warning_count,
+			COUNT(*) FILTER (WHERE status = $3) as up_count,
+			COUNT(*) as total_count
+		FROM prtg_sensor
+		WHERE sensor_type IS NOT NULL AND sensor_type != ''
+		GROUP BY sensor_type
+		HAVING COUNT(*) FILTER (WHERE status = ANY($1) OR status = ANY($2)) > 0
+		ORDER BY COUNT(*) FILTER (WHERE status = ANY($1) OR status = ANY($2)) DESC,
+			down_count DESC,
+			sensor_type
+		LIMIT $4
+	`
+
+	rows, err := db.Query(ctx, query, pq.Array(downStatuses), pq.Array(warningStatuses), types.StatusUp, limit)
+	if err != nil {
+		return nil, fmt.Errorf("sensor type status query failed: %w", err)
+	}
+	defer rows.Close()
+
+	breakdown := []types.SensorTypeStatusCount{}
+
+	for rows.Next() {
+		var entry types.SensorTypeStatusCount
+		if err := rows.Scan(&entry.Type, &entry.Down, &entry.Warning, &entry.Up, &entry.Total); err != nil {
+			return nil, fmt.Errorf("sensor type status scan failed: %w", err)
+		}
+		breakdown = append(breakdown, entry)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("sensor type status rows error: %w", err)
+	}
+
+	return breakdown, nil
+}
+
+// historyMaxRows caps the rows read by GetSensorHistoryFromDB (one row per channel and timestamp).
+// The newest rows are kept.
+const historyMaxRows = 100000
+
+// GetSensorHistoryFromDB reads the channel values of a sensor between start and end (inclusive)
+// from the history table set with SetHistoryTable, e.g. a TimescaleDB hypertable filled by the
+// exporter. The table has one row per channel and timestamp:
+// prtg_sensor_id, channel_name, value, timestamp_utc.
+// Rows are pivoted into the same shape as the PRTG API time series: one data point per
+// timestamp, with one value per channel, oldest first. When the range holds more than
+// historyMaxRows rows, the newest are returned and Truncated is set. Runs under the statement
+// timeout, if configured.
+func (db *DB) GetSensorHistoryFromDB(ctx context.Context, sensorID int, start, end time.Time) (*prtg.TimeSeriesData, error) {
+	table := db.quotedHistoryTable()
+	if table == "" {
+		return nil, fmt.Errorf("no history table configured")
+	}
+
+	query := fmt.Sprintf(`
+		SELECT timestamp_utc, channel_name, value
+		FROM %s
+		WHERE prtg_sensor_id = $1
+		AND timestamp_utc >= $2
+		AND timestamp_utc <= $3
+		ORDER BY timestamp_utc DESC, channel_name
+		LIMIT $4
+	`, table)
+
+	start, end = start.UTC(), end.UTC()
+	data := &prtg.TimeSeriesData{
+		ObjectID:   sensorID,
+		StartTime:  &start,
+		EndTime:    &end,
+		Headers:    []string{"timestamp"},
+		DataPoints: []prtg.TimeSeriesDataPoint{},
+	}
+
+	err := db.withStatementTimeout(ctx, func(ctx context.Context) error {
+		// One row past the cap tells whether older rows were left out
+		rows, err := db.Query(ctx, query, sensorID, start, end, historyMaxRows+1)
+		if err != nil {
+			return fmt.Errorf("history query failed: %w", err)
+		}
+		defer rows.Close()
+
+		channels := make(map[string]bool)
+		count := 0
+
+		for rows.Next() {
+			var timestamp time.Time
+
+			var channel string
+
+			var value sql.NullFloat64
+
+			if err := rows.Scan(&timestamp, &channel, &value); err != nil {
+				return fmt.Errorf("scan failed: %w", err)
+			}
+
+			count++
+			if count > historyMaxRows {
+				data.Truncated = true
+
+				// The cap cut the oldest timestamp read between its channels: drop it rather
+				// than return a partial data point
+				if last := len(data.DataPoints) - 1; data.DataPoints[last].Timestamp.Equal(timestamp) {
+					data.DataPoints = data.DataPoints[:last]
+				}
+
+				break
+			}
+
+			if !channels[channel] {
+				channels[channel] = true
+				data.Headers = append(data.Headers, channel)
+			}
+
+			// Rows are ordered by timestamp: a new timestamp starts a new data point
+			last := len(data.DataPoints) - 1
+			if last < 0 || !data.DataPoints[last].Timestamp.Equal(timestamp) {
+				data.DataPoints = append(data.DataPoints, prtg.TimeSeriesDataPoint{
+					Timestamp: timestamp.UTC(),
+					Values:    make(map[string]interface{}),
+				})
+				last++
+			}
+
+			if value.Valid {
+				data.DataPoints[last].Values[channel] = value.Float64
+			}
+		}
+
+		if data.Truncated {
+			db.logger.Warn().
+				Int("sensor_id", sensorID).
+				Int("max_rows", historyMaxRows).
+				Msg("history query reached the row limit, earlier data points are missing")
+		}
+
+		return rows.Err()
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// Rows were read newest first, data points are returned oldest first
+	slices.Reverse(data.DataPoints)
+
+	// Channels in a stable order, timestamp first
+	sort.Strings(data.Headers[1:])
+
+	return data, nil
+}
+
+// GetLatestChannelValues retrieves the latest value of each sensor channel recorded in the
+// history table since the given time, ordered by sensor ID and channel name (limit 0 = all).
+// Channels without a value are skipped. Returns an error when no history table is configured.
+func (db *DB) GetLatestChannelValues(ctx context.Context, since time.Time, limit int) ([]types.ChannelValue, error) {
+	table := db.quotedHistoryTable()
+	if table == "" {
+		return nil, fmt.Errorf("no history table configured")
+	}
+
+	query := fmt.Sprintf(`
+		SELECT DISTINCT ON (prtg_sensor_id, channel_name)
+			prtg_sensor_id, channel_name, value, timestamp_utc
+		FROM %s
+		WHERE timestamp_utc >= $1
+		AND value IS NOT NULL
+		ORDER BY prtg_sensor_id, channel_name, timestamp_utc DESC
+	`, table)
+
+	args := []interface{}{since.UTC()}
+	if limit > 0 {
+		query += " LIMIT $2"
+		args = append(args, limit)
+	}
+
+	values := []types.ChannelValue{}
+
+	err := db.withStatementTimeout(ctx, func(ctx context.Context) error {
+		rows, err := db.Query(ctx, query, args...)
+		if err != nil {
+			return fmt.Errorf("query failed: %w", err)
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			var value types.ChannelValue
+			if err := rows.Scan(&value.SensorID, &value.Channel, &value.Value, &value.Timestamp); err != nil {
+				return fmt.Errorf("scan failed: %w", err)
+			}
+
+			values = append(values, value)
+		}
+
+		return rows.Err()
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return values, nil
+}
+
+// quotedHistoryTable returns the configured history table quoted for use in a query,
+// or "" when none is configured.
+func (db *DB) quotedHistoryTable() string {
+	db.mu.RLock()
+	table := db.historyTable
+	db.mu.RUnlock()
+
+	if table == "" {
+		return ""
+	}
+
+	// The name is a validated identifier; quote each part so it is used as-is
+	parts := strings.Split(table, ".")
+	for i, part := range parts {
+		parts[i] = pq.QuoteIdentifier(part)
+	}
+
+	return strings.Join(parts, ".")
 }
 
 // scanSensors is a helper function to scan sensor rows.
@@ -1337,6 +2861,7 @@ func scanSensors(rows *sql.Rows) ([]types.Sensor, error) {
 			&sensor.SensorType,
 			&sensor.DeviceID,
 			&sensor.DeviceName,
+			&sensor.DeviceHost,
 			&sensor.ScanningIntervalSecs,
 			&sensor.Status,
 			&lastCheckUTC,
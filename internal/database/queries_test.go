@@ -3,11 +3,16 @@ package database
 import (
 	"context"
 	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
 	"regexp"
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/lib/pq"
 	"github.com/matthieu/mcp-server-prtg/internal/types"
 	"github.com/rs/zerolog"
 	"github.com/stretchr/testify/assert"
@@ -36,7 +41,7 @@ func TestGetAlerts_SeveritySorting(t *testing.T) {
 	// Setup mock expectations - columns must match actual query
 	columns := []string{
 		"id", "prtg_server_address_id", "name", "sensor_type", "prtg_device_id",
-		"device_name", "scanning_interval_seconds", "status", "last_check_utc",
+		"device_name", "device_host", "scanning_interval_seconds", "status", "last_check_utc",
 		"last_up_utc", "last_down_utc", "priority", "message",
 		"uptime_since_seconds", "downtime_since_seconds", "full_path", "tags",
 	}
@@ -47,15 +52,15 @@ func TestGetAlerts_SeveritySorting(t *testing.T) {
 	// So we must return them in the EXPECTED order (after ORDER BY CASE sorting)
 	// Expected order: Down (5), Warning (4), Unusual (10)
 	mock.ExpectQuery(expectedQuery).
-		WithArgs(types.StatusUp, 24).
+		WithArgs(types.StatusUp, 24, types.AlertsLimit, 0).
 		WillReturnRows(sqlmock.NewRows(columns).
-			AddRow(2, 1, "Sensor Down", "ping", 100, "Device1", 60, 5, now, now, &now, 5, "Timeout", nil, 100.0, "/root/device1/sensor2", "critical").
-			AddRow(1, 1, "Sensor Warning", "ping", 100, "Device1", 60, 4, now, now, nil, 3, "High CPU", nil, nil, "/root/device1/sensor1", "").
-			AddRow(3, 1, "Sensor Unusual", "http", 101, "Device2", 120, 10, now, now, nil, 1, "Spike detected", nil, nil, "/root/device2/sensor3", ""))
+			AddRow(2, 1, "Sensor Down", "ping", 100, "Device1", "", 60, 5, now, now, &now, 5, "Timeout", nil, 100.0, "/root/device1/sensor2", "critical").
+			AddRow(1, 1, "Sensor Warning", "ping", 100, "Device1", "", 60, 4, now, now, nil, 3, "High CPU", nil, nil, "/root/device1/sensor1", "").
+			AddRow(3, 1, "Sensor Unusual", "http", 101, "Device2", "", 120, 10, now, now, nil, 1, "Spike detected", nil, nil, "/root/device2/sensor3", ""))
 
 	// Execute query
 	ctx := context.Background()
-	sensors, err := db.GetAlerts(ctx, 24, nil, "")
+	sensors, err := db.GetAlerts(ctx, types.AlertFilter{Hours: 24}, 0, 0)
 
 	// Assertions
 	require.NoError(t, err)
@@ -96,7 +101,7 @@ func TestGetAlerts_FilterByStatus(t *testing.T) {
 
 	columns := []string{
 		"id", "prtg_server_address_id", "name", "sensor_type", "prtg_device_id",
-		"device_name", "scanning_interval_seconds", "status", "last_check_utc",
+		"device_name", "device_host", "scanning_interval_seconds", "status", "last_check_utc",
 		"last_up_utc", "last_down_utc", "priority", "message",
 		"uptime_since_seconds", "downtime_since_seconds", "full_path", "tags",
 	}
@@ -105,12 +110,12 @@ func TestGetAlerts_FilterByStatus(t *testing.T) {
 
 	// Arguments order: $1=status to exclude, $2=hours, $3=status filter
 	mock.ExpectQuery(expectedQuery).
-		WithArgs(types.StatusUp, 24, downStatus).
+		WithArgs(types.StatusUp, 24, downStatus, types.AlertsLimit, 0).
 		WillReturnRows(sqlmock.NewRows(columns).
-			AddRow(1, 1, "Sensor Down", "ping", 100, "Device1", 60, types.StatusDown, now, now, &now, 5, "Timeout", nil, 100.0, "/root/device1/sensor", "critical"))
+			AddRow(1, 1, "Sensor Down", "ping", 100, "Device1", "", 60, types.StatusDown, now, now, &now, 5, "Timeout", nil, 100.0, "/root/device1/sensor", "critical"))
 
 	ctx := context.Background()
-	sensors, err := db.GetAlerts(ctx, 24, &downStatus, "")
+	sensors, err := db.GetAlerts(ctx, types.AlertFilter{Hours: 24, Status: &downStatus}, 0, 0)
 
 	require.NoError(t, err)
 	assert.Len(t, sensors, 1)
@@ -138,7 +143,7 @@ func TestGetAlerts_FilterByDeviceName(t *testing.T) {
 
 	columns := []string{
 		"id", "prtg_server_address_id", "name", "sensor_type", "prtg_device_id",
-		"device_name", "scanning_interval_seconds", "status", "last_check_utc",
+		"device_name", "device_host", "scanning_interval_seconds", "status", "last_check_utc",
 		"last_up_utc", "last_down_utc", "priority", "message",
 		"uptime_since_seconds", "downtime_since_seconds", "full_path", "tags",
 	}
@@ -147,12 +152,12 @@ func TestGetAlerts_FilterByDeviceName(t *testing.T) {
 
 	// Correct argument order: status, hours, device_name
 	mock.ExpectQuery(expectedQuery).
-		WithArgs(types.StatusUp, 24, "%server1%").
+		WithArgs(types.StatusUp, 24, "%server1%", types.AlertsLimit, 0).
 		WillReturnRows(sqlmock.NewRows(columns).
-			AddRow(1, 1, "CPU Sensor", "wmi", 100, "Server1", 60, types.StatusWarning, now, now, nil, 3, "High load", nil, nil, "/root/server1/cpu", ""))
+			AddRow(1, 1, "CPU Sensor", "wmi", 100, "Server1", "", 60, types.StatusWarning, now, now, nil, 3, "High load", nil, nil, "/root/server1/cpu", ""))
 
 	ctx := context.Background()
-	sensors, err := db.GetAlerts(ctx, 24, nil, "server1")
+	sensors, err := db.GetAlerts(ctx, types.AlertFilter{Hours: 24, DeviceName: "server1"}, 0, 0)
 
 	require.NoError(t, err)
 	assert.Len(t, sensors, 1)
@@ -162,6 +167,55 @@ func TestGetAlerts_FilterByDeviceName(t *testing.T) {
 	assert.NoError(t, mock.ExpectationsWereMet())
 }
 
+// TestGetAlerts_MinPriority validates the priority floor and its composition with the hours window,
+// in both the listing and the count query.
+func TestGetAlerts_MinPriority(t *testing.T) {
+	mockDB, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer mockDB.Close()
+
+	logger := zerolog.Nop()
+	db := &DB{
+		conn:   mockDB,
+		logger: &logger,
+	}
+
+	columns := []string{
+		"id", "prtg_server_address_id", "name", "sensor_type", "prtg_device_id",
+		"device_name", "device_host", "scanning_interval_seconds", "status", "last_check_utc",
+		"last_up_utc", "last_down_utc", "priority", "message",
+		"uptime_since_seconds", "downtime_since_seconds", "full_path", "tags",
+	}
+
+	now := time.Now()
+	minPriority := 4
+
+	// Arguments order: $1=status to exclude, $2=hours, $3=priority floor, $4=device name pattern
+	expectedQuery := `WHERE s\.status != \$1 AND s\.last_check_utc >= NOW\(\) - \(\$2 \|\| ' hours'\)::interval ` +
+		`AND s\.priority >= \$3 AND d\.name ILIKE \$4 ORDER BY`
+
+	mock.ExpectQuery(expectedQuery).
+		WithArgs(types.StatusUp, 6, minPriority, "%core%", types.AlertsLimit, 0).
+		WillReturnRows(sqlmock.NewRows(columns).
+			AddRow(1, 1, "Uplink", "snmptraffic", 100, "core-01", "", 60, types.StatusDown, now, now, &now, 5, "No route", nil, 60.0, "/root/core-01/uplink", ""))
+
+	sensors, err := db.GetAlerts(context.Background(), types.AlertFilter{Hours: 6, MinPriority: &minPriority, DeviceName: "core"}, 0, 0)
+	require.NoError(t, err)
+	require.Len(t, sensors, 1)
+	assert.Equal(t, 5, sensors[0].Priority)
+
+	// Without a time window the floor takes $2
+	mock.ExpectQuery(`SELECT COUNT\(\*\)[\s\S]+WHERE s\.status != \$1 AND s\.priority >= \$2$`).
+		WithArgs(types.StatusUp, minPriority).
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(12))
+
+	count, err := db.CountAlerts(context.Background(), types.AlertFilter{MinPriority: &minPriority})
+	require.NoError(t, err)
+	assert.Equal(t, 12, count)
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
 // TestGetSensors_AllFilters validates that all filters work correctly together.
 func TestGetSensors_AllFilters(t *testing.T) {
 	mockDB, mock, err := sqlmock.New()
@@ -181,7 +235,7 @@ func TestGetSensors_AllFilters(t *testing.T) {
 
 	columns := []string{
 		"id", "prtg_server_address_id", "name", "sensor_type", "prtg_device_id",
-		"device_name", "scanning_interval_seconds", "status", "last_check_utc",
+		"device_name", "device_host", "scanning_interval_seconds", "status", "last_check_utc",
 		"last_up_utc", "last_down_utc", "priority", "message",
 		"uptime_since_seconds", "downtime_since_seconds", "full_path", "tags",
 	}
@@ -191,7 +245,7 @@ func TestGetSensors_AllFilters(t *testing.T) {
 	mock.ExpectQuery(expectedQuery).
 		WithArgs("%router%", "%ping%", downStatus, 1000).
 		WillReturnRows(sqlmock.NewRows(columns).
-			AddRow(1, 1, "Ping Sensor", "ping", 100, "Router1", 60, types.StatusDown, now, now, &now, 5, "Timeout", nil, 300.0, "/root/network/router1/ping", "critical,network"))
+			AddRow(1, 1, "Ping Sensor", "ping", 100, "Router1", "", 60, types.StatusDown, now, now, &now, 5, "Timeout", nil, 300.0, "/root/network/router1/ping", "critical,network"))
 
 	ctx := context.Background()
 	sensors, err := db.GetSensors(ctx, "router", "ping", &downStatus, "", 1000)
@@ -206,9 +260,19 @@ func TestGetSensors_AllFilters(t *testing.T) {
 	assert.NoError(t, mock.ExpectationsWereMet())
 }
 
-// TestExecuteCustomQuery_SELECTOnly validates that only SELECT queries are allowed.
-func TestExecuteCustomQuery_SELECTOnly(t *testing.T) {
-	mockDB, mock, err := sqlmock.New()
+// TestCountSensors_NoRowScan validates that count_only issues a COUNT(*) query with the same filters
+// and never selects sensor columns.
+func TestCountSensors_NoRowScan(t *testing.T) {
+	mockDB, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherFunc(
+		func(expectedSQL, actualSQL string) error {
+			// A count query must not fetch sensor rows
+			if strings.Contains(actualSQL, "s.last_check_utc") || strings.Contains(actualSQL, "full_path") {
+				return fmt.Errorf("count query selects sensor columns: %s", actualSQL)
+			}
+
+			return sqlmock.QueryMatcherRegexp.Match(expectedSQL, actualSQL)
+		},
+	)))
 	require.NoError(t, err)
 	defer mockDB.Close()
 
@@ -218,87 +282,94 @@ func TestExecuteCustomQuery_SELECTOnly(t *testing.T) {
 		logger: &logger,
 	}
 
-	tests := []struct {
-		name        string
-		query       string
-		shouldError bool
-		errorMsg    string
-	}{
-		{
-			name:        "Valid SELECT query",
-			query:       "SELECT * FROM prtg_sensor WHERE status = 5",
-			shouldError: false,
-		},
-		{
-			name:        "SELECT with subquery",
-			query:       "SELECT id FROM prtg_sensor WHERE id IN (SELECT sensor_id FROM prtg_sensor_tag)",
-			shouldError: false,
-		},
-		{
-			name:        "DROP table attempt",
-			query:       "DROP TABLE prtg_sensor",
-			shouldError: true,
-			errorMsg:    "only SELECT queries are allowed",
-		},
-		{
-			name:        "DELETE attempt",
-			query:       "DELETE FROM prtg_sensor WHERE id = 1",
-			shouldError: true,
-			errorMsg:    "only SELECT queries are allowed",
-		},
-		{
-			name:        "UPDATE attempt",
-			query:       "UPDATE prtg_sensor SET status = 3 WHERE id = 1",
-			shouldError: true,
-			errorMsg:    "only SELECT queries are allowed",
-		},
-		{
-			name:        "INSERT attempt",
-			query:       "INSERT INTO prtg_sensor (name) VALUES ('hack')",
-			shouldError: true,
-			errorMsg:    "only SELECT queries are allowed",
-		},
-		{
-			name:        "SQL injection attempt with comment",
-			query:       "SELECT * FROM prtg_sensor; DROP TABLE users; --",
-			shouldError: true,
-			errorMsg:    "forbidden keyword",
-		},
-		{
-			name:        "SQL injection with semicolon",
-			query:       "SELECT * FROM prtg_sensor WHERE id = 1; DELETE FROM prtg_device",
-			shouldError: true,
-			errorMsg:    "forbidden keyword",
-		},
-	}
+	downStatus := types.StatusDown
 
-	for _, tt := range tests {
-		t.Run(tt.name, func(t *testing.T) {
-			if !tt.shouldError {
-				// Setup mock for valid SELECT
-				mock.ExpectQuery(regexp.QuoteMeta(tt.query)).
-					WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
-			}
+	expectedQuery := `^SELECT COUNT\(\*\)\s+FROM prtg_sensor s[\s\S]+WHERE d\.name ILIKE \$1 AND g\.name ILIKE \$2 AND s\.status = \$3$`
 
-			ctx := context.Background()
-			results, err := db.ExecuteCustomQuery(ctx, tt.query, 100)
+	mock.ExpectQuery(expectedQuery).
+		WithArgs("%router%", "%network%", downStatus).
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(42))
 
-			if tt.shouldError {
-				assert.Error(t, err)
-				assert.Contains(t, err.Error(), tt.errorMsg)
-				assert.Nil(t, results)
-			} else {
-				assert.NoError(t, err)
-				assert.NotNil(t, results)
-			}
-		})
+	count, err := db.CountSensors(context.Background(), types.SensorFilter{
+		DeviceName: "router",
+		GroupName:  "network",
+		Status:     &downStatus,
+	})
+
+	require.NoError(t, err)
+	assert.Equal(t, 42, count)
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// TestCountSensors_MatchesListingFilters validates that count and listing queries share the same WHERE clause.
+func TestCountSensors_MatchesListingFilters(t *testing.T) {
+	downStatus := types.StatusDown
+	filter := types.SensorFilter{
+		DeviceName: "router",
+		SensorName: "ping",
+		SensorType: "snmp",
+		GroupName:  "network",
+		Status:     &downStatus,
 	}
 
-	// Note: We don't check ExpectationsWereMet here because dangerous queries don't reach the DB
+	whereClause, args := buildSensorWhereClause(filter)
+
+	assert.Equal(t, "WHERE d.name ILIKE $1 AND s.name ILIKE $2 AND s.sensor_type ILIKE $3 AND g.name ILIKE $4 AND s.status = $5", whereClause)
+	assert.Equal(t, []interface{}{"%router%", "%ping%", "%snmp%", "%network%", downStatus}, args)
+
+	emptyClause, emptyArgs := buildSensorWhereClause(types.SensorFilter{})
+	assert.Empty(t, emptyClause)
+	assert.Empty(t, emptyArgs)
 }
 
-// TestGetSensorByID validates retrieval of a specific sensor.
-func TestGetSensorByID(t *testing.T) {
+// TestBuildSensorWhereClause_PriorityRange validates the priority BETWEEN clause and its default bounds.
+func TestBuildSensorWhereClause_PriorityRange(t *testing.T) {
+	downStatus := types.StatusDown
+	four, five, two := 4, 5, 2
+
+	whereClause, args := buildSensorWhereClause(types.SensorFilter{
+		Status:      &downStatus,
+		MinPriority: &four,
+		MaxPriority: &five,
+	})
+	assert.Equal(t, "WHERE s.status = $1 AND s.priority BETWEEN $2 AND $3", whereClause)
+	assert.Equal(t, []interface{}{downStatus, 4, 5}, args)
+
+	// A single bound defaults the other to the end of the 1-5 range
+	whereClause, args = buildSensorWhereClause(types.SensorFilter{MinPriority: &four})
+	assert.Equal(t, "WHERE s.priority BETWEEN $1 AND $2", whereClause)
+	assert.Equal(t, []interface{}{4, 5}, args)
+
+	_, args = buildSensorWhereClause(types.SensorFilter{MaxPriority: &two})
+	assert.Equal(t, []interface{}{1, 2}, args)
+}
+
+// TestBuildSensorWhereClause_PathPrefix validates that the path prefix matches literally below the path.
+func TestBuildSensorWhereClause_PathPrefix(t *testing.T) {
+	stale := 30
+
+	whereClause, args := buildSensorWhereClause(types.SensorFilter{StaleMinutes: stale, PathPrefix: "Root/Web_Servers 100%/"})
+	assert.Equal(t, "WHERE (s.last_check_utc IS NULL OR s.last_check_utc < NOW() - ($1 || ' minutes')::interval) AND sp.path LIKE $2", whereClause)
+	assert.Equal(t, []interface{}{30, `Root/Web\_Servers 100\%/%`}, args)
+}
+
+// TestBuildSensorWhereClause_ExcludePaused validates the paused and inactive status exclusions.
+func TestBuildSensorWhereClause_ExcludePaused(t *testing.T) {
+	whereClause, args := buildSensorWhereClause(types.SensorFilter{})
+	assert.Empty(t, whereClause)
+	assert.Empty(t, args)
+
+	whereClause, _ = buildSensorWhereClause(types.SensorFilter{ExcludePaused: true})
+	assert.Equal(t, "WHERE s.status NOT IN (7,8,9,11,12)", whereClause)
+
+	// active_only supersedes exclude_paused and also drops Unknown and Collecting
+	whereClause, _ = buildSensorWhereClause(types.SensorFilter{ExcludePaused: true, ActiveOnly: true})
+	assert.Equal(t, "WHERE s.status NOT IN (1,2,7,8,9,11,12)", whereClause)
+}
+
+// TestGetSensorsExtended_ExcludePaused validates that paused sensors are filtered only when requested.
+func TestGetSensorsExtended_ExcludePaused(t *testing.T) {
 	mockDB, mock, err := sqlmock.New()
 	require.NoError(t, err)
 	defer mockDB.Close()
@@ -310,40 +381,40 @@ func TestGetSensorByID(t *testing.T) {
 	}
 
 	columns := []string{
-		"id", "prtg_server_address_id", "name", "sensor_type", "prtg_device_id",
-		"device_name", "scanning_interval_seconds", "status", "last_check_utc",
-		"last_up_utc", "last_down_utc", "priority", "message",
-		"uptime_since_seconds", "downtime_since_seconds", "full_path", "tags",
+		"id", "prtg_server_address_id", "name", "sensor_type", "prtg_device_id", "device_name", "device_host",
+		"scanning_interval_seconds", "status", "last_check_utc", "last_up_utc", "last_down_utc",
+		"priority", "message", "uptime_since_seconds", "downtime_since_seconds", "full_path", "tags",
 	}
-
 	now := time.Now()
-	uptime := 3600.0 // 1 hour uptime
 
-	expectedQuery := `WHERE[\s\S]+s\.id = \$1`
-
-	mock.ExpectQuery(expectedQuery).
-		WithArgs(123).
+	// Without the flag, paused sensors are returned and no status exclusion is applied
+	mock.ExpectQuery(`g\.prtg_server_address_id\s+ORDER BY s\.name LIMIT \$1`).
+		WithArgs(50).
 		WillReturnRows(sqlmock.NewRows(columns).
-			AddRow(123, 1, "Test Sensor", "ping", 100, "Test Device", 60, types.StatusUp, now, now, nil, 3, "OK", &uptime, nil, "/root/test/sensor", "production"))
+			AddRow(1, 1, "Core Ping", "ping", 10, "core-sw", "", 60, types.StatusUp, now, now, nil, 3, "OK", nil, nil, "Root > Core", "").
+			AddRow(2, 1, "Lab Ping", "ping", 11, "lab-sw", "", 60, types.StatusPausedByUser, now, now, nil, 3, "Paused", nil, nil, "Root > Lab", ""))
 
-	ctx := context.Background()
-	sensor, err := db.GetSensorByID(ctx, 123)
+	sensors, err := db.GetSensorsExtended(context.Background(), types.SensorFilter{}, "name", 50)
+	require.NoError(t, err)
+	require.Len(t, sensors, 2)
+	assert.Equal(t, types.StatusPausedByUser, sensors[1].Status)
+
+	// With the flag, the paused statuses are excluded in SQL
+	mock.ExpectQuery(`WHERE s\.status NOT IN \(7,8,9,11,12\) ORDER BY s\.name LIMIT \$1`).
+		WithArgs(50).
+		WillReturnRows(sqlmock.NewRows(columns).
+			AddRow(1, 1, "Core Ping", "ping", 10, "core-sw", "", 60, types.StatusUp, now, now, nil, 3, "OK", nil, nil, "Root > Core", ""))
 
+	sensors, err = db.GetSensorsExtended(context.Background(), types.SensorFilter{ExcludePaused: true}, "name", 50)
 	require.NoError(t, err)
-	assert.NotNil(t, sensor)
-	assert.Equal(t, 123, sensor.ID)
-	assert.Equal(t, "Test Sensor", sensor.Name)
-	assert.Equal(t, "Test Device", sensor.DeviceName)
-	assert.Equal(t, types.StatusUp, sensor.Status)
-	assert.NotNil(t, sensor.UptimeSinceSecs)
-	assert.Equal(t, uptime, *sensor.UptimeSinceSecs)
-	assert.Nil(t, sensor.DowntimeSinceSecs)
+	require.Len(t, sensors, 1)
+	assert.Equal(t, types.StatusUp, sensors[0].Status)
 
 	assert.NoError(t, mock.ExpectationsWereMet())
 }
 
-// TestGetSensorByID_NotFound validates error handling when sensor doesn't exist.
-func TestGetSensorByID_NotFound(t *testing.T) {
+// TestGetSensorsExtended_ProblemOnly validates that problem_only keeps problem statuses and composes with device_name.
+func TestGetSensorsExtended_ProblemOnly(t *testing.T) {
 	mockDB, mock, err := sqlmock.New()
 	require.NoError(t, err)
 	defer mockDB.Close()
@@ -354,24 +425,38 @@ func TestGetSensorByID_NotFound(t *testing.T) {
 		logger: &logger,
 	}
 
-	expectedQuery := `WHERE[\s\S]+s\.id = \$1`
+	columns := []string{
+		"id", "prtg_server_address_id", "name", "sensor_type", "prtg_device_id", "device_name", "device_host",
+		"scanning_interval_seconds", "status", "last_check_utc", "last_up_utc", "last_down_utc",
+		"priority", "message", "uptime_since_seconds", "downtime_since_seconds", "full_path", "tags",
+	}
+	now := time.Now()
 
-	mock.ExpectQuery(expectedQuery).
-		WithArgs(999).
-		WillReturnError(sql.ErrNoRows)
+	whereClause, args := buildSensorWhereClause(types.SensorFilter{ProblemOnly: true})
+	assert.Equal(t, "WHERE s.status IN (4,5,10,13,14)", whereClause)
+	assert.Empty(t, args)
 
-	ctx := context.Background()
-	sensor, err := db.GetSensorByID(ctx, 999)
+	mock.ExpectQuery(`WHERE d\.name ILIKE \$1 AND s\.status IN \(4,5,10,13,14\) ORDER BY s\.name LIMIT \$2`).
+		WithArgs("%web01%", 50).
+		WillReturnRows(sqlmock.NewRows(columns).
+			AddRow(1, 1, "HTTP", "http", 10, "web01", "", 60, types.StatusDown, now, now, now, 4, "Timeout", nil, nil, "Root > Web", "").
+			AddRow(2, 1, "Disk", "wmidiskspace", 10, "web01", "", 300, types.StatusWarning, now, now, nil, 3, "90% used", nil, nil, "Root > Web", "").
+			AddRow(3, 1, "CPU", "wmicpu", 10, "web01", "", 60, types.StatusUnusual, now, now, nil, 3, "Unusual", nil, nil, "Root > Web", ""))
 
-	assert.Error(t, err)
-	assert.Nil(t, sensor)
-	assert.Contains(t, err.Error(), "sensor not found")
+	sensors, err := db.GetSensorsExtended(context.Background(), types.SensorFilter{DeviceName: "web01", ProblemOnly: true}, "name", 50)
+	require.NoError(t, err)
+	require.Len(t, sensors, 3)
+
+	for _, sensor := range sensors {
+		assert.Contains(t, types.ProblemStatuses, sensor.Status)
+		assert.Equal(t, "web01", sensor.DeviceName)
+	}
 
 	assert.NoError(t, mock.ExpectationsWereMet())
 }
 
-// TestGetAlerts_EmptyResult validates handling of no alerts.
-func TestGetAlerts_EmptyResult(t *testing.T) {
+// TestGetSensorsExtended_Statuses validates that a sensor in any of the listed statuses is returned.
+func TestGetSensorsExtended_Statuses(t *testing.T) {
 	mockDB, mock, err := sqlmock.New()
 	require.NoError(t, err)
 	defer mockDB.Close()
@@ -383,28 +468,81 @@ func TestGetAlerts_EmptyResult(t *testing.T) {
 	}
 
 	columns := []string{
-		"id", "prtg_server_address_id", "name", "sensor_type", "prtg_device_id",
-		"device_name", "scanning_interval_seconds", "status", "last_check_utc",
-		"last_up_utc", "last_down_utc", "priority", "message",
-		"uptime_since_seconds", "downtime_since_seconds", "full_path", "tags",
+		"id", "prtg_server_address_id", "name", "sensor_type", "prtg_device_id", "device_name", "device_host",
+		"scanning_interval_seconds", "status", "last_check_utc", "last_up_utc", "last_down_utc",
+		"priority", "message", "uptime_since_seconds", "downtime_since_seconds", "full_path", "tags",
 	}
+	now := time.Now()
 
-	// Return empty result set
-	mock.ExpectQuery(`WHERE s\.status != \$1`).
-		WithArgs(types.StatusUp, 24).
-		WillReturnRows(sqlmock.NewRows(columns))
+	statuses := []int{types.StatusDown, types.StatusWarning}
 
-	ctx := context.Background()
-	sensors, err := db.GetAlerts(ctx, 24, nil, "")
+	whereClause, args := buildSensorWhereClause(types.SensorFilter{Statuses: statuses})
+	assert.Equal(t, "WHERE s.status = ANY($1)", whereClause)
+	assert.Equal(t, []interface{}{pq.Array(statuses)}, args)
+
+	// Placeholders continue after the name filter
+	mock.ExpectQuery(`WHERE d\.name ILIKE \$1 AND s\.status = ANY\(\$2\) ORDER BY s\.name LIMIT \$3`).
+		WithArgs("%web01%", pq.Array(statuses), 50).
+		WillReturnRows(sqlmock.NewRows(columns).
+			AddRow(1, 1, "HTTP", "http", 10, "web01", "", 60, types.StatusDown, now, now, now, 4, "Timeout", nil, nil, "Root > Web", "").
+			AddRow(2, 1, "Disk", "wmidiskspace", 10, "web01", "", 300, types.StatusWarning, now, now, nil, 3, "90% used", nil, nil, "Root > Web", ""))
 
+	sensors, err := db.GetSensorsExtended(context.Background(), types.SensorFilter{DeviceName: "web01", Statuses: statuses}, "name", 50)
 	require.NoError(t, err)
-	assert.Empty(t, sensors)
+	require.Len(t, sensors, 2)
+	assert.Equal(t, types.StatusDown, sensors[0].Status)
+	assert.Equal(t, types.StatusWarning, sensors[1].Status)
 
 	assert.NoError(t, mock.ExpectationsWereMet())
 }
 
-// TestGetAlerts_ComplexSeverityOrder validates the full ORDER BY CASE logic with all status codes.
-func TestGetAlerts_ComplexSeverityOrder(t *testing.T) {
+// TestBuildSensorWhereClause_IntervalRange validates the scanning interval clause and its open-ended bounds.
+func TestBuildSensorWhereClause_IntervalRange(t *testing.T) {
+	ten, sixty := 10, 60
+
+	whereClause, args := buildSensorWhereClause(types.SensorFilter{MinInterval: &ten, MaxInterval: &sixty})
+	assert.Equal(t, "WHERE s.scanning_interval_seconds BETWEEN $1 AND $2", whereClause)
+	assert.Equal(t, []interface{}{10, 60}, args)
+
+	whereClause, args = buildSensorWhereClause(types.SensorFilter{MinInterval: &sixty})
+	assert.Equal(t, "WHERE s.scanning_interval_seconds >= $1", whereClause)
+	assert.Equal(t, []interface{}{60}, args)
+
+	whereClause, args = buildSensorWhereClause(types.SensorFilter{MaxInterval: &ten})
+	assert.Equal(t, "WHERE s.scanning_interval_seconds <= $1", whereClause)
+	assert.Equal(t, []interface{}{10}, args)
+
+	// Composition: placeholders continue after the name, status and priority filters
+	downStatus := types.StatusDown
+	four := 4
+	whereClause, args = buildSensorWhereClause(types.SensorFilter{
+		DeviceName:    "core",
+		Status:        &downStatus,
+		MinPriority:   &four,
+		MaxInterval:   &ten,
+		ExcludePaused: true,
+	})
+	assert.Equal(t, "WHERE d.name ILIKE $1 AND s.status = $2 AND s.priority BETWEEN $3 AND $4"+
+		" AND s.scanning_interval_seconds <= $5 AND s.status NOT IN (7,8,9,11,12)", whereClause)
+	assert.Equal(t, []interface{}{"%core%", downStatus, 4, 5, 10}, args)
+}
+
+// TestBuildSensorWhereClause_Stale validates the stale last check filter and its placeholder position.
+func TestBuildSensorWhereClause_Stale(t *testing.T) {
+	whereClause, args := buildSensorWhereClause(types.SensorFilter{StaleMinutes: 60})
+	assert.Equal(t, "WHERE (s.last_check_utc IS NULL OR s.last_check_utc < NOW() - ($1 || ' minutes')::interval)", whereClause)
+	assert.Equal(t, []interface{}{60}, args)
+
+	// Placeholders continue after the interval range
+	ten, sixty := 10, 60
+	whereClause, args = buildSensorWhereClause(types.SensorFilter{MinInterval: &ten, MaxInterval: &sixty, StaleMinutes: 30})
+	assert.Contains(t, whereClause, "s.scanning_interval_seconds BETWEEN $1 AND $2")
+	assert.Contains(t, whereClause, "NOW() - ($3 || ' minutes')::interval")
+	assert.Equal(t, []interface{}{10, 60, 30}, args)
+}
+
+// TestGetSensorsExtended_Stale validates that sensors never checked are returned as stale.
+func TestGetSensorsExtended_Stale(t *testing.T) {
 	mockDB, mock, err := sqlmock.New()
 	require.NoError(t, err)
 	defer mockDB.Close()
@@ -415,73 +553,34 @@ func TestGetAlerts_ComplexSeverityOrder(t *testing.T) {
 		logger: &logger,
 	}
 
-	columns := []string{
-		"id", "prtg_server_address_id", "name", "sensor_type", "prtg_device_id",
-		"device_name", "scanning_interval_seconds", "status", "last_check_utc",
-		"last_up_utc", "last_down_utc", "priority", "message",
-		"uptime_since_seconds", "downtime_since_seconds", "full_path", "tags",
-	}
-
-	now := time.Now()
-
-	// IMPORTANT: sqlmock returns rows in insertion order
-	// We must return them ALREADY SORTED to simulate ORDER BY CASE behavior
-	// Expected order (as per ORDER BY CASE in queries.go lines 286-299):
-	// 1. Down (5) - CASE WHEN 5 THEN 1
-	// 2. DownPartial (14) - CASE WHEN 14 THEN 2
-	// 3. DownAcknowledged (13) - CASE WHEN 13 THEN 3
-	// 4. Warning (4) - CASE WHEN 4 THEN 4
-	// 5. Unusual (10) - CASE WHEN 10 THEN 5
-	// 6. NoProbe (6) - CASE WHEN 6 THEN 6
-	// 7. Unknown (1) - CASE WHEN 1 THEN 7
-
-	mock.ExpectQuery(`WHERE s\.status != \$1`).
-		WithArgs(types.StatusUp, 24).
-		WillReturnRows(sqlmock.NewRows(columns).
-			AddRow(5, 1, "Sensor Down", "ping", 100, "Dev1", 60, types.StatusDown, now, now, &now, 3, "", nil, 100.0, "/s5", "").
-			AddRow(7, 1, "Sensor DownPartial", "ping", 100, "Dev1", 60, types.StatusDownPartial, now, now, &now, 3, "", nil, 75.0, "/s7", "").
-			AddRow(6, 1, "Sensor DownAck", "ping", 100, "Dev1", 60, types.StatusDownAcknowledged, now, now, &now, 3, "", nil, 50.0, "/s6", "").
-			AddRow(3, 1, "Sensor Warning", "ping", 100, "Dev1", 60, types.StatusWarning, now, now, nil, 3, "", nil, nil, "/s3", "").
-			AddRow(4, 1, "Sensor Unusual", "ping", 100, "Dev1", 60, types.StatusUnusual, now, now, nil, 3, "", nil, nil, "/s4", "").
-			AddRow(2, 1, "Sensor NoProbe", "ping", 100, "Dev1", 60, types.StatusNoProbe, now, now, nil, 3, "", nil, nil, "/s2", "").
-			AddRow(1, 1, "Sensor Unknown", "ping", 100, "Dev1", 60, types.StatusUnknown, now, now, nil, 3, "", nil, nil, "/s1", ""))
+	old := time.Now().Add(-3 * time.Hour)
 
-	ctx := context.Background()
-	sensors, err := db.GetAlerts(ctx, 24, nil, "")
+	mock.ExpectQuery(`WHERE \(s\.last_check_utc IS NULL OR s\.last_check_utc < NOW\(\) - \(\$1 \|\| ' minutes'\)::interval\) ORDER BY s\.name LIMIT \$2`).
+		WithArgs(60, 50).
+		WillReturnRows(sqlmock.NewRows([]string{
+			"id", "prtg_server_address_id", "name", "sensor_type", "prtg_device_id", "device_name", "device_host",
+			"scanning_interval_seconds", "status", "last_check_utc", "last_up_utc", "last_down_utc",
+			"priority", "message", "uptime_since_seconds", "downtime_since_seconds", "full_path", "tags",
+		}).
+			AddRow(1, 1, "Branch Ping", "ping", 10, "branch-gw", "", 60, types.StatusUp, old, old, nil, 3, "OK", nil, nil, "Root > Branch", "").
+			AddRow(2, 1, "New HTTP", "http", 11, "web01", "", 60, types.StatusUp, nil, old, nil, 3, "", nil, nil, "Root > Web", ""))
 
+	sensors, err := db.GetSensorsExtended(context.Background(), types.SensorFilter{StaleMinutes: 60}, "name", 50)
 	require.NoError(t, err)
-	assert.Len(t, sensors, 7)
-
-	// Verify EXACT severity order as defined by ORDER BY CASE
-	expectedOrder := []struct {
-		status int
-		name   string
-	}{
-		{types.StatusDown, "Sensor Down"},
-		{types.StatusDownPartial, "Sensor DownPartial"},
-		{types.StatusDownAcknowledged, "Sensor DownAck"},
-		{types.StatusWarning, "Sensor Warning"},
-		{types.StatusUnusual, "Sensor Unusual"},
-		{types.StatusNoProbe, "Sensor NoProbe"},
-		{types.StatusUnknown, "Sensor Unknown"},
-	}
-
-	for i, expected := range expectedOrder {
-		assert.Equal(t, expected.status, sensors[i].Status,
-			"Position %d: expected status %d but got %d", i, expected.status, sensors[i].Status)
-		assert.Equal(t, expected.name, sensors[i].Name,
-			"Position %d: expected %s but got %s", i, expected.name, sensors[i].Name)
-	}
-
+	require.Len(t, sensors, 2)
+	assert.Equal(t, old, *sensors[0].LastCheckUTC)
+	assert.Nil(t, sensors[1].LastCheckUTC)
 	assert.NoError(t, mock.ExpectationsWereMet())
+
+	_, err = db.GetSensorsExtended(context.Background(), types.SensorFilter{StaleMinutes: -5}, "name", 50)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "stale_minutes must not be negative")
 }
 
-// BenchmarkGetAlerts benchmarks the GetAlerts function.
-func BenchmarkGetAlerts(b *testing.B) {
+// TestGetSensorsExtended_IntervalRange validates the interval range query and the rejection of invalid ranges.
+func TestGetSensorsExtended_IntervalRange(t *testing.T) {
 	mockDB, mock, err := sqlmock.New()
-	if err != nil {
-		b.Fatal(err)
-	}
+	require.NoError(t, err)
 	defer mockDB.Close()
 
 	logger := zerolog.Nop()
@@ -490,22 +589,2151 @@ func BenchmarkGetAlerts(b *testing.B) {
 		logger: &logger,
 	}
 
-	columns := []string{
-		"id", "prtg_server_address_id", "name", "sensor_type", "prtg_device_id",
-		"device_name", "scanning_interval_seconds", "status", "last_check_utc",
-		"last_up_utc", "last_down_utc", "priority", "message",
-		"uptime_since_seconds", "downtime_since_seconds", "full_path", "tags",
-	}
-
+	thirty := 30
 	now := time.Now()
 
-	for i := 0; i < b.N; i++ {
-		mock.ExpectQuery(`WHERE s\.status != \$1`).
-			WithArgs(types.StatusUp, 24).
-			WillReturnRows(sqlmock.NewRows(columns).
-				AddRow(1, 1, "Sensor", "ping", 100, "Device", 60, types.StatusDown, now, now, &now, 5, "Timeout", nil, 100.0, "/root/sensor", ""))
-
+	mock.ExpectQuery(`WHERE s\.sensor_type ILIKE \$1 AND s\.scanning_interval_seconds <= \$2 ORDER BY s\.name LIMIT \$3`).
+		WithArgs("%ping%", 30, 50).
+		WillReturnRows(sqlmock.NewRows([]string{
+			"id", "prtg_server_address_id", "name", "sensor_type", "prtg_device_id", "device_name", "device_host",
+			"scanning_interval_seconds", "status", "last_check_utc", "last_up_utc", "last_down_utc",
+			"priority", "message", "uptime_since_seconds", "downtime_since_seconds", "full_path", "tags",
+		}).AddRow(1, 1, "Fast Ping", "ping", 10, "core-sw", "", 10, types.StatusUp, now, now, nil, 3, "OK", nil, nil, "Root > Core", ""))
+
+	sensors, err := db.GetSensorsExtended(context.Background(), types.SensorFilter{
+		SensorType:  "ping",
+		MaxInterval: &thirty,
+	}, "name", 50)
+	require.NoError(t, err)
+	require.Len(t, sensors, 1)
+	assert.Equal(t, 10, sensors[0].ScanningIntervalSecs)
+	assert.NoError(t, mock.ExpectationsWereMet())
+
+	negative, sixty := -1, 60
+
+	tests := []struct {
+		name    string
+		filter  types.SensorFilter
+		wantErr string
+	}{
+		{"negative min", types.SensorFilter{MinInterval: &negative}, "min_interval must not be negative, got -1"},
+		{"negative max", types.SensorFilter{MaxInterval: &negative}, "max_interval must not be negative, got -1"},
+		{"inverted range", types.SensorFilter{MinInterval: &sixty, MaxInterval: &thirty}, "min_interval (60) must not be greater than max_interval (30)"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := db.GetSensorsExtended(context.Background(), tt.filter, "name", 50)
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tt.wantErr)
+		})
+	}
+}
+
+// TestGetSensorsExtended_OrderBy validates the ORDER BY clause generated for each order_by value.
+func TestGetSensorsExtended_OrderBy(t *testing.T) {
+	tests := []struct {
+		orderBy string
+		clause  string
+	}{
+		{"", `ORDER BY s\.name LIMIT \$1$`},
+		{"unknown", `ORDER BY s\.name LIMIT \$1$`},
+		{"device", `ORDER BY d\.name, s\.name LIMIT \$1$`},
+		{"device_status", `ORDER BY d\.name, CASE s\.status\s+WHEN 5 THEN 1 [\s\S]+` +
+			`WHEN 4 THEN 4 [\s\S]+ELSE 9 [\s\S]+ END, s\.name LIMIT \$1$`},
+		{"host", `ORDER BY d\.host, s\.name LIMIT \$1$`},
+		{"host_ip", `ORDER BY CASE WHEN d\.host ~ '\^\(\(25\[0-5\][^']+\$' THEN d\.host::inet END NULLS LAST, ` +
+			`d\.host, s\.name LIMIT \$1$`},
+	}
+
+	_, _, columns := searchColumns()
+
+	for _, tt := range tests {
+		t.Run(tt.orderBy, func(t *testing.T) {
+			mockDB, mock, err := sqlmock.New()
+			require.NoError(t, err)
+			defer mockDB.Close()
+
+			logger := zerolog.Nop()
+			db := &DB{
+				conn:   mockDB,
+				logger: &logger,
+			}
+
+			mock.ExpectQuery(`g\.prtg_server_address_id\s+` + tt.clause).
+				WithArgs(50).
+				WillReturnRows(sqlmock.NewRows(columns))
+
+			_, err = db.GetSensorsExtended(context.Background(), types.SensorFilter{}, tt.orderBy, 50)
+			require.NoError(t, err)
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+
+	// The device_status ranking is the alerts' severity ranking
+	assert.Contains(t, statusSeverityOrder, "WHEN 14 THEN 2  -- Down Partial")
+}
+
+// TestIPv4Pattern validates that only hosts castable to inet are cast by the host_ip ordering.
+func TestIPv4Pattern(t *testing.T) {
+	pattern := regexp.MustCompile(ipv4Pattern)
+
+	for _, host := range []string{"10.0.0.9", "10.0.0.10", "0.0.0.0", "192.168.1.254", "255.255.255.255"} {
+		assert.True(t, pattern.MatchString(host), host)
+	}
+
+	for _, host := range []string{"256.0.0.1", "10.0.0", "10.0.0.1.5", "10.0.0.1/24", "web01.example.com", "::1", "fe80::1", ""} {
+		assert.False(t, pattern.MatchString(host), host)
+	}
+}
+
+// TestGetSensorsExtended_PriorityRange validates the priority range query and the rejection of invalid ranges.
+func TestGetSensorsExtended_PriorityRange(t *testing.T) {
+	mockDB, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer mockDB.Close()
+
+	logger := zerolog.Nop()
+	db := &DB{
+		conn:   mockDB,
+		logger: &logger,
+	}
+
+	four, five := 4, 5
+	now := time.Now()
+
+	mock.ExpectQuery(`WHERE s\.priority BETWEEN \$1 AND \$2 ORDER BY s\.priority DESC, s\.name LIMIT \$3`).
+		WithArgs(4, 5, 50).
+		WillReturnRows(sqlmock.NewRows([]string{
+			"id", "prtg_server_address_id", "name", "sensor_type", "prtg_device_id", "device_name", "device_host",
+			"scanning_interval_seconds", "status", "last_check_utc", "last_up_utc", "last_down_utc",
+			"priority", "message", "uptime_since_seconds", "downtime_since_seconds", "full_path", "tags",
+		}).AddRow(1, 1, "Core Ping", "ping", 10, "core-sw", "", 60, types.StatusUp, now, now, nil, 5, "OK", nil, nil, "Root > Core", ""))
+
+	sensors, err := db.GetSensorsExtended(context.Background(), types.SensorFilter{
+		MinPriority: &four,
+		MaxPriority: &five,
+	}, "priority", 50)
+	require.NoError(t, err)
+	require.Len(t, sensors, 1)
+	assert.Equal(t, 5, sensors[0].Priority)
+	assert.NoError(t, mock.ExpectationsWereMet())
+
+	zero, six := 0, 6
+
+	tests := []struct {
+		name    string
+		filter  types.SensorFilter
+		wantErr string
+	}{
+		{"min below range", types.SensorFilter{MinPriority: &zero}, "min_priority must be between 1 and 5, got 0"},
+		{"max above range", types.SensorFilter{MaxPriority: &six}, "max_priority must be between 1 and 5, got 6"},
+		{"inverted range", types.SensorFilter{MinPriority: &five, MaxPriority: &four}, "min_priority (5) must not be greater than max_priority (4)"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := db.GetSensorsExtended(context.Background(), tt.filter, "name", 50)
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tt.wantErr)
+
+			_, err = db.CountSensors(context.Background(), tt.filter)
+			assert.Error(t, err)
+		})
+	}
+
+	// Invalid ranges never reach the database
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// TestGroupCounts validates the GROUP BY query of each whitelisted dimension.
+func TestGroupCounts(t *testing.T) {
+	tests := []struct {
+		dimension string
+		expr      string
+		values    []string
+	}{
+		{"status", `s\.status::text`, []string{"3", "5"}},
+		{"sensor_type", `COALESCE\(s\.sensor_type, ''\)`, []string{"ping", "http"}},
+		{"device", `d\.name`, []string{"core-sw", "web-01"}},
+		{"group", `g\.name`, []string{"Network", "Servers"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.dimension, func(t *testing.T) {
+			mockDB, mock, err := sqlmock.New()
+			require.NoError(t, err)
+			defer mockDB.Close()
+
+			logger := zerolog.Nop()
+			db := &DB{
+				conn:   mockDB,
+				logger: &logger,
+			}
+
+			expectedQuery := `^SELECT ` + tt.expr + ` AS value, COUNT\(\*\) AS count\s+FROM prtg_sensor s[\s\S]+` +
+				`WHERE g\.name ILIKE \$1 GROUP BY 1 ORDER BY count DESC, value LIMIT \$2$`
+
+			mock.ExpectQuery(expectedQuery).
+				WithArgs("%prod%", 100).
+				WillReturnRows(sqlmock.NewRows([]string{"value", "count"}).
+					AddRow(tt.values[0], 42).
+					AddRow(tt.values[1], 7))
+
+			counts, err := db.GroupCounts(context.Background(), tt.dimension, types.SensorFilter{GroupName: "prod"}, 100)
+			require.NoError(t, err)
+
+			assert.Equal(t, []types.DimensionCount{
+				{Value: tt.values[0], Count: 42},
+				{Value: tt.values[1], Count: 7},
+			}, counts)
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+// TestGroupCounts_InvalidDimension validates that dimensions outside the whitelist never reach the database.
+func TestGroupCounts_InvalidDimension(t *testing.T) {
+	mockDB, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer mockDB.Close()
+
+	logger := zerolog.Nop()
+	db := &DB{
+		conn:   mockDB,
+		logger: &logger,
+	}
+
+	for _, dimension := range []string{"", "s.message", "status; DROP TABLE prtg_sensor"} {
+		counts, err := db.GroupCounts(context.Background(), dimension, types.SensorFilter{}, 100)
+		require.Error(t, err)
+		assert.Nil(t, counts)
+		assert.Contains(t, err.Error(), "invalid dimension")
+	}
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestGetTags_OrderAndCountRange(t *testing.T) {
+	intPtr := func(i int) *int { return &i }
+	columns := []string{"id", "prtg_server_address_id", "name", "sensor_count"}
+
+	tests := []struct {
+		name   string
+		filter types.TagFilter
+		query  string
+		args   []driver.Value
+	}{
+		{
+			name:   "default name order",
+			filter: types.TagFilter{},
+			query:  `GROUP BY t\.id, t\.prtg_server_address_id, t\.name ORDER BY t\.name, t\.id LIMIT \$1$`,
+			args:   []driver.Value{50},
+		},
+		{
+			name:   "usage order",
+			filter: types.TagFilter{Name: "prod", OrderBy: types.TagOrderUsage},
+			query: `WHERE t\.name ILIKE \$1 GROUP BY t\.id, t\.prtg_server_address_id, t\.name ` +
+				`ORDER BY sensor_count DESC, t\.name, t\.id LIMIT \$2$`,
+			args: []driver.Value{"%prod%", 50},
+		},
+		{
+			name:   "count range",
+			filter: types.TagFilter{MinSensorCount: intPtr(2), MaxSensorCount: intPtr(10)},
+			query: `GROUP BY t\.id, t\.prtg_server_address_id, t\.name ` +
+				`HAVING COUNT\(DISTINCT st\.prtg_sensor_id\) >= \$1 AND COUNT\(DISTINCT st\.prtg_sensor_id\) <= \$2 ` +
+				`ORDER BY t\.name, t\.id LIMIT \$3$`,
+			args: []driver.Value{2, 10, 50},
+		},
+		{
+			name:   "unused tags",
+			filter: types.TagFilter{MaxSensorCount: intPtr(0)},
+			query:  `HAVING COUNT\(DISTINCT st\.prtg_sensor_id\) <= \$1 ORDER BY t\.name, t\.id LIMIT \$2$`,
+			args:   []driver.Value{0, 50},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockDB, mock, err := sqlmock.New()
+			require.NoError(t, err)
+			defer mockDB.Close()
+
+			logger := zerolog.Nop()
+			db := &DB{conn: mockDB, logger: &logger}
+
+			mock.ExpectQuery(tt.query).
+				WithArgs(tt.args...).
+				WillReturnRows(sqlmock.NewRows(columns).
+					AddRow(7, 1, "production", 12).
+					AddRow(3, 1, "linux", 4))
+
+			tags, err := db.GetTags(context.Background(), tt.filter, 50)
+			require.NoError(t, err)
+
+			assert.Equal(t, []types.Tag{
+				{ID: 7, ServerID: 1, Name: "production", SensorCount: 12},
+				{ID: 3, ServerID: 1, Name: "linux", SensorCount: 4},
+			}, tags)
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestGetTags_InvalidFilter(t *testing.T) {
+	mockDB, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer mockDB.Close()
+
+	logger := zerolog.Nop()
+	db := &DB{conn: mockDB, logger: &logger}
+
+	minCount, maxCount := 5, 1
+
+	for _, filter := range []types.TagFilter{
+		{OrderBy: "sensor_count"},
+		{MinSensorCount: &minCount, MaxSensorCount: &maxCount},
+	} {
+		tags, err := db.GetTags(context.Background(), filter, 50)
+		assert.Error(t, err)
+		assert.Nil(t, tags)
+	}
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// TestExecuteCustomQuery_SELECTOnly validates that only SELECT queries are allowed.
+func TestExecuteCustomQuery_SELECTOnly(t *testing.T) {
+	mockDB, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer mockDB.Close()
+
+	logger := zerolog.Nop()
+	db := &DB{
+		conn:   mockDB,
+		logger: &logger,
+	}
+
+	tests := []struct {
+		name        string
+		query       string
+		shouldError bool
+		errorMsg    string
+	}{
+		{
+			name:        "Valid SELECT query",
+			query:       "SELECT * FROM prtg_sensor WHERE status = 5",
+			shouldError: false,
+		},
+		{
+			name:        "SELECT with subquery",
+			query:       "SELECT id FROM prtg_sensor WHERE id IN (SELECT sensor_id FROM prtg_sensor_tag)",
+			shouldError: false,
+		},
+		{
+			name:        "DROP table attempt",
+			query:       "DROP TABLE prtg_sensor",
+			shouldError: true,
+			errorMsg:    "only SELECT queries are allowed",
+		},
+		{
+			name:        "DELETE attempt",
+			query:       "DELETE FROM prtg_sensor WHERE id = 1",
+			shouldError: true,
+			errorMsg:    "only SELECT queries are allowed",
+		},
+		{
+			name:        "UPDATE attempt",
+			query:       "UPDATE prtg_sensor SET status = 3 WHERE id = 1",
+			shouldError: true,
+			errorMsg:    "only SELECT queries are allowed",
+		},
+		{
+			name:        "INSERT attempt",
+			query:       "INSERT INTO prtg_sensor (name) VALUES ('hack')",
+			shouldError: true,
+			errorMsg:    "only SELECT queries are allowed",
+		},
+		{
+			name:        "SQL injection attempt with comment",
+			query:       "SELECT * FROM prtg_sensor; DROP TABLE users; --",
+			shouldError: true,
+			errorMsg:    "forbidden keyword",
+		},
+		{
+			name:        "SQL injection with semicolon",
+			query:       "SELECT * FROM prtg_sensor WHERE id = 1; DELETE FROM prtg_device",
+			shouldError: true,
+			errorMsg:    "forbidden keyword",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if !tt.shouldError {
+				// Setup mock for valid SELECT
+				mock.ExpectQuery(regexp.QuoteMeta(tt.query)).
+					WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+			}
+
+			ctx := context.Background()
+			results, err := db.ExecuteCustomQuery(ctx, tt.query, 100)
+
+			if tt.shouldError {
+				assert.Error(t, err)
+				assert.Contains(t, err.Error(), tt.errorMsg)
+				assert.Nil(t, results)
+			} else {
+				assert.NoError(t, err)
+				assert.NotNil(t, results)
+			}
+		})
+	}
+
+	// Note: We don't check ExpectationsWereMet here because dangerous queries don't reach the DB
+}
+
+// TestGetSensorByID validates retrieval of a specific sensor.
+func TestGetSensorByID(t *testing.T) {
+	mockDB, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer mockDB.Close()
+
+	logger := zerolog.Nop()
+	db := &DB{
+		conn:   mockDB,
+		logger: &logger,
+	}
+
+	columns := []string{
+		"id", "prtg_server_address_id", "name", "sensor_type", "prtg_device_id",
+		"device_name", "device_host", "scanning_interval_seconds", "status", "last_check_utc",
+		"last_up_utc", "last_down_utc", "priority", "message",
+		"uptime_since_seconds", "downtime_since_seconds", "full_path", "tags",
+	}
+
+	now := time.Now()
+	uptime := 3600.0 // 1 hour uptime
+
+	expectedQuery := `WHERE[\s\S]+s\.id = \$1`
+
+	mock.ExpectQuery(expectedQuery).
+		WithArgs(123).
+		WillReturnRows(sqlmock.NewRows(columns).
+			AddRow(123, 1, "Test Sensor", "ping", 100, "Test Device", "10.1.2.3", 60, types.StatusUp, now, now, nil, 3, "OK", &uptime, nil, "/root/test/sensor", "production"))
+
+	ctx := context.Background()
+	sensor, err := db.GetSensorByID(ctx, 123, nil)
+
+	require.NoError(t, err)
+	assert.NotNil(t, sensor)
+	assert.Equal(t, 123, sensor.ID)
+	assert.Equal(t, "Test Sensor", sensor.Name)
+	assert.Equal(t, "Test Device", sensor.DeviceName)
+	assert.Equal(t, "10.1.2.3", sensor.DeviceHost)
+	assert.Equal(t, types.StatusUp, sensor.Status)
+	assert.NotNil(t, sensor.UptimeSinceSecs)
+	assert.Equal(t, uptime, *sensor.UptimeSinceSecs)
+	assert.Nil(t, sensor.DowntimeSinceSecs)
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// TestGetSensorByID_MultiServer validates lookups of a sensor ID shared by two PRTG servers.
+func TestGetSensorByID_MultiServer(t *testing.T) {
+	mockDB, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer mockDB.Close()
+
+	logger := zerolog.Nop()
+	db := &DB{
+		conn:   mockDB,
+		logger: &logger,
+	}
+
+	_, _, columns := searchColumns()
+	now := time.Now()
+	ctx := context.Background()
+
+	t.Run("ambiguous without server", func(t *testing.T) {
+		mock.ExpectQuery(`WHERE s\.id = \$1\s+ORDER BY s\.prtg_server_address_id$`).
+			WithArgs(2001).
+			WillReturnRows(sqlmock.NewRows(columns).
+				AddRow(2001, 1, "Ping", "ping", 40, "paris-fw", "10.1.0.1", 60, types.StatusUp, now, now, nil, 3, "OK", nil, nil, "/Paris/paris-fw/Ping", "").
+				AddRow(2001, 2, "HTTP", "http", 40, "lyon-web", "10.2.0.1", 60, types.StatusDown, now, now, now, 4, "Timeout", nil, nil, "/Lyon/lyon-web/HTTP", ""))
+
+		sensor, err := db.GetSensorByID(ctx, 2001, nil)
+		assert.Nil(t, sensor)
+
+		var ambiguous *types.AmbiguousSensorError
+		require.ErrorAs(t, err, &ambiguous)
+		assert.Equal(t, 2001, ambiguous.SensorID)
+		require.Len(t, ambiguous.Sensors, 2)
+		assert.Equal(t, "paris-fw", ambiguous.Sensors[0].DeviceName)
+		assert.Equal(t, 2, ambiguous.Sensors[1].ServerID)
+		assert.EqualError(t, err, "sensor 2001 exists on several PRTG servers (server_id 1, 2), pass server_id to choose one")
+	})
+
+	t.Run("scoped to a server", func(t *testing.T) {
+		serverID := 2
+
+		mock.ExpectQuery(`WHERE s\.id = \$1 AND s\.prtg_server_address_id = \$2 ORDER BY`).
+			WithArgs(2001, 2).
+			WillReturnRows(sqlmock.NewRows(columns).
+				AddRow(2001, 2, "HTTP", "http", 40, "lyon-web", "10.2.0.1", 60, types.StatusDown, now, now, now, 4, "Timeout", nil, nil, "/Lyon/lyon-web/HTTP", ""))
+
+		sensor, err := db.GetSensorByID(ctx, 2001, &serverID)
+		require.NoError(t, err)
+		assert.Equal(t, 2, sensor.ServerID)
+		assert.Equal(t, "HTTP", sensor.Name)
+	})
+
+	t.Run("not on that server", func(t *testing.T) {
+		serverID := 3
+
+		mock.ExpectQuery(`AND s\.prtg_server_address_id = \$2`).
+			WithArgs(2001, 3).
+			WillReturnRows(sqlmock.NewRows(columns))
+
+		_, err := db.GetSensorByID(ctx, 2001, &serverID)
+		assert.EqualError(t, err, "sensor not found")
+	})
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// TestGetSensorsByIDs validates batch lookup of sensors by ID.
+func TestGetSensorsByIDs(t *testing.T) {
+	mockDB, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer mockDB.Close()
+
+	logger := zerolog.Nop()
+	db := &DB{
+		conn:   mockDB,
+		logger: &logger,
+	}
+
+	columns := []string{
+		"id", "prtg_server_address_id", "name", "sensor_type", "prtg_device_id",
+		"device_name", "device_host", "scanning_interval_seconds", "status", "last_check_utc",
+		"last_up_utc", "last_down_utc", "priority", "message",
+		"uptime_since_seconds", "downtime_since_seconds", "full_path", "tags",
+	}
+
+	now := time.Now()
+	downtime := 600.0
+
+	// Sensor IDs are only unique within one PRTG server
+	expectedQuery := `WHERE s\.prtg_server_address_id = \$1 AND s\.id = ANY\(\$2\)[\s\S]+ORDER BY CASE WHEN s\.status = \$3`
+
+	mock.ExpectQuery(expectedQuery).
+		WithArgs(1, sqlmock.AnyArg(), types.StatusUp).
+		WillReturnRows(sqlmock.NewRows(columns).
+			AddRow(102, 1, "DB Port", "port", 10, "DB Server", "db01.example.com", 60, types.StatusDown, now, now, now, 4, "Timeout", nil, &downtime, "/root/db", "").
+			AddRow(101, 1, "Web Ping", "ping", 11, "Web Server", "", 60, types.StatusUp, now, now, nil, 3, "OK", nil, nil, "/root/web", ""))
+
+	ctx := context.Background()
+	sensors, err := db.GetSensorsByIDs(ctx, 1, []int{101, 102})
+
+	require.NoError(t, err)
+	require.Len(t, sensors, 2)
+	assert.Equal(t, 102, sensors[0].ID)
+	assert.Equal(t, types.StatusDown, sensors[0].Status)
+	assert.Equal(t, "db01.example.com", sensors[0].DeviceHost)
+
+	// No IDs should not hit the database
+	sensors, err = db.GetSensorsByIDs(ctx, 1, nil)
+	require.NoError(t, err)
+	assert.Empty(t, sensors)
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// TestGetSensorsByDeviceID validates listing the sensors of a device by ID.
+func TestGetSensorsByDeviceID(t *testing.T) {
+	mockDB, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer mockDB.Close()
+
+	logger := zerolog.Nop()
+	db := &DB{
+		conn:   mockDB,
+		logger: &logger,
+	}
+
+	columns := []string{
+		"id", "prtg_server_address_id", "name", "sensor_type", "prtg_device_id",
+		"device_name", "device_host", "scanning_interval_seconds", "status", "last_check_utc",
+		"last_up_utc", "last_down_utc", "priority", "message",
+		"uptime_since_seconds", "downtime_since_seconds", "full_path", "tags",
+	}
+	deviceColumns := []string{
+		"id", "prtg_server_address_id", "name", "host", "prtg_group_id", "group_name",
+		"full_path", "sensor_count", "tree_depth",
+	}
+
+	sensorQuery := `WHERE s\.prtg_device_id = \$1\s+ORDER BY s\.name LIMIT \$2`
+	deviceQuery := `FROM prtg_device d[\s\S]+WHERE d\.id = \$1`
+	ctx := context.Background()
+	now := time.Now()
+
+	t.Run("device with sensors", func(t *testing.T) {
+		mock.ExpectQuery(sensorQuery).
+			WithArgs(10, 50).
+			WillReturnRows(sqlmock.NewRows(columns).
+				AddRow(101, 1, "CPU Load", "cpu", 10, "DB Server", "db01.example.com", 60, types.StatusUp, now, now, nil, 3, "OK", nil, nil, "/root/db", "").
+				AddRow(102, 1, "Ping", "ping", 10, "DB Server", "db01.example.com", 60, types.StatusDown, now, now, now, 4, "Timeout", nil, nil, "/root/db", ""))
+
+		sensors, err := db.GetSensorsByDeviceID(ctx, 10, 50)
+		require.NoError(t, err)
+		require.Len(t, sensors, 2)
+		assert.Equal(t, 101, sensors[0].ID)
+		assert.Equal(t, "DB Server", sensors[1].DeviceName)
+		assert.Equal(t, types.StatusDown, sensors[1].Status)
+	})
+
+	t.Run("device without sensors", func(t *testing.T) {
+		mock.ExpectQuery(sensorQuery).WithArgs(11, 50).WillReturnRows(sqlmock.NewRows(columns))
+		mock.ExpectQuery(deviceQuery).WithArgs(11).
+			WillReturnRows(sqlmock.NewRows(deviceColumns).AddRow(11, 1, "Spare", "", 5, "Lab", "/root/lab", 0, 2))
+
+		sensors, err := db.GetSensorsByDeviceID(ctx, 11, 50)
+		require.NoError(t, err)
+		assert.NotNil(t, sensors)
+		assert.Empty(t, sensors)
+	})
+
+	t.Run("unknown device", func(t *testing.T) {
+		mock.ExpectQuery(sensorQuery).WithArgs(999, 50).WillReturnRows(sqlmock.NewRows(columns))
+		mock.ExpectQuery(deviceQuery).WithArgs(999).WillReturnError(sql.ErrNoRows)
+
+		sensors, err := db.GetSensorsByDeviceID(ctx, 999, 50)
+		assert.EqualError(t, err, "device not found")
+		assert.Nil(t, sensors)
+	})
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// TestGetSensorByID_NotFound validates error handling when sensor doesn't exist.
+func TestGetSensorByID_NotFound(t *testing.T) {
+	mockDB, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer mockDB.Close()
+
+	logger := zerolog.Nop()
+	db := &DB{
+		conn:   mockDB,
+		logger: &logger,
+	}
+
+	expectedQuery := `WHERE[\s\S]+s\.id = \$1`
+
+	mock.ExpectQuery(expectedQuery).
+		WithArgs(999).
+		WillReturnError(sql.ErrNoRows)
+
+	ctx := context.Background()
+	sensor, err := db.GetSensorByID(ctx, 999, nil)
+
+	assert.Error(t, err)
+	assert.Nil(t, sensor)
+	assert.Contains(t, err.Error(), "sensor not found")
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// TestResolveObjectByID validates the sensor, device, then group lookup cascade.
+func TestResolveObjectByID(t *testing.T) {
+	sensorColumns := []string{
+		"id", "prtg_server_address_id", "name", "sensor_type", "prtg_device_id",
+		"device_name", "device_host", "scanning_interval_seconds", "status", "last_check_utc",
+		"last_up_utc", "last_down_utc", "priority", "message",
+		"uptime_since_seconds", "downtime_since_seconds", "full_path", "tags",
+	}
+	deviceColumns := []string{
+		"id", "prtg_server_address_id", "name", "host", "prtg_group_id",
+		"group_name", "full_path", "sensor_count", "tree_depth",
+	}
+	groupColumns := []string{
+		"id", "prtg_server_address_id", "name", "is_probe_node", "self_group_id", "full_path", "tree_depth",
+	}
+
+	sensorQuery := `FROM prtg_sensor s[\s\S]+WHERE s\.id = \$1`
+	deviceQuery := `FROM prtg_device d[\s\S]+WHERE d\.id = \$1`
+	groupQuery := `FROM prtg_group g[\s\S]+WHERE g\.id = \$1`
+
+	newDB := func(t *testing.T) (*DB, sqlmock.Sqlmock) {
+		mockDB, mock, err := sqlmock.New()
+		require.NoError(t, err)
+		t.Cleanup(func() { mockDB.Close() })
+
+		logger := zerolog.Nop()
+
+		return &DB{conn: mockDB, logger: &logger}, mock
+	}
+
+	t.Run("sensor", func(t *testing.T) {
+		db, mock := newDB(t)
+		now := time.Now()
+
+		mock.ExpectQuery(sensorQuery).WithArgs(2001).
+			WillReturnRows(sqlmock.NewRows(sensorColumns).
+				AddRow(2001, 1, "Ping", "ping", 100, "web01", "10.0.0.1", 60, types.StatusUp, now, now, nil, 3, "OK", nil, nil, "/Root/web01/Ping", ""))
+
+		object, err := db.ResolveObjectByID(context.Background(), 2001)
+		require.NoError(t, err)
+		require.NotNil(t, object)
+		assert.Equal(t, types.ObjectTypeSensor, object.Type)
+		require.NotNil(t, object.Sensor)
+		assert.Equal(t, "Ping", object.Sensor.Name)
+		assert.Nil(t, object.Device)
+		assert.Nil(t, object.Group)
+
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("device", func(t *testing.T) {
+		db, mock := newDB(t)
+
+		mock.ExpectQuery(sensorQuery).WithArgs(100).WillReturnError(sql.ErrNoRows)
+		mock.ExpectQuery(deviceQuery).WithArgs(100).
+			WillReturnRows(sqlmock.NewRows(deviceColumns).
+				AddRow(100, 1, "web01", "10.0.0.1", 10, "Web Servers", "/Root/Web Servers/web01", 12, 2))
+
+		object, err := db.ResolveObjectByID(context.Background(), 100)
+		require.NoError(t, err)
+		require.NotNil(t, object)
+		assert.Equal(t, types.ObjectTypeDevice, object.Type)
+		require.NotNil(t, object.Device)
+		assert.Equal(t, "web01", object.Device.Name)
+		assert.Equal(t, 12, object.Device.SensorCount)
+		assert.Nil(t, object.Sensor)
+
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("group", func(t *testing.T) {
+		db, mock := newDB(t)
+
+		mock.ExpectQuery(sensorQuery).WithArgs(10).WillReturnError(sql.ErrNoRows)
+		mock.ExpectQuery(deviceQuery).WithArgs(10).WillReturnError(sql.ErrNoRows)
+		mock.ExpectQuery(groupQuery).WithArgs(10).
+			WillReturnRows(sqlmock.NewRows(groupColumns).
+				AddRow(10, 1, "Web Servers", false, 1, "/Root/Web Servers", 1))
+
+		object, err := db.ResolveObjectByID(context.Background(), 10)
+		require.NoError(t, err)
+		require.NotNil(t, object)
+		assert.Equal(t, types.ObjectTypeGroup, object.Type)
+		require.NotNil(t, object.Group)
+		assert.Equal(t, "Web Servers", object.Group.Name)
+		require.NotNil(t, object.Group.ParentID)
+		assert.Equal(t, 1, *object.Group.ParentID)
+
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("none", func(t *testing.T) {
+		db, mock := newDB(t)
+
+		mock.ExpectQuery(sensorQuery).WithArgs(999).WillReturnError(sql.ErrNoRows)
+		mock.ExpectQuery(deviceQuery).WithArgs(999).WillReturnError(sql.ErrNoRows)
+		mock.ExpectQuery(groupQuery).WithArgs(999).WillReturnError(sql.ErrNoRows)
+
+		object, err := db.ResolveObjectByID(context.Background(), 999)
+		require.NoError(t, err)
+		assert.Nil(t, object)
+
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("query error stops the cascade", func(t *testing.T) {
+		db, mock := newDB(t)
+
+		mock.ExpectQuery(sensorQuery).WithArgs(5).WillReturnError(sql.ErrNoRows)
+		mock.ExpectQuery(deviceQuery).WithArgs(5).WillReturnError(errors.New("permission denied for table prtg_device"))
+
+		object, err := db.ResolveObjectByID(context.Background(), 5)
+		require.Error(t, err)
+		assert.Nil(t, object)
+		assert.Contains(t, err.Error(), "device lookup failed")
+
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+// TestGetAlerts_Pagination validates that a page continues after the previous one in a stable
+// order and that the count covers all matching alerts.
+func TestGetAlerts_Pagination(t *testing.T) {
+	mockDB, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer mockDB.Close()
+
+	logger := zerolog.Nop()
+	db := &DB{
+		conn:   mockDB,
+		logger: &logger,
+	}
+
+	columns := []string{
+		"id", "prtg_server_address_id", "name", "sensor_type", "prtg_device_id",
+		"device_name", "device_host", "scanning_interval_seconds", "status", "last_check_utc",
+		"last_up_utc", "last_down_utc", "priority", "message",
+		"uptime_since_seconds", "downtime_since_seconds", "full_path", "tags",
+	}
+
+	now := time.Now()
+	filter := types.AlertFilter{DeviceName: "branch"}
+
+	// Second page of 2: the sensor ID breaks ties between same-named sensors
+	mock.ExpectQuery(`WHERE s\.status != \$1 AND d\.name ILIKE \$2 ORDER BY[\s\S]+s\.name,\s+s\.id\s+LIMIT \$3 OFFSET \$4`).
+		WithArgs(types.StatusUp, "%branch%", 2, 2).
+		WillReturnRows(sqlmock.NewRows(columns).
+			AddRow(13, 1, "Ping", "ping", 100, "branch-02", "", 60, types.StatusDown, now, now, &now, 3, "Timeout", nil, 60.0, "/b2/ping", "").
+			AddRow(14, 1, "Ping", "ping", 101, "branch-03", "", 60, types.StatusDown, now, now, &now, 3, "Timeout", nil, 60.0, "/b3/ping", ""))
+
+	sensors, err := db.GetAlerts(context.Background(), filter, 2, 2)
+	require.NoError(t, err)
+	require.Len(t, sensors, 2)
+	assert.Equal(t, 13, sensors[0].ID)
+	assert.Equal(t, 14, sensors[1].ID)
+
+	// The count uses the same filters, without ordering or paging
+	mock.ExpectQuery(`SELECT COUNT\(\*\)[\s\S]+FROM prtg_sensor s[\s\S]+WHERE s\.status != \$1 AND d\.name ILIKE \$2$`).
+		WithArgs(types.StatusUp, "%branch%").
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(5))
+
+	total, err := db.CountAlerts(context.Background(), filter)
+	require.NoError(t, err)
+	assert.Equal(t, 5, total)
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// TestGetAlerts_OrderBySeverity validates that a score-ordered page is ordered by score in SQL,
+// so that the next page continues the order.
+func TestGetAlerts_OrderBySeverity(t *testing.T) {
+	mockDB, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer mockDB.Close()
+
+	logger := zerolog.Nop()
+	db := &DB{
+		conn:   mockDB,
+		logger: &logger,
+	}
+
+	columns := []string{
+		"id", "prtg_server_address_id", "name", "sensor_type", "prtg_device_id",
+		"device_name", "device_host", "scanning_interval_seconds", "status", "last_check_utc",
+		"last_up_utc", "last_down_utc", "priority", "message",
+		"uptime_since_seconds", "downtime_since_seconds", "full_path", "tags",
+	}
+
+	now := time.Now()
+	filter := types.AlertFilter{OrderBy: types.AlertOrderSeverity}
+
+	mock.ExpectQuery(`ORDER BY COALESCE\(CASE s\.status[\s\S]+END \+ LEAST\(GREATEST\(s\.priority, 1\), 5\) \* 2, 0\) DESC,\s+`+
+		`s\.priority DESC,[\s\S]+s\.name,\s+s\.id\s+LIMIT \$2 OFFSET \$3`).
+		WithArgs(types.StatusUp, 2, 2).
+		WillReturnRows(sqlmock.NewRows(columns).
+			AddRow(13, 1, "Disk", "disk", 100, "srv-02", "", 60, types.StatusWarning, now, now, &now, 5, "90%", nil, nil, "/s2/disk", "").
+			AddRow(14, 1, "CPU", "cpu", 101, "srv-03", "", 60, types.StatusWarning, now, now, &now, 4, "80%", nil, nil, "/s3/cpu", ""))
+
+	sensors, err := db.GetAlerts(context.Background(), filter, 2, 2)
+	require.NoError(t, err)
+	require.Len(t, sensors, 2)
+	assert.Equal(t, 13, sensors[0].ID)
+
+	// The default ordering does not rank by score
+	mock.ExpectQuery(`ORDER BY\s+s\.priority DESC,`).
+		WithArgs(types.StatusUp, 2, 0).
+		WillReturnRows(sqlmock.NewRows(columns))
+
+	_, err = db.GetAlerts(context.Background(), types.AlertFilter{}, 2, 0)
+	require.NoError(t, err)
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// TestGetAlerts_EmptyResult validates handling of no alerts.
+func TestGetAlerts_EmptyResult(t *testing.T) {
+	mockDB, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer mockDB.Close()
+
+	logger := zerolog.Nop()
+	db := &DB{
+		conn:   mockDB,
+		logger: &logger,
+	}
+
+	columns := []string{
+		"id", "prtg_server_address_id", "name", "sensor_type", "prtg_device_id",
+		"device_name", "device_host", "scanning_interval_seconds", "status", "last_check_utc",
+		"last_up_utc", "last_down_utc", "priority", "message",
+		"uptime_since_seconds", "downtime_since_seconds", "full_path", "tags",
+	}
+
+	// Return empty result set
+	mock.ExpectQuery(`WHERE s\.status != \$1`).
+		WithArgs(types.StatusUp, 24, types.AlertsLimit, 0).
+		WillReturnRows(sqlmock.NewRows(columns))
+
+	ctx := context.Background()
+	sensors, err := db.GetAlerts(ctx, types.AlertFilter{Hours: 24}, 0, 0)
+
+	require.NoError(t, err)
+	assert.Empty(t, sensors)
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// TestGetAlerts_ComplexSeverityOrder validates the full ORDER BY CASE logic with all status codes.
+func TestGetAlerts_ComplexSeverityOrder(t *testing.T) {
+	mockDB, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer mockDB.Close()
+
+	logger := zerolog.Nop()
+	db := &DB{
+		conn:   mockDB,
+		logger: &logger,
+	}
+
+	columns := []string{
+		"id", "prtg_server_address_id", "name", "sensor_type", "prtg_device_id",
+		"device_name", "device_host", "scanning_interval_seconds", "status", "last_check_utc",
+		"last_up_utc", "last_down_utc", "priority", "message",
+		"uptime_since_seconds", "downtime_since_seconds", "full_path", "tags",
+	}
+
+	now := time.Now()
+
+	// IMPORTANT: sqlmock returns rows in insertion order
+	// We must return them ALREADY SORTED to simulate ORDER BY CASE behavior
+	// Expected order (as per ORDER BY CASE in queries.go lines 286-299):
+	// 1. Down (5) - CASE WHEN 5 THEN 1
+	// 2. DownPartial (14) - CASE WHEN 14 THEN 2
+	// 3. DownAcknowledged (13) - CASE WHEN 13 THEN 3
+	// 4. Warning (4) - CASE WHEN 4 THEN 4
+	// 5. Unusual (10) - CASE WHEN 10 THEN 5
+	// 6. NoProbe (6) - CASE WHEN 6 THEN 6
+	// 7. Unknown (1) - CASE WHEN 1 THEN 7
+
+	mock.ExpectQuery(`WHERE s\.status != \$1`).
+		WithArgs(types.StatusUp, 24, types.AlertsLimit, 0).
+		WillReturnRows(sqlmock.NewRows(columns).
+			AddRow(5, 1, "Sensor Down", "ping", 100, "Dev1", "", 60, types.StatusDown, now, now, &now, 3, "", nil, 100.0, "/s5", "").
+			AddRow(7, 1, "Sensor DownPartial", "ping", 100, "Dev1", "", 60, types.StatusDownPartial, now, now, &now, 3, "", nil, 75.0, "/s7", "").
+			AddRow(6, 1, "Sensor DownAck", "ping", 100, "Dev1", "", 60, types.StatusDownAcknowledged, now, now, &now, 3, "", nil, 50.0, "/s6", "").
+			AddRow(3, 1, "Sensor Warning", "ping", 100, "Dev1", "", 60, types.StatusWarning, now, now, nil, 3, "", nil, nil, "/s3", "").
+			AddRow(4, 1, "Sensor Unusual", "ping", 100, "Dev1", "", 60, types.StatusUnusual, now, now, nil, 3, "", nil, nil, "/s4", "").
+			AddRow(2, 1, "Sensor NoProbe", "ping", 100, "Dev1", "", 60, types.StatusNoProbe, now, now, nil, 3, "", nil, nil, "/s2", "").
+			AddRow(1, 1, "Sensor Unknown", "ping", 100, "Dev1", "", 60, types.StatusUnknown, now, now, nil, 3, "", nil, nil, "/s1", ""))
+
+	ctx := context.Background()
+	sensors, err := db.GetAlerts(ctx, types.AlertFilter{Hours: 24}, 0, 0)
+
+	require.NoError(t, err)
+	assert.Len(t, sensors, 7)
+
+	// Verify EXACT severity order as defined by ORDER BY CASE
+	expectedOrder := []struct {
+		status int
+		name   string
+	}{
+		{types.StatusDown, "Sensor Down"},
+		{types.StatusDownPartial, "Sensor DownPartial"},
+		{types.StatusDownAcknowledged, "Sensor DownAck"},
+		{types.StatusWarning, "Sensor Warning"},
+		{types.StatusUnusual, "Sensor Unusual"},
+		{types.StatusNoProbe, "Sensor NoProbe"},
+		{types.StatusUnknown, "Sensor Unknown"},
+	}
+
+	for i, expected := range expectedOrder {
+		assert.Equal(t, expected.status, sensors[i].Status,
+			"Position %d: expected status %d but got %d", i, expected.status, sensors[i].Status)
+		assert.Equal(t, expected.name, sensors[i].Name,
+			"Position %d: expected %s but got %s", i, expected.name, sensors[i].Name)
+	}
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// BenchmarkGetAlerts benchmarks the GetAlerts function.
+func BenchmarkGetAlerts(b *testing.B) {
+	mockDB, mock, err := sqlmock.New()
+	if err != nil {
+		b.Fatal(err)
+	}
+	defer mockDB.Close()
+
+	logger := zerolog.Nop()
+	db := &DB{
+		conn:   mockDB,
+		logger: &logger,
+	}
+
+	columns := []string{
+		"id", "prtg_server_address_id", "name", "sensor_type", "prtg_device_id",
+		"device_name", "device_host", "scanning_interval_seconds", "status", "last_check_utc",
+		"last_up_utc", "last_down_utc", "priority", "message",
+		"uptime_since_seconds", "downtime_since_seconds", "full_path", "tags",
+	}
+
+	now := time.Now()
+
+	for i := 0; i < b.N; i++ {
+		mock.ExpectQuery(`WHERE s\.status != \$1`).
+			WithArgs(types.StatusUp, 24, types.AlertsLimit, 0).
+			WillReturnRows(sqlmock.NewRows(columns).
+				AddRow(1, 1, "Sensor", "ping", 100, "Device", "", 60, types.StatusDown, now, now, &now, 5, "Timeout", nil, 100.0, "/root/sensor", ""))
+
 		ctx := context.Background()
-		_, _ = db.GetAlerts(ctx, 24, nil, "")
+		_, _ = db.GetAlerts(ctx, types.AlertFilter{Hours: 24}, 0, 0)
+	}
+}
+
+// searchColumns returns the column sets returned by the group, device and sensor search queries.
+func searchColumns() (groupColumns, deviceColumns, sensorColumns []string) {
+	groupColumns = []string{"id", "prtg_server_address_id", "name", "is_probe_node", "self_group_id", "full_path", "tree_depth"}
+	deviceColumns = []string{"id", "prtg_server_address_id", "name", "host", "prtg_group_id", "group_name", "full_path", "sensor_count", "tree_depth"}
+	sensorColumns = []string{
+		"id", "prtg_server_address_id", "name", "sensor_type", "prtg_device_id",
+		"device_name", "device_host", "scanning_interval_seconds", "status", "last_check_utc",
+		"last_up_utc", "last_down_utc", "priority", "message",
+		"uptime_since_seconds", "downtime_since_seconds", "full_path", "tags",
+	}
+
+	return groupColumns, deviceColumns, sensorColumns
+}
+
+// TestSearch_ExactSubstring validates that the default search keeps ILIKE substring matching.
+func TestSearch_ExactSubstring(t *testing.T) {
+	mockDB, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer mockDB.Close()
+
+	logger := zerolog.Nop()
+	db := &DB{
+		conn:   mockDB,
+		logger: &logger,
+	}
+
+	groupColumns, deviceColumns, sensorColumns := searchColumns()
+
+	mock.ExpectQuery(`FROM prtg_group g[\s\S]+WHERE g\.name ILIKE \$1\s+ORDER BY g\.name\s+LIMIT \$2`).
+		WithArgs("%web%", 50).
+		WillReturnRows(sqlmock.NewRows(groupColumns))
+	mock.ExpectQuery(`FROM prtg_device d[\s\S]+WHERE d\.name ILIKE \$1 OR d\.host ILIKE \$1\s+ORDER BY d\.name\s+LIMIT \$2`).
+		WithArgs("%web%", 50).
+		WillReturnRows(sqlmock.NewRows(deviceColumns).
+			AddRow(10, 1, "web-srv-01", "10.0.0.1", 2, "Servers", "/root/servers/web-srv-01", 5, 2))
+	mock.ExpectQuery(`FROM prtg_sensor s[\s\S]+WHERE s\.name ILIKE \$1 OR s\.sensor_type ILIKE \$1 OR s\.message ILIKE \$1\s+`+
+		`ORDER BY \(s\.name ILIKE \$1 OR s\.sensor_type ILIKE \$1\) DESC, s\.name\s+LIMIT \$2`).
+		WithArgs("%web%", 50).
+		WillReturnRows(sqlmock.NewRows(sensorColumns))
+
+	results, err := db.Search(context.Background(), "web", 0)
+
+	require.NoError(t, err)
+	assert.Len(t, results.Devices, 1)
+	assert.Empty(t, results.Groups)
+	assert.Empty(t, results.Sensors)
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// TestSearch_PartialResults validates that a failing category is reported while the
+// categories that succeeded are still returned.
+func TestSearch_PartialResults(t *testing.T) {
+	mockDB, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer mockDB.Close()
+
+	logger := zerolog.Nop()
+	db := &DB{conn: mockDB, logger: &logger}
+
+	groupColumns, _, sensorColumns := searchColumns()
+	now := time.Now()
+
+	mock.ExpectQuery(`FROM prtg_group g`).WithArgs("%web%", 50).
+		WillReturnRows(sqlmock.NewRows(groupColumns).AddRow(5, 1, "Web Servers", false, 1, "/Root/Web Servers", 1))
+	mock.ExpectQuery(`FROM prtg_device d`).WithArgs("%web%", 50).
+		WillReturnError(errors.New("pq: canceling statement due to statement timeout"))
+	mock.ExpectQuery(`FROM prtg_sensor s`).WithArgs("%web%", 50).
+		WillReturnRows(sqlmock.NewRows(sensorColumns).
+			AddRow(100, 1, "HTTP web01", "http", 10, "web01", "", 60, types.StatusUp, now, now, nil, 3, "OK", nil, nil, "/Root/web01/HTTP web01", ""))
+
+	results, err := db.Search(context.Background(), "web", 50)
+	require.NoError(t, err)
+
+	require.Len(t, results.Groups, 1)
+	require.Len(t, results.Sensors, 1)
+	assert.Empty(t, results.Devices)
+	assert.NotNil(t, results.Devices, "a failed category is an empty list, not null")
+
+	require.Len(t, results.Errors, 1)
+	assert.Equal(t, "devices", results.Errors[0].Category)
+	assert.Equal(t, "device search failed: pq: canceling statement due to statement timeout", results.Errors[0].Error)
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+
+	// Every category failing is an error
+	for _, table := range []string{"prtg_group g", "prtg_device d", "prtg_sensor s"} {
+		mock.ExpectQuery(`FROM ` + table).WillReturnError(errors.New("pq: connection reset"))
+	}
+
+	results, err = db.Search(context.Background(), "web", 50)
+	assert.Nil(t, results)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "device search failed: pq: connection reset")
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// TestSearch_MessageOnlyMatch validates that sensors are also found by their status message,
+// listed after name matches.
+func TestSearch_MessageOnlyMatch(t *testing.T) {
+	mockDB, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer mockDB.Close()
+
+	logger := zerolog.Nop()
+	db := &DB{conn: mockDB, logger: &logger}
+
+	groupColumns, deviceColumns, sensorColumns := searchColumns()
+	now := time.Now()
+
+	mock.ExpectQuery(`FROM prtg_group g`).WithArgs("%timeout%", 50).WillReturnRows(sqlmock.NewRows(groupColumns))
+	mock.ExpectQuery(`FROM prtg_device d`).WithArgs("%timeout%", 50).WillReturnRows(sqlmock.NewRows(deviceColumns))
+	mock.ExpectQuery(`FROM prtg_sensor s[\s\S]+OR s\.message ILIKE \$1`).
+		WithArgs("%timeout%", 50).
+		WillReturnRows(sqlmock.NewRows(sensorColumns).
+			AddRow(100, 1, "Timeout Monitor", "ping", 10, "web-srv-01", "", 60, types.StatusUp, now, now, nil, 3, "OK", nil, nil, "/root/web-srv-01/Timeout Monitor", "").
+			AddRow(101, 1, "HTTP", "http", 10, "web-srv-01", "", 60, types.StatusDown, now, now, &now, 4, "Connection timeout after 30s", nil, 60.0, "/root/web-srv-01/HTTP", ""))
+
+	results, err := db.Search(context.Background(), "timeout", 50)
+
+	require.NoError(t, err)
+	require.Len(t, results.Sensors, 2)
+	assert.Equal(t, "HTTP", results.Sensors[1].Name, "message-only match is returned")
+	assert.Equal(t, "Connection timeout after 30s", results.Sensors[1].Message)
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// TestSearchMessages validates the message-only search query.
+func TestSearchMessages(t *testing.T) {
+	mockDB, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer mockDB.Close()
+
+	logger := zerolog.Nop()
+	db := &DB{conn: mockDB, logger: &logger}
+
+	_, _, sensorColumns := searchColumns()
+	now := time.Now()
+
+	mock.ExpectQuery(`FROM prtg_sensor s[\s\S]+WHERE s\.message ILIKE \$1\s+`+
+		`ORDER BY s\.last_check_utc DESC NULLS LAST, s\.name\s+LIMIT \$2`).
+		WithArgs("%refused%", 50).
+		WillReturnRows(sqlmock.NewRows(sensorColumns).
+			AddRow(101, 1, "SMTP", "smtp", 10, "mail-01", "", 60, types.StatusDown, now, now, &now, 4, "Connection refused (10061)", nil, 60.0, "/root/mail-01/SMTP", ""))
+
+	sensors, err := db.SearchMessages(context.Background(), "refused", 0)
+
+	require.NoError(t, err)
+	require.Len(t, sensors, 1)
+	assert.Equal(t, "SMTP", sensors[0].Name)
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// TestGetSensorTypes validates the distinct sensor types query, its ordering and default limit.
+func TestGetSensorTypes(t *testing.T) {
+	mockDB, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer mockDB.Close()
+
+	logger := zerolog.Nop()
+	db := &DB{conn: mockDB, logger: &logger}
+
+	mock.ExpectQuery(`SELECT sensor_type, COUNT\(\*\) AS count\s+FROM prtg_sensor\s+` +
+		`WHERE sensor_type IS NOT NULL AND sensor_type != ''\s+GROUP BY sensor_type\s+` +
+		`ORDER BY count DESC, sensor_type\s+LIMIT \$1`).
+		WithArgs(100).
+		WillReturnRows(sqlmock.NewRows([]string{"sensor_type", "count"}).
+			AddRow("ping", 120).
+			AddRow("HTTP Advanced", 35).
+			AddRow("SNMP Traffic", 35))
+
+	sensorTypes, err := db.GetSensorTypes(context.Background(), 0)
+	require.NoError(t, err)
+
+	assert.Equal(t, []types.SensorTypeCount{
+		{Type: "ping", Count: 120},
+		{Type: "HTTP Advanced", Count: 35},
+		{Type: "SNMP Traffic", Count: 35},
+	}, sensorTypes)
+	assert.NoError(t, mock.ExpectationsWereMet())
+
+	// No sensors: an empty list, not nil
+	mock.ExpectQuery(`GROUP BY sensor_type`).
+		WithArgs(5).
+		WillReturnRows(sqlmock.NewRows([]string{"sensor_type", "count"}))
+
+	sensorTypes, err = db.GetSensorTypes(context.Background(), 5)
+	require.NoError(t, err)
+	assert.Empty(t, sensorTypes)
+	assert.NotNil(t, sensorTypes)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// TestGetMessageFrequency validates that sensors that are not up are grouped by trimmed message
+// text, most common message first, with sensors without message grouped together.
+func TestGetMessageFrequency(t *testing.T) {
+	mockDB, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer mockDB.Close()
+
+	logger := zerolog.Nop()
+	db := &DB{conn: mockDB, logger: &logger}
+
+	columns := []string{"message", "sensor_count", "device_count", "sample_sensors"}
+
+	mock.ExpectQuery(`SELECT\s+BTRIM\(COALESCE\(s\.message, ''\)\) AS message,\s+COUNT\(\*\) AS sensor_count,\s+`+
+		`COUNT\(DISTINCT \(s\.prtg_server_address_id, s\.prtg_device_id\)\) AS device_count,\s+`+
+		`\(ARRAY_AGG\(d\.name \|\| ' / ' \|\| s\.name ORDER BY s\.priority DESC, d\.name, s\.name\)\)\[1:3\] AS sample_sensors\s+`+
+		`FROM prtg_sensor s\s+INNER JOIN prtg_device d .*`+
+		`WHERE s\.status IN \(4,5,10,13,14\) AND s\.last_check_utc >= NOW\(\) - \(\$1 \|\| ' hours'\)::interval\s+`+
+		`GROUP BY 1\s+ORDER BY sensor_count DESC, message\s+LIMIT \$2`).
+		WithArgs(24, 10).
+		WillReturnRows(sqlmock.NewRows(columns).
+			AddRow("SNMP timeout", 82, 41, "{\"core-sw01 / CPU Load\",\"core-sw01 / Traffic\",\"core-sw02 / CPU Load\"}").
+			AddRow("No route to host", 12, 12, "{\"branch-fw03 / Ping\"}").
+			AddRow("", 2, 2, "{\"web01 / HTTP\",\"web02 / HTTP\"}"))
+
+	messages, err := db.GetMessageFrequency(context.Background(), 24, 10)
+	require.NoError(t, err)
+
+	assert.Equal(t, []types.MessageCount{
+		{Message: "SNMP timeout", SensorCount: 82, DeviceCount: 41,
+			SampleSensors: []string{"core-sw01 / CPU Load", "core-sw01 / Traffic", "core-sw02 / CPU Load"}},
+		{Message: "No route to host", SensorCount: 12, DeviceCount: 12, SampleSensors: []string{"branch-fw03 / Ping"}},
+		{Message: "", SensorCount: 2, DeviceCount: 2, SampleSensors: []string{"web01 / HTTP", "web02 / HTTP"}},
+	}, messages)
+	assert.NoError(t, mock.ExpectationsWereMet())
+
+	// hours 0 counts every sensor; no sensor in alert: an empty list, not nil
+	mock.ExpectQuery(`WHERE s\.status IN \(4,5,10,13,14\)\s+GROUP BY 1\s+ORDER BY sensor_count DESC, message\s+LIMIT \$1`).
+		WithArgs(20).
+		WillReturnRows(sqlmock.NewRows(columns))
+
+	messages, err = db.GetMessageFrequency(context.Background(), 0, 0)
+	require.NoError(t, err)
+	assert.Empty(t, messages)
+	assert.NotNil(t, messages)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// TestSearchFuzzy_UsesTrigramSimilarity validates that fuzzy search matches and orders by similarity.
+func TestSearchFuzzy_UsesTrigramSimilarity(t *testing.T) {
+	mockDB, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer mockDB.Close()
+
+	logger := zerolog.Nop()
+	db := &DB{
+		conn:   mockDB,
+		logger: &logger,
+	}
+
+	groupColumns, deviceColumns, sensorColumns := searchColumns()
+	now := time.Now()
+
+	mock.ExpectQuery(`FROM prtg_group g[\s\S]+WHERE g\.name ILIKE \$1 OR similarity\(g\.name, \$2\) >= \$3\s+`+
+		`ORDER BY similarity\(g\.name, \$2\) DESC, g\.name\s+LIMIT \$4`).
+		WithArgs("%websrv%", "websrv", 0.4, 20).
+		WillReturnRows(sqlmock.NewRows(groupColumns))
+	mock.ExpectQuery(`FROM prtg_device d[\s\S]+WHERE [\s\S]*similarity\(d\.name, \$2\)[\s\S]+>= \$3\s+`+
+		`ORDER BY GREATEST\(similarity\(d\.name, \$2\), similarity\(d\.host, \$2\)\) DESC, d\.name\s+LIMIT \$4`).
+		WithArgs("%websrv%", "websrv", 0.4, 20).
+		WillReturnRows(sqlmock.NewRows(deviceColumns).
+			AddRow(10, 1, "web-srv-01", "10.0.0.1", 2, "Servers", "/root/servers/web-srv-01", 5, 2).
+			AddRow(11, 1, "web-srv-02", "10.0.0.2", 2, "Servers", "/root/servers/web-srv-02", 3, 2))
+	mock.ExpectQuery(`FROM prtg_sensor s[\s\S]+WHERE [\s\S]*similarity\(s\.name, \$2\)[\s\S]+>= \$3\s+`+
+		`ORDER BY GREATEST\(similarity\(s\.name, \$2\), similarity\(s\.sensor_type, \$2\)\) DESC, s\.name\s+LIMIT \$4`).
+		WithArgs("%websrv%", "websrv", 0.4, 20).
+		WillReturnRows(sqlmock.NewRows(sensorColumns).
+			AddRow(100, 1, "Web-Srv HTTP", "http", 10, "web-srv-01", "", 60, types.StatusUp, now, now, nil, 3, "OK", nil, nil, "/root/servers/web-srv-01/http", ""))
+
+	results, err := db.SearchFuzzy(context.Background(), "websrv", 0.4, 20)
+
+	require.NoError(t, err)
+	require.Len(t, results.Devices, 2)
+	assert.Equal(t, "web-srv-01", results.Devices[0].Name)
+	assert.Len(t, results.Sensors, 1)
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// TestSearchFuzzy_MissingExtension validates the hint returned when pg_trgm is not installed.
+func TestSearchFuzzy_MissingExtension(t *testing.T) {
+	mockDB, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer mockDB.Close()
+
+	logger := zerolog.Nop()
+	db := &DB{
+		conn:   mockDB,
+		logger: &logger,
+	}
+
+	// Every category calls similarity(), so every category fails
+	for _, table := range []string{"prtg_group g", "prtg_device d", "prtg_sensor s"} {
+		mock.ExpectQuery(`FROM `+table).
+			WithArgs("%websrv%", "websrv", 0.3, 50).
+			WillReturnError(errors.New("pq: function similarity(text, unknown) does not exist"))
+	}
+
+	results, err := db.SearchFuzzy(context.Background(), "websrv", 0, 0)
+
+	assert.Nil(t, results)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "pg_trgm")
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// TestGetSensorTypeStatusBreakdown verifies the per-type cross-tab groups by sensor type and
+// counts statuses into down (incl. partial/acknowledged), warning (incl. unusual) and up.
+func TestGetSensorTypeStatusBreakdown(t *testing.T) {
+	mockDB, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer mockDB.Close()
+
+	logger := zerolog.Nop()
+	db := &DB{conn: mockDB, logger: &logger}
+
+	mock.ExpectQuery(`COUNT\(\*\) FILTER \(WHERE status = ANY\(\$1\)\) as down_count[\s\S]+`+
+		`COUNT\(\*\) FILTER \(WHERE status = ANY\(\$2\)\) as warning_count[\s\S]+`+
+		`COUNT\(\*\) FILTER \(WHERE status = \$3\) as up_count[\s\S]+`+
+		`FROM prtg_sensor[\s\S]+GROUP BY sensor_type[\s\S]+HAVING[\s\S]+ORDER BY[\s\S]+LIMIT \$4`).
+		WithArgs(
+			pq.Array([]int{types.StatusDown, types.StatusDownPartial, types.StatusDownAcknowledged}),
+			pq.Array([]int{types.StatusWarning, types.StatusUnusual}),
+			types.StatusUp,
+			5,
+		).
+		WillReturnRows(sqlmock.NewRows([]string{"sensor_type", "down_count", "warning_count", "up_count", "total_count"}).
+			AddRow("snmptraffic", 12, 3, 40, 56).
+			AddRow("ping", 2, 0, 98, 100))
+
+	breakdown, err := db.getSensorTypeStatusBreakdown(context.Background(), 5)
+	require.NoError(t, err)
+	require.Len(t, breakdown, 2)
+
+	assert.Equal(t, types.SensorTypeStatusCount{Type: "snmptraffic", Down: 12, Warning: 3, Up: 40, Total: 56}, breakdown[0])
+	assert.Equal(t, types.SensorTypeStatusCount{Type: "ping", Down: 2, Warning: 0, Up: 98, Total: 100}, breakdown[1])
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// TestGetStatistics_ProblemSensorTypes verifies the per-type breakdown is included in the statistics.
+func TestGetStatistics_ProblemSensorTypes(t *testing.T) {
+	mockDB, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer mockDB.Close()
+
+	logger := zerolog.Nop()
+	db := &DB{conn: mockDB, logger: &logger}
+
+	mock.ExpectQuery(`SELECT[\s\S]+reltuples`).
+		WillReturnRows(sqlmock.NewRows([]string{"total_sensors", "total_devices", "total_groups", "total_tags", "total_probes"}).
+			AddRow(100, 10, 5, 3, 1))
+	mock.ExpectQuery(`SELECT status, COUNT\(\*\)`).
+		WillReturnRows(sqlmock.NewRows([]string{"status", "count"}).
+			AddRow(types.StatusUp, 90).
+			AddRow(types.StatusDown, 10))
+	mock.ExpectQuery(`SELECT sensor_type, COUNT\(\*\)`).
+		WillReturnRows(sqlmock.NewRows([]string{"sensor_type", "count"}).
+			AddRow("ping", 60))
+	mock.ExpectQuery(`GROUP BY sensor_type[\s\S]+HAVING`).
+		WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg(), types.StatusUp, problemSensorTypesLimit).
+		WillReturnRows(sqlmock.NewRows([]string{"sensor_type", "down_count", "warning_count", "up_count", "total_count"}).
+			AddRow("snmptraffic", 10, 0, 30, 40))
+
+	stats, err := db.GetStatistics(context.Background())
+	require.NoError(t, err)
+
+	assert.Equal(t, 90, stats.SensorsByStatus["Up"])
+	require.Len(t, stats.ProblemSensorTypes, 1)
+	assert.Equal(t, "snmptraffic", stats.ProblemSensorTypes[0].Type)
+	assert.Equal(t, 10, stats.ProblemSensorTypes[0].Down)
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// TestGetRecentChanges verifies the time window filter on last up/down times and that
+// both up and down transitions are returned and labeled, newest first.
+func TestGetRecentChanges(t *testing.T) {
+	mockDB, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer mockDB.Close()
+
+	logger := zerolog.Nop()
+	db := &DB{conn: mockDB, logger: &logger}
+
+	columns := []string{
+		"id", "prtg_server_address_id", "name", "sensor_type", "prtg_device_id",
+		"device_name", "device_host", "scanning_interval_seconds", "status", "last_check_utc",
+		"last_up_utc", "last_down_utc", "priority", "message",
+		"uptime_since_seconds", "downtime_since_seconds", "full_path", "tags",
+	}
+
+	now := time.Now().UTC()
+	wentDownAt := now.Add(-2 * time.Minute)
+	cameUpAt := now.Add(-5 * time.Minute)
+	earlierDown := now.Add(-10 * time.Minute)
+
+	mock.ExpectQuery(`WHERE s\.last_down_utc >= NOW\(\) - \(\$1 \|\| ' minutes'\)::interval\s+`+
+		`OR s\.last_up_utc >= NOW\(\) - \(\$1 \|\| ' minutes'\)::interval\s+`+
+		`ORDER BY GREATEST\(s\.last_down_utc, s\.last_up_utc\) DESC, s\.name\s+LIMIT \$2`).
+		WithArgs(15, 50).
+		WillReturnRows(sqlmock.NewRows(columns).
+			// Went down after its last up time
+			AddRow(1, 1, "Ping", "ping", 100, "web-srv-01", "", 60, types.StatusDown, now, now.Add(-time.Hour), wentDownAt, 4, "Timeout", nil, 120.0, "/root/web-srv-01/Ping", "").
+			// Recovered: last up time is after the last down time
+			AddRow(2, 1, "HTTP", "http", 101, "web-srv-02", "", 60, types.StatusUp, now, cameUpAt, earlierDown, 3, "OK", 300.0, nil, "/root/web-srv-02/HTTP", ""))
+
+	changes, err := db.GetRecentChanges(context.Background(), 15, 50)
+	require.NoError(t, err)
+	require.Len(t, changes, 2)
+
+	assert.Equal(t, "Ping", changes[0].Name)
+	assert.Equal(t, types.TransitionDown, changes[0].Transition)
+	assert.True(t, changes[0].ChangedAt.Equal(wentDownAt))
+
+	assert.Equal(t, "HTTP", changes[1].Name)
+	assert.Equal(t, types.TransitionUp, changes[1].Transition)
+	assert.True(t, changes[1].ChangedAt.Equal(cameUpAt))
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// TestGetTopSensors_RecentChanges validates that the recent_changes metric keeps problem sensors
+// and ranks them by their last down transition, sensors without one last.
+func TestGetTopSensors_RecentChanges(t *testing.T) {
+	mockDB, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer mockDB.Close()
+
+	logger := zerolog.Nop()
+	db := &DB{conn: mockDB, logger: &logger}
+
+	columns := []string{
+		"id", "prtg_server_address_id", "name", "sensor_type", "prtg_device_id",
+		"device_name", "device_host", "scanning_interval_seconds", "status", "last_check_utc",
+		"last_up_utc", "last_down_utc", "priority", "message",
+		"uptime_since_seconds", "downtime_since_seconds", "full_path", "tags",
+	}
+
+	now := time.Now().UTC()
+
+	mock.ExpectQuery(`WHERE s\.status IN \(4,5,10,13,14\) ` +
+		`ORDER BY s\.last_down_utc DESC NULLS LAST, s\.name LIMIT \$1$`).
+		WithArgs(10).
+		WillReturnRows(sqlmock.NewRows(columns).
+			AddRow(1, 1, "HTTP", "http", 100, "web-srv-01", "", 60, types.StatusDown, now, now.Add(-time.Hour), now.Add(-5*time.Minute), 3, "Timeout", nil, 300.0, "/root/web-srv-01/HTTP", "").
+			AddRow(2, 1, "Disk", "wmi", 101, "db-srv-01", "", 60, types.StatusWarning, now, now.Add(-time.Hour), nil, 3, "85 %", 3600.0, nil, "/root/db-srv-01/Disk", ""))
+
+	sensors, err := db.GetTopSensors(context.Background(), "recent_changes", "", 10, 24)
+	require.NoError(t, err)
+	require.Len(t, sensors, 2)
+
+	assert.Equal(t, "HTTP", sensors[0].Name)
+	require.NotNil(t, sensors[0].LastDownUTC)
+	assert.Equal(t, "Disk", sensors[1].Name)
+	assert.Nil(t, sensors[1].LastDownUTC)
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestLabelStatusChange(t *testing.T) {
+	now := time.Now()
+	earlier := now.Add(-time.Minute)
+
+	t.Run("never down is up", func(t *testing.T) {
+		change := labelStatusChange(types.Sensor{LastUpUTC: now})
+		assert.Equal(t, types.TransitionUp, change.Transition)
+		assert.True(t, change.ChangedAt.Equal(now))
+	})
+
+	t.Run("down after up is down", func(t *testing.T) {
+		change := labelStatusChange(types.Sensor{LastUpUTC: earlier, LastDownUTC: &now})
+		assert.Equal(t, types.TransitionDown, change.Transition)
+		assert.True(t, change.ChangedAt.Equal(now))
+	})
+
+	t.Run("up after down is up", func(t *testing.T) {
+		change := labelStatusChange(types.Sensor{LastUpUTC: now, LastDownUTC: &earlier})
+		assert.Equal(t, types.TransitionUp, change.Transition)
+		assert.True(t, change.ChangedAt.Equal(now))
+	})
+}
+
+// TestGetHierarchy_BreadthLimits validates that max_children and the per-device sensor limit are
+// passed to the queries, and that truncated nodes are annotated with their totals.
+func TestGetHierarchy_BreadthLimits(t *testing.T) {
+	mockDB, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer mockDB.Close()
+
+	logger := zerolog.Nop()
+	db := &DB{
+		conn:   mockDB,
+		logger: &logger,
+	}
+
+	groupColumns, deviceColumns, sensorColumns := searchColumns()
+	now := time.Now()
+
+	mock.ExpectQuery(`FROM prtg_group g[\s\S]+WHERE g\.name ILIKE \$1 ORDER BY g\.tree_depth, g\.name LIMIT \$2`).
+		WithArgs("%Servers%", 1).
+		WillReturnRows(sqlmock.NewRows(groupColumns).AddRow(2, 1, "Servers", false, nil, "/root/servers", 1))
+
+	// max_children + 1 devices are fetched to detect truncation
+	mock.ExpectQuery(`FROM prtg_device d[\s\S]+WHERE d\.prtg_group_id = \$1\s+ORDER BY d\.name LIMIT \$2`).
+		WithArgs(2, 3).
+		WillReturnRows(sqlmock.NewRows(deviceColumns).
+			AddRow(10, 1, "srv-01", "10.0.0.1", 2, "Servers", "/root/servers/srv-01", 120, 2).
+			AddRow(11, 1, "srv-02", "10.0.0.2", 2, "Servers", "/root/servers/srv-02", 1, 2).
+			AddRow(12, 1, "srv-03", "10.0.0.3", 2, "Servers", "/root/servers/srv-03", 1, 2))
+	mock.ExpectQuery(`SELECT COUNT\(\*\) FROM prtg_device WHERE prtg_group_id = \$1`).
+		WithArgs(2).
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(214))
+
+	mock.ExpectQuery(`FROM prtg_sensor s[\s\S]+ORDER BY s\.name\s+LIMIT \$4`).
+		WithArgs(10, "srv-01", 1, 1, "10.0.0.1").
+		WillReturnRows(sqlmock.NewRows(sensorColumns).
+			AddRow(100, 1, "Ping", "ping", 10, "srv-01", "10.0.0.1", 60, 3, now, now, nil, 3, "OK", nil, nil, "/root/servers/srv-01/ping", ""))
+	mock.ExpectQuery(`FROM prtg_sensor s[\s\S]+ORDER BY s\.name\s+LIMIT \$4`).
+		WithArgs(11, "srv-02", 1, 1, "10.0.0.2").
+		WillReturnRows(sqlmock.NewRows(sensorColumns).
+			AddRow(101, 1, "Ping", "ping", 11, "srv-02", "10.0.0.2", 60, 3, now, now, nil, 3, "OK", nil, nil, "/root/servers/srv-02/ping", ""))
+
+	mock.ExpectQuery(`FROM prtg_group g[\s\S]+WHERE g\.self_group_id = \$1 ORDER BY g\.tree_depth, g\.name LIMIT \$2`).
+		WithArgs(2, 3).
+		WillReturnRows(sqlmock.NewRows(groupColumns))
+
+	node, err := db.GetHierarchy(context.Background(), "Servers", types.HierarchyOptions{
+		IncludeSensors:      true,
+		MaxDepth:            1,
+		MaxChildren:         2,
+		MaxSensorsPerDevice: 1,
+	})
+	require.NoError(t, err)
+
+	require.Len(t, node.Devices, 2)
+	assert.Equal(t, 214, node.TotalDevices)
+	assert.Zero(t, node.TotalGroups, "child groups were not truncated")
+	assert.False(t, node.NodeLimitReached)
+	assert.Equal(t, 120, node.Devices[0].TotalSensors)
+	assert.Zero(t, node.Devices[1].TotalSensors, "all sensors of srv-02 are listed")
+	assert.Equal(t, "10.0.0.1", node.Devices[0].Sensors[0].DeviceHost, "host is passed from the device row")
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// TestExecuteCustomQuery_StatementTimeout validates that the statement timeout is set in the query transaction before the query runs.
+func TestExecuteCustomQuery_StatementTimeout(t *testing.T) {
+	mockDB, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer mockDB.Close()
+
+	logger := zerolog.Nop()
+	db := &DB{
+		conn:   mockDB,
+		logger: &logger,
+	}
+
+	// Without a timeout the query runs directly on the pool
+	mock.ExpectQuery(`SELECT \* FROM \(SELECT id FROM prtg_sensor\) q LIMIT \$1`).
+		WithArgs(10).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+
+	_, err = db.ExecuteCustomQuery(context.Background(), "SELECT id FROM prtg_sensor", 10)
+	require.NoError(t, err)
+	require.NoError(t, mock.ExpectationsWereMet())
+
+	// With a timeout, SET LOCAL is issued first in a transaction that also runs the query
+	db.SetStatementTimeout(5 * time.Second)
+
+	mock.ExpectBegin()
+	mock.ExpectExec(`SET LOCAL statement_timeout = 5000`).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery(`SELECT \* FROM \(SELECT id FROM prtg_sensor\) q LIMIT \$1`).
+		WithArgs(10).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1).AddRow(2))
+	mock.ExpectRollback()
+
+	results, err := db.ExecuteCustomQuery(context.Background(), "SELECT id FROM prtg_sensor", 10)
+	require.NoError(t, err)
+	assert.Len(t, results, 2)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// TestStreamCustomQuery validates that rows are passed one at a time through a single scan buffer,
+// with the query wrapped in a LIMIT one row past the cap to detect truncation.
+func TestStreamCustomQuery(t *testing.T) {
+	mockDB, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer mockDB.Close()
+
+	logger := zerolog.Nop()
+	db := &DB{
+		conn:   mockDB,
+		logger: &logger,
+	}
+
+	rows := sqlmock.NewRows([]string{"id", "name"})
+	for i := 1; i <= types.MaxCustomQueryRows+1; i++ {
+		rows.AddRow(i, fmt.Sprintf("sensor-%d", i))
+	}
+
+	mock.ExpectQuery(`^SELECT \* FROM \(SELECT id, name FROM prtg_sensor\) q LIMIT \$1$`).
+		WithArgs(types.MaxCustomQueryRows + 1).
+		WillReturnRows(rows)
+
+	var (
+		kept     []string
+		buffers  = map[*interface{}]bool{}
+		idSum    int64
+		lastName string
+	)
+
+	// A limit above the cap is lowered to the cap
+	count, truncated, err := db.StreamCustomQuery(context.Background(), "SELECT id, name FROM prtg_sensor", 5000,
+		func(columns []string, values []interface{}) error {
+			assert.Equal(t, []string{"id", "name"}, columns)
+			buffers[&values[0]] = true
+
+			idSum += values[0].(int64)
+			lastName = values[1].(string)
+
+			// The caller decides how many rows to keep
+			if len(kept) < 10 {
+				kept = append(kept, values[1].(string))
+			}
+
+			return nil
+		})
+	require.NoError(t, err)
+
+	assert.Equal(t, types.MaxCustomQueryRows, count)
+	assert.True(t, truncated, "the row past the cap is detected")
+	assert.Len(t, kept, 10)
+	assert.Equal(t, "sensor-1", kept[0])
+	assert.Equal(t, "sensor-1000", lastName)
+	assert.Equal(t, int64(types.MaxCustomQueryRows*(types.MaxCustomQueryRows+1)/2), idSum)
+	assert.Len(t, buffers, 1, "rows must reuse the same scan buffer")
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// TestStreamCustomQuery_Errors validates query validation and callback errors.
+func TestStreamCustomQuery_Errors(t *testing.T) {
+	mockDB, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer mockDB.Close()
+
+	logger := zerolog.Nop()
+	db := &DB{
+		conn:   mockDB,
+		logger: &logger,
+	}
+
+	noop := func([]string, []interface{}) error { return nil }
+
+	_, _, err = db.StreamCustomQuery(context.Background(), "DELETE FROM prtg_sensor", 10, noop)
+	assert.ErrorContains(t, err, "only SELECT queries are allowed")
+
+	stop := errors.New("stop")
+
+	mock.ExpectQuery(`^SELECT \* FROM \(SELECT id FROM prtg_sensor\) q LIMIT \$1$`).
+		WithArgs(11).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1).AddRow(2))
+
+	count, _, err := db.StreamCustomQuery(context.Background(), "SELECT id FROM prtg_sensor", 10, func([]string, []interface{}) error {
+		return stop
+	})
+	assert.ErrorIs(t, err, stop)
+	assert.Equal(t, 0, count)
+
+	// A LIMIT in the query does not lift the cap, and the default applies without a limit
+	mock.ExpectQuery(`^SELECT \* FROM \(SELECT id FROM prtg_sensor LIMIT 5000\) q LIMIT \$1$`).
+		WithArgs(types.CustomQueryLimit + 1).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1).AddRow(2))
+
+	count, truncated, err := db.StreamCustomQuery(context.Background(), "SELECT id FROM prtg_sensor LIMIT 5000", 0, noop)
+	require.NoError(t, err)
+	assert.Equal(t, 2, count)
+	assert.False(t, truncated)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// TestGetHierarchy_StatementTimeout validates that hierarchy queries join the statement timeout transaction.
+func TestGetHierarchy_StatementTimeout(t *testing.T) {
+	mockDB, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer mockDB.Close()
+
+	logger := zerolog.Nop()
+	db := &DB{
+		conn:   mockDB,
+		logger: &logger,
+	}
+	db.SetStatementTimeout(250 * time.Millisecond)
+
+	mock.ExpectBegin()
+	mock.ExpectExec(`SET LOCAL statement_timeout = 250`).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery(`FROM prtg_group g`).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "prtg_server_address_id", "name", "is_probe_node", "self_group_id", "full_path", "tree_depth"}))
+	mock.ExpectRollback()
+
+	_, err = db.GetHierarchy(context.Background(), "", types.HierarchyOptions{})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "no groups found")
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// TestGetDevicePath validates that the ancestors of a nested device are resolved from the probe down.
+func TestGetDevicePath(t *testing.T) {
+	mockDB, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer mockDB.Close()
+
+	logger := zerolog.Nop()
+	db := &DB{
+		conn:   mockDB,
+		logger: &logger,
+	}
+
+	groupColumns, deviceColumns, _ := searchColumns()
+	deviceQuery := `FROM prtg_device d[\s\S]+WHERE d\.id = \$1`
+	groupQuery := `FROM prtg_group g[\s\S]+WHERE g\.id = \$1 AND g\.prtg_server_address_id = \$2 LIMIT 1`
+	ctx := context.Background()
+
+	t.Run("nested device", func(t *testing.T) {
+		mock.ExpectQuery(deviceQuery).WithArgs(40).
+			WillReturnRows(sqlmock.NewRows(deviceColumns).
+				AddRow(40, 1, "db01", "10.0.0.40", 30, "Databases", "/Local Probe/Servers/Databases/db01", 12, 3))
+		mock.ExpectQuery(groupQuery).WithArgs(30, 1).
+			WillReturnRows(sqlmock.NewRows(groupColumns).AddRow(30, 1, "Databases", false, 20, "/Local Probe/Servers/Databases", 2))
+		mock.ExpectQuery(groupQuery).WithArgs(20, 1).
+			WillReturnRows(sqlmock.NewRows(groupColumns).AddRow(20, 1, "Servers", false, 10, "/Local Probe/Servers", 1))
+		mock.ExpectQuery(groupQuery).WithArgs(10, 1).
+			WillReturnRows(sqlmock.NewRows(groupColumns).AddRow(10, 1, "Local Probe", true, nil, "/Local Probe", 0))
+
+		path, err := db.GetDevicePath(ctx, 40)
+		require.NoError(t, err)
+
+		assert.Equal(t, "db01", path.Device.Name)
+		require.Len(t, path.Ancestors, 3)
+		assert.Equal(t, []string{"Local Probe", "Servers", "Databases"},
+			[]string{path.Ancestors[0].Name, path.Ancestors[1].Name, path.Ancestors[2].Name})
+		assert.True(t, path.Ancestors[0].IsProbeNode)
+		assert.Nil(t, path.Ancestors[0].ParentID)
+		assert.Equal(t, 10, *path.Ancestors[1].ParentID)
+		assert.Equal(t, path.Device.GroupID, path.Ancestors[2].ID)
+	})
+
+	t.Run("unknown device", func(t *testing.T) {
+		mock.ExpectQuery(deviceQuery).WithArgs(999).WillReturnError(sql.ErrNoRows)
+
+		_, err := db.GetDevicePath(ctx, 999)
+		assert.EqualError(t, err, "device not found")
+	})
+
+	t.Run("parent links loop", func(t *testing.T) {
+		mock.ExpectQuery(deviceQuery).WithArgs(41).
+			WillReturnRows(sqlmock.NewRows(deviceColumns).AddRow(41, 1, "web01", "", 31, "A", "/A/B/A/web01", 1, 3))
+		mock.ExpectQuery(groupQuery).WithArgs(31, 1).
+			WillReturnRows(sqlmock.NewRows(groupColumns).AddRow(31, 1, "A", false, 32, "/B/A", 1))
+		mock.ExpectQuery(groupQuery).WithArgs(32, 1).
+			WillReturnRows(sqlmock.NewRows(groupColumns).AddRow(32, 1, "B", false, 31, "/A/B", 1))
+
+		_, err := db.GetDevicePath(ctx, 41)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "loops or is too deep at group 31")
+	})
+
+	t.Run("servers sharing group IDs", func(t *testing.T) {
+		// Server 2 numbers its groups like server 1: the walk stays on the device's server
+		mock.ExpectQuery(deviceQuery).WithArgs(50).
+			WillReturnRows(sqlmock.NewRows(deviceColumns).AddRow(50, 2, "branch-fw", "10.2.0.1", 30, "Firewalls", "/Branch Probe/Firewalls/branch-fw", 4, 2))
+		mock.ExpectQuery(groupQuery).WithArgs(30, 2).
+			WillReturnRows(sqlmock.NewRows(groupColumns).AddRow(30, 2, "Firewalls", false, 0, "/Branch Probe/Firewalls", 1))
+		mock.ExpectQuery(groupQuery).WithArgs(0, 2).
+			WillReturnRows(sqlmock.NewRows(groupColumns).AddRow(0, 2, "Branch Probe", true, nil, "/Branch Probe", 0))
+
+		path, err := db.GetDevicePath(ctx, 50)
+		require.NoError(t, err)
+
+		require.Len(t, path.Ancestors, 2)
+		assert.Equal(t, []string{"Branch Probe", "Firewalls"}, []string{path.Ancestors[0].Name, path.Ancestors[1].Name})
+		assert.Equal(t, 2, path.Ancestors[0].ServerID)
+		assert.Equal(t, 2, path.Ancestors[1].ServerID)
+	})
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// TestGetSensorHistoryFromDB validates that one row per channel and timestamp is pivoted into data points.
+func TestGetSensorHistoryFromDB(t *testing.T) {
+	mockDB, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer mockDB.Close()
+
+	logger := zerolog.Nop()
+	db := &DB{
+		conn:   mockDB,
+		logger: &logger,
+	}
+
+	start := time.Date(2025, 10, 30, 14, 0, 0, 0, time.UTC)
+	end := start.Add(time.Hour)
+
+	// Without a history table nothing is queried
+	_, err = db.GetSensorHistoryFromDB(context.Background(), 1234, start, end)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "no history table configured")
+
+	db.SetHistoryTable("metrics.prtg_channel_history")
+
+	mock.ExpectQuery(`SELECT timestamp_utc, channel_name, value\s+FROM "metrics"\."prtg_channel_history"\s+WHERE prtg_sensor_id = \$1\s+AND timestamp_utc >= \$2\s+AND timestamp_utc <= \$3\s+ORDER BY timestamp_utc DESC, channel_name\s+LIMIT \$4`).
+		WithArgs(1234, start, end, historyMaxRows+1).
+		WillReturnRows(sqlmock.NewRows([]string{"timestamp_utc", "channel_name", "value"}).
+			AddRow(start.Add(time.Minute), "Downtime", nil).
+			AddRow(start.Add(time.Minute), "Response Time", 14.0).
+			AddRow(start, "Downtime", 0.0).
+			AddRow(start, "Response Time", 12.5))
+
+	data, err := db.GetSensorHistoryFromDB(context.Background(), 1234, start, end)
+	require.NoError(t, err)
+
+	assert.Equal(t, 1234, data.ObjectID)
+	assert.Equal(t, start, *data.StartTime)
+	assert.Equal(t, []string{"timestamp", "Downtime", "Response Time"}, data.Headers)
+	require.Len(t, data.DataPoints, 2)
+	assert.Equal(t, map[string]interface{}{"Response Time": 12.5, "Downtime": 0.0}, data.DataPoints[0].Values)
+	assert.Equal(t, start.Add(time.Minute), data.DataPoints[1].Timestamp)
+	assert.Equal(t, map[string]interface{}{"Response Time": 14.0}, data.DataPoints[1].Values, "NULL values are left out")
+	assert.False(t, data.Truncated)
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// TestGetSensorHistoryFromDB_Truncated validates that the newest rows are kept when the range
+// holds more than historyMaxRows rows, and that the possibly partial oldest data point is dropped.
+func TestGetSensorHistoryFromDB_Truncated(t *testing.T) {
+	mockDB, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer mockDB.Close()
+
+	logger := zerolog.Nop()
+	db := &DB{
+		conn:   mockDB,
+		logger: &logger,
+	}
+	db.SetHistoryTable("prtg_channel_history")
+
+	end := time.Date(2025, 10, 30, 14, 0, 0, 0, time.UTC)
+	start := end.Add(-30 * 24 * time.Hour)
+
+	// Newest first: one channel at end, then two channels per minute, so the cap falls
+	// between the channels of the oldest timestamp read
+	rows := sqlmock.NewRows([]string{"timestamp_utc", "channel_name", "value"}).
+		AddRow(end, "Response Time", 12.5)
+	for i := 0; i < historyMaxRows; i++ {
+		rows.AddRow(end.Add(-time.Duration(1+i/2)*time.Minute), []string{"Downtime", "Response Time"}[i%2], float64(i))
+	}
+
+	mock.ExpectQuery(`ORDER BY timestamp_utc DESC, channel_name\s+LIMIT \$4`).
+		WithArgs(1234, start, end, historyMaxRows+1).
+		WillReturnRows(rows)
+
+	data, err := db.GetSensorHistoryFromDB(context.Background(), 1234, start, end)
+	require.NoError(t, err)
+
+	assert.True(t, data.Truncated)
+	require.Len(t, data.DataPoints, historyMaxRows/2)
+	assert.Equal(t, end, data.DataPoints[len(data.DataPoints)-1].Timestamp, "the newest data point is kept")
+	assert.Equal(t, end.Add(-time.Duration(historyMaxRows/2-1)*time.Minute), data.DataPoints[0].Timestamp, "oldest first")
+
+	for _, point := range data.DataPoints[:len(data.DataPoints)-1] {
+		assert.Len(t, point.Values, 2, "no partial data point at %s", point.Timestamp)
+	}
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// TestGetLatestChannelValues validates reading the latest value of each channel from the history table.
+func TestGetLatestChannelValues(t *testing.T) {
+	mockDB, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer mockDB.Close()
+
+	logger := zerolog.Nop()
+	db := &DB{
+		conn:   mockDB,
+		logger: &logger,
+	}
+
+	since := time.Date(2025, 10, 30, 14, 0, 0, 0, time.UTC)
+
+	// Without a history table nothing is queried
+	_, err = db.GetLatestChannelValues(context.Background(), since, 100)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "no history table configured")
+
+	db.SetHistoryTable("prtg_channel_history")
+
+	mock.ExpectQuery(`SELECT DISTINCT ON \(prtg_sensor_id, channel_name\)[\s\S]+FROM "prtg_channel_history"\s+WHERE timestamp_utc >= \$1[\s\S]+ORDER BY prtg_sensor_id, channel_name, timestamp_utc DESC\s+LIMIT \$2`).
+		WithArgs(since, 100).
+		WillReturnRows(sqlmock.NewRows([]string{"prtg_sensor_id", "channel_name", "value", "timestamp_utc"}).
+			AddRow(1234, "Downtime", 0.0, since).
+			AddRow(1234, "Response Time", 14.0, since.Add(time.Minute)))
+
+	values, err := db.GetLatestChannelValues(context.Background(), since, 100)
+	require.NoError(t, err)
+	require.Len(t, values, 2)
+	assert.Equal(t, types.ChannelValue{SensorID: 1234, Channel: "Response Time", Value: 14.0, Timestamp: since.Add(time.Minute)}, values[1])
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// TestGetHierarchy_NodeBudget validates that the node budget stops an unlimited-depth traversal.
+func TestGetHierarchy_NodeBudget(t *testing.T) {
+	mockDB, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer mockDB.Close()
+
+	logger := zerolog.Nop()
+	db := &DB{
+		conn:   mockDB,
+		logger: &logger,
+	}
+
+	groupColumns, deviceColumns, _ := searchColumns()
+
+	mock.ExpectQuery(`FROM prtg_group g[\s\S]+WHERE g\.self_group_id IS NULL`).
+		WillReturnRows(sqlmock.NewRows(groupColumns).AddRow(1, 1, "Root", false, nil, "/root", 0))
+
+	// Root: 2 devices and 2 child groups, the budget of 4 nodes covers root, both devices and Branch A
+	mock.ExpectQuery(`FROM prtg_device d[\s\S]+WHERE d\.prtg_group_id = \$1\s+ORDER BY d\.name LIMIT \$2`).
+		WithArgs(1, 51).
+		WillReturnRows(sqlmock.NewRows(deviceColumns).
+			AddRow(10, 1, "core-01", "10.0.0.1", 1, "Root", "/root/core-01", 0, 1).
+			AddRow(11, 1, "core-02", "10.0.0.2", 1, "Root", "/root/core-02", 0, 1))
+	mock.ExpectQuery(`FROM prtg_group g[\s\S]+WHERE g\.self_group_id = \$1 ORDER BY g\.tree_depth, g\.name LIMIT \$2`).
+		WithArgs(1, 51).
+		WillReturnRows(sqlmock.NewRows(groupColumns).
+			AddRow(2, 1, "Branch A", false, 1, "/root/a", 1).
+			AddRow(3, 1, "Branch B", false, 1, "/root/b", 1))
+
+	// Branch A: its devices no longer fit, its children and Branch B are never queried
+	mock.ExpectQuery(`FROM prtg_device d[\s\S]+WHERE d\.prtg_group_id = \$1\s+ORDER BY d\.name LIMIT \$2`).
+		WithArgs(2, 51).
+		WillReturnRows(sqlmock.NewRows(deviceColumns).
+			AddRow(20, 1, "a-01", "10.1.0.1", 2, "Branch A", "/root/a/a-01", 0, 2))
+
+	node, err := db.GetHierarchy(context.Background(), "", types.HierarchyOptions{MaxDepth: 0, MaxNodes: 4})
+	require.NoError(t, err)
+
+	assert.True(t, node.NodeLimitReached)
+	assert.Len(t, node.Devices, 2)
+	require.Len(t, node.Groups, 1)
+	assert.Equal(t, "Branch A", node.Groups[0].Group.Name)
+	assert.Empty(t, node.Groups[0].Devices)
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// TestGetHierarchy_Cancelled validates that a context cancelled during the traversal stops it
+// before the next query and returns the context error.
+func TestGetHierarchy_Cancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var queries int
+
+	mockDB, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherFunc(
+		func(expectedSQL, actualSQL string) error {
+			queries++
+
+			// The client goes away while the child groups of the first group are fetched
+			if strings.Contains(actualSQL, "self_group_id = $1") {
+				cancel()
+			}
+
+			return sqlmock.QueryMatcherRegexp.Match(expectedSQL, actualSQL)
+		},
+	)))
+	require.NoError(t, err)
+	defer mockDB.Close()
+
+	logger := zerolog.Nop()
+	db := &DB{
+		conn:   mockDB,
+		logger: &logger,
+	}
+
+	groupColumns, deviceColumns, _ := searchColumns()
+
+	mock.ExpectQuery(`FROM prtg_group g[\s\S]+WHERE g\.self_group_id IS NULL`).
+		WillReturnRows(sqlmock.NewRows(groupColumns).AddRow(1, 1, "Root", false, nil, "/root", 0))
+	mock.ExpectQuery(`FROM prtg_device d[\s\S]+WHERE d\.prtg_group_id = \$1\s+ORDER BY d\.name LIMIT \$2`).
+		WithArgs(1, 51).
+		WillReturnRows(sqlmock.NewRows(deviceColumns))
+	mock.ExpectQuery(`FROM prtg_group g[\s\S]+WHERE g\.self_group_id = \$1 ORDER BY g\.tree_depth, g\.name LIMIT \$2`).
+		WithArgs(1, 51).
+		WillReturnRows(sqlmock.NewRows(groupColumns).
+			AddRow(2, 1, "Branch A", false, 1, "/root/a", 1).
+			AddRow(3, 1, "Branch B", false, 1, "/root/b", 1))
+
+	_, err = db.GetHierarchy(ctx, "", types.HierarchyOptions{})
+	require.ErrorIs(t, err, context.Canceled)
+
+	// Neither branch is queried once the context is cancelled
+	assert.Equal(t, 3, queries)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// TestGetEmptyDevices validates that devices are filtered with a NOT EXISTS subquery on their
+// sensors, so a device without sensors is returned and a device with sensors is not.
+func TestGetEmptyDevices(t *testing.T) {
+	mockDB, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer mockDB.Close()
+
+	logger := zerolog.Nop()
+	db := &DB{
+		conn:   mockDB,
+		logger: &logger,
+	}
+
+	_, deviceColumns, _ := searchColumns()
+
+	// web01 (ID 100) has sensors and is excluded by the subquery; spare01 (ID 101) has none
+	mock.ExpectQuery(`FROM prtg_device d[\s\S]+WHERE NOT EXISTS \(\s+SELECT 1 FROM prtg_sensor s\s+` +
+		`WHERE s\.prtg_device_id = d\.id\s+AND s\.prtg_server_address_id = d\.prtg_server_address_id\s+\)\s+` +
+		`ORDER BY dp\.path, d\.id LIMIT \$1`).
+		WithArgs(50).
+		WillReturnRows(sqlmock.NewRows(deviceColumns).
+			AddRow(101, 1, "spare01", "10.0.0.101", 10, "Servers", "/Root/Servers/spare01", 0, 2))
+
+	devices, err := db.GetEmptyDevices(context.Background(), 50)
+	require.NoError(t, err)
+
+	require.Len(t, devices, 1)
+	assert.Equal(t, "spare01", devices[0].Name)
+	assert.Equal(t, 0, devices[0].SensorCount)
+
+	for _, device := range devices {
+		assert.NotEqual(t, 100, device.ID, "a device with sensors must not be returned")
+	}
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// TestGetTagCoverage validates the sensor counts, the per-group and per-device ranking by
+// untagged sensors, and full coverage when there is no sensor.
+func TestGetTagCoverage(t *testing.T) {
+	mockDB, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer mockDB.Close()
+
+	logger := zerolog.Nop()
+	db := &DB{
+		conn:   mockDB,
+		logger: &logger,
+	}
+
+	// Untagged sensors are those without a row in prtg_sensor_tag
+	mock.ExpectQuery(`COUNT\(\*\) FILTER \(WHERE st\.prtg_sensor_id IS NULL\) AS untagged_sensors\s+` +
+		`FROM prtg_sensor s\s+LEFT JOIN \(\s+SELECT DISTINCT prtg_sensor_id, prtg_server_address_id FROM prtg_sensor_tag`).
+		WithoutArgs().
+		WillReturnRows(sqlmock.NewRows([]string{"total_sensors", "untagged_sensors"}).AddRow(200, 30))
+
+	// Groups whose sensors are all tagged are excluded by the HAVING clause
+	mock.ExpectQuery(`SELECT\s+g\.id, g\.prtg_server_address_id, g\.name, gp\.path,[\s\S]+` +
+		`INNER JOIN prtg_group g ON g\.id = d\.prtg_group_id[\s\S]+` +
+		`HAVING COUNT\(\*\) FILTER \(WHERE st\.prtg_sensor_id IS NULL\) > 0\s+` +
+		`ORDER BY untagged_sensors DESC, gp\.path\s+LIMIT \$1`).
+		WithArgs(20).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "prtg_server_address_id", "name", "path", "untagged_sensors", "total_sensors"}).
+			AddRow(50, 1, "Branch Offices", "/Root/Branch Offices", 25, 40).
+			AddRow(60, 1, "Lab", "/Root/Lab", 5, 5))
+
+	coverage, err := db.GetTagCoverage(context.Background(), "group", 20)
+	require.NoError(t, err)
+
+	assert.Equal(t, 200, coverage.TotalSensors)
+	assert.Equal(t, 30, coverage.UntaggedSensors)
+	assert.InDelta(t, 85.0, coverage.CoveragePercent, 0.001)
+	require.Len(t, coverage.Objects, 2)
+	assert.Equal(t, types.UntaggedSensors{
+		ID: 50, ServerID: 1, Name: "Branch Offices", FullPath: "/Root/Branch Offices", UntaggedSensors: 25, TotalSensors: 40,
+	}, coverage.Objects[0])
+
+	// Per device, and full coverage when there is no sensor at all
+	mock.ExpectQuery(`FROM prtg_sensor s\s+LEFT JOIN`).
+		WillReturnRows(sqlmock.NewRows([]string{"total_sensors", "untagged_sensors"}).AddRow(0, 0))
+	mock.ExpectQuery(`SELECT\s+d\.id, d\.prtg_server_address_id, d\.name, dp\.path,[\s\S]+` +
+		`INNER JOIN prtg_device_path dp ON dp\.device_id = d\.id[\s\S]+ORDER BY untagged_sensors DESC, dp\.path`).
+		WithArgs(10).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "prtg_server_address_id", "name", "path", "untagged_sensors", "total_sensors"}))
+
+	coverage, err = db.GetTagCoverage(context.Background(), "device", 10)
+	require.NoError(t, err)
+
+	assert.InDelta(t, 100.0, coverage.CoveragePercent, 0.001)
+	assert.Empty(t, coverage.Objects)
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// TestGetEmptyGroups validates that groups with devices or child groups are excluded, and that
+// limit 0 returns all empty groups.
+func TestGetEmptyGroups(t *testing.T) {
+	mockDB, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer mockDB.Close()
+
+	logger := zerolog.Nop()
+	db := &DB{
+		conn:   mockDB,
+		logger: &logger,
 	}
+
+	groupColumns, _, _ := searchColumns()
+
+	mock.ExpectQuery(`FROM prtg_group g[\s\S]+WHERE NOT EXISTS \(\s+SELECT 1 FROM prtg_device d\s+` +
+		`WHERE d\.prtg_group_id = g\.id[\s\S]+AND NOT EXISTS \(\s+SELECT 1 FROM prtg_group c\s+` +
+		`WHERE c\.self_group_id = g\.id[\s\S]+ORDER BY gp\.path, g\.id$`).
+		WithoutArgs().
+		WillReturnRows(sqlmock.NewRows(groupColumns).
+			AddRow(20, 1, "Decommissioned", false, 1, "/Root/Decommissioned", 1))
+
+	groups, err := db.GetEmptyGroups(context.Background(), 0)
+	require.NoError(t, err)
+
+	require.Len(t, groups, 1)
+	assert.Equal(t, "Decommissioned", groups[0].Name)
+
+	assert.NoError(t, mock.ExpectationsWereMet())
 }
@@ -0,0 +1,119 @@
+package handlers
+
+import (
+	"container/list"
+	"context"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/matthieu/mcp-server-prtg/internal/prtg"
+)
+
+const (
+	// channelCacheTTL is how long channel values fetched from the PRTG API are reused.
+	// PRTG scans most sensors every 60 seconds or more, so fresher values rarely differ.
+	channelCacheTTL = 30 * time.Second
+
+	// channelCacheSize bounds the number of sensors whose channels are kept in memory.
+	channelCacheSize = 512
+)
+
+// channelCache keeps the channels of recently queried sensors for channelCacheTTL, so that
+// enrichments repeated by consecutive tool calls don't hit the PRTG API every time. The least
+// recently used sensor is evicted when the cache is full. The zero value is ready to use.
+type channelCache struct {
+	mu      sync.Mutex
+	entries map[int]*list.Element // Values are *channelCacheEntry
+	order   *list.List            // Most recently used first
+	now     func() time.Time      // Overridden in tests
+}
+
+// channelCacheEntry is the channels of one sensor.
+type channelCacheEntry struct {
+	sensorID int
+	channels []prtg.Channel
+	fetched  time.Time
+}
+
+// getChannels returns the channels of a sensor from the channel cache, or from the PRTG API.
+func (h *ToolHandler) getChannels(ctx context.Context, sensorID int) ([]prtg.Channel, error) {
+	return h.channelCache.fetch(ctx, h.prtgClient, sensorID)
+}
+
+// fetch returns the cached channels of a sensor, or fetches them with client and caches them.
+func (c *channelCache) fetch(ctx context.Context, client PRTGClient, sensorID int) ([]prtg.Channel, error) {
+	if channels, ok := c.get(sensorID); ok {
+		return channels, nil
+	}
+
+	channels, err := client.GetChannelsBySensor(ctx, sensorID)
+	if err != nil {
+		return nil, err
+	}
+
+	c.put(sensorID, channels)
+
+	return channels, nil
+}
+
+// get returns a copy of the cached channels of a sensor if they are younger than channelCacheTTL.
+func (c *channelCache) get(sensorID int) ([]prtg.Channel, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	element, ok := c.entries[sensorID]
+	if !ok {
+		return nil, false
+	}
+
+	entry := element.Value.(*channelCacheEntry)
+
+	if c.clock().Sub(entry.fetched) >= channelCacheTTL {
+		c.order.Remove(element)
+		delete(c.entries, sensorID)
+
+		return nil, false
+	}
+
+	c.order.MoveToFront(element)
+
+	return slices.Clone(entry.channels), true
+}
+
+// put caches the channels of a sensor, evicting the least recently used sensor when full.
+func (c *channelCache) put(sensorID int, channels []prtg.Channel) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.entries == nil {
+		c.entries = make(map[int]*list.Element)
+		c.order = list.New()
+	}
+
+	entry := &channelCacheEntry{sensorID: sensorID, channels: slices.Clone(channels), fetched: c.clock()}
+
+	if element, ok := c.entries[sensorID]; ok {
+		element.Value = entry
+		c.order.MoveToFront(element)
+
+		return
+	}
+
+	c.entries[sensorID] = c.order.PushFront(entry)
+
+	if c.order.Len() > channelCacheSize {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.entries, oldest.Value.(*channelCacheEntry).sensorID)
+	}
+}
+
+// clock returns the current time. The lock must be held.
+func (c *channelCache) clock() time.Time {
+	if c.now != nil {
+		return c.now()
+	}
+
+	return time.Now()
+}
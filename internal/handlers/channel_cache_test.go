@@ -0,0 +1,49 @@
+package handlers
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/matthieu/mcp-server-prtg/internal/prtg"
+)
+
+func TestChannelCache_ExpiryAndEviction(t *testing.T) {
+	now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	cache := channelCache{now: func() time.Time { return now }}
+
+	_, ok := cache.get(1001)
+	assert.False(t, ok)
+
+	cache.put(1001, []prtg.Channel{{ID: "1001.0", Name: "Total"}})
+
+	now = now.Add(channelCacheTTL - time.Second)
+
+	channels, ok := cache.get(1001)
+	require.True(t, ok)
+	assert.Equal(t, "Total", channels[0].Name)
+
+	// Callers get a copy of the cached channels
+	channels[0].Name = "changed"
+	channels, _ = cache.get(1001)
+	assert.Equal(t, "Total", channels[0].Name)
+
+	now = now.Add(time.Second)
+
+	_, ok = cache.get(1001)
+	assert.False(t, ok)
+
+	// The least recently used sensor is evicted when the cache is full
+	for id := 1; id <= channelCacheSize+1; id++ {
+		cache.put(id, nil)
+	}
+
+	_, ok = cache.get(1)
+	assert.False(t, ok)
+
+	_, ok = cache.get(channelCacheSize + 1)
+	assert.True(t, ok)
+	assert.Equal(t, channelCacheSize, cache.order.Len())
+}
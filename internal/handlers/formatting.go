@@ -4,8 +4,13 @@ package handlers
 import (
 	"encoding/json"
 	"fmt"
+	"reflect"
+	"slices"
+	"sort"
+	"strconv"
 	"strings"
 	"time"
+	"unicode/utf8"
 
 	"github.com/matthieu/mcp-server-prtg/internal/types"
 )
@@ -32,24 +37,133 @@ func formatDuration(seconds *float64) string {
 	return fmt.Sprintf("%.1fd", days)
 }
 
+// formatTimestamp renders a stored UTC timestamp in the display timezone (nil = UTC).
+func formatTimestamp(t time.Time, loc *time.Location, layout string) string {
+	if loc == nil {
+		loc = time.UTC
+	}
+
+	return t.In(loc).Format(layout)
+}
+
+// timezoneName returns the name of the display timezone shown in table headers, e.g. "Europe/Paris".
+func timezoneName(loc *time.Location) string {
+	if loc == nil {
+		return "UTC"
+	}
+
+	return loc.String()
+}
+
+// formatElapsed formats a duration with its two largest units, e.g. "3h12m" or "2d4h".
+func formatElapsed(d time.Duration) string {
+	if d < time.Minute {
+		return "<1m"
+	}
+
+	days := int(d / (24 * time.Hour))
+	hours := int(d % (24 * time.Hour) / time.Hour)
+	minutes := int(d % time.Hour / time.Minute)
+
+	switch {
+	case days > 0:
+		return fmt.Sprintf("%dd%dh", days, hours)
+	case hours > 0:
+		return fmt.Sprintf("%dh%02dm", hours, minutes)
+	default:
+		return fmt.Sprintf("%dm", minutes)
+	}
+}
+
+// timeInState describes how long a sensor has been in its current state, e.g. "down for 3h12m",
+// from its last up/down transition. It returns "" when the transition is unknown, does not match
+// the current status (e.g. a down sensor whose last transition went up), or the sensor is
+// neither up nor down (paused, unknown, collecting).
+func timeInState(sensor types.Sensor, now time.Time) string {
+	transition, at := sensor.LastTransition()
+	if at.IsZero() || at.After(now) {
+		return ""
+	}
+
+	switch {
+	case isDownStatus(sensor.Status) && transition == types.TransitionDown:
+	case !isDownStatus(sensor.Status) && transition == types.TransitionUp &&
+		(sensor.Status == types.StatusUp || sensor.Status == types.StatusWarning || sensor.Status == types.StatusUnusual):
+	default:
+		return ""
+	}
+
+	return fmt.Sprintf("%s for %s", transition, formatElapsed(now.Sub(at)))
+}
+
 // getStatusEmoji returns an emoji for a PRTG status code.
 func getStatusEmoji(status int) string {
 	switch status {
-	case 3: // Up
+	case types.StatusUnknown:
+		return "❓"
+	case types.StatusCollecting:
+		return "⏳"
+	case types.StatusUp:
 		return "🟢"
-	case 4: // Warning
+	case types.StatusWarning:
 		return "🟡"
-	case 5: // Down
+	case types.StatusDown:
 		return "🔴"
-	case 7: // Paused
+	case types.StatusNoProbe:
+		return "🔌"
+	case types.StatusPausedByUser, types.StatusPausedByDependency, types.StatusPausedBySchedule,
+		types.StatusPausedByLicense, types.StatusPausedUntil:
 		return "⏸️"
-	case 13: // Unknown
-		return "❓"
+	case types.StatusUnusual:
+		return "🟠"
+	case types.StatusDownAcknowledged:
+		return "🔕"
+	case types.StatusDownPartial:
+		return "🟥"
 	default:
 		return "⚪"
 	}
 }
 
+// statusBreakdownOrder lists every PRTG status code, most severe first, as shown in status breakdowns.
+var statusBreakdownOrder = []int{
+	types.StatusDown,
+	types.StatusDownPartial,
+	types.StatusDownAcknowledged,
+	types.StatusWarning,
+	types.StatusUnusual,
+	types.StatusNoProbe,
+	types.StatusUnknown,
+	types.StatusCollecting,
+	types.StatusUp,
+	types.StatusPausedByUser,
+	types.StatusPausedByDependency,
+	types.StatusPausedBySchedule,
+	types.StatusPausedByLicense,
+	types.StatusPausedUntil,
+}
+
+// writeStatusBreakdown writes one line per status present in counts, in statusBreakdownOrder.
+// Codes outside the documented 1-14 range are summed into a single "Other" line.
+func writeStatusBreakdown(sb *strings.Builder, counts map[int]int, noun string) {
+	for _, status := range statusBreakdownOrder {
+		if count := counts[status]; count > 0 {
+			sb.WriteString(fmt.Sprintf("- %s **%s:** %d %s\n", getStatusEmoji(status), types.GetStatusText(status), count, noun))
+		}
+	}
+
+	other := 0
+	for status, count := range counts {
+		if !types.IsKnownStatus(status) {
+			other += count
+		}
+	}
+
+	if other > 0 {
+		sb.WriteString(fmt.Sprintf("- ⚪ **Other:** %d %s\n", other, noun))
+	}
+}
+
 // getPriorityEmoji returns an emoji for a priority level (1-5).
 func getPriorityEmoji(priority int) string {
 	switch priority {
@@ -68,14 +182,98 @@ func getPriorityEmoji(priority int) string {
 	}
 }
 
+// resultMetadata is the machine-readable header of listing tool results, so clients can
+// detect totals and truncation without parsing the markdown.
+type resultMetadata struct {
+	Total     *int `json:"total,omitempty"` // Matching items, nil when items were dropped and not counted
+	Returned  int  `json:"returned"`        // Items included in the result
+	Truncated bool `json:"truncated"`       // Items were dropped by the result limit
+
+	// NextOffset is the offset of the next page, for paginated tools (0 = last page)
+	NextOffset int `json:"next_offset,omitempty"`
+}
+
+// fetchLimit returns the number of rows to ask the database for a listing limited to limit
+// (0 = unlimited): one more, so that the extra row tells a truncated listing from one that
+// has exactly limit items.
+func fetchLimit(limit int) int {
+	if limit <= 0 {
+		return limit
+	}
+
+	return limit + 1
+}
+
+// newResultMeta builds the metadata of a listing of fetched items, fetched with fetchLimit(limit).
+// The total is known when no item was dropped.
+func newResultMeta(fetched, limit int) resultMetadata {
+	if limit > 0 && fetched > limit {
+		return resultMetadata{Returned: limit, Truncated: true}
+	}
+
+	return resultMetadata{Total: &fetched, Returned: fetched}
+}
+
+// limitResults cuts items fetched with fetchLimit(limit) down to limit and builds their metadata.
+func limitResults[T any](items []T, limit int) ([]T, resultMetadata) {
+	meta := newResultMeta(len(items), limit)
+
+	return items[:meta.Returned], meta
+}
+
+// limitSearchResults cuts search results fetched with fetchLimit(limit) down to limit per
+// category and builds their metadata.
+func limitSearchResults(results *types.SearchResults, limit int) resultMetadata {
+	var groups, devices, sensors resultMetadata
+
+	results.Groups, groups = limitResults(results.Groups, limit)
+	results.Devices, devices = limitResults(results.Devices, limit)
+	results.Sensors, sensors = limitResults(results.Sensors, limit)
+
+	returned := groups.Returned + devices.Returned + sensors.Returned
+	meta := resultMetadata{
+		Returned:  returned,
+		Truncated: groups.Truncated || devices.Truncated || sensors.Truncated,
+	}
+
+	if !meta.Truncated {
+		meta.Total = &returned
+	}
+
+	return meta
+}
+
+// Formatters end their response with the complete data as a JSON block (the "Complete ... below"
+// section) only when their includeJSON argument is set, see ToolHandler.includeJSON.
+
+// resultMeta renders the metadata as a single JSON line, followed by a blank line.
+func resultMeta(meta resultMetadata) string {
+	jsonData, _ := json.Marshal(meta)
+	return string(jsonData) + "\n\n"
+}
+
 // formatAlertsResponse formats alerts in a visual Markdown table format with full JSON data.
-func formatAlertsResponse(alerts []types.Sensor) string {
+// With groupByDevice, alerts are listed in one section per device instead of a flat table.
+func formatAlertsResponse(alerts []types.ScoredAlert, meta resultMetadata, groupByDevice, fullMessages bool, includeJSON bool) string {
 	var sb strings.Builder
 
+	// 0. Machine-readable metadata
+	sb.WriteString(resultMeta(meta))
+
 	// 1. Header with count
 	sb.WriteString(fmt.Sprintf("## 🚨 Alert Summary\n\n"))
 	sb.WriteString(fmt.Sprintf("Found **%d alert(s)** requiring attention\n\n", len(alerts)))
 
+	if meta.NextOffset > 0 {
+		shown := fmt.Sprintf("%d-%d", meta.NextOffset-meta.Returned+1, meta.NextOffset)
+		if meta.Total != nil {
+			shown += fmt.Sprintf(" of %d", *meta.Total)
+		}
+
+		sb.WriteString(fmt.Sprintf("➡️ **More alerts:** showing %s; call again with `offset: %d` for the next page\n\n",
+			shown, meta.NextOffset))
+	}
+
 	if len(alerts) == 0 {
 		sb.WriteString("✅ No alerts found. All systems operational!\n")
 		return sb.String()
@@ -88,34 +286,71 @@ func formatAlertsResponse(alerts []types.Sensor) string {
 	}
 
 	sb.WriteString("**Breakdown by status:**\n")
-	if count, ok := statusCount[5]; ok {
-		sb.WriteString(fmt.Sprintf("- 🔴 **Critical (Down):** %d sensor(s)\n", count))
+	writeStatusBreakdown(&sb, statusCount, "sensor(s)")
+	sb.WriteString("\n")
+
+	// 3. Per-device sections, or a flat Markdown table (show top 25)
+	if groupByDevice {
+		writeAlertsByDevice(&sb, alerts)
+	} else {
+		writeAlertsTable(&sb, alerts)
 	}
-	if count, ok := statusCount[4]; ok {
-		sb.WriteString(fmt.Sprintf("- 🟡 **Warning:** %d sensor(s)\n", count))
+
+	if fullMessages {
+		sensors := make([]types.Sensor, len(alerts))
+		for i, alert := range alerts {
+			sensors[i] = alert.Sensor
+		}
+
+		writeFullMessages(&sb, sensors)
 	}
-	if count, ok := statusCount[13]; ok {
-		sb.WriteString(fmt.Sprintf("- ❓ **Unknown:** %d sensor(s)\n", count))
+
+	// 4. Hint for artifact
+	if includeJSON {
+		sb.WriteString("\n---\n\n")
+		sb.WriteString("💾 **Complete dataset below** (downloadable for further analysis)\n\n")
+
+		// 5. Full JSON data
+		sb.WriteString("```json\n")
+		jsonData, _ := json.MarshalIndent(alerts, "", "  ")
+		sb.WriteString(string(jsonData))
+		sb.WriteString("\n```\n")
 	}
-	sb.WriteString("\n")
 
-	// 3. Markdown table (show top 25)
-	sb.WriteString("| Priority | Sensor | Device | Status | Downtime | Message |\n")
-	sb.WriteString("|----------|--------|--------|--------|----------|----------|\n")
+	return sb.String()
+}
+
+// alertDowntime is the Downtime cell of an alert: the time in its current state, or else the
+// downtime counter.
+func alertDowntime(sensor types.Sensor, now time.Time) string {
+	if inState := timeInState(sensor, now); inState != "" {
+		return inState
+	}
+
+	return formatDuration(sensor.DowntimeSinceSecs)
+}
+
+// writeAlertsTable writes the most severe alerts as a single Markdown table.
+func writeAlertsTable(sb *strings.Builder, alerts []types.ScoredAlert) {
+	sb.WriteString("| Score | Priority | Sensor | Device | Status | Downtime | Message |\n")
+	sb.WriteString("|-------|----------|--------|--------|--------|----------|----------|\n")
 
 	displayCount := len(alerts)
 	if displayCount > 25 {
 		displayCount = 25
 	}
 
+	now := time.Now()
+
 	for i := 0; i < displayCount; i++ {
 		alert := alerts[i]
 		statusEmoji := getStatusEmoji(alert.Status)
 		priorityEmoji := getPriorityEmoji(alert.Priority)
-		downtime := formatDuration(alert.DowntimeSinceSecs)
+		downtime := alertDowntime(alert.Sensor, now)
 		message := truncateString(alert.Message, 50)
 
-		sb.WriteString(fmt.Sprintf("| %s %d | %s | %s | %s %s | %s | %s |\n",
+		sb.WriteString(fmt.Sprintf("| %d | %s %d | %s | %s | %s %s | %s | %s |\n",
+			alert.SeverityScore,
 			priorityEmoji,
 			alert.Priority,
 			truncateString(alert.Name, 25),
@@ -128,866 +363,2903 @@ func formatAlertsResponse(alerts []types.Sensor) string {
 	}
 
 	if len(alerts) > 25 {
-		sb.WriteString(fmt.Sprintf("| ... | *%d more alerts* | ... | ... | ... | ... |\n", len(alerts)-25))
+		sb.WriteString(fmt.Sprintf("| ... | ... | *%d more alerts* | ... | ... | ... | ... |\n", len(alerts)-25))
 	}
+}
 
-	// 4. Hint for artifact
-	sb.WriteString("\n---\n\n")
-	sb.WriteString("💾 **Complete dataset below** (downloadable for further analysis)\n\n")
-
-	// 5. Full JSON data
-	sb.WriteString("```json\n")
-	jsonData, _ := json.MarshalIndent(alerts, "", "  ")
-	sb.WriteString(string(jsonData))
-	sb.WriteString("\n```\n")
+// maxAlertsPerDevice caps the rows listed under each device when alerts are grouped.
+const maxAlertsPerDevice = 10
 
-	return sb.String()
+// alertGroup holds the alerts of one device, in severity order.
+type alertGroup struct {
+	DeviceName string
+	Alerts     []types.ScoredAlert
 }
 
-// formatSensorsResponse formats sensors in a visual Markdown table format with full JSON data.
-func formatSensorsResponse(sensors []types.Sensor) string {
-	var sb strings.Builder
+// groupAlertsByDevice groups alerts by device. Devices appear in the order of their
+// first alert, so with severity-sorted input the most critical device comes first.
+func groupAlertsByDevice(alerts []types.ScoredAlert) []alertGroup {
+	var groups []alertGroup
 
-	// 1. Header with count
-	sb.WriteString(fmt.Sprintf("## 📊 Sensors Overview\n\n"))
-	sb.WriteString(fmt.Sprintf("Found **%d sensor(s)**\n\n", len(sensors)))
+	index := make(map[int]int)
 
-	if len(sensors) == 0 {
-		sb.WriteString("No sensors found matching the criteria.\n")
-		return sb.String()
+	for _, alert := range alerts {
+		i, ok := index[alert.DeviceID]
+		if !ok {
+			i = len(groups)
+			index[alert.DeviceID] = i
+			groups = append(groups, alertGroup{DeviceName: alert.DeviceName})
+		}
+
+		groups[i].Alerts = append(groups[i].Alerts, alert)
 	}
 
-	// 2. Status breakdown
-	statusCount := make(map[int]int)
-	for _, sensor := range sensors {
-		statusCount[sensor.Status]++
+	return groups
+}
+
+// writeAlertsByDevice writes one section per device, headed by its per-status counts
+// (e.g. "🔴 5 Down, 🟡 1 Warning"), followed by the device's alerts.
+func writeAlertsByDevice(sb *strings.Builder, alerts []types.ScoredAlert) {
+	now := time.Now()
+
+	for _, group := range groupAlertsByDevice(alerts) {
+		counts := make(map[int]int)
+		for _, alert := range group.Alerts {
+			counts[alert.Status]++
+		}
+
+		var summary []string
+		for _, status := range statusBreakdownOrder {
+			if count := counts[status]; count > 0 {
+				summary = append(summary, fmt.Sprintf("%s %d %s", getStatusEmoji(status), count, types.GetStatusText(status)))
+			}
+		}
+
+		other := 0
+		for status, count := range counts {
+			if !types.IsKnownStatus(status) {
+				other += count
+			}
+		}
+
+		if other > 0 {
+			summary = append(summary, fmt.Sprintf("⚪ %d Other", other))
+		}
+
+		deviceName := group.DeviceName
+		if deviceName == "" {
+			deviceName = "Unknown device"
+		}
+
+		sb.WriteString(fmt.Sprintf("### 🖥️ %s: %s\n\n", deviceName, strings.Join(summary, ", ")))
+		sb.WriteString("| Score | Priority | Sensor | Status | Downtime | Message |\n")
+		sb.WriteString("|-------|----------|--------|--------|----------|---------|\n")
+
+		displayCount := len(group.Alerts)
+		if displayCount > maxAlertsPerDevice {
+			displayCount = maxAlertsPerDevice
+		}
+
+		for _, alert := range group.Alerts[:displayCount] {
+			sb.WriteString(fmt.Sprintf("| %d | %s %d | %s | %s %s | %s | %s |\n",
+				alert.SeverityScore,
+				getPriorityEmoji(alert.Priority),
+				alert.Priority,
+				truncateString(alert.Name, 25),
+				getStatusEmoji(alert.Status),
+				alert.StatusText,
+				alertDowntime(alert.Sensor, now),
+				truncateString(alert.Message, 50),
+			))
+		}
+
+		if len(group.Alerts) > maxAlertsPerDevice {
+			sb.WriteString(fmt.Sprintf("| ... | ... | *%d more alerts* | ... | ... | ... |\n", len(group.Alerts)-maxAlertsPerDevice))
+		}
+
+		sb.WriteString("\n")
 	}
+}
 
-	sb.WriteString("**Breakdown by status:**\n")
-	if count, ok := statusCount[3]; ok {
-		sb.WriteString(fmt.Sprintf("- 🟢 **Up:** %d sensor(s)\n", count))
+// formatAlertsJSON formats alerts as pure JSON for downstream automation.
+// Alerts are expected to be sorted by severity score (see sortBySeverity).
+func formatAlertsJSON(alerts []types.ScoredAlert, meta resultMetadata) (string, error) {
+	output := struct {
+		Count      int                 `json:"count"`
+		Total      *int                `json:"total,omitempty"`
+		NextOffset int                 `json:"next_offset,omitempty"`
+		Alerts     []types.ScoredAlert `json:"alerts"`
+	}{
+		Count:      len(alerts),
+		Total:      meta.Total,
+		NextOffset: meta.NextOffset,
+		Alerts:     alerts,
 	}
-	if count, ok := statusCount[4]; ok {
-		sb.WriteString(fmt.Sprintf("- 🟡 **Warning:** %d sensor(s)\n", count))
+
+	jsonData, err := json.MarshalIndent(output, "", "  ")
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal alerts: %w", err)
 	}
-	if count, ok := statusCount[5]; ok {
-		sb.WriteString(fmt.Sprintf("- 🔴 **Down:** %d sensor(s)\n", count))
+
+	return string(jsonData), nil
+}
+
+// formatRecentChangesResponse formats recent status transitions, newest first.
+func formatRecentChangesResponse(changes []types.StatusChange, minutes int, meta resultMetadata, fullMessages bool,
+	loc *time.Location, includeJSON bool) string {
+	var sb strings.Builder
+
+	// 0. Machine-readable metadata
+	sb.WriteString(resultMeta(meta))
+
+	// 1. Header with count
+	sb.WriteString("## 🔄 Recent Status Changes\n\n")
+	sb.WriteString(fmt.Sprintf("Found **%d change(s)** in the last %d minute(s)\n\n", len(changes), minutes))
+
+	if len(changes) == 0 {
+		sb.WriteString("✅ No sensor went down or came back up in this window.\n")
+		return sb.String()
 	}
-	if count, ok := statusCount[7]; ok {
-		sb.WriteString(fmt.Sprintf("- ⏸️ **Paused:** %d sensor(s)\n", count))
+
+	// 2. Transition breakdown
+	downCount := 0
+	for _, change := range changes {
+		if change.Transition == types.TransitionDown {
+			downCount++
+		}
 	}
-	sb.WriteString("\n")
 
-	// 3. Markdown table (show top 20)
-	sb.WriteString("| ID | Name | Status | Device | Type | Uptime |\n")
-	sb.WriteString("|----|------|--------|--------|------|--------|\n")
+	sb.WriteString(fmt.Sprintf("- 🔴 **Went down:** %d sensor(s)\n", downCount))
+	sb.WriteString(fmt.Sprintf("- 🟢 **Came back up:** %d sensor(s)\n\n", len(changes)-downCount))
+
+	// 3. Markdown table (show top 25)
+	sb.WriteString(fmt.Sprintf("| Changed At (%s) | Transition | Sensor | Device | Status | Message |\n", timezoneName(loc)))
+	sb.WriteString("|------------------|------------|--------|--------|--------|---------|\n")
 
-	displayCount := len(sensors)
-	if displayCount > 20 {
-		displayCount = 20
+	displayCount := len(changes)
+	if displayCount > 25 {
+		displayCount = 25
 	}
 
 	for i := 0; i < displayCount; i++ {
-		sensor := sensors[i]
-		statusEmoji := getStatusEmoji(sensor.Status)
-		uptime := formatDuration(sensor.UptimeSinceSecs)
+		change := changes[i]
+		transition := "🟢 up"
+		if change.Transition == types.TransitionDown {
+			transition = "🔴 down"
+		}
 
-		sb.WriteString(fmt.Sprintf("| %d | %s | %s %s | %s | %s | %s |\n",
-			sensor.ID,
-			truncateString(sensor.Name, 25),
-			statusEmoji,
-			sensor.StatusText,
-			truncateString(sensor.DeviceName, 20),
-			truncateString(sensor.SensorType, 15),
-			uptime,
+		sb.WriteString(fmt.Sprintf("| %s | %s | %s | %s | %s %s | %s |\n",
+			formatTimestamp(change.ChangedAt, loc, "2006-01-02 15:04:05"),
+			transition,
+			truncateString(change.Name, 25),
+			truncateString(change.DeviceName, 20),
+			getStatusEmoji(change.Status),
+			change.StatusText,
+			truncateString(change.Message, 50),
 		))
 	}
 
-	if len(sensors) > 20 {
-		sb.WriteString(fmt.Sprintf("| ... | *%d more sensors* | ... | ... | ... | ... |\n", len(sensors)-20))
+	if len(changes) > 25 {
+		sb.WriteString(fmt.Sprintf("| ... | ... | *%d more changes* | ... | ... | ... |\n", len(changes)-25))
+	}
+
+	if fullMessages {
+		sensors := make([]types.Sensor, len(changes))
+		for i, change := range changes {
+			sensors[i] = change.Sensor
+		}
+
+		writeFullMessages(&sb, sensors)
 	}
 
 	// 4. Hint for artifact
-	sb.WriteString("\n---\n\n")
-	sb.WriteString("💾 **Complete dataset below** (downloadable for further analysis)\n\n")
+	if includeJSON {
+		sb.WriteString("\n---\n\n")
+		sb.WriteString("💾 **Complete dataset below** (downloadable for further analysis)\n\n")
 
-	// 5. Full JSON data
-	sb.WriteString("```json\n")
-	jsonData, _ := json.MarshalIndent(sensors, "", "  ")
-	sb.WriteString(string(jsonData))
-	sb.WriteString("\n```\n")
+		// 5. Full JSON data
+		sb.WriteString("```json\n")
+		jsonData, _ := json.MarshalIndent(changes, "", "  ")
+		sb.WriteString(string(jsonData))
+		sb.WriteString("\n```\n")
+	}
 
 	return sb.String()
 }
 
-// formatDeviceOverviewResponse formats device overview in a visual format.
-func formatDeviceOverviewResponse(overview *types.DeviceOverview) string {
-	var sb strings.Builder
+// scoreAlerts computes the severity score of each alert. Alerts keep their order: sort the
+// sensors with sortBySeverity first to rank them by score.
+func scoreAlerts(sensors []types.Sensor) []types.ScoredAlert {
+	alerts := make([]types.ScoredAlert, 0, len(sensors))
+	for _, sensor := range sensors {
+		alerts = append(alerts, types.ScoredAlert{
+			Sensor:        sensor,
+			SeverityScore: alertSeverityScore(sensor),
+		})
+	}
 
-	// 1. Header
-	sb.WriteString(fmt.Sprintf("## 🖥️ Device Overview: %s\n\n", overview.Device.Name))
+	return alerts
+}
 
-	// 2. Device info
-	sb.WriteString("**Device Information:**\n")
-	sb.WriteString(fmt.Sprintf("- **Device ID:** %d\n", overview.Device.ID))
-	sb.WriteString(fmt.Sprintf("- **Host:** %s\n", overview.Device.Host))
-	if overview.Device.GroupName != "" {
-		sb.WriteString(fmt.Sprintf("- **Group:** %s\n", overview.Device.GroupName))
+// diffAlerts compares alerts with the sensor IDs in alert at a previous poll: alerts of sensors
+// outside the baseline are new, baseline sensors without a current alert have cleared. Alerts keep
+// their order, the sensor ID lists are sorted.
+func diffAlerts(alerts []types.ScoredAlert, baseline []int) types.AlertDiff {
+	inBaseline := make(map[int]bool, len(baseline))
+	for _, id := range baseline {
+		inBaseline[id] = true
 	}
-	sb.WriteString(fmt.Sprintf("- **Total Sensors:** %d\n", overview.TotalSensors))
-	if overview.Device.FullPath != "" {
-		sb.WriteString(fmt.Sprintf("- **Path:** %s\n", overview.Device.FullPath))
+
+	diff := types.AlertDiff{
+		New:              []types.ScoredAlert{},
+		Cleared:          []int{},
+		CurrentSensorIDs: make([]int, 0, len(alerts)),
 	}
-	sb.WriteString("\n")
 
-	// 3. Status summary
-	sb.WriteString("**Status Summary:**\n")
-	sb.WriteString(fmt.Sprintf("- 🟢 **Up:** %d sensor(s)\n", overview.UpSensors))
-	sb.WriteString(fmt.Sprintf("- 🟡 **Warning:** %d sensor(s)\n", overview.WarnSensors))
-	sb.WriteString(fmt.Sprintf("- 🔴 **Down:** %d sensor(s)\n", overview.DownSensors))
+	alerting := make(map[int]bool, len(alerts))
 
-	// Calculate other statuses
-	otherSensors := overview.TotalSensors - overview.UpSensors - overview.WarnSensors - overview.DownSensors
-	if otherSensors > 0 {
-		sb.WriteString(fmt.Sprintf("- ⚪ **Other:** %d sensor(s)\n", otherSensors))
-	}
-	sb.WriteString("\n")
+	for _, alert := range alerts {
+		alerting[alert.ID] = true
+		diff.CurrentSensorIDs = append(diff.CurrentSensorIDs, alert.ID)
 
-	// 4. Tag summary
-	if len(overview.Sensors) > 0 {
-		tagMap := make(map[string]int)
-		for _, sensor := range overview.Sensors {
-			if sensor.Tags != "" {
-				tags := strings.Split(sensor.Tags, ",")
-				for _, tag := range tags {
-					tag = strings.TrimSpace(tag)
-					if tag != "" {
-						tagMap[tag]++
-					}
-				}
-			}
+		if inBaseline[alert.ID] {
+			diff.Unchanged++
+		} else {
+			diff.New = append(diff.New, alert)
 		}
+	}
 
-		if len(tagMap) > 0 {
-			sb.WriteString("**Tags used on this device:**\n")
-			// Sort tags by usage count
-			type tagCount struct {
-				tag   string
-				count int
-			}
-			tagCounts := make([]tagCount, 0, len(tagMap))
-			for tag, count := range tagMap {
-				tagCounts = append(tagCounts, tagCount{tag, count})
-			}
-			// Sort by count descending
-			for i := 0; i < len(tagCounts); i++ {
-				for j := i + 1; j < len(tagCounts); j++ {
-					if tagCounts[j].count > tagCounts[i].count {
-						tagCounts[i], tagCounts[j] = tagCounts[j], tagCounts[i]
-					}
-				}
-			}
-			// Show top 10 tags
-			displayCount := len(tagCounts)
-			if displayCount > 10 {
-				displayCount = 10
-			}
-			for i := 0; i < displayCount; i++ {
-				sb.WriteString(fmt.Sprintf("- 🏷️ **%s** (%d sensor%s)\n",
-					tagCounts[i].tag,
-					tagCounts[i].count,
-					func() string {
-						if tagCounts[i].count > 1 {
-							return "s"
-						}
-						return ""
-					}(),
-				))
-			}
-			if len(tagCounts) > 10 {
-				sb.WriteString(fmt.Sprintf("- *...and %d more tags*\n", len(tagCounts)-10))
-			}
-			sb.WriteString("\n")
+	for id := range inBaseline {
+		if !alerting[id] {
+			diff.Cleared = append(diff.Cleared, id)
 		}
 	}
 
-	// 5. Sensors table
-	if len(overview.Sensors) > 0 {
-		sb.WriteString("**Sensors:**\n\n")
-		sb.WriteString("| Name | Status | Type | Last Check | Tags |\n")
-		sb.WriteString("|------|--------|------|------------|------|\n")
+	sort.Ints(diff.Cleared)
+	sort.Ints(diff.CurrentSensorIDs)
 
-		displayCount := len(overview.Sensors)
-		if displayCount > 50 {
-			displayCount = 50
+	return diff
+}
+
+// formatAlertDiffResponse formats the alerts that are new since a baseline of baselineSize sensors,
+// and the sensors whose alert cleared.
+func formatAlertDiffResponse(diff types.AlertDiff, baselineSize int, includeJSON bool) string {
+	var sb strings.Builder
+
+	sb.WriteString("## 🚨 Alert Changes Since Last Poll\n\n")
+	sb.WriteString(fmt.Sprintf("**%d new**, **%d cleared**, %d unchanged (baseline: %d sensor(s), now: %d alert(s))\n\n",
+		len(diff.New), len(diff.Cleared), diff.Unchanged, baselineSize, len(diff.CurrentSensorIDs)))
+
+	if len(diff.New) == 0 && len(diff.Cleared) == 0 {
+		sb.WriteString("✅ No change since the last poll.\n")
+		return sb.String()
+	}
+
+	if len(diff.New) > 0 {
+		sb.WriteString("### 🆕 New Alerts\n\n")
+		writeAlertsTable(&sb, diff.New)
+		sb.WriteString("\n")
+	}
+
+	if len(diff.Cleared) > 0 {
+		cleared := make([]string, len(diff.Cleared))
+		for i, id := range diff.Cleared {
+			cleared[i] = strconv.Itoa(id)
+		}
+
+		sb.WriteString("### ✅ Cleared\n\n")
+		sb.WriteString(fmt.Sprintf("Sensor IDs no longer in alert: %s\n", strings.Join(cleared, ", ")))
+	}
+
+	if includeJSON {
+		sb.WriteString("\n---\n\n")
+		sb.WriteString("💾 **Complete dataset below** (current_sensor_ids is the baseline of the next poll)\n\n")
+		sb.WriteString("```json\n")
+		jsonData, _ := json.MarshalIndent(diff, "", "  ")
+		sb.WriteString(string(jsonData))
+		sb.WriteString("\n```\n")
+	}
+
+	return sb.String()
+}
+
+// alertSeverityScore combines status weight (18-90) and priority weight (2-10) into a 0-100 score.
+// The status weights are 12 apart, more than the priority range, so a status always outranks
+// the statuses below it regardless of priority: any Down sensor outranks any Warning sensor.
+// Sensors in a non-alert state (Up, paused, collecting) score 0. The database computes the same
+// score to page score-ordered alerts (alertSeverityScoreSQL).
+func alertSeverityScore(sensor types.Sensor) int {
+	var statusWeight int
+
+	switch sensor.Status {
+	case types.StatusDown:
+		statusWeight = 90
+	case types.StatusDownPartial:
+		statusWeight = 78
+	case types.StatusNoProbe:
+		statusWeight = 66
+	case types.StatusWarning:
+		statusWeight = 54
+	case types.StatusUnusual:
+		statusWeight = 42
+	case types.StatusDownAcknowledged:
+		statusWeight = 30
+	case types.StatusUnknown:
+		statusWeight = 18
+	default:
+		return 0
+	}
+
+	// PRTG priority ranges from 1 (lowest) to 5 (highest)
+	priority := sensor.Priority
+	if priority < 1 {
+		priority = 1
+	} else if priority > 5 {
+		priority = 5
+	}
+
+	return statusWeight + priority*2
+}
+
+// sensorLink returns the address of a sensor page in the PRTG web interface at baseURL.
+func sensorLink(baseURL string, sensorID int) string {
+	return fmt.Sprintf("%s/sensor.htm?id=%d", baseURL, sensorID)
+}
+
+// setSensorLinks sets the URL of each sensor to its page in the PRTG web interface at baseURL.
+func setSensorLinks(sensors []types.Sensor, baseURL string) {
+	for i := range sensors {
+		sensors[i].URL = sensorLink(baseURL, sensors[i].ID)
+	}
+}
+
+// sensorFields lists the JSON fields of a sensor, in declaration order. It is the whitelist of
+// the fields parameter of prtg_get_sensors.
+var sensorFields = jsonFieldNames(reflect.TypeOf(types.Sensor{}))
+
+// jsonFieldNames returns the JSON names of the exported fields of a struct type.
+func jsonFieldNames(t reflect.Type) []string {
+	names := make([]string, 0, t.NumField())
+
+	for i := 0; i < t.NumField(); i++ {
+		name, _, _ := strings.Cut(t.Field(i).Tag.Get("json"), ",")
+		if name != "" && name != "-" {
+			names = append(names, name)
+		}
+	}
+
+	return names
+}
+
+// validateSensorFields checks that every requested field is a sensor JSON field.
+func validateSensorFields(fields []string) error {
+	for _, field := range fields {
+		valid := false
+
+		for _, name := range sensorFields {
+			if field == name {
+				valid = true
+				break
+			}
+		}
+
+		if !valid {
+			return fmt.Errorf("unknown field %q, valid fields: %s", field, strings.Join(sensorFields, ", "))
+		}
+	}
+
+	return nil
+}
+
+// projectSensors returns the sensors reduced to the given fields, for the JSON block.
+// Fields omitted from a sensor's JSON because they are empty are reported as null.
+func projectSensors(sensors []types.Sensor, fields []string) []map[string]json.RawMessage {
+	projected := make([]map[string]json.RawMessage, 0, len(sensors))
+
+	for _, sensor := range sensors {
+		data, _ := json.Marshal(sensor)
+
+		var all map[string]json.RawMessage
+		_ = json.Unmarshal(data, &all)
+
+		row := make(map[string]json.RawMessage, len(fields))
+		for _, field := range fields {
+			value, ok := all[field]
+			if !ok {
+				value = json.RawMessage("null")
+			}
+
+			row[field] = value
+		}
+
+		projected = append(projected, row)
+	}
+
+	return projected
+}
+
+// formatSensorsResponse formats sensors in a visual Markdown table format with full JSON data.
+// Sensors with a URL (see setSensorLinks) get a link column.
+// With verbose, the table also shows the device host and the (shortened) full path,
+// which operators need to reach the device or find the sensor in the PRTG UI.
+// With groupByType, the table is split into one section per sensor type.
+// With fields, the JSON block only contains these sensor fields (see projectSensors).
+func formatSensorsResponse(sensors []types.Sensor, meta resultMetadata, verbose, fullMessages, groupByType bool,
+	fields []string, includeJSON bool) string {
+	var sb strings.Builder
+
+	// 0. Machine-readable metadata
+	sb.WriteString(resultMeta(meta))
+
+	// 1. Header with count
+	sb.WriteString(fmt.Sprintf("## 📊 Sensors Overview\n\n"))
+	sb.WriteString(fmt.Sprintf("Found **%d sensor(s)**\n\n", len(sensors)))
+
+	if len(sensors) == 0 {
+		sb.WriteString("No sensors found matching the criteria.\n")
+		return sb.String()
+	}
+
+	// 2. Status breakdown
+	statusCount := make(map[int]int)
+	for _, sensor := range sensors {
+		statusCount[sensor.Status]++
+	}
+
+	sb.WriteString("**Breakdown by status:**\n")
+	writeStatusBreakdown(&sb, statusCount, "sensor(s)")
+	sb.WriteString("\n")
+
+	// 3. Markdown table (show top 20), or one table per sensor type
+	if groupByType {
+		writeSensorsByType(&sb, sensors, verbose)
+	} else {
+		writeSensorTable(&sb, sensors, verbose, true)
+	}
+
+	if fullMessages {
+		writeFullMessages(&sb, sensors)
+	}
+
+	// 4. Hint for artifact
+	if includeJSON {
+		sb.WriteString("\n---\n\n")
+		sb.WriteString("💾 **Complete dataset below** (downloadable for further analysis)\n\n")
+
+		// 5. Full JSON data
+		var jsonData []byte
+		if len(fields) > 0 {
+			jsonData, _ = json.MarshalIndent(projectSensors(sensors, fields), "", "  ")
+		} else {
+			jsonData, _ = json.MarshalIndent(sensors, "", "  ")
+		}
+
+		sb.WriteString("```json\n")
+		sb.WriteString(string(jsonData))
+		sb.WriteString("\n```\n")
+	}
+
+	return sb.String()
+}
+
+// maxSensorTableRows is the number of sensors listed in a sensors table; the JSON has them all.
+const maxSensorTableRows = 20
+
+// writeSensorTable writes a Markdown table of the first maxSensorTableRows sensors. Value and
+// link columns are added when sensors have a primary channel value or a URL. The type column
+// can be left out when the sensors are already grouped by type.
+func writeSensorTable(sb *strings.Builder, sensors []types.Sensor, verbose, typeColumn bool) {
+	links := sensors[0].URL != ""
+	values := slices.ContainsFunc(sensors, func(s types.Sensor) bool { return s.PrimaryValue != "" })
+	now := time.Now()
+
+	columns := []string{"ID", "Name", "Status", "Device"}
+	if verbose {
+		columns = append(columns, "Host")
+	}
+
+	if typeColumn {
+		columns = append(columns, "Type")
+	}
+
+	columns = append(columns, "In State")
+	if verbose {
+		columns = append(columns, "Path")
+	}
+
+	if values {
+		columns = append(columns, "Value")
+	}
+
+	if links {
+		columns = append(columns, "Link")
+	}
+
+	writeRow := func(cells []string) {
+		sb.WriteString("| " + strings.Join(cells, " | ") + " |\n")
+	}
+
+	writeRow(columns)
+
+	sb.WriteString("|")
+	for _, column := range columns {
+		sb.WriteString(strings.Repeat("-", len(column)+2) + "|")
+	}
+	sb.WriteString("\n")
+
+	for i, sensor := range sensors {
+		if i == maxSensorTableRows {
+			more := slices.Repeat([]string{"..."}, len(columns))
+			more[1] = fmt.Sprintf("*%d more sensors*", len(sensors)-maxSensorTableRows)
+			writeRow(more)
+
+			break
+		}
+
+		inState := timeInState(sensor, now)
+		if inState == "" {
+			inState = "-"
+		}
+
+		cells := []string{
+			strconv.Itoa(sensor.ID),
+			truncateString(sensor.Name, 25),
+			getStatusEmoji(sensor.Status) + " " + sensor.StatusText,
+			truncateString(sensor.DeviceName, 20),
+		}
+
+		if verbose {
+			cells = append(cells, sensor.DeviceHost)
+		}
+
+		if typeColumn {
+			cells = append(cells, truncateString(sensor.SensorType, 15))
+		}
+
+		cells = append(cells, inState)
+		if verbose {
+			cells = append(cells, shortenPath(sensor.FullPath, 40))
+		}
+
+		if values {
+			value := sensor.PrimaryValue
+			if value == "" {
+				value = "-"
+			}
+
+			cells = append(cells, value)
+		}
+
+		if links {
+			cells = append(cells, fmt.Sprintf("[open](%s)", sensor.URL))
+		}
+
+		writeRow(cells)
+	}
+}
+
+// writeSensorsByType writes one section per sensor type with its count and sensors table, most
+// common types first. Sensors keep their order (order_by) within each type.
+func writeSensorsByType(sb *strings.Builder, sensors []types.Sensor, verbose bool) {
+	var sensorTypes []string
+	byType := make(map[string][]types.Sensor)
+
+	for _, sensor := range sensors {
+		sensorType := sensor.SensorType
+		if sensorType == "" {
+			sensorType = "(no type)"
+		}
+
+		if _, ok := byType[sensorType]; !ok {
+			sensorTypes = append(sensorTypes, sensorType)
+		}
+
+		byType[sensorType] = append(byType[sensorType], sensor)
+	}
+
+	sort.Slice(sensorTypes, func(i, j int) bool {
+		a, b := sensorTypes[i], sensorTypes[j]
+		if len(byType[a]) != len(byType[b]) {
+			return len(byType[a]) > len(byType[b])
+		}
+
+		return a < b
+	})
+
+	sb.WriteString(fmt.Sprintf("**%d sensor type(s):**\n\n", len(sensorTypes)))
+
+	for i, sensorType := range sensorTypes {
+		if i > 0 {
+			sb.WriteString("\n")
+		}
+
+		sb.WriteString(fmt.Sprintf("### %s (%d)\n\n", sensorType, len(byType[sensorType])))
+		writeSensorTable(sb, byType[sensorType], verbose, false)
+	}
+}
+
+// formatSensorCountResponse formats a sensor count with the filters that were applied.
+func formatSensorCountResponse(count int, filter types.SensorFilter) string {
+	var sb strings.Builder
+
+	sb.WriteString("## 📊 Sensor Count\n\n")
+	sb.WriteString(fmt.Sprintf("Found **%d sensor(s)** matching the criteria\n", count))
+
+	var filters []string
+	if filter.DeviceName != "" {
+		filters = append(filters, fmt.Sprintf("device: %s", filter.DeviceName))
+	}
+	if filter.SensorName != "" {
+		filters = append(filters, fmt.Sprintf("sensor: %s", filter.SensorName))
+	}
+	if filter.SensorType != "" {
+		filters = append(filters, fmt.Sprintf("type: %s", filter.SensorType))
+	}
+	if filter.GroupName != "" {
+		filters = append(filters, fmt.Sprintf("group: %s", filter.GroupName))
+	}
+	if filter.Status != nil {
+		filters = append(filters, fmt.Sprintf("status: %s", types.GetStatusText(*filter.Status)))
+	}
+
+	if len(filters) > 0 {
+		sb.WriteString(fmt.Sprintf("\n**Filters:** %s\n", strings.Join(filters, ", ")))
+	}
+
+	return sb.String()
+}
+
+// formatDeviceOverviewResponse formats device overview in a visual format.
+// Channel values, when fetched, are shown in an extra column of the sensors table.
+func formatDeviceOverviewResponse(overview *types.DeviceOverview, channels *deviceChannels, loc *time.Location,
+	includeJSON bool) string {
+	var sb strings.Builder
+
+	// 1. Header
+	sb.WriteString(fmt.Sprintf("## 🖥️ Device Overview: %s\n\n", overview.Device.Name))
+
+	// 2. Device info
+	sb.WriteString("**Device Information:**\n")
+	sb.WriteString(fmt.Sprintf("- **Device ID:** %d\n", overview.Device.ID))
+	sb.WriteString(fmt.Sprintf("- **Host:** %s\n", overview.Device.Host))
+	if overview.Device.GroupName != "" {
+		sb.WriteString(fmt.Sprintf("- **Group:** %s\n", overview.Device.GroupName))
+	}
+	sb.WriteString(fmt.Sprintf("- **Total Sensors:** %d\n", overview.TotalSensors))
+	if overview.Device.FullPath != "" {
+		sb.WriteString(fmt.Sprintf("- **Path:** %s\n", overview.Device.FullPath))
+	}
+	sb.WriteString("\n")
+
+	// 3. Status summary
+	sb.WriteString("**Status Summary:**\n")
+	sb.WriteString(fmt.Sprintf("- 🟢 **Up:** %d sensor(s)\n", overview.UpSensors))
+	sb.WriteString(fmt.Sprintf("- 🟡 **Warning:** %d sensor(s)\n", overview.WarnSensors))
+	sb.WriteString(fmt.Sprintf("- 🔴 **Down:** %d sensor(s)\n", overview.DownSensors))
+
+	// Break the remaining statuses down from the sensor list; only sensors missing from it stay "Other"
+	otherSensors := overview.TotalSensors - overview.UpSensors - overview.WarnSensors - overview.DownSensors
+	if otherSensors > 0 {
+		otherCounts := make(map[int]int)
+		for _, sensor := range overview.Sensors {
+			if sensor.Status != types.StatusUp && sensor.Status != types.StatusWarning && sensor.Status != types.StatusDown {
+				otherCounts[sensor.Status]++
+				otherSensors--
+			}
+		}
+
+		if otherSensors > 0 {
+			otherCounts[0] += otherSensors
+		}
+
+		writeStatusBreakdown(&sb, otherCounts, "sensor(s)")
+	}
+	sb.WriteString("\n")
+
+	// 4. Tag summary
+	if len(overview.Sensors) > 0 {
+		tagMap := make(map[string]int)
+		for _, sensor := range overview.Sensors {
+			if sensor.Tags != "" {
+				tags := strings.Split(sensor.Tags, ",")
+				for _, tag := range tags {
+					tag = strings.TrimSpace(tag)
+					if tag != "" {
+						tagMap[tag]++
+					}
+				}
+			}
+		}
+
+		if len(tagMap) > 0 {
+			sb.WriteString("**Tags used on this device:**\n")
+			// Sort tags by usage count
+			type tagCount struct {
+				tag   string
+				count int
+			}
+			tagCounts := make([]tagCount, 0, len(tagMap))
+			for tag, count := range tagMap {
+				tagCounts = append(tagCounts, tagCount{tag, count})
+			}
+			// Sort by count descending
+			for i := 0; i < len(tagCounts); i++ {
+				for j := i + 1; j < len(tagCounts); j++ {
+					if tagCounts[j].count > tagCounts[i].count {
+						tagCounts[i], tagCounts[j] = tagCounts[j], tagCounts[i]
+					}
+				}
+			}
+			// Show top 10 tags
+			displayCount := len(tagCounts)
+			if displayCount > 10 {
+				displayCount = 10
+			}
+			for i := 0; i < displayCount; i++ {
+				sb.WriteString(fmt.Sprintf("- 🏷️ **%s** (%d sensor%s)\n",
+					tagCounts[i].tag,
+					tagCounts[i].count,
+					func() string {
+						if tagCounts[i].count > 1 {
+							return "s"
+						}
+						return ""
+					}(),
+				))
+			}
+			if len(tagCounts) > 10 {
+				sb.WriteString(fmt.Sprintf("- *...and %d more tags*\n", len(tagCounts)-10))
+			}
+			sb.WriteString("\n")
+		}
+	}
+
+	// 5. Sensors table
+	showChannels := channels != nil && channels.Unavailable == ""
+
+	if channels != nil && channels.Unavailable != "" {
+		sb.WriteString(fmt.Sprintf("⚠️ *Channel values unavailable (%s), showing database data only*\n\n", channels.Unavailable))
+	}
+
+	if len(overview.Sensors) > 0 {
+		sb.WriteString("**Sensors:**\n\n")
+		if showChannels {
+			sb.WriteString(fmt.Sprintf("| Name | Status | Type | Key Values | Last Check (%s) | Tags |\n", timezoneName(loc)))
+			sb.WriteString("|------|--------|------|------------|------------|------|\n")
+		} else {
+			sb.WriteString(fmt.Sprintf("| Name | Status | Type | Last Check (%s) | Tags |\n", timezoneName(loc)))
+			sb.WriteString("|------|--------|------|------------|------|\n")
+		}
+
+		displayCount := len(overview.Sensors)
+		if displayCount > 50 {
+			displayCount = 50
+		}
+
+		for i := 0; i < displayCount; i++ {
+			sensor := overview.Sensors[i]
+			statusEmoji := getStatusEmoji(sensor.Status)
+			lastCheck := "-"
+			if sensor.LastCheckUTC != nil {
+				lastCheck = formatTimestamp(*sensor.LastCheckUTC, loc, "2006-01-02 15:04")
+			}
+
+			tags := "-"
+			if sensor.Tags != "" {
+				tags = truncateString(strings.ReplaceAll(sensor.Tags, ",", ", "), 30)
+			}
+
+			keyValues := ""
+			if showChannels {
+				keyValues = " - |"
+				if value, ok := channels.Values[sensor.ID]; ok {
+					keyValues = fmt.Sprintf(" %s |", truncateString(value, 40))
+				}
+			}
+
+			sb.WriteString(fmt.Sprintf("| %s | %s %s | %s |%s %s | %s |\n",
+				truncateString(sensor.Name, 30),
+				statusEmoji,
+				sensor.StatusText,
+				truncateString(sensor.SensorType, 15),
+				keyValues,
+				lastCheck,
+				tags,
+			))
+		}
+
+		if len(overview.Sensors) > 50 {
+			extraColumn := ""
+			if showChannels {
+				extraColumn = " ... |"
+			}
+
+			sb.WriteString(fmt.Sprintf("| ... | *%d more sensors* | ... |%s ... | ... |\n", len(overview.Sensors)-50, extraColumn))
+		}
+	}
+
+	if includeJSON {
+		// 6. Full JSON data
+		sb.WriteString("\n---\n\n")
+		sb.WriteString("💾 **Complete data below** (downloadable)\n\n")
+		sb.WriteString("```json\n")
+		jsonData, _ := json.MarshalIndent(overview, "", "  ")
+		sb.WriteString(string(jsonData))
+		sb.WriteString("\n```\n")
+	}
+
+	return sb.String()
+}
+
+// formatTopSensorsResponse formats top sensors in a visual format.
+func formatTopSensorsResponse(sensors []types.Sensor, metric string, meta resultMetadata, fullMessages bool, includeJSON bool) string {
+	var sb strings.Builder
+
+	// 0. Machine-readable metadata
+	sb.WriteString(resultMeta(meta))
+
+	// 1. Header
+	metricLabel := "sensors"
+	switch metric {
+	case "downtime":
+		metricLabel = "sensors by downtime"
+	case "priority":
+		metricLabel = "sensors by priority"
+	case "recent_changes":
+		metricLabel = "problem sensors by most recent change"
+	}
+
+	sb.WriteString(fmt.Sprintf("## 📈 Top %s\n\n", metricLabel))
+	sb.WriteString(fmt.Sprintf("Found **%d sensor(s)**\n\n", len(sensors)))
+
+	if len(sensors) == 0 {
+		sb.WriteString("No sensors found.\n")
+		return sb.String()
+	}
+
+	// 2. Table
+	sb.WriteString("| Rank | Sensor | Device | Status | Metric | Message |\n")
+	sb.WriteString("|------|--------|--------|--------|--------|----------|\n")
+
+	now := time.Now()
+
+	for i, sensor := range sensors {
+		statusEmoji := getStatusEmoji(sensor.Status)
+		metricValue := "-"
+
+		switch metric {
+		case "downtime":
+			metricValue = formatDuration(sensor.DowntimeSinceSecs)
+		case "priority":
+			metricValue = fmt.Sprintf("%s %d", getPriorityEmoji(sensor.Priority), sensor.Priority)
+		case "recent_changes":
+			metricValue = formatDownAgo(sensor.LastDownUTC, now)
+		}
+
+		sb.WriteString(fmt.Sprintf("| #%d | %s | %s | %s %s | %s | %s |\n",
+			i+1,
+			truncateString(sensor.Name, 25),
+			truncateString(sensor.DeviceName, 20),
+			statusEmoji,
+			sensor.StatusText,
+			metricValue,
+			truncateString(sensor.Message, 30),
+		))
+	}
+
+	if fullMessages {
+		writeFullMessages(&sb, sensors)
+	}
+
+	if includeJSON {
+		// 3. Full JSON data
+		sb.WriteString("\n---\n\n")
+		sb.WriteString("💾 **Complete dataset below** (downloadable)\n\n")
+		sb.WriteString("```json\n")
+		jsonData, _ := json.MarshalIndent(sensors, "", "  ")
+		sb.WriteString(string(jsonData))
+		sb.WriteString("\n```\n")
+	}
+
+	return sb.String()
+}
+
+// formatDownAgo renders how long ago a sensor last went down, e.g. "⬇️ 12m ago",
+// or "-" when no down transition is recorded.
+func formatDownAgo(lastDown *time.Time, now time.Time) string {
+	if lastDown == nil || lastDown.IsZero() || lastDown.After(now) {
+		return "-"
+	}
+
+	return fmt.Sprintf("⬇️ %s ago", formatElapsed(now.Sub(*lastDown)))
+}
+
+// Output formats of prtg_get_hierarchy.
+const (
+	hierarchyOutputTree = "tree" // ASCII tree and summary only
+	hierarchyOutputJSON = "json" // Indented JSON tree only
+	hierarchyOutputBoth = "both" // ASCII tree followed by the JSON block (default)
+)
+
+// formatHierarchyResponse formats hierarchy in a visual tree format with full JSON data.
+// outputFormat selects the ASCII tree, the JSON tree or both (hierarchyOutput* constants).
+func formatHierarchyResponse(node *types.HierarchyNode, outputFormat string) string {
+	if outputFormat == hierarchyOutputJSON {
+		jsonData, _ := json.MarshalIndent(node, "", "  ")
+		return string(jsonData)
+	}
+
+	var sb strings.Builder
+
+	// 1. Header
+	sb.WriteString(fmt.Sprintf("## 🌳 PRTG Hierarchy: %s\n\n", node.Group.Name))
+
+	// 2. Group info
+	sb.WriteString("**Group Information:**\n")
+	sb.WriteString(fmt.Sprintf("- **Group ID:** %d\n", node.Group.ID))
+	sb.WriteString(fmt.Sprintf("- **Tree Depth:** %d\n", node.Group.TreeDepth))
+	if node.Group.IsProbeNode {
+		sb.WriteString("- **Type:** 📡 Probe Node\n")
+	} else {
+		sb.WriteString("- **Type:** 📁 Group\n")
+	}
+	if node.Group.FullPath != "" {
+		sb.WriteString(fmt.Sprintf("- **Path:** %s\n", node.Group.FullPath))
+	}
+	sb.WriteString("\n")
+
+	// 3. Tree structure
+	sb.WriteString("**Tree Structure:**\n\n")
+	formatHierarchyNode(&sb, node, "", true)
+	sb.WriteString("\n")
+
+	// 4. Statistics summary
+	deviceCount, sensorCount := countHierarchyStats(node)
+	childGroupCount := len(node.Groups)
+
+	sb.WriteString("**Summary:**\n")
+	sb.WriteString(fmt.Sprintf("- **Child Groups:** %d\n", childGroupCount))
+	sb.WriteString(fmt.Sprintf("- **Total Devices:** %d\n", deviceCount))
+	sb.WriteString(fmt.Sprintf("- **Total Sensors:** %d\n", sensorCount))
+	if node.NodeLimitReached {
+		sb.WriteString("- ⚠️ **Node limit reached:** the tree is partial, it stopped growing when the server's " +
+			"node limit (max_hierarchy_nodes) was reached. Lower max_depth, leave out sensors or start from a narrower group\n")
+	} else if hierarchyTruncated(node) {
+		sb.WriteString("- ⚠️ **Truncated:** some groups or devices have more children than shown " +
+			"(raise max_children or max_sensors_per_device, or start from a narrower group)\n")
+	}
+	sb.WriteString("\n")
+
+	if outputFormat == hierarchyOutputTree {
+		return sb.String()
+	}
+
+	// 5. Full JSON data
+	sb.WriteString("---\n\n")
+	sb.WriteString("💾 **Complete hierarchy data below** (downloadable)\n\n")
+	sb.WriteString("```json\n")
+	jsonData, _ := json.MarshalIndent(node, "", "  ")
+	sb.WriteString(string(jsonData))
+	sb.WriteString("\n```\n")
+
+	return sb.String()
+}
+
+// formatHierarchyNode recursively formats a hierarchy node as a tree structure.
+func formatHierarchyNode(sb *strings.Builder, node *types.HierarchyNode, prefix string, isLast bool) {
+	// Determine the branch characters
+	branch := "├── "
+	if isLast {
+		branch = "└── "
+	}
+
+	// Group name
+	groupType := "📁"
+	if node.Group.IsProbeNode {
+		groupType = "📡"
+	}
+	sb.WriteString(fmt.Sprintf("%s%s %s %s%s\n", prefix, branch, groupType, node.Group.Name, groupTruncationNote(node)))
+
+	// Prepare prefix for children
+	childPrefix := prefix
+	if isLast {
+		childPrefix += "    "
+	} else {
+		childPrefix += "│   "
+	}
+
+	// Devices in this group
+	for i, device := range node.Devices {
+		isLastDevice := i == len(node.Devices)-1 && len(node.Groups) == 0
+
+		deviceBranch := "├── "
+		if isLastDevice {
+			deviceBranch = "└── "
+		}
+
+		statusInfo := ""
+		if device.Device.SensorCount > 0 {
+			statusInfo = fmt.Sprintf(" (%d sensors)", device.Device.SensorCount)
+		}
+
+		if device.TotalSensors > 0 {
+			statusInfo += fmt.Sprintf(" ⚠️ showing %d of %d sensors", len(device.Sensors), device.TotalSensors)
+		}
+
+		sb.WriteString(fmt.Sprintf("%s%s 🖥️  %s%s\n", childPrefix, deviceBranch, device.Device.Name, statusInfo))
+
+		// Sensors if included
+		if len(device.Sensors) > 0 {
+			sensorPrefix := childPrefix
+			if isLastDevice {
+				sensorPrefix += "    "
+			} else {
+				sensorPrefix += "│   "
+			}
+
+			for j, sensor := range device.Sensors {
+				isLastSensor := j == len(device.Sensors)-1
+				sensorBranch := "├── "
+				if isLastSensor {
+					sensorBranch = "└── "
+				}
+
+				emoji := getStatusEmoji(sensor.Status)
+				sb.WriteString(fmt.Sprintf("%s%s %s %s (%s)\n",
+					sensorPrefix, sensorBranch, emoji, sensor.Name, sensor.StatusText))
+			}
+		}
+	}
+
+	// Child groups
+	for i, childGroup := range node.Groups {
+		isLastGroup := i == len(node.Groups)-1
+		formatHierarchyNode(sb, childGroup, childPrefix, isLastGroup)
+	}
+}
+
+// formatAmbiguousSensorResponse lists the PRTG servers having a sensor ID, so the caller can
+// repeat prtg_get_sensor_status with the server_id of the sensor it meant.
+func formatAmbiguousSensorResponse(e *types.AmbiguousSensorError) string {
+	var sb strings.Builder
+
+	sb.WriteString(fmt.Sprintf("## ⚠️ Sensor ID %d exists on %d PRTG servers\n\n", e.SensorID, len(e.Sensors)))
+	sb.WriteString("Call `prtg_get_sensor_status` again with the `server_id` of the sensor you mean.\n\n")
+
+	sb.WriteString("| Server ID | Sensor | Device | Status | Path |\n")
+	sb.WriteString("|-----------|--------|--------|--------|------|\n")
+
+	for _, sensor := range e.Sensors {
+		sb.WriteString(fmt.Sprintf("| %d | %s | %s | %s %s | %s |\n",
+			sensor.ServerID,
+			truncateString(sensor.Name, 25),
+			truncateString(sensor.DeviceName, 20),
+			getStatusEmoji(sensor.Status),
+			sensor.StatusText,
+			sensor.FullPath,
+		))
+	}
+
+	return sb.String()
+}
+
+// formatDevicePathResponse formats the path from the probe down to a device, with the device's sensors.
+func formatDevicePathResponse(path *types.DevicePath, outputFormat string) string {
+	if outputFormat == hierarchyOutputJSON {
+		jsonData, _ := json.MarshalIndent(path, "", "  ")
+		return string(jsonData)
+	}
+
+	var sb strings.Builder
+
+	sb.WriteString(fmt.Sprintf("## 🌳 PRTG Hierarchy: %s\n\n", path.Device.Name))
+
+	names := make([]string, 0, len(path.Ancestors)+1)
+	for _, group := range path.Ancestors {
+		names = append(names, group.Name)
+	}
+	names = append(names, path.Device.Name)
+
+	sb.WriteString(fmt.Sprintf("**Path:** %s\n\n", strings.Join(names, " → ")))
+
+	sb.WriteString("**Tree Structure:**\n\n")
+
+	prefix := ""
+
+	for _, group := range path.Ancestors {
+		groupType := "📁"
+		if group.IsProbeNode {
+			groupType = "📡"
+		}
+
+		sb.WriteString(fmt.Sprintf("%s└── %s %s (ID: %d)\n", prefix, groupType, group.Name, group.ID))
+		prefix += "    "
+	}
+
+	deviceInfo := fmt.Sprintf(" (ID: %d", path.Device.ID)
+	if path.Device.Host != "" {
+		deviceInfo += ", " + path.Device.Host
+	}
+	deviceInfo += ")"
+
+	if path.TotalSensors > 0 {
+		deviceInfo += fmt.Sprintf(" ⚠️ showing %d of %d sensors", len(path.Sensors), path.TotalSensors)
+	}
+
+	sb.WriteString(fmt.Sprintf("%s└── 🖥️  %s%s\n", prefix, path.Device.Name, deviceInfo))
+	prefix += "    "
+
+	for i, sensor := range path.Sensors {
+		branch := "├── "
+		if i == len(path.Sensors)-1 {
+			branch = "└── "
+		}
+
+		sb.WriteString(fmt.Sprintf("%s%s %s %s (%s)\n", prefix, branch, getStatusEmoji(sensor.Status), sensor.Name, sensor.StatusText))
+	}
+
+	sb.WriteString("\n")
+
+	if outputFormat == hierarchyOutputTree {
+		return sb.String()
+	}
+
+	sb.WriteString("---\n\n")
+	sb.WriteString("💾 **Complete path data below** (downloadable)\n\n")
+	sb.WriteString("```json\n")
+	jsonData, _ := json.MarshalIndent(path, "", "  ")
+	sb.WriteString(string(jsonData))
+	sb.WriteString("\n```\n")
+
+	return sb.String()
+}
+
+// groupTruncationNote returns the "showing N of M" annotation of a group whose children were truncated.
+func groupTruncationNote(node *types.HierarchyNode) string {
+	var notes []string
+
+	if node.TotalDevices > 0 {
+		notes = append(notes, fmt.Sprintf("%d of %d devices", len(node.Devices), node.TotalDevices))
+	}
+
+	if node.TotalGroups > 0 {
+		notes = append(notes, fmt.Sprintf("%d of %d groups", len(node.Groups), node.TotalGroups))
+	}
+
+	if len(notes) == 0 {
+		return ""
+	}
+
+	return " ⚠️ showing " + strings.Join(notes, ", ")
+}
+
+// hierarchyTruncated reports whether any node or device of the tree was truncated by the breadth limits.
+func hierarchyTruncated(node *types.HierarchyNode) bool {
+	if node.TotalDevices > 0 || node.TotalGroups > 0 {
+		return true
+	}
+
+	for _, device := range node.Devices {
+		if device.TotalSensors > 0 {
+			return true
+		}
+	}
+
+	for _, childGroup := range node.Groups {
+		if hierarchyTruncated(childGroup) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// countHierarchyStats counts total devices and sensors in the hierarchy tree.
+func countHierarchyStats(node *types.HierarchyNode) (devices, sensors int) {
+	devices = len(node.Devices)
+
+	for _, device := range node.Devices {
+		sensors += len(device.Sensors)
+	}
+
+	for _, childGroup := range node.Groups {
+		childDevices, childSensors := countHierarchyStats(childGroup)
+		devices += childDevices
+		sensors += childSensors
+	}
+
+	return devices, sensors
+}
+
+// formatSearchResponse formats universal search results in a visual format with full JSON data.
+func formatSearchResponse(results *types.SearchResults, searchTerm string, meta resultMetadata, includeJSON bool) string {
+	var sb strings.Builder
+
+	totalResults := len(results.Groups) + len(results.Devices) + len(results.Sensors)
+
+	// 0. Machine-readable metadata
+	sb.WriteString(resultMeta(meta))
+
+	// 1. Header
+	sb.WriteString(fmt.Sprintf("## 🔍 Search Results for \"%s\"\n\n", searchTerm))
+	sb.WriteString(fmt.Sprintf("Found **%d total result(s)** across all categories\n\n", totalResults))
+
+	// Failed categories: the results of the others are still shown
+	for _, searchErr := range results.Errors {
+		sb.WriteString(fmt.Sprintf("⚠️ **Partial results**: the %s search failed (%s)\n", searchErr.Category,
+			truncateString(searchErr.Error, 200)))
+	}
+
+	if len(results.Errors) > 0 {
+		sb.WriteString("\n")
+	}
+
+	if totalResults == 0 {
+		sb.WriteString("No results found. Try a different search term.\n")
+		return sb.String()
+	}
+
+	// 2. Summary breakdown
+	sb.WriteString("**Results by category:**\n")
+	sb.WriteString(fmt.Sprintf("- 📁 **Groups:** %d\n", len(results.Groups)))
+	sb.WriteString(fmt.Sprintf("- 🖥️  **Devices:** %d\n", len(results.Devices)))
+	sb.WriteString(fmt.Sprintf("- 📊 **Sensors:** %d\n", len(results.Sensors)))
+	sb.WriteString("\n")
+
+	// 3. Groups section
+	if len(results.Groups) > 0 {
+		sb.WriteString("### 📁 Groups\n\n")
+		sb.WriteString("| ID | Name | Type | Path |\n")
+		sb.WriteString("|----|------|------|------|\n")
+
+		displayCount := len(results.Groups)
+		if displayCount > 20 {
+			displayCount = 20
+		}
+
+		for i := 0; i < displayCount; i++ {
+			group := results.Groups[i]
+			groupType := "Group"
+			if group.IsProbeNode {
+				groupType = "Probe"
+			}
+
+			sb.WriteString(fmt.Sprintf("| %d | %s | %s | %s |\n",
+				group.ID,
+				truncateString(group.Name, 30),
+				groupType,
+				truncateString(group.FullPath, 40),
+			))
+		}
+
+		if len(results.Groups) > 20 {
+			sb.WriteString(fmt.Sprintf("| ... | *%d more groups* | ... | ... |\n", len(results.Groups)-20))
+		}
+		sb.WriteString("\n")
+	}
+
+	// 4. Devices section
+	if len(results.Devices) > 0 {
+		sb.WriteString("### 🖥️  Devices\n\n")
+		sb.WriteString("| ID | Name | Host | Group | Sensors |\n")
+		sb.WriteString("|----|------|------|-------|----------|\n")
+
+		displayCount := len(results.Devices)
+		if displayCount > 20 {
+			displayCount = 20
+		}
+
+		for i := 0; i < displayCount; i++ {
+			device := results.Devices[i]
+
+			sb.WriteString(fmt.Sprintf("| %d | %s | %s | %s | %d |\n",
+				device.ID,
+				truncateString(device.Name, 25),
+				truncateString(device.Host, 20),
+				truncateString(device.GroupName, 20),
+				device.SensorCount,
+			))
+		}
+
+		if len(results.Devices) > 20 {
+			sb.WriteString(fmt.Sprintf("| ... | *%d more devices* | ... | ... | ... |\n", len(results.Devices)-20))
+		}
+		sb.WriteString("\n")
+	}
+
+	// 5. Sensors section
+	if len(results.Sensors) > 0 {
+		sb.WriteString("### 📊 Sensors\n\n")
+		sb.WriteString("| ID | Name | Device | Type | Status | Matched on |\n")
+		sb.WriteString("|----|------|--------|------|--------|------------|\n")
+
+		displayCount := len(results.Sensors)
+		if displayCount > 20 {
+			displayCount = 20
+		}
+
+		for i := 0; i < displayCount; i++ {
+			sensor := results.Sensors[i]
+			statusEmoji := getStatusEmoji(sensor.Status)
+
+			// Message matches show where the term appears, since the name alone does not explain the hit
+			matchedOn := sensorMatchField(sensor, searchTerm)
+			if matchedOn == "message" {
+				matchedOn = "message: " + messageExcerpt(sensor.Message, searchTerm, 20)
+			}
+
+			sb.WriteString(fmt.Sprintf("| %d | %s | %s | %s | %s %s | %s |\n",
+				sensor.ID,
+				truncateString(sensor.Name, 25),
+				truncateString(sensor.DeviceName, 20),
+				truncateString(sensor.SensorType, 15),
+				statusEmoji,
+				sensor.StatusText,
+				matchedOn,
+			))
+		}
+
+		if len(results.Sensors) > 20 {
+			sb.WriteString(fmt.Sprintf("| ... | *%d more sensors* | ... | ... | ... | ... |\n", len(results.Sensors)-20))
+		}
+		sb.WriteString("\n")
+	}
+
+	if includeJSON {
+		// 6. Full JSON data
+		sb.WriteString("---\n\n")
+		sb.WriteString("💾 **Complete search results below** (downloadable)\n\n")
+		sb.WriteString("```json\n")
+		jsonData, _ := json.MarshalIndent(results, "", "  ")
+		sb.WriteString(string(jsonData))
+		sb.WriteString("\n```\n")
+	}
+
+	return sb.String()
+}
+
+// formatSensorTypesResponse formats the distinct sensor types with their counts, followed by the full JSON data.
+func formatSensorTypesResponse(sensorTypes []types.SensorTypeCount, meta resultMetadata, includeJSON bool) string {
+	var sb strings.Builder
+
+	// 0. Machine-readable metadata
+	sb.WriteString(resultMeta(meta))
+
+	// 1. Header
+	sb.WriteString("## 🔧 PRTG Sensor Types\n\n")
+	sb.WriteString(fmt.Sprintf("Found **%d sensor type(s)**\n\n", len(sensorTypes)))
+
+	if len(sensorTypes) == 0 {
+		sb.WriteString("No sensor types found.\n")
+		return sb.String()
+	}
+
+	sb.WriteString("💡 Use these exact values for `sensor_type` filters.\n\n")
+
+	// 2. Types table
+	sb.WriteString("| Rank | Sensor Type | Sensors |\n")
+	sb.WriteString("|------|-------------|---------|\n")
+
+	for i, sensorType := range sensorTypes {
+		sb.WriteString(fmt.Sprintf("| %d | `%s` | %d |\n", i+1, escapeTableCell(sensorType.Type), sensorType.Count))
+	}
+
+	// 3. Hint for artifact
+	if includeJSON {
+		sb.WriteString("\n---\n\n")
+		sb.WriteString("💾 **Complete dataset below** (downloadable for further analysis)\n\n")
+
+		// 4. Full JSON data
+		sb.WriteString("```json\n")
+		jsonData, _ := json.MarshalIndent(sensorTypes, "", "  ")
+		sb.WriteString(string(jsonData))
+		sb.WriteString("\n```\n")
+	}
+
+	return sb.String()
+}
+
+// formatMessageSearchResponse formats sensors found by message search, with the matching part
+// of each message highlighted, followed by the full JSON data.
+func formatMessageSearchResponse(sensors []types.Sensor, searchTerm string, meta resultMetadata, includeJSON bool) string {
+	var sb strings.Builder
+
+	// 0. Machine-readable metadata
+	sb.WriteString(resultMeta(meta))
+
+	// 1. Header
+	sb.WriteString(fmt.Sprintf("## 💬 Sensor messages containing \"%s\"\n\n", searchTerm))
+	sb.WriteString(fmt.Sprintf("Found **%d sensor(s)**\n\n", len(sensors)))
+
+	if len(sensors) == 0 {
+		sb.WriteString("No sensor message contains this text.\n")
+		return sb.String()
+	}
+
+	// 2. Table
+	sb.WriteString("| ID | Sensor | Device | Status | Message |\n")
+	sb.WriteString("|----|--------|--------|--------|---------|\n")
+
+	for _, sensor := range sensors {
+		sb.WriteString(fmt.Sprintf("| %d | %s | %s | %s %s | %s |\n",
+			sensor.ID,
+			truncateString(sensor.Name, 25),
+			truncateString(sensor.DeviceName, 20),
+			getStatusEmoji(sensor.Status),
+			sensor.StatusText,
+			messageExcerpt(sensor.Message, searchTerm, 40),
+		))
+	}
+
+	if includeJSON {
+		// 3. Full JSON data
+		sb.WriteString("\n---\n\n")
+		sb.WriteString("💾 **Complete dataset below** (downloadable)\n\n")
+		sb.WriteString("```json\n")
+		jsonData, _ := json.MarshalIndent(sensors, "", "  ")
+		sb.WriteString(string(jsonData))
+		sb.WriteString("\n```\n")
+	}
+
+	return sb.String()
+}
+
+// messageExcerpt returns the part of a message around the first case-insensitive occurrence of term,
+// with the match in bold and up to context bytes on each side. Safe to use in a markdown table cell.
+func messageExcerpt(message, term string, context int) string {
+	idx := strings.Index(strings.ToLower(message), strings.ToLower(term))
+	if idx < 0 || term == "" || len(strings.ToLower(message)) != len(message) {
+		// No match, or lowercasing changed byte offsets: fall back to a plain truncation
+		return escapeTableCell(truncateString(message, 2*context))
+	}
+
+	start := max(idx-context, 0)
+	for start > 0 && !utf8.RuneStart(message[start]) {
+		start--
+	}
+
+	end := min(idx+len(term)+context, len(message))
+	for end < len(message) && !utf8.RuneStart(message[end]) {
+		end++
+	}
+
+	excerpt := escapeTableCell(message[start:idx]) +
+		"**" + escapeTableCell(message[idx:idx+len(term)]) + "**" +
+		escapeTableCell(message[idx+len(term):end])
+
+	if start > 0 {
+		excerpt = "…" + excerpt
+	}
+
+	if end < len(message) {
+		excerpt += "…"
+	}
+
+	return excerpt
+}
+
+// sensorMatchField reports why a sensor matched a search term: "name", "type" or "message",
+// or "similar" for fuzzy matches that do not contain the term literally.
+func sensorMatchField(sensor types.Sensor, searchTerm string) string {
+	term := strings.ToLower(searchTerm)
+
+	switch {
+	case strings.Contains(strings.ToLower(sensor.Name), term):
+		return "name"
+	case strings.Contains(strings.ToLower(sensor.SensorType), term):
+		return "type"
+	case strings.Contains(strings.ToLower(sensor.Message), term):
+		return "message"
+	default:
+		return "similar"
+	}
+}
+
+// escapeTableCell makes text safe to embed in a markdown table cell.
+func escapeTableCell(s string) string {
+	return strings.NewReplacer("|", "\\|", "\n", " ", "\r", "").Replace(s)
+}
+
+// writeFullMessages writes the complete status message of each sensor, keyed by sensor ID,
+// as tables only show the beginning of messages. Line breaks are flattened to keep one
+// sensor per line; sensors without a message are skipped.
+func writeFullMessages(sb *strings.Builder, sensors []types.Sensor) {
+	sb.WriteString("\n### 📝 Full Messages\n\n")
+
+	flatten := strings.NewReplacer("\r", "", "\n", " ")
+	written := 0
+
+	for _, sensor := range sensors {
+		message := strings.TrimSpace(flatten.Replace(sensor.Message))
+		if message == "" {
+			continue
+		}
+
+		sb.WriteString(fmt.Sprintf("- **%d** %s (%s): %s\n", sensor.ID, sensor.Name, sensor.DeviceName, message))
+		written++
+	}
+
+	if written == 0 {
+		sb.WriteString("No status messages.\n")
+	}
+}
+
+// formatGroupsResponse formats groups in a visual format with full JSON data.
+func formatGroupsResponse(groups []types.Group, meta resultMetadata, includeJSON bool) string {
+	var sb strings.Builder
+
+	// 0. Machine-readable metadata
+	sb.WriteString(resultMeta(meta))
+
+	// 1. Header
+	sb.WriteString(fmt.Sprintf("## 📁 PRTG Groups\n\n"))
+	sb.WriteString(fmt.Sprintf("Found **%d group(s)**\n\n", len(groups)))
+
+	if len(groups) == 0 {
+		sb.WriteString("No groups found matching the criteria.\n")
+		return sb.String()
+	}
+
+	// 2. Breakdown by type
+	probeCount := 0
+	groupCount := 0
+	for _, group := range groups {
+		if group.IsProbeNode {
+			probeCount++
+		} else {
+			groupCount++
+		}
+	}
+
+	sb.WriteString("**Breakdown by type:**\n")
+	sb.WriteString(fmt.Sprintf("- 📡 **Probes:** %d\n", probeCount))
+	sb.WriteString(fmt.Sprintf("- 📁 **Groups:** %d\n", groupCount))
+	sb.WriteString("\n")
+
+	// 3. Groups table
+	sb.WriteString("| ID | Name | Type | Tree Depth | Path |\n")
+	sb.WriteString("|----|------|------|------------|------|\n")
+
+	displayCount := len(groups)
+	if displayCount > 50 {
+		displayCount = 50
+	}
+
+	for i := 0; i < displayCount; i++ {
+		group := groups[i]
+		groupType := "Group"
+		typeIcon := "📁"
+		if group.IsProbeNode {
+			groupType = "Probe"
+			typeIcon = "📡"
+		}
+
+		sb.WriteString(fmt.Sprintf("| %d | %s | %s %s | %d | %s |\n",
+			group.ID,
+			truncateString(group.Name, 30),
+			typeIcon,
+			groupType,
+			group.TreeDepth,
+			truncateString(group.FullPath, 50),
+		))
+	}
+
+	if len(groups) > 50 {
+		sb.WriteString(fmt.Sprintf("| ... | *%d more groups* | ... | ... | ... |\n", len(groups)-50))
+	}
+	sb.WriteString("\n")
+
+	if includeJSON {
+		// 4. Full JSON data
+		sb.WriteString("---\n\n")
+		sb.WriteString("💾 **Complete groups data below** (downloadable)\n\n")
+		sb.WriteString("```json\n")
+		jsonData, _ := json.MarshalIndent(groups, "", "  ")
+		sb.WriteString(string(jsonData))
+		sb.WriteString("\n```\n")
+	}
+
+	return sb.String()
+}
+
+// formatTagCoverageResponse formats the tag coverage and the groups or devices with untagged sensors.
+func formatTagCoverageResponse(coverage *types.TagCoverage, meta resultMetadata, includeJSON bool) string {
+	var sb strings.Builder
+
+	// 0. Machine-readable metadata
+	sb.WriteString(resultMeta(meta))
+
+	// 1. Header
+	sb.WriteString("## 🏷️ Tag Coverage\n\n")
+	sb.WriteString(fmt.Sprintf("**%.1f%%** of sensors have at least one tag: **%d untagged** out of %d sensors\n\n",
+		coverage.CoveragePercent, coverage.UntaggedSensors, coverage.TotalSensors))
+
+	if len(coverage.Objects) == 0 {
+		sb.WriteString("Every sensor has at least one tag.\n")
+		return sb.String()
+	}
+
+	// 2. Objects with the most untagged sensors
+	label := "Groups"
+	if coverage.GroupBy == "device" {
+		label = "Devices"
+	}
+
+	sb.WriteString(fmt.Sprintf("### %s with the most untagged sensors\n\n", label))
+	sb.WriteString("| ID | Name | Untagged | Total | Path |\n")
+	sb.WriteString("|----|------|----------|-------|------|\n")
+
+	for _, object := range coverage.Objects {
+		sb.WriteString(fmt.Sprintf("| %d | %s | %d | %d | %s |\n",
+			object.ID,
+			truncateString(object.Name, 30),
+			object.UntaggedSensors,
+			object.TotalSensors,
+			truncateString(object.FullPath, 50),
+		))
+	}
+	sb.WriteString("\n")
+
+	if includeJSON {
+		// 3. Full JSON data
+		sb.WriteString("---\n\n")
+		sb.WriteString("💾 **Complete data below** (downloadable)\n\n")
+		sb.WriteString("```json\n")
+		jsonData, _ := json.MarshalIndent(coverage, "", "  ")
+		sb.WriteString(string(jsonData))
+		sb.WriteString("\n```\n")
+	}
+
+	return sb.String()
+}
+
+// formatErrorMessageSummaryResponse formats the status messages of sensors in a problem status,
+// grouped by message text.
+func formatErrorMessageSummaryResponse(messages []types.MessageCount, hours int, meta resultMetadata, includeJSON bool) string {
+	var sb strings.Builder
+
+	// 0. Machine-readable metadata
+	sb.WriteString(resultMeta(meta))
+
+	// 1. Header
+	window := "all time"
+	if hours > 0 {
+		window = fmt.Sprintf("last %d hour(s)", hours)
+	}
+
+	sb.WriteString("## 🧩 Error Message Summary\n\n")
+	sb.WriteString(fmt.Sprintf("Found **%d distinct message(s)** on sensors in a problem status (%s)\n\n", len(messages), window))
+
+	if len(messages) == 0 {
+		sb.WriteString("No sensor is in a non-up status.\n")
+		return sb.String()
+	}
+
+	// 2. Messages, most common first
+	sb.WriteString("| Sensors | Devices | Message | Examples |\n")
+	sb.WriteString("|---------|---------|---------|----------|\n")
+
+	for _, message := range messages {
+		text := "_(no message)_"
+		if message.Message != "" {
+			text = truncateString(message.Message, 80)
+		}
+
+		sb.WriteString(fmt.Sprintf("| %d | %d | %s | %s |\n",
+			message.SensorCount,
+			message.DeviceCount,
+			text,
+			truncateString(strings.Join(message.SampleSensors, ", "), 60),
+		))
+	}
+	sb.WriteString("\n")
+
+	if includeJSON {
+		// 3. Full JSON data
+		sb.WriteString("---\n\n")
+		sb.WriteString("💾 **Complete data below** (downloadable)\n\n")
+		sb.WriteString("```json\n")
+		jsonData, _ := json.MarshalIndent(messages, "", "  ")
+		sb.WriteString(string(jsonData))
+		sb.WriteString("\n```\n")
+	}
+
+	return sb.String()
+}
+
+// formatEmptyObjectsResponse formats devices without sensors and groups without content.
+func formatEmptyObjectsResponse(result *types.EmptyObjects, meta resultMetadata, includeJSON bool) string {
+	var sb strings.Builder
+
+	// 0. Machine-readable metadata
+	sb.WriteString(resultMeta(meta))
+
+	// 1. Header
+	sb.WriteString("## 🧹 Empty PRTG Objects\n\n")
+	sb.WriteString(fmt.Sprintf("Found **%d device(s) without sensors** and **%d group(s) without devices or child groups**\n\n",
+		len(result.Devices), len(result.Groups)))
+
+	if len(result.Devices) == 0 && len(result.Groups) == 0 {
+		sb.WriteString("No empty devices or groups found.\n")
+		return sb.String()
+	}
+
+	// 2. Devices table
+	if len(result.Devices) > 0 {
+		sb.WriteString("### 🖥️ Devices without sensors\n\n")
+		sb.WriteString("| ID | Name | Host | Path |\n")
+		sb.WriteString("|----|------|------|------|\n")
+
+		for _, device := range result.Devices {
+			sb.WriteString(fmt.Sprintf("| %d | %s | %s | %s |\n",
+				device.ID,
+				truncateString(device.Name, 30),
+				truncateString(device.Host, 30),
+				truncateString(device.FullPath, 50),
+			))
 		}
+		sb.WriteString("\n")
+	}
 
-		for i := 0; i < displayCount; i++ {
-			sensor := overview.Sensors[i]
-			statusEmoji := getStatusEmoji(sensor.Status)
-			lastCheck := "-"
-			if sensor.LastCheckUTC != nil {
-				lastCheck = sensor.LastCheckUTC.Format("2006-01-02 15:04")
+	// 3. Groups table
+	if len(result.Groups) > 0 {
+		sb.WriteString("### 📁 Groups without devices or child groups\n\n")
+		sb.WriteString("| ID | Name | Type | Path |\n")
+		sb.WriteString("|----|------|------|------|\n")
+
+		for _, group := range result.Groups {
+			groupType := "📁 Group"
+			if group.IsProbeNode {
+				groupType = "📡 Probe"
 			}
 
-			tags := "-"
-			if sensor.Tags != "" {
-				tags = truncateString(strings.ReplaceAll(sensor.Tags, ",", ", "), 30)
+			sb.WriteString(fmt.Sprintf("| %d | %s | %s | %s |\n",
+				group.ID,
+				truncateString(group.Name, 30),
+				groupType,
+				truncateString(group.FullPath, 50),
+			))
+		}
+		sb.WriteString("\n")
+	}
+
+	if includeJSON {
+		// 4. Full JSON data
+		sb.WriteString("---\n\n")
+		sb.WriteString("💾 **Complete data below** (downloadable)\n\n")
+		sb.WriteString("```json\n")
+		jsonData, _ := json.MarshalIndent(result, "", "  ")
+		sb.WriteString(string(jsonData))
+		sb.WriteString("\n```\n")
+	}
+
+	return sb.String()
+}
+
+// maxDiffRows bounds the rows of each section of a group diff table.
+const maxDiffRows = 50
+
+// formatGroupDiffResponse formats the sensor inventory diff of two groups as three sections.
+func formatGroupDiffResponse(result *types.GroupDiff, includeJSON bool) string {
+	var sb strings.Builder
+
+	// 1. Header
+	sb.WriteString("## 🔀 Group Inventory Diff\n\n")
+	sb.WriteString(fmt.Sprintf("- **A:** %s (`%s`)\n", result.GroupA.Name, result.GroupA.FullPath))
+	sb.WriteString(fmt.Sprintf("- **B:** %s (`%s`)\n\n", result.GroupB.Name, result.GroupB.FullPath))
+	sb.WriteString(fmt.Sprintf("**%d** only in A, **%d** only in B, **%d** in common (matched by sensor name and type)\n\n",
+		len(result.OnlyInA), len(result.OnlyInB), len(result.Common)))
+
+	if result.Truncated {
+		sb.WriteString(fmt.Sprintf("⚠️ A group has more than %d sensors: only the first %d of each group were compared.\n\n",
+			maxDiffGroupSensors, maxDiffGroupSensors))
+	}
+
+	// 2. Sensors unique to each group
+	writeOnly := func(title string, sensors []types.Sensor) {
+		sb.WriteString(fmt.Sprintf("### %s (%d)\n\n", title, len(sensors)))
+
+		if len(sensors) == 0 {
+			sb.WriteString("None.\n\n")
+			return
+		}
+
+		sb.WriteString("| ID | Name | Type | Device |\n")
+		sb.WriteString("|----|------|------|--------|\n")
+
+		for i, sensor := range sensors {
+			if i == maxDiffRows {
+				sb.WriteString(fmt.Sprintf("| ... | *%d more sensors* | ... | ... |\n", len(sensors)-maxDiffRows))
+				break
 			}
 
-			sb.WriteString(fmt.Sprintf("| %s | %s %s | %s | %s | %s |\n",
+			sb.WriteString(fmt.Sprintf("| %d | %s | %s | %s |\n",
+				sensor.ID,
 				truncateString(sensor.Name, 30),
-				statusEmoji,
-				sensor.StatusText,
-				truncateString(sensor.SensorType, 15),
-				lastCheck,
-				tags,
+				truncateString(sensor.SensorType, 20),
+				truncateString(sensor.DeviceName, 25),
 			))
 		}
 
-		if len(overview.Sensors) > 50 {
-			sb.WriteString(fmt.Sprintf("| ... | *%d more sensors* | ... | ... | ... |\n", len(overview.Sensors)-50))
+		sb.WriteString("\n")
+	}
+
+	writeOnly("🅰️ Only in A", result.OnlyInA)
+	writeOnly("🅱️ Only in B", result.OnlyInB)
+
+	// 3. Sensors present in both groups
+	sb.WriteString(fmt.Sprintf("### 🟰 In both groups (%d)\n\n", len(result.Common)))
+
+	if len(result.Common) == 0 {
+		sb.WriteString("None.\n\n")
+	} else {
+		sb.WriteString("| Name | Type | Device A (ID) | Device B (ID) |\n")
+		sb.WriteString("|------|------|---------------|---------------|\n")
+
+		for i, pair := range result.Common {
+			if i == maxDiffRows {
+				sb.WriteString(fmt.Sprintf("| *%d more sensors* | ... | ... | ... |\n", len(result.Common)-maxDiffRows))
+				break
+			}
+
+			sb.WriteString(fmt.Sprintf("| %s | %s | %s (%d) | %s (%d) |\n",
+				truncateString(pair.A.Name, 30),
+				truncateString(pair.A.SensorType, 20),
+				truncateString(pair.A.DeviceName, 25), pair.A.ID,
+				truncateString(pair.B.DeviceName, 25), pair.B.ID,
+			))
 		}
+
+		sb.WriteString("\n")
 	}
 
-	// 6. Full JSON data
-	sb.WriteString("\n---\n\n")
-	sb.WriteString("💾 **Complete data below** (downloadable)\n\n")
-	sb.WriteString("```json\n")
-	jsonData, _ := json.MarshalIndent(overview, "", "  ")
-	sb.WriteString(string(jsonData))
-	sb.WriteString("\n```\n")
+	if includeJSON {
+		// 4. Full JSON data
+		sb.WriteString("---\n\n")
+		sb.WriteString("💾 **Complete data below** (downloadable)\n\n")
+		sb.WriteString("```json\n")
+		jsonData, _ := json.MarshalIndent(result, "", "  ")
+		sb.WriteString(string(jsonData))
+		sb.WriteString("\n```\n")
+	}
 
 	return sb.String()
 }
 
-// formatTopSensorsResponse formats top sensors in a visual format.
-func formatTopSensorsResponse(sensors []types.Sensor, metric string) string {
+// formatTagsResponse formats tags data with visual summary and JSON export.
+func formatTagsResponse(tags []types.Tag, meta resultMetadata, includeJSON bool) string {
 	var sb strings.Builder
 
+	// 0. Machine-readable metadata
+	sb.WriteString(resultMeta(meta))
+
 	// 1. Header
-	metricLabel := "sensors"
-	switch metric {
-	case "downtime":
-		metricLabel = "sensors by downtime"
-	case "priority":
-		metricLabel = "sensors by priority"
+	sb.WriteString(fmt.Sprintf("## 🏷️ PRTG Tags\n\n"))
+	sb.WriteString(fmt.Sprintf("Found **%d tag(s)**\n\n", len(tags)))
+
+	if len(tags) == 0 {
+		sb.WriteString("No tags found matching the criteria.\n")
+		return sb.String()
+	}
+
+	// 2. Statistics
+	totalSensorCount := 0
+	for _, tag := range tags {
+		totalSensorCount += tag.SensorCount
+	}
+
+	sb.WriteString("**Tag usage statistics:**\n")
+	sb.WriteString(fmt.Sprintf("- 📊 **Total sensor associations:** %d\n", totalSensorCount))
+	if len(tags) > 0 {
+		avgUsage := float64(totalSensorCount) / float64(len(tags))
+		sb.WriteString(fmt.Sprintf("- 📈 **Average sensors per tag:** %.1f\n", avgUsage))
+	}
+	sb.WriteString("\n")
+
+	// 3. Tags table
+	sb.WriteString("| ID | Tag Name | Sensor Count |\n")
+	sb.WriteString("|----|----------|-------------|\n")
+
+	displayCount := len(tags)
+	if displayCount > 50 {
+		displayCount = 50
+	}
+
+	for i := 0; i < displayCount; i++ {
+		tag := tags[i]
+		sb.WriteString(fmt.Sprintf("| %d | %s | %d |\n",
+			tag.ID,
+			truncateString(tag.Name, 40),
+			tag.SensorCount,
+		))
+	}
+
+	if len(tags) > 50 {
+		sb.WriteString(fmt.Sprintf("| ... | *%d more tags* | ... |\n", len(tags)-50))
+	}
+	sb.WriteString("\n")
+
+	if includeJSON {
+		// 4. Full JSON data
+		sb.WriteString("---\n\n")
+		sb.WriteString("💾 **Complete tags data below** (downloadable)\n\n")
+		sb.WriteString("```json\n")
+		jsonData, _ := json.MarshalIndent(tags, "", "  ")
+		sb.WriteString(string(jsonData))
+		sb.WriteString("\n```\n")
+	}
+
+	return sb.String()
+}
+
+// formatBusinessProcessesResponse formats business process sensors with visual summary and JSON export.
+// The Since column shows how long each process has been up or down; stability, when requested,
+// adds a column with its 24h stability note.
+func formatBusinessProcessesResponse(processes []types.Sensor, meta resultMetadata, fullMessages bool,
+	stability *processStability, loc *time.Location, includeJSON bool) string {
+	var sb strings.Builder
+
+	// 0. Machine-readable metadata
+	sb.WriteString(resultMeta(meta))
+
+	// 1. Header
+	sb.WriteString(fmt.Sprintf("## 📊 PRTG Business Processes\n\n"))
+	sb.WriteString(fmt.Sprintf("Found **%d business process(es)**\n\n", len(processes)))
+
+	if len(processes) == 0 {
+		sb.WriteString("No business process sensors found matching the criteria.\n")
+		sb.WriteString("\n💡 **Note:** Business Process sensors aggregate the status of multiple source sensors to monitor complete workflows.\n")
+		return sb.String()
+	}
+
+	// 2. Status breakdown
+	statusCounts := make(map[int]int)
+	for _, process := range processes {
+		statusCounts[process.Status]++
+	}
+
+	sb.WriteString("**Status breakdown:**\n")
+	writeStatusBreakdown(&sb, statusCounts, "process(es)")
+	sb.WriteString("\n")
+
+	// 3. Business processes table
+	showStability := stability != nil && stability.Unavailable == ""
+
+	if stability != nil && stability.Unavailable != "" {
+		sb.WriteString(fmt.Sprintf("⚠️ *Stability unavailable (%s), showing database data only*\n\n", stability.Unavailable))
+	}
+
+	if showStability {
+		sb.WriteString(fmt.Sprintf("| ID | Name | Status | Since | Stability | Priority | Device | Last Check (%s) | Message |\n",
+			timezoneName(loc)))
+		sb.WriteString("|----|------|--------|-------|-----------|----------|--------|------------|----------|\n")
+	} else {
+		sb.WriteString(fmt.Sprintf("| ID | Name | Status | Since | Priority | Device | Last Check (%s) | Message |\n", timezoneName(loc)))
+		sb.WriteString("|----|------|--------|-------|----------|--------|------------|----------|\n")
+	}
+
+	displayCount := len(processes)
+	if displayCount > 50 {
+		displayCount = 50
+	}
+
+	for i := 0; i < displayCount; i++ {
+		process := processes[i]
+		statusEmoji := getStatusEmoji(process.Status)
+
+		lastCheck := "Never"
+		if process.LastCheckUTC != nil {
+			lastCheck = formatTimestamp(*process.LastCheckUTC, loc, "2006-01-02 15:04")
+		}
+
+		// A process is down or up since the last transition, whichever PRTG recorded
+		since := "-"
+		if process.DowntimeSinceSecs != nil && *process.DowntimeSinceSecs > 0 {
+			since = "down " + formatDuration(process.DowntimeSinceSecs)
+		} else if process.UptimeSinceSecs != nil && *process.UptimeSinceSecs > 0 {
+			since = "up " + formatDuration(process.UptimeSinceSecs)
+		}
+
+		note := ""
+		if showStability {
+			note = " - |"
+			if value, ok := stability.Notes[process.ID]; ok {
+				note = " " + value + " |"
+			}
+		}
+
+		sb.WriteString(fmt.Sprintf("| %d | %s | %s %s | %s |%s %d | %s | %s | %s |\n",
+			process.ID,
+			truncateString(process.Name, 30),
+			statusEmoji,
+			process.StatusText,
+			since,
+			note,
+			process.Priority,
+			truncateString(process.DeviceName, 20),
+			lastCheck,
+			truncateString(process.Message, 30),
+		))
+	}
+
+	if len(processes) > 50 {
+		more := "| ... | *%d more processes* | ... | ... | ... | ... | ... | ... |\n"
+		if showStability {
+			more = "| ... | *%d more processes* | ... | ... | ... | ... | ... | ... | ... |\n"
+		}
+
+		sb.WriteString(fmt.Sprintf(more, len(processes)-50))
+	}
+
+	if showStability && len(processes) > maxStabilityProcesses {
+		sb.WriteString(fmt.Sprintf("\n*Stability is fetched for the first %d processes only.*\n", maxStabilityProcesses))
+	}
+
+	if fullMessages {
+		writeFullMessages(&sb, processes)
+	}
+	sb.WriteString("\n")
+
+	if includeJSON {
+		// 4. Full JSON data
+		sb.WriteString("---\n\n")
+		sb.WriteString("💾 **Complete business processes data below** (downloadable)\n\n")
+		sb.WriteString("```json\n")
+		jsonData, _ := json.MarshalIndent(processes, "", "  ")
+		sb.WriteString(string(jsonData))
+		sb.WriteString("\n```\n")
+	}
+
+	return sb.String()
+}
+
+// formatBusinessProcessSourcesResponse formats a business process drill-down with its source sensors.
+func formatBusinessProcessSourcesResponse(result *types.BusinessProcessSources, includeJSON bool) string {
+	var sb strings.Builder
+
+	// 1. Header
+	process := result.Process
+	sb.WriteString(fmt.Sprintf("## 🔎 Business Process Sources: %s\n\n", process.Name))
+	sb.WriteString(fmt.Sprintf("- **Process ID:** %d\n", process.ID))
+	sb.WriteString(fmt.Sprintf("- **Process Status:** %s %s\n", getStatusEmoji(process.Status), process.StatusText))
+	if process.Message != "" {
+		sb.WriteString(fmt.Sprintf("- **Message:** %s\n", truncateString(process.Message, 100)))
+	}
+	sb.WriteString(fmt.Sprintf("- **Source Sensors:** %d\n", len(result.Sources)))
+	sb.WriteString(fmt.Sprintf("- **Down Sources:** %d\n\n", result.DownSources))
+
+	if len(result.Sources) == 0 {
+		sb.WriteString("No source sensors found for this business process.\n")
+		return sb.String()
+	}
+
+	if result.DownSources > 0 {
+		sb.WriteString(fmt.Sprintf("🚨 **%d source sensor(s) down** - likely cause of the process status\n\n", result.DownSources))
+	} else {
+		sb.WriteString("✅ No source sensors are down.\n\n")
+	}
+
+	// 2. Sources table (down sources are listed first by the query)
+	sb.WriteString("| ID | Sensor | Device | Status | Downtime | Message |\n")
+	sb.WriteString("|----|--------|--------|--------|----------|---------|\n")
+
+	for _, source := range result.Sources {
+		name := truncateString(source.Name, 25)
+		if isDownStatus(source.Status) {
+			name = "**" + name + "**"
+		}
+
+		sb.WriteString(fmt.Sprintf("| %d | %s | %s | %s %s | %s | %s |\n",
+			source.ID,
+			name,
+			truncateString(source.DeviceName, 20),
+			getStatusEmoji(source.Status),
+			source.StatusText,
+			formatDuration(source.DowntimeSinceSecs),
+			truncateString(source.Message, 40),
+		))
+	}
+	sb.WriteString("\n")
+
+	if len(result.Unresolved) > 0 {
+		sb.WriteString(fmt.Sprintf("⚠️ %d source ID(s) reported by PRTG were not found in the database: %v\n\n",
+			len(result.Unresolved), result.Unresolved))
+	}
+
+	if includeJSON {
+		// 3. Full JSON data
+		sb.WriteString("---\n\n")
+		sb.WriteString("💾 **Complete business process data below** (downloadable)\n\n")
+		sb.WriteString("```json\n")
+		jsonData, _ := json.MarshalIndent(result, "", "  ")
+		sb.WriteString(string(jsonData))
+		sb.WriteString("\n```\n")
+	}
+
+	return sb.String()
+}
+
+// isDownStatus reports whether a PRTG status code represents a down state.
+func isDownStatus(status int) bool {
+	switch status {
+	case types.StatusDown, types.StatusDownPartial, types.StatusDownAcknowledged:
+		return true
+	default:
+		return false
 	}
+}
+
+// formatStatisticsResponse formats PRTG server statistics with visual summary and JSON export.
+func formatStatisticsResponse(stats *types.Statistics, includeJSON bool) string {
+	var sb strings.Builder
+
+	// 1. Header
+	sb.WriteString("## 📊 PRTG Server Statistics\n\n")
+
+	// 2. Overall counts
+	sb.WriteString("**Overall Infrastructure:**\n")
+	sb.WriteString(fmt.Sprintf("- 🖥️ **Devices:** %d\n", stats.TotalDevices))
+	sb.WriteString(fmt.Sprintf("- 📁 **Groups:** %d\n", stats.TotalGroups))
+	sb.WriteString(fmt.Sprintf("- 📡 **Probes:** %d\n", stats.TotalProbes))
+	sb.WriteString(fmt.Sprintf("- 🔍 **Sensors:** %d\n", stats.TotalSensors))
+	sb.WriteString(fmt.Sprintf("- 🏷️ **Tags:** %d\n", stats.TotalTags))
+	sb.WriteString(fmt.Sprintf("- 📈 **Avg Sensors/Device:** %.1f\n", stats.AvgSensorsPerDevice))
+	sb.WriteString("\n")
+
+	// 3. Status breakdown
+	if len(stats.SensorsByStatus) > 0 {
+		sb.WriteString("**Sensor Status Breakdown:**\n")
 
-	sb.WriteString(fmt.Sprintf("## 📈 Top %s\n\n", metricLabel))
-	sb.WriteString(fmt.Sprintf("Found **%d sensor(s)**\n\n", len(sensors)))
+		percentage := func(count int) float64 {
+			if stats.TotalSensors == 0 {
+				return 0
+			}
 
-	if len(sensors) == 0 {
-		sb.WriteString("No sensors found.\n")
-		return sb.String()
+			return float64(count) / float64(stats.TotalSensors) * 100
+		}
+
+		for _, status := range statusBreakdownOrder {
+			text := types.GetStatusText(status)
+			if count, ok := stats.SensorsByStatus[text]; ok {
+				sb.WriteString(fmt.Sprintf("- %s **%s:** %d (%.1f%%)\n", getStatusEmoji(status), text, count, percentage(count)))
+			}
+		}
+
+		if count, ok := stats.SensorsByStatus["Other"]; ok {
+			sb.WriteString(fmt.Sprintf("- ⚪ **Other:** %d (%.1f%%)\n", count, percentage(count)))
+		}
+		sb.WriteString("\n")
 	}
 
-	// 2. Table
-	sb.WriteString("| Rank | Sensor | Device | Status | Metric | Message |\n")
-	sb.WriteString("|------|--------|--------|--------|--------|----------|\n")
+	// 4. Top sensor types
+	if len(stats.TopSensorTypes) > 0 {
+		sb.WriteString("**Top Sensor Types:**\n\n")
+		sb.WriteString("| Rank | Sensor Type | Count | % of Total |\n")
+		sb.WriteString("|------|-------------|-------|------------|\n")
 
-	for i, sensor := range sensors {
-		statusEmoji := getStatusEmoji(sensor.Status)
-		metricValue := "-"
+		displayCount := len(stats.TopSensorTypes)
+		if displayCount > 15 {
+			displayCount = 15
+		}
 
-		switch metric {
-		case "downtime":
-			metricValue = formatDuration(sensor.DowntimeSinceSecs)
-		case "priority":
-			metricValue = fmt.Sprintf("%s %d", getPriorityEmoji(sensor.Priority), sensor.Priority)
+		for i := 0; i < displayCount; i++ {
+			st := stats.TopSensorTypes[i]
+			percentage := 0.0
+			if stats.TotalSensors > 0 {
+				percentage = float64(st.Count) / float64(stats.TotalSensors) * 100
+			}
+			sb.WriteString(fmt.Sprintf("| #%d | %s | %d | %.1f%% |\n",
+				i+1,
+				truncateString(st.Type, 40),
+				st.Count,
+				percentage,
+			))
 		}
+		sb.WriteString("\n")
+	}
 
-		sb.WriteString(fmt.Sprintf("| #%d | %s | %s | %s %s | %s | %s |\n",
-			i+1,
-			truncateString(sensor.Name, 25),
-			truncateString(sensor.DeviceName, 20),
-			statusEmoji,
-			sensor.StatusText,
-			metricValue,
-			truncateString(sensor.Message, 30),
-		))
+	// 5. Sensor types with the most problems
+	if len(stats.ProblemSensorTypes) > 0 {
+		sb.WriteString("**Sensor Types with Most Problems:**\n\n")
+		sb.WriteString("| Sensor Type | ❌ Down | ⚠️ Warning | ✅ Up | Total |\n")
+		sb.WriteString("|-------------|--------|------------|-------|-------|\n")
+
+		for _, st := range stats.ProblemSensorTypes {
+			sb.WriteString(fmt.Sprintf("| %s | %d | %d | %d | %d |\n",
+				truncateString(st.Type, 40),
+				st.Down,
+				st.Warning,
+				st.Up,
+				st.Total,
+			))
+		}
+		sb.WriteString("\n")
 	}
 
-	// 3. Full JSON data
-	sb.WriteString("\n---\n\n")
-	sb.WriteString("💾 **Complete dataset below** (downloadable)\n\n")
-	sb.WriteString("```json\n")
-	jsonData, _ := json.MarshalIndent(sensors, "", "  ")
-	sb.WriteString(string(jsonData))
-	sb.WriteString("\n```\n")
+	if includeJSON {
+		// 6. Full JSON data
+		sb.WriteString("---\n\n")
+		sb.WriteString("💾 **Complete statistics data below** (downloadable)\n\n")
+		sb.WriteString("```json\n")
+		jsonData, _ := json.MarshalIndent(stats, "", "  ")
+		sb.WriteString(string(jsonData))
+		sb.WriteString("\n```\n")
+	}
 
 	return sb.String()
 }
 
-// formatHierarchyResponse formats hierarchy in a visual tree format with full JSON data.
-func formatHierarchyResponse(node *types.HierarchyNode) string {
+// formatGroupCountsResponse formats sensor counts grouped by a dimension.
+func formatGroupCountsResponse(counts []types.DimensionCount, dimension string, meta resultMetadata, includeJSON bool) string {
 	var sb strings.Builder
 
-	// 1. Header
-	sb.WriteString(fmt.Sprintf("## 🌳 PRTG Hierarchy: %s\n\n", node.Group.Name))
+	// 0. Machine-readable metadata
+	sb.WriteString(resultMeta(meta))
 
-	// 2. Group info
-	sb.WriteString("**Group Information:**\n")
-	sb.WriteString(fmt.Sprintf("- **Group ID:** %d\n", node.Group.ID))
-	sb.WriteString(fmt.Sprintf("- **Tree Depth:** %d\n", node.Group.TreeDepth))
-	if node.Group.IsProbeNode {
-		sb.WriteString("- **Type:** 📡 Probe Node\n")
-	} else {
-		sb.WriteString("- **Type:** 📁 Group\n")
-	}
-	if node.Group.FullPath != "" {
-		sb.WriteString(fmt.Sprintf("- **Path:** %s\n", node.Group.FullPath))
+	// 1. Header with totals
+	total := 0
+	for _, c := range counts {
+		total += c.Count
 	}
-	sb.WriteString("\n")
 
-	// 3. Tree structure
-	sb.WriteString("**Tree Structure:**\n\n")
-	formatHierarchyNode(&sb, node, "", true)
-	sb.WriteString("\n")
+	sb.WriteString(fmt.Sprintf("## 🧮 Sensors by %s\n\n", dimension))
+	sb.WriteString(fmt.Sprintf("Found **%d sensor(s)** in **%d %s value(s)**\n\n", total, len(counts), dimension))
 
-	// 4. Statistics summary
-	deviceCount, sensorCount := countHierarchyStats(node)
-	childGroupCount := len(node.Groups)
+	// 2. Breakdown table
+	if len(counts) > 0 {
+		sb.WriteString(fmt.Sprintf("| %s | Count | %% |\n", dimension))
+		sb.WriteString("|------|-------|---|\n")
 
-	sb.WriteString("**Summary:**\n")
-	sb.WriteString(fmt.Sprintf("- **Child Groups:** %d\n", childGroupCount))
-	sb.WriteString(fmt.Sprintf("- **Total Devices:** %d\n", deviceCount))
-	sb.WriteString(fmt.Sprintf("- **Total Sensors:** %d\n", sensorCount))
-	sb.WriteString("\n")
+		for _, c := range counts {
+			percentage := float64(c.Count) / float64(total) * 100
+			sb.WriteString(fmt.Sprintf("| %s | %d | %.1f%% |\n", dimensionLabel(dimension, c.Value), c.Count, percentage))
+		}
+	}
 
-	// 5. Full JSON data
-	sb.WriteString("---\n\n")
-	sb.WriteString("💾 **Complete hierarchy data below** (downloadable)\n\n")
-	sb.WriteString("```json\n")
-	jsonData, _ := json.MarshalIndent(node, "", "  ")
-	sb.WriteString(string(jsonData))
-	sb.WriteString("\n```\n")
+	if includeJSON {
+		// 3. Full JSON data
+		sb.WriteString("\n---\n\n")
+		sb.WriteString("💾 **Complete data below** (downloadable)\n\n")
+		sb.WriteString("```json\n")
+		jsonData, _ := json.MarshalIndent(counts, "", "  ")
+		sb.WriteString(string(jsonData))
+		sb.WriteString("\n```\n")
+	}
 
 	return sb.String()
 }
 
-// formatHierarchyNode recursively formats a hierarchy node as a tree structure.
-func formatHierarchyNode(sb *strings.Builder, node *types.HierarchyNode, prefix string, isLast bool) {
-	// Determine the branch characters
-	branch := "├── "
-	if isLast {
-		branch = "└── "
+// dimensionLabel renders a dimension value for display: status codes get their name and emoji.
+func dimensionLabel(dimension, value string) string {
+	if value == "" {
+		return "(none)"
 	}
 
-	// Group name
-	groupType := "📁"
-	if node.Group.IsProbeNode {
-		groupType = "📡"
+	if dimension == "status" {
+		if status, err := strconv.Atoi(value); err == nil {
+			return fmt.Sprintf("%s %s (%d)", getStatusEmoji(status), types.GetStatusText(status), status)
+		}
 	}
-	sb.WriteString(fmt.Sprintf("%s%s %s %s\n", prefix, branch, groupType, node.Group.Name))
 
-	// Prepare prefix for children
-	childPrefix := prefix
-	if isLast {
-		childPrefix += "    "
-	} else {
-		childPrefix += "│   "
-	}
+	return truncateString(value, 50)
+}
 
-	// Devices in this group
-	for i, device := range node.Devices {
-		isLastDevice := i == len(node.Devices)-1 && len(node.Groups) == 0
+// Health percentage thresholds of the estate RAG status.
+const (
+	healthGreenThreshold = 98.0
+	healthAmberThreshold = 90.0
+)
 
-		deviceBranch := "├── "
-		if isLastDevice {
-			deviceBranch = "└── "
+// computeEstateHealth combines statistics and alerts into a single health score and RAG status.
+// The status is green at 98%+ of monitored sensors up, amber at 90%+, red below.
+// Sensors on disconnected probes cap the status at amber, since their real state is unknown.
+func computeEstateHealth(stats *types.Statistics, alerts []types.Sensor, topProblems int) *types.EstateHealth {
+	health := &types.EstateHealth{
+		TotalProbes: stats.TotalProbes,
+		TopProblems: []types.ScoredAlert{},
+	}
+
+	for status := types.StatusUnknown; status <= types.StatusDownPartial; status++ {
+		count := stats.SensorsByStatus[types.GetStatusText(status)]
+
+		switch status {
+		case types.StatusUp:
+			health.UpSensors = count
+		case types.StatusWarning, types.StatusUnusual:
+			health.WarningSensors += count
+		case types.StatusDown, types.StatusDownPartial:
+			health.DownSensors += count
+		case types.StatusDownAcknowledged:
+			health.AcknowledgedSensors = count
+		case types.StatusNoProbe:
+			health.NoProbeSensors = count
+		case types.StatusPausedByUser, types.StatusPausedByDependency, types.StatusPausedBySchedule,
+			types.StatusPausedByLicense, types.StatusPausedUntil:
+			health.PausedSensors += count
+
+			continue
 		}
 
-		statusInfo := ""
-		if device.Device.SensorCount > 0 {
-			statusInfo = fmt.Sprintf(" (%d sensors)", device.Device.SensorCount)
-		}
+		health.MonitoredSensors += count
+	}
 
-		sb.WriteString(fmt.Sprintf("%s%s 🖥️  %s%s\n", childPrefix, deviceBranch, device.Device.Name, statusInfo))
+	health.HealthPercent = 100
+	if health.MonitoredSensors > 0 {
+		health.HealthPercent = float64(health.UpSensors) / float64(health.MonitoredSensors) * 100
+	}
 
-		// Sensors if included
-		if len(device.Sensors) > 0 {
-			sensorPrefix := childPrefix
-			if isLastDevice {
-				sensorPrefix += "    "
-			} else {
-				sensorPrefix += "│   "
-			}
+	switch {
+	case health.HealthPercent < healthAmberThreshold:
+		health.Status = types.HealthRed
+	case health.HealthPercent < healthGreenThreshold || health.NoProbeSensors > 0:
+		health.Status = types.HealthAmber
+	default:
+		health.Status = types.HealthGreen
+	}
 
-			for j, sensor := range device.Sensors {
-				isLastSensor := j == len(device.Sensors)-1
-				sensorBranch := "├── "
-				if isLastSensor {
-					sensorBranch = "└── "
-				}
+	ranked := slices.Clone(alerts)
+	sortBySeverity(ranked)
 
-				emoji := getStatusEmoji(sensor.Status)
-				sb.WriteString(fmt.Sprintf("%s%s %s %s (%s)\n",
-					sensorPrefix, sensorBranch, emoji, sensor.Name, sensor.StatusText))
-			}
+	for _, alert := range scoreAlerts(ranked) {
+		if len(health.TopProblems) >= topProblems || alert.SeverityScore == 0 {
+			break
 		}
+
+		health.TopProblems = append(health.TopProblems, alert)
 	}
 
-	// Child groups
-	for i, childGroup := range node.Groups {
-		isLastGroup := i == len(node.Groups)-1
-		formatHierarchyNode(sb, childGroup, childPrefix, isLastGroup)
+	probes := "all probes connected"
+	if health.NoProbeSensors > 0 {
+		probes = fmt.Sprintf("%d sensor(s) on disconnected probes", health.NoProbeSensors)
 	}
+
+	health.Summary = fmt.Sprintf("%.1f%% of sensors up; %d critical issue(s); %d warning(s); %s",
+		health.HealthPercent, health.DownSensors, health.WarningSensors, probes)
+
+	return health
 }
 
-// countHierarchyStats counts total devices and sensors in the hierarchy tree.
-func countHierarchyStats(node *types.HierarchyNode) (devices, sensors int) {
-	devices = len(node.Devices)
+// formatEstateHealthResponse formats the estate health summary.
+func formatEstateHealthResponse(health *types.EstateHealth, includeJSON bool) string {
+	var sb strings.Builder
 
-	for _, device := range node.Devices {
-		sensors += len(device.Sensors)
+	// 1. Header with RAG status
+	ragEmoji := map[string]string{
+		types.HealthGreen: "🟢",
+		types.HealthAmber: "🟠",
+		types.HealthRed:   "🔴",
+	}[health.Status]
+
+	sb.WriteString("## 🩺 PRTG Estate Health\n\n")
+	sb.WriteString(fmt.Sprintf("%s **%s** - %.1f%% healthy\n\n", ragEmoji, strings.ToUpper(health.Status), health.HealthPercent))
+	sb.WriteString(fmt.Sprintf("%s\n\n", health.Summary))
+
+	// 2. Sensor counts
+	sb.WriteString("**Monitored Sensors:**\n")
+	sb.WriteString(fmt.Sprintf("- ✅ **Up:** %d\n", health.UpSensors))
+	sb.WriteString(fmt.Sprintf("- ⚠️ **Warning:** %d\n", health.WarningSensors))
+	sb.WriteString(fmt.Sprintf("- ❌ **Down:** %d\n", health.DownSensors))
+	sb.WriteString(fmt.Sprintf("- 👁️ **Acknowledged:** %d\n", health.AcknowledgedSensors))
+	sb.WriteString(fmt.Sprintf("- 📡 **Probes:** %d (%d sensor(s) without probe connection)\n", health.TotalProbes, health.NoProbeSensors))
+	sb.WriteString(fmt.Sprintf("- ⏸️ **Paused (excluded):** %d\n\n", health.PausedSensors))
+
+	// 3. Top problems
+	if len(health.TopProblems) > 0 {
+		sb.WriteString("**Top Problems:**\n\n")
+		sb.WriteString("| Score | Sensor | Device | Status | Message |\n")
+		sb.WriteString("|-------|--------|--------|--------|---------|\n")
+
+		for _, alert := range health.TopProblems {
+			sb.WriteString(fmt.Sprintf("| %d | %s | %s | %s %s | %s |\n",
+				alert.SeverityScore,
+				truncateString(alert.Name, 30),
+				truncateString(alert.DeviceName, 25),
+				getStatusEmoji(alert.Status),
+				alert.StatusText,
+				truncateString(alert.Message, 40),
+			))
+		}
+		sb.WriteString("\n")
 	}
 
-	for _, childGroup := range node.Groups {
-		childDevices, childSensors := countHierarchyStats(childGroup)
-		devices += childDevices
-		sensors += childSensors
+	if includeJSON {
+		// 4. Full JSON data
+		sb.WriteString("---\n\n")
+		sb.WriteString("💾 **Complete health data below** (downloadable)\n\n")
+		sb.WriteString("```json\n")
+		jsonData, _ := json.MarshalIndent(health, "", "  ")
+		sb.WriteString(string(jsonData))
+		sb.WriteString("\n```\n")
 	}
 
-	return devices, sensors
+	return sb.String()
 }
 
-// formatSearchResponse formats universal search results in a visual format with full JSON data.
-func formatSearchResponse(results *types.SearchResults, searchTerm string) string {
-	var sb strings.Builder
+// maxOutageSensorsShown caps the sensors listed under each probable cause.
+const maxOutageSensorsShown = 10
 
-	totalResults := len(results.Groups) + len(results.Devices) + len(results.Sensors)
+// deviceProblems holds the monitored sensor counts and problem sensors of one device.
+type deviceProblems struct {
+	name      string
+	monitored int
+	down      int
+	pingDown  bool
+	problems  []types.Sensor
+}
 
-	// 1. Header
-	sb.WriteString(fmt.Sprintf("## 🔍 Search Results for \"%s\"\n\n", searchTerm))
-	sb.WriteString(fmt.Sprintf("Found **%d total result(s)** across all categories\n\n", totalResults))
+// explainOutage groups the problem sensors of a scope by probable root cause:
+//   - a device whose monitored sensors are all down (at least two), or whose ping sensor is down,
+//     is a device outage (unreachable host or network path);
+//   - when every monitored device of the scope is in that case, it is a site outage
+//     (shared network link, probe or power);
+//   - a reachable device with several sensors down has failing services;
+//   - any other problem (a single down sensor, warnings) is isolated.
+//
+// Paused, Unknown and Collecting sensors are not monitored; sensors paused by dependency
+// are counted because they usually hide more of an outage.
+func explainOutage(scope string, sensors []types.Sensor) *types.OutageExplanation {
+	explanation := &types.OutageExplanation{
+		Scope:  scope,
+		Causes: []types.OutageCause{},
+	}
 
-	if totalResults == 0 {
-		sb.WriteString("No results found. Try a different search term.\n")
-		return sb.String()
+	inactive := make(map[int]bool, len(types.InactiveStatuses))
+	for _, status := range types.InactiveStatuses {
+		inactive[status] = true
 	}
 
-	// 2. Summary breakdown
-	sb.WriteString("**Results by category:**\n")
-	sb.WriteString(fmt.Sprintf("- 📁 **Groups:** %d\n", len(results.Groups)))
-	sb.WriteString(fmt.Sprintf("- 🖥️  **Devices:** %d\n", len(results.Devices)))
-	sb.WriteString(fmt.Sprintf("- 📊 **Sensors:** %d\n", len(results.Sensors)))
-	sb.WriteString("\n")
+	devices := make(map[int]*deviceProblems)
 
-	// 3. Groups section
-	if len(results.Groups) > 0 {
-		sb.WriteString("### 📁 Groups\n\n")
-		sb.WriteString("| ID | Name | Type | Path |\n")
-		sb.WriteString("|----|------|------|------|\n")
+	var order []*deviceProblems
 
-		displayCount := len(results.Groups)
-		if displayCount > 20 {
-			displayCount = 20
+	for _, sensor := range sensors {
+		if sensor.Status == types.StatusPausedByDependency {
+			explanation.PausedByDependency++
 		}
 
-		for i := 0; i < displayCount; i++ {
-			group := results.Groups[i]
-			groupType := "Group"
-			if group.IsProbeNode {
-				groupType = "Probe"
-			}
-
-			sb.WriteString(fmt.Sprintf("| %d | %s | %s | %s |\n",
-				group.ID,
-				truncateString(group.Name, 30),
-				groupType,
-				truncateString(group.FullPath, 40),
-			))
+		if inactive[sensor.Status] {
+			continue
 		}
 
-		if len(results.Groups) > 20 {
-			sb.WriteString(fmt.Sprintf("| ... | *%d more groups* | ... | ... |\n", len(results.Groups)-20))
+		device, ok := devices[sensor.DeviceID]
+		if !ok {
+			device = &deviceProblems{name: sensor.DeviceName}
+			devices[sensor.DeviceID] = device
+			order = append(order, device)
 		}
-		sb.WriteString("\n")
-	}
-
-	// 4. Devices section
-	if len(results.Devices) > 0 {
-		sb.WriteString("### 🖥️  Devices\n\n")
-		sb.WriteString("| ID | Name | Host | Group | Sensors |\n")
-		sb.WriteString("|----|------|------|-------|----------|\n")
 
-		displayCount := len(results.Devices)
-		if displayCount > 20 {
-			displayCount = 20
-		}
+		device.monitored++
+		explanation.MonitoredSensors++
 
-		for i := 0; i < displayCount; i++ {
-			device := results.Devices[i]
+		switch {
+		case isDownStatus(sensor.Status):
+			device.down++
+			explanation.DownSensors++
 
-			sb.WriteString(fmt.Sprintf("| %d | %s | %s | %s | %d |\n",
-				device.ID,
-				truncateString(device.Name, 25),
-				truncateString(device.Host, 20),
-				truncateString(device.GroupName, 20),
-				device.SensorCount,
-			))
+			if strings.Contains(strings.ToLower(sensor.SensorType), "ping") {
+				device.pingDown = true
+			}
+		case sensor.Status == types.StatusWarning || sensor.Status == types.StatusUnusual:
+			explanation.WarningSensors++
+		default:
+			continue
 		}
 
-		if len(results.Devices) > 20 {
-			sb.WriteString(fmt.Sprintf("| ... | *%d more devices* | ... | ... | ... |\n", len(results.Devices)-20))
-		}
-		sb.WriteString("\n")
+		device.problems = append(device.problems, sensor)
 	}
 
-	// 5. Sensors section
-	if len(results.Sensors) > 0 {
-		sb.WriteString("### 📊 Sensors\n\n")
-		sb.WriteString("| ID | Name | Device | Type | Status |\n")
-		sb.WriteString("|----|------|--------|------|--------|\n")
+	explanation.MonitoredDevices = len(order)
 
-		displayCount := len(results.Sensors)
-		if displayCount > 20 {
-			displayCount = 20
+	var outages, services []*deviceProblems
+
+	isolated := types.OutageCause{
+		Cause:       types.CauseIsolated,
+		Explanation: "Single problems on otherwise healthy devices: check each sensor individually.",
+		Devices:     []string{},
+		Sensors:     []types.Sensor{},
+	}
+
+	for _, device := range order {
+		switch {
+		case len(device.problems) == 0:
+		case device.pingDown || (device.monitored >= 2 && device.down == device.monitored):
+			outages = append(outages, device)
+		case device.down >= 2:
+			services = append(services, device)
+		default:
+			isolated.Devices = append(isolated.Devices, device.name)
+			isolated.Sensors = append(isolated.Sensors, device.problems...)
 		}
+	}
 
-		for i := 0; i < displayCount; i++ {
-			sensor := results.Sensors[i]
-			statusEmoji := getStatusEmoji(sensor.Status)
+	if len(outages) >= 2 && len(outages) == len(order) {
+		site := types.OutageCause{
+			Cause: types.CauseSiteOutage,
+			Explanation: fmt.Sprintf("All %d monitored devices are down: probable site-wide outage "+
+				"(shared network link, probe connection or power).", len(outages)),
+		}
 
-			sb.WriteString(fmt.Sprintf("| %d | %s | %s | %s | %s %s |\n",
-				sensor.ID,
-				truncateString(sensor.Name, 25),
-				truncateString(sensor.DeviceName, 20),
-				truncateString(sensor.SensorType, 15),
-				statusEmoji,
-				sensor.StatusText,
-			))
+		for _, device := range outages {
+			site.Devices = append(site.Devices, device.name)
+			site.Sensors = append(site.Sensors, device.problems...)
 		}
 
-		if len(results.Sensors) > 20 {
-			sb.WriteString(fmt.Sprintf("| ... | *%d more sensors* | ... | ... | ... |\n", len(results.Sensors)-20))
+		explanation.Causes = append(explanation.Causes, site)
+		outages = nil
+	}
+
+	for _, device := range outages {
+		reason := fmt.Sprintf("All %d monitored sensors are down", device.monitored)
+		if device.down < device.monitored {
+			reason = "The ping sensor is down"
 		}
-		sb.WriteString("\n")
+
+		explanation.Causes = append(explanation.Causes, types.OutageCause{
+			Cause:       types.CauseDeviceOutage,
+			Explanation: reason + ": probable device or network outage (host unreachable, powered off or link down).",
+			Devices:     []string{device.name},
+			Sensors:     device.problems,
+		})
 	}
 
-	// 6. Full JSON data
-	sb.WriteString("---\n\n")
-	sb.WriteString("💾 **Complete search results below** (downloadable)\n\n")
-	sb.WriteString("```json\n")
-	jsonData, _ := json.MarshalIndent(results, "", "  ")
-	sb.WriteString(string(jsonData))
-	sb.WriteString("\n```\n")
+	for _, device := range services {
+		explanation.Causes = append(explanation.Causes, types.OutageCause{
+			Cause: types.CauseMultipleServices,
+			Explanation: fmt.Sprintf("%d of %d monitored sensors are down while the device still responds: "+
+				"probable failure of services or resources on the device.", device.down, device.monitored),
+			Devices: []string{device.name},
+			Sensors: device.problems,
+		})
+	}
 
-	return sb.String()
-}
+	if len(isolated.Sensors) > 0 {
+		explanation.Causes = append(explanation.Causes, isolated)
+	}
 
-// formatGroupsResponse formats groups in a visual format with full JSON data.
-func formatGroupsResponse(groups []types.Group) string {
-	var sb strings.Builder
+	for i := range explanation.Causes {
+		sortBySeverity(explanation.Causes[i].Sensors)
+	}
 
-	// 1. Header
-	sb.WriteString(fmt.Sprintf("## 📁 PRTG Groups\n\n"))
-	sb.WriteString(fmt.Sprintf("Found **%d group(s)**\n\n", len(groups)))
+	explanation.Summary = outageSummary(explanation)
 
-	if len(groups) == 0 {
-		sb.WriteString("No groups found matching the criteria.\n")
-		return sb.String()
+	return explanation
+}
+
+// sortBySeverity orders sensors by alert severity score, most severe first.
+func sortBySeverity(sensors []types.Sensor) {
+	sort.SliceStable(sensors, func(i, j int) bool {
+		return alertSeverityScore(sensors[i]) > alertSeverityScore(sensors[j])
+	})
+}
+
+// outageSummary returns a one-line narrative of an outage explanation.
+func outageSummary(explanation *types.OutageExplanation) string {
+	if len(explanation.Causes) == 0 {
+		return fmt.Sprintf("No down or warning sensors among %d monitored sensor(s) in %s.",
+			explanation.MonitoredSensors, explanation.Scope)
 	}
 
-	// 2. Breakdown by type
-	probeCount := 0
-	groupCount := 0
-	for _, group := range groups {
-		if group.IsProbeNode {
-			probeCount++
-		} else {
-			groupCount++
+	counts := make(map[string]int)
+	isolated := 0
+
+	for _, cause := range explanation.Causes {
+		counts[cause.Cause]++
+
+		if cause.Cause == types.CauseIsolated {
+			isolated = len(cause.Sensors)
 		}
 	}
 
-	sb.WriteString("**Breakdown by type:**\n")
-	sb.WriteString(fmt.Sprintf("- 📡 **Probes:** %d\n", probeCount))
-	sb.WriteString(fmt.Sprintf("- 📁 **Groups:** %d\n", groupCount))
-	sb.WriteString("\n")
-
-	// 3. Groups table
-	sb.WriteString("| ID | Name | Type | Tree Depth | Path |\n")
-	sb.WriteString("|----|------|------|------------|------|\n")
+	var parts []string
 
-	displayCount := len(groups)
-	if displayCount > 50 {
-		displayCount = 50
+	if counts[types.CauseSiteOutage] > 0 {
+		parts = append(parts, "site-wide outage")
 	}
 
-	for i := 0; i < displayCount; i++ {
-		group := groups[i]
-		groupType := "Group"
-		typeIcon := "📁"
-		if group.IsProbeNode {
-			groupType = "Probe"
-			typeIcon = "📡"
-		}
+	if n := counts[types.CauseDeviceOutage]; n > 0 {
+		parts = append(parts, fmt.Sprintf("%d device outage(s)", n))
+	}
 
-		sb.WriteString(fmt.Sprintf("| %d | %s | %s %s | %d | %s |\n",
-			group.ID,
-			truncateString(group.Name, 30),
-			typeIcon,
-			groupType,
-			group.TreeDepth,
-			truncateString(group.FullPath, 50),
-		))
+	if n := counts[types.CauseMultipleServices]; n > 0 {
+		parts = append(parts, fmt.Sprintf("%d device(s) with failing services", n))
 	}
 
-	if len(groups) > 50 {
-		sb.WriteString(fmt.Sprintf("| ... | *%d more groups* | ... | ... | ... |\n", len(groups)-50))
+	if isolated > 0 {
+		parts = append(parts, fmt.Sprintf("%d isolated problem(s)", isolated))
 	}
-	sb.WriteString("\n")
 
-	// 4. Full JSON data
-	sb.WriteString("---\n\n")
-	sb.WriteString("💾 **Complete groups data below** (downloadable)\n\n")
-	sb.WriteString("```json\n")
-	jsonData, _ := json.MarshalIndent(groups, "", "  ")
-	sb.WriteString(string(jsonData))
-	sb.WriteString("\n```\n")
+	return fmt.Sprintf("%d down and %d warning sensor(s) among %d monitored in %s: %s.",
+		explanation.DownSensors, explanation.WarningSensors, explanation.MonitoredSensors,
+		explanation.Scope, strings.Join(parts, ", "))
+}
 
-	return sb.String()
+// outageCauseTitles are the section titles of each probable cause.
+var outageCauseTitles = map[string]string{
+	types.CauseSiteOutage:       "🌐 Probable site outage",
+	types.CauseDeviceOutage:     "🔴 Probable device outage",
+	types.CauseMultipleServices: "🟠 Failing services",
+	types.CauseIsolated:         "🟡 Isolated problems",
 }
 
-// formatTagsResponse formats tags data with visual summary and JSON export.
-func formatTagsResponse(tags []types.Tag) string {
+// formatOutageExplanationResponse formats an outage explanation, one section per probable cause.
+func formatOutageExplanationResponse(explanation *types.OutageExplanation, meta resultMetadata, includeJSON bool) string {
 	var sb strings.Builder
 
-	// 1. Header
-	sb.WriteString(fmt.Sprintf("## 🏷️ PRTG Tags\n\n"))
-	sb.WriteString(fmt.Sprintf("Found **%d tag(s)**\n\n", len(tags)))
+	// 0. Machine-readable metadata
+	sb.WriteString(resultMeta(meta))
 
-	if len(tags) == 0 {
-		sb.WriteString("No tags found matching the criteria.\n")
-		return sb.String()
-	}
+	// 1. Header with narrative summary
+	sb.WriteString(fmt.Sprintf("## 🔎 Outage Explanation: %s\n\n", explanation.Scope))
+	sb.WriteString(explanation.Summary + "\n\n")
 
-	// 2. Statistics
-	totalSensorCount := 0
-	for _, tag := range tags {
-		totalSensorCount += tag.SensorCount
+	if meta.Truncated {
+		sb.WriteString(fmt.Sprintf("⚠️ Only the first %d sensors were analyzed; narrow the scope for a complete picture.\n\n", meta.Returned))
 	}
 
-	sb.WriteString("**Tag usage statistics:**\n")
-	sb.WriteString(fmt.Sprintf("- 📊 **Total sensor associations:** %d\n", totalSensorCount))
-	if len(tags) > 0 {
-		avgUsage := float64(totalSensorCount) / float64(len(tags))
-		sb.WriteString(fmt.Sprintf("- 📈 **Average sensors per tag:** %.1f\n", avgUsage))
+	if explanation.PausedByDependency > 0 {
+		sb.WriteString(fmt.Sprintf("⏸️ %d sensor(s) paused by dependency, usually because a parent sensor or device is down.\n\n",
+			explanation.PausedByDependency))
 	}
-	sb.WriteString("\n")
 
-	// 3. Tags table
-	sb.WriteString("| ID | Tag Name | Sensor Count |\n")
-	sb.WriteString("|----|----------|-------------|\n")
+	// 2. One section per probable cause
+	for _, cause := range explanation.Causes {
+		title := outageCauseTitles[cause.Cause]
+		if cause.Cause == types.CauseDeviceOutage || cause.Cause == types.CauseMultipleServices {
+			title += ": " + cause.Devices[0]
+		}
 
-	displayCount := len(tags)
-	if displayCount > 50 {
-		displayCount = 50
-	}
+		sb.WriteString(fmt.Sprintf("### %s\n\n", title))
+		sb.WriteString(cause.Explanation + "\n\n")
 
-	for i := 0; i < displayCount; i++ {
-		tag := tags[i]
-		sb.WriteString(fmt.Sprintf("| %d | %s | %d |\n",
-			tag.ID,
-			truncateString(tag.Name, 40),
-			tag.SensorCount,
-		))
-	}
+		if cause.Cause == types.CauseSiteOutage {
+			sb.WriteString(fmt.Sprintf("**Devices:** %s\n\n", strings.Join(cause.Devices, ", ")))
+		}
 
-	if len(tags) > 50 {
-		sb.WriteString(fmt.Sprintf("| ... | *%d more tags* | ... |\n", len(tags)-50))
+		sb.WriteString("| Sensor | Device | Status | Message |\n")
+		sb.WriteString("|--------|--------|--------|---------|\n")
+
+		shown := cause.Sensors
+		if len(shown) > maxOutageSensorsShown {
+			shown = shown[:maxOutageSensorsShown]
+		}
+
+		for _, sensor := range shown {
+			sb.WriteString(fmt.Sprintf("| %s | %s | %s %s | %s |\n",
+				escapeTableCell(truncateString(sensor.Name, 30)),
+				escapeTableCell(truncateString(sensor.DeviceName, 25)),
+				getStatusEmoji(sensor.Status),
+				sensor.StatusText,
+				escapeTableCell(truncateString(sensor.Message, 60)),
+			))
+		}
+
+		if len(cause.Sensors) > maxOutageSensorsShown {
+			sb.WriteString(fmt.Sprintf("| *%d more sensors* | ... | ... | ... |\n", len(cause.Sensors)-maxOutageSensorsShown))
+		}
+
+		sb.WriteString("\n")
 	}
-	sb.WriteString("\n")
 
-	// 4. Full JSON data
-	sb.WriteString("---\n\n")
-	sb.WriteString("💾 **Complete tags data below** (downloadable)\n\n")
-	sb.WriteString("```json\n")
-	jsonData, _ := json.MarshalIndent(tags, "", "  ")
-	sb.WriteString(string(jsonData))
-	sb.WriteString("\n```\n")
+	if includeJSON {
+		// 3. Full JSON data
+		sb.WriteString("---\n\n")
+		sb.WriteString("💾 **Complete dataset below** (downloadable for further analysis)\n\n")
+		sb.WriteString("```json\n")
+		jsonData, _ := json.MarshalIndent(explanation, "", "  ")
+		sb.WriteString(string(jsonData))
+		sb.WriteString("\n```\n")
+	}
 
 	return sb.String()
 }
 
-// formatBusinessProcessesResponse formats business process sensors with visual summary and JSON export.
-func formatBusinessProcessesResponse(processes []types.Sensor) string {
-	var sb strings.Builder
-
-	// 1. Header
-	sb.WriteString(fmt.Sprintf("## 📊 PRTG Business Processes\n\n"))
-	sb.WriteString(fmt.Sprintf("Found **%d business process(es)**\n\n", len(processes)))
+// diffSensor reports what changed between a previous snapshot of a sensor and its current state:
+// status (with the up/down transition), message, priority, and uptime/downtime.
+// A shorter uptime (or downtime) than before means the sensor left that state and came back
+// between the snapshots, which is a change even when the status is the same.
+func diffSensor(old, current types.Sensor) *types.SensorDiff {
+	diff := &types.SensorDiff{
+		SensorID: current.ID,
+		Changes:  []types.FieldChange{},
+		Current:  current,
+	}
 
-	if len(processes) == 0 {
-		sb.WriteString("No business process sensors found matching the criteria.\n")
-		sb.WriteString("\n💡 **Note:** Business Process sensors aggregate the status of multiple source sensors to monitor complete workflows.\n")
-		return sb.String()
+	if old.Status != current.Status {
+		diff.Changes = append(diff.Changes, types.FieldChange{
+			Field: "status",
+			Old:   types.GetStatusText(old.Status),
+			New:   types.GetStatusText(current.Status),
+		})
+		diff.Transition = statusTransition(old.Status, current.Status)
 	}
 
-	// 2. Status breakdown
-	statusCounts := make(map[int]int)
-	for _, process := range processes {
-		statusCounts[process.Status]++
+	if old.Message != current.Message {
+		diff.Changes = append(diff.Changes, types.FieldChange{Field: "message", Old: old.Message, New: current.Message})
 	}
 
-	sb.WriteString("**Status breakdown:**\n")
-	if count, ok := statusCounts[types.StatusUp]; ok && count > 0 {
-		sb.WriteString(fmt.Sprintf("- ✅ **Up:** %d\n", count))
+	if old.Priority != current.Priority {
+		diff.Changes = append(diff.Changes, types.FieldChange{
+			Field: "priority",
+			Old:   strconv.Itoa(old.Priority),
+			New:   strconv.Itoa(current.Priority),
+		})
 	}
-	if count, ok := statusCounts[types.StatusWarning]; ok && count > 0 {
-		sb.WriteString(fmt.Sprintf("- ⚠️ **Warning:** %d\n", count))
+
+	diff.UptimeDeltaSecs = secondsDelta(old.UptimeSinceSecs, current.UptimeSinceSecs)
+	if diff.UptimeDeltaSecs != nil && *diff.UptimeDeltaSecs < 0 {
+		diff.Changes = append(diff.Changes, types.FieldChange{
+			Field: "uptime_since_seconds",
+			Old:   formatDuration(old.UptimeSinceSecs),
+			New:   formatDuration(current.UptimeSinceSecs),
+		})
 	}
-	if count, ok := statusCounts[types.StatusDown]; ok && count > 0 {
-		sb.WriteString(fmt.Sprintf("- ❌ **Down:** %d\n", count))
+
+	diff.DowntimeDeltaSecs = secondsDelta(old.DowntimeSinceSecs, current.DowntimeSinceSecs)
+	if diff.DowntimeDeltaSecs != nil && *diff.DowntimeDeltaSecs < 0 {
+		diff.Changes = append(diff.Changes, types.FieldChange{
+			Field: "downtime_since_seconds",
+			Old:   formatDuration(old.DowntimeSinceSecs),
+			New:   formatDuration(current.DowntimeSinceSecs),
+		})
 	}
-	// Show other statuses if present
-	for status, count := range statusCounts {
-		if status != types.StatusUp && status != types.StatusWarning && status != types.StatusDown && count > 0 {
-			sb.WriteString(fmt.Sprintf("- 🔵 **%s:** %d\n", types.GetStatusText(status), count))
+
+	if diff.Transition != "" {
+		if transition, changedAt := current.LastTransition(); transition == diff.Transition {
+			diff.ChangedAt = &changedAt
 		}
 	}
-	sb.WriteString("\n")
 
-	// 3. Business processes table
-	sb.WriteString("| ID | Name | Status | Priority | Device | Last Check | Message |\n")
-	sb.WriteString("|----|------|--------|----------|--------|------------|----------|\n")
+	diff.Changed = len(diff.Changes) > 0
 
-	displayCount := len(processes)
-	if displayCount > 50 {
-		displayCount = 50
+	return diff
+}
+
+// diffSensorSince reports whether the sensor went down or came back up after since.
+// Without a previous snapshot, message and priority changes cannot be detected.
+func diffSensorSince(current types.Sensor, since, now time.Time) *types.SensorDiff {
+	diff := &types.SensorDiff{
+		SensorID: current.ID,
+		Since:    &since,
+		Changes:  []types.FieldChange{},
+		Current:  current,
 	}
 
-	for i := 0; i < displayCount; i++ {
-		process := processes[i]
-		statusEmoji := getStatusEmoji(process.Status)
+	transition, changedAt := stateEnteredAt(current, now)
+	if changedAt.After(since) {
+		diff.Changed = true
+		diff.Transition = transition
+		diff.ChangedAt = &changedAt
+	}
 
-		lastCheck := "Never"
-		if process.LastCheckUTC != nil {
-			lastCheck = process.LastCheckUTC.Format("2006-01-02 15:04")
-		}
+	return diff
+}
 
-		sb.WriteString(fmt.Sprintf("| %d | %s | %s %s | %d | %s | %s | %s |\n",
-			process.ID,
-			truncateString(process.Name, 30),
-			statusEmoji,
-			process.StatusText,
-			process.Priority,
-			truncateString(process.DeviceName, 20),
-			lastCheck,
-			truncateString(process.Message, 30),
-		))
+// stateEnteredAt returns the most recent up/down transition of a sensor. The uptime/downtime
+// counters are preferred, measured from the last check; otherwise the last up/down timestamps are used.
+func stateEnteredAt(sensor types.Sensor, now time.Time) (string, time.Time) {
+	ref := now
+	if sensor.LastCheckUTC != nil {
+		ref = *sensor.LastCheckUTC
 	}
 
-	if len(processes) > 50 {
-		sb.WriteString(fmt.Sprintf("| ... | *%d more processes* | ... | ... | ... | ... | ... |\n", len(processes)-50))
+	switch {
+	case sensor.Status == types.StatusUp && sensor.UptimeSinceSecs != nil:
+		return types.TransitionUp, ref.Add(-time.Duration(*sensor.UptimeSinceSecs * float64(time.Second)))
+	case isDownStatus(sensor.Status) && sensor.DowntimeSinceSecs != nil:
+		return types.TransitionDown, ref.Add(-time.Duration(*sensor.DowntimeSinceSecs * float64(time.Second)))
+	default:
+		return sensor.LastTransition()
 	}
-	sb.WriteString("\n")
+}
 
-	// 4. Full JSON data
-	sb.WriteString("---\n\n")
-	sb.WriteString("💾 **Complete business processes data below** (downloadable)\n\n")
-	sb.WriteString("```json\n")
-	jsonData, _ := json.MarshalIndent(processes, "", "  ")
-	sb.WriteString(string(jsonData))
-	sb.WriteString("\n```\n")
+// statusTransition labels a status change as going down or coming back up ("" for other changes).
+func statusTransition(oldStatus, newStatus int) string {
+	switch {
+	case isDownStatus(newStatus) && !isDownStatus(oldStatus):
+		return types.TransitionDown
+	case newStatus == types.StatusUp:
+		return types.TransitionUp
+	default:
+		return ""
+	}
+}
 
-	return sb.String()
+// secondsDelta returns current - old, or nil if either value is missing.
+func secondsDelta(old, current *float64) *float64 {
+	if old == nil || current == nil {
+		return nil
+	}
+
+	delta := *current - *old
+
+	return &delta
 }
 
-// formatStatisticsResponse formats PRTG server statistics with visual summary and JSON export.
-func formatStatisticsResponse(stats *types.Statistics) string {
+// formatSensorDiffResponse formats the changes of a sensor since a previous look.
+func formatSensorDiffResponse(diff *types.SensorDiff, loc *time.Location, includeJSON bool) string {
 	var sb strings.Builder
 
-	// 1. Header
-	sb.WriteString("## 📊 PRTG Server Statistics\n\n")
+	// 1. Header with sensor identification
+	sb.WriteString("## 🔍 Sensor Status Diff\n\n")
+	sb.WriteString(fmt.Sprintf("**Sensor:** %s (ID: %d) on %s\n", diff.Current.Name, diff.SensorID, diff.Current.DeviceName))
+	sb.WriteString(fmt.Sprintf("**Current Status:** %s %s\n\n", getStatusEmoji(diff.Current.Status), diff.Current.StatusText))
 
-	// 2. Overall counts
-	sb.WriteString("**Overall Infrastructure:**\n")
-	sb.WriteString(fmt.Sprintf("- 🖥️ **Devices:** %d\n", stats.TotalDevices))
-	sb.WriteString(fmt.Sprintf("- 📁 **Groups:** %d\n", stats.TotalGroups))
-	sb.WriteString(fmt.Sprintf("- 📡 **Probes:** %d\n", stats.TotalProbes))
-	sb.WriteString(fmt.Sprintf("- 🔍 **Sensors:** %d\n", stats.TotalSensors))
-	sb.WriteString(fmt.Sprintf("- 🏷️ **Tags:** %d\n", stats.TotalTags))
-	sb.WriteString(fmt.Sprintf("- 📈 **Avg Sensors/Device:** %.1f\n", stats.AvgSensorsPerDevice))
-	sb.WriteString("\n")
+	reference := "the previous snapshot"
+	if diff.Since != nil {
+		reference = formatTimestamp(*diff.Since, loc, "2006-01-02 15:04:05 MST")
+	}
 
-	// 3. Status breakdown
-	if len(stats.SensorsByStatus) > 0 {
-		sb.WriteString("**Sensor Status Breakdown:**\n")
+	// 2. Verdict and transition
+	if diff.Changed {
+		sb.WriteString(fmt.Sprintf("🔄 **Changed** since %s.\n\n", reference))
+	} else {
+		sb.WriteString(fmt.Sprintf("✅ No change since %s.\n\n", reference))
+	}
 
-		// Order: Up, Warning, Down, then others
-		statusOrder := []string{"Up", "Warning", "Down"}
-		for _, status := range statusOrder {
-			if count, ok := stats.SensorsByStatus[status]; ok {
-				emoji := ""
-				switch status {
-				case "Up":
-					emoji = "✅"
-				case "Warning":
-					emoji = "⚠️"
-				case "Down":
-					emoji = "❌"
-				}
-				percentage := 0.0
-				if stats.TotalSensors > 0 {
-					percentage = float64(count) / float64(stats.TotalSensors) * 100
-				}
-				sb.WriteString(fmt.Sprintf("- %s **%s:** %d (%.1f%%)\n", emoji, status, count, percentage))
-			}
+	if diff.Transition != "" {
+		label := "🟢 **Came back up**"
+		if diff.Transition == types.TransitionDown {
+			label = "🔴 **Went down**"
 		}
 
-		// Show other statuses
-		for status, count := range stats.SensorsByStatus {
-			if status != "Up" && status != "Warning" && status != "Down" {
-				percentage := 0.0
-				if stats.TotalSensors > 0 {
-					percentage = float64(count) / float64(stats.TotalSensors) * 100
-				}
-				sb.WriteString(fmt.Sprintf("- 🔵 **%s:** %d (%.1f%%)\n", status, count, percentage))
-			}
+		sb.WriteString("- " + label)
+		if diff.ChangedAt != nil {
+			sb.WriteString(" at " + formatTimestamp(*diff.ChangedAt, loc, "2006-01-02 15:04:05 MST"))
 		}
-		sb.WriteString("\n")
+		sb.WriteString("\n\n")
 	}
 
-	// 4. Top sensor types
-	if len(stats.TopSensorTypes) > 0 {
-		sb.WriteString("**Top Sensor Types:**\n\n")
-		sb.WriteString("| Rank | Sensor Type | Count | % of Total |\n")
-		sb.WriteString("|------|-------------|-------|------------|\n")
-
-		displayCount := len(stats.TopSensorTypes)
-		if displayCount > 15 {
-			displayCount = 15
-		}
+	// 3. Changed fields
+	if len(diff.Changes) > 0 {
+		sb.WriteString("| Field | Before | Now |\n")
+		sb.WriteString("|-------|--------|-----|\n")
 
-		for i := 0; i < displayCount; i++ {
-			st := stats.TopSensorTypes[i]
-			percentage := 0.0
-			if stats.TotalSensors > 0 {
-				percentage = float64(st.Count) / float64(stats.TotalSensors) * 100
-			}
-			sb.WriteString(fmt.Sprintf("| #%d | %s | %d | %.1f%% |\n",
-				i+1,
-				truncateString(st.Type, 40),
-				st.Count,
-				percentage,
+		for _, change := range diff.Changes {
+			sb.WriteString(fmt.Sprintf("| %s | %s | %s |\n",
+				change.Field,
+				truncateString(change.Old, 50),
+				truncateString(change.New, 50),
 			))
 		}
 		sb.WriteString("\n")
 	}
 
-	// 5. Full JSON data
-	sb.WriteString("---\n\n")
-	sb.WriteString("💾 **Complete statistics data below** (downloadable)\n\n")
-	sb.WriteString("```json\n")
-	jsonData, _ := json.MarshalIndent(stats, "", "  ")
-	sb.WriteString(string(jsonData))
-	sb.WriteString("\n```\n")
+	if diff.UptimeDeltaSecs != nil && *diff.UptimeDeltaSecs > 0 {
+		sb.WriteString(fmt.Sprintf("- ⏱️ **Uptime:** +%s\n", formatDuration(diff.UptimeDeltaSecs)))
+	}
+
+	if diff.DowntimeDeltaSecs != nil && *diff.DowntimeDeltaSecs > 0 {
+		sb.WriteString(fmt.Sprintf("- ⏱️ **Downtime:** +%s\n", formatDuration(diff.DowntimeDeltaSecs)))
+	}
+
+	if diff.Since != nil {
+		sb.WriteString("\nℹ️ Compared against a timestamp: only up/down transitions are detected. " +
+			"Pass the returned `current` object as `previous` to also detect message changes.\n")
+	}
+
+	if includeJSON {
+		// 4. Full JSON data
+		sb.WriteString("\n---\n\n")
+		sb.WriteString("💾 **Complete diff below** (pass `current` as `previous` next time)\n\n")
+		sb.WriteString("```json\n")
+		jsonData, _ := json.MarshalIndent(diff, "", "  ")
+		sb.WriteString(string(jsonData))
+		sb.WriteString("\n```\n")
+	}
 
 	return sb.String()
 }
 
+// shortenPath shortens a PRTG full path to maxLen characters by dropping its beginning,
+// keeping the most specific part (group, device) readable.
+func shortenPath(path string, maxLen int) string {
+	if len(path) <= maxLen {
+		return path
+	}
+
+	return "..." + path[len(path)-maxLen+3:]
+}
+
 // truncateString truncates a string to maxLen characters, adding "..." if truncated.
 func truncateString(s string, maxLen int) string {
 	if len(s) <= maxLen {
@@ -0,0 +1,1062 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/matthieu/mcp-server-prtg/internal/types"
+)
+
+// Test alertSeverityScore across status and priority combinations
+func TestAlertSeverityScore(t *testing.T) {
+	tests := []struct {
+		name     string
+		status   int
+		priority int
+		expected int
+	}{
+		{"Down priority 5", types.StatusDown, 5, 100},
+		{"Down priority 1", types.StatusDown, 1, 92},
+		{"Down partial priority 3", types.StatusDownPartial, 3, 84},
+		{"No probe priority 3", types.StatusNoProbe, 3, 72},
+		{"Warning priority 5", types.StatusWarning, 5, 64},
+		{"Warning priority 1", types.StatusWarning, 1, 56},
+		{"Unusual priority 3", types.StatusUnusual, 3, 48},
+		{"Down acknowledged priority 5", types.StatusDownAcknowledged, 5, 40},
+		{"Unknown priority 3", types.StatusUnknown, 3, 24},
+		{"Priority below range is clamped", types.StatusDown, 0, 92},
+		{"Priority above range is clamped", types.StatusDown, 9, 100},
+		{"Up scores zero", types.StatusUp, 5, 0},
+		{"Paused scores zero", types.StatusPausedByUser, 5, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			score := alertSeverityScore(types.Sensor{Status: tt.status, Priority: tt.priority})
+			assert.Equal(t, tt.expected, score)
+			assert.GreaterOrEqual(t, score, 0)
+			assert.LessOrEqual(t, score, 100)
+		})
+	}
+
+	t.Run("Down outranks Warning at same priority", func(t *testing.T) {
+		for priority := 1; priority <= 5; priority++ {
+			down := alertSeverityScore(types.Sensor{Status: types.StatusDown, Priority: priority})
+			warning := alertSeverityScore(types.Sensor{Status: types.StatusWarning, Priority: priority})
+			assert.Greater(t, down, warning, "priority %d", priority)
+		}
+	})
+
+	t.Run("Down priority 1 outranks Warning priority 5", func(t *testing.T) {
+		down := alertSeverityScore(types.Sensor{Status: types.StatusDown, Priority: 1})
+		warning := alertSeverityScore(types.Sensor{Status: types.StatusWarning, Priority: 5})
+		assert.Greater(t, down, warning)
+	})
+
+	t.Run("Down partial priority 1 outranks Warning priority 5", func(t *testing.T) {
+		downPartial := alertSeverityScore(types.Sensor{Status: types.StatusDownPartial, Priority: 1})
+		warning := alertSeverityScore(types.Sensor{Status: types.StatusWarning, Priority: 5})
+		assert.Greater(t, downPartial, warning)
+	})
+
+	t.Run("Each status outranks the next one whatever the priorities", func(t *testing.T) {
+		ranking := []int{
+			types.StatusDown,
+			types.StatusDownPartial,
+			types.StatusNoProbe,
+			types.StatusWarning,
+			types.StatusUnusual,
+			types.StatusDownAcknowledged,
+			types.StatusUnknown,
+		}
+		for i := 0; i < len(ranking)-1; i++ {
+			lowest := alertSeverityScore(types.Sensor{Status: ranking[i], Priority: 1})
+			highest := alertSeverityScore(types.Sensor{Status: ranking[i+1], Priority: 5})
+			assert.Greater(t, lowest, highest, "status %d over status %d", ranking[i], ranking[i+1])
+		}
+	})
+
+	t.Run("Higher priority outranks lower priority at same status", func(t *testing.T) {
+		high := alertSeverityScore(types.Sensor{Status: types.StatusWarning, Priority: 4})
+		low := alertSeverityScore(types.Sensor{Status: types.StatusWarning, Priority: 2})
+		assert.Greater(t, high, low)
+	})
+}
+
+// Test scoreAlerts, sortBySeverity and JSON output
+func TestScoreAlerts(t *testing.T) {
+	sensors := []types.Sensor{
+		{ID: 1, Name: "Warning P5", Status: types.StatusWarning, Priority: 5},
+		{ID: 2, Name: "Down P3", Status: types.StatusDown, Priority: 3},
+		{ID: 3, Name: "Down P5", Status: types.StatusDown, Priority: 5},
+		{ID: 4, Name: "Warning P5 bis", Status: types.StatusWarning, Priority: 5},
+	}
+
+	alerts := scoreAlerts(sensors)
+	require.Len(t, alerts, 4)
+
+	assert.Equal(t, []int{1, 2, 3, 4}, []int{alerts[0].ID, alerts[1].ID, alerts[2].ID, alerts[3].ID}, "scoring keeps the order")
+	assert.Equal(t, []int{64, 96, 100, 64}, []int{alerts[0].SeverityScore, alerts[1].SeverityScore, alerts[2].SeverityScore, alerts[3].SeverityScore})
+
+	sortBySeverity(sensors)
+	alerts = scoreAlerts(sensors)
+
+	assert.Equal(t, []int{3, 2, 1, 4}, []int{alerts[0].ID, alerts[1].ID, alerts[2].ID, alerts[3].ID}, "ties keep their order")
+	assert.Equal(t, 100, alerts[0].SeverityScore)
+
+	jsonText, err := formatAlertsJSON(alerts, newResultMeta(len(alerts), types.AlertsLimit))
+	require.NoError(t, err)
+
+	var output struct {
+		Count  int `json:"count"`
+		Alerts []struct {
+			ID            int    `json:"id"`
+			Name          string `json:"name"`
+			SeverityScore int    `json:"severity_score"`
+		} `json:"alerts"`
+	}
+	require.NoError(t, json.Unmarshal([]byte(jsonText), &output))
+
+	assert.Equal(t, 4, output.Count)
+	assert.Equal(t, "Down P5", output.Alerts[0].Name)
+	assert.Equal(t, 100, output.Alerts[0].SeverityScore)
+}
+
+func TestDiffAlerts(t *testing.T) {
+	alerts := scoreAlerts([]types.Sensor{
+		{ID: 20, Name: "Ping", Status: types.StatusDown, Priority: 5},
+		{ID: 30, Name: "HTTP", Status: types.StatusDown, Priority: 3},
+		{ID: 10, Name: "Disk", Status: types.StatusWarning, Priority: 3},
+	})
+
+	tests := []struct {
+		name          string
+		baseline      []int
+		wantNew       []int
+		wantCleared   []int
+		wantUnchanged int
+	}{
+		{
+			name:     "first poll",
+			baseline: []int{},
+			wantNew:  []int{20, 30, 10},
+		},
+		{
+			name:          "new alerts",
+			baseline:      []int{10},
+			wantNew:       []int{20, 30},
+			wantUnchanged: 1,
+		},
+		{
+			name:          "cleared alerts",
+			baseline:      []int{45, 10, 20, 30, 5},
+			wantCleared:   []int{5, 45},
+			wantUnchanged: 3,
+		},
+		{
+			name:          "unchanged",
+			baseline:      []int{30, 20, 10, 20},
+			wantUnchanged: 3,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			diff := diffAlerts(alerts, tt.baseline)
+
+			newIDs := []int{}
+			for _, alert := range diff.New {
+				newIDs = append(newIDs, alert.ID)
+			}
+
+			assert.Equal(t, append([]int{}, tt.wantNew...), newIDs, "new alerts, most severe first")
+			assert.Equal(t, append([]int{}, tt.wantCleared...), diff.Cleared)
+			assert.Equal(t, tt.wantUnchanged, diff.Unchanged)
+			assert.Equal(t, []int{10, 20, 30}, diff.CurrentSensorIDs)
+		})
+	}
+
+	t.Run("markdown", func(t *testing.T) {
+		text := formatAlertDiffResponse(diffAlerts(alerts, []int{10, 20, 45, 5}), 4, false)
+		assert.Contains(t, text, "**1 new**, **2 cleared**, 2 unchanged (baseline: 4 sensor(s), now: 3 alert(s))")
+		assert.Contains(t, text, "| HTTP |")
+		assert.NotContains(t, text, "| Ping |")
+		assert.Contains(t, text, "Sensor IDs no longer in alert: 5, 45")
+
+		text = formatAlertDiffResponse(diffAlerts(alerts, []int{10, 20, 30}), 3, false)
+		assert.Contains(t, text, "No change since the last poll")
+	})
+}
+
+// parseResultMeta decodes the metadata line at the top of a formatted listing.
+func parseResultMeta(t *testing.T, text string) resultMetadata {
+	t.Helper()
+
+	firstLine, _, found := strings.Cut(text, "\n")
+	require.True(t, found)
+
+	var meta resultMetadata
+	require.NoError(t, json.Unmarshal([]byte(firstLine), &meta))
+
+	return meta
+}
+
+// knownTotal returns a pointer to total, for the Total field of expected metadata.
+func knownTotal(total int) *int {
+	return &total
+}
+
+// Test that the metadata header reflects the real slice length and truncation state
+func TestResultMeta(t *testing.T) {
+	sensors := []types.Sensor{{ID: 1, Name: "Ping"}, {ID: 2, Name: "HTTP"}, {ID: 3, Name: "CPU"}}
+
+	t.Run("sensors below limit", func(t *testing.T) {
+		meta := parseResultMeta(t, formatSensorsResponse(sensors, newResultMeta(len(sensors), 10), false, false, false, nil, true))
+		assert.Equal(t, resultMetadata{Total: knownTotal(3), Returned: 3, Truncated: false}, meta)
+	})
+
+	t.Run("exactly the limit is not truncated", func(t *testing.T) {
+		limited, meta := limitResults(sensors, 3)
+		assert.Len(t, limited, 3)
+		assert.Equal(t, resultMetadata{Total: knownTotal(3), Returned: 3, Truncated: false}, meta)
+	})
+
+	t.Run("extra row is dropped and total is unknown", func(t *testing.T) {
+		limited, input := limitResults(sensors, 2)
+		assert.Equal(t, []int{1, 2}, []int{limited[0].ID, limited[1].ID})
+
+		meta := parseResultMeta(t, formatSensorsResponse(limited, input, false, false, false, nil, true))
+		assert.Equal(t, resultMetadata{Returned: 2, Truncated: true}, meta)
+	})
+
+	t.Run("truncated with counted total", func(t *testing.T) {
+		limited, input := limitResults(sensors, 2)
+		input.Total = knownTotal(342)
+
+		meta := parseResultMeta(t, formatSensorsResponse(limited, input, false, false, false, nil, true))
+		assert.Equal(t, resultMetadata{Total: knownTotal(342), Returned: 2, Truncated: true}, meta)
+	})
+
+	t.Run("unlimited is never truncated", func(t *testing.T) {
+		assert.False(t, newResultMeta(5, 0).Truncated)
+		assert.Equal(t, fetchLimit(0), 0)
+		assert.Equal(t, fetchLimit(50), 51)
+	})
+
+	t.Run("groups over limit", func(t *testing.T) {
+		groups := []types.Group{{ID: 1, Name: "Servers"}, {ID: 2, Name: "Network"}, {ID: 3, Name: "Storage"}}
+		groups, input := limitResults(groups, 2)
+
+		meta := parseResultMeta(t, formatGroupsResponse(groups, input, true))
+		assert.Equal(t, resultMetadata{Returned: 2, Truncated: true}, meta)
+	})
+
+	t.Run("empty tags", func(t *testing.T) {
+		meta := parseResultMeta(t, formatTagsResponse(nil, newResultMeta(0, 100), true))
+		assert.Equal(t, resultMetadata{Total: knownTotal(0), Returned: 0, Truncated: false}, meta)
+	})
+
+	t.Run("search cuts each category and flags any truncated category", func(t *testing.T) {
+		results := &types.SearchResults{
+			Groups:  []types.Group{{ID: 1, Name: "Linux"}},
+			Devices: []types.Device{{ID: 2, Name: "linux-01"}, {ID: 3, Name: "linux-02"}, {ID: 4, Name: "linux-03"}},
+		}
+
+		meta := parseResultMeta(t, formatSearchResponse(results, "linux", limitSearchResults(results, 2), true))
+		assert.Equal(t, resultMetadata{Returned: 3, Truncated: true}, meta)
+		assert.Len(t, results.Devices, 2)
+
+		meta = parseResultMeta(t, formatSearchResponse(results, "linux", limitSearchResults(results, 50), true))
+		assert.Equal(t, resultMetadata{Total: knownTotal(3), Returned: 3, Truncated: false}, meta)
+	})
+}
+
+// Test estate health percentage and RAG thresholds
+func TestComputeEstateHealth(t *testing.T) {
+	tests := []struct {
+		name        string
+		byStatus    map[string]int
+		wantPercent float64
+		wantStatus  string
+	}{
+		{"green at 98%", map[string]int{"Up": 98, "Down": 2}, 98, types.HealthGreen},
+		{"amber just below 98%", map[string]int{"Up": 979, "Warning": 21}, 97.9, types.HealthAmber},
+		{"amber at 90%", map[string]int{"Up": 90, "Down": 5, "Unusual": 5}, 90, types.HealthAmber},
+		{"red below 90%", map[string]int{"Up": 89, "Down (Partial)": 11}, 89, types.HealthRed},
+		{"paused excluded", map[string]int{"Up": 99, "Warning": 1, "Paused (Schedule)": 500}, 99, types.HealthGreen},
+		{"disconnected probe caps at amber", map[string]int{"Up": 999, "No Probe": 1}, 99.9, types.HealthAmber},
+		{"nothing monitored", map[string]int{}, 100, types.HealthGreen},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			health := computeEstateHealth(&types.Statistics{SensorsByStatus: tt.byStatus}, nil, 5)
+
+			assert.InDelta(t, tt.wantPercent, health.HealthPercent, 0.01)
+			assert.Equal(t, tt.wantStatus, health.Status)
+		})
+	}
+
+	t.Run("top problems limited and ordered by severity", func(t *testing.T) {
+		alerts := []types.Sensor{
+			{ID: 1, Status: types.StatusWarning, Priority: 5},
+			{ID: 2, Status: types.StatusDown, Priority: 1},
+			{ID: 3, Status: types.StatusDown, Priority: 5},
+		}
+
+		health := computeEstateHealth(&types.Statistics{SensorsByStatus: map[string]int{"Up": 1}}, alerts, 2)
+
+		require.Len(t, health.TopProblems, 2)
+		assert.Equal(t, 3, health.TopProblems[0].ID)
+		assert.Equal(t, 2, health.TopProblems[1].ID)
+	})
+}
+
+func TestDiffSensor(t *testing.T) {
+	seconds := func(v float64) *float64 { return &v }
+	now := time.Now().UTC()
+	wentDown := now.Add(-2 * time.Minute)
+
+	previous := types.Sensor{
+		ID:              1001,
+		Status:          types.StatusUp,
+		Message:         "OK",
+		Priority:        3,
+		LastUpUTC:       now.Add(-10 * time.Minute),
+		UptimeSinceSecs: seconds(3600),
+	}
+
+	t.Run("status change", func(t *testing.T) {
+		current := previous
+		current.Status = types.StatusDown
+		current.LastDownUTC = &wentDown
+		current.UptimeSinceSecs = nil
+		current.DowntimeSinceSecs = seconds(120)
+
+		diff := diffSensor(previous, current)
+
+		assert.True(t, diff.Changed)
+		assert.Equal(t, types.TransitionDown, diff.Transition)
+		require.NotNil(t, diff.ChangedAt)
+		assert.Equal(t, wentDown, *diff.ChangedAt)
+		assert.Equal(t, []types.FieldChange{{Field: "status", Old: "Up", New: "Down"}}, diff.Changes)
+	})
+
+	t.Run("message change", func(t *testing.T) {
+		current := previous
+		current.Message = "Response time 950 ms"
+		current.UptimeSinceSecs = seconds(3660)
+
+		diff := diffSensor(previous, current)
+
+		assert.True(t, diff.Changed)
+		assert.Empty(t, diff.Transition)
+		assert.Equal(t, []types.FieldChange{{Field: "message", Old: "OK", New: "Response time 950 ms"}}, diff.Changes)
+		require.NotNil(t, diff.UptimeDeltaSecs)
+		assert.InDelta(t, 60, *diff.UptimeDeltaSecs, 0.01)
+	})
+
+	t.Run("no change", func(t *testing.T) {
+		current := previous
+		current.UptimeSinceSecs = seconds(3900)
+
+		diff := diffSensor(previous, current)
+
+		assert.False(t, diff.Changed)
+		assert.Empty(t, diff.Transition)
+		assert.Empty(t, diff.Changes)
+	})
+
+	t.Run("uptime reset reveals a hidden outage", func(t *testing.T) {
+		current := previous
+		current.UptimeSinceSecs = seconds(300)
+
+		diff := diffSensor(previous, current)
+
+		assert.True(t, diff.Changed)
+		require.Len(t, diff.Changes, 1)
+		assert.Equal(t, "uptime_since_seconds", diff.Changes[0].Field)
+	})
+}
+
+func TestDiffSensorSince(t *testing.T) {
+	now := time.Date(2025, 10, 26, 12, 0, 0, 0, time.UTC)
+	downtime := 600.0
+
+	sensor := types.Sensor{ID: 1001, Status: types.StatusDown, DowntimeSinceSecs: &downtime, LastCheckUTC: &now}
+
+	diff := diffSensorSince(sensor, now.Add(-time.Hour), now)
+	assert.True(t, diff.Changed)
+	assert.Equal(t, types.TransitionDown, diff.Transition)
+	require.NotNil(t, diff.ChangedAt)
+	assert.Equal(t, now.Add(-10*time.Minute), *diff.ChangedAt)
+
+	diff = diffSensorSince(sensor, now.Add(-5*time.Minute), now)
+	assert.False(t, diff.Changed, "down since before the last look")
+	assert.Nil(t, diff.ChangedAt)
+}
+
+func TestFormatHierarchyResponse_Truncation(t *testing.T) {
+	node := &types.HierarchyNode{
+		Group: types.Group{ID: 2, Name: "Servers"},
+		Devices: []types.HierarchyDevice{
+			{
+				Device:       types.Device{Name: "srv-01", SensorCount: 120},
+				Sensors:      []types.Sensor{{Name: "Ping", Status: types.StatusUp, StatusText: "Up"}},
+				TotalSensors: 120,
+			},
+		},
+		TotalDevices: 214,
+	}
+
+	text := formatHierarchyResponse(node, hierarchyOutputBoth)
+
+	assert.Contains(t, text, "📁 Servers ⚠️ showing 1 of 214 devices")
+	assert.Contains(t, text, "srv-01 (120 sensors) ⚠️ showing 1 of 120 sensors")
+	assert.Contains(t, text, "⚠️ **Truncated:**")
+
+	t.Run("complete tree has no annotation", func(t *testing.T) {
+		text := formatHierarchyResponse(&types.HierarchyNode{Group: types.Group{Name: "Servers"}}, hierarchyOutputBoth)
+
+		assert.NotContains(t, text, "showing")
+		assert.NotContains(t, text, "Truncated")
+	})
+
+	t.Run("node limit", func(t *testing.T) {
+		node.NodeLimitReached = true
+		text := formatHierarchyResponse(node, hierarchyOutputBoth)
+
+		assert.Contains(t, text, "⚠️ **Node limit reached:** the tree is partial")
+		assert.Contains(t, text, `"node_limit_reached": true`)
+	})
+}
+
+func TestFormatHierarchyResponse_OutputFormats(t *testing.T) {
+	node := &types.HierarchyNode{
+		Group: types.Group{ID: 2, Name: "Servers"},
+		Devices: []types.HierarchyDevice{
+			{Device: types.Device{ID: 40, Name: "srv-01"}},
+		},
+	}
+
+	t.Run("both", func(t *testing.T) {
+		text := formatHierarchyResponse(node, hierarchyOutputBoth)
+
+		assert.Contains(t, text, "**Tree Structure:**")
+		assert.Contains(t, text, "📁 Servers")
+		assert.Contains(t, text, "**Summary:**")
+		assert.Contains(t, text, "```json")
+	})
+
+	t.Run("tree", func(t *testing.T) {
+		text := formatHierarchyResponse(node, hierarchyOutputTree)
+
+		assert.Contains(t, text, "**Tree Structure:**")
+		assert.Contains(t, text, "**Summary:**")
+		assert.NotContains(t, text, "```json")
+		assert.NotContains(t, text, "Complete hierarchy data")
+	})
+
+	t.Run("json", func(t *testing.T) {
+		text := formatHierarchyResponse(node, hierarchyOutputJSON)
+
+		assert.NotContains(t, text, "Tree Structure")
+		assert.NotContains(t, text, "└──")
+		assert.NotContains(t, text, "```")
+
+		var decoded types.HierarchyNode
+		require.NoError(t, json.Unmarshal([]byte(text), &decoded))
+		assert.Equal(t, "Servers", decoded.Group.Name)
+		require.Len(t, decoded.Devices, 1)
+		assert.Equal(t, "srv-01", decoded.Devices[0].Device.Name)
+	})
+}
+
+func TestFormatTopSensorsResponse_RecentChanges(t *testing.T) {
+	now := time.Now().UTC()
+	downAt := now.Add(-192 * time.Minute)
+
+	sensors := []types.Sensor{
+		{ID: 1, Name: "HTTP", DeviceName: "web-srv-01", Status: types.StatusDown, StatusText: "Down", LastDownUTC: &downAt},
+		{ID: 2, Name: "Disk", DeviceName: "db-srv-01", Status: types.StatusWarning, StatusText: "Warning"},
+	}
+
+	text := formatTopSensorsResponse(sensors, "recent_changes", newResultMeta(len(sensors), 10), false, false)
+
+	assert.Contains(t, text, "Top problem sensors by most recent change")
+	assert.Contains(t, text, "| ⬇️ 3h12m ago |")
+	assert.Contains(t, text, "| #2 | Disk | db-srv-01 | 🟡 Warning | - |")
+
+	// A last down in the future (clock skew) is not rendered as elapsed time
+	future := now.Add(time.Hour)
+	assert.Equal(t, "-", formatDownAgo(&future, now))
+}
+
+func TestMessageExcerpt(t *testing.T) {
+	tests := []struct {
+		name    string
+		message string
+		term    string
+		want    string
+	}{
+		{"short message", "Connection Timeout", "timeout", "Connection **Timeout**"},
+		{"long message", "Request failed: the remote host did not answer before the timeout expired, retrying later",
+			"timeout", "…t answer before the **timeout** expired, retrying l…"},
+		{"pipes are escaped", "a|b timeout", "timeout", "a\\|b **timeout**"},
+		{"no match", "OK", "timeout", "OK"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, messageExcerpt(tt.message, tt.term, 20))
+		})
+	}
+}
+
+func TestFormatSearchResponse_MessageMatches(t *testing.T) {
+	results := &types.SearchResults{
+		Groups:  []types.Group{},
+		Devices: []types.Device{},
+		Sensors: []types.Sensor{
+			{ID: 100, Name: "Timeout Monitor", SensorType: "ping", Status: types.StatusUp, StatusText: "Up", Message: "OK"},
+			{ID: 101, Name: "HTTP", SensorType: "http", Status: types.StatusDown, StatusText: "Down", Message: "Connection timeout after 30s"},
+		},
+	}
+
+	text := formatSearchResponse(results, "timeout", limitSearchResults(results, 50), true)
+
+	assert.Contains(t, text, "| Matched on |")
+	assert.Contains(t, text, "| 🟢 Up | name |", "name matches are labeled as such")
+	assert.Contains(t, text, "| message: Connection **timeout** after 30s |", "message matches show the highlighted message")
+}
+
+func TestFormatSearchResponse_PartialResults(t *testing.T) {
+	results := &types.SearchResults{
+		Groups:  []types.Group{{ID: 5, Name: "Web Servers", FullPath: "/Root/Web Servers"}},
+		Devices: []types.Device{},
+		Sensors: []types.Sensor{},
+		Errors:  []types.SearchError{{Category: "devices", Error: "device search failed: statement timeout"}},
+	}
+
+	text := formatSearchResponse(results, "web", limitSearchResults(results, 50), true)
+
+	assert.Contains(t, text, "⚠️ **Partial results**: the devices search failed (device search failed: statement timeout)")
+	assert.Contains(t, text, "| 5 | Web Servers |")
+	assert.Contains(t, text, `"errors": [`)
+}
+
+func TestFormatMessageSearchResponse(t *testing.T) {
+	sensors := []types.Sensor{
+		{ID: 101, Name: "SMTP", DeviceName: "mail-01", Status: types.StatusDown, StatusText: "Down", Message: "Connection refused (10061)"},
+	}
+
+	text := formatMessageSearchResponse(sensors, "refused", newResultMeta(len(sensors), 50), true)
+
+	assert.Contains(t, text, "Sensor messages containing \"refused\"")
+	assert.Contains(t, text, "| 101 | SMTP | mail-01 |")
+	assert.Contains(t, text, "Connection **refused** (10061)")
+	assert.Contains(t, text, "```json")
+}
+
+// breakdownLines returns the "- <emoji> **<label>:** ..." lines of a breakdown section.
+func breakdownLines(text, heading string) []string {
+	section := text[strings.Index(text, heading)+len(heading):]
+	section = section[:strings.Index(section, "\n\n")]
+
+	return strings.Split(strings.TrimSpace(section), "\n")
+}
+
+func TestGetStatusEmoji_AllStatuses(t *testing.T) {
+	for status := types.StatusUnknown; status <= types.StatusDownPartial; status++ {
+		assert.NotEqual(t, "⚪", getStatusEmoji(status), "status %d", status)
+	}
+
+	assert.Equal(t, "⚪", getStatusEmoji(0))
+	assert.Equal(t, "⚪", getStatusEmoji(99))
+}
+
+func TestFormatSensorsResponse_StatusBreakdownAllStatuses(t *testing.T) {
+	var sensors []types.Sensor
+	for status := types.StatusUnknown; status <= types.StatusDownPartial; status++ {
+		sensors = append(sensors, types.Sensor{ID: status, Name: "s", Status: status, StatusText: types.GetStatusText(status)})
+	}
+
+	// Undocumented codes (including a NULL status scanned as 0) fall into "Other"
+	sensors = append(sensors, types.Sensor{ID: 98, Status: 0}, types.Sensor{ID: 99, Status: 42})
+
+	text := formatSensorsResponse(sensors, resultMetadata{Returned: len(sensors)}, false, false, false, nil, true)
+	lines := breakdownLines(text, "**Breakdown by status:**\n")
+
+	require.Len(t, lines, 15)
+
+	seen := make(map[string]bool)
+	for _, line := range lines {
+		assert.False(t, seen[line], "duplicate line %q", line)
+		seen[line] = true
+	}
+
+	for status := types.StatusUnknown; status <= types.StatusDownPartial; status++ {
+		assert.Contains(t, text, "**"+types.GetStatusText(status)+":** 1 sensor(s)")
+	}
+
+	assert.Equal(t, "- 🔴 **Down:** 1 sensor(s)", lines[0])
+	assert.Equal(t, "- ⚪ **Other:** 2 sensor(s)", lines[14])
+}
+
+func TestFormatAlertsResponse_StatusBreakdown(t *testing.T) {
+	alerts := []types.ScoredAlert{
+		{Sensor: types.Sensor{ID: 1, Status: types.StatusDown}},
+		{Sensor: types.Sensor{ID: 2, Status: types.StatusDownAcknowledged}},
+		{Sensor: types.Sensor{ID: 3, Status: types.StatusNoProbe}},
+		{Sensor: types.Sensor{ID: 4, Status: types.StatusPausedByDependency}},
+	}
+
+	text := formatAlertsResponse(alerts, resultMetadata{Returned: len(alerts)}, false, false, true)
+
+	assert.Equal(t, []string{
+		"- 🔴 **Down:** 1 sensor(s)",
+		"- 🔕 **Down (Acknowledged):** 1 sensor(s)",
+		"- 🔌 **No Probe:** 1 sensor(s)",
+		"- ⏸️ **Paused (Dependency):** 1 sensor(s)",
+	}, breakdownLines(text, "**Breakdown by status:**\n"))
+	assert.NotContains(t, text, "**Other:**")
+}
+
+func TestFormatAlertsResponse_GroupByDevice(t *testing.T) {
+	alerts := []types.ScoredAlert{
+		{Sensor: types.Sensor{ID: 1, Name: "HTTP", DeviceID: 10, DeviceName: "web01", Status: types.StatusDown}, SeverityScore: 90},
+		{Sensor: types.Sensor{ID: 2, Name: "Ping", DeviceID: 20, DeviceName: "db01", Status: types.StatusDown}, SeverityScore: 85},
+		{Sensor: types.Sensor{ID: 3, Name: "HTTPS", DeviceID: 10, DeviceName: "web01", Status: types.StatusDown}, SeverityScore: 80},
+		{Sensor: types.Sensor{ID: 4, Name: "Disk", DeviceID: 10, DeviceName: "web01", Status: types.StatusWarning}, SeverityScore: 50},
+		{Sensor: types.Sensor{ID: 5, Name: "CPU", DeviceID: 20, DeviceName: "db01", Status: types.StatusWarning}, SeverityScore: 40},
+	}
+
+	text := formatAlertsResponse(alerts, resultMetadata{Returned: len(alerts)}, true, false, true)
+
+	web := strings.Index(text, "### 🖥️ web01: 🔴 2 Down, 🟡 1 Warning\n")
+	db := strings.Index(text, "### 🖥️ db01: 🔴 1 Down, 🟡 1 Warning\n")
+	require.NotEqual(t, -1, web)
+	require.NotEqual(t, -1, db)
+	assert.Less(t, web, db, "devices keep the severity order of their first alert")
+
+	// Each section lists only its device's sensors, without a Device column
+	webSection := text[web:db]
+	assert.Equal(t, 3, strings.Count(webSection, "| 🔴 ")+strings.Count(webSection, "| 🟡 "))
+	assert.Contains(t, webSection, "| HTTPS |")
+	assert.NotContains(t, webSection, "| Ping |")
+	assert.NotContains(t, text, "| Device |")
+
+	// The flat table stays the default
+	flat := formatAlertsResponse(alerts, resultMetadata{Returned: len(alerts)}, false, false, true)
+	assert.NotContains(t, flat, "### 🖥️")
+	assert.Contains(t, flat, "| Device |")
+}
+
+func TestFormatDeviceOverviewResponse_OtherStatuses(t *testing.T) {
+	overview := &types.DeviceOverview{
+		Device:       types.Device{ID: 1, Name: "core-sw"},
+		TotalSensors: 5,
+		UpSensors:    1,
+		DownSensors:  1,
+		Sensors: []types.Sensor{
+			{ID: 1, Status: types.StatusUp},
+			{ID: 2, Status: types.StatusDown},
+			{ID: 3, Status: types.StatusPausedBySchedule},
+			{ID: 4, Status: types.StatusPausedBySchedule},
+		},
+	}
+
+	text := formatDeviceOverviewResponse(overview, nil, time.UTC, true)
+
+	assert.Contains(t, text, "- ⏸️ **Paused (Schedule):** 2 sensor(s)\n")
+	// One sensor is counted but missing from the list, so it stays unclassified
+	assert.Contains(t, text, "- ⚪ **Other:** 1 sensor(s)\n")
+}
+
+// outageSensor builds a sensor of a device for outage explanation tests.
+func outageSensor(id, deviceID int, deviceName, sensorType string, status int) types.Sensor {
+	return types.Sensor{
+		ID:         id,
+		Name:       fmt.Sprintf("%s %d", sensorType, id),
+		SensorType: sensorType,
+		DeviceID:   deviceID,
+		DeviceName: deviceName,
+		Status:     status,
+		StatusText: types.GetStatusText(status),
+		Message:    "msg",
+	}
+}
+
+func TestExplainOutage_WholeDeviceDown(t *testing.T) {
+	sensors := []types.Sensor{
+		outageSensor(1, 10, "web01", "http", types.StatusDown),
+		outageSensor(2, 10, "web01", "wmidiskspace", types.StatusDown),
+		outageSensor(3, 10, "web01", "wmicpu", types.StatusDownAcknowledged),
+		outageSensor(4, 10, "web01", "wmimemory", types.StatusPausedByDependency),
+		outageSensor(5, 20, "db01", "ping", types.StatusUp),
+		outageSensor(6, 20, "db01", "postgresql", types.StatusUp),
+	}
+
+	explanation := explainOutage("group Web", sensors)
+
+	require.Len(t, explanation.Causes, 1)
+	cause := explanation.Causes[0]
+	assert.Equal(t, types.CauseDeviceOutage, cause.Cause)
+	assert.Equal(t, []string{"web01"}, cause.Devices)
+	assert.Len(t, cause.Sensors, 3)
+	assert.Contains(t, cause.Explanation, "All 3 monitored sensors are down")
+
+	assert.Equal(t, 2, explanation.MonitoredDevices)
+	assert.Equal(t, 5, explanation.MonitoredSensors, "paused sensors are not monitored")
+	assert.Equal(t, 3, explanation.DownSensors)
+	assert.Equal(t, 1, explanation.PausedByDependency)
+	assert.Contains(t, explanation.Summary, "1 device outage(s)")
+
+	// A down ping sensor means the host is unreachable, even if other sensors still report
+	pingDown := []types.Sensor{
+		outageSensor(1, 10, "web01", "ping", types.StatusDown),
+		outageSensor(2, 10, "web01", "http", types.StatusUp),
+		outageSensor(3, 20, "db01", "ping", types.StatusUp),
+	}
+
+	explanation = explainOutage("group Web", pingDown)
+	require.Len(t, explanation.Causes, 1)
+	assert.Equal(t, types.CauseDeviceOutage, explanation.Causes[0].Cause)
+	assert.Contains(t, explanation.Causes[0].Explanation, "ping sensor is down")
+}
+
+func TestExplainOutage_IsolatedSensor(t *testing.T) {
+	sensors := []types.Sensor{
+		outageSensor(1, 10, "web01", "http", types.StatusDown),
+		outageSensor(2, 10, "web01", "ping", types.StatusUp),
+		outageSensor(3, 10, "web01", "wmicpu", types.StatusUp),
+		outageSensor(4, 20, "db01", "wmidiskspace", types.StatusWarning),
+		outageSensor(5, 20, "db01", "ping", types.StatusUp),
+	}
+
+	explanation := explainOutage("group Web", sensors)
+
+	require.Len(t, explanation.Causes, 1)
+	cause := explanation.Causes[0]
+	assert.Equal(t, types.CauseIsolated, cause.Cause)
+	assert.Equal(t, []string{"web01", "db01"}, cause.Devices)
+	require.Len(t, cause.Sensors, 2)
+	assert.Equal(t, 1, cause.Sensors[0].ID, "most severe first")
+	assert.Contains(t, explanation.Summary, "2 isolated problem(s)")
+
+	// A device that has nothing else monitored is not enough evidence of a device outage
+	explanation = explainOutage("device app01", []types.Sensor{outageSensor(1, 30, "app01", "http", types.StatusDown)})
+	require.Len(t, explanation.Causes, 1)
+	assert.Equal(t, types.CauseIsolated, explanation.Causes[0].Cause)
+}
+
+func TestExplainOutage_SiteAndServices(t *testing.T) {
+	// Every device down: one site-wide cause instead of one outage per device
+	site := []types.Sensor{
+		outageSensor(1, 10, "sw01", "ping", types.StatusDown),
+		outageSensor(2, 20, "ap01", "ping", types.StatusDown),
+		outageSensor(3, 20, "ap01", "snmptraffic", types.StatusDown),
+	}
+
+	explanation := explainOutage("group Branch", site)
+	require.Len(t, explanation.Causes, 1)
+	assert.Equal(t, types.CauseSiteOutage, explanation.Causes[0].Cause)
+	assert.Equal(t, []string{"sw01", "ap01"}, explanation.Causes[0].Devices)
+
+	// Several services down on a device that still answers ping
+	services := []types.Sensor{
+		outageSensor(1, 10, "web01", "ping", types.StatusUp),
+		outageSensor(2, 10, "web01", "http", types.StatusDown),
+		outageSensor(3, 10, "web01", "https", types.StatusDown),
+	}
+
+	explanation = explainOutage("device web01", services)
+	require.Len(t, explanation.Causes, 1)
+	assert.Equal(t, types.CauseMultipleServices, explanation.Causes[0].Cause)
+	assert.Contains(t, explanation.Causes[0].Explanation, "2 of 3 monitored sensors are down")
+
+	// Nothing wrong
+	explanation = explainOutage("device web01", []types.Sensor{outageSensor(1, 10, "web01", "ping", types.StatusUp)})
+	assert.Empty(t, explanation.Causes)
+	assert.Contains(t, explanation.Summary, "No down or warning sensors")
+}
+
+func TestFormatSensorsResponse_Verbose(t *testing.T) {
+	sensors := []types.Sensor{{
+		ID:         101,
+		Name:       "Ping",
+		Status:     types.StatusDown,
+		StatusText: "Down",
+		DeviceName: "web01",
+		DeviceHost: "10.20.0.11",
+		SensorType: "ping",
+		FullPath:   "Root > Local Probe > Production > Europe > Paris > Web Servers > web01",
+	}}
+
+	// Default table keeps its columns
+	text := formatSensorsResponse(sensors, newResultMeta(1, 10), false, false, false, nil, true)
+	assert.Contains(t, text, "| ID | Name | Status | Device | Type | In State |\n")
+	assert.NotContains(t, text, "| 10.20.0.11 |")
+
+	text = formatSensorsResponse(sensors, newResultMeta(1, 10), true, false, false, nil, true)
+	assert.Contains(t, text, "| ID | Name | Status | Device | Host | Type | In State | Path |\n")
+	assert.Contains(t, text, "| 101 | Ping | 🔴 Down | web01 | 10.20.0.11 | ping | - | ... Europe > Paris > Web Servers > web01 |\n")
+	assert.Contains(t, text, `"device_host": "10.20.0.11"`)
+}
+
+func TestFormatSensorsResponse_Links(t *testing.T) {
+	sensors := []types.Sensor{{ID: 1001, Name: "Ping", Status: types.StatusUp, StatusText: "Up", DeviceName: "web01"}}
+
+	// No links unless requested
+	text := formatSensorsResponse(sensors, newResultMeta(1, 10), false, false, false, nil, true)
+	assert.NotContains(t, text, "Link")
+	assert.NotContains(t, text, `"url"`)
+
+	setSensorLinks(sensors, "https://prtg.example.com")
+
+	text = formatSensorsResponse(sensors, newResultMeta(1, 10), false, false, false, nil, true)
+	assert.Contains(t, text, "| ID | Name | Status | Device | Type | In State | Link |\n")
+	assert.Contains(t, text, "| 1001 | Ping | 🟢 Up | web01 |  | - | [open](https://prtg.example.com/sensor.htm?id=1001) |\n")
+	assert.Contains(t, text, `"url": "https://prtg.example.com/sensor.htm?id=1001"`)
+
+	text = formatSensorsResponse(sensors, newResultMeta(1, 10), true, false, false, nil, true)
+	assert.Contains(t, text, "| In State | Path | Link |\n")
+	assert.Contains(t, text, "[open](https://prtg.example.com/sensor.htm?id=1001) |\n")
+}
+
+func TestFormatSensorsResponse_GroupByType(t *testing.T) {
+	// Ordered by name, as returned by the database
+	sensors := []types.Sensor{
+		{ID: 1, Name: "CPU", Status: types.StatusUp, StatusText: "Up", DeviceName: "db01", SensorType: "snmpcpu"},
+		{ID: 2, Name: "Disk C", Status: types.StatusWarning, StatusText: "Warning", DeviceName: "db01", SensorType: "wmidiskspace"},
+		{ID: 3, Name: "Ping", Status: types.StatusDown, StatusText: "Down", DeviceName: "db01", SensorType: "ping"},
+		{ID: 4, Name: "Ping", Status: types.StatusUp, StatusText: "Up", DeviceName: "web01", SensorType: "ping"},
+		{ID: 5, Name: "Ping", Status: types.StatusUp, StatusText: "Up", DeviceName: "web02", SensorType: "ping"},
+		{ID: 6, Name: "Uptime", Status: types.StatusUp, StatusText: "Up", DeviceName: "web01"},
+		{ID: 7, Name: "Disk D", Status: types.StatusUp, StatusText: "Up", DeviceName: "web01", SensorType: "wmidiskspace"},
+	}
+
+	text := formatSensorsResponse(sensors, newResultMeta(len(sensors), 50), false, false, true, nil, true)
+
+	assert.Contains(t, text, "Found **7 sensor(s)**")
+	assert.Contains(t, text, "**4 sensor type(s):**")
+
+	// Most common types first, ties by name; sensors keep their order within a type
+	ping := strings.Index(text, "### ping (3)\n\n| ID | Name | Status | Device | In State |\n")
+	disk := strings.Index(text, "### wmidiskspace (2)\n")
+	none := strings.Index(text, "### (no type) (1)\n")
+	cpu := strings.Index(text, "### snmpcpu (1)\n")
+	require.True(t, ping >= 0 && disk >= 0 && none >= 0 && cpu >= 0, text)
+	assert.True(t, ping < disk && disk < none && none < cpu, "sections ordered by count, then type")
+
+	section := text[ping:disk]
+	assert.Contains(t, section, "| 3 | Ping | 🔴 Down | db01 | - |\n| 4 | Ping | 🟢 Up | web01 | - |\n| 5 | Ping | 🟢 Up | web02 | - |\n")
+	assert.Contains(t, text[disk:none], "| 2 | Disk C |")
+	assert.Contains(t, text[disk:none], "| 7 | Disk D |")
+
+	// Flat output stays the default
+	text = formatSensorsResponse(sensors, newResultMeta(len(sensors), 50), false, false, false, nil, true)
+	assert.NotContains(t, text, "###")
+	assert.Contains(t, text, "| ID | Name | Status | Device | Type | In State |\n")
+}
+
+func TestFormatSensorsResponse_Fields(t *testing.T) {
+	sensors := []types.Sensor{
+		{ID: 1001, Name: "Ping", Status: types.StatusUp, StatusText: "Up", DeviceName: "web01", Message: "OK"},
+		{ID: 1002, Name: "HTTP", Status: types.StatusDown, StatusText: "Down", DeviceName: "web01"},
+	}
+
+	text := formatSensorsResponse(sensors, newResultMeta(2, 10), false, false, false, []string{"id", "name", "message"}, true)
+
+	// The table is unchanged, the JSON block only has the requested keys
+	assert.Contains(t, text, "| 1001 | Ping | 🟢 Up | web01 |")
+
+	start := strings.Index(text, "```json\n") + len("```json\n")
+	end := strings.LastIndex(text, "\n```")
+
+	var projected []map[string]interface{}
+	require.NoError(t, json.Unmarshal([]byte(text[start:end]), &projected))
+
+	assert.Equal(t, []map[string]interface{}{
+		{"id": float64(1001), "name": "Ping", "message": "OK"},
+		{"id": float64(1002), "name": "HTTP", "message": nil},
+	}, projected)
+}
+
+func TestValidateSensorFields(t *testing.T) {
+	assert.NoError(t, validateSensorFields(nil))
+	assert.NoError(t, validateSensorFields([]string{"id", "status_text", "url"}))
+
+	err := validateSensorFields([]string{"id", "name; DROP TABLE prtg_sensor"})
+	assert.ErrorContains(t, err, `unknown field "name; DROP TABLE prtg_sensor"`)
+	assert.ErrorContains(t, err, "valid fields: id, server_id, name,")
+}
+
+func TestFormatFullMessages(t *testing.T) {
+	long := "HTTP/1.1 503 Service Unavailable: upstream connect error or disconnect/reset before headers, reset reason: connection timeout"
+	sensors := []types.Sensor{
+		{ID: 2001, Name: "HTTPS", Status: types.StatusDown, StatusText: "Down", DeviceName: "web01", Message: long},
+		{ID: 2002, Name: "Ping", Status: types.StatusUp, StatusText: "Up", DeviceName: "web01", Message: ""},
+		{ID: 2003, Name: "Disk", Status: types.StatusWarning, StatusText: "Warning", DeviceName: "db01", Message: "Free space 4%\non C:"},
+	}
+
+	// Without the flag only the truncated table cell is there
+	text := formatTopSensorsResponse(sensors, "downtime", newResultMeta(len(sensors), 10), false, true)
+	assert.NotContains(t, text, "Full Messages")
+	assert.Contains(t, text, "| HTTP/1.1 503 Service Unavai... |")
+
+	text = formatTopSensorsResponse(sensors, "downtime", newResultMeta(len(sensors), 10), true, true)
+	assert.Contains(t, text, "### 📝 Full Messages\n\n")
+	assert.Contains(t, text, "| HTTP/1.1 503 Service Unavai... |")
+	assert.Contains(t, text, "- **2001** HTTPS (web01): "+long+"\n")
+	assert.Contains(t, text, "- **2003** Disk (db01): Free space 4% on C:\n")
+	assert.NotContains(t, text, "- **2002**")
+
+	// The section comes before the JSON data
+	assert.Less(t, strings.Index(text, "Full Messages"), strings.Index(text, "```json"))
+
+	// Sensor listings have no message column, so the section is the only place to read them
+	text = formatSensorsResponse(sensors, newResultMeta(len(sensors), 10), false, true, false, nil, true)
+	assert.Contains(t, text, "- **2001** HTTPS (web01): "+long+"\n")
+
+	alerts := []types.ScoredAlert{{Sensor: sensors[0], SeverityScore: 90}}
+	text = formatAlertsResponse(alerts, newResultMeta(1, types.AlertsLimit), true, true, true)
+	assert.Contains(t, text, "- **2001** HTTPS (web01): "+long+"\n")
+
+	text = formatBusinessProcessesResponse(sensors[1:2], newResultMeta(1, 10), true, nil, time.UTC, true)
+	assert.Contains(t, text, "No status messages.\n")
+}
+
+func TestTimeInState(t *testing.T) {
+	now := time.Date(2026, 3, 10, 12, 0, 0, 0, time.UTC)
+	wentDown := now.Add(-3*time.Hour - 12*time.Minute)
+	downBefore := now.Add(-51 * time.Hour)
+
+	tests := []struct {
+		name   string
+		sensor types.Sensor
+		want   string
+	}{
+		{
+			name:   "down since the last down transition",
+			sensor: types.Sensor{Status: types.StatusDown, LastUpUTC: now.Add(-4 * time.Hour), LastDownUTC: &wentDown},
+			want:   "down for 3h12m",
+		},
+		{
+			name:   "acknowledged counts as down",
+			sensor: types.Sensor{Status: types.StatusDownAcknowledged, LastUpUTC: now.Add(-4 * time.Hour), LastDownUTC: &wentDown},
+			want:   "down for 3h12m",
+		},
+		{
+			name:   "up since it came back",
+			sensor: types.Sensor{Status: types.StatusUp, LastUpUTC: now.Add(-50 * time.Hour), LastDownUTC: &downBefore},
+			want:   "up for 2d2h",
+		},
+		{
+			name:   "up for less than a minute",
+			sensor: types.Sensor{Status: types.StatusUp, LastUpUTC: now.Add(-20 * time.Second)},
+			want:   "up for <1m",
+		},
+		{
+			name:   "never checked",
+			sensor: types.Sensor{Status: types.StatusUnknown},
+		},
+		{
+			name:   "down without a down transition",
+			sensor: types.Sensor{Status: types.StatusDown, LastUpUTC: now.Add(-time.Hour)},
+		},
+		{
+			name:   "paused sensor",
+			sensor: types.Sensor{Status: types.StatusPausedByUser, LastUpUTC: now.Add(-time.Hour)},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, timeInState(tt.sensor, now))
+		})
+	}
+}
+
+func TestFormatters_IncludeJSON(t *testing.T) {
+	sensors := []types.Sensor{{ID: 1001, Name: "Ping", Status: types.StatusDown, StatusText: "Down", DeviceName: "web01"}}
+	meta := newResultMeta(1, 10)
+
+	formatters := map[string]func(includeJSON bool) string{
+		"sensors": func(includeJSON bool) string {
+			return formatSensorsResponse(sensors, meta, false, false, false, nil, includeJSON)
+		},
+		"alerts": func(includeJSON bool) string {
+			return formatAlertsResponse([]types.ScoredAlert{{Sensor: sensors[0], SeverityScore: 80}}, meta, false, false, includeJSON)
+		},
+		"top sensors": func(includeJSON bool) string {
+			return formatTopSensorsResponse(sensors, "downtime", meta, false, includeJSON)
+		},
+		"groups": func(includeJSON bool) string {
+			return formatGroupsResponse([]types.Group{{ID: 1, Name: "Servers"}}, meta, includeJSON)
+		},
+		"statistics": func(includeJSON bool) string {
+			return formatStatisticsResponse(&types.Statistics{TotalSensors: 1}, includeJSON)
+		},
+		"sensor diff": func(includeJSON bool) string {
+			return formatSensorDiffResponse(diffSensor(sensors[0], sensors[0]), time.UTC, includeJSON)
+		},
+	}
+
+	for name, format := range formatters {
+		t.Run(name, func(t *testing.T) {
+			text := format(true)
+			assert.Contains(t, text, "```json\n")
+			assert.Contains(t, text, "💾 **Complete")
+
+			text = format(false)
+			assert.NotContains(t, text, "```json")
+			assert.NotContains(t, text, "💾")
+			assert.NotEmpty(t, strings.TrimSpace(text))
+		})
+	}
+}
+
+func TestShortenPath(t *testing.T) {
+	assert.Equal(t, "Root > Servers", shortenPath("Root > Servers", 40))
+	assert.Equal(t, "... > web01", shortenPath("Root > Servers > web01", 11))
+}
+
+func TestFormatters_DisplayTimezone(t *testing.T) {
+	paris, err := time.LoadLocation("Europe/Paris")
+	require.NoError(t, err)
+
+	changedAt := time.Date(2025, 1, 15, 10, 0, 0, 0, time.UTC)
+
+	assert.Equal(t, "2025-01-15 11:00", formatTimestamp(changedAt, paris, "2006-01-02 15:04"))
+	assert.Equal(t, "2025-07-15 12:00 CEST", formatTimestamp(changedAt.AddDate(0, 6, 0), paris, "2006-01-02 15:04 MST"))
+	assert.Equal(t, "2025-01-15 10:00", formatTimestamp(changedAt, nil, "2006-01-02 15:04"), "nil is UTC")
+
+	changes := []types.StatusChange{{
+		Sensor:     types.Sensor{ID: 1, Name: "Ping", DeviceName: "web01", Status: types.StatusDown, StatusText: "Down"},
+		Transition: types.TransitionDown,
+		ChangedAt:  changedAt,
+	}}
+
+	text := formatRecentChangesResponse(changes, 60, newResultMeta(1, 10), false, paris, true)
+	assert.Contains(t, text, "| Changed At (Europe/Paris) |")
+	assert.Contains(t, text, "| 2025-01-15 11:00:00 |")
+	assert.Contains(t, text, `"changed_at": "2025-01-15T10:00:00Z"`, "the JSON keeps UTC")
+
+	overview := &types.DeviceOverview{
+		Device:       types.Device{ID: 1, Name: "web01"},
+		TotalSensors: 1,
+		Sensors:      []types.Sensor{{ID: 1, Name: "Ping", Status: types.StatusUp, LastCheckUTC: &changedAt}},
+	}
+
+	text = formatDeviceOverviewResponse(overview, nil, paris, false)
+	assert.Contains(t, text, "Last Check (Europe/Paris)")
+	assert.Contains(t, text, "2025-01-15 11:00")
+	assert.NotContains(t, text, "2025-01-15 10:00")
+}
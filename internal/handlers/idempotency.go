@@ -0,0 +1,207 @@
+package handlers
+
+import (
+	"container/list"
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+const (
+	// idempotencyWindow is how long the result of a write tool call is replayed for its key.
+	idempotencyWindow = 10 * time.Minute
+
+	// idempotencyCacheSize bounds the number of idempotency keys kept in memory.
+	idempotencyCacheSize = 1024
+
+	// maxIdempotencyKeyLength rejects keys that are clearly not request identifiers.
+	maxIdempotencyKeyLength = 200
+)
+
+// idempotencyKeyProperty is the input schema property shared by the write tools.
+var idempotencyKeyProperty = map[string]interface{}{
+	"type":      "string",
+	"maxLength": maxIdempotencyKeyLength,
+	"description": "Optional client-generated key (e.g. a UUID). Retrying a call with the same key " +
+		"within 10 minutes returns the first result instead of changing PRTG again",
+}
+
+// idempotencyCache remembers the results of write tool calls by idempotency key for
+// idempotencyWindow, so a retried call is not applied twice. Only successful results are
+// kept: a failed call can be retried with the same key. The oldest key is evicted when the
+// cache is full. The zero value is ready to use.
+type idempotencyCache struct {
+	mu      sync.Mutex
+	entries map[string]*list.Element // Values are *idempotencyEntry
+	order   *list.List               // Oldest first
+	now     func() time.Time         // Overridden in tests
+}
+
+// idempotencyEntry is the result of one write tool call, kept for idempotencyWindow after the
+// call started. done is closed when the call ends, so a duplicate arriving while the first
+// call is running waits for its result.
+type idempotencyEntry struct {
+	key       string
+	arguments string
+	result    *mcp.CallToolResult
+	done      chan struct{}
+	stored    time.Time
+}
+
+// idempotent wraps a write tool handler with idempotency key handling. Calls without an
+// idempotency_key run as usual. A key reused with different arguments is rejected.
+func (h *WriteToolHandler) idempotent(tool string, handler server.ToolHandlerFunc) server.ToolHandlerFunc {
+	return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		args := request.GetArguments()
+
+		raw, ok := args["idempotency_key"]
+		if !ok || raw == nil {
+			return handler(ctx, request)
+		}
+
+		key, ok := raw.(string)
+		key = strings.TrimSpace(key)
+
+		if !ok || key == "" || len(key) > maxIdempotencyKeyLength {
+			return mcp.NewToolResultError(fmt.Sprintf("idempotency_key must be a non-empty string of at most %d characters",
+				maxIdempotencyKeyLength)), nil
+		}
+
+		entry, first, err := h.idempotency.claim(ctx, tool+"\x00"+key, idempotencyArguments(args))
+		if err != nil {
+			return mcp.NewToolResultError(err.Error()), nil
+		}
+
+		if !first {
+			h.handler.logger.Info().
+				Str("tool", tool).
+				Str("idempotency_key", key).
+				Msg("Duplicate write tool call, returning the previous result")
+
+			return entry.result, nil
+		}
+
+		var result *mcp.CallToolResult
+
+		// Deferred so that a panicking handler still releases the key
+		defer func() {
+			h.idempotency.complete(entry, result, err == nil && result != nil && !result.IsError)
+		}()
+
+		result, err = handler(ctx, request)
+
+		return result, err
+	}
+}
+
+// idempotencyArguments returns the call arguments without the key, to detect a key reused
+// for a different call. JSON encoding sorts map keys, so the result is stable.
+func idempotencyArguments(args map[string]interface{}) string {
+	rest := make(map[string]interface{}, len(args))
+
+	for name, value := range args {
+		if name != "idempotency_key" {
+			rest[name] = value
+		}
+	}
+
+	data, _ := json.Marshal(rest)
+
+	return string(data)
+}
+
+// claim returns the entry of key. When first is true the caller must run the call and
+// complete the entry; otherwise the entry holds the result of an earlier call with the key.
+func (c *idempotencyCache) claim(ctx context.Context, key, arguments string) (*idempotencyEntry, bool, error) {
+	for {
+		c.mu.Lock()
+
+		if c.entries == nil {
+			c.entries = make(map[string]*list.Element)
+			c.order = list.New()
+		}
+
+		c.expire()
+
+		element, ok := c.entries[key]
+		if !ok {
+			entry := &idempotencyEntry{key: key, arguments: arguments, done: make(chan struct{}), stored: c.clock()}
+			c.entries[key] = c.order.PushBack(entry)
+
+			if c.order.Len() > idempotencyCacheSize {
+				c.remove(c.order.Front())
+			}
+
+			c.mu.Unlock()
+
+			return entry, true, nil
+		}
+
+		entry := element.Value.(*idempotencyEntry)
+		c.mu.Unlock()
+
+		if entry.arguments != arguments {
+			return nil, false, fmt.Errorf("idempotency_key was already used with different arguments")
+		}
+
+		select {
+		case <-entry.done:
+		case <-ctx.Done():
+			return nil, false, ctx.Err()
+		}
+
+		// A failed call releases its key: claim it again to run the call
+		if entry.result != nil {
+			return entry, false, nil
+		}
+	}
+}
+
+// complete stores the result of a claimed entry, or releases the key when the call failed.
+func (c *idempotencyCache) complete(entry *idempotencyEntry, result *mcp.CallToolResult, success bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if success {
+		entry.result = result
+	} else if element, ok := c.entries[entry.key]; ok && element.Value == entry {
+		c.remove(element)
+	}
+
+	close(entry.done)
+}
+
+// expire drops the entries older than idempotencyWindow. The lock must be held.
+func (c *idempotencyCache) expire() {
+	now := c.clock()
+
+	for element := c.order.Front(); element != nil; element = c.order.Front() {
+		entry := element.Value.(*idempotencyEntry)
+		if now.Sub(entry.stored) < idempotencyWindow {
+			return
+		}
+
+		c.remove(element)
+	}
+}
+
+// remove drops an entry. The lock must be held.
+func (c *idempotencyCache) remove(element *list.Element) {
+	c.order.Remove(element)
+	delete(c.entries, element.Value.(*idempotencyEntry).key)
+}
+
+// clock returns the current time. The lock must be held.
+func (c *idempotencyCache) clock() time.Time {
+	if c.now != nil {
+		return c.now()
+	}
+
+	return time.Now()
+}
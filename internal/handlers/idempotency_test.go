@@ -0,0 +1,152 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// pauseRequest builds a prtg_pause_sensor call with an optional idempotency key.
+func pauseRequest(sensorID int, key string) mcp.CallToolRequest {
+	request := mcp.CallToolRequest{}
+	request.Params.Name = "prtg_pause_sensor"
+	request.Params.Arguments = map[string]interface{}{
+		"sensor_id": float64(sensorID),
+		"minutes":   float64(30),
+		"message":   "Firmware upgrade",
+	}
+
+	if key != "" {
+		request.Params.Arguments.(map[string]interface{})["idempotency_key"] = key
+	}
+
+	return request
+}
+
+func TestIdempotent_RepeatedKeyReturnsCachedResult(t *testing.T) {
+	mockClient := new(MockPRTGWriteClient)
+	handler := newTestWriteHandler(&MockConfig{allowWriteOperations: true}, mockClient)
+	pause := handler.idempotent("prtg_pause_sensor", handler.handlePauseSensor)
+
+	mockClient.On("PauseSensor", mock.Anything, 1234, 30, "Firmware upgrade").Return(nil).Once()
+
+	first, err := pause(context.Background(), pauseRequest(1234, "req-1"))
+	require.NoError(t, err)
+	require.False(t, first.IsError)
+
+	// The retry is a no-op returning the first result
+	retry, err := pause(context.Background(), pauseRequest(1234, "req-1"))
+	require.NoError(t, err)
+	assert.Same(t, first, retry)
+
+	mockClient.AssertNumberOfCalls(t, "PauseSensor", 1)
+}
+
+func TestIdempotent_DistinctKeysExecute(t *testing.T) {
+	mockClient := new(MockPRTGWriteClient)
+	handler := newTestWriteHandler(&MockConfig{allowWriteOperations: true}, mockClient)
+	pause := handler.idempotent("prtg_pause_sensor", handler.handlePauseSensor)
+	resume := handler.idempotent("prtg_resume_sensor", handler.handleResumeSensor)
+
+	mockClient.On("PauseSensor", mock.Anything, 1234, 30, "Firmware upgrade").Return(nil)
+	mockClient.On("ResumeSensor", mock.Anything, 1234).Return(nil)
+
+	for _, key := range []string{"req-1", "req-2", ""} {
+		result, err := pause(context.Background(), pauseRequest(1234, key))
+		require.NoError(t, err)
+		assert.False(t, result.IsError)
+	}
+
+	// Keys are scoped by tool
+	request := mcp.CallToolRequest{}
+	request.Params.Arguments = map[string]interface{}{"sensor_id": float64(1234), "idempotency_key": "req-1"}
+
+	result, err := resume(context.Background(), request)
+	require.NoError(t, err)
+	assert.Contains(t, resultText(t, result), "Sensor 1234 resumed")
+
+	mockClient.AssertNumberOfCalls(t, "PauseSensor", 3)
+	mockClient.AssertNumberOfCalls(t, "ResumeSensor", 1)
+}
+
+func TestIdempotent_FailedCallCanBeRetried(t *testing.T) {
+	mockClient := new(MockPRTGWriteClient)
+	handler := newTestWriteHandler(&MockConfig{allowWriteOperations: true}, mockClient)
+	pause := handler.idempotent("prtg_pause_sensor", handler.handlePauseSensor)
+
+	mockClient.On("PauseSensor", mock.Anything, 1234, 30, "Firmware upgrade").Return(errors.New("timeout")).Once()
+	mockClient.On("PauseSensor", mock.Anything, 1234, 30, "Firmware upgrade").Return(nil).Once()
+
+	result, err := pause(context.Background(), pauseRequest(1234, "req-1"))
+	require.NoError(t, err)
+	assert.True(t, result.IsError)
+
+	result, err = pause(context.Background(), pauseRequest(1234, "req-1"))
+	require.NoError(t, err)
+	assert.False(t, result.IsError)
+
+	mockClient.AssertNumberOfCalls(t, "PauseSensor", 2)
+}
+
+func TestIdempotent_KeyReusedWithDifferentArguments(t *testing.T) {
+	mockClient := new(MockPRTGWriteClient)
+	handler := newTestWriteHandler(&MockConfig{allowWriteOperations: true}, mockClient)
+	pause := handler.idempotent("prtg_pause_sensor", handler.handlePauseSensor)
+
+	mockClient.On("PauseSensor", mock.Anything, 1234, 30, "Firmware upgrade").Return(nil)
+
+	_, err := pause(context.Background(), pauseRequest(1234, "req-1"))
+	require.NoError(t, err)
+
+	result, err := pause(context.Background(), pauseRequest(5678, "req-1"))
+	require.NoError(t, err)
+	assert.True(t, result.IsError)
+	assert.Contains(t, resultText(t, result), "already used with different arguments")
+
+	mockClient.AssertNotCalled(t, "PauseSensor", mock.Anything, 5678, mock.Anything, mock.Anything)
+}
+
+func TestIdempotencyCache_Window(t *testing.T) {
+	now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	cache := idempotencyCache{now: func() time.Time { return now }}
+	ok := mcp.NewToolResultText("ok")
+
+	entry, first, err := cache.claim(context.Background(), "k1", "{}")
+	require.NoError(t, err)
+	require.True(t, first)
+	cache.complete(entry, ok, true)
+
+	now = now.Add(idempotencyWindow - time.Second)
+
+	entry, first, err = cache.claim(context.Background(), "k1", "{}")
+	require.NoError(t, err)
+	assert.False(t, first)
+	assert.Same(t, ok, entry.result)
+
+	// After the window the key runs again
+	now = now.Add(time.Second)
+
+	_, first, err = cache.claim(context.Background(), "k1", "{}")
+	require.NoError(t, err)
+	assert.True(t, first)
+}
+
+func TestIdempotencyCache_Bounded(t *testing.T) {
+	var cache idempotencyCache
+
+	for i := 0; i <= idempotencyCacheSize; i++ {
+		entry, _, err := cache.claim(context.Background(), fmt.Sprintf("key-%d", i), "{}")
+		require.NoError(t, err)
+		cache.complete(entry, mcp.NewToolResultText("ok"), true)
+	}
+
+	assert.Equal(t, idempotencyCacheSize, cache.order.Len())
+	assert.Len(t, cache.entries, idempotencyCacheSize)
+}
@@ -0,0 +1,151 @@
+package handlers
+
+import (
+	"container/list"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+const (
+	// resultLinkTTL is how long a result returned as a resource link can be read.
+	resultLinkTTL = 10 * time.Minute
+
+	// resultStoreMaxBytes bounds the memory held by stored results; the oldest results are
+	// dropped first when a new one does not fit.
+	resultStoreMaxBytes = 64 << 20
+
+	// resultURIPrefix is the URI of stored results, followed by their token.
+	resultURIPrefix = "prtg://result/"
+
+	// resultMIMEType is the MIME type of stored results.
+	resultMIMEType = "application/json"
+)
+
+// resultStore keeps complete tool results in memory for resultLinkTTL, so tools can return a
+// summary and a prtg://result/{token} resource link instead of inlining a large JSON dataset.
+// Clients read the link only when they need the full data. The zero value is ready to use.
+type resultStore struct {
+	mu      sync.Mutex
+	entries map[string]*list.Element // Values are *storedResult
+	order   *list.List               // Oldest first
+	size    int                      // Bytes held by entries
+	now     func() time.Time         // Overridden in tests
+}
+
+// storedResult is one stored tool result.
+type storedResult struct {
+	token   string
+	data    []byte
+	expires time.Time
+}
+
+// put stores data and returns the URI it can be read from until it expires.
+func (s *resultStore) put(data []byte) (string, error) {
+	if len(data) > resultStoreMaxBytes {
+		return "", fmt.Errorf("result of %d bytes is too large to be returned as a link", len(data))
+	}
+
+	token, err := newResultToken()
+	if err != nil {
+		return "", err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.entries == nil {
+		s.entries = make(map[string]*list.Element)
+		s.order = list.New()
+	}
+
+	now := s.clock()
+	s.evict(func(entry *storedResult) bool {
+		return !now.Before(entry.expires) || s.size+len(data) > resultStoreMaxBytes
+	})
+
+	s.entries[token] = s.order.PushBack(&storedResult{token: token, data: data, expires: now.Add(resultLinkTTL)})
+	s.size += len(data)
+
+	return resultURIPrefix + token, nil
+}
+
+// get returns the data stored under a result URI, unless it is unknown or expired.
+func (s *resultStore) get(uri string) ([]byte, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	element, ok := s.entries[strings.TrimPrefix(uri, resultURIPrefix)]
+	if !ok {
+		return nil, false
+	}
+
+	entry := element.Value.(*storedResult)
+	if !s.clock().Before(entry.expires) {
+		s.remove(element)
+		return nil, false
+	}
+
+	return entry.data, true
+}
+
+// evict removes the oldest results while drop returns true for them. The lock must be held.
+func (s *resultStore) evict(drop func(*storedResult) bool) {
+	for s.order.Len() > 0 {
+		oldest := s.order.Front()
+		if !drop(oldest.Value.(*storedResult)) {
+			return
+		}
+
+		s.remove(oldest)
+	}
+}
+
+// remove deletes a result. The lock must be held.
+func (s *resultStore) remove(element *list.Element) {
+	entry := s.order.Remove(element).(*storedResult)
+	delete(s.entries, entry.token)
+	s.size -= len(entry.data)
+}
+
+// clock returns the current time. The lock must be held.
+func (s *resultStore) clock() time.Time {
+	if s.now != nil {
+		return s.now()
+	}
+
+	return time.Now()
+}
+
+// newResultToken returns an unguessable token, so results cannot be read by other clients
+// that did not receive the link.
+func newResultToken() (string, error) {
+	token := make([]byte, 16)
+	if _, err := rand.Read(token); err != nil {
+		return "", fmt.Errorf("failed to generate result token: %w", err)
+	}
+
+	return hex.EncodeToString(token), nil
+}
+
+// handleReadResult handles resources/read requests for prtg://result/{token} URIs.
+func (h *ToolHandler) handleReadResult(_ context.Context, request mcp.ReadResourceRequest) ([]mcp.ResourceContents, error) {
+	data, ok := h.results.get(request.Params.URI)
+	if !ok {
+		return nil, fmt.Errorf("result %s not found or expired, call the tool again", request.Params.URI)
+	}
+
+	return []mcp.ResourceContents{
+		mcp.TextResourceContents{
+			URI:      request.Params.URI,
+			MIMEType: resultMIMEType,
+			Text:     string(data),
+		},
+	}, nil
+}
@@ -0,0 +1,125 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/matthieu/mcp-server-prtg/internal/types"
+)
+
+func TestResultStore_ExpiryAndEviction(t *testing.T) {
+	now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	store := resultStore{now: func() time.Time { return now }}
+
+	uri, err := store.put([]byte(`[{"id":1001}]`))
+	require.NoError(t, err)
+	assert.Regexp(t, `^prtg://result/[0-9a-f]{32}$`, uri)
+
+	other, err := store.put([]byte(`[]`))
+	require.NoError(t, err)
+	assert.NotEqual(t, uri, other, "each result gets its own token")
+
+	now = now.Add(resultLinkTTL - time.Second)
+
+	data, ok := store.get(uri)
+	require.True(t, ok)
+	assert.Equal(t, `[{"id":1001}]`, string(data))
+
+	_, ok = store.get(resultURIPrefix + "unknown")
+	assert.False(t, ok)
+
+	now = now.Add(time.Second)
+
+	_, ok = store.get(uri)
+	assert.False(t, ok)
+	assert.Equal(t, 1, store.order.Len(), "expired results are dropped when read")
+
+	// Expired results are also dropped when storing a new one
+	_, err = store.put([]byte(`[]`))
+	require.NoError(t, err)
+	assert.Equal(t, 1, store.order.Len())
+
+	// The oldest results are dropped when the memory budget is exceeded
+	half := make([]byte, resultStoreMaxBytes/2+1)
+
+	first, err := store.put(half)
+	require.NoError(t, err)
+
+	second, err := store.put(half)
+	require.NoError(t, err)
+
+	_, ok = store.get(first)
+	assert.False(t, ok)
+
+	_, ok = store.get(second)
+	assert.True(t, ok)
+	assert.LessOrEqual(t, store.size, resultStoreMaxBytes)
+
+	_, err = store.put(make([]byte, resultStoreMaxBytes+1))
+	assert.ErrorContains(t, err, "too large")
+}
+
+func TestHandleGetSensors_ResultLink(t *testing.T) {
+	mockDB := new(MockDB)
+	handler := NewToolHandler(mockDB, &MockConfig{}, newTestLogger())
+
+	now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	handler.results.now = func() time.Time { return now }
+
+	mcpServer := server.NewMCPServer("test", "1.0.0")
+	handler.RegisterTools(mcpServer)
+
+	mockDB.On("GetSensorsExtended", mock.Anything, types.SensorFilter{}, "name", types.SensorsLimit+1).
+		Return([]types.Sensor{
+			{ID: 1001, Name: "Ping", DeviceName: "web01", Status: types.StatusUp},
+			{ID: 1002, Name: "HTTP", DeviceName: "web01", Status: types.StatusDown},
+		}, nil)
+
+	result, err := handler.handleGetSensors(context.Background(), createTestRequest(map[string]interface{}{
+		"result_link": true,
+		"fields":      []interface{}{"id", "name"},
+	}))
+	require.NoError(t, err)
+	require.Len(t, result.Content, 2)
+
+	// The summary table is returned, the JSON block is replaced by the link
+	text := result.Content[0].(mcp.TextContent).Text
+	assert.Contains(t, text, "| 1002 |")
+	assert.NotContains(t, text, "```json")
+
+	link, ok := result.Content[1].(mcp.ResourceLink)
+	require.True(t, ok)
+	assert.Equal(t, "resource_link", link.Type)
+	assert.Equal(t, "application/json", link.MIMEType)
+	assert.True(t, strings.HasPrefix(link.URI, resultURIPrefix))
+	assert.Contains(t, text, "`"+link.URI+"` (JSON, 2 sensor(s), readable for 10 minutes)")
+
+	read := func() mcp.JSONRPCMessage {
+		message := json.RawMessage(`{"jsonrpc":"2.0","id":1,"method":"resources/read","params":{"uri":"` + link.URI + `"}}`)
+		return mcpServer.HandleMessage(context.Background(), message)
+	}
+
+	response, ok := read().(mcp.JSONRPCResponse)
+	require.True(t, ok, "the link can be read")
+
+	contents := response.Result.(mcp.ReadResourceResult).Contents
+	require.Len(t, contents, 1)
+
+	resource := contents[0].(mcp.TextResourceContents)
+	assert.Equal(t, link.URI, resource.URI)
+	assert.JSONEq(t, `[{"id":1001,"name":"Ping"},{"id":1002,"name":"HTTP"}]`, resource.Text)
+
+	now = now.Add(resultLinkTTL)
+
+	_, ok = read().(mcp.JSONRPCError)
+	assert.True(t, ok, "the link expires")
+}
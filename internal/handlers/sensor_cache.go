@@ -0,0 +1,120 @@
+package handlers
+
+import (
+	"container/list"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/matthieu/mcp-server-prtg/internal/types"
+)
+
+// sensorQueryCacheSize bounds the number of distinct prtg_get_sensors queries kept in memory.
+const sensorQueryCacheSize = 256
+
+// sensorQueryCache keeps recent GetSensorsExtended results for a short TTL, so dashboards
+// polling the same sensor query every few seconds don't re-run it. Entries are keyed by a hash
+// of the normalized query and the least recently used entry is evicted when the cache is full.
+// The zero value is ready to use.
+type sensorQueryCache struct {
+	mu      sync.Mutex
+	entries map[string]*list.Element // Values are *sensorQueryEntry
+	order   *list.List               // Most recently used first
+	now     func() time.Time         // Overridden in tests
+}
+
+// sensorQueryEntry is one cached query result.
+type sensorQueryEntry struct {
+	key     string
+	sensors []types.Sensor
+	fetched time.Time
+}
+
+// sensorQueryKey returns the cache key of a sensor query. Text filters are trimmed, as the
+// handler would match the same sensors with or without surrounding spaces.
+func sensorQueryKey(filter types.SensorFilter, orderBy string, limit int) string {
+	filter.DeviceName = strings.TrimSpace(filter.DeviceName)
+	filter.SensorName = strings.TrimSpace(filter.SensorName)
+	filter.SensorType = strings.TrimSpace(filter.SensorType)
+	filter.GroupName = strings.TrimSpace(filter.GroupName)
+	filter.Tags = strings.TrimSpace(filter.Tags)
+	filter.Statuses = slices.Sorted(slices.Values(filter.Statuses))
+
+	data, _ := json.Marshal(struct {
+		Filter  types.SensorFilter
+		OrderBy string
+		Limit   int
+	}{filter, strings.ToLower(strings.TrimSpace(orderBy)), limit})
+
+	sum := sha256.Sum256(data)
+
+	return hex.EncodeToString(sum[:])
+}
+
+// get returns a copy of the sensors cached under key if they are younger than ttl,
+// and their age.
+func (c *sensorQueryCache) get(key string, ttl time.Duration) ([]types.Sensor, time.Duration, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	element, ok := c.entries[key]
+	if !ok {
+		return nil, 0, false
+	}
+
+	entry := element.Value.(*sensorQueryEntry)
+
+	age := c.clock().Sub(entry.fetched)
+	if age >= ttl {
+		c.order.Remove(element)
+		delete(c.entries, key)
+
+		return nil, 0, false
+	}
+
+	c.order.MoveToFront(element)
+
+	// Callers modify the sensors they get (e.g. links), keep the cached slice intact
+	return slices.Clone(entry.sensors), age, true
+}
+
+// put caches the sensors under key, evicting the least recently used entry when full.
+func (c *sensorQueryCache) put(key string, sensors []types.Sensor) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.entries == nil {
+		c.entries = make(map[string]*list.Element)
+		c.order = list.New()
+	}
+
+	entry := &sensorQueryEntry{key: key, sensors: slices.Clone(sensors), fetched: c.clock()}
+
+	if element, ok := c.entries[key]; ok {
+		element.Value = entry
+		c.order.MoveToFront(element)
+
+		return
+	}
+
+	c.entries[key] = c.order.PushFront(entry)
+
+	if c.order.Len() > sensorQueryCacheSize {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.entries, oldest.Value.(*sensorQueryEntry).key)
+	}
+}
+
+// clock returns the current time. The lock must be held.
+func (c *sensorQueryCache) clock() time.Time {
+	if c.now != nil {
+		return c.now()
+	}
+
+	return time.Now()
+}
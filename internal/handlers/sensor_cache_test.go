@@ -0,0 +1,137 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/matthieu/mcp-server-prtg/internal/types"
+)
+
+func TestSensorQueryKey(t *testing.T) {
+	down := types.StatusDown
+	warning := types.StatusWarning
+
+	base := sensorQueryKey(types.SensorFilter{DeviceName: "web01", Status: &down}, "name", 100)
+
+	// Surrounding spaces and the case of order_by do not change the query
+	assert.Equal(t, base, sensorQueryKey(types.SensorFilter{DeviceName: " web01 ", Status: &down}, "Name", 100))
+
+	for name, key := range map[string]string{
+		"device":    sensorQueryKey(types.SensorFilter{DeviceName: "web02", Status: &down}, "name", 100),
+		"status":    sensorQueryKey(types.SensorFilter{DeviceName: "web01", Status: &warning}, "name", 100),
+		"no status": sensorQueryKey(types.SensorFilter{DeviceName: "web01"}, "name", 100),
+		"order by":  sensorQueryKey(types.SensorFilter{DeviceName: "web01", Status: &down}, "status", 100),
+		"limit":     sensorQueryKey(types.SensorFilter{DeviceName: "web01", Status: &down}, "name", 10),
+	} {
+		assert.NotEqual(t, base, key, name)
+	}
+}
+
+func TestSensorQueryCache_ExpiryAndEviction(t *testing.T) {
+	now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	cache := sensorQueryCache{now: func() time.Time { return now }}
+
+	cache.put("a", []types.Sensor{{ID: 1}})
+
+	now = now.Add(5 * time.Second)
+
+	sensors, age, ok := cache.get("a", 10*time.Second)
+	require.True(t, ok)
+	assert.Equal(t, 5*time.Second, age)
+	assert.Equal(t, 1, sensors[0].ID)
+
+	// Changing the returned sensors does not change the cache
+	sensors[0].URL = "https://prtg.example.com/sensor.htm?id=1"
+	sensors, _, _ = cache.get("a", 10*time.Second)
+	assert.Empty(t, sensors[0].URL)
+
+	now = now.Add(5 * time.Second)
+
+	_, _, ok = cache.get("a", 10*time.Second)
+	assert.False(t, ok, "expired")
+
+	// The least recently used entry goes first
+	for i := 0; i < sensorQueryCacheSize; i++ {
+		cache.put(fmt.Sprint(i), nil)
+	}
+
+	_, _, ok = cache.get("0", time.Minute)
+	require.True(t, ok)
+
+	cache.put("new", nil)
+
+	_, _, ok = cache.get("1", time.Minute)
+	assert.False(t, ok, "evicted")
+
+	_, _, ok = cache.get("0", time.Minute)
+	assert.True(t, ok, "recently used")
+	assert.Len(t, cache.entries, sensorQueryCacheSize)
+}
+
+func TestHandleGetSensors_QueryCache(t *testing.T) {
+	web := []types.Sensor{{ID: 1001, Name: "Ping", DeviceName: "web01"}}
+	db := []types.Sensor{{ID: 2001, Name: "Ping", DeviceName: "db01"}}
+
+	t.Run("repeated queries reuse the result", func(t *testing.T) {
+		mockDB := new(MockDB)
+		handler := NewToolHandler(mockDB, &MockConfig{sensorQueryCacheTTL: time.Minute}, newTestLogger())
+
+		mockDB.On("GetSensorsExtended", mock.Anything, types.SensorFilter{DeviceName: "web01"}, "name", types.SensorsLimit+1).
+			Return(web, nil).Once()
+		mockDB.On("GetSensorsExtended", mock.Anything, types.SensorFilter{DeviceName: "db01"}, "name", types.SensorsLimit+1).
+			Return(db, nil).Once()
+
+		for i := 0; i < 3; i++ {
+			for _, device := range []string{"web01", "db01"} {
+				result, err := handler.handleGetSensors(context.Background(), createTestRequest(map[string]interface{}{
+					"device_name": device,
+				}))
+				require.NoError(t, err)
+				assert.Contains(t, result.Content[0].(mcp.TextContent).Text, device)
+			}
+		}
+
+		// One query per distinct filter
+		mockDB.AssertExpectations(t)
+	})
+
+	t.Run("fresh bypasses the cache", func(t *testing.T) {
+		mockDB := new(MockDB)
+		handler := NewToolHandler(mockDB, &MockConfig{sensorQueryCacheTTL: time.Minute}, newTestLogger())
+
+		mockDB.On("GetSensorsExtended", mock.Anything, types.SensorFilter{DeviceName: "web01"}, "name", types.SensorsLimit+1).
+			Return(web, nil).Twice()
+
+		for _, fresh := range []bool{false, true} {
+			_, err := handler.handleGetSensors(context.Background(), createTestRequest(map[string]interface{}{
+				"device_name": "web01",
+				"fresh":       fresh,
+			}))
+			require.NoError(t, err)
+		}
+
+		mockDB.AssertExpectations(t)
+	})
+
+	t.Run("disabled by default", func(t *testing.T) {
+		mockDB := new(MockDB)
+		handler := NewToolHandler(mockDB, &MockConfig{}, newTestLogger())
+
+		mockDB.On("GetSensorsExtended", mock.Anything, types.SensorFilter{}, "name", types.SensorsLimit+1).
+			Return(web, nil).Twice()
+
+		for i := 0; i < 2; i++ {
+			_, err := handler.handleGetSensors(context.Background(), createTestRequest(map[string]interface{}{}))
+			require.NoError(t, err)
+		}
+
+		mockDB.AssertExpectations(t)
+	})
+}
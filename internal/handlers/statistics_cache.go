@@ -0,0 +1,51 @@
+package handlers
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/matthieu/mcp-server-prtg/internal/types"
+)
+
+// statisticsCache keeps the last GetStatistics result for a configurable TTL, so repeated
+// dashboard refreshes don't re-scan the sensor table. GetStatistics takes no arguments,
+// so a single entry is enough. The zero value is ready to use.
+type statisticsCache struct {
+	mu      sync.Mutex
+	stats   *types.Statistics
+	fetched time.Time
+	now     func() time.Time // Overridden in tests
+}
+
+// get returns the cached statistics if they are younger than ttl, otherwise calls fetch
+// and caches its result. fresh forces a fetch. The lock is held during fetch so that
+// concurrent callers on an expired entry wait for a single query instead of each running it.
+// Also returns the age of the returned statistics (0 when just fetched).
+func (c *statisticsCache) get(ctx context.Context, ttl time.Duration, fresh bool,
+	fetch func(context.Context) (*types.Statistics, error)) (*types.Statistics, time.Duration, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := time.Now
+	if c.now != nil {
+		now = c.now
+	}
+
+	if !fresh && c.stats != nil {
+		if age := now().Sub(c.fetched); age < ttl {
+			return c.stats, age, nil
+		}
+	}
+
+	stats, err := fetch(ctx)
+	if err != nil {
+		// Errors are not cached: the next call retries
+		return nil, 0, err
+	}
+
+	c.stats = stats
+	c.fetched = now()
+
+	return stats, 0, nil
+}
@@ -0,0 +1,58 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/matthieu/mcp-server-prtg/internal/types"
+)
+
+func TestStatisticsCache_ConcurrentCallersShareOneQuery(t *testing.T) {
+	var cache statisticsCache
+	var queries atomic.Int32
+
+	fetch := func(context.Context) (*types.Statistics, error) {
+		queries.Add(1)
+		time.Sleep(10 * time.Millisecond)
+
+		return &types.Statistics{TotalSensors: 42}, nil
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			stats, _, err := cache.get(context.Background(), time.Minute, false, fetch)
+			assert.NoError(t, err)
+			assert.Equal(t, 42, stats.TotalSensors)
+		}()
+	}
+	wg.Wait()
+
+	assert.Equal(t, int32(1), queries.Load())
+}
+
+func TestStatisticsCache_ErrorsAreNotCached(t *testing.T) {
+	var cache statisticsCache
+
+	_, _, err := cache.get(context.Background(), time.Minute, false, func(context.Context) (*types.Statistics, error) {
+		return nil, errors.New("connection refused")
+	})
+	require.Error(t, err)
+
+	stats, age, err := cache.get(context.Background(), time.Minute, false, func(context.Context) (*types.Statistics, error) {
+		return &types.Statistics{TotalSensors: 7}, nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, 7, stats.TotalSensors)
+	assert.Zero(t, age)
+}
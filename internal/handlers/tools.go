@@ -1,69 +1,119 @@
 // Package handlers implements MCP (Model Context Protocol) tool handlers for PRTG monitoring data.
-// It provides 12 MCP tools: sensors, sensor status, alerts, device overview, top sensors, hierarchy, search, groups, tags, business processes, statistics, and custom SQL.
+// It provides 20 MCP tools: sensors, sensor status, alerts, device overview, top sensors, hierarchy, search, groups, tags, business processes, statistics, custom SQL, recent status changes, estate health, group counts, sensor status diff, message search, sensor types, outage explanation, and object lookup by ID.
 package handlers
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/mark3labs/mcp-go/mcp"
 	"github.com/mark3labs/mcp-go/server"
 	"github.com/rs/zerolog"
 
+	"github.com/matthieu/mcp-server-prtg/internal/prtg"
 	"github.com/matthieu/mcp-server-prtg/internal/types"
 )
 
 // Config is an interface for accessing configuration settings.
 type Config interface {
 	AllowCustomQueries() bool
+	AllowWriteOperations() bool
+	FuzzySearchThreshold() float64
+	StatisticsCacheTTL() time.Duration
+	SensorQueryCacheTTL() time.Duration
+	HistoryTable() string
+	MaxHierarchyNodes() int
+	AlertsPageSize() int
+	DefaultSensorLimit() int
+	PRTGUIBaseURL() string
+	IncludeJSONPayload() bool
+	DisplayLocation() *time.Location
+	IDOffsets() types.IDOffsets
 }
 
 // DatabaseQuerier is an interface for database operations.
 // This interface allows mocking in tests while maintaining type safety.
 type DatabaseQuerier interface {
 	GetSensors(ctx context.Context, deviceName, sensorName string, status *int, tags string, limit int) ([]types.Sensor, error)
-	GetSensorsExtended(ctx context.Context, deviceName, sensorName, sensorType, groupName string, status *int, tags, orderBy string, limit int) ([]types.Sensor, error)
-	GetSensorByID(ctx context.Context, sensorID int) (*types.Sensor, error)
-	GetAlerts(ctx context.Context, hours int, status *int, deviceName string) ([]types.Sensor, error)
+	GetSensorsExtended(ctx context.Context, filter types.SensorFilter, orderBy string, limit int) ([]types.Sensor, error)
+	CountSensors(ctx context.Context, filter types.SensorFilter) (int, error)
+	GroupCounts(ctx context.Context, dimension string, filter types.SensorFilter, limit int) ([]types.DimensionCount, error)
+	GetSensorByID(ctx context.Context, sensorID int, serverID *int) (*types.Sensor, error)
+	GetSensorsByIDs(ctx context.Context, serverID int, sensorIDs []int) ([]types.Sensor, error)
+	GetSensorsByDeviceID(ctx context.Context, deviceID, limit int) ([]types.Sensor, error)
+	GetAlerts(ctx context.Context, filter types.AlertFilter, limit, offset int) ([]types.Sensor, error)
+	CountAlerts(ctx context.Context, filter types.AlertFilter) (int, error)
+	GetRecentChanges(ctx context.Context, minutes, limit int) ([]types.StatusChange, error)
 	GetDeviceOverview(ctx context.Context, deviceName string) (*types.DeviceOverview, error)
 	GetTopSensors(ctx context.Context, metric, sensorType string, limit, hours int) ([]types.Sensor, error)
-	GetHierarchy(ctx context.Context, groupName string, includeSensors bool, maxDepth int) (*types.HierarchyNode, error)
+	GetHierarchy(ctx context.Context, groupName string, opts types.HierarchyOptions) (*types.HierarchyNode, error)
+	GetDevicePath(ctx context.Context, deviceID int) (*types.DevicePath, error)
 	Search(ctx context.Context, searchTerm string, limit int) (*types.SearchResults, error)
+	SearchFuzzy(ctx context.Context, searchTerm string, threshold float64, limit int) (*types.SearchResults, error)
+	SearchMessages(ctx context.Context, searchTerm string, limit int) ([]types.Sensor, error)
+	GetMessageFrequency(ctx context.Context, hours, limit int) ([]types.MessageCount, error)
 	GetGroups(ctx context.Context, groupName string, parentID *int, limit int) ([]types.Group, error)
-	GetTags(ctx context.Context, tagName string, limit int) ([]types.Tag, error)
+	GetEmptyDevices(ctx context.Context, limit int) ([]types.Device, error)
+	GetEmptyGroups(ctx context.Context, limit int) ([]types.Group, error)
+	GetTagCoverage(ctx context.Context, groupBy string, limit int) (*types.TagCoverage, error)
+	GetTags(ctx context.Context, filter types.TagFilter, limit int) ([]types.Tag, error)
+	GetSensorTypes(ctx context.Context, limit int) ([]types.SensorTypeCount, error)
 	GetBusinessProcesses(ctx context.Context, processName string, status *int, limit int) ([]types.Sensor, error)
 	GetStatistics(ctx context.Context) (*types.Statistics, error)
-	ExecuteCustomQuery(ctx context.Context, query string, limit int) ([]map[string]interface{}, error)
+	StreamCustomQuery(ctx context.Context, query string, limit int, fn func(columns []string, values []interface{}) error) (int, bool, error)
+	GetSensorHistoryFromDB(ctx context.Context, sensorID int, start, end time.Time) (*prtg.TimeSeriesData, error)
+	ResolveObjectByID(ctx context.Context, objectID int) (*types.PRTGObject, error)
 }
 
 // ToolHandler handles MCP tool requests and dispatches them to the database layer.
 // Each tool request includes context, authentication, and parameter validation.
 type ToolHandler struct {
-	db     DatabaseQuerier
-	config Config
-	logger *zerolog.Logger
+	db           DatabaseQuerier
+	config       Config
+	logger       *zerolog.Logger
+	prtgClient   PRTGClient         // Optional, enables live channel values in prtg_device_overview
+	validator    *ArgumentValidator // Checks arguments against each tool's InputSchema before dispatch
+	statsCache   statisticsCache    // Last GetStatistics result, shared by prtg_get_statistics and prtg_estate_health
+	sensorsCache sensorQueryCache   // Recent prtg_get_sensors results, when sensor_query_cache_seconds is set
+	channelCache channelCache       // Recent PRTG API channel values, see getChannels
+	results      resultStore        // Complete results returned as prtg://result/{token} links
 }
 
 // NewToolHandler creates a new MCP tool handler with the given database, config, and logger.
 func NewToolHandler(db DatabaseQuerier, config Config, logger *zerolog.Logger) *ToolHandler {
 	return &ToolHandler{
-		db:     db,
-		config: config,
-		logger: logger,
+		db:        db,
+		config:    config,
+		logger:    logger,
+		validator: NewArgumentValidator(),
 	}
 }
 
-// RegisterTools registers all 12 MCP tools with the server.
+// SetPRTGClient enables PRTG API enrichments (e.g. channel values in device overviews).
+// Must be called before the MCP server starts serving requests.
+func (h *ToolHandler) SetPRTGClient(client PRTGClient) {
+	h.prtgClient = client
+}
+
+// RegisterTools registers all 25 MCP tools with the server.
 // Tools: prtg_get_sensors, prtg_get_sensor_status, prtg_get_alerts,
 // prtg_device_overview, prtg_top_sensors, prtg_get_hierarchy, prtg_search,
-// prtg_get_groups, prtg_get_tags, prtg_get_business_processes, prtg_get_statistics, prtg_query_sql.
+// prtg_get_groups, prtg_get_tags, prtg_get_business_processes, prtg_get_statistics, prtg_query_sql,
+// prtg_get_recent_status_changes, prtg_estate_health, prtg_group_counts, prtg_sensor_status_diff,
+// prtg_search_messages, prtg_list_sensor_types.
 //
 //nolint:funlen // Tool registration function must define all MCP tools with their complete schemas inline.
 func (h *ToolHandler) RegisterTools(s *server.MCPServer) {
 	// Tool 1: prtg_get_sensors
-	s.AddTool(mcp.Tool{
+	addTool(s, h.validator, mcp.Tool{
 		Name: "prtg_get_sensors",
 		Description: "Retrieve PRTG sensors with optional filters (device, sensor name, type, group, status, tags). " +
 			"Returns current sensor status and metadata. Supports ordering by various fields.",
@@ -92,27 +142,146 @@ func (h *ToolHandler) RegisterTools(s *server.MCPServer) {
 						"7=PausedByUser, 8=PausedByDependency, 9=PausedBySchedule, 10=Unusual, " +
 						"11=PausedByLicense, 12=PausedUntil, 13=DownAcknowledged, 14=DownPartial)",
 				},
+				"statuses": map[string]interface{}{
+					"type": "array",
+					"items": map[string]interface{}{
+						"type": "integer",
+					},
+					"description": "Filter by any of several statuses, e.g. [5, 4] for Down or Warning (same codes as status). " +
+						"Use instead of status",
+				},
+				"min_priority": map[string]interface{}{
+					"type":        "integer",
+					"description": "Minimum sensor priority, inclusive (1-5, e.g. 4 with max_priority 5 for the most important sensors)",
+					"minimum":     1,
+					"maximum":     5,
+				},
+				"max_priority": map[string]interface{}{
+					"type":        "integer",
+					"description": "Maximum sensor priority, inclusive (1-5)",
+					"minimum":     1,
+					"maximum":     5,
+				},
 				"tags": map[string]string{
 					"type":        "string",
 					"description": "Filter by tag name (partial match)",
 				},
+				"min_interval": map[string]interface{}{
+					"type":        "integer",
+					"description": "Minimum scanning interval in seconds, inclusive (e.g. 3600 for sensors polled hourly or less often)",
+					"minimum":     0,
+				},
+				"max_interval": map[string]interface{}{
+					"type":        "integer",
+					"description": "Maximum scanning interval in seconds, inclusive (e.g. 30 for sensors polled every 30s or more often)",
+					"minimum":     0,
+				},
+				"exclude_paused": map[string]interface{}{
+					"type":        "boolean",
+					"description": "Exclude paused sensors (statuses 7, 8, 9, 11, 12) (default: false)",
+					"default":     false,
+				},
+				"active_only": map[string]interface{}{
+					"type":        "boolean",
+					"description": "Exclude paused, Unknown (1) and Collecting (2) sensors; implies exclude_paused (default: false)",
+					"default":     false,
+				},
+				"problem_only": map[string]interface{}{
+					"type": "boolean",
+					"description": "Only sensors that are not green: Warning (4), Down (5), Unusual (10), " +
+						"Down Acknowledged (13) and Down Partial (14) (default: false)",
+					"default": false,
+				},
+				"stale_minutes": map[string]interface{}{
+					"type": "integer",
+					"description": "Only sensors PRTG has not checked for more than this many minutes, or never checked, " +
+						"whatever their status (e.g. 60 to find sensors of a dead probe still reading Up)",
+					"minimum": 1,
+				},
 				"order_by": map[string]interface{}{
-					"type":        "string",
-					"description": "Order results by field: 'name' (default), 'status', 'priority', 'device', 'type', 'last_check'",
-					"enum":        []string{"name", "status", "priority", "device", "type", "last_check"},
-					"default":     "name",
+					"type": "string",
+					"description": "Order results by field: 'name' (default), 'status', 'priority', 'device', " +
+						"'device_status' (by device, most critical status first within each device), 'type', 'last_check', " +
+						"'host' (device host or IP, lexical: 10.0.0.10 before 10.0.0.9; see ip_sort)",
+					"enum":    []string{"name", "status", "priority", "device", "device_status", "type", "last_check", "host"},
+					"default": "name",
+				},
+				"ip_sort": map[string]interface{}{
+					"type": "boolean",
+					"description": "With order_by='host', sort IPv4 hosts numerically so sensors are grouped by subnet; " +
+						"hosts that are not IPv4 addresses (DNS names, IPv6) follow, in lexical order (default: false)",
+					"default": false,
 				},
 				"limit": map[string]interface{}{
 					"type":        "integer",
-					"description": "Maximum number of results (default: 50)",
-					"default":     50,
+					"description": "Maximum number of results (default: 50, or server.default_sensor_limit when configured)",
+				},
+				"count_only": map[string]interface{}{
+					"type":        "boolean",
+					"description": "Return only the number of matching sensors, without sensor data (default: false)",
+					"default":     false,
+				},
+				"verbose": map[string]interface{}{
+					"type":        "boolean",
+					"description": "Add the device host and the full group path to the table (default: false)",
+					"default":     false,
+				},
+				"group_by_type": map[string]interface{}{
+					"type":        "boolean",
+					"description": "Show one section per sensor type, each with its sensor count and table, instead of a single table (default: false)",
+					"default":     false,
+				},
+				"full_messages": map[string]interface{}{
+					"type":        "boolean",
+					"description": "Add a section with the complete status message of each sensor, keyed by sensor ID (default: false)",
+					"default":     false,
+				},
+				"include_links": map[string]interface{}{
+					"type": "boolean",
+					"description": "Add a link to each sensor in the PRTG web interface, as a table column and a 'url' JSON field " +
+						"(requires prtg.ui_base_url in the server configuration) (default: false)",
+					"default": false,
+				},
+				"id_prefix": map[string]interface{}{
+					"type": "string",
+					"description": "Server prefix from database.id_offsets, for databases that offset the IDs of several PRTG servers: " +
+						"sensor and device IDs and links in the results are then those of that server's PRTG web interface",
+				},
+				"fresh": map[string]interface{}{
+					"type": "boolean",
+					"description": "Bypass the sensor query cache and query the database, when the server caches sensor queries " +
+						"(default: false)",
+					"default": false,
+				},
+				"include_primary_channel": map[string]interface{}{
+					"type": "boolean",
+					"description": "Add the current value of each sensor's primary channel (e.g. 'Total: 92.00 %') from the PRTG API, " +
+						"as a table column and a 'primary_value' JSON field. Applies to the first 25 sensors; use on small result sets " +
+						"(default: false)",
+					"default": false,
 				},
+				"fields": map[string]interface{}{
+					"type": "array",
+					"items": map[string]interface{}{
+						"type": "string",
+						"enum": sensorFields,
+					},
+					"description": "Only include these sensor fields in the JSON output, e.g. [\"id\", \"name\", \"status\"] " +
+						"(default: all fields)",
+				},
+				"result_link": map[string]interface{}{
+					"type": "boolean",
+					"description": "Return the complete dataset as a prtg://result/{token} resource link, readable for 10 minutes, " +
+						"instead of a JSON block. Use for large result sets when the summary table may be enough (default: false)",
+					"default": false,
+				},
+				"include_json": includeJSONProperty,
 			},
 		},
 	}, h.handleGetSensors)
 
 	// Tool 2: prtg_get_sensor_status
-	s.AddTool(mcp.Tool{
+	addTool(s, h.validator, mcp.Tool{
 		Name: "prtg_get_sensor_status",
 		Description: "Get detailed current status of a specific sensor by ID. " +
 			"Returns current values, uptime, downtime, and status information.",
@@ -123,15 +292,22 @@ func (h *ToolHandler) RegisterTools(s *server.MCPServer) {
 					"type":        "integer",
 					"description": "The sensor ID to query",
 				},
+				"server_id": map[string]interface{}{
+					"type": "integer",
+					"description": "PRTG server ID (server_id in results), for databases synchronizing several servers " +
+						"whose sensor IDs overlap. When omitted and several servers have this sensor ID, they are listed instead",
+				},
+				"id_prefix": idPrefixProperty,
 			},
 			Required: []string{"sensor_id"},
 		},
 	}, h.handleGetSensorStatus)
 
 	// Tool 3: prtg_get_alerts
-	s.AddTool(mcp.Tool{
-		Name:        "prtg_get_alerts",
-		Description: "Retrieve sensors in alert state (not Up). Returns sensors with warnings, errors, or down status.",
+	addTool(s, h.validator, mcp.Tool{
+		Name: "prtg_get_alerts",
+		Description: "Retrieve sensors in alert state (not Up). Returns sensors with warnings, errors, or down status. " +
+			"Each alert has a severity_score (0-100) combining status and priority.",
 		InputSchema: mcp.ToolInputSchema{
 			Type: "object",
 			Properties: map[string]interface{}{
@@ -144,16 +320,52 @@ func (h *ToolHandler) RegisterTools(s *server.MCPServer) {
 					"type":        "integer",
 					"description": "Filter by specific status (4=Warning, 5=Down)",
 				},
+				"min_priority": map[string]interface{}{
+					"type":        "integer",
+					"description": "Only alerts of sensors with at least this priority (1-5, e.g. 4 for priority 4 and 5 only)",
+					"minimum":     1,
+					"maximum":     5,
+				},
 				"device_name": map[string]string{
 					"type":        "string",
 					"description": "Filter by device name",
 				},
+				"offset": map[string]interface{}{
+					"type":        "integer",
+					"description": "Number of alerts to skip, from next_offset of the previous page (default: 0)",
+					"minimum":     0,
+					"default":     0,
+				},
+				"group_by_device": map[string]interface{}{
+					"type": "boolean",
+					"description": "Group alerts under each device with per-device status counts, most critical device first " +
+						"(markdown format only, default: false)",
+					"default": false,
+				},
+				"full_messages": map[string]interface{}{
+					"type":        "boolean",
+					"description": "Add a section with the complete status message of each sensor, keyed by sensor ID (default: false)",
+					"default":     false,
+				},
+				"baseline_sensor_ids": map[string]interface{}{
+					"type":  "array",
+					"items": map[string]interface{}{"type": "integer"},
+					"description": "Sensor IDs in alert at the previous poll (current_sensor_ids of the previous response, [] on the " +
+						"first poll). Returns only the alerts that are new since then, and the baseline sensors whose alert cleared",
+				},
+				"format": map[string]interface{}{
+					"type":        "string",
+					"description": "Output format: 'markdown' (default) or 'json' (machine-readable, sorted by severity_score)",
+					"enum":        []string{"markdown", "json"},
+					"default":     "markdown",
+				},
+				"include_json": includeJSONProperty,
 			},
 		},
 	}, h.handleGetAlerts)
 
 	// Tool 4: prtg_device_overview
-	s.AddTool(mcp.Tool{
+	addTool(s, h.validator, mcp.Tool{
 		Name:        "prtg_device_overview",
 		Description: "Get a complete overview of a device including all its sensors and statistics (up/down/warning counts).",
 		InputSchema: mcp.ToolInputSchema{
@@ -163,23 +375,37 @@ func (h *ToolHandler) RegisterTools(s *server.MCPServer) {
 					"type":        "string",
 					"description": "Device name to query (partial match)",
 				},
+				"include_channels": map[string]interface{}{
+					"type": "boolean",
+					"description": "Include current channel values for key sensors (down/warning first, then by priority). " +
+						"Requires the PRTG API; falls back to the database-only overview when unavailable (default: false)",
+					"default": false,
+				},
+				"channels_limit": map[string]interface{}{
+					"type":        "integer",
+					"description": "Maximum number of sensors to fetch channel values for (default: 5, max: 20)",
+					"default":     5,
+				},
+				"include_json": includeJSONProperty,
 			},
 			Required: []string{"device_name"},
 		},
 	}, h.handleDeviceOverview)
 
 	// Tool 5: prtg_top_sensors
-	s.AddTool(mcp.Tool{
-		Name:        "prtg_top_sensors",
-		Description: "Get top sensors ranked by various metrics (uptime, downtime, or alerts).",
+	addTool(s, h.validator, mcp.Tool{
+		Name: "prtg_top_sensors",
+		Description: "Get top sensors ranked by various metrics (uptime, downtime, alerts, or recent_changes). " +
+			"'recent_changes' answers 'what broke most recently': problem sensors, most recently gone down first.",
 		InputSchema: mcp.ToolInputSchema{
 			Type: "object",
 			Properties: map[string]interface{}{
 				"metric": map[string]interface{}{
-					"type":        "string",
-					"description": "Metric to rank by: 'uptime', 'downtime', or 'alerts'",
-					"enum":        []string{"uptime", "downtime", "alerts"},
-					"default":     "downtime",
+					"type": "string",
+					"description": "Metric to rank by: 'uptime', 'downtime', 'alerts', " +
+						"or 'recent_changes' (problem sensors, most recently gone down first)",
+					"enum":    []string{"uptime", "downtime", "alerts", "recent_changes"},
+					"default": "downtime",
 				},
 				"sensor_type": map[string]string{
 					"type":        "string",
@@ -195,15 +421,22 @@ func (h *ToolHandler) RegisterTools(s *server.MCPServer) {
 					"description": "Time window in hours (default: 24)",
 					"default":     24,
 				},
+				"full_messages": map[string]interface{}{
+					"type":        "boolean",
+					"description": "Add a section with the complete status message of each sensor, keyed by sensor ID (default: false)",
+					"default":     false,
+				},
+				"include_json": includeJSONProperty,
 			},
 		},
 	}, h.handleTopSensors)
 
 	// Tool 6: prtg_get_hierarchy
-	s.AddTool(mcp.Tool{
+	addTool(s, h.validator, mcp.Tool{
 		Name: "prtg_get_hierarchy",
 		Description: "Navigate the PRTG hierarchy tree structure. " +
 			"Returns groups, devices, and optionally sensors in a tree format. " +
+			"With device_name, returns the path from the probe down to the device instead, with the device's sensors. " +
 			"Useful for understanding the organization and structure of your PRTG installation.",
 		InputSchema: mcp.ToolInputSchema{
 			Type: "object",
@@ -212,6 +445,11 @@ func (h *ToolHandler) RegisterTools(s *server.MCPServer) {
 					"type":        "string",
 					"description": "Starting group name (leave empty for root groups)",
 				},
+				"device_name": map[string]string{
+					"type": "string",
+					"description": "Device name (partial match, case-insensitive): returns its path probe → groups → device " +
+						"and its sensors instead of a tree. Cannot be combined with group_name",
+				},
 				"include_sensors": map[string]interface{}{
 					"type":        "boolean",
 					"description": "Include sensors in the output (default: false)",
@@ -222,15 +460,33 @@ func (h *ToolHandler) RegisterTools(s *server.MCPServer) {
 					"description": "Maximum depth to traverse (0 = unlimited, default: 2)",
 					"default":     2,
 				},
+				"max_children": map[string]interface{}{
+					"type":        "integer",
+					"description": "Maximum devices and child groups listed per group (default: 50). Truncated groups show 'showing N of M'",
+					"default":     types.DefaultHierarchyMaxChildren,
+				},
+				"max_sensors_per_device": map[string]interface{}{
+					"type":        "integer",
+					"description": "Maximum sensors listed per device when include_sensors is true (default: 50)",
+					"default":     types.DefaultHierarchyMaxSensorsPerDevice,
+				},
+				"output_format": map[string]interface{}{
+					"type": "string",
+					"description": "Output: 'tree' (ASCII tree and summary), 'json' (JSON tree only, for automation) " +
+						"or 'both' (default)",
+					"enum":    []string{hierarchyOutputTree, hierarchyOutputJSON, hierarchyOutputBoth},
+					"default": hierarchyOutputBoth,
+				},
+				"include_json": includeJSONProperty,
 			},
 		},
 	}, h.handleGetHierarchy)
 
 	// Tool 7: prtg_search
-	s.AddTool(mcp.Tool{
+	addTool(s, h.validator, mcp.Tool{
 		Name: "prtg_search",
 		Description: "Universal search across groups, devices, and sensors. " +
-			"Searches by name, host, or sensor type. Returns all matching results organized by type.",
+			"Searches by name, host, sensor type, or sensor message. Returns all matching results organized by type.",
 		InputSchema: mcp.ToolInputSchema{
 			Type: "object",
 			Properties: map[string]interface{}{
@@ -240,16 +496,34 @@ func (h *ToolHandler) RegisterTools(s *server.MCPServer) {
 				},
 				"limit": map[string]interface{}{
 					"type":        "integer",
-					"description": "Maximum results per category (default: 50)",
+					"description": "Maximum results per category (default: 50, or total_limit when only total_limit is set)",
 					"default":     50,
 				},
+				"total_limit": map[string]interface{}{
+					"type": "integer",
+					"description": "Maximum combined results across all categories, shared fairly between groups, " +
+						"devices and sensors with sensors served first. Applied after the per-category limit (default: no total limit)",
+					"minimum": 1,
+				},
+				"fuzzy": map[string]interface{}{
+					"type":        "boolean",
+					"description": "Typo-tolerant matching using trigram similarity, best matches first (default: false). Requires pg_trgm.",
+					"default":     false,
+				},
+				"similarity_threshold": map[string]interface{}{
+					"type":        "number",
+					"description": "Minimum similarity (0-1) for fuzzy matches (default: server configuration, 0.3)",
+					"minimum":     0,
+					"maximum":     1,
+				},
+				"include_json": includeJSONProperty,
 			},
 			Required: []string{"search_term"},
 		},
 	}, h.handleSearch)
 
 	// Tool 8: prtg_get_groups
-	s.AddTool(mcp.Tool{
+	addTool(s, h.validator, mcp.Tool{
 		Name: "prtg_get_groups",
 		Description: "List PRTG groups/probes with optional filtering. " +
 			"Groups organize devices in a hierarchical structure. Returns group information including paths and probe status.",
@@ -269,15 +543,17 @@ func (h *ToolHandler) RegisterTools(s *server.MCPServer) {
 					"description": "Maximum number of results (default: 100)",
 					"default":     100,
 				},
+				"include_json": includeJSONProperty,
 			},
 		},
 	}, h.handleGetGroups)
 
 	// Tool 9: prtg_get_tags
-	s.AddTool(mcp.Tool{
+	addTool(s, h.validator, mcp.Tool{
 		Name: "prtg_get_tags",
 		Description: "List PRTG tags with usage statistics. " +
-			"Tags are labels applied to sensors for organization and filtering. Returns tag names and sensor counts.",
+			"Tags are labels applied to sensors for organization and filtering. Returns tag names and sensor counts. " +
+			"Use order_by='usage' for the most-used tags and max_sensor_count=0 to find unused tags.",
 		InputSchema: mcp.ToolInputSchema{
 			Type: "object",
 			Properties: map[string]interface{}{
@@ -285,17 +561,34 @@ func (h *ToolHandler) RegisterTools(s *server.MCPServer) {
 					"type":        "string",
 					"description": "Filter by tag name (partial match, case-insensitive)",
 				},
+				"order_by": map[string]interface{}{
+					"type":        "string",
+					"description": "Sort order: 'name' (alphabetical) or 'usage' (most-used first) (default: name)",
+					"enum":        []string{"name", "usage"},
+					"default":     "name",
+				},
+				"min_sensor_count": map[string]interface{}{
+					"type":        "integer",
+					"description": "Only tags used by at least this many sensors",
+					"minimum":     0,
+				},
+				"max_sensor_count": map[string]interface{}{
+					"type":        "integer",
+					"description": "Only tags used by at most this many sensors (0 = unused tags)",
+					"minimum":     0,
+				},
 				"limit": map[string]interface{}{
 					"type":        "integer",
 					"description": "Maximum number of results (default: 100)",
 					"default":     100,
 				},
+				"include_json": includeJSONProperty,
 			},
 		},
 	}, h.handleGetTags)
 
 	// Tool 10: prtg_get_business_processes
-	s.AddTool(mcp.Tool{
+	addTool(s, h.validator, mcp.Tool{
 		Name: "prtg_get_business_processes",
 		Description: "List PRTG Business Process sensors with optional filtering. " +
 			"Business Process sensors aggregate the status of multiple source sensors to monitor complete business workflows. " +
@@ -318,23 +611,42 @@ func (h *ToolHandler) RegisterTools(s *server.MCPServer) {
 					"description": "Maximum number of results (default: 100)",
 					"default":     100,
 				},
+				"full_messages": map[string]interface{}{
+					"type":        "boolean",
+					"description": "Add a section with the complete status message of each sensor, keyed by sensor ID (default: false)",
+					"default":     false,
+				},
+				"include_stability": map[string]interface{}{
+					"type": "boolean",
+					"description": "Fetch the last 24h of history from the PRTG API for the first 20 processes and add a stability note " +
+						"(e.g. '3 dips in last 24h'). Requires the PRTG API (default: false)",
+					"default": false,
+				},
+				"include_json": includeJSONProperty,
 			},
 		},
 	}, h.handleGetBusinessProcesses)
 
 	// Tool 11: prtg_get_statistics
-	s.AddTool(mcp.Tool{
+	addTool(s, h.validator, mcp.Tool{
 		Name: "prtg_get_statistics",
 		Description: "Get aggregated PRTG server statistics including total counts, status breakdown, and sensor type distribution. " +
 			"Provides a comprehensive overview of your PRTG installation's health and composition.",
 		InputSchema: mcp.ToolInputSchema{
-			Type:       "object",
-			Properties: map[string]interface{}{},
+			Type: "object",
+			Properties: map[string]interface{}{
+				"fresh": map[string]interface{}{
+					"type":        "boolean",
+					"description": "Bypass the statistics cache and query the database (default: false)",
+					"default":     false,
+				},
+				"include_json": includeJSONProperty,
+			},
 		},
 	}, h.handleGetStatistics)
 
 	// Tool 12: prtg_query_sql
-	s.AddTool(mcp.Tool{
+	addTool(s, h.validator, mcp.Tool{
 		Name: "prtg_query_sql",
 		Description: "Execute a custom SQL query on the PRTG database (SELECT only). " +
 			"Use for advanced queries not covered by other tools.\n\n" +
@@ -357,179 +669,2129 @@ func (h *ToolHandler) RegisterTools(s *server.MCPServer) {
 				},
 				"limit": map[string]interface{}{
 					"type":        "integer",
-					"description": "Maximum number of results (default: 100)",
-					"default":     100,
+					"description": "Maximum number of rows returned (default: 100, max: 1000), also when the query has a higher LIMIT",
+					"default":     types.CustomQueryLimit,
 				},
 			},
 			Required: []string{"query"},
 		},
 	}, h.handleCustomQuery)
-}
-
-// handleGetSensors handles the prtg_get_sensors tool.
-func (h *ToolHandler) handleGetSensors(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
-	h.logger.Info().Interface("arguments", request.Params.Arguments).Msg("handling prtg_get_sensors")
-
-	var args struct {
-		DeviceName string `json:"device_name"`
-		SensorName string `json:"sensor_name"`
-		SensorType string `json:"sensor_type"`
-		GroupName  string `json:"group_name"`
-		Status     *int   `json:"status"`
-		Tags       string `json:"tags"`
-		OrderBy    string `json:"order_by"`
-		Limit      int    `json:"limit"`
-	}
-
-	if err := parseArguments(request.Params.Arguments, &args); err != nil {
-		return nil, fmt.Errorf("invalid arguments: %w", err)
-	}
-
-	if args.Limit <= 0 {
-		args.Limit = 1000 // Default to reasonable limit, user can override
-	}
-
-	if args.OrderBy == "" {
-		args.OrderBy = "name"
-	}
-
-	h.logger.Debug().
-		Str("device_name", args.DeviceName).
-		Str("sensor_name", args.SensorName).
-		Str("sensor_type", args.SensorType).
-		Str("group_name", args.GroupName).
-		Interface("status", args.Status).
-		Str("tags", args.Tags).
-		Str("order_by", args.OrderBy).
-		Int("limit", args.Limit).
-		Msg("calling db.GetSensorsExtended")
 
-	// Add timeout to parent context (preserves cancellation chain)
-	// This allows client cancellation while providing reasonable timeout for DB operations
-	dbCtx, cancel := context.WithTimeout(ctx, 30*time.Second)
-	defer cancel()
-
-	sensors, err := h.db.GetSensorsExtended(dbCtx, args.DeviceName, args.SensorName, args.SensorType, args.GroupName, args.Status, args.Tags, args.OrderBy, args.Limit)
-	if err != nil {
-		h.logger.Error().Err(err).Msg("db.GetSensorsExtended failed")
-		return nil, fmt.Errorf("failed to get sensors: %w", err)
-	}
-
-	h.logger.Debug().Int("count", len(sensors)).Msg("db.GetSensors returned")
+	// Tool 13: prtg_get_recent_status_changes
+	addTool(s, h.validator, mcp.Tool{
+		Name: "prtg_get_recent_status_changes",
+		Description: "List sensors across all devices that went down or came back up in the last N minutes, newest first. " +
+			"Each result is labeled with its transition direction ('down' or 'up').",
+		InputSchema: mcp.ToolInputSchema{
+			Type: "object",
+			Properties: map[string]interface{}{
+				"minutes": map[string]interface{}{
+					"type":        "integer",
+					"description": "Time window in minutes (default: 15)",
+					"default":     15,
+				},
+				"limit": map[string]interface{}{
+					"type":        "integer",
+					"description": "Maximum number of results (default: 100)",
+					"default":     100,
+				},
+				"full_messages": map[string]interface{}{
+					"type":        "boolean",
+					"description": "Add a section with the complete status message of each sensor, keyed by sensor ID (default: false)",
+					"default":     false,
+				},
+				"include_json": includeJSONProperty,
+			},
+		},
+	}, h.handleGetRecentStatusChanges)
+
+	// Tool 14: prtg_estate_health
+	addTool(s, h.validator, mcp.Tool{
+		Name: "prtg_estate_health",
+		Description: "One-shot health summary of the whole PRTG estate: a health percentage (up / monitored sensors, paused excluded), " +
+			"a RAG status (green >= 98%, amber >= 90%, red below; disconnected probes cap it at amber), " +
+			"probe connectivity and the worst current problems. Use this to answer 'how is everything doing?'.",
+		InputSchema: mcp.ToolInputSchema{
+			Type: "object",
+			Properties: map[string]interface{}{
+				"top_problems": map[string]interface{}{
+					"type":        "integer",
+					"description": "Number of worst problems to list (default: 5, max: 25)",
+					"default":     5,
+				},
+				"include_json": includeJSONProperty,
+			},
+		},
+	}, h.handleEstateHealth)
 
-	// Use visual formatting for sensors
-	formattedText := formatSensorsResponse(sensors)
+	// Tool 15: prtg_group_counts
+	addTool(s, h.validator, mcp.Tool{
+		Name: "prtg_group_counts",
+		Description: "Count sensors grouped by a dimension (status, sensor_type, device or group) in a single query, largest groups first. " +
+			"Accepts the same filters as prtg_get_sensors. Use this for dashboards and breakdowns instead of fetching all sensors.",
+		InputSchema: mcp.ToolInputSchema{
+			Type: "object",
+			Properties: map[string]interface{}{
+				"dimension": map[string]interface{}{
+					"type":        "string",
+					"description": "Dimension to group by",
+					"enum":        []string{"status", "sensor_type", "device", "group"},
+				},
+				"device_name": map[string]string{
+					"type":        "string",
+					"description": "Filter by device name (partial match)",
+				},
+				"sensor_name": map[string]string{
+					"type":        "string",
+					"description": "Filter by sensor name (partial match)",
+				},
+				"sensor_type": map[string]string{
+					"type":        "string",
+					"description": "Filter by sensor type (partial match)",
+				},
+				"group_name": map[string]string{
+					"type":        "string",
+					"description": "Filter by group name (partial match)",
+				},
+				"status": map[string]interface{}{
+					"type":        "integer",
+					"description": "Filter by status code (3=Up, 4=Warning, 5=Down, ...)",
+				},
+				"min_priority": map[string]interface{}{
+					"type":        "integer",
+					"description": "Minimum sensor priority, inclusive (1-5)",
+				},
+				"max_priority": map[string]interface{}{
+					"type":        "integer",
+					"description": "Maximum sensor priority, inclusive (1-5)",
+				},
+				"limit": map[string]interface{}{
+					"type":        "integer",
+					"description": "Maximum number of groups to return (default: 100)",
+					"default":     100,
+				},
+				"include_json": includeJSONProperty,
+			},
+			Required: []string{"dimension"},
+		},
+	}, h.handleGroupCounts)
+
+	// Tool 16: prtg_sensor_status_diff
+	addTool(s, h.validator, mcp.Tool{
+		Name: "prtg_sensor_status_diff",
+		Description: "Report what changed on a sensor since a previous look: status transition, message change, uptime/downtime change. " +
+			"Pass either 'previous' (the status JSON previously returned by prtg_get_sensor_status or this tool's 'current') " +
+			"or 'since' (a timestamp; only up/down transitions can be detected this way).",
+		InputSchema: mcp.ToolInputSchema{
+			Type: "object",
+			Properties: map[string]interface{}{
+				"sensor_id": map[string]interface{}{
+					"type":        "integer",
+					"description": "The sensor ID to compare",
+				},
+				"previous": map[string]interface{}{
+					"type":        []string{"object", "string"},
+					"description": "Previously returned sensor status JSON (object, or the text returned by prtg_get_sensor_status)",
+				},
+				"since": map[string]interface{}{
+					"type":        "string",
+					"description": "RFC 3339 timestamp of the last look (e.g. '2025-10-26T10:30:00Z')",
+				},
+				"id_prefix":    idPrefixProperty,
+				"include_json": includeJSONProperty,
+			},
+			Required: []string{"sensor_id"},
+		},
+	}, h.handleSensorStatusDiff)
+
+	// Tool 17: prtg_search_messages
+	addTool(s, h.validator, mcp.Tool{
+		Name: "prtg_search_messages",
+		Description: "Full-text search in sensor status messages (e.g. 'timeout', 'connection refused', 'certificate'). " +
+			"Returns the matching sensors with the matching part of their message highlighted. " +
+			"Use prtg_search to find objects by name, host or type.",
+		InputSchema: mcp.ToolInputSchema{
+			Type: "object",
+			Properties: map[string]interface{}{
+				"search_term": map[string]interface{}{
+					"type":        "string",
+					"description": "Text to find in sensor messages (case-insensitive, partial match)",
+				},
+				"limit": map[string]interface{}{
+					"type":        "integer",
+					"description": "Maximum number of sensors to return (default: 50)",
+					"default":     50,
+				},
+				"include_json": includeJSONProperty,
+			},
+			Required: []string{"search_term"},
+		},
+	}, h.handleSearchMessages)
 
-	h.logger.Info().
-		Int("sensors_count", len(sensors)).
-		Int("response_size_bytes", len(formattedText)).
-		Msg("returning result to MCP client")
+	// Tool 18: prtg_list_sensor_types
+	addTool(s, h.validator, mcp.Tool{
+		Name: "prtg_list_sensor_types",
+		Description: "List the distinct sensor types stored in the database with their sensor counts, most common first. " +
+			"Use the exact values returned as sensor_type filters in the other tools.",
+		InputSchema: mcp.ToolInputSchema{
+			Type: "object",
+			Properties: map[string]interface{}{
+				"limit": map[string]interface{}{
+					"type":        "integer",
+					"description": "Maximum number of sensor types to return (default: 100)",
+					"default":     100,
+				},
+				"include_json": includeJSONProperty,
+			},
+		},
+	}, h.handleListSensorTypes)
+
+	// Tool 19: prtg_explain_outage
+	addTool(s, h.validator, mcp.Tool{
+		Name: "prtg_explain_outage",
+		Description: "Explain the problems of a device or group in one call: gathers its down and warning sensors with their messages " +
+			"and groups them by probable root cause (site outage, device outage, failing services on a reachable device, " +
+			"isolated sensor). Use this instead of fetching sensors one by one during an incident.",
+		InputSchema: mcp.ToolInputSchema{
+			Type: "object",
+			Properties: map[string]interface{}{
+				"device_name": map[string]string{
+					"type":        "string",
+					"description": "Device name (partial match). At least one of device_name or group_name is required",
+				},
+				"group_name": map[string]string{
+					"type":        "string",
+					"description": "Group name (partial match), to explain the problems of all its devices",
+				},
+				"include_json": includeJSONProperty,
+			},
+		},
+	}, h.handleExplainOutage)
 
-	return &mcp.CallToolResult{
-		Content: []mcp.Content{
-			mcp.TextContent{
-				Type: "text",
-				Text: formattedText,
+	// Tool 20: prtg_get_object
+	addTool(s, h.validator, mcp.Tool{
+		Name: "prtg_get_object",
+		Description: "Get a PRTG object by ID when its type is unknown (e.g. an ID copied from the PRTG web interface). " +
+			"Looks the ID up as a sensor, then a device, then a group, and returns the match with its type.",
+		InputSchema: mcp.ToolInputSchema{
+			Type: "object",
+			Properties: map[string]interface{}{
+				"object_id": map[string]interface{}{
+					"type":        "integer",
+					"description": "The PRTG object ID (sensor, device or group)",
+				},
+				"id_prefix": idPrefixProperty,
 			},
+			Required: []string{"object_id"},
 		},
-	}, nil
-}
+	}, h.handleGetObject)
 
-// handleGetSensorStatus handles the prtg_get_sensor_status tool.
-func (h *ToolHandler) handleGetSensorStatus(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
-	h.logger.Info().Interface("arguments", request.Params.Arguments).Msg("handling prtg_get_sensor_status")
+	// Tool 21: prtg_find_empty_objects
+	addTool(s, h.validator, mcp.Tool{
+		Name: "prtg_find_empty_objects",
+		Description: "Find devices without any sensor and groups without devices or child groups. " +
+			"Such objects are usually leftovers of removed monitoring and candidates for cleanup.",
+		InputSchema: mcp.ToolInputSchema{
+			Type: "object",
+			Properties: map[string]interface{}{
+				"limit": map[string]interface{}{
+					"type":        "integer",
+					"description": "Maximum number of devices and of groups to return (default: 100 each)",
+					"default":     100,
+				},
+				"include_json": includeJSONProperty,
+			},
+		},
+	}, h.handleFindEmptyObjects)
+
+	// Tool 22: prtg_diff_groups
+	addTool(s, h.validator, mcp.Tool{
+		Name: "prtg_diff_groups",
+		Description: "Compare the sensor inventories of two groups, including their subgroups. " +
+			"Returns the sensors only in group A, only in group B, and common to both, matched by sensor name and type. " +
+			"Use to verify parity during migrations (e.g. 'which sensors does the old site have that the new one doesn't').",
+		InputSchema: mcp.ToolInputSchema{
+			Type: "object",
+			Properties: map[string]interface{}{
+				"group_a": map[string]interface{}{
+					"type":        "string",
+					"description": "Name of the first group (exact match preferred, partial match when unique)",
+				},
+				"group_b": map[string]interface{}{
+					"type":        "string",
+					"description": "Name of the second group (exact match preferred, partial match when unique)",
+				},
+				"include_json": includeJSONProperty,
+			},
+			Required: []string{"group_a", "group_b"},
+		},
+	}, h.handleDiffGroups)
+
+	// Tool 23: prtg_get_device_sensors
+	addTool(s, h.validator, mcp.Tool{
+		Name: "prtg_get_device_sensors",
+		Description: "List the sensors of a device by device ID, ordered by name. " +
+			"Use when the device ID is already known (e.g. from prtg_get_hierarchy or prtg_search) " +
+			"to avoid the ambiguity of matching devices by name.",
+		InputSchema: mcp.ToolInputSchema{
+			Type: "object",
+			Properties: map[string]interface{}{
+				"device_id": map[string]interface{}{
+					"type":        "integer",
+					"description": "The PRTG device ID",
+				},
+				"limit": map[string]interface{}{
+					"type":        "integer",
+					"description": "Maximum number of sensors to return (default: 50, or server.default_sensor_limit when configured)",
+				},
+				"id_prefix":    idPrefixProperty,
+				"include_json": includeJSONProperty,
+			},
+			Required: []string{"device_id"},
+		},
+	}, h.handleGetDeviceSensors)
+
+	// Tool 24: prtg_tag_coverage
+	addTool(s, h.validator, mcp.Tool{
+		Name: "prtg_tag_coverage",
+		Description: "Tagging governance: the percentage of sensors with at least one tag, and the groups (or devices) " +
+			"with the most untagged sensors. Use to find where tags are missing before relying on tag filters.",
+		InputSchema: mcp.ToolInputSchema{
+			Type: "object",
+			Properties: map[string]interface{}{
+				"group_by": map[string]interface{}{
+					"type":        "string",
+					"enum":        []string{"group", "device"},
+					"default":     "group",
+					"description": "List untagged sensors per 'group' (the group directly holding the devices) or per 'device'",
+				},
+				"limit": map[string]interface{}{
+					"type":        "integer",
+					"description": "Maximum number of groups or devices to list (default: 20)",
+					"default":     20,
+				},
+				"include_json": includeJSONProperty,
+			},
+		},
+	}, h.handleTagCoverage)
+
+	// Tool 25: prtg_error_message_summary
+	addTool(s, h.validator, mcp.Tool{
+		Name: "prtg_error_message_summary",
+		Description: "Group the sensors in a problem status (warning, down, unusual, partially down or acknowledged) " +
+			"by status message, most common first, with the number of sensors " +
+			"and devices showing each message. During an incident this collapses hundreds of alerts into the few " +
+			"underlying errors (e.g. one 'SNMP timeout' affecting 80 sensors). Use prtg_search_messages to list the sensors of a message.",
+		InputSchema: mcp.ToolInputSchema{
+			Type: "object",
+			Properties: map[string]interface{}{
+				"hours": map[string]interface{}{
+					"type":        "integer",
+					"description": "Only count sensors checked within the last N hours (default: 24, 0 = all)",
+					"default":     24,
+					"minimum":     0,
+				},
+				"limit": map[string]interface{}{
+					"type":        "integer",
+					"description": "Maximum number of distinct messages to return (default: 20)",
+					"default":     20,
+					"maximum":     maxErrorMessages,
+				},
+				"include_json": includeJSONProperty,
+			},
+		},
+	}, h.handleErrorMessageSummary)
+
+	// Resource: complete results of tools called with result_link
+	s.AddResourceTemplate(mcp.NewResourceTemplate(resultURIPrefix+"{token}", "Tool result",
+		mcp.WithTemplateDescription("Complete dataset of a tool call made with result_link, readable for 10 minutes"),
+		mcp.WithTemplateMIMEType(resultMIMEType),
+	), h.handleReadResult)
+}
+
+// handleGetSensors handles the prtg_get_sensors tool.
+func (h *ToolHandler) handleGetSensors(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	h.logger.Info().Interface("arguments", request.Params.Arguments).Msg("handling prtg_get_sensors")
+
+	var args struct {
+		DeviceName    string   `json:"device_name"`
+		SensorName    string   `json:"sensor_name"`
+		SensorType    string   `json:"sensor_type"`
+		GroupName     string   `json:"group_name"`
+		Status        *int     `json:"status"`
+		Statuses      []int    `json:"statuses"`
+		MinPriority   *int     `json:"min_priority"`
+		MaxPriority   *int     `json:"max_priority"`
+		Tags          string   `json:"tags"`
+		MinInterval   *int     `json:"min_interval"`
+		MaxInterval   *int     `json:"max_interval"`
+		ExcludePaused bool     `json:"exclude_paused"`
+		ActiveOnly    bool     `json:"active_only"`
+		ProblemOnly   bool     `json:"problem_only"`
+		StaleMinutes  int      `json:"stale_minutes"`
+		OrderBy       string   `json:"order_by"`
+		IPSort        bool     `json:"ip_sort"`
+		Limit         int      `json:"limit"`
+		CountOnly     bool     `json:"count_only"`
+		Verbose       bool     `json:"verbose"`
+		GroupByType   bool     `json:"group_by_type"`
+		FullMessages  bool     `json:"full_messages"`
+		IncludeLinks  bool     `json:"include_links"`
+		IDPrefix      string   `json:"id_prefix"`
+		Fields        []string `json:"fields"`
+		Fresh         bool     `json:"fresh"`
+		ResultLink    bool     `json:"result_link"`
+
+		IncludePrimaryChannel bool `json:"include_primary_channel"`
+	}
+
+	if err := parseArguments(request.Params.Arguments, &args); err != nil {
+		return nil, fmt.Errorf("invalid arguments: %w", err)
+	}
+
+	uiBaseURL := h.config.PRTGUIBaseURL()
+	if args.IncludeLinks && uiBaseURL == "" {
+		return nil, fmt.Errorf("include_links requires prtg.ui_base_url in the server configuration")
+	}
+
+	offsets, err := h.idOffsets(args.IDPrefix)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := validateSensorFields(args.Fields); err != nil {
+		return nil, err
+	}
+
+	filter := types.SensorFilter{
+		DeviceName:  args.DeviceName,
+		SensorName:  args.SensorName,
+		SensorType:  args.SensorType,
+		GroupName:   args.GroupName,
+		Status:      args.Status,
+		Statuses:    args.Statuses,
+		Tags:        args.Tags,
+		MinPriority: args.MinPriority,
+		MaxPriority: args.MaxPriority,
+		MinInterval: args.MinInterval,
+		MaxInterval: args.MaxInterval,
+
+		ExcludePaused: args.ExcludePaused,
+		ActiveOnly:    args.ActiveOnly,
+		ProblemOnly:   args.ProblemOnly,
+		StaleMinutes:  args.StaleMinutes,
+	}
+
+	if err := filter.Validate(); err != nil {
+		return nil, err
+	}
+
+	if args.CountOnly {
+		return h.handleCountSensors(ctx, filter)
+	}
+
+	if args.Limit <= 0 {
+		args.Limit = h.config.DefaultSensorLimit()
+	}
+
+	if args.OrderBy == "" {
+		args.OrderBy = "name"
+	}
+
+	if args.IPSort {
+		if args.OrderBy != "host" {
+			return nil, fmt.Errorf("ip_sort requires order_by 'host'")
+		}
+
+		args.OrderBy = "host_ip"
+	}
+
+	h.logger.Debug().
+		Str("device_name", args.DeviceName).
+		Str("sensor_name", args.SensorName).
+		Str("sensor_type", args.SensorType).
+		Str("group_name", args.GroupName).
+		Interface("status", args.Status).
+		Ints("statuses", args.Statuses).
+		Interface("min_priority", args.MinPriority).
+		Interface("max_priority", args.MaxPriority).
+		Interface("min_interval", args.MinInterval).
+		Interface("max_interval", args.MaxInterval).
+		Str("tags", args.Tags).
+		Bool("exclude_paused", args.ExcludePaused).
+		Bool("active_only", args.ActiveOnly).
+		Bool("problem_only", args.ProblemOnly).
+		Int("stale_minutes", args.StaleMinutes).
+		Str("order_by", args.OrderBy).
+		Int("limit", args.Limit).
+		Msg("calling db.GetSensorsExtended")
+
+	// Add timeout to parent context (preserves cancellation chain)
+	// This allows client cancellation while providing reasonable timeout for DB operations
+	dbCtx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	sensors, age, err := h.getSensorsExtended(dbCtx, filter, args.OrderBy, fetchLimit(args.Limit), args.Fresh)
+	if err != nil {
+		h.logger.Error().Err(err).Msg("db.GetSensorsExtended failed")
+		return nil, fmt.Errorf("failed to get sensors: %w", err)
+	}
+
+	h.logger.Debug().Int("count", len(sensors)).Msg("db.GetSensors returned")
+
+	sensors, meta := limitResults(sensors, args.Limit)
+	if meta.Truncated {
+		meta.Total = h.countSensorsTotal(ctx, filter)
+	}
+
+	channelNote := ""
+	if args.IncludePrimaryChannel {
+		channelNote = h.setPrimaryChannelValues(ctx, sensors)
+	}
+
+	// Links open the sensor pages of the web interface, which know the UI IDs only
+	uiSensorListIDs(offsets, args.IDPrefix, sensors)
+
+	if args.IncludeLinks {
+		setSensorLinks(sensors, uiBaseURL)
+	}
+
+	// Use visual formatting for sensors
+	formattedText := formatSensorsResponse(sensors, meta, args.Verbose, args.FullMessages, args.GroupByType,
+		args.Fields, h.includeJSON(request) && !args.ResultLink)
+
+	var link *mcp.ResourceLink
+
+	if args.ResultLink && len(sensors) > 0 {
+		link, err = h.linkSensorsResult(sensors, args.Fields)
+		if err != nil {
+			return nil, err
+		}
+
+		formattedText += fmt.Sprintf("\n---\n\n📎 **Complete dataset**: `%s` (JSON, %d sensor(s), readable for %d minutes)\n",
+			link.URI, len(sensors), int(resultLinkTTL.Minutes()))
+	}
+
+	if age > 0 {
+		formattedText += fmt.Sprintf("\n_Cached %s ago; use fresh=true for current values._\n", age.Round(time.Second))
+	}
+
+	if channelNote != "" {
+		formattedText += fmt.Sprintf("\n_%s_\n", channelNote)
+	}
+
+	h.logger.Info().
+		Int("sensors_count", len(sensors)).
+		Int("response_size_bytes", len(formattedText)).
+		Msg("returning result to MCP client")
+
+	result := &mcp.CallToolResult{
+		Content: []mcp.Content{
+			mcp.TextContent{
+				Type: "text",
+				Text: formattedText,
+			},
+		},
+	}
+
+	if link != nil {
+		result.Content = append(result.Content, *link)
+	}
+
+	return result, nil
+}
+
+// linkSensorsResult stores the sensors as JSON, projected on fields when set, and returns
+// the resource link to read them.
+func (h *ToolHandler) linkSensorsResult(sensors []types.Sensor, fields []string) (*mcp.ResourceLink, error) {
+	var data interface{} = sensors
+	if len(fields) > 0 {
+		data = projectSensors(sensors, fields)
+	}
+
+	jsonData, err := json.Marshal(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal result: %w", err)
+	}
+
+	uri, err := h.results.put(jsonData)
+	if err != nil {
+		return nil, err
+	}
+
+	link := mcp.NewResourceLink(uri, "prtg_get_sensors result",
+		fmt.Sprintf("%d sensor(s) as JSON", len(sensors)), resultMIMEType)
+
+	return &link, nil
+}
+
+// getSensorsExtended runs GetSensorsExtended, reusing a recent identical query when the sensor
+// query cache is enabled (sensor_query_cache_seconds) and fresh is not set.
+// Also returns the age of cached sensors (0 when just fetched).
+func (h *ToolHandler) getSensorsExtended(ctx context.Context, filter types.SensorFilter, orderBy string, limit int,
+	fresh bool) ([]types.Sensor, time.Duration, error) {
+	ttl := h.config.SensorQueryCacheTTL()
+	if ttl <= 0 {
+		sensors, err := h.db.GetSensorsExtended(ctx, filter, orderBy, limit)
+		return sensors, 0, err
+	}
+
+	key := sensorQueryKey(filter, orderBy, limit)
+
+	if !fresh {
+		if sensors, age, ok := h.sensorsCache.get(key, ttl); ok {
+			return sensors, age, nil
+		}
+	}
+
+	sensors, err := h.db.GetSensorsExtended(ctx, filter, orderBy, limit)
+	if err != nil {
+		// Errors are not cached: the next call retries
+		return nil, 0, err
+	}
+
+	h.sensorsCache.put(key, sensors)
+
+	return sensors, 0, nil
+}
+
+// handleCountSensors handles prtg_get_sensors requests with count_only enabled.
+// Only the count is fetched, no sensor rows are scanned or formatted.
+func (h *ToolHandler) handleCountSensors(ctx context.Context, filter types.SensorFilter) (*mcp.CallToolResult, error) {
+	// Add timeout to parent context (preserves cancellation chain)
+	dbCtx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	count, err := h.db.CountSensors(dbCtx, filter)
+	if err != nil {
+		h.logger.Error().Err(err).Msg("db.CountSensors failed")
+		return nil, fmt.Errorf("failed to count sensors: %w", err)
+	}
+
+	h.logger.Info().Int("sensors_count", count).Msg("returning sensor count to MCP client")
+
+	return mcp.NewToolResultText(formatSensorCountResponse(count, filter)), nil
+}
+
+// countSensorsTotal returns the number of sensors matching the filter, for the metadata of a truncated listing.
+// The metadata is best effort: on failure, nil is returned and the listing itself is still served.
+func (h *ToolHandler) countSensorsTotal(ctx context.Context, filter types.SensorFilter) *int {
+	dbCtx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	count, err := h.db.CountSensors(dbCtx, filter)
+	if err != nil {
+		h.logger.Warn().Err(err).Msg("db.CountSensors failed, reporting no total")
+		return nil
+	}
+
+	return &count
+}
+
+// handleGetSensorStatus handles the prtg_get_sensor_status tool.
+func (h *ToolHandler) handleGetSensorStatus(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	h.logger.Info().Interface("arguments", request.Params.Arguments).Msg("handling prtg_get_sensor_status")
+
+	var args struct {
+		SensorID int    `json:"sensor_id"`
+		ServerID *int   `json:"server_id"`
+		IDPrefix string `json:"id_prefix"`
+	}
+
+	if err := parseArguments(request.Params.Arguments, &args); err != nil {
+		return nil, fmt.Errorf("invalid arguments: %w", err)
+	}
+
+	if args.SensorID <= 0 {
+		return nil, fmt.Errorf("sensor_id must be greater than 0")
+	}
+
+	if args.ServerID != nil && *args.ServerID <= 0 {
+		return nil, fmt.Errorf("server_id must be greater than 0")
+	}
+
+	offsets, err := h.idOffsets(args.IDPrefix)
+	if err != nil {
+		return nil, err
+	}
+
+	// Add timeout to parent context (preserves cancellation chain)
+	dbCtx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	sensor, err := h.db.GetSensorByID(dbCtx, offsets.ToDatabaseID(args.IDPrefix, args.SensorID), args.ServerID)
+	if err != nil {
+		var ambiguous *types.AmbiguousSensorError
+		if errors.As(err, &ambiguous) {
+			ambiguous.SensorID = args.SensorID
+			uiSensorListIDs(offsets, args.IDPrefix, ambiguous.Sensors)
+
+			return &mcp.CallToolResult{
+				Content: []mcp.Content{
+					mcp.TextContent{
+						Type: "text",
+						Text: formatAmbiguousSensorResponse(ambiguous),
+					},
+				},
+			}, nil
+		}
+
+		return nil, fmt.Errorf("failed to get sensor: %w", err)
+	}
+
+	uiSensorIDs(offsets, args.IDPrefix, sensor)
+
+	return formatResult(sensor, 1)
+}
+
+// handleGetDeviceSensors handles the prtg_get_device_sensors tool.
+func (h *ToolHandler) handleGetDeviceSensors(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	h.logger.Info().Interface("arguments", request.Params.Arguments).Msg("handling prtg_get_device_sensors")
+
+	var args struct {
+		DeviceID int    `json:"device_id"`
+		Limit    int    `json:"limit"`
+		IDPrefix string `json:"id_prefix"`
+	}
+
+	if err := parseArguments(request.Params.Arguments, &args); err != nil {
+		return nil, fmt.Errorf("invalid arguments: %w", err)
+	}
+
+	if args.DeviceID <= 0 {
+		return nil, fmt.Errorf("device_id must be greater than 0")
+	}
+
+	offsets, err := h.idOffsets(args.IDPrefix)
+	if err != nil {
+		return nil, err
+	}
+
+	deviceID := offsets.ToDatabaseID(args.IDPrefix, args.DeviceID)
+
+	if args.Limit <= 0 {
+		args.Limit = h.config.DefaultSensorLimit()
+	}
+
+	// Add timeout to parent context (preserves cancellation chain)
+	dbCtx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	sensors, err := h.db.GetSensorsByDeviceID(dbCtx, deviceID, fetchLimit(args.Limit))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get sensors of device %d: %w", args.DeviceID, err)
+	}
+
+	sensors, meta := limitResults(sensors, args.Limit)
+
+	uiSensorListIDs(offsets, args.IDPrefix, sensors)
+
+	h.logger.Info().
+		Int("device_id", deviceID).
+		Int("sensors_count", len(sensors)).
+		Msg("returning device sensors to MCP client")
+
+	return &mcp.CallToolResult{
+		Content: []mcp.Content{
+			mcp.TextContent{
+				Type: "text",
+				Text: formatSensorsResponse(sensors, meta, false, false, false, nil, h.includeJSON(request)),
+			},
+		},
+	}, nil
+}
+
+// handleGetObject handles the prtg_get_object tool.
+func (h *ToolHandler) handleGetObject(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	h.logger.Info().Interface("arguments", request.Params.Arguments).Msg("handling prtg_get_object")
+
+	var args struct {
+		ObjectID int    `json:"object_id"`
+		IDPrefix string `json:"id_prefix"`
+	}
+
+	if err := parseArguments(request.Params.Arguments, &args); err != nil {
+		return nil, fmt.Errorf("invalid arguments: %w", err)
+	}
+
+	if args.ObjectID <= 0 {
+		return nil, fmt.Errorf("object_id must be greater than 0")
+	}
+
+	offsets, err := h.idOffsets(args.IDPrefix)
+	if err != nil {
+		return nil, err
+	}
+
+	// Add timeout to parent context (preserves cancellation chain)
+	dbCtx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	object, err := h.db.ResolveObjectByID(dbCtx, offsets.ToDatabaseID(args.IDPrefix, args.ObjectID))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get object: %w", err)
+	}
+
+	if object == nil {
+		// Quote the ID as given, not its database translation when id_prefix is set
+		return nil, fmt.Errorf("no sensor, device or group with ID %d", args.ObjectID)
+	}
+
+	uiObjectIDs(offsets, args.IDPrefix, object)
+
+	return formatResult(object, 1)
+}
+
+// handleFindEmptyObjects handles the prtg_find_empty_objects tool.
+func (h *ToolHandler) handleFindEmptyObjects(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	h.logger.Info().Interface("arguments", request.Params.Arguments).Msg("handling prtg_find_empty_objects")
+
+	var args struct {
+		Limit int `json:"limit"`
+	}
+
+	if err := parseArguments(request.Params.Arguments, &args); err != nil {
+		return nil, fmt.Errorf("invalid arguments: %w", err)
+	}
+
+	if args.Limit <= 0 {
+		args.Limit = 100
+	}
+
+	// Add timeout to parent context (preserves cancellation chain)
+	dbCtx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	devices, err := h.db.GetEmptyDevices(dbCtx, fetchLimit(args.Limit))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get empty devices: %w", err)
+	}
+
+	groups, err := h.db.GetEmptyGroups(dbCtx, fetchLimit(args.Limit))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get empty groups: %w", err)
+	}
+
+	devices, devicesMeta := limitResults(devices, args.Limit)
+	groups, groupsMeta := limitResults(groups, args.Limit)
+
+	result := &types.EmptyObjects{Devices: devices, Groups: groups}
+	returned := len(devices) + len(groups)
+	meta := resultMetadata{
+		Returned:  returned,
+		Truncated: devicesMeta.Truncated || groupsMeta.Truncated,
+	}
+
+	if !meta.Truncated {
+		meta.Total = &returned
+	}
+
+	h.logger.Info().
+		Int("devices_count", len(devices)).
+		Int("groups_count", len(groups)).
+		Msg("returning empty objects to MCP client")
+
+	return &mcp.CallToolResult{
+		Content: []mcp.Content{
+			mcp.TextContent{
+				Type: "text",
+				Text: formatEmptyObjectsResponse(result, meta, h.includeJSON(request)),
+			},
+		},
+	}, nil
+}
+
+// handleTagCoverage handles the prtg_tag_coverage tool.
+func (h *ToolHandler) handleTagCoverage(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	h.logger.Info().Interface("arguments", request.Params.Arguments).Msg("handling prtg_tag_coverage")
+
+	var args struct {
+		GroupBy string `json:"group_by"`
+		Limit   int    `json:"limit"`
+	}
+
+	if err := parseArguments(request.Params.Arguments, &args); err != nil {
+		return nil, fmt.Errorf("invalid arguments: %w", err)
+	}
+
+	switch args.GroupBy {
+	case "":
+		args.GroupBy = "group"
+	case "group", "device":
+	default:
+		return nil, fmt.Errorf("invalid group_by: %s (must be 'group' or 'device')", args.GroupBy)
+	}
+
+	if args.Limit <= 0 {
+		args.Limit = 20
+	}
+
+	// Add timeout to parent context (preserves cancellation chain)
+	dbCtx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	coverage, err := h.db.GetTagCoverage(dbCtx, args.GroupBy, fetchLimit(args.Limit))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get tag coverage: %w", err)
+	}
+
+	var meta resultMetadata
+	coverage.Objects, meta = limitResults(coverage.Objects, args.Limit)
+
+	return &mcp.CallToolResult{
+		Content: []mcp.Content{
+			mcp.TextContent{
+				Type: "text",
+				Text: formatTagCoverageResponse(coverage, meta, h.includeJSON(request)),
+			},
+		},
+	}, nil
+}
+
+// maxErrorMessages bounds the distinct messages returned by prtg_error_message_summary.
+const maxErrorMessages = 200
+
+// handleErrorMessageSummary handles the prtg_error_message_summary tool.
+func (h *ToolHandler) handleErrorMessageSummary(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	h.logger.Info().Interface("arguments", request.Params.Arguments).Msg("handling prtg_error_message_summary")
+
+	var args struct {
+		Hours *int `json:"hours"`
+		Limit int  `json:"limit"`
+	}
+
+	if err := parseArguments(request.Params.Arguments, &args); err != nil {
+		return nil, fmt.Errorf("invalid arguments: %w", err)
+	}
+
+	hours := 24
+	if args.Hours != nil {
+		if *args.Hours < 0 {
+			return nil, fmt.Errorf("hours must not be negative")
+		}
+
+		hours = *args.Hours
+	}
+
+	if args.Limit <= 0 {
+		args.Limit = 20
+	}
+
+	// Add timeout to parent context (preserves cancellation chain)
+	dbCtx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	messages, err := h.db.GetMessageFrequency(dbCtx, hours, fetchLimit(args.Limit))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get error messages: %w", err)
+	}
+
+	messages, meta := limitResults(messages, args.Limit)
+
+	return &mcp.CallToolResult{
+		Content: []mcp.Content{
+			mcp.TextContent{
+				Type: "text",
+				Text: formatErrorMessageSummaryResponse(messages, hours, meta, h.includeJSON(request)),
+			},
+		},
+	}, nil
+}
+
+// maxDiffGroupSensors bounds the sensors of each group compared by prtg_diff_groups.
+const maxDiffGroupSensors = 5000
+
+// handleDiffGroups handles the prtg_diff_groups tool.
+func (h *ToolHandler) handleDiffGroups(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	h.logger.Info().Interface("arguments", request.Params.Arguments).Msg("handling prtg_diff_groups")
+
+	var args struct {
+		GroupA string `json:"group_a"`
+		GroupB string `json:"group_b"`
+	}
+
+	if err := parseArguments(request.Params.Arguments, &args); err != nil {
+		return nil, fmt.Errorf("invalid arguments: %w", err)
+	}
+
+	// Add timeout to parent context (preserves cancellation chain)
+	dbCtx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	result := &types.GroupDiff{}
+	sensors := make([][]types.Sensor, 2)
+
+	for i, name := range []string{args.GroupA, args.GroupB} {
+		group, err := h.resolveGroup(dbCtx, name)
+		if err != nil {
+			return nil, err
+		}
+
+		// One extra sensor tells whether the group was cut off
+		groupSensors, err := h.db.GetSensorsExtended(dbCtx, types.SensorFilter{PathPrefix: group.FullPath}, "name", maxDiffGroupSensors+1)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get sensors of group %s: %w", group.Name, err)
+		}
+
+		if len(groupSensors) > maxDiffGroupSensors {
+			groupSensors = groupSensors[:maxDiffGroupSensors]
+			result.Truncated = true
+		}
+
+		if i == 0 {
+			result.GroupA = *group
+		} else {
+			result.GroupB = *group
+		}
+
+		sensors[i] = groupSensors
+	}
+
+	result.OnlyInA, result.OnlyInB, result.Common = diffSensorInventories(sensors[0], sensors[1])
+
+	h.logger.Info().
+		Int("only_in_a", len(result.OnlyInA)).
+		Int("only_in_b", len(result.OnlyInB)).
+		Int("common", len(result.Common)).
+		Msg("returning group diff to MCP client")
+
+	return &mcp.CallToolResult{
+		Content: []mcp.Content{
+			mcp.TextContent{
+				Type: "text",
+				Text: formatGroupDiffResponse(result, h.includeJSON(request)),
+			},
+		},
+	}, nil
+}
+
+// resolveGroup finds the group with the given name: an exact (case-insensitive) match, or else
+// the only group whose name contains it. Ambiguous names fail with the candidate paths.
+func (h *ToolHandler) resolveGroup(ctx context.Context, name string) (*types.Group, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return nil, fmt.Errorf("group name must not be empty")
+	}
+
+	groups, err := h.db.GetGroups(ctx, name, nil, 20)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get groups: %w", err)
+	}
+
+	var exact []types.Group
+
+	for _, group := range groups {
+		if strings.EqualFold(group.Name, name) {
+			exact = append(exact, group)
+		}
+	}
+
+	candidates := groups
+	if len(exact) > 0 {
+		candidates = exact
+	}
+
+	switch len(candidates) {
+	case 0:
+		return nil, fmt.Errorf("group not found: %s", name)
+	case 1:
+		return &candidates[0], nil
+	}
+
+	paths := make([]string, len(candidates))
+	for i, group := range candidates {
+		paths[i] = group.FullPath
+	}
+
+	return nil, fmt.Errorf("group name %q is ambiguous, it matches: %s", name, strings.Join(paths, ", "))
+}
+
+// diffSensorInventories matches the sensors of two groups by name and type (case-insensitive)
+// and returns the sensors only in a, only in b, and the matched pairs. A name and type present
+// several times is matched as many times as it appears in both, preferring sensors on devices
+// with the same name. The results are ordered by sensor name, type and device.
+func diffSensorInventories(a, b []types.Sensor) (onlyInA, onlyInB []types.Sensor, common []types.SensorPair) {
+	a, b = sortedForDiff(a), sortedForDiff(b)
+
+	key := func(s types.Sensor) string {
+		return strings.ToLower(strings.TrimSpace(s.Name)) + "\x00" + strings.ToLower(s.SensorType)
+	}
+	deviceKey := func(s types.Sensor) string {
+		return key(s) + "\x00" + strings.ToLower(s.DeviceName)
+	}
+
+	matched := make([]int, len(a)) // Index in b of the counterpart of each sensor of a, -1 = none
+	used := make([]bool, len(b))
+
+	for i := range matched {
+		matched[i] = -1
+	}
+
+	// Same device name first, then any remaining sensor with the same name and type
+	for _, keyOf := range []func(types.Sensor) string{deviceKey, key} {
+		available := map[string][]int{}
+
+		for j, sensor := range b {
+			if !used[j] {
+				available[keyOf(sensor)] = append(available[keyOf(sensor)], j)
+			}
+		}
+
+		for i, sensor := range a {
+			if matched[i] != -1 {
+				continue
+			}
+
+			if queue := available[keyOf(sensor)]; len(queue) > 0 {
+				matched[i], used[queue[0]] = queue[0], true
+				available[keyOf(sensor)] = queue[1:]
+			}
+		}
+	}
+
+	for i, sensor := range a {
+		if matched[i] == -1 {
+			onlyInA = append(onlyInA, sensor)
+		} else {
+			common = append(common, types.SensorPair{A: sensor, B: b[matched[i]]})
+		}
+	}
+
+	for j, sensor := range b {
+		if !used[j] {
+			onlyInB = append(onlyInB, sensor)
+		}
+	}
+
+	return onlyInA, onlyInB, common
+}
+
+// sortedForDiff returns a copy of sensors ordered by name, type, device and ID.
+func sortedForDiff(sensors []types.Sensor) []types.Sensor {
+	sorted := slices.Clone(sensors)
+
+	sort.SliceStable(sorted, func(i, j int) bool {
+		x, y := sorted[i], sorted[j]
+
+		switch {
+		case !strings.EqualFold(x.Name, y.Name):
+			return strings.ToLower(x.Name) < strings.ToLower(y.Name)
+		case !strings.EqualFold(x.SensorType, y.SensorType):
+			return strings.ToLower(x.SensorType) < strings.ToLower(y.SensorType)
+		case !strings.EqualFold(x.DeviceName, y.DeviceName):
+			return strings.ToLower(x.DeviceName) < strings.ToLower(y.DeviceName)
+		default:
+			return x.ID < y.ID
+		}
+	})
+
+	return sorted
+}
+
+// handleSensorStatusDiff handles the prtg_sensor_status_diff tool.
+func (h *ToolHandler) handleSensorStatusDiff(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	h.logger.Info().Interface("arguments", request.Params.Arguments).Msg("handling prtg_sensor_status_diff")
+
+	var args struct {
+		SensorID int             `json:"sensor_id"`
+		Previous json.RawMessage `json:"previous"`
+		Since    string          `json:"since"`
+		IDPrefix string          `json:"id_prefix"`
+	}
+
+	if err := parseArguments(request.Params.Arguments, &args); err != nil {
+		return nil, fmt.Errorf("invalid arguments: %w", err)
+	}
+
+	if args.SensorID <= 0 {
+		return nil, fmt.Errorf("sensor_id must be greater than 0")
+	}
+
+	offsets, err := h.idOffsets(args.IDPrefix)
+	if err != nil {
+		return nil, err
+	}
+
+	hasPrevious := len(args.Previous) > 0 && string(args.Previous) != "null"
+
+	switch {
+	case hasPrevious && args.Since != "":
+		return nil, fmt.Errorf("provide either previous or since, not both")
+	case !hasPrevious && args.Since == "":
+		return nil, fmt.Errorf("previous or since is required")
+	}
+
+	var previous types.Sensor
+
+	var since time.Time
+
+	if hasPrevious {
+		var err error
+		if previous, err = parsePreviousSensor(args.Previous); err != nil {
+			return nil, err
+		}
+
+		if previous.ID != 0 && previous.ID != args.SensorID {
+			return nil, fmt.Errorf("previous status is for sensor %d, not sensor %d", previous.ID, args.SensorID)
+		}
+	} else {
+		var err error
+		if since, err = time.Parse(time.RFC3339, args.Since); err != nil {
+			return nil, fmt.Errorf("since must be an RFC 3339 timestamp (e.g. 2025-10-26T10:30:00Z): %w", err)
+		}
+	}
+
+	// Add timeout to parent context (preserves cancellation chain)
+	dbCtx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	sensor, err := h.db.GetSensorByID(dbCtx, offsets.ToDatabaseID(args.IDPrefix, args.SensorID), nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get sensor: %w", err)
+	}
+
+	// Compared with a previous status rendered with the same id_prefix
+	uiSensorIDs(offsets, args.IDPrefix, sensor)
+
+	var diff *types.SensorDiff
+	if hasPrevious {
+		diff = diffSensor(previous, *sensor)
+	} else {
+		diff = diffSensorSince(*sensor, since, time.Now())
+	}
+
+	return mcp.NewToolResultText(formatSensorDiffResponse(diff, h.config.DisplayLocation(), h.includeJSON(request))), nil
+}
+
+// parsePreviousSensor decodes a previously returned sensor status. It accepts a JSON object,
+// or a string holding one, possibly prefixed by text (e.g. "Found 1 result(s):").
+func parsePreviousSensor(raw json.RawMessage) (types.Sensor, error) {
+	var sensor types.Sensor
+
+	var text string
+	if err := json.Unmarshal(raw, &text); err == nil {
+		start := strings.Index(text, "{")
+		if start < 0 {
+			return sensor, fmt.Errorf("previous must contain a sensor status JSON object")
+		}
+
+		raw = json.RawMessage(text[start:])
+	}
+
+	// Decode the first JSON value only, ignoring any trailing text
+	if err := json.NewDecoder(bytes.NewReader(raw)).Decode(&sensor); err != nil {
+		return sensor, fmt.Errorf("previous must be a sensor status JSON object: %w", err)
+	}
+
+	return sensor, nil
+}
+
+// handleGetAlerts handles the prtg_get_alerts tool.
+func (h *ToolHandler) handleGetAlerts(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	h.logger.Info().Interface("arguments", request.Params.Arguments).Msg("handling prtg_get_alerts")
+
+	var args struct {
+		Hours         int    `json:"hours"`
+		Status        *int   `json:"status"`
+		MinPriority   *int   `json:"min_priority"`
+		DeviceName    string `json:"device_name"`
+		Offset        int    `json:"offset"`
+		GroupByDevice bool   `json:"group_by_device"`
+		FullMessages  bool   `json:"full_messages"`
+		Format        string `json:"format"`
+
+		BaselineSensorIDs []int `json:"baseline_sensor_ids"` // Empty but not nil for an empty baseline
+	}
+
+	if err := parseArguments(request.Params.Arguments, &args); err != nil {
+		return nil, fmt.Errorf("invalid arguments: %w", err)
+	}
+
+	if args.Hours == 0 {
+		args.Hours = 24
+	}
+
+	if args.Format == "" {
+		args.Format = "markdown"
+	}
+
+	if args.Format != "markdown" && args.Format != "json" {
+		return nil, fmt.Errorf("invalid format: %s (must be 'markdown' or 'json')", args.Format)
+	}
+
+	if args.Offset < 0 {
+		return nil, fmt.Errorf("offset must not be negative")
+	}
+
+	filter := types.AlertFilter{
+		Hours:       args.Hours,
+		Status:      args.Status,
+		MinPriority: args.MinPriority,
+		DeviceName:  args.DeviceName,
+	}
+
+	if err := filter.Validate(); err != nil {
+		return nil, err
+	}
+
+	if args.BaselineSensorIDs != nil {
+		if args.Offset > 0 {
+			return nil, fmt.Errorf("offset cannot be combined with baseline_sensor_ids")
+		}
+
+		return h.getAlertsSinceBaseline(ctx, filter, args.BaselineSensorIDs, args.Format, h.includeJSON(request))
+	}
+
+	// The markdown table keeps the priority order, the JSON output ranks alerts by severity score.
+	// The database orders by score so that the next page continues where this one ends.
+	if args.Format == "json" {
+		filter.OrderBy = types.AlertOrderSeverity
+	}
+
+	pageSize := h.config.AlertsPageSize()
+
+	// Add timeout to parent context (preserves cancellation chain)
+	dbCtx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	sensors, err := h.db.GetAlerts(dbCtx, filter, fetchLimit(pageSize), args.Offset)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get alerts: %w", err)
+	}
+
+	sensors, meta := limitResults(sensors, pageSize)
+	h.setAlertsPageTotal(ctx, filter, args.Offset, &meta)
+
+	alerts := scoreAlerts(sensors)
+
+	if args.Format == "json" {
+		jsonText, err := formatAlertsJSON(alerts, meta)
+		if err != nil {
+			return nil, err
+		}
+
+		return mcp.NewToolResultText(jsonText), nil
+	}
+
+	// Use visual formatting for alerts
+	formattedText := formatAlertsResponse(alerts, meta, args.GroupByDevice, args.FullMessages, h.includeJSON(request))
+
+	return &mcp.CallToolResult{
+		Content: []mcp.Content{
+			mcp.TextContent{
+				Type: "text",
+				Text: formattedText,
+			},
+		},
+	}, nil
+}
+
+// alertsBaselineLimit bounds the alerts compared with a baseline. The comparison needs every
+// alert at once: a baseline sensor missing from a partial list would wrongly count as cleared.
+const alertsBaselineLimit = 5000
+
+// getAlertsSinceBaseline returns the baseline mode of prtg_get_alerts: the alerts that are not
+// in the baseline, and the baseline sensors no longer in alert.
+func (h *ToolHandler) getAlertsSinceBaseline(ctx context.Context, filter types.AlertFilter, baseline []int,
+	format string, includeJSON bool) (*mcp.CallToolResult, error) {
+	dbCtx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	sensors, err := h.db.GetAlerts(dbCtx, filter, alertsBaselineLimit+1, 0)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get alerts: %w", err)
+	}
+
+	if len(sensors) > alertsBaselineLimit {
+		return nil, fmt.Errorf("more than %d alerts match, narrow the filters to compare them with a baseline", alertsBaselineLimit)
+	}
+
+	// New alerts are listed most severe first
+	sortBySeverity(sensors)
+	diff := diffAlerts(scoreAlerts(sensors), baseline)
+
+	if format == "json" {
+		jsonData, err := json.MarshalIndent(diff, "", "  ")
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal alert changes: %w", err)
+		}
+
+		return mcp.NewToolResultText(string(jsonData)), nil
+	}
+
+	return &mcp.CallToolResult{
+		Content: []mcp.Content{
+			mcp.TextContent{
+				Type: "text",
+				Text: formatAlertDiffResponse(diff, len(baseline), includeJSON),
+			},
+		},
+	}, nil
+}
+
+// setAlertsPageTotal completes the metadata of the page of alerts at offset, built by limitResults.
+// The last page ends the alert set, so its total follows from the offset; the total of an
+// earlier page is counted, and left unknown if counting fails.
+func (h *ToolHandler) setAlertsPageTotal(ctx context.Context, filter types.AlertFilter, offset int, meta *resultMetadata) {
+	if !meta.Truncated {
+		total := offset + meta.Returned
+		meta.Total = &total
+
+		return
+	}
+
+	meta.NextOffset = offset + meta.Returned
+
+	dbCtx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	total, err := h.db.CountAlerts(dbCtx, filter)
+	if err != nil {
+		h.logger.Warn().Err(err).Msg("db.CountAlerts failed, reporting no total")
+		return
+	}
+
+	// The alert set may have changed between both queries: the total covers at least the next alert
+	total = max(total, meta.NextOffset+1)
+	meta.Total = &total
+}
+
+// handleGroupCounts handles the prtg_group_counts tool.
+func (h *ToolHandler) handleGroupCounts(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	h.logger.Info().Interface("arguments", request.Params.Arguments).Msg("handling prtg_group_counts")
+
+	var args struct {
+		Dimension   string `json:"dimension"`
+		DeviceName  string `json:"device_name"`
+		SensorName  string `json:"sensor_name"`
+		SensorType  string `json:"sensor_type"`
+		GroupName   string `json:"group_name"`
+		Status      *int   `json:"status"`
+		MinPriority *int   `json:"min_priority"`
+		MaxPriority *int   `json:"max_priority"`
+		Limit       int    `json:"limit"`
+	}
+
+	if err := parseArguments(request.Params.Arguments, &args); err != nil {
+		return nil, fmt.Errorf("invalid arguments: %w", err)
+	}
+
+	if args.Dimension == "" {
+		return nil, fmt.Errorf("dimension is required")
+	}
+
+	if args.Limit <= 0 {
+		args.Limit = 100
+	}
+
+	filter := types.SensorFilter{
+		DeviceName:  args.DeviceName,
+		SensorName:  args.SensorName,
+		SensorType:  args.SensorType,
+		GroupName:   args.GroupName,
+		Status:      args.Status,
+		MinPriority: args.MinPriority,
+		MaxPriority: args.MaxPriority,
+	}
+
+	// Add timeout to parent context (preserves cancellation chain)
+	dbCtx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	counts, err := h.db.GroupCounts(dbCtx, args.Dimension, filter, fetchLimit(args.Limit))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get group counts: %w", err)
+	}
+
+	counts, meta := limitResults(counts, args.Limit)
+
+	return mcp.NewToolResultText(formatGroupCountsResponse(counts, args.Dimension, meta, h.includeJSON(request))), nil
+}
+
+// handleEstateHealth handles the prtg_estate_health tool.
+func (h *ToolHandler) handleEstateHealth(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	h.logger.Info().Interface("arguments", request.Params.Arguments).Msg("handling prtg_estate_health")
+
+	var args struct {
+		TopProblems int `json:"top_problems"`
+	}
+
+	if err := parseArguments(request.Params.Arguments, &args); err != nil {
+		return nil, fmt.Errorf("invalid arguments: %w", err)
+	}
+
+	if args.TopProblems <= 0 {
+		args.TopProblems = 5
+	}
+
+	if args.TopProblems > 25 {
+		args.TopProblems = 25
+	}
+
+	// Add timeout to parent context (same budget as prtg_get_statistics)
+	dbCtx, cancel := context.WithTimeout(ctx, 60*time.Second)
+	defer cancel()
+
+	stats, _, err := h.getStatistics(dbCtx, false)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get statistics: %w", err)
+	}
+
+	// Current alerts regardless of last check time
+	alerts, err := h.db.GetAlerts(dbCtx, types.AlertFilter{}, h.config.AlertsPageSize(), 0)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get alerts: %w", err)
+	}
+
+	health := computeEstateHealth(stats, alerts, args.TopProblems)
+
+	h.logger.Info().
+		Float64("health_percent", health.HealthPercent).
+		Str("status", health.Status).
+		Msg("returning estate health to MCP client")
+
+	return mcp.NewToolResultText(formatEstateHealthResponse(health, h.includeJSON(request))), nil
+}
+
+// handleGetRecentStatusChanges handles the prtg_get_recent_status_changes tool.
+func (h *ToolHandler) handleGetRecentStatusChanges(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	h.logger.Info().Interface("arguments", request.Params.Arguments).Msg("handling prtg_get_recent_status_changes")
+
+	var args struct {
+		Minutes      int  `json:"minutes"`
+		Limit        int  `json:"limit"`
+		FullMessages bool `json:"full_messages"`
+	}
+
+	if err := parseArguments(request.Params.Arguments, &args); err != nil {
+		return nil, fmt.Errorf("invalid arguments: %w", err)
+	}
+
+	if args.Minutes <= 0 {
+		args.Minutes = 15
+	}
+
+	if args.Limit <= 0 {
+		args.Limit = 100
+	}
+
+	// Add timeout to parent context (preserves cancellation chain)
+	dbCtx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	changes, err := h.db.GetRecentChanges(dbCtx, args.Minutes, fetchLimit(args.Limit))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get recent status changes: %w", err)
+	}
+
+	changes, meta := limitResults(changes, args.Limit)
+
+	return mcp.NewToolResultText(formatRecentChangesResponse(changes, args.Minutes, meta, args.FullMessages,
+		h.config.DisplayLocation(), h.includeJSON(request))), nil
+}
+
+// handleDeviceOverview handles the prtg_device_overview tool.
+func (h *ToolHandler) handleDeviceOverview(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	h.logger.Info().Interface("arguments", request.Params.Arguments).Msg("handling prtg_device_overview")
+
+	var args struct {
+		DeviceName      string `json:"device_name"`
+		IncludeChannels bool   `json:"include_channels"`
+		ChannelsLimit   int    `json:"channels_limit"`
+	}
+
+	if err := parseArguments(request.Params.Arguments, &args); err != nil {
+		return nil, fmt.Errorf("invalid arguments: %w", err)
+	}
+
+	if args.DeviceName == "" {
+		return nil, fmt.Errorf("device_name is required")
+	}
+
+	if args.ChannelsLimit <= 0 {
+		args.ChannelsLimit = 5
+	}
+
+	if args.ChannelsLimit > maxChannelSensors {
+		args.ChannelsLimit = maxChannelSensors
+	}
+
+	// Add timeout to parent context (preserves cancellation chain)
+	dbCtx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	overview, err := h.db.GetDeviceOverview(dbCtx, args.DeviceName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get device overview: %w", err)
+	}
+
+	var channels *deviceChannels
+	if args.IncludeChannels {
+		channels = h.fetchDeviceChannels(ctx, overview.Sensors, args.ChannelsLimit)
+	}
+
+	// Use visual formatting for device overview
+	formattedText := formatDeviceOverviewResponse(overview, channels, h.config.DisplayLocation(), h.includeJSON(request))
+
+	return &mcp.CallToolResult{
+		Content: []mcp.Content{
+			mcp.TextContent{
+				Type: "text",
+				Text: formattedText,
+			},
+		},
+	}, nil
+}
+
+const (
+	// maxChannelSensors bounds the PRTG API calls made by a single device overview.
+	maxChannelSensors = 20
+
+	// channelFetchConcurrency caps the concurrent PRTG API calls of a device overview.
+	channelFetchConcurrency = 4
+)
+
+// maxPrimaryChannelSensors bounds the PRTG API calls made by prtg_get_sensors include_primary_channel.
+const maxPrimaryChannelSensors = 25
+
+// setPrimaryChannelValues sets the PrimaryValue of the first sensors to the current value of
+// their primary channel, fetched from the PRTG API through the channel cache. Failures never fail
+// the listing: it returns a note explaining which values are missing, or "".
+func (h *ToolHandler) setPrimaryChannelValues(ctx context.Context, sensors []types.Sensor) string {
+	if h.prtgClient == nil {
+		return "Primary channel values unavailable: PRTG API not configured."
+	}
+
+	enriched := sensors
+	if len(enriched) > maxPrimaryChannelSensors {
+		enriched = enriched[:maxPrimaryChannelSensors]
+	}
+
+	apiCtx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	var (
+		mu     sync.Mutex
+		wg     sync.WaitGroup
+		failed int
+	)
+
+	sem := make(chan struct{}, channelFetchConcurrency)
+
+	for i := range enriched {
+		wg.Add(1)
+
+		go func(sensor *types.Sensor) {
+			defer wg.Done()
+
+			sem <- struct{}{}
+			defer func() { <-sem }()
+
+			channels, err := h.getChannels(apiCtx, sensor.ID)
+
+			mu.Lock()
+			defer mu.Unlock()
+
+			if err != nil {
+				h.logger.Warn().Err(err).Int("sensor_id", sensor.ID).Msg("failed to fetch primary channel value")
+				failed++
+
+				return
+			}
+
+			if ch := primaryChannel(channels); ch != nil {
+				sensor.PrimaryValue = summarizeChannels([]prtg.Channel{*ch})
+			}
+		}(&enriched[i])
+	}
+
+	wg.Wait()
+
+	switch {
+	case len(enriched) > 0 && failed == len(enriched):
+		return "Primary channel values unavailable: PRTG API unavailable."
+	case len(sensors) > len(enriched):
+		return fmt.Sprintf("Primary channel values shown for the first %d of %d sensors; narrow the filter to see more.",
+			len(enriched), len(sensors))
+	default:
+		return ""
+	}
+}
+
+// primaryChannel returns the primary channel (ID 0) of a sensor, or else its first channel with a
+// value other than Downtime. It returns nil when no channel has a current value.
+func primaryChannel(channels []prtg.Channel) *prtg.Channel {
+	for i := range channels {
+		if channelIDMatches(channels[i].ID, 0) && channels[i].LastMeasurement != nil {
+			return &channels[i]
+		}
+	}
+
+	for i := range channels {
+		if channels[i].LastMeasurement != nil && !strings.EqualFold(channels[i].Name, "Downtime") {
+			return &channels[i]
+		}
+	}
+
+	return nil
+}
+
+// deviceChannels holds the key channel values of device overview sensors.
+type deviceChannels struct {
+	Values      map[int]string // Key channel values by sensor ID
+	Unavailable string         // Reason channel values could not be fetched at all
+}
+
+// fetchDeviceChannels fetches current channel values from the PRTG API for the key sensors of a device.
+// Failures never fail the overview: sensors without values are shown without them.
+func (h *ToolHandler) fetchDeviceChannels(ctx context.Context, sensors []types.Sensor, limit int) *deviceChannels {
+	if h.prtgClient == nil {
+		return &deviceChannels{Unavailable: "PRTG API not configured"}
+	}
+
+	keySensors := selectKeySensors(sensors, limit)
+
+	apiCtx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	var (
+		mu     sync.Mutex
+		wg     sync.WaitGroup
+		failed int
+	)
+
+	values := make(map[int]string, len(keySensors))
+	sem := make(chan struct{}, channelFetchConcurrency)
+
+	for _, sensor := range keySensors {
+		wg.Add(1)
+
+		go func(sensorID int) {
+			defer wg.Done()
+
+			sem <- struct{}{}
+			defer func() { <-sem }()
+
+			channels, err := h.prtgClient.GetChannelsBySensor(apiCtx, sensorID)
+
+			mu.Lock()
+			defer mu.Unlock()
+
+			if err != nil {
+				h.logger.Warn().Err(err).Int("sensor_id", sensorID).Msg("failed to fetch channel values for device overview")
+				failed++
+
+				return
+			}
+
+			if summary := summarizeChannels(channels); summary != "" {
+				values[sensorID] = summary
+			}
+		}(sensor.ID)
+	}
+
+	wg.Wait()
+
+	if len(keySensors) > 0 && failed == len(keySensors) {
+		return &deviceChannels{Unavailable: "PRTG API unavailable"}
+	}
+
+	return &deviceChannels{Values: values}
+}
+
+// selectKeySensors returns up to limit sensors worth fetching channel values for:
+// down sensors first, then warning sensors, then the rest, each by priority.
+func selectKeySensors(sensors []types.Sensor, limit int) []types.Sensor {
+	rank := func(status int) int {
+		switch {
+		case isDownStatus(status):
+			return 0
+		case status == types.StatusWarning || status == types.StatusUnusual:
+			return 1
+		default:
+			return 2
+		}
+	}
+
+	sorted := make([]types.Sensor, len(sensors))
+	copy(sorted, sensors)
+
+	sort.SliceStable(sorted, func(i, j int) bool {
+		if rank(sorted[i].Status) != rank(sorted[j].Status) {
+			return rank(sorted[i].Status) < rank(sorted[j].Status)
+		}
+
+		return sorted[i].Priority > sorted[j].Priority
+	})
+
+	if len(sorted) > limit {
+		sorted = sorted[:limit]
+	}
+
+	return sorted
+}
+
+// maxStabilityProcesses bounds the PRTG API calls made by a single business process listing.
+const maxStabilityProcesses = 20
+
+// processStability holds the 24h stability notes of business processes.
+type processStability struct {
+	Notes       map[int]string // Stability note by sensor ID
+	Unavailable string         // Reason the history could not be fetched at all
+}
+
+// fetchProcessStability fetches the last 24h of history from the PRTG API for the first
+// business processes and summarizes how often they dipped. Failures never fail the listing:
+// processes without history are shown without a note.
+func (h *ToolHandler) fetchProcessStability(ctx context.Context, processes []types.Sensor) *processStability {
+	if h.prtgClient == nil {
+		return &processStability{Unavailable: "PRTG API not configured"}
+	}
+
+	if len(processes) > maxStabilityProcesses {
+		processes = processes[:maxStabilityProcesses]
+	}
+
+	apiCtx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	var (
+		mu     sync.Mutex
+		wg     sync.WaitGroup
+		failed int
+	)
+
+	notes := make(map[int]string, len(processes))
+	sem := make(chan struct{}, channelFetchConcurrency)
+
+	for _, process := range processes {
+		wg.Add(1)
+
+		go func(sensorID int) {
+			defer wg.Done()
+
+			sem <- struct{}{}
+			defer func() { <-sem }()
+
+			data, err := h.prtgClient.GetTimeSeries(apiCtx, sensorID, prtg.TimeSeriesShort)
+
+			mu.Lock()
+			defer mu.Unlock()
+
+			if err != nil {
+				h.logger.Warn().Err(err).Int("sensor_id", sensorID).Msg("failed to fetch business process history")
+				failed++
+
+				return
+			}
+
+			if note := summarizeStability(data); note != "" {
+				notes[sensorID] = note
+			}
+		}(process.ID)
+	}
+
+	wg.Wait()
+
+	if len(processes) > 0 && failed == len(processes) {
+		return &processStability{Unavailable: "PRTG API unavailable"}
+	}
+
+	return &processStability{Notes: notes}
+}
+
+// summarizeStability counts the dips in a sensor history: runs of consecutive data points with
+// downtime. It returns "" when the history has no downtime channel or no data points.
+func summarizeStability(data *prtg.TimeSeriesData) string {
+	channel := ""
+
+	for _, header := range data.Headers {
+		if strings.Contains(strings.ToLower(header), "downtime") {
+			channel = header
+			break
+		}
+	}
+
+	if channel == "" || len(data.DataPoints) == 0 {
+		return ""
+	}
+
+	dips := 0
+	down := false
+
+	for _, point := range data.DataPoints {
+		value, ok := toFloat(point.Values[channel])
+		if ok && value > 0 && !down {
+			dips++
+		}
+
+		down = ok && value > 0
+	}
+
+	switch dips {
+	case 0:
+		return "stable over last 24h"
+	case 1:
+		return "1 dip in last 24h"
+	default:
+		return fmt.Sprintf("%d dips in last 24h", dips)
+	}
+}
+
+// handleTopSensors handles the prtg_top_sensors tool.
+func (h *ToolHandler) handleTopSensors(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	h.logger.Info().Interface("arguments", request.Params.Arguments).Msg("handling prtg_top_sensors")
+
+	var args struct {
+		Metric       string `json:"metric"`
+		SensorType   string `json:"sensor_type"`
+		Limit        int    `json:"limit"`
+		Hours        int    `json:"hours"`
+		FullMessages bool   `json:"full_messages"`
+	}
+
+	if err := parseArguments(request.Params.Arguments, &args); err != nil {
+		return nil, fmt.Errorf("invalid arguments: %w", err)
+	}
+
+	if args.Metric == "" {
+		args.Metric = "downtime"
+	}
+
+	if args.Limit <= 0 {
+		args.Limit = 10
+	}
+
+	if args.Hours <= 0 {
+		args.Hours = 24
+	}
+
+	// Add timeout to parent context (preserves cancellation chain)
+	dbCtx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	sensors, err := h.db.GetTopSensors(dbCtx, args.Metric, args.SensorType, fetchLimit(args.Limit), args.Hours)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get top sensors: %w", err)
+	}
+
+	sensors, meta := limitResults(sensors, args.Limit)
+
+	// Use visual formatting for top sensors
+	formattedText := formatTopSensorsResponse(sensors, args.Metric, meta, args.FullMessages, h.includeJSON(request))
+
+	return &mcp.CallToolResult{
+		Content: []mcp.Content{
+			mcp.TextContent{
+				Type: "text",
+				Text: formattedText,
+			},
+		},
+	}, nil
+}
+
+// handleGetHierarchy handles the prtg_get_hierarchy tool.
+func (h *ToolHandler) handleGetHierarchy(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	h.logger.Info().Interface("arguments", request.Params.Arguments).Msg("handling prtg_get_hierarchy")
 
 	var args struct {
-		SensorID int `json:"sensor_id"`
+		GroupName           string `json:"group_name"`
+		DeviceName          string `json:"device_name"`
+		IncludeSensors      bool   `json:"include_sensors"`
+		MaxDepth            int    `json:"max_depth"`
+		MaxChildren         int    `json:"max_children"`
+		MaxSensorsPerDevice int    `json:"max_sensors_per_device"`
+		OutputFormat        string `json:"output_format"`
 	}
 
 	if err := parseArguments(request.Params.Arguments, &args); err != nil {
 		return nil, fmt.Errorf("invalid arguments: %w", err)
 	}
 
-	if args.SensorID <= 0 {
-		return nil, fmt.Errorf("sensor_id must be greater than 0")
+	switch args.OutputFormat {
+	case "":
+		args.OutputFormat = hierarchyOutputBoth
+	case hierarchyOutputTree, hierarchyOutputJSON, hierarchyOutputBoth:
+	default:
+		return nil, fmt.Errorf("invalid output_format: %s (must be 'tree', 'json' or 'both')", args.OutputFormat)
 	}
 
-	// Add timeout to parent context (preserves cancellation chain)
-	dbCtx, cancel := context.WithTimeout(ctx, 30*time.Second)
-	defer cancel()
+	// Without the JSON payload "both" is just the tree; an explicit "json" is kept
+	if args.OutputFormat == hierarchyOutputBoth && !h.includeJSON(request) {
+		args.OutputFormat = hierarchyOutputTree
+	}
 
-	sensor, err := h.db.GetSensorByID(dbCtx, args.SensorID)
-	if err != nil {
-		return nil, fmt.Errorf("failed to get sensor: %w", err)
+	if args.MaxDepth < 0 {
+		args.MaxDepth = 2 // Default to 2 levels deep
 	}
 
-	return formatResult(sensor, 1)
-}
+	if args.MaxChildren <= 0 {
+		args.MaxChildren = types.DefaultHierarchyMaxChildren
+	}
 
-// handleGetAlerts handles the prtg_get_alerts tool.
-func (h *ToolHandler) handleGetAlerts(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
-	h.logger.Info().Interface("arguments", request.Params.Arguments).Msg("handling prtg_get_alerts")
+	if args.MaxSensorsPerDevice <= 0 {
+		args.MaxSensorsPerDevice = types.DefaultHierarchyMaxSensorsPerDevice
+	}
 
-	var args struct {
-		Hours      int    `json:"hours"`
-		Status     *int   `json:"status"`
-		DeviceName string `json:"device_name"`
+	if args.DeviceName != "" {
+		if args.GroupName != "" {
+			return nil, fmt.Errorf("group_name and device_name cannot be combined")
+		}
+
+		return h.getDeviceHierarchy(ctx, args.DeviceName, args.MaxSensorsPerDevice, args.OutputFormat)
 	}
 
-	if err := parseArguments(request.Params.Arguments, &args); err != nil {
-		return nil, fmt.Errorf("invalid arguments: %w", err)
+	opts := types.HierarchyOptions{
+		IncludeSensors:      args.IncludeSensors,
+		MaxDepth:            args.MaxDepth,
+		MaxChildren:         args.MaxChildren,
+		MaxSensorsPerDevice: args.MaxSensorsPerDevice,
+		MaxNodes:            h.config.MaxHierarchyNodes(),
 	}
 
-	if args.Hours == 0 {
-		args.Hours = 24
+	h.logger.Debug().
+		Str("group_name", args.GroupName).
+		Interface("options", opts).
+		Msg("calling db.GetHierarchy")
+
+	// Add timeout to parent context
+	dbCtx, cancel := context.WithTimeout(ctx, 60*time.Second) // Longer timeout for hierarchy traversal
+	defer cancel()
+
+	hierarchy, err := h.db.GetHierarchy(dbCtx, args.GroupName, opts)
+	if err != nil {
+		h.logger.Error().Err(err).Msg("db.GetHierarchy failed")
+		return nil, fmt.Errorf("failed to get hierarchy: %w", err)
 	}
 
-	// Add timeout to parent context (preserves cancellation chain)
+	// Use visual formatting for hierarchy
+	formattedText := formatHierarchyResponse(hierarchy, args.OutputFormat)
+
+	h.logger.Info().Msg("returning hierarchy result to MCP client")
+
+	return &mcp.CallToolResult{
+		Content: []mcp.Content{
+			mcp.TextContent{
+				Type: "text",
+				Text: formattedText,
+			},
+		},
+	}, nil
+}
+
+// getDeviceHierarchy returns the device mode of prtg_get_hierarchy: the path from the probe
+// down to the device, and up to maxSensors of its sensors, problems first.
+func (h *ToolHandler) getDeviceHierarchy(ctx context.Context, deviceName string, maxSensors int, outputFormat string) (*mcp.CallToolResult, error) {
 	dbCtx, cancel := context.WithTimeout(ctx, 30*time.Second)
 	defer cancel()
 
-	sensors, err := h.db.GetAlerts(dbCtx, args.Hours, args.Status, args.DeviceName)
+	overview, err := h.db.GetDeviceOverview(dbCtx, deviceName)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get alerts: %w", err)
+		return nil, fmt.Errorf("failed to get device: %w", err)
 	}
 
-	// Use visual formatting for alerts
-	formattedText := formatAlertsResponse(sensors)
+	path, err := h.db.GetDevicePath(dbCtx, overview.Device.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get path of device %d: %w", overview.Device.ID, err)
+	}
+
+	path.Sensors = overview.Sensors
+	if len(path.Sensors) > maxSensors {
+		path.TotalSensors = len(path.Sensors)
+		path.Sensors = path.Sensors[:maxSensors]
+	}
 
 	return &mcp.CallToolResult{
 		Content: []mcp.Content{
 			mcp.TextContent{
 				Type: "text",
-				Text: formattedText,
+				Text: formatDevicePathResponse(path, outputFormat),
 			},
 		},
 	}, nil
 }
 
-// handleDeviceOverview handles the prtg_device_overview tool.
-func (h *ToolHandler) handleDeviceOverview(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
-	h.logger.Info().Interface("arguments", request.Params.Arguments).Msg("handling prtg_device_overview")
+// handleSearch handles the prtg_search tool.
+func (h *ToolHandler) handleSearch(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	h.logger.Info().Interface("arguments", request.Params.Arguments).Msg("handling prtg_search")
 
 	var args struct {
-		DeviceName string `json:"device_name"`
+		SearchTerm          string  `json:"search_term"`
+		Limit               int     `json:"limit"`
+		TotalLimit          int     `json:"total_limit"`
+		Fuzzy               bool    `json:"fuzzy"`
+		SimilarityThreshold float64 `json:"similarity_threshold"`
 	}
 
 	if err := parseArguments(request.Params.Arguments, &args); err != nil {
 		return nil, fmt.Errorf("invalid arguments: %w", err)
 	}
 
-	if args.DeviceName == "" {
-		return nil, fmt.Errorf("device_name is required")
+	if args.SearchTerm == "" {
+		return nil, fmt.Errorf("search_term is required")
 	}
 
-	// Add timeout to parent context (preserves cancellation chain)
+	if args.TotalLimit < 0 {
+		return nil, fmt.Errorf("total_limit must be positive")
+	}
+
+	if args.Limit <= 0 {
+		args.Limit = 50
+
+		// A total limit alone also bounds each category, no category can exceed it anyway
+		if args.TotalLimit > 0 {
+			args.Limit = args.TotalLimit
+		}
+	}
+
+	if args.SimilarityThreshold < 0 || args.SimilarityThreshold > 1 {
+		return nil, fmt.Errorf("similarity_threshold must be between 0 and 1")
+	}
+
+	if args.SimilarityThreshold == 0 {
+		args.SimilarityThreshold = h.config.FuzzySearchThreshold()
+	}
+
+	h.logger.Debug().
+		Str("search_term", args.SearchTerm).
+		Int("limit", args.Limit).
+		Int("total_limit", args.TotalLimit).
+		Bool("fuzzy", args.Fuzzy).
+		Msg("calling db.Search")
+
+	// Add timeout to parent context
 	dbCtx, cancel := context.WithTimeout(ctx, 30*time.Second)
 	defer cancel()
 
-	overview, err := h.db.GetDeviceOverview(dbCtx, args.DeviceName)
+	var results *types.SearchResults
+	var err error
+
+	if args.Fuzzy {
+		results, err = h.db.SearchFuzzy(dbCtx, args.SearchTerm, args.SimilarityThreshold, fetchLimit(args.Limit))
+	} else {
+		results, err = h.db.Search(dbCtx, args.SearchTerm, fetchLimit(args.Limit))
+	}
+
 	if err != nil {
-		return nil, fmt.Errorf("failed to get device overview: %w", err)
+		h.logger.Error().Err(err).Msg("db.Search failed")
+		return nil, fmt.Errorf("failed to search: %w", err)
 	}
 
-	// Use visual formatting for device overview
-	formattedText := formatDeviceOverviewResponse(overview)
+	meta := limitSearchResults(results, args.Limit)
+
+	if args.TotalLimit > 0 && capSearchResults(results, args.TotalLimit) {
+		meta.Returned = len(results.Groups) + len(results.Devices) + len(results.Sensors)
+		meta.Truncated = true
+	}
+
+	// Use visual formatting for search results
+	formattedText := formatSearchResponse(results, args.SearchTerm, meta, h.includeJSON(request))
+
+	h.logger.Info().
+		Int("groups_count", len(results.Groups)).
+		Int("devices_count", len(results.Devices)).
+		Int("sensors_count", len(results.Sensors)).
+		Msg("returning search results to MCP client")
 
 	return &mcp.CallToolResult{
 		Content: []mcp.Content{
@@ -541,44 +2803,86 @@ func (h *ToolHandler) handleDeviceOverview(ctx context.Context, request mcp.Call
 	}, nil
 }
 
-// handleTopSensors handles the prtg_top_sensors tool.
-func (h *ToolHandler) handleTopSensors(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
-	h.logger.Info().Interface("arguments", request.Params.Arguments).Msg("handling prtg_top_sensors")
+// capSearchResults trims search results to at most total items overall. The budget is
+// shared evenly between categories, and what a category cannot use goes to the others;
+// leftovers of an uneven split go to sensors, then devices, then groups.
+// It reports whether any result was dropped.
+func capSearchResults(results *types.SearchResults, total int) bool {
+	// Categories in priority order: sensors, devices, groups
+	counts := []int{len(results.Sensors), len(results.Devices), len(results.Groups)}
+	if counts[0]+counts[1]+counts[2] <= total {
+		return false
+	}
+
+	alloc := make([]int, len(counts))
+	remaining := total
+
+	for remaining > 0 {
+		var active []int
+
+		for i, count := range counts {
+			if alloc[i] < count {
+				active = append(active, i)
+			}
+		}
+
+		share := remaining / len(active)
+
+		if share == 0 {
+			for _, i := range active[:remaining] {
+				alloc[i]++
+			}
+
+			break
+		}
+
+		for _, i := range active {
+			given := min(share, counts[i]-alloc[i])
+			alloc[i] += given
+			remaining -= given
+		}
+	}
+
+	results.Sensors = results.Sensors[:alloc[0]]
+	results.Devices = results.Devices[:alloc[1]]
+	results.Groups = results.Groups[:alloc[2]]
+
+	return true
+}
+
+// handleSearchMessages handles the prtg_search_messages tool.
+func (h *ToolHandler) handleSearchMessages(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	h.logger.Info().Interface("arguments", request.Params.Arguments).Msg("handling prtg_search_messages")
 
 	var args struct {
-		Metric     string `json:"metric"`
-		SensorType string `json:"sensor_type"`
+		SearchTerm string `json:"search_term"`
 		Limit      int    `json:"limit"`
-		Hours      int    `json:"hours"`
 	}
 
 	if err := parseArguments(request.Params.Arguments, &args); err != nil {
 		return nil, fmt.Errorf("invalid arguments: %w", err)
 	}
 
-	if args.Metric == "" {
-		args.Metric = "downtime"
+	if strings.TrimSpace(args.SearchTerm) == "" {
+		return nil, fmt.Errorf("search_term is required")
 	}
 
 	if args.Limit <= 0 {
-		args.Limit = 10
-	}
-
-	if args.Hours <= 0 {
-		args.Hours = 24
+		args.Limit = 50
 	}
 
-	// Add timeout to parent context (preserves cancellation chain)
+	// Add timeout to parent context
 	dbCtx, cancel := context.WithTimeout(ctx, 30*time.Second)
 	defer cancel()
 
-	sensors, err := h.db.GetTopSensors(dbCtx, args.Metric, args.SensorType, args.Limit, args.Hours)
+	sensors, err := h.db.SearchMessages(dbCtx, args.SearchTerm, fetchLimit(args.Limit))
 	if err != nil {
-		return nil, fmt.Errorf("failed to get top sensors: %w", err)
+		return nil, fmt.Errorf("failed to search messages: %w", err)
 	}
 
-	// Use visual formatting for top sensors
-	formattedText := formatTopSensorsResponse(sensors, args.Metric)
+	sensors, meta := limitResults(sensors, args.Limit)
+
+	formattedText := formatMessageSearchResponse(sensors, args.SearchTerm, meta, h.includeJSON(request))
 
 	return &mcp.CallToolResult{
 		Content: []mcp.Content{
@@ -590,44 +2894,35 @@ func (h *ToolHandler) handleTopSensors(ctx context.Context, request mcp.CallTool
 	}, nil
 }
 
-// handleGetHierarchy handles the prtg_get_hierarchy tool.
-func (h *ToolHandler) handleGetHierarchy(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
-	h.logger.Info().Interface("arguments", request.Params.Arguments).Msg("handling prtg_get_hierarchy")
+// handleListSensorTypes handles the prtg_list_sensor_types tool.
+func (h *ToolHandler) handleListSensorTypes(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	h.logger.Info().Interface("arguments", request.Params.Arguments).Msg("handling prtg_list_sensor_types")
 
 	var args struct {
-		GroupName      string `json:"group_name"`
-		IncludeSensors bool   `json:"include_sensors"`
-		MaxDepth       int    `json:"max_depth"`
+		Limit int `json:"limit"`
 	}
 
 	if err := parseArguments(request.Params.Arguments, &args); err != nil {
 		return nil, fmt.Errorf("invalid arguments: %w", err)
 	}
 
-	if args.MaxDepth < 0 {
-		args.MaxDepth = 2 // Default to 2 levels deep
+	if args.Limit <= 0 {
+		args.Limit = 100
 	}
 
-	h.logger.Debug().
-		Str("group_name", args.GroupName).
-		Bool("include_sensors", args.IncludeSensors).
-		Int("max_depth", args.MaxDepth).
-		Msg("calling db.GetHierarchy")
-
 	// Add timeout to parent context
-	dbCtx, cancel := context.WithTimeout(ctx, 60*time.Second) // Longer timeout for hierarchy traversal
+	dbCtx, cancel := context.WithTimeout(ctx, 30*time.Second)
 	defer cancel()
 
-	hierarchy, err := h.db.GetHierarchy(dbCtx, args.GroupName, args.IncludeSensors, args.MaxDepth)
+	sensorTypes, err := h.db.GetSensorTypes(dbCtx, fetchLimit(args.Limit))
 	if err != nil {
-		h.logger.Error().Err(err).Msg("db.GetHierarchy failed")
-		return nil, fmt.Errorf("failed to get hierarchy: %w", err)
+		h.logger.Error().Err(err).Msg("db.GetSensorTypes failed")
+		return nil, fmt.Errorf("failed to list sensor types: %w", err)
 	}
 
-	// Use visual formatting for hierarchy
-	formattedText := formatHierarchyResponse(hierarchy)
+	sensorTypes, meta := limitResults(sensorTypes, args.Limit)
 
-	h.logger.Info().Msg("returning hierarchy result to MCP client")
+	formattedText := formatSensorTypesResponse(sensorTypes, meta, h.includeJSON(request))
 
 	return &mcp.CallToolResult{
 		Content: []mcp.Content{
@@ -639,59 +2934,60 @@ func (h *ToolHandler) handleGetHierarchy(ctx context.Context, request mcp.CallTo
 	}, nil
 }
 
-// handleSearch handles the prtg_search tool.
-func (h *ToolHandler) handleSearch(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
-	h.logger.Info().Interface("arguments", request.Params.Arguments).Msg("handling prtg_search")
+// explainOutageSensorLimit caps the sensors analyzed by prtg_explain_outage.
+const explainOutageSensorLimit = 2000
+
+// handleExplainOutage handles the prtg_explain_outage tool.
+func (h *ToolHandler) handleExplainOutage(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	h.logger.Info().Interface("arguments", request.Params.Arguments).Msg("handling prtg_explain_outage")
 
 	var args struct {
-		SearchTerm string `json:"search_term"`
-		Limit      int    `json:"limit"`
+		DeviceName string `json:"device_name"`
+		GroupName  string `json:"group_name"`
 	}
 
 	if err := parseArguments(request.Params.Arguments, &args); err != nil {
 		return nil, fmt.Errorf("invalid arguments: %w", err)
 	}
 
-	if args.SearchTerm == "" {
-		return nil, fmt.Errorf("search_term is required")
+	args.DeviceName = strings.TrimSpace(args.DeviceName)
+	args.GroupName = strings.TrimSpace(args.GroupName)
+
+	if args.DeviceName == "" && args.GroupName == "" {
+		return nil, fmt.Errorf("device_name or group_name is required")
 	}
 
-	if args.Limit <= 0 {
-		args.Limit = 50
+	var scope []string
+	if args.DeviceName != "" {
+		scope = append(scope, "device "+args.DeviceName)
 	}
 
-	h.logger.Debug().
-		Str("search_term", args.SearchTerm).
-		Int("limit", args.Limit).
-		Msg("calling db.Search")
+	if args.GroupName != "" {
+		scope = append(scope, "group "+args.GroupName)
+	}
 
 	// Add timeout to parent context
 	dbCtx, cancel := context.WithTimeout(ctx, 30*time.Second)
 	defer cancel()
 
-	results, err := h.db.Search(dbCtx, args.SearchTerm, args.Limit)
+	// All sensors of the scope, not only problems: healthy sensors tell a device outage from an isolated failure
+	filter := types.SensorFilter{DeviceName: args.DeviceName, GroupName: args.GroupName}
+
+	sensors, err := h.db.GetSensorsExtended(dbCtx, filter, "device", fetchLimit(explainOutageSensorLimit))
 	if err != nil {
-		h.logger.Error().Err(err).Msg("db.Search failed")
-		return nil, fmt.Errorf("failed to search: %w", err)
+		return nil, fmt.Errorf("failed to get sensors: %w", err)
 	}
 
-	// Use visual formatting for search results
-	formattedText := formatSearchResponse(results, args.SearchTerm)
+	sensors, meta := limitResults(sensors, explainOutageSensorLimit)
+
+	explanation := explainOutage(strings.Join(scope, " in "), sensors)
 
 	h.logger.Info().
-		Int("groups_count", len(results.Groups)).
-		Int("devices_count", len(results.Devices)).
-		Int("sensors_count", len(results.Sensors)).
-		Msg("returning search results to MCP client")
+		Int("sensors", len(sensors)).
+		Int("causes", len(explanation.Causes)).
+		Msg("returning outage explanation to MCP client")
 
-	return &mcp.CallToolResult{
-		Content: []mcp.Content{
-			mcp.TextContent{
-				Type: "text",
-				Text: formattedText,
-			},
-		},
-	}, nil
+	return mcp.NewToolResultText(formatOutageExplanationResponse(explanation, meta, h.includeJSON(request))), nil
 }
 
 // handleGetGroups handles the prtg_get_groups tool.
@@ -722,14 +3018,16 @@ func (h *ToolHandler) handleGetGroups(ctx context.Context, request mcp.CallToolR
 	dbCtx, cancel := context.WithTimeout(ctx, 30*time.Second)
 	defer cancel()
 
-	groups, err := h.db.GetGroups(dbCtx, args.GroupName, args.ParentID, args.Limit)
+	groups, err := h.db.GetGroups(dbCtx, args.GroupName, args.ParentID, fetchLimit(args.Limit))
 	if err != nil {
 		h.logger.Error().Err(err).Msg("db.GetGroups failed")
 		return nil, fmt.Errorf("failed to get groups: %w", err)
 	}
 
+	groups, meta := limitResults(groups, args.Limit)
+
 	// Use visual formatting for groups
-	formattedText := formatGroupsResponse(groups)
+	formattedText := formatGroupsResponse(groups, meta, h.includeJSON(request))
 
 	h.logger.Info().
 		Int("groups_count", len(groups)).
@@ -750,8 +3048,11 @@ func (h *ToolHandler) handleGetTags(ctx context.Context, request mcp.CallToolReq
 	h.logger.Info().Interface("arguments", request.Params.Arguments).Msg("handling prtg_get_tags")
 
 	var args struct {
-		TagName string `json:"tag_name"`
-		Limit   int    `json:"limit"`
+		TagName        string `json:"tag_name"`
+		OrderBy        string `json:"order_by"`
+		MinSensorCount *int   `json:"min_sensor_count"`
+		MaxSensorCount *int   `json:"max_sensor_count"`
+		Limit          int    `json:"limit"`
 	}
 
 	if err := parseArguments(request.Params.Arguments, &args); err != nil {
@@ -762,18 +3063,31 @@ func (h *ToolHandler) handleGetTags(ctx context.Context, request mcp.CallToolReq
 		args.Limit = 100
 	}
 
+	filter := types.TagFilter{
+		Name:           args.TagName,
+		OrderBy:        args.OrderBy,
+		MinSensorCount: args.MinSensorCount,
+		MaxSensorCount: args.MaxSensorCount,
+	}
+
+	if err := filter.Validate(); err != nil {
+		return nil, err
+	}
+
 	// Add timeout to parent context
 	dbCtx, cancel := context.WithTimeout(ctx, 30*time.Second)
 	defer cancel()
 
-	tags, err := h.db.GetTags(dbCtx, args.TagName, args.Limit)
+	tags, err := h.db.GetTags(dbCtx, filter, fetchLimit(args.Limit))
 	if err != nil {
 		h.logger.Error().Err(err).Msg("db.GetTags failed")
 		return nil, fmt.Errorf("failed to get tags: %w", err)
 	}
 
+	tags, meta := limitResults(tags, args.Limit)
+
 	// Use visual formatting for tags
-	formattedText := formatTagsResponse(tags)
+	formattedText := formatTagsResponse(tags, meta, h.includeJSON(request))
 
 	h.logger.Info().
 		Int("tags_count", len(tags)).
@@ -794,9 +3108,11 @@ func (h *ToolHandler) handleGetBusinessProcesses(ctx context.Context, request mc
 	h.logger.Info().Interface("arguments", request.Params.Arguments).Msg("handling prtg_get_business_processes")
 
 	var args struct {
-		ProcessName string `json:"process_name"`
-		Status      *int   `json:"status"`
-		Limit       int    `json:"limit"`
+		ProcessName      string `json:"process_name"`
+		Status           *int   `json:"status"`
+		Limit            int    `json:"limit"`
+		FullMessages     bool   `json:"full_messages"`
+		IncludeStability bool   `json:"include_stability"`
 	}
 
 	if err := parseArguments(request.Params.Arguments, &args); err != nil {
@@ -811,14 +3127,22 @@ func (h *ToolHandler) handleGetBusinessProcesses(ctx context.Context, request mc
 	dbCtx, cancel := context.WithTimeout(ctx, 60*time.Second)
 	defer cancel()
 
-	processes, err := h.db.GetBusinessProcesses(dbCtx, args.ProcessName, args.Status, args.Limit)
+	processes, err := h.db.GetBusinessProcesses(dbCtx, args.ProcessName, args.Status, fetchLimit(args.Limit))
 	if err != nil {
 		h.logger.Error().Err(err).Msg("db.GetBusinessProcesses failed")
 		return nil, fmt.Errorf("failed to get business processes: %w", err)
 	}
 
+	processes, meta := limitResults(processes, args.Limit)
+
+	var stability *processStability
+	if args.IncludeStability {
+		stability = h.fetchProcessStability(ctx, processes)
+	}
+
 	// Use visual formatting for business processes
-	formattedText := formatBusinessProcessesResponse(processes)
+	formattedText := formatBusinessProcessesResponse(processes, meta, args.FullMessages, stability,
+		h.config.DisplayLocation(), h.includeJSON(request))
 
 	h.logger.Info().
 		Int("processes_count", len(processes)).
@@ -834,22 +3158,51 @@ func (h *ToolHandler) handleGetBusinessProcesses(ctx context.Context, request mc
 	}, nil
 }
 
+// getStatistics returns the server statistics, from the cache unless it is disabled
+// (statistics_cache_seconds: 0), fresh is set or the cached result is older than the
+// configured TTL. Also returns the age of cached statistics.
+func (h *ToolHandler) getStatistics(ctx context.Context, fresh bool) (*types.Statistics, time.Duration, error) {
+	ttl := h.config.StatisticsCacheTTL()
+	if ttl <= 0 {
+		stats, err := h.db.GetStatistics(ctx)
+		return stats, 0, err
+	}
+
+	stats, age, err := h.statsCache.get(ctx, ttl, fresh, h.db.GetStatistics)
+	if err == nil && age > 0 {
+		h.logger.Debug().Dur("age", age).Msg("statistics served from cache")
+	}
+
+	return stats, age, err
+}
+
 // handleGetStatistics handles the prtg_get_statistics tool.
 func (h *ToolHandler) handleGetStatistics(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
 	h.logger.Info().Interface("arguments", request.Params.Arguments).Msg("handling prtg_get_statistics")
 
+	var args struct {
+		Fresh bool `json:"fresh"`
+	}
+
+	if err := parseArguments(request.Params.Arguments, &args); err != nil {
+		return nil, fmt.Errorf("invalid arguments: %w", err)
+	}
+
 	// Add timeout to parent context
 	dbCtx, cancel := context.WithTimeout(ctx, 60*time.Second)
 	defer cancel()
 
-	stats, err := h.db.GetStatistics(dbCtx)
+	stats, age, err := h.getStatistics(dbCtx, args.Fresh)
 	if err != nil {
 		h.logger.Error().Err(err).Msg("db.GetStatistics failed")
 		return nil, fmt.Errorf("failed to get statistics: %w", err)
 	}
 
 	// Use visual formatting for statistics
-	formattedText := formatStatisticsResponse(stats)
+	formattedText := formatStatisticsResponse(stats, h.includeJSON(request))
+	if age > 0 {
+		formattedText += fmt.Sprintf("\n_Cached %s ago; use fresh=true for current values._\n", age.Round(time.Second))
+	}
 
 	h.logger.Info().Msg("returning statistics to MCP client")
 
@@ -890,27 +3243,82 @@ func (h *ToolHandler) handleCustomQuery(ctx context.Context, request mcp.CallToo
 	}
 
 	if args.Limit <= 0 {
-		args.Limit = 100
+		args.Limit = types.CustomQueryLimit
 	}
 
+	args.Limit = min(args.Limit, types.MaxCustomQueryRows)
+
 	h.logger.Debug().
 		Str("query", args.Query).
 		Int("limit", args.Limit).
-		Msg("calling db.ExecuteCustomQuery")
+		Msg("calling db.StreamCustomQuery")
 
 	// Add timeout to parent context (preserves cancellation chain)
 	dbCtx, cancel := context.WithTimeout(ctx, 30*time.Second)
 	defer cancel()
 
-	results, err := h.db.ExecuteCustomQuery(dbCtx, args.Query, args.Limit)
+	// Rows are formatted as they are read, the database stops after args.Limit rows
+	var body strings.Builder
+
+	returned := 0
+	row := make(map[string]interface{})
+
+	_, truncated, err := h.db.StreamCustomQuery(dbCtx, args.Query, args.Limit, func(columns []string, values []interface{}) error {
+		clear(row)
+
+		for i, col := range columns {
+			row[col] = values[i]
+		}
+
+		data, err := json.MarshalIndent(row, "  ", "  ")
+		if err != nil {
+			return fmt.Errorf("failed to marshal row: %w", err)
+		}
+
+		if returned > 0 {
+			body.WriteString(",")
+		}
+
+		body.WriteString("\n  ")
+		body.Write(data)
+
+		returned++
+
+		return nil
+	})
 	if err != nil {
-		h.logger.Error().Err(err).Msg("db.ExecuteCustomQuery failed")
+		h.logger.Error().Err(err).Msg("db.StreamCustomQuery failed")
 		return nil, fmt.Errorf("query execution failed: %w", err)
 	}
 
-	h.logger.Debug().Int("result_count", len(results)).Msg("db.ExecuteCustomQuery returned")
+	h.logger.Debug().
+		Int("returned", returned).
+		Bool("truncated", truncated).
+		Msg("db.StreamCustomQuery returned")
+
+	// Rows past the limit are not read, so their number is unknown
+	header := fmt.Sprintf("Found %d result(s):", returned)
+
+	switch {
+	case truncated && returned < types.MaxCustomQueryRows:
+		header = fmt.Sprintf("Found more than %d result(s), showing the first %d (raise limit to see more):", returned, returned)
+	case truncated:
+		header = fmt.Sprintf("Found more than %d result(s), showing the first %d (the maximum, narrow the query to see the rest):",
+			returned, returned)
+	}
+
+	if returned > 0 {
+		body.WriteString("\n")
+	}
 
-	return formatResult(results, len(results))
+	return &mcp.CallToolResult{
+		Content: []mcp.Content{
+			mcp.TextContent{
+				Type: "text",
+				Text: header + "\n\n[" + body.String() + "]",
+			},
+		},
+	}, nil
 }
 
 // parseArguments parses tool arguments from interface{} to target struct.
@@ -923,6 +3331,78 @@ func parseArguments(args, target interface{}) error {
 	return json.Unmarshal(data, target)
 }
 
+// includeJSONProperty is the include_json parameter of the tools whose response ends with the
+// complete data as a JSON block.
+var includeJSONProperty = map[string]interface{}{
+	"type": "boolean",
+	"description": "Append the complete data as a JSON block after the tables and summaries. " +
+		"Set to false to save tokens when the summary is enough (default: server setting, normally true)",
+}
+
+// idPrefixProperty is the id_prefix parameter of the tools taking a PRTG object ID.
+var idPrefixProperty = map[string]interface{}{
+	"type": "string",
+	"description": "Server prefix from database.id_offsets, for databases that offset the IDs of several PRTG servers: " +
+		"the ID is then the one shown in that server's PRTG web interface, as are the IDs in the results",
+}
+
+// idOffsets returns the configured object ID offsets, after checking that prefix is one of
+// them. Without prefix IDs translate as-is.
+func (h *ToolHandler) idOffsets(prefix string) (types.IDOffsets, error) {
+	offsets := h.config.IDOffsets()
+	if _, ok := offsets[prefix]; prefix != "" && !ok {
+		return nil, fmt.Errorf("unknown id_prefix %q, server prefixes are configured in database.id_offsets", prefix)
+	}
+
+	return offsets, nil
+}
+
+// uiSensorIDs translates the sensor and device IDs of sensors into the IDs shown in the PRTG
+// web interface of the server with the given prefix.
+func uiSensorIDs(offsets types.IDOffsets, prefix string, sensors ...*types.Sensor) {
+	for _, sensor := range sensors {
+		sensor.ID = offsets.ToUIID(prefix, sensor.ID)
+		sensor.DeviceID = offsets.ToUIID(prefix, sensor.DeviceID)
+	}
+}
+
+// uiSensorListIDs does the same as uiSensorIDs for a list of sensors.
+func uiSensorListIDs(offsets types.IDOffsets, prefix string, sensors []types.Sensor) {
+	for i := range sensors {
+		uiSensorIDs(offsets, prefix, &sensors[i])
+	}
+}
+
+// uiObjectIDs translates the IDs of a resolved object into the IDs shown in the PRTG web
+// interface of the server with the given prefix.
+func uiObjectIDs(offsets types.IDOffsets, prefix string, object *types.PRTGObject) {
+	switch {
+	case object.Sensor != nil:
+		uiSensorIDs(offsets, prefix, object.Sensor)
+	case object.Device != nil:
+		object.Device.ID = offsets.ToUIID(prefix, object.Device.ID)
+		object.Device.GroupID = offsets.ToUIID(prefix, object.Device.GroupID)
+	case object.Group != nil:
+		object.Group.ID = offsets.ToUIID(prefix, object.Group.ID)
+		if object.Group.ParentID != nil {
+			parentID := offsets.ToUIID(prefix, *object.Group.ParentID)
+			object.Group.ParentID = &parentID
+		}
+	}
+}
+
+// includeJSON reports whether the response to request ends with its data as a JSON block:
+// the include_json argument when given, else the server's include_json_payload setting.
+func (h *ToolHandler) includeJSON(request mcp.CallToolRequest) bool {
+	if args, ok := request.Params.Arguments.(map[string]interface{}); ok {
+		if include, ok := args["include_json"].(bool); ok {
+			return include
+		}
+	}
+
+	return h.config.IncludeJSONPayload()
+}
+
 // formatResult formats the response data as MCP tool result.
 func formatResult(data interface{}, count int) (*mcp.CallToolResult, error) {
 	jsonData, err := json.MarshalIndent(data, "", "  ")
@@ -3,13 +3,21 @@ package handlers
 
 import (
 	"context"
+	"encoding/csv"
+	"errors"
 	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/mark3labs/mcp-go/mcp"
 	"github.com/mark3labs/mcp-go/server"
 
 	"github.com/matthieu/mcp-server-prtg/internal/prtg"
+	"github.com/matthieu/mcp-server-prtg/internal/types"
 )
 
 // PRTGClient interface for PRTG API operations.
@@ -17,6 +25,7 @@ type PRTGClient interface {
 	GetTimeSeries(ctx context.Context, objectID int, timeType prtg.TimeSeriesType) (*prtg.TimeSeriesData, error)
 	GetTimeSeriesCustom(ctx context.Context, objectID int, start, end time.Time) (*prtg.TimeSeriesData, error)
 	GetChannelsBySensor(ctx context.Context, sensorID int) ([]prtg.Channel, error)
+	GetBusinessProcessSources(ctx context.Context, sensorID int) ([]prtg.BusinessProcessSource, error)
 }
 
 // MetricsToolHandler handles MCP tool requests for PRTG metrics/historical data.
@@ -26,6 +35,7 @@ type MetricsToolHandler struct {
 }
 
 // NewMetricsToolHandler creates a new metrics tool handler.
+// prtgClient may be nil when only the database history table is available, see RegisterHistoryTools.
 func NewMetricsToolHandler(prtgClient PRTGClient, mainHandler *ToolHandler) *MetricsToolHandler {
 	return &MetricsToolHandler{
 		prtgClient: prtgClient,
@@ -35,8 +45,150 @@ func NewMetricsToolHandler(prtgClient PRTGClient, mainHandler *ToolHandler) *Met
 
 // RegisterMetricsTools registers all PRTG metrics-related MCP tools.
 func (h *MetricsToolHandler) RegisterMetricsTools(s *server.MCPServer) {
+	h.RegisterHistoryTools(s)
+
+	// Tool 3: prtg_get_channel_current_values
+	addTool(s, h.handler.validator, mcp.Tool{
+		Name: "prtg_get_channel_current_values",
+		Description: "**PRIMARY TOOL for checking sensor current state and discovering available channels.** " +
+			"Returns ALL channels of a sensor with their current values, names, units, and last update time. " +
+			"Each PRTG sensor has multiple channels (measurements). Examples: " +
+			"SSL sensors → 'Days to Expiration', 'Response Time'; " +
+			"Server sensors → 'CPU Load', 'Memory Usage', 'Disk Space'; " +
+			"Network sensors → 'Traffic In', 'Traffic Out', 'Packet Loss'. " +
+			"**ALWAYS use this tool first** when asked about a sensor's current state, values, or status. " +
+			"Use prtg_get_sensor_timeseries only for historical trends. " +
+			"Use channel_name or channel_id to return only specific channels of multi-channel sensors.",
+		InputSchema: mcp.ToolInputSchema{
+			Type: "object",
+			Properties: map[string]interface{}{
+				"sensor_id": map[string]interface{}{
+					"type":        "integer",
+					"description": "PRTG sensor ID",
+				},
+				"channel_name": map[string]interface{}{
+					"type":        "string",
+					"description": "Only return channels whose name contains this text (case-insensitive, e.g. 'CPU Load')",
+				},
+				"channel_id": map[string]interface{}{
+					"type":        "integer",
+					"description": "Only return the channel with this ID (e.g. 0 for the primary channel)",
+				},
+			},
+			Required: []string{"sensor_id"},
+		},
+	}, h.handleGetChannelCurrentValues)
+
+	// Tool 4: prtg_compare_channel
+	addTool(s, h.handler.validator, mcp.Tool{
+		Name: "prtg_compare_channel",
+		Description: "Compare the current value of one channel across many sensors and rank them. " +
+			"Finds the sensors of a group or of devices matching a name pattern, fetches the named channel " +
+			"of each from the PRTG API and returns a ranked table (highest first by default). " +
+			"Use cases: 'compare Response Time across all web servers', 'which device has the highest CPU Load in Production'. " +
+			"Sensors without the channel are listed separately.",
+		InputSchema: mcp.ToolInputSchema{
+			Type: "object",
+			Properties: map[string]interface{}{
+				"channel_name": map[string]interface{}{
+					"type":        "string",
+					"description": "Channel to compare (case-insensitive, e.g. 'Response Time'). An exact name match is preferred over a partial one.",
+				},
+				"group_name": map[string]interface{}{
+					"type":        "string",
+					"description": "Compare sensors of groups whose name contains this text",
+				},
+				"device_name": map[string]interface{}{
+					"type":        "string",
+					"description": "Compare sensors of devices whose name contains this text (e.g. 'web')",
+				},
+				"sensor_name": map[string]interface{}{
+					"type":        "string",
+					"description": "Only compare sensors whose name contains this text (e.g. 'HTTP')",
+				},
+				"sensor_type": map[string]interface{}{
+					"type":        "string",
+					"description": "Only compare sensors of this type (e.g. 'ping')",
+				},
+				"order": map[string]interface{}{
+					"type":        "string",
+					"enum":        []string{"desc", "asc"},
+					"default":     "desc",
+					"description": "Ranking order: 'desc' (highest first) or 'asc' (lowest first)",
+				},
+				"limit": map[string]interface{}{
+					"type":        "integer",
+					"default":     defaultCompareSensors,
+					"minimum":     1,
+					"maximum":     maxCompareSensors,
+					"description": "Maximum number of sensors to compare",
+				},
+			},
+			Required: []string{"channel_name"},
+		},
+	}, h.handleCompareChannel)
+
+	// Tool 5: prtg_get_device_channels
+	addTool(s, h.handler.validator, mcp.Tool{
+		Name: "prtg_get_device_channels",
+		Description: "Get the current value of every channel of every sensor of a device in one call. " +
+			"Loads the device's sensors from the database and fetches their channels from the PRTG API, " +
+			"returning one consolidated table (sensor → channel → value → unit). " +
+			"Use cases: 'show me everything web01 measures right now', full snapshot of a device before a deep-dive. " +
+			"Use prtg_get_channel_current_values for a single sensor.",
+		InputSchema: mcp.ToolInputSchema{
+			Type: "object",
+			Properties: map[string]interface{}{
+				"device_id": map[string]interface{}{
+					"type":        "integer",
+					"description": "PRTG device ID",
+				},
+				"max_sensors": map[string]interface{}{
+					"type":        "integer",
+					"default":     defaultDeviceChannelSensors,
+					"minimum":     1,
+					"maximum":     maxDeviceChannelSensors,
+					"description": "Maximum number of sensors whose channels are fetched (sensors are taken by name)",
+				},
+			},
+			Required: []string{"device_id"},
+		},
+	}, h.handleGetDeviceChannels)
+
+	// Tool 6: prtg_business_process_sources
+	addTool(s, h.handler.validator, mcp.Tool{
+		Name: "prtg_business_process_sources",
+		Description: "Drill down from a Business Process sensor to the source sensors it aggregates. " +
+			"Fetches the source objects of the process from the PRTG API and returns their current status " +
+			"from the database, down sources first. " +
+			"Use cases: 'why is the Webshop business process down', 'which sensors make up this process'. " +
+			"Source IDs unknown to the database are listed as unresolved. " +
+			"Relies on an experimental PRTG API endpoint; PRTG versions without it return an explanatory error.",
+		InputSchema: mcp.ToolInputSchema{
+			Type: "object",
+			Properties: map[string]interface{}{
+				"sensor_id": map[string]interface{}{
+					"type":        "integer",
+					"description": "Business Process sensor ID",
+				},
+				"server_id": map[string]interface{}{
+					"type": "integer",
+					"description": "PRTG server ID (server_id in results), for databases synchronizing several servers " +
+						"whose sensor IDs overlap",
+				},
+				"include_json": includeJSONProperty,
+			},
+			Required: []string{"sensor_id"},
+		},
+	}, h.handleGetBusinessProcessSources)
+}
+
+// RegisterHistoryTools registers the historical time series tools. They read the database
+// history table when configured and fall back to the PRTG API, so they are also registered
+// when only one of the two sources is available.
+func (h *MetricsToolHandler) RegisterHistoryTools(s *server.MCPServer) {
 	// Tool 1: prtg_get_sensor_timeseries
-	s.AddTool(mcp.Tool{
+	addTool(s, h.handler.validator, mcp.Tool{
 		Name: "prtg_get_sensor_timeseries",
 		Description: "Retrieve **HISTORICAL** time series data for analyzing trends over time. " +
 			"Returns time-stamped measurements showing how channel values evolved. " +
@@ -56,13 +208,19 @@ func (h *MetricsToolHandler) RegisterMetricsTools(s *server.MCPServer) {
 					"description": "Time period: 'live' (last minutes), 'short' (last 24h), " +
 						"'medium' (last 7 days), 'long' (last 30+ days)",
 				},
+				"output_format": map[string]interface{}{
+					"type":        "string",
+					"enum":        []string{"markdown", "csv"},
+					"default":     "markdown",
+					"description": "Output format: 'markdown' (readable table, long series are abbreviated) or 'csv' (every data point, for spreadsheet import)",
+				},
 			},
 			Required: []string{"sensor_id", "time_type"},
 		},
 	}, h.handleGetSensorTimeSeries)
 
 	// Tool 2: prtg_get_sensor_history_custom
-	s.AddTool(mcp.Tool{
+	addTool(s, h.handler.validator, mcp.Tool{
 		Name: "prtg_get_sensor_history_custom",
 		Description: "Retrieve **HISTORICAL** data for a specific date/time range. " +
 			"**For CURRENT values, use prtg_get_channel_current_values instead.** " +
@@ -83,40 +241,24 @@ func (h *MetricsToolHandler) RegisterMetricsTools(s *server.MCPServer) {
 					"type":        "string",
 					"description": "End time in RFC3339 format (e.g., '2025-10-31T23:59:59Z')",
 				},
+				"output_format": map[string]interface{}{
+					"type":        "string",
+					"enum":        []string{"markdown", "csv"},
+					"default":     "markdown",
+					"description": "Output format: 'markdown' (readable table, long series are abbreviated) or 'csv' (every data point, for spreadsheet import)",
+				},
 			},
 			Required: []string{"sensor_id", "start_time", "end_time"},
 		},
 	}, h.handleGetSensorHistoryCustom)
-
-	// Tool 3: prtg_get_channel_current_values
-	s.AddTool(mcp.Tool{
-		Name: "prtg_get_channel_current_values",
-		Description: "**PRIMARY TOOL for checking sensor current state and discovering available channels.** " +
-			"Returns ALL channels of a sensor with their current values, names, units, and last update time. " +
-			"Each PRTG sensor has multiple channels (measurements). Examples: " +
-			"SSL sensors → 'Days to Expiration', 'Response Time'; " +
-			"Server sensors → 'CPU Load', 'Memory Usage', 'Disk Space'; " +
-			"Network sensors → 'Traffic In', 'Traffic Out', 'Packet Loss'. " +
-			"**ALWAYS use this tool first** when asked about a sensor's current state, values, or status. " +
-			"Use prtg_get_sensor_timeseries only for historical trends.",
-		InputSchema: mcp.ToolInputSchema{
-			Type: "object",
-			Properties: map[string]interface{}{
-				"sensor_id": map[string]interface{}{
-					"type":        "integer",
-					"description": "PRTG sensor ID",
-				},
-			},
-			Required: []string{"sensor_id"},
-		},
-	}, h.handleGetChannelCurrentValues)
 }
 
 // handleGetSensorTimeSeries handles prtg_get_sensor_timeseries tool requests.
 func (h *MetricsToolHandler) handleGetSensorTimeSeries(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
 	var params struct {
-		SensorID int    `json:"sensor_id"`
-		TimeType string `json:"time_type"`
+		SensorID     int    `json:"sensor_id"`
+		TimeType     string `json:"time_type"`
+		OutputFormat string `json:"output_format"`
 	}
 
 	if err := parseArguments(request.Params.Arguments, &params); err != nil {
@@ -139,20 +281,26 @@ func (h *MetricsToolHandler) handleGetSensorTimeSeries(ctx context.Context, requ
 	h.handler.logger.Info().
 		Int("sensor_id", params.SensorID).
 		Str("time_type", params.TimeType).
-		Msg("Fetching sensor time series from PRTG API")
+		Msg("Fetching sensor time series")
+
+	end := time.Now().UTC()
+	start := end.Add(-timeSeriesRanges[timeType])
 
-	// Fetch data from PRTG API
-	data, err := h.prtgClient.GetTimeSeries(ctx, params.SensorID, timeType)
+	data, err := h.fetchHistory(ctx, params.SensorID, start, end, func(ctx context.Context) (*prtg.TimeSeriesData, error) {
+		return h.prtgClient.GetTimeSeries(ctx, params.SensorID, timeType)
+	})
 	if err != nil {
 		h.handler.logger.Error().
 			Err(err).
 			Int("sensor_id", params.SensorID).
-			Msg("Failed to fetch time series from PRTG API")
-		return mcp.NewToolResultError(fmt.Sprintf("Failed to fetch time series: %v", err)), nil
+			Msg("Failed to fetch time series")
+		return apiErrorResult("fetch time series", err), nil
 	}
 
-	// Format response for LLM
-	formatted := formatTimeSeriesForLLM(data)
+	data.TimeType = timeType
+
+	// Format response for LLM (or as CSV when requested)
+	formatted := formatTimeSeries(data, params.OutputFormat, h.handler.config.DisplayLocation())
 
 	return mcp.NewToolResultText(formatted), nil
 }
@@ -160,9 +308,10 @@ func (h *MetricsToolHandler) handleGetSensorTimeSeries(ctx context.Context, requ
 // handleGetSensorHistoryCustom handles prtg_get_sensor_history_custom tool requests.
 func (h *MetricsToolHandler) handleGetSensorHistoryCustom(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
 	var params struct {
-		SensorID  int    `json:"sensor_id"`
-		StartTime string `json:"start_time"`
-		EndTime   string `json:"end_time"`
+		SensorID     int    `json:"sensor_id"`
+		StartTime    string `json:"start_time"`
+		EndTime      string `json:"end_time"`
+		OutputFormat string `json:"output_format"`
 	}
 
 	if err := parseArguments(request.Params.Arguments, &params); err != nil {
@@ -189,28 +338,80 @@ func (h *MetricsToolHandler) handleGetSensorHistoryCustom(ctx context.Context, r
 		Int("sensor_id", params.SensorID).
 		Time("start", startTime).
 		Time("end", endTime).
-		Msg("Fetching custom time range from PRTG API")
+		Msg("Fetching custom time range")
 
-	// Fetch data from PRTG API
-	data, err := h.prtgClient.GetTimeSeriesCustom(ctx, params.SensorID, startTime, endTime)
+	data, err := h.fetchHistory(ctx, params.SensorID, startTime, endTime, func(ctx context.Context) (*prtg.TimeSeriesData, error) {
+		return h.prtgClient.GetTimeSeriesCustom(ctx, params.SensorID, startTime, endTime)
+	})
 	if err != nil {
 		h.handler.logger.Error().
 			Err(err).
 			Int("sensor_id", params.SensorID).
-			Msg("Failed to fetch custom time series from PRTG API")
-		return mcp.NewToolResultError(fmt.Sprintf("Failed to fetch time series: %v", err)), nil
+			Msg("Failed to fetch custom time series")
+		return apiErrorResult("fetch time series", err), nil
 	}
 
-	// Format response for LLM
-	formatted := formatTimeSeriesForLLM(data)
+	// Format response for LLM (or as CSV when requested)
+	formatted := formatTimeSeries(data, params.OutputFormat, h.handler.config.DisplayLocation())
 
 	return mcp.NewToolResultText(formatted), nil
 }
 
+// apiErrorResult returns the tool error of a failed PRTG API call. An invalid sensor ID is
+// reported as a bad parameter rather than as an API failure.
+func apiErrorResult(action string, err error) *mcp.CallToolResult {
+	if errors.Is(err, prtg.ErrInvalidObjectID) {
+		return mcp.NewToolResultError("Invalid parameters: sensor_id must be greater than 0")
+	}
+
+	return mcp.NewToolResultError(fmt.Sprintf("Failed to %s: %v", action, err))
+}
+
+// timeSeriesRanges is the range read from the database history table for each time_type.
+var timeSeriesRanges = map[prtg.TimeSeriesType]time.Duration{
+	prtg.TimeSeriesLive:   time.Hour,
+	prtg.TimeSeriesShort:  24 * time.Hour,
+	prtg.TimeSeriesMedium: 7 * 24 * time.Hour,
+	prtg.TimeSeriesLong:   30 * 24 * time.Hour,
+}
+
+// fetchHistory returns the sensor history between start and end from the database history
+// table when one is configured, which is faster and does not depend on PRTG being up.
+// It falls back to fetchAPI when the query fails or returns no data points (e.g. a sensor
+// the exporter does not record), unless the PRTG API is not configured.
+func (h *MetricsToolHandler) fetchHistory(ctx context.Context, sensorID int, start, end time.Time,
+	fetchAPI func(context.Context) (*prtg.TimeSeriesData, error)) (*prtg.TimeSeriesData, error) {
+	if h.handler.config.HistoryTable() != "" {
+		dbCtx, cancel := context.WithTimeout(ctx, 30*time.Second)
+		data, err := h.handler.db.GetSensorHistoryFromDB(dbCtx, sensorID, start, end)
+		cancel()
+
+		switch {
+		case h.prtgClient == nil:
+			return data, err
+		case err != nil:
+			h.handler.logger.Warn().Err(err).Int("sensor_id", sensorID).Msg("Database history query failed, falling back to PRTG API")
+		case len(data.DataPoints) == 0:
+			h.handler.logger.Debug().Int("sensor_id", sensorID).Msg("No database history for sensor, falling back to PRTG API")
+		default:
+			h.handler.logger.Debug().Int("sensor_id", sensorID).Int("data_points", len(data.DataPoints)).Msg("Time series read from database history")
+			return data, nil
+		}
+	}
+
+	if h.prtgClient == nil {
+		return nil, fmt.Errorf("no history source available: PRTG API disabled and no database.history_table configured")
+	}
+
+	return fetchAPI(ctx)
+}
+
 // handleGetChannelCurrentValues handles prtg_get_channel_current_values tool requests.
 func (h *MetricsToolHandler) handleGetChannelCurrentValues(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
 	var params struct {
-		SensorID int `json:"sensor_id"`
+		SensorID    int    `json:"sensor_id"`
+		ChannelName string `json:"channel_name"`
+		ChannelID   *int   `json:"channel_id"`
 	}
 
 	if err := parseArguments(request.Params.Arguments, &params); err != nil {
@@ -219,6 +420,7 @@ func (h *MetricsToolHandler) handleGetChannelCurrentValues(ctx context.Context,
 
 	h.handler.logger.Info().
 		Int("sensor_id", params.SensorID).
+		Str("channel_name", params.ChannelName).
 		Msg("Fetching channel current values from PRTG API")
 
 	// Fetch channels from PRTG API
@@ -228,21 +430,618 @@ func (h *MetricsToolHandler) handleGetChannelCurrentValues(ctx context.Context,
 			Err(err).
 			Int("sensor_id", params.SensorID).
 			Msg("Failed to fetch channels from PRTG API")
-		return mcp.NewToolResultError(fmt.Sprintf("Failed to fetch channels: %v", err)), nil
+		return apiErrorResult("fetch channels", err), nil
 	}
 
 	if len(channels) == 0 {
 		return mcp.NewToolResultText(fmt.Sprintf("No channels found for sensor %d", params.SensorID)), nil
 	}
 
+	// Narrow down multi-channel sensors (the PRTG API has no channel filter)
+	if params.ChannelName != "" || params.ChannelID != nil {
+		filtered := filterChannels(channels, params.ChannelName, params.ChannelID)
+		if len(filtered) == 0 {
+			return mcp.NewToolResultText(formatNoMatchingChannels(params.SensorID, channels)), nil
+		}
+
+		channels = filtered
+	}
+
 	// Format response for LLM
-	formatted := formatChannelsForLLM(params.SensorID, channels)
+	formatted := formatChannelsForLLM(params.SensorID, channels, h.handler.config.DisplayLocation())
 
 	return mcp.NewToolResultText(formatted), nil
 }
 
+const (
+	// defaultCompareSensors and maxCompareSensors bound the sensors compared by prtg_compare_channel,
+	// each of them costs one PRTG API call.
+	defaultCompareSensors = 50
+	maxCompareSensors     = 200
+)
+
+// channelReading is the current value of the compared channel for one sensor.
+type channelReading struct {
+	Sensor  types.Sensor
+	Channel string
+	Value   float64
+	Unit    string
+}
+
+// channelComparison is the outcome of comparing a channel across sensors.
+type channelComparison struct {
+	Readings []channelReading
+	Missing  []types.Sensor // Sensors without the channel or without a current value
+	Failed   []types.Sensor // Sensors whose channels could not be fetched
+}
+
+// handleCompareChannel handles prtg_compare_channel tool requests.
+func (h *MetricsToolHandler) handleCompareChannel(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	var params struct {
+		ChannelName string `json:"channel_name"`
+		GroupName   string `json:"group_name"`
+		DeviceName  string `json:"device_name"`
+		SensorName  string `json:"sensor_name"`
+		SensorType  string `json:"sensor_type"`
+		Order       string `json:"order"`
+		Limit       int    `json:"limit"`
+	}
+
+	if err := parseArguments(request.Params.Arguments, &params); err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Invalid parameters: %v", err)), nil
+	}
+
+	if strings.TrimSpace(params.ChannelName) == "" {
+		return mcp.NewToolResultError("channel_name is required"), nil
+	}
+
+	if params.GroupName == "" && params.DeviceName == "" {
+		return mcp.NewToolResultError("group_name or device_name is required"), nil
+	}
+
+	if params.Order == "" {
+		params.Order = "desc"
+	}
+
+	if params.Limit <= 0 {
+		params.Limit = defaultCompareSensors
+	}
+
+	if params.Limit > maxCompareSensors {
+		params.Limit = maxCompareSensors
+	}
+
+	h.handler.logger.Info().
+		Str("channel_name", params.ChannelName).
+		Str("group_name", params.GroupName).
+		Str("device_name", params.DeviceName).
+		Int("limit", params.Limit).
+		Msg("Comparing channel across sensors")
+
+	// Add timeout to parent context (preserves cancellation chain)
+	dbCtx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	filter := types.SensorFilter{
+		GroupName:  params.GroupName,
+		DeviceName: params.DeviceName,
+		SensorName: params.SensorName,
+		SensorType: params.SensorType,
+	}
+
+	// Fetch one extra sensor to know whether the comparison was truncated
+	sensors, err := h.handler.db.GetSensorsExtended(dbCtx, filter, "name", params.Limit+1)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to get sensors: %v", err)), nil
+	}
+
+	truncated := len(sensors) > params.Limit
+	if truncated {
+		sensors = sensors[:params.Limit]
+	}
+
+	if len(sensors) == 0 {
+		return mcp.NewToolResultText("No sensors match the given group/device filter."), nil
+	}
+
+	comparison := h.compareChannel(ctx, sensors, params.ChannelName)
+
+	if len(comparison.Failed) == len(sensors) {
+		return mcp.NewToolResultError("Failed to fetch channels: PRTG API unavailable"), nil
+	}
+
+	rankChannelReadings(comparison.Readings, params.Order == "asc")
+
+	return mcp.NewToolResultText(formatChannelComparison(params.ChannelName, comparison, params.Order, truncated)), nil
+}
+
+// compareChannel fetches the channels of each sensor from the PRTG API, with bounded concurrency,
+// and picks the current value of the named channel. The result keeps the order of sensors.
+func (h *MetricsToolHandler) compareChannel(ctx context.Context, sensors []types.Sensor, channelName string) *channelComparison {
+	apiCtx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	var wg sync.WaitGroup
+
+	readings := make([]*channelReading, len(sensors))
+	failed := make([]bool, len(sensors))
+	sem := make(chan struct{}, channelFetchConcurrency)
+
+	for i, sensor := range sensors {
+		wg.Add(1)
+
+		go func(i int, sensor types.Sensor) {
+			defer wg.Done()
+
+			sem <- struct{}{}
+			defer func() { <-sem }()
+
+			channels, err := h.prtgClient.GetChannelsBySensor(apiCtx, sensor.ID)
+			if err != nil {
+				h.handler.logger.Warn().Err(err).Int("sensor_id", sensor.ID).Msg("failed to fetch channels for comparison")
+				failed[i] = true
+
+				return
+			}
+
+			if ch := matchChannel(channels, channelName); ch != nil && ch.LastMeasurement != nil {
+				readings[i] = &channelReading{
+					Sensor:  sensor,
+					Channel: ch.Name,
+					Value:   ch.LastMeasurement.DisplayValue,
+					Unit:    ch.Basic.DisplayUnit,
+				}
+			}
+		}(i, sensor)
+	}
+
+	wg.Wait()
+
+	comparison := &channelComparison{}
+
+	for i, sensor := range sensors {
+		switch {
+		case failed[i]:
+			comparison.Failed = append(comparison.Failed, sensor)
+		case readings[i] == nil:
+			comparison.Missing = append(comparison.Missing, sensor)
+		default:
+			comparison.Readings = append(comparison.Readings, *readings[i])
+		}
+	}
+
+	return comparison
+}
+
+// matchChannel returns the channel named name (case-insensitive), or else the first channel
+// whose name contains it, or nil.
+func matchChannel(channels []prtg.Channel, name string) *prtg.Channel {
+	for i := range channels {
+		if strings.EqualFold(channels[i].Name, name) {
+			return &channels[i]
+		}
+	}
+
+	if matches := filterChannels(channels, name, nil); len(matches) > 0 {
+		return &matches[0]
+	}
+
+	return nil
+}
+
+// rankChannelReadings sorts readings by value, highest first unless ascending is set.
+// Ties keep the sensor order.
+func rankChannelReadings(readings []channelReading, ascending bool) {
+	sort.SliceStable(readings, func(i, j int) bool {
+		if ascending {
+			return readings[i].Value < readings[j].Value
+		}
+
+		return readings[i].Value > readings[j].Value
+	})
+}
+
+// formatChannelComparison formats a ranked channel comparison in a readable format for LLMs.
+func formatChannelComparison(channelName string, comparison *channelComparison, order string, truncated bool) string {
+	total := len(comparison.Readings) + len(comparison.Missing) + len(comparison.Failed)
+
+	ranking := "highest first"
+	if order == "asc" {
+		ranking = "lowest first"
+	}
+
+	output := fmt.Sprintf("# Channel Comparison - %s\n\n", channelName)
+	output += fmt.Sprintf("Compared %d sensors, ranked %s\n\n", total, ranking)
+
+	if truncated {
+		output += "_More sensors match the filter: narrow it down or increase limit._\n\n"
+	}
+
+	if len(comparison.Readings) == 0 {
+		output += fmt.Sprintf("No sensor has a current value for a channel matching '%s'.\n", channelName)
+	} else {
+		output += "| Rank | Sensor | Device | Channel | Value | Unit |\n"
+		output += "|------|--------|--------|---------|-------|------|\n"
+
+		for i, reading := range comparison.Readings {
+			output += fmt.Sprintf("| %d | %s (ID: %d) | %s | %s | %.2f | %s |\n",
+				i+1,
+				reading.Sensor.Name,
+				reading.Sensor.ID,
+				reading.Sensor.DeviceName,
+				reading.Channel,
+				reading.Value,
+				reading.Unit)
+		}
+	}
+
+	if len(comparison.Missing) > 0 {
+		output += fmt.Sprintf("\n## Without channel '%s' (%d)\n\n", channelName, len(comparison.Missing))
+
+		for _, sensor := range comparison.Missing {
+			output += fmt.Sprintf("- %s (ID: %d) on %s\n", sensor.Name, sensor.ID, sensor.DeviceName)
+		}
+	}
+
+	if len(comparison.Failed) > 0 {
+		output += fmt.Sprintf("\n## Channels unavailable (%d)\n\n", len(comparison.Failed))
+
+		for _, sensor := range comparison.Failed {
+			output += fmt.Sprintf("- %s (ID: %d) on %s\n", sensor.Name, sensor.ID, sensor.DeviceName)
+		}
+	}
+
+	return output
+}
+
+const (
+	// defaultDeviceChannelSensors and maxDeviceChannelSensors bound the sensors whose channels are
+	// fetched by prtg_get_device_channels, each of them costs one PRTG API call.
+	defaultDeviceChannelSensors = 25
+	maxDeviceChannelSensors     = 100
+)
+
+// sensorChannels is the channels of one sensor, or the error that prevented fetching them.
+type sensorChannels struct {
+	Sensor   types.Sensor
+	Channels []prtg.Channel
+	Err      error
+}
+
+// handleGetDeviceChannels handles prtg_get_device_channels tool requests.
+func (h *MetricsToolHandler) handleGetDeviceChannels(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	var params struct {
+		DeviceID   int `json:"device_id"`
+		MaxSensors int `json:"max_sensors"`
+	}
+
+	if err := parseArguments(request.Params.Arguments, &params); err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Invalid parameters: %v", err)), nil
+	}
+
+	if params.DeviceID <= 0 {
+		return mcp.NewToolResultError("device_id must be greater than 0"), nil
+	}
+
+	if params.MaxSensors <= 0 {
+		params.MaxSensors = defaultDeviceChannelSensors
+	}
+
+	if params.MaxSensors > maxDeviceChannelSensors {
+		params.MaxSensors = maxDeviceChannelSensors
+	}
+
+	h.handler.logger.Info().
+		Int("device_id", params.DeviceID).
+		Int("max_sensors", params.MaxSensors).
+		Msg("Fetching channels of all sensors of device")
+
+	// Add timeout to parent context (preserves cancellation chain)
+	dbCtx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	// Fetch one extra sensor to know whether the sensors were truncated
+	sensors, err := h.handler.db.GetSensorsByDeviceID(dbCtx, params.DeviceID, params.MaxSensors+1)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to get sensors of device %d: %v", params.DeviceID, err)), nil
+	}
+
+	if len(sensors) == 0 {
+		return mcp.NewToolResultText(fmt.Sprintf("No sensors found for device %d.", params.DeviceID)), nil
+	}
+
+	truncated := len(sensors) > params.MaxSensors
+	if truncated {
+		sensors = sensors[:params.MaxSensors]
+	}
+
+	results := h.fetchSensorChannels(ctx, sensors)
+
+	failed := 0
+	for _, result := range results {
+		if result.Err != nil {
+			failed++
+		}
+	}
+
+	if failed == len(results) {
+		return mcp.NewToolResultError("Failed to fetch channels: PRTG API unavailable"), nil
+	}
+
+	return mcp.NewToolResultText(formatDeviceChannels(params.DeviceID, results, truncated)), nil
+}
+
+// fetchSensorChannels fetches the channels of each sensor through the channel cache, with bounded
+// concurrency. The result keeps the order of sensors.
+func (h *MetricsToolHandler) fetchSensorChannels(ctx context.Context, sensors []types.Sensor) []sensorChannels {
+	apiCtx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	var wg sync.WaitGroup
+
+	results := make([]sensorChannels, len(sensors))
+	sem := make(chan struct{}, channelFetchConcurrency)
+
+	for i, sensor := range sensors {
+		wg.Add(1)
+
+		go func(i int, sensor types.Sensor) {
+			defer wg.Done()
+
+			sem <- struct{}{}
+			defer func() { <-sem }()
+
+			channels, err := h.handler.channelCache.fetch(apiCtx, h.prtgClient, sensor.ID)
+			if err != nil {
+				h.handler.logger.Warn().Err(err).Int("sensor_id", sensor.ID).Msg("failed to fetch channels of device sensor")
+			}
+
+			results[i] = sensorChannels{Sensor: sensor, Channels: channels, Err: err}
+		}(i, sensor)
+	}
+
+	wg.Wait()
+
+	return results
+}
+
+// formatDeviceChannels formats the channels of a device's sensors as one table in a readable format for LLMs.
+func formatDeviceChannels(deviceID int, results []sensorChannels, truncated bool) string {
+	var unavailable []types.Sensor
+
+	channelCount := 0
+	for _, result := range results {
+		channelCount += len(result.Channels)
+	}
+
+	output := fmt.Sprintf("# Device Channels - %s (ID: %d)\n\n", results[0].Sensor.DeviceName, deviceID)
+	output += fmt.Sprintf("%d channels from %d sensors\n\n", channelCount, len(results))
+
+	if truncated {
+		output += fmt.Sprintf("_Only the first %d sensors are shown: increase max_sensors to see more._\n\n", len(results))
+	}
+
+	output += "| Sensor | Channel | Value | Unit |\n"
+	output += "|--------|---------|-------|------|\n"
+
+	for _, result := range results {
+		if result.Err != nil {
+			unavailable = append(unavailable, result.Sensor)
+			continue
+		}
+
+		sensor := fmt.Sprintf("%s (ID: %d)", result.Sensor.Name, result.Sensor.ID)
+
+		if len(result.Channels) == 0 {
+			output += fmt.Sprintf("| %s | - | - | |\n", sensor)
+			continue
+		}
+
+		for _, ch := range result.Channels {
+			value := "-"
+			if ch.LastMeasurement != nil {
+				value = fmt.Sprintf("%.2f", ch.LastMeasurement.DisplayValue)
+			}
+
+			output += fmt.Sprintf("| %s | %s | %s | %s |\n", sensor, ch.Name, value, ch.Basic.DisplayUnit)
+		}
+	}
+
+	if len(unavailable) > 0 {
+		output += fmt.Sprintf("\n## Channels unavailable (%d)\n\n", len(unavailable))
+
+		for _, sensor := range unavailable {
+			output += fmt.Sprintf("- %s (ID: %d)\n", sensor.Name, sensor.ID)
+		}
+	}
+
+	return output
+}
+
+// handleGetBusinessProcessSources handles prtg_business_process_sources tool requests.
+func (h *MetricsToolHandler) handleGetBusinessProcessSources(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	var params struct {
+		SensorID int  `json:"sensor_id"`
+		ServerID *int `json:"server_id"`
+	}
+
+	if err := parseArguments(request.Params.Arguments, &params); err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Invalid parameters: %v", err)), nil
+	}
+
+	if params.SensorID <= 0 {
+		return mcp.NewToolResultError("sensor_id must be greater than 0"), nil
+	}
+
+	if params.ServerID != nil && *params.ServerID <= 0 {
+		return mcp.NewToolResultError("server_id must be greater than 0"), nil
+	}
+
+	// Add timeout to parent context (preserves cancellation chain)
+	dbCtx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	process, err := h.handler.db.GetSensorByID(dbCtx, params.SensorID, params.ServerID)
+	if err != nil {
+		var ambiguous *types.AmbiguousSensorError
+		if errors.As(err, &ambiguous) {
+			return mcp.NewToolResultError(fmt.Sprintf("Sensor ID %d exists on %d PRTG servers, call again with server_id",
+				params.SensorID, len(ambiguous.Sensors))), nil
+		}
+
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to get business process: %v", err)), nil
+	}
+
+	if !strings.Contains(strings.ToLower(process.SensorType), "business") {
+		return mcp.NewToolResultError(fmt.Sprintf("Sensor %d is not a Business Process sensor (type: %s)",
+			params.SensorID, process.SensorType)), nil
+	}
+
+	h.handler.logger.Info().
+		Int("sensor_id", params.SensorID).
+		Msg("Fetching business process sources from PRTG API")
+
+	rawSources, err := h.prtgClient.GetBusinessProcessSources(ctx, params.SensorID)
+	if err != nil {
+		h.handler.logger.Error().
+			Err(err).
+			Int("sensor_id", params.SensorID).
+			Msg("Failed to fetch business process sources from PRTG API")
+
+		// The sources endpoint is experimental: PRTG versions without it answer 404 like an unknown sensor
+		if errors.Is(err, prtg.ErrNotFound) {
+			return mcp.NewToolResultError(fmt.Sprintf("PRTG did not return the sources of business process %d: "+
+				"this PRTG version may not provide the experimental sources endpoint, or the sensor is unknown to the "+
+				"PRTG server of the API (%v)", params.SensorID, err)), nil
+		}
+
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to fetch business process sources: %v", err)), nil
+	}
+
+	if len(rawSources) == 0 {
+		return mcp.NewToolResultError(fmt.Sprintf("PRTG reported no source objects for business process %d", params.SensorID)), nil
+	}
+
+	// Deduplicate source IDs - the same sensor can contribute to several channels
+	sourceIDs := make([]int, 0, len(rawSources))
+	seen := make(map[int]bool, len(rawSources))
+
+	for _, source := range rawSources {
+		sourceID, err := prtg.ParseObjectID(source.ID)
+		if err != nil {
+			h.handler.logger.Warn().Err(err).Str("source_id", source.ID).Msg("Skipping invalid source ID")
+			continue
+		}
+
+		if !seen[sourceID] {
+			seen[sourceID] = true
+			sourceIDs = append(sourceIDs, sourceID)
+		}
+	}
+
+	// Sources belong to the PRTG server of the process
+	sources, err := h.handler.db.GetSensorsByIDs(dbCtx, process.ServerID, sourceIDs)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to get source sensors: %v", err)), nil
+	}
+
+	result := &types.BusinessProcessSources{
+		Process: *process,
+		Sources: sources,
+	}
+
+	found := make(map[int]bool, len(sources))
+	for i := range sources {
+		found[sources[i].ID] = true
+
+		if isDownStatus(sources[i].Status) {
+			result.DownSources++
+		}
+	}
+
+	// Source IDs known to PRTG but missing from the exporter database
+	for _, sourceID := range sourceIDs {
+		if !found[sourceID] {
+			result.Unresolved = append(result.Unresolved, sourceID)
+		}
+	}
+
+	return mcp.NewToolResultText(formatBusinessProcessSourcesResponse(result, h.handler.includeJSON(request))), nil
+}
+
+// formatTimeSeries formats time series data in the requested output format ("markdown" or "csv"),
+// with timestamps in the display timezone loc.
+func formatTimeSeries(data *prtg.TimeSeriesData, outputFormat string, loc *time.Location) string {
+	if outputFormat == "csv" {
+		return formatTimeSeriesCSV(data, loc)
+	}
+
+	return formatTimeSeriesForLLM(data, loc)
+}
+
+// formatTimeSeriesCSV formats the full time series as CSV for spreadsheet import.
+// Unlike the markdown table, no data points are omitted. Numbers always use a dot decimal
+// separator and missing values are left empty.
+func formatTimeSeriesCSV(data *prtg.TimeSeriesData, loc *time.Location) string {
+	if len(data.DataPoints) == 0 {
+		return fmt.Sprintf("No data available for sensor %d", data.ObjectID)
+	}
+
+	var buf strings.Builder
+	w := csv.NewWriter(&buf)
+
+	// Header row: timestamp + channel names, in the order of data.Headers
+	header := make([]string, len(data.Headers))
+	copy(header, data.Headers)
+	if len(header) == 0 {
+		header = []string{"timestamp"}
+	}
+	_ = w.Write(header)
+
+	for _, point := range data.DataPoints {
+		record := make([]string, len(header))
+		record[0] = formatTimestamp(point.Timestamp, loc, time.RFC3339)
+
+		for j := 1; j < len(header); j++ {
+			record[j] = csvValue(point.Values[header[j]])
+		}
+
+		_ = w.Write(record)
+	}
+
+	w.Flush()
+
+	var output strings.Builder
+	output.WriteString(fmt.Sprintf("# Time Series Data - Sensor %d", data.ObjectID))
+	if data.TimeType != "" {
+		output.WriteString(fmt.Sprintf(" (%s)", data.TimeType))
+	}
+	output.WriteString("\n\n")
+	output.WriteString(fmt.Sprintf("Total data points: %d\n\n", len(data.DataPoints)))
+	output.WriteString(timeSeriesTruncatedNote(data, loc))
+	output.WriteString("```csv\n")
+	output.WriteString(buf.String())
+	output.WriteString("```\n")
+
+	return output.String()
+}
+
+// csvValue formats a channel value as a locale-neutral CSV cell (empty when missing).
+func csvValue(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case string:
+		return v
+	default:
+		return fmt.Sprintf("%v", v)
+	}
+}
+
 // formatTimeSeriesForLLM formats time series data in a readable format for LLMs.
-func formatTimeSeriesForLLM(data *prtg.TimeSeriesData) string {
+func formatTimeSeriesForLLM(data *prtg.TimeSeriesData, loc *time.Location) string {
 	if len(data.DataPoints) == 0 {
 		return fmt.Sprintf("No data available for sensor %d", data.ObjectID)
 	}
@@ -256,22 +1055,35 @@ func formatTimeSeriesForLLM(data *prtg.TimeSeriesData) string {
 		output += fmt.Sprintf("# Time Series Data - Sensor %d\n", data.ObjectID)
 		if data.StartTime != nil && data.EndTime != nil {
 			output += fmt.Sprintf("Period: %s to %s\n\n",
-				data.StartTime.Format("2006-01-02 15:04:05"),
-				data.EndTime.Format("2006-01-02 15:04:05"))
+				formatTimestamp(*data.StartTime, loc, "2006-01-02 15:04:05"),
+				formatTimestamp(*data.EndTime, loc, "2006-01-02 15:04:05 MST"))
 		}
 	}
 
 	// Summary
 	output += fmt.Sprintf("Total data points: %d\n", len(data.DataPoints))
 	output += fmt.Sprintf("Channels: %s\n\n", formatChannelNames(data.Headers))
+	output += timeSeriesTruncatedNote(data, loc)
 
 	// Data table (show first 10 and last 5 if more than 15 points)
 	output += "## Measurements\n\n"
-	output += formatDataTable(data)
+	output += formatDataTable(data, loc)
 
 	return output
 }
 
+// timeSeriesTruncatedNote warns that the oldest data points of a truncated time series are
+// missing, or returns "" when the series is complete.
+func timeSeriesTruncatedNote(data *prtg.TimeSeriesData, loc *time.Location) string {
+	if !data.Truncated || len(data.DataPoints) == 0 {
+		return ""
+	}
+
+	return fmt.Sprintf("⚠️ **Truncated:** too many rows in the history table for this range, "+
+		"data points before %s are missing. Request a shorter range to see them.\n\n",
+		formatTimestamp(data.DataPoints[0].Timestamp, loc, "2006-01-02 15:04:05 MST"))
+}
+
 // formatChannelNames formats channel names from headers (skip first which is timestamp).
 func formatChannelNames(headers []string) string {
 	if len(headers) <= 1 {
@@ -289,8 +1101,8 @@ func formatChannelNames(headers []string) string {
 	return channels
 }
 
-// formatDataTable formats the time series data as a markdown table.
-func formatDataTable(data *prtg.TimeSeriesData) string {
+// formatDataTable formats the time series data as a markdown table, with timestamps in loc.
+func formatDataTable(data *prtg.TimeSeriesData, loc *time.Location) string {
 	if len(data.DataPoints) == 0 {
 		return "No data\n"
 	}
@@ -302,7 +1114,7 @@ func formatDataTable(data *prtg.TimeSeriesData) string {
 	truncated := totalPoints > (showFirst + showLast)
 
 	// Build header row
-	table := "| Timestamp |"
+	table := fmt.Sprintf("| Timestamp (%s) |", timezoneName(loc))
 	for i := 1; i < len(data.Headers); i++ {
 		table += fmt.Sprintf(" %s |", data.Headers[i])
 	}
@@ -323,12 +1135,12 @@ func formatDataTable(data *prtg.TimeSeriesData) string {
 
 	for i := 0; i < pointsToShow && i < totalPoints; i++ {
 		point := data.DataPoints[i]
-		table += fmt.Sprintf("| %s |", point.Timestamp.Format("2006-01-02 15:04:05"))
+		table += fmt.Sprintf("| %s |", formatTimestamp(point.Timestamp, loc, "2006-01-02 15:04:05"))
 
 		for j := 1; j < len(data.Headers); j++ {
 			channelName := data.Headers[j]
-			value := point.Values[channelName]
-			table += fmt.Sprintf(" %v |", formatValue(value))
+			unit := data.Units[channelName]
+			table += fmt.Sprintf(" %s |", formatValueWithUnit(point.Values[channelName], unit.DisplayUnit, unit.UnitType))
 		}
 		table += "\n"
 	}
@@ -344,12 +1156,12 @@ func formatDataTable(data *prtg.TimeSeriesData) string {
 		// Add last N points
 		for i := totalPoints - showLast; i < totalPoints; i++ {
 			point := data.DataPoints[i]
-			table += fmt.Sprintf("| %s |", point.Timestamp.Format("2006-01-02 15:04:05"))
+			table += fmt.Sprintf("| %s |", formatTimestamp(point.Timestamp, loc, "2006-01-02 15:04:05"))
 
 			for j := 1; j < len(data.Headers); j++ {
 				channelName := data.Headers[j]
-				value := point.Values[channelName]
-				table += fmt.Sprintf(" %v |", formatValue(value))
+				unit := data.Units[channelName]
+				table += fmt.Sprintf(" %s |", formatValueWithUnit(point.Values[channelName], unit.DisplayUnit, unit.UnitType))
 			}
 			table += "\n"
 		}
@@ -377,30 +1189,170 @@ func formatValue(value interface{}) string {
 	}
 }
 
+// byteUnits are the suffixes of formatBytes, in powers of 1024.
+var byteUnits = []string{"B", "KB", "MB", "GB", "TB", "PB"}
+
+// formatValueWithUnit formats a channel value according to its PRTG unit type (e.g. "BYTES_MEMORY",
+// "PERCENT", "TIME_RESPONSE"): raw byte counts get a KB/MB/GB suffix, percentages a % sign and
+// durations a readable unit. Other values are followed by their display unit, if any.
+func formatValueWithUnit(value interface{}, unit, unitType string) string {
+	v, ok := value.(float64)
+	if !ok {
+		return formatValue(value)
+	}
+
+	switch kind := strings.ToUpper(strings.ReplaceAll(unitType, "_", "")); {
+	case strings.HasPrefix(kind, "BYTES"):
+		return formatBytes(v)
+	case kind == "PERCENT":
+		return fmt.Sprintf("%.2f %%", v)
+	case kind == "TIMERESPONSE":
+		if math.Abs(v) < 1000 {
+			return fmt.Sprintf("%.1f ms", v)
+		}
+
+		return formatSeconds(v / 1000)
+	case kind == "TIMESECONDS":
+		return formatSeconds(v)
+	case kind == "TIMEHOURS":
+		return formatSeconds(v * 3600)
+	}
+
+	if unit == "" {
+		return fmt.Sprintf("%.2f", v)
+	}
+
+	return fmt.Sprintf("%.2f %s", v, unit)
+}
+
+// formatBytes formats a byte count with a binary unit suffix, e.g. "1.50 MB".
+func formatBytes(bytes float64) string {
+	i := 0
+	for math.Abs(bytes) >= 1024 && i < len(byteUnits)-1 {
+		bytes /= 1024
+		i++
+	}
+
+	if i == 0 {
+		return fmt.Sprintf("%.0f B", bytes)
+	}
+
+	return fmt.Sprintf("%.2f %s", bytes, byteUnits[i])
+}
+
+// formatSeconds formats a duration in seconds: "12.50 s" under a minute, e.g. "3h12m" above.
+func formatSeconds(seconds float64) string {
+	if math.Abs(seconds) < 60 {
+		return fmt.Sprintf("%.2f s", seconds)
+	}
+
+	return formatElapsed(time.Duration(seconds * float64(time.Second)))
+}
+
+// filterChannels returns the channels matching the name substring (case-insensitive)
+// and/or the exact channel ID. Empty name and nil ID match everything.
+func filterChannels(channels []prtg.Channel, name string, channelID *int) []prtg.Channel {
+	name = strings.ToLower(name)
+	filtered := []prtg.Channel{}
+
+	for _, ch := range channels {
+		if name != "" && !strings.Contains(strings.ToLower(ch.Name), name) {
+			continue
+		}
+
+		if channelID != nil && !channelIDMatches(ch.ID, *channelID) {
+			continue
+		}
+
+		filtered = append(filtered, ch)
+	}
+
+	return filtered
+}
+
+// channelIDMatches compares a channel ID against the PRTG v2 channel ID format "<sensor_id>.<channel_id>".
+func channelIDMatches(id string, channelID int) bool {
+	if idx := strings.LastIndex(id, "."); idx != -1 {
+		id = id[idx+1:]
+	}
+
+	return id == strconv.Itoa(channelID)
+}
+
+// formatNoMatchingChannels explains that the channel filter matched nothing and lists the available channels.
+func formatNoMatchingChannels(sensorID int, channels []prtg.Channel) string {
+	output := fmt.Sprintf("No channel of sensor %d matches the filter.\n\n", sensorID)
+	output += "Available channels:\n"
+
+	for _, ch := range channels {
+		output += fmt.Sprintf("- %s (ID: %s)\n", ch.Name, ch.ID)
+	}
+
+	return output
+}
+
 // formatChannelsForLLM formats channel data in a readable format for LLMs.
-func formatChannelsForLLM(sensorID int, channels []prtg.Channel) string {
+func formatChannelsForLLM(sensorID int, channels []prtg.Channel, loc *time.Location) string {
 	output := fmt.Sprintf("# Current Channel Values - Sensor %d\n\n", sensorID)
 	output += fmt.Sprintf("Total channels: %d\n\n", len(channels))
 
-	output += "| Channel | Value | Unit | Timestamp |\n"
-	output += "|---------|-------|------|----------|\n"
+	output += fmt.Sprintf("| Channel | Value | Timestamp (%s) |\n", timezoneName(loc))
+	output += "|---------|-------|-----------|\n"
 
 	for _, ch := range channels {
 		value := "N/A"
 		timestamp := "-"
-		unit := ch.Basic.DisplayUnit
 
 		if ch.LastMeasurement != nil {
-			value = fmt.Sprintf("%.2f", ch.LastMeasurement.DisplayValue)
+			value = formatChannelValue(ch)
 			timestamp = ch.LastMeasurement.Timestamp
+			if t, err := time.Parse(time.RFC3339, timestamp); err == nil {
+				timestamp = formatTimestamp(t, loc, "2006-01-02 15:04:05")
+			}
 		}
 
-		output += fmt.Sprintf("| %s | %s | %s | %s |\n",
+		output += fmt.Sprintf("| %s | %s | %s |\n",
 			ch.Name,
 			value,
-			unit,
 			timestamp)
 	}
 
 	return output
 }
+
+// formatChannelValue formats the last measurement of a channel with its unit. Byte channels
+// are formatted from the raw value, as their display value is already scaled to the display unit.
+func formatChannelValue(ch prtg.Channel) string {
+	value := ch.LastMeasurement.DisplayValue
+	if strings.HasPrefix(strings.ToUpper(ch.Basic.UnitType), "BYTES") {
+		value = ch.LastMeasurement.Value
+	}
+
+	return formatValueWithUnit(value, ch.Basic.DisplayUnit, ch.Basic.UnitType)
+}
+
+// maxKeyChannels is the number of channels summarized per sensor in device overviews.
+const maxKeyChannels = 2
+
+// summarizeChannels returns the first measured channel values of a sensor, e.g. "Total: 95.00 %".
+func summarizeChannels(channels []prtg.Channel) string {
+	parts := make([]string, 0, maxKeyChannels)
+
+	for _, ch := range channels {
+		if ch.LastMeasurement == nil {
+			continue
+		}
+
+		value := fmt.Sprintf("%s: %.2f", ch.Name, ch.LastMeasurement.DisplayValue)
+		if ch.Basic.DisplayUnit != "" {
+			value += " " + ch.Basic.DisplayUnit
+		}
+
+		parts = append(parts, value)
+		if len(parts) == maxKeyChannels {
+			break
+		}
+	}
+
+	return strings.Join(parts, "; ")
+}
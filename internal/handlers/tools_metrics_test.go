@@ -0,0 +1,997 @@
+package handlers
+
+import (
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/matthieu/mcp-server-prtg/internal/prtg"
+	"github.com/matthieu/mcp-server-prtg/internal/types"
+)
+
+// MockPRTGClient is a mock implementation of PRTGClient interface
+type MockPRTGClient struct {
+	mock.Mock
+}
+
+func (m *MockPRTGClient) GetTimeSeries(ctx context.Context, objectID int, timeType prtg.TimeSeriesType) (*prtg.TimeSeriesData, error) {
+	args := m.Called(ctx, objectID, timeType)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*prtg.TimeSeriesData), args.Error(1)
+}
+
+func (m *MockPRTGClient) GetTimeSeriesCustom(ctx context.Context, objectID int, start, end time.Time) (*prtg.TimeSeriesData, error) {
+	args := m.Called(ctx, objectID, start, end)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*prtg.TimeSeriesData), args.Error(1)
+}
+
+func (m *MockPRTGClient) GetChannelsBySensor(ctx context.Context, sensorID int) ([]prtg.Channel, error) {
+	args := m.Called(ctx, sensorID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]prtg.Channel), args.Error(1)
+}
+
+func (m *MockPRTGClient) GetBusinessProcessSources(ctx context.Context, sensorID int) ([]prtg.BusinessProcessSource, error) {
+	args := m.Called(ctx, sensorID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]prtg.BusinessProcessSource), args.Error(1)
+}
+
+// Helper to create a metrics handler backed by mocks
+func newTestMetricsHandler(mockDB *MockDB, mockClient *MockPRTGClient) *MetricsToolHandler {
+	handler := NewToolHandler(mockDB, &MockConfig{allowCustomQueries: false}, newTestLogger())
+	return NewMetricsToolHandler(mockClient, handler)
+}
+
+// Helper to extract the text of a tool result
+func resultText(t *testing.T, result *mcp.CallToolResult) string {
+	t.Helper()
+
+	if !assert.NotNil(t, result) || !assert.NotEmpty(t, result.Content) {
+		return ""
+	}
+
+	textContent, ok := result.Content[0].(mcp.TextContent)
+	assert.True(t, ok)
+
+	return textContent.Text
+}
+
+func TestRegisterMetricsTools(t *testing.T) {
+	handler := newTestMetricsHandler(new(MockDB), new(MockPRTGClient))
+	s := server.NewMCPServer("test", "1.0.0", server.WithToolCapabilities(false))
+
+	handler.RegisterMetricsTools(s)
+	tools := s.ListTools()
+
+	assert.Len(t, tools, 6)
+
+	for _, name := range []string{
+		"prtg_get_sensor_timeseries",
+		"prtg_get_sensor_history_custom",
+		"prtg_get_channel_current_values",
+		"prtg_compare_channel",
+		"prtg_get_device_channels",
+		"prtg_business_process_sources",
+	} {
+		assert.Contains(t, tools, name)
+	}
+}
+
+// Test handleGetBusinessProcessSources
+func TestHandleGetBusinessProcessSources(t *testing.T) {
+	process := &types.Sensor{
+		ID:         5000,
+		ServerID:   1,
+		Name:       "Webshop Checkout",
+		SensorType: "Business Process",
+		Status:     types.StatusDown,
+		StatusText: "Down",
+	}
+
+	t.Run("Several sources with some down", func(t *testing.T) {
+		mockDB := new(MockDB)
+		mockClient := new(MockPRTGClient)
+		handler := newTestMetricsHandler(mockDB, mockClient)
+
+		mockDB.On("GetSensorByID", mock.Anything, 5000, (*int)(nil)).Return(process, nil)
+		mockClient.On("GetBusinessProcessSources", mock.Anything, 5000).Return([]prtg.BusinessProcessSource{
+			{ID: "101", Name: "Web Ping", Channel: "Frontend"},
+			{ID: "102", Name: "DB Port", Channel: "Backend"},
+			{ID: "103", Name: "Payment API", Channel: "Backend"},
+			{ID: "101", Name: "Web Ping", Channel: "Backend"}, // duplicate across channels
+			{ID: "104", Name: "Cache", Channel: "Backend"},
+		}, nil)
+		mockDB.On("GetSensorsByIDs", mock.Anything, 1, []int{101, 102, 103, 104}).Return([]types.Sensor{
+			{ID: 102, Name: "DB Port", Status: types.StatusDown, StatusText: "Down"},
+			{ID: 103, Name: "Payment API", Status: types.StatusDownPartial, StatusText: "Down (Partial)"},
+			{ID: 101, Name: "Web Ping", Status: types.StatusUp, StatusText: "Up"},
+		}, nil)
+
+		request := createTestRequest(map[string]interface{}{
+			"sensor_id": float64(5000),
+		})
+
+		result, err := handler.handleGetBusinessProcessSources(context.Background(), request)
+		assert.NoError(t, err)
+		assert.False(t, result.IsError)
+
+		text := resultText(t, result)
+		assert.Contains(t, text, "Webshop Checkout")
+		assert.Contains(t, text, "**2 source sensor(s) down**")
+		assert.Contains(t, text, "**DB Port**")
+		assert.Contains(t, text, "**Payment API**")
+		assert.NotContains(t, text, "**Web Ping**")
+		assert.Contains(t, text, `"down_sources": 2`)
+		assert.Contains(t, text, `"unresolved_source_ids": [`)
+
+		mockDB.AssertExpectations(t)
+		mockClient.AssertExpectations(t)
+	})
+
+	t.Run("All sources up", func(t *testing.T) {
+		mockDB := new(MockDB)
+		mockClient := new(MockPRTGClient)
+		handler := newTestMetricsHandler(mockDB, mockClient)
+
+		mockDB.On("GetSensorByID", mock.Anything, 5000, (*int)(nil)).Return(process, nil)
+		mockClient.On("GetBusinessProcessSources", mock.Anything, 5000).Return([]prtg.BusinessProcessSource{
+			{ID: "101", Name: "Web Ping"},
+		}, nil)
+		mockDB.On("GetSensorsByIDs", mock.Anything, 1, []int{101}).Return([]types.Sensor{
+			{ID: 101, Name: "Web Ping", Status: types.StatusUp, StatusText: "Up"},
+		}, nil)
+
+		request := createTestRequest(map[string]interface{}{
+			"sensor_id": float64(5000),
+		})
+
+		result, err := handler.handleGetBusinessProcessSources(context.Background(), request)
+		assert.NoError(t, err)
+		assert.Contains(t, resultText(t, result), "No source sensors are down")
+	})
+
+	t.Run("Not a business process sensor", func(t *testing.T) {
+		mockDB := new(MockDB)
+		mockClient := new(MockPRTGClient)
+		handler := newTestMetricsHandler(mockDB, mockClient)
+
+		mockDB.On("GetSensorByID", mock.Anything, 42, (*int)(nil)).Return(&types.Sensor{ID: 42, SensorType: "ping"}, nil)
+
+		request := createTestRequest(map[string]interface{}{
+			"sensor_id": float64(42),
+		})
+
+		result, err := handler.handleGetBusinessProcessSources(context.Background(), request)
+		assert.NoError(t, err)
+		assert.True(t, result.IsError)
+		assert.Contains(t, resultText(t, result), "not a Business Process sensor")
+
+		mockClient.AssertNotCalled(t, "GetBusinessProcessSources")
+	})
+
+	t.Run("PRTG API error", func(t *testing.T) {
+		mockDB := new(MockDB)
+		mockClient := new(MockPRTGClient)
+		handler := newTestMetricsHandler(mockDB, mockClient)
+
+		mockDB.On("GetSensorByID", mock.Anything, 5000, (*int)(nil)).Return(process, nil)
+		mockClient.On("GetBusinessProcessSources", mock.Anything, 5000).Return(nil, errors.New("connection refused"))
+
+		request := createTestRequest(map[string]interface{}{
+			"sensor_id": float64(5000),
+		})
+
+		result, err := handler.handleGetBusinessProcessSources(context.Background(), request)
+		assert.NoError(t, err)
+		assert.True(t, result.IsError)
+
+		mockDB.AssertNotCalled(t, "GetSensorsByIDs")
+	})
+
+	t.Run("Sources endpoint not available", func(t *testing.T) {
+		mockDB := new(MockDB)
+		mockClient := new(MockPRTGClient)
+		handler := newTestMetricsHandler(mockDB, mockClient)
+
+		mockDB.On("GetSensorByID", mock.Anything, 5000, (*int)(nil)).Return(process, nil)
+		mockClient.On("GetBusinessProcessSources", mock.Anything, 5000).
+			Return(nil, fmt.Errorf("%w: Not Found", prtg.ErrNotFound))
+
+		result, err := handler.handleGetBusinessProcessSources(context.Background(), createTestRequest(map[string]interface{}{
+			"sensor_id": float64(5000),
+		}))
+		assert.NoError(t, err)
+		assert.True(t, result.IsError)
+		assert.Contains(t, resultText(t, result), "may not provide the experimental sources endpoint")
+
+		mockDB.AssertNotCalled(t, "GetSensorsByIDs")
+	})
+
+	t.Run("No sources reported", func(t *testing.T) {
+		mockDB := new(MockDB)
+		mockClient := new(MockPRTGClient)
+		handler := newTestMetricsHandler(mockDB, mockClient)
+
+		mockDB.On("GetSensorByID", mock.Anything, 5000, (*int)(nil)).Return(process, nil)
+		mockClient.On("GetBusinessProcessSources", mock.Anything, 5000).Return([]prtg.BusinessProcessSource{}, nil)
+
+		result, err := handler.handleGetBusinessProcessSources(context.Background(), createTestRequest(map[string]interface{}{
+			"sensor_id": float64(5000),
+		}))
+		assert.NoError(t, err)
+		assert.True(t, result.IsError)
+		assert.Contains(t, resultText(t, result), "PRTG reported no source objects for business process 5000")
+
+		mockDB.AssertNotCalled(t, "GetSensorsByIDs")
+	})
+
+	t.Run("Process of another server", func(t *testing.T) {
+		mockDB := new(MockDB)
+		mockClient := new(MockPRTGClient)
+		handler := newTestMetricsHandler(mockDB, mockClient)
+
+		serverID := 2
+		other := *process
+		other.ServerID = serverID
+
+		// Sources are looked up on the server of the process only
+		mockDB.On("GetSensorByID", mock.Anything, 5000, &serverID).Return(&other, nil)
+		mockClient.On("GetBusinessProcessSources", mock.Anything, 5000).Return([]prtg.BusinessProcessSource{{ID: "101"}}, nil)
+		mockDB.On("GetSensorsByIDs", mock.Anything, 2, []int{101}).Return([]types.Sensor{
+			{ID: 101, ServerID: 2, Name: "Web Ping", Status: types.StatusUp, StatusText: "Up"},
+		}, nil)
+
+		result, err := handler.handleGetBusinessProcessSources(context.Background(), createTestRequest(map[string]interface{}{
+			"sensor_id": float64(5000),
+			"server_id": float64(2),
+		}))
+		assert.NoError(t, err)
+		assert.False(t, result.IsError)
+
+		mockDB.AssertExpectations(t)
+	})
+
+	t.Run("Process ID on several servers", func(t *testing.T) {
+		mockDB := new(MockDB)
+		mockClient := new(MockPRTGClient)
+		handler := newTestMetricsHandler(mockDB, mockClient)
+
+		mockDB.On("GetSensorByID", mock.Anything, 5000, (*int)(nil)).Return(nil, &types.AmbiguousSensorError{
+			SensorID: 5000,
+			Sensors:  []types.Sensor{{ID: 5000, ServerID: 1}, {ID: 5000, ServerID: 2}},
+		})
+
+		result, err := handler.handleGetBusinessProcessSources(context.Background(), createTestRequest(map[string]interface{}{
+			"sensor_id": float64(5000),
+		}))
+		assert.NoError(t, err)
+		assert.True(t, result.IsError)
+		assert.Contains(t, resultText(t, result), "exists on 2 PRTG servers, call again with server_id")
+
+		mockClient.AssertNotCalled(t, "GetBusinessProcessSources")
+	})
+
+	t.Run("Invalid sensor ID", func(t *testing.T) {
+		mockDB := new(MockDB)
+		mockClient := new(MockPRTGClient)
+		handler := newTestMetricsHandler(mockDB, mockClient)
+
+		request := createTestRequest(map[string]interface{}{
+			"sensor_id": float64(0),
+		})
+
+		result, err := handler.handleGetBusinessProcessSources(context.Background(), request)
+		assert.NoError(t, err)
+		assert.True(t, result.IsError)
+
+		mockDB.AssertNotCalled(t, "GetSensorByID")
+	})
+}
+
+// Test handleGetChannelCurrentValues channel filters
+func TestHandleGetChannelCurrentValues_ChannelFilter(t *testing.T) {
+	channels := []prtg.Channel{
+		{ID: "2001.0", Name: "Total", Basic: prtg.ChannelBasic{DisplayUnit: "%"}},
+		{ID: "2001.1", Name: "CPU Load", Basic: prtg.ChannelBasic{DisplayUnit: "%"},
+			LastMeasurement: &prtg.ChannelMeasurement{DisplayValue: 42.5, Timestamp: "2025-01-15T10:00:00Z"}},
+		{ID: "2001.2", Name: "Memory Usage", Basic: prtg.ChannelBasic{DisplayUnit: "MB"}},
+	}
+
+	run := func(t *testing.T, args map[string]interface{}) string {
+		t.Helper()
+
+		mockClient := new(MockPRTGClient)
+		handler := newTestMetricsHandler(new(MockDB), mockClient)
+
+		mockClient.On("GetChannelsBySensor", mock.Anything, 2001).Return(channels, nil)
+
+		args["sensor_id"] = float64(2001)
+		result, err := handler.handleGetChannelCurrentValues(context.Background(), createTestRequest(args))
+		assert.NoError(t, err)
+		assert.False(t, result.IsError)
+
+		mockClient.AssertExpectations(t)
+
+		return resultText(t, result)
+	}
+
+	t.Run("No filter returns all channels", func(t *testing.T) {
+		text := run(t, map[string]interface{}{})
+		assert.Contains(t, text, "Total channels: 3")
+		assert.Contains(t, text, "| Memory Usage |")
+	})
+
+	t.Run("Name filter is case-insensitive substring", func(t *testing.T) {
+		text := run(t, map[string]interface{}{"channel_name": "cpu"})
+		assert.Contains(t, text, "Total channels: 1")
+		assert.Contains(t, text, "| CPU Load | 42.50 % |")
+		assert.NotContains(t, text, "Memory Usage")
+	})
+
+	t.Run("ID filter matches channel part of ID", func(t *testing.T) {
+		text := run(t, map[string]interface{}{"channel_id": float64(2)})
+		assert.Contains(t, text, "Total channels: 1")
+		assert.Contains(t, text, "| Memory Usage |")
+	})
+
+	t.Run("Channel ID 0 is a valid filter", func(t *testing.T) {
+		text := run(t, map[string]interface{}{"channel_id": float64(0)})
+		assert.Contains(t, text, "| Total |")
+		assert.NotContains(t, text, "CPU Load")
+	})
+
+	t.Run("Non-matching filter lists available channels", func(t *testing.T) {
+		text := run(t, map[string]interface{}{"channel_name": "Disk"})
+		assert.Contains(t, text, "No channel of sensor 2001 matches the filter")
+		assert.Contains(t, text, "- CPU Load (ID: 2001.1)")
+		assert.Contains(t, text, "- Memory Usage (ID: 2001.2)")
+	})
+}
+
+// Test prtg_device_overview channel values enrichment
+func TestHandleMetricsTools_InvalidSensorID(t *testing.T) {
+	mockClient := new(MockPRTGClient)
+	invalid := fmt.Errorf("%w 0: must be greater than 0", prtg.ErrInvalidObjectID)
+
+	mockClient.On("GetChannelsBySensor", mock.Anything, 0).Return(nil, invalid)
+	mockClient.On("GetTimeSeries", mock.Anything, 0, prtg.TimeSeriesLive).Return(nil, invalid)
+
+	handler := newTestMetricsHandler(new(MockDB), mockClient)
+	request := createTestRequest(map[string]interface{}{"sensor_id": float64(0), "time_type": "live"})
+
+	result, err := handler.handleGetChannelCurrentValues(context.Background(), request)
+	require.NoError(t, err)
+	assert.True(t, result.IsError)
+	assert.Equal(t, "Invalid parameters: sensor_id must be greater than 0", resultText(t, result))
+
+	result, err = handler.handleGetSensorTimeSeries(context.Background(), request)
+	require.NoError(t, err)
+	assert.True(t, result.IsError)
+	assert.Equal(t, "Invalid parameters: sensor_id must be greater than 0", resultText(t, result))
+}
+
+func TestHandleDeviceOverview_IncludeChannels(t *testing.T) {
+	overview := &types.DeviceOverview{
+		Device: types.Device{ID: 10, Name: "Server1"},
+		Sensors: []types.Sensor{
+			{ID: 1, Name: "Ping", Status: types.StatusUp, Priority: 5},
+			{ID: 2, Name: "CPU Load", Status: types.StatusDown, Priority: 3},
+			{ID: 3, Name: "Disk Free", Status: types.StatusWarning, Priority: 4},
+		},
+		TotalSensors: 3,
+	}
+
+	cpuChannels := []prtg.Channel{
+		{
+			ID:              "2.0",
+			Name:            "Total",
+			Basic:           prtg.ChannelBasic{DisplayUnit: "%"},
+			LastMeasurement: &prtg.ChannelMeasurement{DisplayValue: 95},
+		},
+	}
+
+	request := func(limit int) mcp.CallToolRequest {
+		return createTestRequest(map[string]interface{}{
+			"device_name":      "Server1",
+			"include_channels": true,
+			"channels_limit":   float64(limit),
+		})
+	}
+
+	t.Run("embeds values for key sensors within limit", func(t *testing.T) {
+		mockDB := new(MockDB)
+		mockClient := new(MockPRTGClient)
+
+		handler := NewToolHandler(mockDB, &MockConfig{}, newTestLogger())
+		handler.SetPRTGClient(mockClient)
+
+		mockDB.On("GetDeviceOverview", mock.Anything, "Server1").Return(overview, nil)
+		mockClient.On("GetChannelsBySensor", mock.Anything, 2).Return(cpuChannels, nil)
+		mockClient.On("GetChannelsBySensor", mock.Anything, 3).Return([]prtg.Channel{}, nil)
+
+		result, err := handler.handleDeviceOverview(context.Background(), request(2))
+		assert.NoError(t, err)
+
+		text := resultText(t, result)
+		assert.Contains(t, text, "| Key Values |")
+		assert.Contains(t, text, "Total: 95.00 %")
+
+		mockClient.AssertExpectations(t)
+		mockClient.AssertNotCalled(t, "GetChannelsBySensor", mock.Anything, 1)
+	})
+
+	t.Run("falls back to database-only overview when API fails", func(t *testing.T) {
+		mockDB := new(MockDB)
+		mockClient := new(MockPRTGClient)
+
+		handler := NewToolHandler(mockDB, &MockConfig{}, newTestLogger())
+		handler.SetPRTGClient(mockClient)
+
+		mockDB.On("GetDeviceOverview", mock.Anything, "Server1").Return(overview, nil)
+		mockClient.On("GetChannelsBySensor", mock.Anything, mock.Anything).Return(nil, errors.New("connection refused"))
+
+		result, err := handler.handleDeviceOverview(context.Background(), request(5))
+		assert.NoError(t, err)
+
+		text := resultText(t, result)
+		assert.Contains(t, text, "Channel values unavailable (PRTG API unavailable)")
+		assert.NotContains(t, text, "| Key Values |")
+		assert.Contains(t, text, "CPU Load")
+	})
+
+	t.Run("without PRTG client", func(t *testing.T) {
+		mockDB := new(MockDB)
+		handler := NewToolHandler(mockDB, &MockConfig{}, newTestLogger())
+
+		mockDB.On("GetDeviceOverview", mock.Anything, "Server1").Return(overview, nil)
+
+		result, err := handler.handleDeviceOverview(context.Background(), request(5))
+		assert.NoError(t, err)
+		assert.Contains(t, resultText(t, result), "Channel values unavailable (PRTG API not configured)")
+	})
+}
+
+func TestHandleGetBusinessProcesses_Stability(t *testing.T) {
+	up := 7200.0
+	down := 600.0
+	processes := []types.Sensor{
+		{ID: 501, Name: "Webshop", Status: types.StatusUp, StatusText: "Up", UptimeSinceSecs: &up},
+		{ID: 502, Name: "Payments", Status: types.StatusDown, StatusText: "Down", DowntimeSinceSecs: &down},
+	}
+
+	// 3 consecutive points with downtime are one dip
+	history := func(downtimes ...interface{}) *prtg.TimeSeriesData {
+		data := &prtg.TimeSeriesData{Headers: []string{"timestamp", "Downtime", "Business Process"}}
+		for i, value := range downtimes {
+			data.DataPoints = append(data.DataPoints, prtg.TimeSeriesDataPoint{
+				Timestamp: time.Date(2026, 1, 1, i, 0, 0, 0, time.UTC),
+				Values:    map[string]interface{}{"Downtime": value, "Business Process": 100.0},
+			})
+		}
+
+		return data
+	}
+
+	t.Run("database only", func(t *testing.T) {
+		mockDB := new(MockDB)
+		mockClient := new(MockPRTGClient)
+
+		handler := NewToolHandler(mockDB, &MockConfig{}, newTestLogger())
+		handler.SetPRTGClient(mockClient)
+
+		mockDB.On("GetBusinessProcesses", mock.Anything, "", (*int)(nil), 101).Return(processes, nil)
+
+		result, err := handler.handleGetBusinessProcesses(context.Background(), createTestRequest(map[string]interface{}{}))
+		require.NoError(t, err)
+
+		text := resultText(t, result)
+		assert.Contains(t, text, "| ID | Name | Status | Since | Priority |")
+		assert.Contains(t, text, "| 501 | Webshop | 🟢 Up | up 2.0h |")
+		assert.Contains(t, text, "| 502 | Payments | 🔴 Down | down 10m |")
+		assert.NotContains(t, text, "Stability")
+		mockClient.AssertNotCalled(t, "GetTimeSeries", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("with stability", func(t *testing.T) {
+		mockDB := new(MockDB)
+		mockClient := new(MockPRTGClient)
+
+		handler := NewToolHandler(mockDB, &MockConfig{}, newTestLogger())
+		handler.SetPRTGClient(mockClient)
+
+		mockDB.On("GetBusinessProcesses", mock.Anything, "", (*int)(nil), 101).Return(processes, nil)
+		mockClient.On("GetTimeSeries", mock.Anything, 501, prtg.TimeSeriesShort).Return(history(0.0, 0.0, nil), nil)
+		mockClient.On("GetTimeSeries", mock.Anything, 502, prtg.TimeSeriesShort).
+			Return(history(0.0, 25.0, 100.0, 0.0, 50.0, 0.0, 100.0), nil)
+
+		result, err := handler.handleGetBusinessProcesses(context.Background(),
+			createTestRequest(map[string]interface{}{"include_stability": true}))
+		require.NoError(t, err)
+
+		text := resultText(t, result)
+		assert.Contains(t, text, "| Since | Stability | Priority |")
+		assert.Contains(t, text, "| up 2.0h | stable over last 24h |")
+		assert.Contains(t, text, "| down 10m | 3 dips in last 24h |")
+		mockClient.AssertExpectations(t)
+	})
+
+	t.Run("PRTG API unavailable", func(t *testing.T) {
+		mockDB := new(MockDB)
+		mockClient := new(MockPRTGClient)
+
+		handler := NewToolHandler(mockDB, &MockConfig{}, newTestLogger())
+		handler.SetPRTGClient(mockClient)
+
+		mockDB.On("GetBusinessProcesses", mock.Anything, "", (*int)(nil), 101).Return(processes, nil)
+		mockClient.On("GetTimeSeries", mock.Anything, mock.Anything, mock.Anything).Return(nil, errors.New("connection refused"))
+
+		result, err := handler.handleGetBusinessProcesses(context.Background(),
+			createTestRequest(map[string]interface{}{"include_stability": true}))
+		require.NoError(t, err)
+
+		text := resultText(t, result)
+		assert.Contains(t, text, "Stability unavailable (PRTG API unavailable)")
+		assert.Contains(t, text, "| 501 | Webshop | 🟢 Up | up 2.0h | 0 |")
+	})
+}
+
+// Test key sensor selection: down first, then warning, then by priority
+func TestSelectKeySensors(t *testing.T) {
+	sensors := []types.Sensor{
+		{ID: 1, Status: types.StatusUp, Priority: 5},
+		{ID: 2, Status: types.StatusWarning, Priority: 2},
+		{ID: 3, Status: types.StatusDown, Priority: 1},
+		{ID: 4, Status: types.StatusWarning, Priority: 4},
+	}
+
+	selected := selectKeySensors(sensors, 3)
+
+	ids := make([]int, 0, len(selected))
+	for _, sensor := range selected {
+		ids = append(ids, sensor.ID)
+	}
+
+	assert.Equal(t, []int{3, 4, 2}, ids)
+	assert.Equal(t, 1, sensors[0].ID, "input must not be reordered")
+}
+
+// Test formatTimeSeriesCSV
+func TestFormatTimeSeriesCSV(t *testing.T) {
+	start := time.Date(2025, 10, 26, 12, 0, 0, 0, time.UTC)
+	data := &prtg.TimeSeriesData{
+		ObjectID: 1234,
+		TimeType: prtg.TimeSeriesShort,
+		Headers:  []string{"timestamp", "Response Time", "Traffic In, Total"},
+	}
+
+	// More points than the markdown table shows, to check nothing is truncated
+	for i := 0; i < 20; i++ {
+		var traffic interface{} = float64(i) + 0.25
+		if i == 3 {
+			traffic = nil
+		}
+
+		data.DataPoints = append(data.DataPoints, prtg.TimeSeriesDataPoint{
+			Timestamp: start.Add(time.Duration(i) * time.Minute),
+			Values:    map[string]interface{}{"Response Time": 1234.5, "Traffic In, Total": traffic},
+		})
+	}
+
+	output := formatTimeSeriesCSV(data, time.UTC)
+
+	begin := strings.Index(output, "```csv\n")
+	end := strings.LastIndex(output, "```")
+	require.True(t, begin >= 0 && end > begin, "output must contain a csv block")
+
+	records, err := csv.NewReader(strings.NewReader(output[begin+len("```csv\n") : end])).ReadAll()
+	require.NoError(t, err)
+
+	require.Len(t, records, len(data.DataPoints)+1, "header row plus one row per data point")
+	assert.Equal(t, data.Headers, records[0], "header order must match Headers")
+
+	assert.Equal(t, []string{"2025-10-26T12:00:00Z", "1234.5", "0.25"}, records[1])
+	assert.Equal(t, "", records[4][2], "missing values are empty cells")
+	assert.Equal(t, "19.25", records[20][2])
+}
+
+func TestFormatValueWithUnit(t *testing.T) {
+	tests := []struct {
+		name     string
+		value    interface{}
+		unit     string
+		unitType string
+		want     string
+	}{
+		{"bytes", 512.0, "Bytes", "BYTES", "512 B"},
+		{"kilobytes", 1536.0, "Bytes", "BYTES", "1.50 KB"},
+		{"megabytes", 5.5 * 1024 * 1024, "MB", "BYTES_MEMORY", "5.50 MB"},
+		{"gigabytes", 3.0 * 1024 * 1024 * 1024, "GB", "BYTES_DISK", "3.00 GB"},
+		{"percent", 45.2, "%", "PERCENT", "45.20 %"},
+		{"response time", 23.0, "msec", "TIME_RESPONSE", "23.0 ms"},
+		{"slow response time", 1500.0, "msec", "TIME_RESPONSE", "1.50 s"},
+		{"uptime", 3*3600.0 + 12*60, "s", "TIME_SECONDS", "3h12m"},
+		{"custom unit", 21.5, "°C", "TEMPERATURE", "21.50 °C"},
+		{"unitless", 42.0, "", "", "42.00"},
+		{"missing value", nil, "Bytes", "BYTES", "N/A"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, formatValueWithUnit(tt.value, tt.unit, tt.unitType))
+		})
+	}
+}
+
+func TestFormatDataTable_Units(t *testing.T) {
+	data := &prtg.TimeSeriesData{
+		ObjectID: 1234,
+		Headers:  []string{"timestamp", "Traffic In", "CPU Load", "Count"},
+		Units: map[string]prtg.ChannelBasic{
+			"Traffic In": {DisplayUnit: "Bytes", UnitType: "BYTES_BANDWIDTH"},
+			"CPU Load":   {DisplayUnit: "%", UnitType: "PERCENT"},
+		},
+		DataPoints: []prtg.TimeSeriesDataPoint{{
+			Timestamp: time.Date(2025, 10, 26, 12, 0, 0, 0, time.UTC),
+			Values:    map[string]interface{}{"Traffic In": 2.5 * 1024 * 1024, "CPU Load": 45.2, "Count": 7.0},
+		}},
+	}
+
+	assert.Contains(t, formatDataTable(data, time.UTC), "| 2025-10-26 12:00:00 | 2.50 MB | 45.20 % | 7.00 |")
+}
+
+func TestFormatChannelsForLLM_Units(t *testing.T) {
+	channels := []prtg.Channel{
+		{Name: "Traffic In", Basic: prtg.ChannelBasic{DisplayUnit: "MB", UnitType: "BYTES_BANDWIDTH"},
+			LastMeasurement: &prtg.ChannelMeasurement{Value: 3 * 1024 * 1024, DisplayValue: 3, Timestamp: "2025-01-15T10:00:00Z"}},
+		{Name: "CPU Load", Basic: prtg.ChannelBasic{DisplayUnit: "%", UnitType: "PERCENT"},
+			LastMeasurement: &prtg.ChannelMeasurement{Value: 0.425, DisplayValue: 42.5, Timestamp: "2025-01-15T10:00:00Z"}},
+		{Name: "Sessions"},
+	}
+
+	text := formatChannelsForLLM(2001, channels, time.UTC)
+	assert.Contains(t, text, "| Traffic In | 3.00 MB | 2025-01-15 10:00:00 |")
+	assert.Contains(t, text, "| CPU Load | 42.50 % | 2025-01-15 10:00:00 |")
+	assert.Contains(t, text, "| Sessions | N/A | - |")
+}
+
+// Test formatTimeSeries dispatches on output_format
+func TestFormatTimeSeries_OutputFormat(t *testing.T) {
+	data := &prtg.TimeSeriesData{
+		ObjectID: 1234,
+		Headers:  []string{"timestamp", "Ping Time"},
+		DataPoints: []prtg.TimeSeriesDataPoint{
+			{Timestamp: time.Date(2025, 10, 26, 12, 0, 0, 0, time.UTC), Values: map[string]interface{}{"Ping Time": 12.0}},
+		},
+	}
+
+	assert.Contains(t, formatTimeSeries(data, "csv", time.UTC), "```csv\ntimestamp,Ping Time\n2025-10-26T12:00:00Z,12\n```")
+	assert.Contains(t, formatTimeSeries(data, "markdown", time.UTC), "## Measurements")
+	assert.Contains(t, formatTimeSeries(data, "", time.UTC), "## Measurements")
+	assert.NotContains(t, formatTimeSeries(data, "", time.UTC), "Truncated")
+
+	// Both formats warn when the oldest data points were left out
+	data.Truncated = true
+	assert.Contains(t, formatTimeSeries(data, "csv", time.UTC), "data points before 2025-10-26 12:00:00 UTC are missing")
+	assert.Contains(t, formatTimeSeries(data, "markdown", time.UTC), "⚠️ **Truncated:**")
+}
+
+func TestHistorySource_PrefersDatabase(t *testing.T) {
+	start := time.Date(2025, 10, 30, 14, 0, 0, 0, time.UTC)
+	end := start.Add(2 * time.Hour)
+	request := createTestRequest(map[string]interface{}{
+		"sensor_id":  1234,
+		"start_time": "2025-10-30T14:00:00Z",
+		"end_time":   "2025-10-30T16:00:00Z",
+	})
+
+	dbData := &prtg.TimeSeriesData{
+		ObjectID:   1234,
+		Headers:    []string{"timestamp", "Response Time"},
+		DataPoints: []prtg.TimeSeriesDataPoint{{Timestamp: start, Values: map[string]interface{}{"Response Time": 12.5}}},
+	}
+	apiData := &prtg.TimeSeriesData{
+		ObjectID:   1234,
+		Headers:    []string{"timestamp", "Response Time"},
+		DataPoints: []prtg.TimeSeriesDataPoint{{Timestamp: start, Values: map[string]interface{}{"Response Time": 99.0}}},
+	}
+
+	newHandler := func(mockDB *MockDB, client PRTGClient) *MetricsToolHandler {
+		handler := NewToolHandler(mockDB, &MockConfig{historyTable: "prtg_channel_history"}, newTestLogger())
+		return NewMetricsToolHandler(client, handler)
+	}
+
+	t.Run("database history is used when available", func(t *testing.T) {
+		mockDB, mockClient := new(MockDB), new(MockPRTGClient)
+		mockDB.On("GetSensorHistoryFromDB", mock.Anything, 1234, start, end).Return(dbData, nil)
+
+		result, err := newHandler(mockDB, mockClient).handleGetSensorHistoryCustom(context.Background(), request)
+		require.NoError(t, err)
+		assert.False(t, result.IsError)
+		assert.Contains(t, resultText(t, result), "12.5")
+		mockClient.AssertNotCalled(t, "GetTimeSeriesCustom", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("database error falls back to the PRTG API", func(t *testing.T) {
+		mockDB, mockClient := new(MockDB), new(MockPRTGClient)
+		mockDB.On("GetSensorHistoryFromDB", mock.Anything, 1234, start, end).Return(nil, errors.New("relation does not exist"))
+		mockClient.On("GetTimeSeriesCustom", mock.Anything, 1234, start, end).Return(apiData, nil)
+
+		result, err := newHandler(mockDB, mockClient).handleGetSensorHistoryCustom(context.Background(), request)
+		require.NoError(t, err)
+		assert.Contains(t, resultText(t, result), "99")
+		mockClient.AssertExpectations(t)
+	})
+
+	t.Run("sensor without database history falls back to the PRTG API", func(t *testing.T) {
+		mockDB, mockClient := new(MockDB), new(MockPRTGClient)
+		mockDB.On("GetSensorHistoryFromDB", mock.Anything, 1234, start, end).
+			Return(&prtg.TimeSeriesData{ObjectID: 1234, Headers: []string{"timestamp"}, DataPoints: []prtg.TimeSeriesDataPoint{}}, nil)
+		mockClient.On("GetTimeSeriesCustom", mock.Anything, 1234, start, end).Return(apiData, nil)
+
+		_, err := newHandler(mockDB, mockClient).handleGetSensorHistoryCustom(context.Background(), request)
+		require.NoError(t, err)
+		mockClient.AssertExpectations(t)
+	})
+
+	t.Run("without the PRTG API database errors are reported", func(t *testing.T) {
+		mockDB := new(MockDB)
+		mockDB.On("GetSensorHistoryFromDB", mock.Anything, 1234, start, end).Return(nil, errors.New("relation does not exist"))
+
+		result, err := newHandler(mockDB, nil).handleGetSensorHistoryCustom(context.Background(), request)
+		require.NoError(t, err)
+		assert.True(t, result.IsError)
+		assert.Contains(t, resultText(t, result), "relation does not exist")
+	})
+
+	t.Run("without a history table the PRTG API is used directly", func(t *testing.T) {
+		mockDB, mockClient := new(MockDB), new(MockPRTGClient)
+		mockClient.On("GetTimeSeries", mock.Anything, 1234, prtg.TimeSeriesShort).Return(apiData, nil)
+
+		handler := newTestMetricsHandler(mockDB, mockClient)
+		_, err := handler.handleGetSensorTimeSeries(context.Background(), createTestRequest(map[string]interface{}{
+			"sensor_id": 1234,
+			"time_type": "short",
+		}))
+		require.NoError(t, err)
+		mockDB.AssertNotCalled(t, "GetSensorHistoryFromDB", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+		mockClient.AssertExpectations(t)
+	})
+}
+
+// Test handleCompareChannel ranking and sensors without the channel
+func TestHandleCompareChannel(t *testing.T) {
+	sensors := []types.Sensor{
+		{ID: 101, Name: "HTTP", DeviceName: "web01"},
+		{ID: 102, Name: "HTTP", DeviceName: "web02"},
+		{ID: 103, Name: "Ping", DeviceName: "web03"},
+		{ID: 104, Name: "HTTP", DeviceName: "web04"},
+	}
+
+	responseTime := func(value float64) []prtg.Channel {
+		return []prtg.Channel{
+			{ID: "0", Name: "Response Time Index", Basic: prtg.ChannelBasic{DisplayUnit: "%"},
+				LastMeasurement: &prtg.ChannelMeasurement{DisplayValue: 99}},
+			{ID: "1", Name: "Response Time", Basic: prtg.ChannelBasic{DisplayUnit: "msec"},
+				LastMeasurement: &prtg.ChannelMeasurement{DisplayValue: value}},
+		}
+	}
+
+	setup := func() (*MockDB, *MockPRTGClient, *MetricsToolHandler) {
+		mockDB := new(MockDB)
+		mockClient := new(MockPRTGClient)
+
+		mockDB.On("GetSensorsExtended", mock.Anything, types.SensorFilter{DeviceName: "web"}, "name", defaultCompareSensors+1).
+			Return(sensors, nil)
+		mockClient.On("GetChannelsBySensor", mock.Anything, 101).Return(responseTime(120), nil)
+		mockClient.On("GetChannelsBySensor", mock.Anything, 102).Return(responseTime(340), nil)
+		mockClient.On("GetChannelsBySensor", mock.Anything, 103).Return([]prtg.Channel{
+			{ID: "0", Name: "Packet Loss", Basic: prtg.ChannelBasic{DisplayUnit: "%"},
+				LastMeasurement: &prtg.ChannelMeasurement{DisplayValue: 0}},
+		}, nil)
+		mockClient.On("GetChannelsBySensor", mock.Anything, 104).Return(nil, errors.New("connection reset"))
+
+		return mockDB, mockClient, newTestMetricsHandler(mockDB, mockClient)
+	}
+
+	t.Run("Ranked highest first", func(t *testing.T) {
+		mockDB, mockClient, handler := setup()
+
+		result, err := handler.handleCompareChannel(context.Background(), createTestRequest(map[string]interface{}{
+			"device_name":  "web",
+			"channel_name": "response time",
+		}))
+		require.NoError(t, err)
+		require.False(t, result.IsError)
+
+		text := resultText(t, result)
+		assert.Contains(t, text, "Compared 4 sensors, ranked highest first")
+		assert.Contains(t, text, "| 1 | HTTP (ID: 102) | web02 | Response Time | 340.00 | msec |")
+		assert.Contains(t, text, "| 2 | HTTP (ID: 101) | web01 | Response Time | 120.00 | msec |")
+		assert.NotContains(t, text, "Response Time Index")
+
+		// The ping sensor has no such channel, the last sensor could not be fetched
+		assert.Contains(t, text, "## Without channel 'response time' (1)\n\n- Ping (ID: 103) on web03")
+		assert.Contains(t, text, "## Channels unavailable (1)\n\n- HTTP (ID: 104) on web04")
+
+		mockDB.AssertExpectations(t)
+		mockClient.AssertExpectations(t)
+	})
+
+	t.Run("Ranked lowest first", func(t *testing.T) {
+		_, _, handler := setup()
+
+		result, err := handler.handleCompareChannel(context.Background(), createTestRequest(map[string]interface{}{
+			"device_name":  "web",
+			"channel_name": "Response Time",
+			"order":        "asc",
+		}))
+		require.NoError(t, err)
+
+		text := resultText(t, result)
+		assert.Contains(t, text, "| 1 | HTTP (ID: 101) | web01 | Response Time | 120.00 | msec |")
+		assert.Contains(t, text, "| 2 | HTTP (ID: 102) | web02 | Response Time | 340.00 | msec |")
+	})
+
+	t.Run("Group or device required", func(t *testing.T) {
+		mockDB := new(MockDB)
+		handler := newTestMetricsHandler(mockDB, new(MockPRTGClient))
+
+		result, err := handler.handleCompareChannel(context.Background(), createTestRequest(map[string]interface{}{
+			"channel_name": "Response Time",
+		}))
+		require.NoError(t, err)
+		assert.True(t, result.IsError)
+
+		mockDB.AssertNotCalled(t, "GetSensorsExtended")
+	})
+}
+
+func TestHandleGetDeviceChannels(t *testing.T) {
+	sensors := []types.Sensor{
+		{ID: 101, Name: "CPU Load", DeviceName: "web01"},
+		{ID: 102, Name: "HTTP", DeviceName: "web01"},
+		{ID: 103, Name: "Ping", DeviceName: "web01"},
+	}
+
+	t.Run("Consolidated table", func(t *testing.T) {
+		mockDB := new(MockDB)
+		mockClient := new(MockPRTGClient)
+
+		mockDB.On("GetSensorsByDeviceID", mock.Anything, 40, defaultDeviceChannelSensors+1).Return(sensors, nil)
+		mockClient.On("GetChannelsBySensor", mock.Anything, 101).Return([]prtg.Channel{
+			{ID: "0", Name: "Total", Basic: prtg.ChannelBasic{DisplayUnit: "%"},
+				LastMeasurement: &prtg.ChannelMeasurement{DisplayValue: 42}},
+			{ID: "1", Name: "Core 1", Basic: prtg.ChannelBasic{DisplayUnit: "%"}},
+		}, nil).Once()
+		mockClient.On("GetChannelsBySensor", mock.Anything, 102).Return([]prtg.Channel{
+			{ID: "0", Name: "Response Time", Basic: prtg.ChannelBasic{DisplayUnit: "msec"},
+				LastMeasurement: &prtg.ChannelMeasurement{DisplayValue: 120}},
+		}, nil).Once()
+		mockClient.On("GetChannelsBySensor", mock.Anything, 103).Return(nil, errors.New("connection reset"))
+
+		handler := newTestMetricsHandler(mockDB, mockClient)
+		request := createTestRequest(map[string]interface{}{"device_id": float64(40)})
+
+		result, err := handler.handleGetDeviceChannels(context.Background(), request)
+		require.NoError(t, err)
+		require.False(t, result.IsError)
+
+		text := resultText(t, result)
+		assert.Contains(t, text, "# Device Channels - web01 (ID: 40)")
+		assert.Contains(t, text, "3 channels from 3 sensors")
+		assert.Contains(t, text, "| CPU Load (ID: 101) | Total | 42.00 | % |\n| CPU Load (ID: 101) | Core 1 | - | % |\n")
+		assert.Contains(t, text, "| HTTP (ID: 102) | Response Time | 120.00 | msec |")
+		assert.Contains(t, text, "## Channels unavailable (1)\n\n- Ping (ID: 103)")
+		assert.NotContains(t, text, "increase max_sensors")
+
+		// A second call within the cache TTL only refetches the sensor that failed
+		_, err = handler.handleGetDeviceChannels(context.Background(), request)
+		require.NoError(t, err)
+
+		mockClient.AssertNumberOfCalls(t, "GetChannelsBySensor", 4)
+		mockDB.AssertExpectations(t)
+	})
+
+	t.Run("Sensors capped", func(t *testing.T) {
+		mockDB := new(MockDB)
+		mockClient := new(MockPRTGClient)
+
+		mockDB.On("GetSensorsByDeviceID", mock.Anything, 40, 3).Return(sensors, nil)
+		mockClient.On("GetChannelsBySensor", mock.Anything, mock.Anything).Return([]prtg.Channel{}, nil)
+
+		result, err := newTestMetricsHandler(mockDB, mockClient).handleGetDeviceChannels(context.Background(),
+			createTestRequest(map[string]interface{}{"device_id": float64(40), "max_sensors": float64(2)}))
+		require.NoError(t, err)
+
+		text := resultText(t, result)
+		assert.Contains(t, text, "0 channels from 2 sensors")
+		assert.Contains(t, text, "_Only the first 2 sensors are shown: increase max_sensors to see more._")
+		assert.Contains(t, text, "| HTTP (ID: 102) | - | - | |")
+		assert.NotContains(t, text, "Ping")
+
+		mockClient.AssertNotCalled(t, "GetChannelsBySensor", mock.Anything, 103)
+	})
+
+	t.Run("Bounded concurrency", func(t *testing.T) {
+		mockDB := new(MockDB)
+		mockClient := new(MockPRTGClient)
+
+		many := make([]types.Sensor, 12)
+		for i := range many {
+			many[i] = types.Sensor{ID: 200 + i, Name: fmt.Sprintf("Sensor %d", i), DeviceName: "web01"}
+		}
+
+		var inFlight, maxInFlight atomic.Int32
+
+		mockDB.On("GetSensorsByDeviceID", mock.Anything, 40, defaultDeviceChannelSensors+1).Return(many, nil)
+		mockClient.On("GetChannelsBySensor", mock.Anything, mock.Anything).
+			Run(func(mock.Arguments) {
+				current := inFlight.Add(1)
+				defer inFlight.Add(-1)
+
+				for {
+					highest := maxInFlight.Load()
+					if current <= highest || maxInFlight.CompareAndSwap(highest, current) {
+						break
+					}
+				}
+
+				time.Sleep(10 * time.Millisecond)
+			}).
+			Return([]prtg.Channel{}, nil)
+
+		result, err := newTestMetricsHandler(mockDB, mockClient).handleGetDeviceChannels(context.Background(),
+			createTestRequest(map[string]interface{}{"device_id": float64(40)}))
+		require.NoError(t, err)
+		assert.Contains(t, resultText(t, result), "0 channels from 12 sensors")
+
+		mockClient.AssertNumberOfCalls(t, "GetChannelsBySensor", len(many))
+		assert.LessOrEqual(t, maxInFlight.Load(), int32(channelFetchConcurrency))
+	})
+
+	t.Run("PRTG API unavailable", func(t *testing.T) {
+		mockDB := new(MockDB)
+		mockClient := new(MockPRTGClient)
+
+		mockDB.On("GetSensorsByDeviceID", mock.Anything, 40, defaultDeviceChannelSensors+1).Return(sensors, nil)
+		mockClient.On("GetChannelsBySensor", mock.Anything, mock.Anything).Return(nil, errors.New("connection refused"))
+
+		result, err := newTestMetricsHandler(mockDB, mockClient).handleGetDeviceChannels(context.Background(),
+			createTestRequest(map[string]interface{}{"device_id": float64(40)}))
+		require.NoError(t, err)
+		assert.True(t, result.IsError)
+		assert.Contains(t, resultText(t, result), "PRTG API unavailable")
+	})
+
+	t.Run("Invalid device ID", func(t *testing.T) {
+		mockDB := new(MockDB)
+
+		result, err := newTestMetricsHandler(mockDB, new(MockPRTGClient)).handleGetDeviceChannels(context.Background(),
+			createTestRequest(map[string]interface{}{"device_id": float64(0)}))
+		require.NoError(t, err)
+		assert.True(t, result.IsError)
+
+		mockDB.AssertNotCalled(t, "GetSensorsByDeviceID")
+	})
+}
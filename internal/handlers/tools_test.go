@@ -2,6 +2,10 @@ package handlers
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
 	"testing"
 	"time"
 
@@ -9,7 +13,9 @@ import (
 	"github.com/rs/zerolog"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
 
+	"github.com/matthieu/mcp-server-prtg/internal/prtg"
 	"github.com/matthieu/mcp-server-prtg/internal/types"
 )
 
@@ -26,30 +32,72 @@ func (m *MockDB) GetSensors(ctx context.Context, deviceName, sensorName string,
 	return args.Get(0).([]types.Sensor), args.Error(1)
 }
 
-func (m *MockDB) GetSensorsExtended(ctx context.Context, deviceName, sensorName, sensorType, groupName string, status *int, tags, orderBy string, limit int) ([]types.Sensor, error) {
-	args := m.Called(ctx, deviceName, sensorName, sensorType, groupName, status, tags, orderBy, limit)
+func (m *MockDB) GetSensorsExtended(ctx context.Context, filter types.SensorFilter, orderBy string, limit int) ([]types.Sensor, error) {
+	args := m.Called(ctx, filter, orderBy, limit)
 	if args.Get(0) == nil {
 		return nil, args.Error(1)
 	}
 	return args.Get(0).([]types.Sensor), args.Error(1)
 }
 
-func (m *MockDB) GetSensorByID(ctx context.Context, sensorID int) (*types.Sensor, error) {
-	args := m.Called(ctx, sensorID)
+func (m *MockDB) GroupCounts(ctx context.Context, dimension string, filter types.SensorFilter, limit int) ([]types.DimensionCount, error) {
+	args := m.Called(ctx, dimension, filter, limit)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]types.DimensionCount), args.Error(1)
+}
+
+func (m *MockDB) CountSensors(ctx context.Context, filter types.SensorFilter) (int, error) {
+	args := m.Called(ctx, filter)
+	return args.Int(0), args.Error(1)
+}
+
+func (m *MockDB) GetSensorByID(ctx context.Context, sensorID int, serverID *int) (*types.Sensor, error) {
+	args := m.Called(ctx, sensorID, serverID)
 	if args.Get(0) == nil {
 		return nil, args.Error(1)
 	}
 	return args.Get(0).(*types.Sensor), args.Error(1)
 }
 
-func (m *MockDB) GetAlerts(ctx context.Context, hours int, status *int, deviceName string) ([]types.Sensor, error) {
-	args := m.Called(ctx, hours, status, deviceName)
+func (m *MockDB) GetSensorsByIDs(ctx context.Context, serverID int, sensorIDs []int) ([]types.Sensor, error) {
+	args := m.Called(ctx, serverID, sensorIDs)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]types.Sensor), args.Error(1)
+}
+
+func (m *MockDB) GetSensorsByDeviceID(ctx context.Context, deviceID, limit int) ([]types.Sensor, error) {
+	args := m.Called(ctx, deviceID, limit)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]types.Sensor), args.Error(1)
+}
+
+func (m *MockDB) GetAlerts(ctx context.Context, filter types.AlertFilter, limit, offset int) ([]types.Sensor, error) {
+	args := m.Called(ctx, filter, limit, offset)
 	if args.Get(0) == nil {
 		return nil, args.Error(1)
 	}
 	return args.Get(0).([]types.Sensor), args.Error(1)
 }
 
+func (m *MockDB) CountAlerts(ctx context.Context, filter types.AlertFilter) (int, error) {
+	args := m.Called(ctx, filter)
+	return args.Int(0), args.Error(1)
+}
+
+func (m *MockDB) GetRecentChanges(ctx context.Context, minutes, limit int) ([]types.StatusChange, error) {
+	args := m.Called(ctx, minutes, limit)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]types.StatusChange), args.Error(1)
+}
+
 func (m *MockDB) GetDeviceOverview(ctx context.Context, deviceName string) (*types.DeviceOverview, error) {
 	args := m.Called(ctx, deviceName)
 	if args.Get(0) == nil {
@@ -66,14 +114,22 @@ func (m *MockDB) GetTopSensors(ctx context.Context, metric, sensorType string, l
 	return args.Get(0).([]types.Sensor), args.Error(1)
 }
 
-func (m *MockDB) GetHierarchy(ctx context.Context, groupName string, includeSensors bool, maxDepth int) (*types.HierarchyNode, error) {
-	args := m.Called(ctx, groupName, includeSensors, maxDepth)
+func (m *MockDB) GetHierarchy(ctx context.Context, groupName string, opts types.HierarchyOptions) (*types.HierarchyNode, error) {
+	args := m.Called(ctx, groupName, opts)
 	if args.Get(0) == nil {
 		return nil, args.Error(1)
 	}
 	return args.Get(0).(*types.HierarchyNode), args.Error(1)
 }
 
+func (m *MockDB) GetDevicePath(ctx context.Context, deviceID int) (*types.DevicePath, error) {
+	args := m.Called(ctx, deviceID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*types.DevicePath), args.Error(1)
+}
+
 func (m *MockDB) Search(ctx context.Context, searchTerm string, limit int) (*types.SearchResults, error) {
 	args := m.Called(ctx, searchTerm, limit)
 	if args.Get(0) == nil {
@@ -82,6 +138,22 @@ func (m *MockDB) Search(ctx context.Context, searchTerm string, limit int) (*typ
 	return args.Get(0).(*types.SearchResults), args.Error(1)
 }
 
+func (m *MockDB) SearchFuzzy(ctx context.Context, searchTerm string, threshold float64, limit int) (*types.SearchResults, error) {
+	args := m.Called(ctx, searchTerm, threshold, limit)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*types.SearchResults), args.Error(1)
+}
+
+func (m *MockDB) SearchMessages(ctx context.Context, searchTerm string, limit int) ([]types.Sensor, error) {
+	args := m.Called(ctx, searchTerm, limit)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]types.Sensor), args.Error(1)
+}
+
 func (m *MockDB) GetGroups(ctx context.Context, groupName string, parentID *int, limit int) ([]types.Group, error) {
 	args := m.Called(ctx, groupName, parentID, limit)
 	if args.Get(0) == nil {
@@ -90,14 +162,54 @@ func (m *MockDB) GetGroups(ctx context.Context, groupName string, parentID *int,
 	return args.Get(0).([]types.Group), args.Error(1)
 }
 
-func (m *MockDB) GetTags(ctx context.Context, tagName string, limit int) ([]types.Tag, error) {
-	args := m.Called(ctx, tagName, limit)
+func (m *MockDB) GetEmptyDevices(ctx context.Context, limit int) ([]types.Device, error) {
+	args := m.Called(ctx, limit)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]types.Device), args.Error(1)
+}
+
+func (m *MockDB) GetEmptyGroups(ctx context.Context, limit int) ([]types.Group, error) {
+	args := m.Called(ctx, limit)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]types.Group), args.Error(1)
+}
+
+func (m *MockDB) GetMessageFrequency(ctx context.Context, hours, limit int) ([]types.MessageCount, error) {
+	args := m.Called(ctx, hours, limit)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]types.MessageCount), args.Error(1)
+}
+
+func (m *MockDB) GetTagCoverage(ctx context.Context, groupBy string, limit int) (*types.TagCoverage, error) {
+	args := m.Called(ctx, groupBy, limit)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*types.TagCoverage), args.Error(1)
+}
+
+func (m *MockDB) GetTags(ctx context.Context, filter types.TagFilter, limit int) ([]types.Tag, error) {
+	args := m.Called(ctx, filter, limit)
 	if args.Get(0) == nil {
 		return nil, args.Error(1)
 	}
 	return args.Get(0).([]types.Tag), args.Error(1)
 }
 
+func (m *MockDB) GetSensorTypes(ctx context.Context, limit int) ([]types.SensorTypeCount, error) {
+	args := m.Called(ctx, limit)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]types.SensorTypeCount), args.Error(1)
+}
+
 func (m *MockDB) GetBusinessProcesses(ctx context.Context, processName string, status *int, limit int) ([]types.Sensor, error) {
 	args := m.Called(ctx, processName, status, limit)
 	if args.Get(0) == nil {
@@ -114,12 +226,42 @@ func (m *MockDB) GetStatistics(ctx context.Context) (*types.Statistics, error) {
 	return args.Get(0).(*types.Statistics), args.Error(1)
 }
 
-func (m *MockDB) ExecuteCustomQuery(ctx context.Context, query string, limit int) ([]map[string]interface{}, error) {
+// StreamCustomQuery passes the first limit mocked rows (columns, [][]interface{}) to fn one at
+// a time, and reports whether rows were left out.
+func (m *MockDB) StreamCustomQuery(ctx context.Context, query string, limit int,
+	fn func(columns []string, values []interface{}) error) (int, bool, error) {
 	args := m.Called(ctx, query, limit)
+	if err := args.Error(2); err != nil {
+		return 0, false, err
+	}
+
+	columns := args.Get(0).([]string)
+	rows := args.Get(1).([][]interface{})
+	returned := min(len(rows), limit)
+
+	for _, row := range rows[:returned] {
+		if err := fn(columns, row); err != nil {
+			return 0, false, err
+		}
+	}
+
+	return returned, len(rows) > returned, nil
+}
+
+func (m *MockDB) GetSensorHistoryFromDB(ctx context.Context, sensorID int, start, end time.Time) (*prtg.TimeSeriesData, error) {
+	args := m.Called(ctx, sensorID, start, end)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*prtg.TimeSeriesData), args.Error(1)
+}
+
+func (m *MockDB) ResolveObjectByID(ctx context.Context, objectID int) (*types.PRTGObject, error) {
+	args := m.Called(ctx, objectID)
 	if args.Get(0) == nil {
 		return nil, args.Error(1)
 	}
-	return args.Get(0).([]map[string]interface{}), args.Error(1)
+	return args.Get(0).(*types.PRTGObject), args.Error(1)
 }
 
 func (m *MockDB) Close() error {
@@ -129,13 +271,85 @@ func (m *MockDB) Close() error {
 
 // MockConfig is a mock implementation of Config interface
 type MockConfig struct {
-	allowCustomQueries bool
+	allowCustomQueries   bool
+	allowWriteOperations bool
+	fuzzySearchThreshold float64
+	statisticsCacheTTL   time.Duration
+	sensorQueryCacheTTL  time.Duration
+	historyTable         string
+	maxHierarchyNodes    int
+	alertsPageSize       int
+	defaultSensorLimit   int
+	prtgUIBaseURL        string
+	omitJSONPayload      bool
+	displayLocation      *time.Location
+	idOffsets            types.IDOffsets
 }
 
 func (m *MockConfig) AllowCustomQueries() bool {
 	return m.allowCustomQueries
 }
 
+func (m *MockConfig) AllowWriteOperations() bool {
+	return m.allowWriteOperations
+}
+
+func (m *MockConfig) FuzzySearchThreshold() float64 {
+	if m.fuzzySearchThreshold == 0 {
+		return 0.3
+	}
+	return m.fuzzySearchThreshold
+}
+
+func (m *MockConfig) MaxHierarchyNodes() int {
+	return m.maxHierarchyNodes
+}
+
+func (m *MockConfig) AlertsPageSize() int {
+	if m.alertsPageSize == 0 {
+		return types.AlertsLimit
+	}
+	return m.alertsPageSize
+}
+
+func (m *MockConfig) DefaultSensorLimit() int {
+	if m.defaultSensorLimit == 0 {
+		return types.SensorsLimit
+	}
+	return m.defaultSensorLimit
+}
+
+func (m *MockConfig) SensorQueryCacheTTL() time.Duration {
+	return m.sensorQueryCacheTTL
+}
+
+func (m *MockConfig) IncludeJSONPayload() bool {
+	return !m.omitJSONPayload
+}
+
+func (m *MockConfig) DisplayLocation() *time.Location {
+	if m.displayLocation == nil {
+		return time.UTC
+	}
+	return m.displayLocation
+}
+
+func (m *MockConfig) PRTGUIBaseURL() string {
+	return m.prtgUIBaseURL
+}
+
+func (m *MockConfig) IDOffsets() types.IDOffsets {
+	return m.idOffsets
+}
+
+func (m *MockConfig) HistoryTable() string {
+	return m.historyTable
+}
+
+func (m *MockConfig) StatisticsCacheTTL() time.Duration {
+	return m.statisticsCacheTTL
+}
+
 // Helper to create test logger
 func newTestLogger() *zerolog.Logger {
 	logger := zerolog.Nop()
@@ -250,305 +464,1595 @@ func TestFormatResult(t *testing.T) {
 	})
 }
 
-// Test handleGetSensorStatus
-func TestHandleGetSensorStatus(t *testing.T) {
-	t.Run("Valid sensor ID", func(t *testing.T) {
+// Test handleGetObject
+func TestHandleGetObject(t *testing.T) {
+	t.Run("Returns the object with its type", func(t *testing.T) {
 		mockDB := new(MockDB)
-		mockConfig := &MockConfig{allowCustomQueries: false}
-		logger := newTestLogger()
+		handler := NewToolHandler(mockDB, &MockConfig{}, newTestLogger())
 
-		handler := NewToolHandler(mockDB, mockConfig, logger)
-
-		expectedSensor := &types.Sensor{
-			ID:   123,
-			Name: "Test Sensor",
+		object := &types.PRTGObject{
+			Type:   types.ObjectTypeDevice,
+			Device: &types.Device{ID: 100, Name: "web01", Host: "10.0.0.1"},
 		}
+		mockDB.On("ResolveObjectByID", mock.Anything, 100).Return(object, nil)
 
-		mockDB.On("GetSensorByID", mock.Anything, 123).Return(expectedSensor, nil)
-
-		request := createTestRequest(map[string]interface{}{
-			"sensor_id": float64(123),
-		})
+		result, err := handler.handleGetObject(context.Background(), createTestRequest(map[string]interface{}{
+			"object_id": float64(100),
+		}))
+		require.NoError(t, err)
 
-		result, err := handler.handleGetSensorStatus(context.Background(), request)
-		assert.NoError(t, err)
-		assert.NotNil(t, result)
+		text := result.Content[0].(mcp.TextContent).Text
+		assert.Contains(t, text, `"type": "device"`)
+		assert.Contains(t, text, `"name": "web01"`)
+		assert.NotContains(t, text, `"sensor"`)
 
 		mockDB.AssertExpectations(t)
 	})
 
-	t.Run("Invalid sensor ID - zero", func(t *testing.T) {
+	t.Run("ID of a prefixed server", func(t *testing.T) {
 		mockDB := new(MockDB)
-		mockConfig := &MockConfig{allowCustomQueries: false}
-		logger := newTestLogger()
-
-		handler := NewToolHandler(mockDB, mockConfig, logger)
-
-		request := createTestRequest(map[string]interface{}{
-			"sensor_id": float64(0),
-		})
+		handler := NewToolHandler(mockDB, &MockConfig{idOffsets: types.IDOffsets{"eu": 1000000}}, newTestLogger())
+
+		object := &types.PRTGObject{Type: types.ObjectTypeSensor, Sensor: &types.Sensor{ID: 1002045, DeviceID: 1000100, Name: "Ping"}}
+		mockDB.On("ResolveObjectByID", mock.Anything, 1002045).Return(object, nil)
+
+		result, err := handler.handleGetObject(context.Background(), createTestRequest(map[string]interface{}{
+			"object_id": float64(2045),
+			"id_prefix": "eu",
+		}))
+		require.NoError(t, err)
+
+		// IDs are rendered as shown in the PRTG web interface of the server
+		text := result.Content[0].(mcp.TextContent).Text
+		assert.Contains(t, text, `"id": 2045`)
+		assert.Contains(t, text, `"device_id": 100`)
+		assert.NotContains(t, text, "1002045")
+
+		parentID := 1000001
+		group := &types.PRTGObject{Type: types.ObjectTypeGroup, Group: &types.Group{ID: 1000050, ParentID: &parentID}}
+		mockDB.On("ResolveObjectByID", mock.Anything, 1000050).Return(group, nil)
+
+		result, err = handler.handleGetObject(context.Background(), createTestRequest(map[string]interface{}{
+			"object_id": float64(50),
+			"id_prefix": "eu",
+		}))
+		require.NoError(t, err)
+
+		text = result.Content[0].(mcp.TextContent).Text
+		assert.Contains(t, text, `"id": 50`)
+		assert.Contains(t, text, `"parent_id": 1`)
+
+		// Errors quote the ID from the PRTG web interface, not the database ID
+		mockDB.On("ResolveObjectByID", mock.Anything, 1002046).Return(nil, nil)
+
+		_, err = handler.handleGetObject(context.Background(), createTestRequest(map[string]interface{}{
+			"object_id": float64(2046),
+			"id_prefix": "eu",
+		}))
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "no sensor, device or group with ID 2046")
 
-		result, err := handler.handleGetSensorStatus(context.Background(), request)
-		assert.Error(t, err)
-		assert.Nil(t, result)
-		assert.Contains(t, err.Error(), "sensor_id must be greater than 0")
+		mockDB.AssertExpectations(t)
 
-		mockDB.AssertNotCalled(t, "GetSensorByID")
+		// A prefix missing from database.id_offsets is rejected rather than queried as-is
+		_, err = handler.handleGetObject(context.Background(), createTestRequest(map[string]interface{}{
+			"object_id": float64(2045),
+			"id_prefix": "us",
+		}))
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), `unknown id_prefix "us"`)
 	})
 
-	t.Run("Invalid sensor ID - negative", func(t *testing.T) {
+	t.Run("Unknown ID", func(t *testing.T) {
 		mockDB := new(MockDB)
-		mockConfig := &MockConfig{allowCustomQueries: false}
-		logger := newTestLogger()
-
-		handler := NewToolHandler(mockDB, mockConfig, logger)
+		handler := NewToolHandler(mockDB, &MockConfig{}, newTestLogger())
 
-		request := createTestRequest(map[string]interface{}{
-			"sensor_id": float64(-5),
-		})
+		mockDB.On("ResolveObjectByID", mock.Anything, 999).Return(nil, nil)
 
-		result, err := handler.handleGetSensorStatus(context.Background(), request)
-		assert.Error(t, err)
+		result, err := handler.handleGetObject(context.Background(), createTestRequest(map[string]interface{}{
+			"object_id": float64(999),
+		}))
+		require.Error(t, err)
 		assert.Nil(t, result)
-		assert.Contains(t, err.Error(), "sensor_id must be greater than 0")
-
-		mockDB.AssertNotCalled(t, "GetSensorByID")
+		assert.Contains(t, err.Error(), "no sensor, device or group with ID 999")
 	})
 
-	t.Run("Missing sensor_id argument", func(t *testing.T) {
+	t.Run("Invalid ID", func(t *testing.T) {
 		mockDB := new(MockDB)
-		mockConfig := &MockConfig{allowCustomQueries: false}
-		logger := newTestLogger()
-
-		handler := NewToolHandler(mockDB, mockConfig, logger)
-
-		request := createTestRequest(map[string]interface{}{})
+		handler := NewToolHandler(mockDB, &MockConfig{}, newTestLogger())
 
-		result, err := handler.handleGetSensorStatus(context.Background(), request)
+		_, err := handler.handleGetObject(context.Background(), createTestRequest(map[string]interface{}{
+			"object_id": float64(0),
+		}))
 		assert.Error(t, err)
-		assert.Nil(t, result)
-		assert.Contains(t, err.Error(), "sensor_id must be greater than 0")
 
-		mockDB.AssertNotCalled(t, "GetSensorByID")
+		mockDB.AssertNotCalled(t, "ResolveObjectByID")
 	})
 }
 
-// Test handleDeviceOverview
-func TestHandleDeviceOverview(t *testing.T) {
-	t.Run("Valid device name", func(t *testing.T) {
+func TestHandleGetDeviceSensors(t *testing.T) {
+	t.Run("Lists the sensors of the device", func(t *testing.T) {
 		mockDB := new(MockDB)
-		mockConfig := &MockConfig{allowCustomQueries: false}
-		logger := newTestLogger()
-
-		handler := NewToolHandler(mockDB, mockConfig, logger)
-
-		expectedOverview := &types.DeviceOverview{
-			Device: types.Device{
-				Name: "Server1",
-			},
-			TotalSensors: 10,
-			UpSensors:    8,
-			DownSensors:  2,
-		}
+		handler := NewToolHandler(mockDB, &MockConfig{}, newTestLogger())
 
-		mockDB.On("GetDeviceOverview", mock.Anything, "Server1").Return(expectedOverview, nil)
+		mockDB.On("GetSensorsByDeviceID", mock.Anything, 100, types.SensorsLimit+1).Return([]types.Sensor{
+			{ID: 1001, Name: "CPU Load", DeviceName: "web01", Status: types.StatusUp, StatusText: "Up"},
+			{ID: 1002, Name: "Ping", DeviceName: "web01", Status: types.StatusDown, StatusText: "Down"},
+		}, nil)
 
-		request := createTestRequest(map[string]interface{}{
-			"device_name": "Server1",
-		})
+		result, err := handler.handleGetDeviceSensors(context.Background(), createTestRequest(map[string]interface{}{
+			"device_id": float64(100),
+		}))
+		require.NoError(t, err)
 
-		result, err := handler.handleDeviceOverview(context.Background(), request)
-		assert.NoError(t, err)
-		assert.NotNil(t, result)
+		text := result.Content[0].(mcp.TextContent).Text
+		assert.Contains(t, text, "CPU Load")
+		assert.Contains(t, text, "Ping")
 
 		mockDB.AssertExpectations(t)
 	})
 
-	t.Run("Empty device name", func(t *testing.T) {
+	t.Run("Device ID of a prefixed server", func(t *testing.T) {
 		mockDB := new(MockDB)
-		mockConfig := &MockConfig{allowCustomQueries: false}
-		logger := newTestLogger()
+		handler := NewToolHandler(mockDB, &MockConfig{idOffsets: types.IDOffsets{"eu": 1000000}}, newTestLogger())
 
-		handler := NewToolHandler(mockDB, mockConfig, logger)
+		mockDB.On("GetSensorsByDeviceID", mock.Anything, 1000100, types.SensorsLimit+1).Return([]types.Sensor{
+			{ID: 1001001, DeviceID: 1000100, Name: "Ping", DeviceName: "web01", Status: types.StatusUp, StatusText: "Up"},
+		}, nil)
 
-		request := createTestRequest(map[string]interface{}{
-			"device_name": "",
-		})
+		result, err := handler.handleGetDeviceSensors(context.Background(), createTestRequest(map[string]interface{}{
+			"device_id": float64(100),
+			"id_prefix": "eu",
+		}))
+		require.NoError(t, err)
 
-		result, err := handler.handleDeviceOverview(context.Background(), request)
-		assert.Error(t, err)
-		assert.Nil(t, result)
-		assert.Contains(t, err.Error(), "device_name is required")
+		text := result.Content[0].(mcp.TextContent).Text
+		assert.Contains(t, text, `"id": 1001,`)
+		assert.Contains(t, text, `"device_id": 100,`)
+		assert.NotContains(t, text, "1001001")
 
-		mockDB.AssertNotCalled(t, "GetDeviceOverview")
+		mockDB.AssertExpectations(t)
 	})
 
-	t.Run("Missing device_name argument", func(t *testing.T) {
+	t.Run("Unknown device", func(t *testing.T) {
 		mockDB := new(MockDB)
-		mockConfig := &MockConfig{allowCustomQueries: false}
-		logger := newTestLogger()
+		handler := NewToolHandler(mockDB, &MockConfig{}, newTestLogger())
 
-		handler := NewToolHandler(mockDB, mockConfig, logger)
+		mockDB.On("GetSensorsByDeviceID", mock.Anything, 999, 11).Return(nil, errors.New("device not found"))
 
-		request := createTestRequest(map[string]interface{}{})
+		result, err := handler.handleGetDeviceSensors(context.Background(), createTestRequest(map[string]interface{}{
+			"device_id": float64(999),
+			"limit":     float64(10),
+		}))
+		require.Error(t, err)
+		assert.Nil(t, result)
+		assert.Contains(t, err.Error(), "device not found")
+	})
 
-		result, err := handler.handleDeviceOverview(context.Background(), request)
+	t.Run("Invalid ID", func(t *testing.T) {
+		mockDB := new(MockDB)
+		handler := NewToolHandler(mockDB, &MockConfig{}, newTestLogger())
+
+		_, err := handler.handleGetDeviceSensors(context.Background(), createTestRequest(map[string]interface{}{
+			"device_id": float64(-1),
+		}))
 		assert.Error(t, err)
-		assert.Nil(t, result)
-		assert.Contains(t, err.Error(), "device_name is required")
 
-		mockDB.AssertNotCalled(t, "GetDeviceOverview")
+		mockDB.AssertNotCalled(t, "GetSensorsByDeviceID")
 	})
 }
 
-// Test handleCustomQuery - SECURITY CRITICAL
-func TestHandleCustomQuery_Security(t *testing.T) {
-	t.Run("Custom queries disabled - SECURITY", func(t *testing.T) {
+func TestHandleFindEmptyObjects(t *testing.T) {
+	t.Run("Lists empty devices and groups", func(t *testing.T) {
 		mockDB := new(MockDB)
-		mockConfig := &MockConfig{allowCustomQueries: false} // DISABLED
-		logger := newTestLogger()
+		handler := NewToolHandler(mockDB, &MockConfig{}, newTestLogger())
 
-		handler := NewToolHandler(mockDB, mockConfig, logger)
+		mockDB.On("GetEmptyDevices", mock.Anything, 101).Return([]types.Device{
+			{ID: 101, Name: "spare01", Host: "10.0.0.101", FullPath: "/Root/Servers/spare01"},
+		}, nil)
+		mockDB.On("GetEmptyGroups", mock.Anything, 101).Return([]types.Group{
+			{ID: 20, Name: "Decommissioned", FullPath: "/Root/Decommissioned"},
+		}, nil)
 
-		request := createTestRequest(map[string]interface{}{
-			"query": "SELECT * FROM prtg_sensor",
-			"limit": float64(100),
-		})
+		result, err := handler.handleFindEmptyObjects(context.Background(), createTestRequest(map[string]interface{}{}))
+		require.NoError(t, err)
 
-		result, err := handler.handleCustomQuery(context.Background(), request)
-		assert.Error(t, err)
-		assert.Nil(t, result)
-		assert.Contains(t, err.Error(), "custom SQL queries are disabled")
-		assert.Contains(t, err.Error(), "allow_custom_queries: true")
+		text := result.Content[0].(mcp.TextContent).Text
+		assert.Contains(t, text, "**1 device(s) without sensors**")
+		assert.Contains(t, text, "| 101 | spare01 | 10.0.0.101 |")
+		assert.Contains(t, text, "| 20 | Decommissioned | 📁 Group |")
+		assert.Contains(t, text, `"devices": [`)
 
-		// Database should NOT be called when queries are disabled
-		mockDB.AssertNotCalled(t, "ExecuteCustomQuery")
+		mockDB.AssertExpectations(t)
 	})
 
-	t.Run("Custom queries enabled - valid query", func(t *testing.T) {
+	t.Run("Truncated when a list exceeds the limit", func(t *testing.T) {
 		mockDB := new(MockDB)
-		mockConfig := &MockConfig{allowCustomQueries: true} // ENABLED
-		logger := newTestLogger()
+		handler := NewToolHandler(mockDB, &MockConfig{}, newTestLogger())
 
-		handler := NewToolHandler(mockDB, mockConfig, logger)
+		mockDB.On("GetEmptyDevices", mock.Anything, 2).Return([]types.Device{{ID: 101, Name: "spare01"}, {ID: 102, Name: "spare02"}}, nil)
+		mockDB.On("GetEmptyGroups", mock.Anything, 2).Return([]types.Group{}, nil)
 
-		expectedResults := []map[string]interface{}{
-			{"id": 1, "name": "Sensor1"},
-			{"id": 2, "name": "Sensor2"},
-		}
+		result, err := handler.handleFindEmptyObjects(context.Background(), createTestRequest(map[string]interface{}{
+			"limit": float64(1),
+		}))
+		require.NoError(t, err)
 
-		mockDB.On("ExecuteCustomQuery", mock.Anything, "SELECT * FROM prtg_sensor", 100).
-			Return(expectedResults, nil)
+		text := result.Content[0].(mcp.TextContent).Text
+		assert.True(t, strings.HasPrefix(text, `{"returned":1,"truncated":true}`), text)
+		assert.NotContains(t, text, "spare02")
+		assert.NotContains(t, text, "Groups without devices")
+	})
 
-		request := createTestRequest(map[string]interface{}{
-			"query": "SELECT * FROM prtg_sensor",
-			"limit": float64(100),
-		})
+	t.Run("Database error", func(t *testing.T) {
+		mockDB := new(MockDB)
+		handler := NewToolHandler(mockDB, &MockConfig{}, newTestLogger())
 
-		result, err := handler.handleCustomQuery(context.Background(), request)
-		assert.NoError(t, err)
-		assert.NotNil(t, result)
+		mockDB.On("GetEmptyDevices", mock.Anything, 101).Return(nil, errors.New("connection refused"))
 
-		mockDB.AssertExpectations(t)
+		_, err := handler.handleFindEmptyObjects(context.Background(), createTestRequest(map[string]interface{}{}))
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "failed to get empty devices")
+
+		mockDB.AssertNotCalled(t, "GetEmptyGroups", mock.Anything, mock.Anything)
 	})
+}
 
-	t.Run("Empty query", func(t *testing.T) {
+func TestHandleErrorMessageSummary(t *testing.T) {
+	t.Run("Messages by frequency", func(t *testing.T) {
 		mockDB := new(MockDB)
-		mockConfig := &MockConfig{allowCustomQueries: true}
-		logger := newTestLogger()
+		handler := NewToolHandler(mockDB, &MockConfig{}, newTestLogger())
 
-		handler := NewToolHandler(mockDB, mockConfig, logger)
+		mockDB.On("GetMessageFrequency", mock.Anything, 24, 21).Return([]types.MessageCount{
+			{Message: "SNMP timeout", SensorCount: 82, DeviceCount: 41, SampleSensors: []string{"core-sw01 / CPU Load"}},
+			{Message: "", SensorCount: 2, DeviceCount: 2, SampleSensors: []string{"web01 / HTTP", "web02 / HTTP"}},
+		}, nil)
+
+		result, err := handler.handleErrorMessageSummary(context.Background(), createTestRequest(map[string]interface{}{}))
+		require.NoError(t, err)
+
+		text := result.Content[0].(mcp.TextContent).Text
+		assert.Contains(t, text, "Found **2 distinct message(s)** on sensors in a problem status (last 24 hour(s))")
+		assert.Contains(t, text, "| 82 | 41 | SNMP timeout | core-sw01 / CPU Load |")
+		assert.Contains(t, text, "| 2 | 2 | _(no message)_ | web01 / HTTP, web02 / HTTP |")
+
+		mockDB.AssertExpectations(t)
+	})
+
+	t.Run("All time", func(t *testing.T) {
+		mockDB := new(MockDB)
+		handler := NewToolHandler(mockDB, &MockConfig{}, newTestLogger())
+
+		mockDB.On("GetMessageFrequency", mock.Anything, 0, 6).Return([]types.MessageCount{}, nil)
+
+		result, err := handler.handleErrorMessageSummary(context.Background(), createTestRequest(map[string]interface{}{
+			"hours": float64(0),
+			"limit": float64(5),
+		}))
+		require.NoError(t, err)
+
+		text := result.Content[0].(mcp.TextContent).Text
+		assert.Contains(t, text, "(all time)")
+		assert.Contains(t, text, "No sensor is in a non-up status.")
+
+		mockDB.AssertExpectations(t)
+	})
+
+	t.Run("Negative hours", func(t *testing.T) {
+		handler := NewToolHandler(new(MockDB), &MockConfig{}, newTestLogger())
+
+		_, err := handler.handleErrorMessageSummary(context.Background(), createTestRequest(map[string]interface{}{
+			"hours": float64(-1),
+		}))
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "hours must not be negative")
+	})
+}
+
+func TestHandleTagCoverage(t *testing.T) {
+	t.Run("Coverage and untagged groups", func(t *testing.T) {
+		mockDB := new(MockDB)
+		handler := NewToolHandler(mockDB, &MockConfig{}, newTestLogger())
+
+		mockDB.On("GetTagCoverage", mock.Anything, "group", 21).Return(&types.TagCoverage{
+			TotalSensors:    200,
+			UntaggedSensors: 30,
+			CoveragePercent: 85,
+			GroupBy:         "group",
+			Objects: []types.UntaggedSensors{
+				{ID: 50, ServerID: 1, Name: "Branch Offices", FullPath: "/Root/Branch Offices", UntaggedSensors: 25, TotalSensors: 40},
+			},
+		}, nil)
+
+		result, err := handler.handleTagCoverage(context.Background(), createTestRequest(map[string]interface{}{}))
+		require.NoError(t, err)
+
+		text := result.Content[0].(mcp.TextContent).Text
+		assert.Contains(t, text, "**85.0%** of sensors have at least one tag: **30 untagged** out of 200 sensors")
+		assert.Contains(t, text, "### Groups with the most untagged sensors")
+		assert.Contains(t, text, "| 50 | Branch Offices | 25 | 40 | /Root/Branch Offices |")
+
+		mockDB.AssertExpectations(t)
+	})
+
+	t.Run("Fully tagged", func(t *testing.T) {
+		mockDB := new(MockDB)
+		handler := NewToolHandler(mockDB, &MockConfig{}, newTestLogger())
+
+		mockDB.On("GetTagCoverage", mock.Anything, "device", 6).Return(&types.TagCoverage{
+			TotalSensors:    10,
+			CoveragePercent: 100,
+			GroupBy:         "device",
+			Objects:         []types.UntaggedSensors{},
+		}, nil)
+
+		result, err := handler.handleTagCoverage(context.Background(), createTestRequest(map[string]interface{}{
+			"group_by": "device",
+			"limit":    float64(5),
+		}))
+		require.NoError(t, err)
+
+		text := result.Content[0].(mcp.TextContent).Text
+		assert.Contains(t, text, "**100.0%**")
+		assert.Contains(t, text, "Every sensor has at least one tag.")
+	})
+
+	t.Run("Invalid group_by", func(t *testing.T) {
+		mockDB := new(MockDB)
+		handler := NewToolHandler(mockDB, &MockConfig{}, newTestLogger())
+
+		_, err := handler.handleTagCoverage(context.Background(), createTestRequest(map[string]interface{}{
+			"group_by": "probe",
+		}))
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "invalid group_by: probe")
+
+		mockDB.AssertNotCalled(t, "GetTagCoverage", mock.Anything, mock.Anything, mock.Anything)
+	})
+}
+
+func TestDiffSensorInventories(t *testing.T) {
+	sensor := func(id int, name, sensorType, device string) types.Sensor {
+		return types.Sensor{ID: id, Name: name, SensorType: sensorType, DeviceName: device}
+	}
+
+	ids := func(sensors []types.Sensor) []int {
+		result := []int{}
+		for _, s := range sensors {
+			result = append(result, s.ID)
+		}
+
+		return result
+	}
+
+	pairs := func(common []types.SensorPair) [][2]int {
+		result := [][2]int{}
+		for _, p := range common {
+			result = append(result, [2]int{p.A.ID, p.B.ID})
+		}
+
+		return result
+	}
+
+	t.Run("overlap, A-only and B-only", func(t *testing.T) {
+		a := []types.Sensor{
+			sensor(1, "Ping", "ping", "web01"),
+			sensor(2, "HTTP", "http", "web01"),
+			sensor(3, "Disk Free", "wmidiskspace", "web01"),
+		}
+		b := []types.Sensor{
+			sensor(11, "ping", "PING", "web01-new"),
+			sensor(12, "HTTP", "http", "web01-new"),
+			sensor(13, "SSL Certificate", "sslcertificate", "web01-new"),
+		}
+
+		onlyInA, onlyInB, common := diffSensorInventories(a, b)
+		assert.Equal(t, []int{3}, ids(onlyInA))
+		assert.Equal(t, []int{13}, ids(onlyInB))
+		assert.Equal(t, [][2]int{{2, 12}, {1, 11}}, pairs(common))
+	})
+
+	t.Run("same name with a different type is not a match", func(t *testing.T) {
+		onlyInA, onlyInB, common := diffSensorInventories(
+			[]types.Sensor{sensor(1, "CPU Load", "snmpcpu", "db01")},
+			[]types.Sensor{sensor(11, "CPU Load", "wmicpu", "db01")},
+		)
+		assert.Equal(t, []int{1}, ids(onlyInA))
+		assert.Equal(t, []int{11}, ids(onlyInB))
+		assert.Empty(t, common)
+	})
+
+	t.Run("duplicates are matched once, on the same device first", func(t *testing.T) {
+		a := []types.Sensor{
+			sensor(1, "Ping", "ping", "app01"),
+			sensor(2, "Ping", "ping", "app02"),
+			sensor(3, "Ping", "ping", "app03"),
+		}
+		b := []types.Sensor{
+			sensor(12, "Ping", "ping", "app02"),
+			sensor(14, "Ping", "ping", "app04"),
+		}
+
+		onlyInA, onlyInB, common := diffSensorInventories(a, b)
+		assert.Equal(t, [][2]int{{1, 14}, {2, 12}}, pairs(common))
+		assert.Equal(t, []int{3}, ids(onlyInA))
+		assert.Empty(t, onlyInB)
+	})
+
+	t.Run("empty groups", func(t *testing.T) {
+		onlyInA, onlyInB, common := diffSensorInventories(nil, []types.Sensor{sensor(11, "Ping", "ping", "web01")})
+		assert.Empty(t, onlyInA)
+		assert.Equal(t, []int{11}, ids(onlyInB))
+		assert.Empty(t, common)
+	})
+}
+
+func TestHandleDiffGroups(t *testing.T) {
+	oldSite := types.Group{ID: 10, Name: "Paris", FullPath: "Root/Sites/Paris"}
+	newSite := types.Group{ID: 20, Name: "Paris-New", FullPath: "Root/Sites/Paris-New"}
+
+	t.Run("compares the sensors below each group path", func(t *testing.T) {
+		mockDB := new(MockDB)
+		handler := NewToolHandler(mockDB, &MockConfig{}, newTestLogger())
+
+		// "Paris" also matches "Paris-New": the exact name wins
+		mockDB.On("GetGroups", mock.Anything, "Paris", (*int)(nil), 20).Return([]types.Group{oldSite, newSite}, nil)
+		mockDB.On("GetGroups", mock.Anything, "Paris-New", (*int)(nil), 20).Return([]types.Group{newSite}, nil)
+		mockDB.On("GetSensorsExtended", mock.Anything, types.SensorFilter{PathPrefix: "Root/Sites/Paris"}, "name", maxDiffGroupSensors+1).
+			Return([]types.Sensor{
+				{ID: 1, Name: "Ping", SensorType: "ping", DeviceName: "fw01"},
+				{ID: 2, Name: "Traffic", SensorType: "snmptraffic", DeviceName: "fw01"},
+			}, nil)
+		mockDB.On("GetSensorsExtended", mock.Anything, types.SensorFilter{PathPrefix: "Root/Sites/Paris-New"}, "name", maxDiffGroupSensors+1).
+			Return([]types.Sensor{
+				{ID: 11, Name: "Ping", SensorType: "ping", DeviceName: "fw01"},
+				{ID: 13, Name: "VPN", SensorType: "ipsec", DeviceName: "fw01"},
+			}, nil)
+
+		result, err := handler.handleDiffGroups(context.Background(), createTestRequest(map[string]interface{}{
+			"group_a": "Paris",
+			"group_b": "Paris-New",
+		}))
+		require.NoError(t, err)
+
+		text := resultText(t, result)
+		assert.Contains(t, text, "**1** only in A, **1** only in B, **1** in common")
+		assert.Contains(t, text, "### 🅰️ Only in A (1)\n\n| ID | Name | Type | Device |\n|----|------|------|--------|\n| 2 | Traffic | snmptraffic | fw01 |")
+		assert.Contains(t, text, "### 🅱️ Only in B (1)\n\n| ID | Name | Type | Device |\n|----|------|------|--------|\n| 13 | VPN | ipsec | fw01 |")
+		assert.Contains(t, text, "| Ping | ping | fw01 (1) | fw01 (11) |")
+
+		mockDB.AssertExpectations(t)
+	})
+
+	t.Run("ambiguous group name", func(t *testing.T) {
+		mockDB := new(MockDB)
+		handler := NewToolHandler(mockDB, &MockConfig{}, newTestLogger())
+
+		mockDB.On("GetGroups", mock.Anything, "Par", (*int)(nil), 20).Return([]types.Group{oldSite, newSite}, nil)
+
+		_, err := handler.handleDiffGroups(context.Background(), createTestRequest(map[string]interface{}{
+			"group_a": "Par",
+			"group_b": "Paris-New",
+		}))
+		assert.EqualError(t, err, `group name "Par" is ambiguous, it matches: Root/Sites/Paris, Root/Sites/Paris-New`)
+
+		mockDB.AssertNotCalled(t, "GetSensorsExtended", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("unknown group", func(t *testing.T) {
+		mockDB := new(MockDB)
+		handler := NewToolHandler(mockDB, &MockConfig{}, newTestLogger())
+
+		mockDB.On("GetGroups", mock.Anything, "Lyon", (*int)(nil), 20).Return([]types.Group{}, nil)
+
+		_, err := handler.handleDiffGroups(context.Background(), createTestRequest(map[string]interface{}{
+			"group_a": "Lyon",
+			"group_b": "Paris",
+		}))
+		assert.EqualError(t, err, "group not found: Lyon")
+	})
+}
+
+// Test handleSearch fuzzy option
+func TestHandleSearch_Fuzzy(t *testing.T) {
+	emptyResults := &types.SearchResults{
+		Groups:  []types.Group{},
+		Devices: []types.Device{},
+		Sensors: []types.Sensor{},
+	}
+
+	t.Run("Exact search by default", func(t *testing.T) {
+		mockDB := new(MockDB)
+		handler := NewToolHandler(mockDB, &MockConfig{}, newTestLogger())
+
+		mockDB.On("Search", mock.Anything, "web", 51).Return(emptyResults, nil)
+
+		request := createTestRequest(map[string]interface{}{
+			"search_term": "web",
+		})
+
+		result, err := handler.handleSearch(context.Background(), request)
+		assert.NoError(t, err)
+		assert.NotNil(t, result)
+
+		mockDB.AssertExpectations(t)
+		mockDB.AssertNotCalled(t, "SearchFuzzy")
+	})
+
+	t.Run("Fuzzy search uses configured threshold", func(t *testing.T) {
+		mockDB := new(MockDB)
+		handler := NewToolHandler(mockDB, &MockConfig{fuzzySearchThreshold: 0.45}, newTestLogger())
+
+		mockDB.On("SearchFuzzy", mock.Anything, "websrv", 0.45, 51).Return(emptyResults, nil)
+
+		request := createTestRequest(map[string]interface{}{
+			"search_term": "websrv",
+			"fuzzy":       true,
+		})
+
+		result, err := handler.handleSearch(context.Background(), request)
+		assert.NoError(t, err)
+		assert.NotNil(t, result)
+
+		mockDB.AssertExpectations(t)
+		mockDB.AssertNotCalled(t, "Search")
+	})
+
+	t.Run("Fuzzy search with explicit threshold", func(t *testing.T) {
+		mockDB := new(MockDB)
+		handler := NewToolHandler(mockDB, &MockConfig{}, newTestLogger())
+
+		mockDB.On("SearchFuzzy", mock.Anything, "websrv", 0.2, 11).Return(emptyResults, nil)
+
+		request := createTestRequest(map[string]interface{}{
+			"search_term":          "websrv",
+			"fuzzy":                true,
+			"similarity_threshold": 0.2,
+			"limit":                float64(10),
+		})
+
+		_, err := handler.handleSearch(context.Background(), request)
+		assert.NoError(t, err)
+
+		mockDB.AssertExpectations(t)
+	})
+
+	t.Run("Invalid threshold", func(t *testing.T) {
+		mockDB := new(MockDB)
+		handler := NewToolHandler(mockDB, &MockConfig{}, newTestLogger())
+
+		request := createTestRequest(map[string]interface{}{
+			"search_term":          "websrv",
+			"fuzzy":                true,
+			"similarity_threshold": 1.5,
+		})
+
+		result, err := handler.handleSearch(context.Background(), request)
+		assert.Error(t, err)
+		assert.Nil(t, result)
+
+		mockDB.AssertNotCalled(t, "SearchFuzzy")
+	})
+}
+
+// searchResultsOf builds search results with the given number of groups, devices and sensors.
+func searchResultsOf(groups, devices, sensors int) *types.SearchResults {
+	return &types.SearchResults{
+		Groups:  make([]types.Group, groups),
+		Devices: make([]types.Device, devices),
+		Sensors: make([]types.Sensor, sensors),
+	}
+}
+
+// Test capSearchResults distribution across categories
+func TestCapSearchResults(t *testing.T) {
+	tests := []struct {
+		name                     string
+		groups, devices, sensors int
+		total                    int
+		wantGroups, wantDevices  int
+		wantSensors              int
+		wantCapped               bool
+	}{
+		{"under the total", 2, 3, 4, 10, 2, 3, 4, false},
+		{"exactly the total", 3, 3, 4, 10, 3, 3, 4, false},
+		{"even split", 50, 50, 50, 30, 10, 10, 10, true},
+		{"remainder goes to sensors first", 50, 50, 50, 11, 3, 4, 4, true},
+		{"unused share is redistributed", 1, 50, 50, 21, 1, 10, 10, true},
+		{"single category", 0, 0, 50, 20, 0, 0, 20, true},
+		{"total smaller than categories", 5, 5, 5, 2, 0, 1, 1, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			results := searchResultsOf(tt.groups, tt.devices, tt.sensors)
+
+			assert.Equal(t, tt.wantCapped, capSearchResults(results, tt.total))
+			assert.Len(t, results.Groups, tt.wantGroups)
+			assert.Len(t, results.Devices, tt.wantDevices)
+			assert.Len(t, results.Sensors, tt.wantSensors)
+			assert.LessOrEqual(t, len(results.Groups)+len(results.Devices)+len(results.Sensors), tt.total)
+		})
+	}
+}
+
+// Test handleSearch total_limit option
+func TestHandleSearch_TotalLimit(t *testing.T) {
+	t.Run("Caps the aggregate", func(t *testing.T) {
+		mockDB := new(MockDB)
+		handler := NewToolHandler(mockDB, &MockConfig{}, newTestLogger())
+
+		// Without an explicit limit, each category is fetched up to total_limit
+		mockDB.On("Search", mock.Anything, "web", 31).Return(searchResultsOf(30, 30, 30), nil)
+
+		request := createTestRequest(map[string]interface{}{
+			"search_term": "web",
+			"total_limit": float64(30),
+		})
+
+		result, err := handler.handleSearch(context.Background(), request)
+		require.NoError(t, err)
+
+		text := result.Content[0].(mcp.TextContent).Text
+		assert.True(t, strings.HasPrefix(text, `{"total":90,"returned":30,"truncated":true}`), text)
+
+		mockDB.AssertExpectations(t)
+	})
+
+	t.Run("Per-category limit still applies", func(t *testing.T) {
+		mockDB := new(MockDB)
+		handler := NewToolHandler(mockDB, &MockConfig{}, newTestLogger())
+
+		mockDB.On("Search", mock.Anything, "web", 6).Return(searchResultsOf(6, 5, 5), nil)
+
+		request := createTestRequest(map[string]interface{}{
+			"search_term": "web",
+			"limit":       float64(5),
+			"total_limit": float64(100),
+		})
+
+		result, err := handler.handleSearch(context.Background(), request)
+		require.NoError(t, err)
+
+		text := result.Content[0].(mcp.TextContent).Text
+		assert.True(t, strings.HasPrefix(text, `{"returned":15,"truncated":true}`), text)
+
+		mockDB.AssertExpectations(t)
+	})
+
+	t.Run("Negative total_limit", func(t *testing.T) {
+		mockDB := new(MockDB)
+		handler := NewToolHandler(mockDB, &MockConfig{}, newTestLogger())
+
+		request := createTestRequest(map[string]interface{}{
+			"search_term": "web",
+			"total_limit": float64(-1),
+		})
+
+		_, err := handler.handleSearch(context.Background(), request)
+		assert.Error(t, err)
+
+		mockDB.AssertNotCalled(t, "Search")
+	})
+}
+
+// Test handleGetSensorStatus
+func TestHandleGetSensorStatus(t *testing.T) {
+	t.Run("Valid sensor ID", func(t *testing.T) {
+		mockDB := new(MockDB)
+		mockConfig := &MockConfig{allowCustomQueries: false}
+		logger := newTestLogger()
+
+		handler := NewToolHandler(mockDB, mockConfig, logger)
+
+		expectedSensor := &types.Sensor{
+			ID:   123,
+			Name: "Test Sensor",
+		}
+
+		mockDB.On("GetSensorByID", mock.Anything, 123, (*int)(nil)).Return(expectedSensor, nil)
+
+		request := createTestRequest(map[string]interface{}{
+			"sensor_id": float64(123),
+		})
+
+		result, err := handler.handleGetSensorStatus(context.Background(), request)
+		assert.NoError(t, err)
+		assert.NotNil(t, result)
+
+		mockDB.AssertExpectations(t)
+	})
+
+	t.Run("Invalid sensor ID - zero", func(t *testing.T) {
+		mockDB := new(MockDB)
+		mockConfig := &MockConfig{allowCustomQueries: false}
+		logger := newTestLogger()
+
+		handler := NewToolHandler(mockDB, mockConfig, logger)
+
+		request := createTestRequest(map[string]interface{}{
+			"sensor_id": float64(0),
+		})
+
+		result, err := handler.handleGetSensorStatus(context.Background(), request)
+		assert.Error(t, err)
+		assert.Nil(t, result)
+		assert.Contains(t, err.Error(), "sensor_id must be greater than 0")
+
+		mockDB.AssertNotCalled(t, "GetSensorByID")
+	})
+
+	t.Run("Invalid sensor ID - negative", func(t *testing.T) {
+		mockDB := new(MockDB)
+		mockConfig := &MockConfig{allowCustomQueries: false}
+		logger := newTestLogger()
+
+		handler := NewToolHandler(mockDB, mockConfig, logger)
+
+		request := createTestRequest(map[string]interface{}{
+			"sensor_id": float64(-5),
+		})
+
+		result, err := handler.handleGetSensorStatus(context.Background(), request)
+		assert.Error(t, err)
+		assert.Nil(t, result)
+		assert.Contains(t, err.Error(), "sensor_id must be greater than 0")
+
+		mockDB.AssertNotCalled(t, "GetSensorByID")
+	})
+
+	t.Run("Missing sensor_id argument", func(t *testing.T) {
+		mockDB := new(MockDB)
+		mockConfig := &MockConfig{allowCustomQueries: false}
+		logger := newTestLogger()
+
+		handler := NewToolHandler(mockDB, mockConfig, logger)
+
+		request := createTestRequest(map[string]interface{}{})
+
+		result, err := handler.handleGetSensorStatus(context.Background(), request)
+		assert.Error(t, err)
+		assert.Nil(t, result)
+		assert.Contains(t, err.Error(), "sensor_id must be greater than 0")
+
+		mockDB.AssertNotCalled(t, "GetSensorByID")
+	})
+
+	t.Run("Sensor ID on several servers", func(t *testing.T) {
+		mockDB := new(MockDB)
+		handler := NewToolHandler(mockDB, &MockConfig{}, newTestLogger())
+
+		mockDB.On("GetSensorByID", mock.Anything, 2001, (*int)(nil)).Return(nil, &types.AmbiguousSensorError{
+			SensorID: 2001,
+			Sensors: []types.Sensor{
+				{ID: 2001, ServerID: 1, Name: "Ping", DeviceName: "paris-fw", Status: types.StatusUp, StatusText: "Up"},
+				{ID: 2001, ServerID: 2, Name: "HTTP", DeviceName: "lyon-web", Status: types.StatusDown, StatusText: "Down"},
+			},
+		})
+
+		result, err := handler.handleGetSensorStatus(context.Background(), createTestRequest(map[string]interface{}{
+			"sensor_id": float64(2001),
+		}))
+		require.NoError(t, err)
+
+		text := result.Content[0].(mcp.TextContent).Text
+		assert.Contains(t, text, "Sensor ID 2001 exists on 2 PRTG servers")
+		assert.Contains(t, text, "| 1 | Ping | paris-fw |")
+		assert.Contains(t, text, "| 2 | HTTP | lyon-web |")
+	})
+
+	t.Run("Scoped to a server", func(t *testing.T) {
+		mockDB := new(MockDB)
+		handler := NewToolHandler(mockDB, &MockConfig{}, newTestLogger())
+
+		serverID := 2
+		mockDB.On("GetSensorByID", mock.Anything, 2001, &serverID).
+			Return(&types.Sensor{ID: 2001, ServerID: 2, Name: "HTTP"}, nil)
+
+		result, err := handler.handleGetSensorStatus(context.Background(), createTestRequest(map[string]interface{}{
+			"sensor_id": float64(2001),
+			"server_id": float64(2),
+		}))
+		require.NoError(t, err)
+		assert.Contains(t, result.Content[0].(mcp.TextContent).Text, `"server_id": 2`)
+
+		mockDB.AssertExpectations(t)
+	})
+}
+
+// Test handleDeviceOverview
+func TestHandleDeviceOverview(t *testing.T) {
+	t.Run("Valid device name", func(t *testing.T) {
+		mockDB := new(MockDB)
+		mockConfig := &MockConfig{allowCustomQueries: false}
+		logger := newTestLogger()
+
+		handler := NewToolHandler(mockDB, mockConfig, logger)
+
+		expectedOverview := &types.DeviceOverview{
+			Device: types.Device{
+				Name: "Server1",
+			},
+			TotalSensors: 10,
+			UpSensors:    8,
+			DownSensors:  2,
+		}
+
+		mockDB.On("GetDeviceOverview", mock.Anything, "Server1").Return(expectedOverview, nil)
+
+		request := createTestRequest(map[string]interface{}{
+			"device_name": "Server1",
+		})
+
+		result, err := handler.handleDeviceOverview(context.Background(), request)
+		assert.NoError(t, err)
+		assert.NotNil(t, result)
+
+		mockDB.AssertExpectations(t)
+	})
+
+	t.Run("Empty device name", func(t *testing.T) {
+		mockDB := new(MockDB)
+		mockConfig := &MockConfig{allowCustomQueries: false}
+		logger := newTestLogger()
+
+		handler := NewToolHandler(mockDB, mockConfig, logger)
+
+		request := createTestRequest(map[string]interface{}{
+			"device_name": "",
+		})
+
+		result, err := handler.handleDeviceOverview(context.Background(), request)
+		assert.Error(t, err)
+		assert.Nil(t, result)
+		assert.Contains(t, err.Error(), "device_name is required")
+
+		mockDB.AssertNotCalled(t, "GetDeviceOverview")
+	})
+
+	t.Run("Missing device_name argument", func(t *testing.T) {
+		mockDB := new(MockDB)
+		mockConfig := &MockConfig{allowCustomQueries: false}
+		logger := newTestLogger()
+
+		handler := NewToolHandler(mockDB, mockConfig, logger)
+
+		request := createTestRequest(map[string]interface{}{})
+
+		result, err := handler.handleDeviceOverview(context.Background(), request)
+		assert.Error(t, err)
+		assert.Nil(t, result)
+		assert.Contains(t, err.Error(), "device_name is required")
+
+		mockDB.AssertNotCalled(t, "GetDeviceOverview")
+	})
+}
+
+// Test handleCustomQuery - SECURITY CRITICAL
+func TestHandleCustomQuery_Security(t *testing.T) {
+	t.Run("Custom queries disabled - SECURITY", func(t *testing.T) {
+		mockDB := new(MockDB)
+		mockConfig := &MockConfig{allowCustomQueries: false} // DISABLED
+		logger := newTestLogger()
+
+		handler := NewToolHandler(mockDB, mockConfig, logger)
+
+		request := createTestRequest(map[string]interface{}{
+			"query": "SELECT * FROM prtg_sensor",
+			"limit": float64(100),
+		})
+
+		result, err := handler.handleCustomQuery(context.Background(), request)
+		assert.Error(t, err)
+		assert.Nil(t, result)
+		assert.Contains(t, err.Error(), "custom SQL queries are disabled")
+		assert.Contains(t, err.Error(), "allow_custom_queries: true")
+
+		// Database should NOT be called when queries are disabled
+		mockDB.AssertNotCalled(t, "StreamCustomQuery")
+	})
+
+	t.Run("Custom queries enabled - valid query", func(t *testing.T) {
+		mockDB := new(MockDB)
+		mockConfig := &MockConfig{allowCustomQueries: true} // ENABLED
+		logger := newTestLogger()
+
+		handler := NewToolHandler(mockDB, mockConfig, logger)
+
+		mockDB.On("StreamCustomQuery", mock.Anything, "SELECT * FROM prtg_sensor", 100).
+			Return([]string{"id", "name"}, [][]interface{}{{1, "Sensor1"}, {2, "Sensor2"}}, nil)
+
+		request := createTestRequest(map[string]interface{}{
+			"query": "SELECT * FROM prtg_sensor",
+			"limit": float64(100),
+		})
+
+		result, err := handler.handleCustomQuery(context.Background(), request)
+		require.NoError(t, err)
+
+		textContent, ok := result.Content[0].(mcp.TextContent)
+		require.True(t, ok)
+
+		// Same layout as a JSON array of the rows
+		expected, err := json.MarshalIndent([]map[string]interface{}{
+			{"id": 1, "name": "Sensor1"},
+			{"id": 2, "name": "Sensor2"},
+		}, "", "  ")
+		require.NoError(t, err)
+		assert.Equal(t, "Found 2 result(s):\n\n"+string(expected), textContent.Text)
+
+		mockDB.AssertExpectations(t)
+	})
+
+	t.Run("Empty query", func(t *testing.T) {
+		mockDB := new(MockDB)
+		mockConfig := &MockConfig{allowCustomQueries: true}
+		logger := newTestLogger()
+
+		handler := NewToolHandler(mockDB, mockConfig, logger)
 
 		request := createTestRequest(map[string]interface{}{
 			"query": "",
 		})
 
-		result, err := handler.handleCustomQuery(context.Background(), request)
-		assert.Error(t, err)
-		assert.Nil(t, result)
-		assert.Contains(t, err.Error(), "query is required")
+		result, err := handler.handleCustomQuery(context.Background(), request)
+		assert.Error(t, err)
+		assert.Nil(t, result)
+		assert.Contains(t, err.Error(), "query is required")
+
+		mockDB.AssertNotCalled(t, "StreamCustomQuery")
+	})
+
+	t.Run("Default limit applied", func(t *testing.T) {
+		mockDB := new(MockDB)
+		mockConfig := &MockConfig{allowCustomQueries: true}
+		logger := newTestLogger()
+
+		handler := NewToolHandler(mockDB, mockConfig, logger)
+
+		rows := make([][]interface{}, 1000)
+		for i := range rows {
+			rows[i] = []interface{}{i + 1}
+		}
+
+		mockDB.On("StreamCustomQuery", mock.Anything, "SELECT * FROM prtg_sensor", types.CustomQueryLimit).
+			Return([]string{"id"}, rows, nil)
+
+		request := createTestRequest(map[string]interface{}{
+			"query": "SELECT * FROM prtg_sensor",
+			// No limit specified
+		})
+
+		result, err := handler.handleCustomQuery(context.Background(), request)
+		require.NoError(t, err)
+
+		// Only the default 100 rows are read, the rest is reported as truncated
+		text := result.Content[0].(mcp.TextContent).Text
+		header, body, found := strings.Cut(text, "\n\n")
+		require.True(t, found)
+		assert.Equal(t, "Found more than 100 result(s), showing the first 100 (raise limit to see more):", header)
+
+		var returned []map[string]int
+		require.NoError(t, json.Unmarshal([]byte(body), &returned))
+		require.Len(t, returned, 100)
+		assert.Equal(t, 1, returned[0]["id"])
+		assert.Equal(t, 100, returned[99]["id"])
+
+		mockDB.AssertExpectations(t)
+	})
+
+	t.Run("Empty result", func(t *testing.T) {
+		mockDB := new(MockDB)
+		handler := NewToolHandler(mockDB, &MockConfig{allowCustomQueries: true}, newTestLogger())
+
+		mockDB.On("StreamCustomQuery", mock.Anything, "SELECT id FROM prtg_sensor WHERE 1=0", types.CustomQueryLimit).
+			Return([]string{"id"}, [][]interface{}{}, nil)
+
+		result, err := handler.handleCustomQuery(context.Background(), createTestRequest(map[string]interface{}{
+			"query": "SELECT id FROM prtg_sensor WHERE 1=0",
+		}))
+		require.NoError(t, err)
+		assert.Equal(t, "Found 0 result(s):\n\n[]", result.Content[0].(mcp.TextContent).Text)
+	})
+
+	t.Run("User limit is passed to the database", func(t *testing.T) {
+		mockDB := new(MockDB)
+		handler := NewToolHandler(mockDB, &MockConfig{allowCustomQueries: true}, newTestLogger())
+
+		mockDB.On("StreamCustomQuery", mock.Anything, "SELECT id FROM prtg_sensor", 2).
+			Return([]string{"id"}, [][]interface{}{{1}, {2}, {3}}, nil)
+
+		result, err := handler.handleCustomQuery(context.Background(), createTestRequest(map[string]interface{}{
+			"query": "SELECT id FROM prtg_sensor",
+			"limit": float64(2),
+		}))
+		require.NoError(t, err)
+
+		header, _, _ := strings.Cut(result.Content[0].(mcp.TextContent).Text, "\n\n")
+		assert.Equal(t, "Found more than 2 result(s), showing the first 2 (raise limit to see more):", header)
+		mockDB.AssertExpectations(t)
+	})
+
+	t.Run("Limit above the cap", func(t *testing.T) {
+		mockDB := new(MockDB)
+		handler := NewToolHandler(mockDB, &MockConfig{allowCustomQueries: true}, newTestLogger())
+
+		rows := make([][]interface{}, types.MaxCustomQueryRows+1)
+		for i := range rows {
+			rows[i] = []interface{}{i + 1}
+		}
+
+		// A LIMIT in the query and a limit argument above the cap both stop at the cap
+		mockDB.On("StreamCustomQuery", mock.Anything, "SELECT id FROM prtg_sensor LIMIT 5000", types.MaxCustomQueryRows).
+			Return([]string{"id"}, rows, nil)
+
+		result, err := handler.handleCustomQuery(context.Background(), createTestRequest(map[string]interface{}{
+			"query": "SELECT id FROM prtg_sensor LIMIT 5000",
+			"limit": float64(5000),
+		}))
+		require.NoError(t, err)
+
+		header, _, _ := strings.Cut(result.Content[0].(mcp.TextContent).Text, "\n\n")
+		assert.Equal(t, "Found more than 1000 result(s), showing the first 1000 (the maximum, narrow the query to see the rest):", header)
+		mockDB.AssertExpectations(t)
+	})
+}
+
+// Test handleGetSensors - default values
+func TestHandleGetSensors_Defaults(t *testing.T) {
+	t.Run("Default limit applied", func(t *testing.T) {
+		mockDB := new(MockDB)
+		mockConfig := &MockConfig{allowCustomQueries: false}
+		logger := newTestLogger()
+
+		handler := NewToolHandler(mockDB, mockConfig, logger)
+
+		expectedSensors := []types.Sensor{
+			{ID: 1, Name: "Sensor1"},
+		}
+
+		// The documented default of 50 applies when limit is omitted or <= 0
+		mockDB.On("GetSensorsExtended", mock.Anything, types.SensorFilter{}, "name", 51).
+			Return(expectedSensors, nil)
+
+		for _, arguments := range []map[string]interface{}{{}, {"limit": float64(0)}} {
+			result, err := handler.handleGetSensors(context.Background(), createTestRequest(arguments))
+			assert.NoError(t, err)
+			assert.NotNil(t, result)
+		}
+
+		mockDB.AssertExpectations(t)
+		mockDB.AssertNumberOfCalls(t, "GetSensorsExtended", 2)
+	})
+
+	t.Run("Configured default limit", func(t *testing.T) {
+		mockDB := new(MockDB)
+		handler := NewToolHandler(mockDB, &MockConfig{defaultSensorLimit: 200}, newTestLogger())
+
+		mockDB.On("GetSensorsExtended", mock.Anything, types.SensorFilter{}, "name", 201).
+			Return([]types.Sensor{}, nil)
+
+		_, err := handler.handleGetSensors(context.Background(), createTestRequest(map[string]interface{}{}))
+		require.NoError(t, err)
+
+		mockDB.AssertExpectations(t)
+	})
+
+	t.Run("Negative limit corrected", func(t *testing.T) {
+		mockDB := new(MockDB)
+		mockConfig := &MockConfig{allowCustomQueries: false}
+		logger := newTestLogger()
+
+		handler := NewToolHandler(mockDB, mockConfig, logger)
+
+		expectedSensors := []types.Sensor{}
+
+		mockDB.On("GetSensorsExtended", mock.Anything, types.SensorFilter{}, "name", types.SensorsLimit+1).
+			Return(expectedSensors, nil)
+
+		request := createTestRequest(map[string]interface{}{
+			"limit": float64(-10),
+		})
+
+		result, err := handler.handleGetSensors(context.Background(), request)
+		assert.NoError(t, err)
+		assert.NotNil(t, result)
+
+		mockDB.AssertExpectations(t)
+	})
+}
+
+func TestHandleGetSensors_HostOrder(t *testing.T) {
+	t.Run("Lexical and numeric IP order", func(t *testing.T) {
+		mockDB := new(MockDB)
+		handler := NewToolHandler(mockDB, &MockConfig{}, newTestLogger())
+
+		mockDB.On("GetSensorsExtended", mock.Anything, types.SensorFilter{}, "host", 51).Return([]types.Sensor{}, nil)
+		mockDB.On("GetSensorsExtended", mock.Anything, types.SensorFilter{}, "host_ip", 51).Return([]types.Sensor{}, nil)
+
+		_, err := handler.handleGetSensors(context.Background(), createTestRequest(map[string]interface{}{
+			"order_by": "host",
+		}))
+		require.NoError(t, err)
+
+		_, err = handler.handleGetSensors(context.Background(), createTestRequest(map[string]interface{}{
+			"order_by": "host",
+			"ip_sort":  true,
+		}))
+		require.NoError(t, err)
+
+		mockDB.AssertExpectations(t)
+	})
+
+	t.Run("ip_sort without host order", func(t *testing.T) {
+		mockDB := new(MockDB)
+		handler := NewToolHandler(mockDB, &MockConfig{}, newTestLogger())
+
+		_, err := handler.handleGetSensors(context.Background(), createTestRequest(map[string]interface{}{
+			"ip_sort": true,
+		}))
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "ip_sort requires order_by 'host'")
+
+		mockDB.AssertNotCalled(t, "GetSensorsExtended", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	})
+}
+
+func TestHandleGetSensors_Statuses(t *testing.T) {
+	t.Run("Any of several statuses", func(t *testing.T) {
+		mockDB := new(MockDB)
+		handler := NewToolHandler(mockDB, &MockConfig{}, newTestLogger())
+
+		filter := types.SensorFilter{Statuses: []int{types.StatusDown, types.StatusWarning}}
+		mockDB.On("GetSensorsExtended", mock.Anything, filter, "name", 51).Return([]types.Sensor{
+			{ID: 1, Name: "HTTP", Status: types.StatusDown, StatusText: "Down"},
+			{ID: 2, Name: "Disk", Status: types.StatusWarning, StatusText: "Warning"},
+		}, nil)
+
+		result, err := handler.handleGetSensors(context.Background(), createTestRequest(map[string]interface{}{
+			"statuses": []interface{}{float64(5), float64(4)},
+		}))
+		require.NoError(t, err)
+
+		text := result.Content[0].(mcp.TextContent).Text
+		assert.Contains(t, text, "HTTP")
+		assert.Contains(t, text, "Disk")
+
+		mockDB.AssertExpectations(t)
+	})
+
+	tests := []struct {
+		name    string
+		args    map[string]interface{}
+		wantErr string
+	}{
+		{"status and statuses", map[string]interface{}{"status": float64(5), "statuses": []interface{}{float64(4)}},
+			"provide either status or statuses, not both"},
+		{"unknown status code", map[string]interface{}{"statuses": []interface{}{float64(5), float64(42)}},
+			"statuses must be PRTG status codes (1-14), got 42"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockDB := new(MockDB)
+			handler := NewToolHandler(mockDB, &MockConfig{}, newTestLogger())
+
+			_, err := handler.handleGetSensors(context.Background(), createTestRequest(tt.args))
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tt.wantErr)
+
+			mockDB.AssertNotCalled(t, "GetSensorsExtended", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+		})
+	}
+}
+
+func TestHandleGetSensors_IncludeLinks(t *testing.T) {
+	request := createTestRequest(map[string]interface{}{"include_links": true})
+
+	t.Run("requires a PRTG web interface address", func(t *testing.T) {
+		mockDB := new(MockDB)
+		handler := NewToolHandler(mockDB, &MockConfig{}, newTestLogger())
+
+		_, err := handler.handleGetSensors(context.Background(), request)
+		assert.ErrorContains(t, err, "prtg.ui_base_url")
+		mockDB.AssertNotCalled(t, "GetSensorsExtended", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("links each sensor", func(t *testing.T) {
+		mockDB := new(MockDB)
+		handler := NewToolHandler(mockDB, &MockConfig{prtgUIBaseURL: "https://prtg.example.com"}, newTestLogger())
+
+		mockDB.On("GetSensorsExtended", mock.Anything, types.SensorFilter{}, "name", types.SensorsLimit+1).
+			Return([]types.Sensor{{ID: 1001, Name: "Ping"}, {ID: 1002, Name: "HTTP"}}, nil)
+
+		result, err := handler.handleGetSensors(context.Background(), request)
+		require.NoError(t, err)
+
+		text := result.Content[0].(mcp.TextContent).Text
+		assert.Contains(t, text, "[open](https://prtg.example.com/sensor.htm?id=1001)")
+		assert.Contains(t, text, `"url": "https://prtg.example.com/sensor.htm?id=1002"`)
+	})
+
+	t.Run("links the UI ID of a prefixed server", func(t *testing.T) {
+		mockDB := new(MockDB)
+		handler := NewToolHandler(mockDB, &MockConfig{
+			prtgUIBaseURL: "https://prtg-eu.example.com",
+			idOffsets:     types.IDOffsets{"eu": 1000000},
+		}, newTestLogger())
+
+		mockDB.On("GetSensorsExtended", mock.Anything, types.SensorFilter{}, "name", types.SensorsLimit+1).
+			Return([]types.Sensor{{ID: 1002045, DeviceID: 1000100, Name: "Ping"}}, nil)
+
+		result, err := handler.handleGetSensors(context.Background(), createTestRequest(map[string]interface{}{
+			"include_links": true,
+			"id_prefix":     "eu",
+		}))
+		require.NoError(t, err)
+
+		text := result.Content[0].(mcp.TextContent).Text
+		assert.Contains(t, text, "[open](https://prtg-eu.example.com/sensor.htm?id=2045)")
+		assert.Contains(t, text, `"id": 2045,`)
+		assert.Contains(t, text, `"device_id": 100,`)
+		assert.NotContains(t, text, "1002045")
+
+		_, err = handler.handleGetSensors(context.Background(), createTestRequest(map[string]interface{}{
+			"id_prefix": "us",
+		}))
+		assert.ErrorContains(t, err, `unknown id_prefix "us"`)
+	})
+}
+
+func TestHandleGetSensors_IncludePrimaryChannel(t *testing.T) {
+	request := createTestRequest(map[string]interface{}{"include_primary_channel": true})
+
+	channels := []prtg.Channel{
+		{ID: "1001.-4", Name: "Downtime", Basic: prtg.ChannelBasic{DisplayUnit: "%"},
+			LastMeasurement: &prtg.ChannelMeasurement{DisplayValue: 0}},
+		{ID: "1001.0", Name: "Total", Basic: prtg.ChannelBasic{DisplayUnit: "%"},
+			LastMeasurement: &prtg.ChannelMeasurement{DisplayValue: 92}},
+	}
+
+	sensors := func(n int) []types.Sensor {
+		list := make([]types.Sensor, n)
+		for i := range list {
+			list[i] = types.Sensor{ID: 1001 + i, Name: fmt.Sprintf("CPU %d", i)}
+		}
+
+		return list
+	}
+
+	t.Run("shows the primary channel value", func(t *testing.T) {
+		mockDB := new(MockDB)
+		mockClient := new(MockPRTGClient)
+
+		handler := NewToolHandler(mockDB, &MockConfig{}, newTestLogger())
+		handler.SetPRTGClient(mockClient)
+
+		mockDB.On("GetSensorsExtended", mock.Anything, types.SensorFilter{}, "name", types.SensorsLimit+1).Return(sensors(2), nil)
+		mockClient.On("GetChannelsBySensor", mock.Anything, 1001).Return(channels, nil)
+		mockClient.On("GetChannelsBySensor", mock.Anything, 1002).Return([]prtg.Channel{}, nil)
+
+		result, err := handler.handleGetSensors(context.Background(), request)
+		require.NoError(t, err)
+
+		text := resultText(t, result)
+		assert.Contains(t, text, "| Value |")
+		assert.Contains(t, text, "| Total: 92.00 % |")
+		assert.Contains(t, text, `"primary_value": "Total: 92.00 %"`)
+		assert.NotContains(t, text, "Primary channel values")
+
+		// A second listing is served from the channel cache
+		_, err = handler.handleGetSensors(context.Background(), request)
+		require.NoError(t, err)
+		mockClient.AssertNumberOfCalls(t, "GetChannelsBySensor", 2)
+	})
+
+	t.Run("caps the sensors enriched", func(t *testing.T) {
+		mockDB := new(MockDB)
+		mockClient := new(MockPRTGClient)
+
+		handler := NewToolHandler(mockDB, &MockConfig{}, newTestLogger())
+		handler.SetPRTGClient(mockClient)
+
+		mockDB.On("GetSensorsExtended", mock.Anything, types.SensorFilter{}, "name", types.SensorsLimit+1).
+			Return(sensors(maxPrimaryChannelSensors+5), nil)
+		mockClient.On("GetChannelsBySensor", mock.Anything, mock.Anything).Return(channels, nil)
+
+		result, err := handler.handleGetSensors(context.Background(), request)
+		require.NoError(t, err)
+
+		mockClient.AssertNumberOfCalls(t, "GetChannelsBySensor", maxPrimaryChannelSensors)
+		mockClient.AssertNotCalled(t, "GetChannelsBySensor", mock.Anything, 1001+maxPrimaryChannelSensors)
+		assert.Contains(t, resultText(t, result), "Primary channel values shown for the first 25 of 30 sensors")
+	})
+
+	t.Run("degrades to status only when the API is down", func(t *testing.T) {
+		mockDB := new(MockDB)
+		mockClient := new(MockPRTGClient)
+
+		handler := NewToolHandler(mockDB, &MockConfig{}, newTestLogger())
+		handler.SetPRTGClient(mockClient)
+
+		mockDB.On("GetSensorsExtended", mock.Anything, types.SensorFilter{}, "name", types.SensorsLimit+1).Return(sensors(2), nil)
+		mockClient.On("GetChannelsBySensor", mock.Anything, mock.Anything).Return(nil, errors.New("connection refused"))
+
+		result, err := handler.handleGetSensors(context.Background(), request)
+		require.NoError(t, err)
 
-		mockDB.AssertNotCalled(t, "ExecuteCustomQuery")
+		text := resultText(t, result)
+		assert.Contains(t, text, "Primary channel values unavailable: PRTG API unavailable.")
+		assert.NotContains(t, text, "| Value |")
+		assert.Contains(t, text, "CPU 1")
 	})
+}
 
-	t.Run("Default limit applied", func(t *testing.T) {
+func TestHandleGetSensors_Fields(t *testing.T) {
+	t.Run("rejects unknown fields", func(t *testing.T) {
 		mockDB := new(MockDB)
-		mockConfig := &MockConfig{allowCustomQueries: true}
-		logger := newTestLogger()
+		handler := NewToolHandler(mockDB, &MockConfig{}, newTestLogger())
 
-		handler := NewToolHandler(mockDB, mockConfig, logger)
+		_, err := handler.handleGetSensors(context.Background(), createTestRequest(map[string]interface{}{
+			"fields": []interface{}{"id", "password"},
+		}))
+		assert.ErrorContains(t, err, `unknown field "password"`)
+		mockDB.AssertNotCalled(t, "GetSensorsExtended", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	})
 
-		expectedResults := []map[string]interface{}{}
+	t.Run("limits the JSON output", func(t *testing.T) {
+		mockDB := new(MockDB)
+		handler := NewToolHandler(mockDB, &MockConfig{}, newTestLogger())
 
-		// Should use default limit of 100
-		mockDB.On("ExecuteCustomQuery", mock.Anything, "SELECT * FROM prtg_sensor", 100).
-			Return(expectedResults, nil)
+		mockDB.On("GetSensorsExtended", mock.Anything, types.SensorFilter{}, "name", types.SensorsLimit+1).
+			Return([]types.Sensor{{ID: 1001, Name: "Ping", SensorType: "ping", DeviceName: "web01"}}, nil)
 
-		request := createTestRequest(map[string]interface{}{
-			"query": "SELECT * FROM prtg_sensor",
-			// No limit specified
+		result, err := handler.handleGetSensors(context.Background(), createTestRequest(map[string]interface{}{
+			"fields": []interface{}{"id", "status"},
+		}))
+		require.NoError(t, err)
+
+		text := result.Content[0].(mcp.TextContent).Text
+		assert.Contains(t, text, `"id": 1001`)
+		assert.Contains(t, text, `"status": 0`)
+		assert.NotContains(t, text, `"sensor_type"`)
+		assert.NotContains(t, text, `"device_name"`)
+	})
+}
+
+func TestHandleGetSensors_IncludeJSON(t *testing.T) {
+	tests := []struct {
+		name     string
+		omitJSON bool
+		args     map[string]interface{}
+		wantJSON bool
+	}{
+		{"server default", false, map[string]interface{}{}, true},
+		{"disabled by configuration", true, map[string]interface{}{}, false},
+		{"disabled per call", false, map[string]interface{}{"include_json": false}, false},
+		{"enabled per call", true, map[string]interface{}{"include_json": true}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockDB := new(MockDB)
+			handler := NewToolHandler(mockDB, &MockConfig{omitJSONPayload: tt.omitJSON}, newTestLogger())
+
+			mockDB.On("GetSensorsExtended", mock.Anything, types.SensorFilter{}, "name", types.SensorsLimit+1).
+				Return([]types.Sensor{{ID: 1001, Name: "Ping"}}, nil)
+
+			result, err := handler.handleGetSensors(context.Background(), createTestRequest(tt.args))
+			require.NoError(t, err)
+
+			text := result.Content[0].(mcp.TextContent).Text
+			assert.Contains(t, text, "| 1001 | Ping |")
+			assert.Equal(t, tt.wantJSON, strings.Contains(text, "```json"))
 		})
+	}
+}
 
-		result, err := handler.handleCustomQuery(context.Background(), request)
-		assert.NoError(t, err)
-		assert.NotNil(t, result)
+func TestHandleGetHierarchy_IncludeJSON(t *testing.T) {
+	node := &types.HierarchyNode{Group: types.Group{ID: 1, Name: "Servers"}}
+
+	for format, wantJSON := range map[string]bool{"": false, "both": false, "json": true} {
+		mockDB := new(MockDB)
+		handler := NewToolHandler(mockDB, &MockConfig{omitJSONPayload: true}, newTestLogger())
+
+		mockDB.On("GetHierarchy", mock.Anything, "", mock.Anything).Return(node, nil)
+
+		result, err := handler.handleGetHierarchy(context.Background(),
+			createTestRequest(map[string]interface{}{"output_format": format}))
+		require.NoError(t, err)
+
+		text := result.Content[0].(mcp.TextContent).Text
+		assert.Equal(t, wantJSON, strings.Contains(text, `"name": "Servers"`), "output_format %q", format)
+	}
+}
+
+func TestHandleGetHierarchy_DeviceName(t *testing.T) {
+	parentID := 10
+	device := types.Device{ID: 40, Name: "db01", Host: "10.0.0.40", GroupID: 20}
+
+	t.Run("path and sensors", func(t *testing.T) {
+		mockDB := new(MockDB)
+		handler := NewToolHandler(mockDB, &MockConfig{}, newTestLogger())
+
+		mockDB.On("GetDeviceOverview", mock.Anything, "db01").Return(&types.DeviceOverview{
+			Device: device,
+			Sensors: []types.Sensor{
+				{ID: 401, Name: "Ping", Status: types.StatusDown, StatusText: "Down"},
+				{ID: 402, Name: "CPU Load", Status: types.StatusUp, StatusText: "Up"},
+			},
+		}, nil)
+		mockDB.On("GetDevicePath", mock.Anything, 40).Return(&types.DevicePath{
+			Ancestors: []types.Group{
+				{ID: 10, Name: "Local Probe", IsProbeNode: true},
+				{ID: 20, Name: "Servers", ParentID: &parentID},
+			},
+			Device: device,
+		}, nil)
+
+		result, err := handler.handleGetHierarchy(context.Background(), createTestRequest(map[string]interface{}{
+			"device_name":            "db01",
+			"max_sensors_per_device": float64(1),
+			"output_format":          "tree",
+		}))
+		require.NoError(t, err)
+
+		text := result.Content[0].(mcp.TextContent).Text
+		assert.Contains(t, text, "**Path:** Local Probe → Servers → db01")
+		assert.Contains(t, text, "└── 📡 Local Probe (ID: 10)\n    └── 📁 Servers (ID: 20)\n        └── 🖥️  db01 (ID: 40, 10.0.0.40)")
+		assert.Contains(t, text, "showing 1 of 2 sensors")
+		assert.Contains(t, text, "Ping (Down)")
+		assert.NotContains(t, text, "CPU Load")
 
 		mockDB.AssertExpectations(t)
+		mockDB.AssertNotCalled(t, "GetHierarchy")
 	})
-}
 
-// Test handleGetSensors - default values
-func TestHandleGetSensors_Defaults(t *testing.T) {
-	t.Run("Default limit applied", func(t *testing.T) {
+	t.Run("combined with group_name", func(t *testing.T) {
 		mockDB := new(MockDB)
-		mockConfig := &MockConfig{allowCustomQueries: false}
-		logger := newTestLogger()
+		handler := NewToolHandler(mockDB, &MockConfig{}, newTestLogger())
 
-		handler := NewToolHandler(mockDB, mockConfig, logger)
+		_, err := handler.handleGetHierarchy(context.Background(), createTestRequest(map[string]interface{}{
+			"device_name": "db01",
+			"group_name":  "Servers",
+		}))
+		assert.EqualError(t, err, "group_name and device_name cannot be combined")
+		mockDB.AssertNotCalled(t, "GetDeviceOverview")
+	})
 
-		expectedSensors := []types.Sensor{
-			{ID: 1, Name: "Sensor1"},
-		}
+	t.Run("device not found", func(t *testing.T) {
+		mockDB := new(MockDB)
+		handler := NewToolHandler(mockDB, &MockConfig{}, newTestLogger())
 
-		// Should use default limit of 1000 when limit <= 0
-		mockDB.On("GetSensorsExtended", mock.Anything, "", "", "", "", (*int)(nil), "", "name", 1000).
-			Return(expectedSensors, nil)
+		mockDB.On("GetDeviceOverview", mock.Anything, "nope").Return(nil, errors.New("device not found"))
 
-		request := createTestRequest(map[string]interface{}{
-			"limit": float64(0), // Zero or negative should default to 1000
-		})
+		_, err := handler.handleGetHierarchy(context.Background(), createTestRequest(map[string]interface{}{
+			"device_name": "nope",
+		}))
+		assert.EqualError(t, err, "failed to get device: device not found")
+		mockDB.AssertNotCalled(t, "GetDevicePath")
+	})
+}
 
-		result, err := handler.handleGetSensors(context.Background(), request)
-		assert.NoError(t, err)
-		assert.NotNil(t, result)
+// Test handleGetSensors count_only mode
+func TestHandleGetSensors_CountOnly(t *testing.T) {
+	mockDB := new(MockDB)
+	handler := NewToolHandler(mockDB, &MockConfig{}, newTestLogger())
+
+	downStatus := types.StatusDown
+	expectedFilter := types.SensorFilter{
+		GroupName: "network",
+		Status:    &downStatus,
+	}
+
+	mockDB.On("CountSensors", mock.Anything, expectedFilter).Return(17, nil)
+
+	request := createTestRequest(map[string]interface{}{
+		"group_name": "network",
+		"status":     float64(types.StatusDown),
+		"count_only": true,
+	})
+
+	result, err := handler.handleGetSensors(context.Background(), request)
+	require.NoError(t, err)
+
+	textContent, ok := result.Content[0].(mcp.TextContent)
+	require.True(t, ok)
+	assert.Contains(t, textContent.Text, "**17 sensor(s)**")
+	assert.Contains(t, textContent.Text, "group: network")
+	assert.NotContains(t, textContent.Text, "```json")
+
+	mockDB.AssertExpectations(t)
+	mockDB.AssertNotCalled(t, "GetSensorsExtended")
+}
+
+// Test handleGetSensors reports the real total in the metadata when the listing is truncated
+func TestHandleGetSensors_TruncatedMetadata(t *testing.T) {
+	sensors := []types.Sensor{{ID: 1, Name: "Ping"}, {ID: 2, Name: "HTTP"}, {ID: 3, Name: "CPU"}}
+
+	t.Run("Rows beyond the limit are counted", func(t *testing.T) {
+		mockDB := new(MockDB)
+		handler := NewToolHandler(mockDB, &MockConfig{}, newTestLogger())
+
+		mockDB.On("GetSensorsExtended", mock.Anything, types.SensorFilter{}, "name", 3).
+			Return(sensors, nil)
+		mockDB.On("CountSensors", mock.Anything, types.SensorFilter{}).Return(342, nil)
+
+		result, err := handler.handleGetSensors(context.Background(), createTestRequest(map[string]interface{}{
+			"limit": float64(2),
+		}))
+		require.NoError(t, err)
+
+		text := result.Content[0].(mcp.TextContent).Text
+		assert.True(t, strings.HasPrefix(text, `{"total":342,"returned":2,"truncated":true}`), text)
+		assert.NotContains(t, text, "CPU")
 
 		mockDB.AssertExpectations(t)
 	})
 
-	t.Run("Negative limit corrected", func(t *testing.T) {
+	t.Run("Exactly the limit is complete", func(t *testing.T) {
 		mockDB := new(MockDB)
-		mockConfig := &MockConfig{allowCustomQueries: false}
-		logger := newTestLogger()
+		handler := NewToolHandler(mockDB, &MockConfig{}, newTestLogger())
 
-		handler := NewToolHandler(mockDB, mockConfig, logger)
+		mockDB.On("GetSensorsExtended", mock.Anything, types.SensorFilter{}, "name", 4).
+			Return(sensors, nil)
 
-		expectedSensors := []types.Sensor{}
+		result, err := handler.handleGetSensors(context.Background(), createTestRequest(map[string]interface{}{
+			"limit": float64(3),
+		}))
+		require.NoError(t, err)
 
-		mockDB.On("GetSensorsExtended", mock.Anything, "", "", "", "", (*int)(nil), "", "name", 1000).
-			Return(expectedSensors, nil)
+		text := result.Content[0].(mcp.TextContent).Text
+		assert.True(t, strings.HasPrefix(text, `{"total":3,"returned":3,"truncated":false}`), text)
 
-		request := createTestRequest(map[string]interface{}{
-			"limit": float64(-10),
-		})
+		mockDB.AssertNotCalled(t, "CountSensors", mock.Anything, mock.Anything)
+	})
 
-		result, err := handler.handleGetSensors(context.Background(), request)
-		assert.NoError(t, err)
-		assert.NotNil(t, result)
+	t.Run("Failed count leaves the total out", func(t *testing.T) {
+		mockDB := new(MockDB)
+		handler := NewToolHandler(mockDB, &MockConfig{}, newTestLogger())
 
-		mockDB.AssertExpectations(t)
+		mockDB.On("GetSensorsExtended", mock.Anything, types.SensorFilter{}, "name", 3).
+			Return(sensors, nil)
+		mockDB.On("CountSensors", mock.Anything, types.SensorFilter{}).Return(0, errors.New("timeout"))
+
+		result, err := handler.handleGetSensors(context.Background(), createTestRequest(map[string]interface{}{
+			"limit": float64(2),
+		}))
+		require.NoError(t, err)
+
+		text := result.Content[0].(mcp.TextContent).Text
+		assert.True(t, strings.HasPrefix(text, `{"returned":2,"truncated":true}`), text)
 	})
 }
 
+// Test handleGetTags passes ordering and sensor count range to the database
+func TestHandleGetTags_UsageFilter(t *testing.T) {
+	mockDB := new(MockDB)
+	handler := NewToolHandler(mockDB, &MockConfig{}, newTestLogger())
+
+	zero := 0
+	mockDB.On("GetTags", mock.Anything, types.TagFilter{OrderBy: types.TagOrderUsage, MaxSensorCount: &zero}, 101).
+		Return([]types.Tag{{ID: 9, Name: "legacy"}}, nil)
+
+	result, err := handler.handleGetTags(context.Background(), createTestRequest(map[string]interface{}{
+		"order_by":         "usage",
+		"max_sensor_count": float64(0),
+	}))
+	require.NoError(t, err)
+	assert.Contains(t, result.Content[0].(mcp.TextContent).Text, "legacy")
+
+	// An inverted range never reaches the database
+	_, err = handler.handleGetTags(context.Background(), createTestRequest(map[string]interface{}{
+		"min_sensor_count": float64(3),
+		"max_sensor_count": float64(1),
+	}))
+	assert.ErrorContains(t, err, "must not be greater than max_sensor_count")
+
+	mockDB.AssertExpectations(t)
+	mockDB.AssertNumberOfCalls(t, "GetTags", 1)
+}
+
 // Test handleGetAlerts - default values
 func TestHandleGetAlerts_Defaults(t *testing.T) {
 	t.Run("Default hours applied", func(t *testing.T) {
@@ -561,7 +2065,7 @@ func TestHandleGetAlerts_Defaults(t *testing.T) {
 		expectedSensors := []types.Sensor{}
 
 		// Should use default hours of 24
-		mockDB.On("GetAlerts", mock.Anything, 24, (*int)(nil), "").
+		mockDB.On("GetAlerts", mock.Anything, types.AlertFilter{Hours: 24}, types.AlertsLimit+1, 0).
 			Return(expectedSensors, nil)
 
 		request := createTestRequest(map[string]interface{}{
@@ -574,6 +2078,223 @@ func TestHandleGetAlerts_Defaults(t *testing.T) {
 
 		mockDB.AssertExpectations(t)
 	})
+
+	t.Run("Priority floor", func(t *testing.T) {
+		mockDB := new(MockDB)
+		handler := NewToolHandler(mockDB, &MockConfig{}, newTestLogger())
+
+		minPriority := 4
+		mockDB.On("GetAlerts", mock.Anything, types.AlertFilter{Hours: 24, MinPriority: &minPriority}, types.AlertsLimit+1, 0).
+			Return([]types.Sensor{}, nil)
+
+		_, err := handler.handleGetAlerts(context.Background(), createTestRequest(map[string]interface{}{"min_priority": 4}))
+		require.NoError(t, err)
+		mockDB.AssertExpectations(t)
+
+		_, err = handler.handleGetAlerts(context.Background(), createTestRequest(map[string]interface{}{"min_priority": 6}))
+		assert.ErrorContains(t, err, "min_priority must be between 1 and 5")
+	})
+
+	t.Run("Baseline", func(t *testing.T) {
+		mockDB := new(MockDB)
+		handler := NewToolHandler(mockDB, &MockConfig{}, newTestLogger())
+
+		// The whole alert set is read, not a page
+		mockDB.On("GetAlerts", mock.Anything, types.AlertFilter{Hours: 24}, alertsBaselineLimit+1, 0).
+			Return([]types.Sensor{
+				{ID: 1, Name: "Disk Warning", Status: types.StatusWarning, Priority: 3},
+				{ID: 2, Name: "Ping Down", Status: types.StatusDown, Priority: 5},
+			}, nil)
+
+		result, err := handler.handleGetAlerts(context.Background(), createTestRequest(map[string]interface{}{
+			"baseline_sensor_ids": []interface{}{float64(1), float64(7)},
+			"format":              "json",
+		}))
+		require.NoError(t, err)
+
+		var diff types.AlertDiff
+		require.NoError(t, json.Unmarshal([]byte(result.Content[0].(mcp.TextContent).Text), &diff))
+		require.Len(t, diff.New, 1)
+		assert.Equal(t, 2, diff.New[0].ID)
+		assert.Equal(t, []int{7}, diff.Cleared)
+		assert.Equal(t, 1, diff.Unchanged)
+		assert.Equal(t, []int{1, 2}, diff.CurrentSensorIDs)
+		mockDB.AssertExpectations(t)
+
+		_, err = handler.handleGetAlerts(context.Background(), createTestRequest(map[string]interface{}{
+			"baseline_sensor_ids": []interface{}{},
+			"offset":              float64(100),
+		}))
+		assert.EqualError(t, err, "offset cannot be combined with baseline_sensor_ids")
+	})
+
+	t.Run("JSON format sorted by severity score", func(t *testing.T) {
+		mockDB := new(MockDB)
+		handler := NewToolHandler(mockDB, &MockConfig{}, newTestLogger())
+
+		// The database orders by score, so that the pages of the JSON output follow each other
+		mockDB.On("GetAlerts", mock.Anything, types.AlertFilter{Hours: 24, OrderBy: types.AlertOrderSeverity}, types.AlertsLimit+1, 0).
+			Return([]types.Sensor{
+				{ID: 2, Name: "Ping Down", Status: types.StatusDown, Priority: 5},
+				{ID: 1, Name: "Disk Warning", Status: types.StatusWarning, Priority: 5},
+			}, nil)
+
+		request := createTestRequest(map[string]interface{}{
+			"format": "json",
+		})
+
+		result, err := handler.handleGetAlerts(context.Background(), request)
+		require.NoError(t, err)
+
+		textContent, ok := result.Content[0].(mcp.TextContent)
+		require.True(t, ok)
+		assert.NotContains(t, textContent.Text, "##")
+
+		var output struct {
+			Count  int                 `json:"count"`
+			Alerts []types.ScoredAlert `json:"alerts"`
+		}
+		require.NoError(t, json.Unmarshal([]byte(textContent.Text), &output))
+
+		assert.Equal(t, 2, output.Count)
+		assert.Equal(t, 2, output.Alerts[0].ID)
+		assert.Equal(t, 100, output.Alerts[0].SeverityScore)
+		assert.Equal(t, 64, output.Alerts[1].SeverityScore)
+	})
+
+	t.Run("Markdown keeps database order", func(t *testing.T) {
+		mockDB := new(MockDB)
+		handler := NewToolHandler(mockDB, &MockConfig{}, newTestLogger())
+
+		mockDB.On("GetAlerts", mock.Anything, types.AlertFilter{Hours: 24}, types.AlertsLimit+1, 0).
+			Return([]types.Sensor{
+				{ID: 1, Name: "Disk Warning", Status: types.StatusWarning, Priority: 5},
+				{ID: 2, Name: "Ping Down", Status: types.StatusDown, Priority: 5},
+			}, nil)
+
+		result, err := handler.handleGetAlerts(context.Background(), createTestRequest(map[string]interface{}{}))
+		require.NoError(t, err)
+
+		text := result.Content[0].(mcp.TextContent).Text
+		warning, down := strings.Index(text, "Disk Warning"), strings.Index(text, "Ping Down")
+		require.NotEqual(t, -1, warning)
+		require.NotEqual(t, -1, down)
+		assert.Less(t, warning, down)
+	})
+
+	t.Run("Invalid format", func(t *testing.T) {
+		mockDB := new(MockDB)
+		handler := NewToolHandler(mockDB, &MockConfig{}, newTestLogger())
+
+		request := createTestRequest(map[string]interface{}{
+			"format": "xml",
+		})
+
+		result, err := handler.handleGetAlerts(context.Background(), request)
+		assert.Error(t, err)
+		assert.Nil(t, result)
+
+		mockDB.AssertNotCalled(t, "GetAlerts")
+	})
+
+	t.Run("Pagination reports total and next offset", func(t *testing.T) {
+		mockDB := new(MockDB)
+		handler := NewToolHandler(mockDB, &MockConfig{alertsPageSize: 2}, newTestLogger())
+
+		filter := types.AlertFilter{Hours: 24, OrderBy: types.AlertOrderSeverity}
+		mockDB.On("GetAlerts", mock.Anything, filter, 3, 2).
+			Return([]types.Sensor{
+				{ID: 3, Name: "Ping", Status: types.StatusDown},
+				{ID: 4, Name: "HTTP", Status: types.StatusDown},
+				{ID: 5, Name: "DNS", Status: types.StatusDown},
+			}, nil)
+		mockDB.On("CountAlerts", mock.Anything, filter).Return(5, nil)
+
+		request := createTestRequest(map[string]interface{}{
+			"offset": 2,
+			"format": "json",
+		})
+
+		result, err := handler.handleGetAlerts(context.Background(), request)
+		require.NoError(t, err)
+
+		var output struct {
+			Count      int `json:"count"`
+			Total      int `json:"total"`
+			NextOffset int `json:"next_offset"`
+		}
+		require.NoError(t, json.Unmarshal([]byte(result.Content[0].(mcp.TextContent).Text), &output))
+
+		assert.Equal(t, 2, output.Count)
+		assert.Equal(t, 5, output.Total)
+		assert.Equal(t, 4, output.NextOffset)
+
+		mockDB.AssertExpectations(t)
+	})
+
+	t.Run("Full last page is not truncated", func(t *testing.T) {
+		mockDB := new(MockDB)
+		handler := NewToolHandler(mockDB, &MockConfig{alertsPageSize: 2}, newTestLogger())
+
+		mockDB.On("GetAlerts", mock.Anything, types.AlertFilter{Hours: 24}, 3, 2).
+			Return([]types.Sensor{
+				{ID: 3, Name: "Ping", Status: types.StatusDown},
+				{ID: 4, Name: "HTTP", Status: types.StatusDown},
+			}, nil)
+
+		result, err := handler.handleGetAlerts(context.Background(), createTestRequest(map[string]interface{}{"offset": 2}))
+		require.NoError(t, err)
+
+		text := result.Content[0].(mcp.TextContent).Text
+		assert.True(t, strings.HasPrefix(text, `{"total":4,"returned":2,"truncated":false}`), text)
+		assert.NotContains(t, text, "More alerts")
+
+		mockDB.AssertNotCalled(t, "CountAlerts", mock.Anything, mock.Anything)
+	})
+
+	t.Run("Failed count leaves the total out", func(t *testing.T) {
+		mockDB := new(MockDB)
+		handler := NewToolHandler(mockDB, &MockConfig{alertsPageSize: 2}, newTestLogger())
+
+		mockDB.On("GetAlerts", mock.Anything, types.AlertFilter{Hours: 24}, 3, 0).
+			Return([]types.Sensor{
+				{ID: 1, Name: "Ping", Status: types.StatusDown},
+				{ID: 2, Name: "HTTP", Status: types.StatusDown},
+				{ID: 3, Name: "DNS", Status: types.StatusDown},
+			}, nil)
+		mockDB.On("CountAlerts", mock.Anything, types.AlertFilter{Hours: 24}).Return(0, errors.New("timeout"))
+
+		result, err := handler.handleGetAlerts(context.Background(), createTestRequest(map[string]interface{}{}))
+		require.NoError(t, err)
+
+		text := result.Content[0].(mcp.TextContent).Text
+		assert.True(t, strings.HasPrefix(text, `{"returned":2,"truncated":true,"next_offset":2}`), text)
+		assert.Contains(t, text, "showing 1-2; call again with `offset: 2`")
+	})
+
+	t.Run("Partial first page skips count", func(t *testing.T) {
+		mockDB := new(MockDB)
+		handler := NewToolHandler(mockDB, &MockConfig{alertsPageSize: 2}, newTestLogger())
+
+		mockDB.On("GetAlerts", mock.Anything, types.AlertFilter{Hours: 24}, 3, 0).
+			Return([]types.Sensor{{ID: 1, Name: "Ping", Status: types.StatusDown}}, nil)
+
+		result, err := handler.handleGetAlerts(context.Background(), createTestRequest(map[string]interface{}{}))
+		require.NoError(t, err)
+		assert.NotContains(t, result.Content[0].(mcp.TextContent).Text, "next_offset")
+
+		mockDB.AssertNotCalled(t, "CountAlerts", mock.Anything, mock.Anything)
+	})
+
+	t.Run("Negative offset", func(t *testing.T) {
+		mockDB := new(MockDB)
+		handler := NewToolHandler(mockDB, &MockConfig{}, newTestLogger())
+
+		_, err := handler.handleGetAlerts(context.Background(), createTestRequest(map[string]interface{}{"offset": -1}))
+		assert.Error(t, err)
+
+		mockDB.AssertNotCalled(t, "GetAlerts")
+	})
 }
 
 // Test handleTopSensors - default values and validation
@@ -588,7 +2309,7 @@ func TestHandleTopSensors_Defaults(t *testing.T) {
 		expectedSensors := []types.Sensor{}
 
 		// Should use defaults: metric="downtime", limit=10, hours=24
-		mockDB.On("GetTopSensors", mock.Anything, "downtime", "", 10, 24).
+		mockDB.On("GetTopSensors", mock.Anything, "downtime", "", 11, 24).
 			Return(expectedSensors, nil)
 
 		request := createTestRequest(map[string]interface{}{
@@ -611,7 +2332,7 @@ func TestHandleTopSensors_Defaults(t *testing.T) {
 
 		expectedSensors := []types.Sensor{}
 
-		mockDB.On("GetTopSensors", mock.Anything, "uptime", "", 10, 24).
+		mockDB.On("GetTopSensors", mock.Anything, "uptime", "", 11, 24).
 			Return(expectedSensors, nil)
 
 		request := createTestRequest(map[string]interface{}{
@@ -635,7 +2356,7 @@ func TestHandleTopSensors_Defaults(t *testing.T) {
 		expectedSensors := []types.Sensor{}
 
 		// Should correct negative limit to default 10
-		mockDB.On("GetTopSensors", mock.Anything, "downtime", "", 10, 24).
+		mockDB.On("GetTopSensors", mock.Anything, "downtime", "", 11, 24).
 			Return(expectedSensors, nil)
 
 		request := createTestRequest(map[string]interface{}{
@@ -659,7 +2380,7 @@ func TestHandleTopSensors_Defaults(t *testing.T) {
 		expectedSensors := []types.Sensor{}
 
 		// Should correct negative hours to default 24
-		mockDB.On("GetTopSensors", mock.Anything, "downtime", "", 10, 24).
+		mockDB.On("GetTopSensors", mock.Anything, "downtime", "", 11, 24).
 			Return(expectedSensors, nil)
 
 		request := createTestRequest(map[string]interface{}{
@@ -692,7 +2413,7 @@ func TestHandleGetSensors_ContextTimeout(t *testing.T) {
 			// Should have a deadline within ~30 seconds from now
 			timeUntilDeadline := time.Until(deadline)
 			return timeUntilDeadline > 29*time.Second && timeUntilDeadline <= 30*time.Second
-		}), "", "", "", "", (*int)(nil), "", "name", 1000).
+		}), types.SensorFilter{}, "name", types.SensorsLimit+1).
 			Return([]types.Sensor{}, nil)
 
 		request := createTestRequest(map[string]interface{}{})
@@ -735,3 +2456,297 @@ func BenchmarkFormatResult(b *testing.B) {
 		_, _ = formatResult(sensors, len(sensors))
 	}
 }
+
+func TestHandleGetRecentStatusChanges(t *testing.T) {
+	t.Run("Defaults applied", func(t *testing.T) {
+		mockDB := new(MockDB)
+		handler := NewToolHandler(mockDB, &MockConfig{}, newTestLogger())
+
+		mockDB.On("GetRecentChanges", mock.Anything, 15, 101).
+			Return([]types.StatusChange{}, nil)
+
+		result, err := handler.handleGetRecentStatusChanges(context.Background(), createTestRequest(map[string]interface{}{}))
+		require.NoError(t, err)
+		require.NotNil(t, result)
+
+		mockDB.AssertExpectations(t)
+	})
+
+	t.Run("Transitions rendered", func(t *testing.T) {
+		mockDB := new(MockDB)
+		handler := NewToolHandler(mockDB, &MockConfig{}, newTestLogger())
+
+		now := time.Now()
+		mockDB.On("GetRecentChanges", mock.Anything, 60, 21).
+			Return([]types.StatusChange{
+				{Sensor: types.Sensor{ID: 1, Name: "Ping", Status: types.StatusDown, StatusText: "Down"}, Transition: types.TransitionDown, ChangedAt: now},
+				{Sensor: types.Sensor{ID: 2, Name: "HTTP", Status: types.StatusUp, StatusText: "Up"}, Transition: types.TransitionUp, ChangedAt: now.Add(-time.Minute)},
+			}, nil)
+
+		result, err := handler.handleGetRecentStatusChanges(context.Background(), createTestRequest(map[string]interface{}{
+			"minutes": 60,
+			"limit":   20,
+		}))
+		require.NoError(t, err)
+
+		text := result.Content[0].(mcp.TextContent).Text
+		assert.Contains(t, text, "**Went down:** 1 sensor(s)")
+		assert.Contains(t, text, "**Came back up:** 1 sensor(s)")
+		assert.Contains(t, text, `"transition": "down"`)
+
+		mockDB.AssertExpectations(t)
+	})
+}
+
+func TestHandleEstateHealth(t *testing.T) {
+	mockDB := new(MockDB)
+	handler := NewToolHandler(mockDB, &MockConfig{}, newTestLogger())
+
+	// 982 up out of 1000 monitored, paused sensors excluded
+	mockDB.On("GetStatistics", mock.Anything).Return(&types.Statistics{
+		TotalProbes: 3,
+		SensorsByStatus: map[string]int{
+			"Up":            982,
+			"Warning":       15,
+			"Down":          3,
+			"Paused (User)": 40,
+		},
+	}, nil)
+	mockDB.On("GetAlerts", mock.Anything, types.AlertFilter{}, types.AlertsLimit, 0).Return([]types.Sensor{
+		{ID: 1, Name: "Disk Free", Status: types.StatusWarning, StatusText: "Warning", Priority: 3},
+		{ID: 2, Name: "Paused Ping", Status: types.StatusPausedByUser, Priority: 5},
+		{ID: 3, Name: "Core Switch", Status: types.StatusDown, StatusText: "Down", Priority: 5},
+	}, nil)
+
+	result, err := handler.handleEstateHealth(context.Background(), createTestRequest(map[string]interface{}{
+		"top_problems": 5,
+	}))
+	require.NoError(t, err)
+
+	text := result.Content[0].(mcp.TextContent).Text
+	assert.Contains(t, text, "🟢 **GREEN** - 98.2% healthy")
+	assert.Contains(t, text, "98.2% of sensors up; 3 critical issue(s); 15 warning(s); all probes connected")
+	assert.Contains(t, text, "Core Switch")
+	assert.NotContains(t, text, "| Paused Ping", "paused sensors are not problems")
+
+	mockDB.AssertExpectations(t)
+}
+
+func TestHandleGetStatistics_Cache(t *testing.T) {
+	mockDB := new(MockDB)
+	handler := NewToolHandler(mockDB, &MockConfig{statisticsCacheTTL: 30 * time.Second}, newTestLogger())
+
+	now := time.Date(2025, 1, 15, 10, 0, 0, 0, time.UTC)
+	handler.statsCache.now = func() time.Time { return now }
+
+	mockDB.On("GetStatistics", mock.Anything).Return(&types.Statistics{TotalSensors: 1200, SensorsByStatus: map[string]int{}}, nil).Once()
+
+	result, err := handler.handleGetStatistics(context.Background(), createTestRequest(map[string]interface{}{}))
+	require.NoError(t, err)
+	assert.NotContains(t, result.Content[0].(mcp.TextContent).Text, "Cached")
+
+	// Within the TTL the database is not queried again
+	now = now.Add(10 * time.Second)
+	result, err = handler.handleGetStatistics(context.Background(), createTestRequest(map[string]interface{}{}))
+	require.NoError(t, err)
+	assert.Contains(t, result.Content[0].(mcp.TextContent).Text, "_Cached 10s ago; use fresh=true for current values._")
+	mockDB.AssertNumberOfCalls(t, "GetStatistics", 1)
+
+	// fresh=true forces a query
+	mockDB.On("GetStatistics", mock.Anything).Return(&types.Statistics{TotalSensors: 1201, SensorsByStatus: map[string]int{}}, nil).Once()
+	_, err = handler.handleGetStatistics(context.Background(), createTestRequest(map[string]interface{}{"fresh": true}))
+	require.NoError(t, err)
+	mockDB.AssertNumberOfCalls(t, "GetStatistics", 2)
+
+	// After expiry the cache is refreshed
+	now = now.Add(31 * time.Second)
+	mockDB.On("GetStatistics", mock.Anything).Return(&types.Statistics{TotalSensors: 1202, SensorsByStatus: map[string]int{}}, nil).Once()
+	result, err = handler.handleGetStatistics(context.Background(), createTestRequest(map[string]interface{}{}))
+	require.NoError(t, err)
+	assert.Contains(t, result.Content[0].(mcp.TextContent).Text, "1202")
+	mockDB.AssertNumberOfCalls(t, "GetStatistics", 3)
+}
+
+func TestHandleGetStatistics_CacheDisabled(t *testing.T) {
+	mockDB := new(MockDB)
+	handler := NewToolHandler(mockDB, &MockConfig{statisticsCacheTTL: 0}, newTestLogger())
+
+	mockDB.On("GetStatistics", mock.Anything).Return(&types.Statistics{TotalSensors: 1200, SensorsByStatus: map[string]int{}}, nil)
+
+	// With statistics_cache_seconds: 0 every call queries the database
+	for i := 0; i < 2; i++ {
+		result, err := handler.handleGetStatistics(context.Background(), createTestRequest(map[string]interface{}{}))
+		require.NoError(t, err)
+		assert.NotContains(t, result.Content[0].(mcp.TextContent).Text, "Cached")
+	}
+
+	mockDB.AssertNumberOfCalls(t, "GetStatistics", 2)
+}
+
+func TestHandleGroupCounts(t *testing.T) {
+	mockDB := new(MockDB)
+	handler := NewToolHandler(mockDB, &MockConfig{}, newTestLogger())
+
+	four := 4
+	mockDB.On("GroupCounts", mock.Anything, "status", types.SensorFilter{GroupName: "network", MinPriority: &four}, 101).
+		Return([]types.DimensionCount{{Value: "3", Count: 30}, {Value: "5", Count: 10}}, nil)
+
+	result, err := handler.handleGroupCounts(context.Background(), createTestRequest(map[string]interface{}{
+		"dimension":    "status",
+		"group_name":   "network",
+		"min_priority": float64(4),
+	}))
+	require.NoError(t, err)
+
+	text := result.Content[0].(mcp.TextContent).Text
+	assert.Contains(t, text, "Found **40 sensor(s)** in **2 status value(s)**")
+	assert.Contains(t, text, "| 🔴 Down (5) | 10 | 25.0% |")
+
+	mockDB.AssertExpectations(t)
+
+	t.Run("dimension required", func(t *testing.T) {
+		_, err := handler.handleGroupCounts(context.Background(), createTestRequest(map[string]interface{}{}))
+		assert.EqualError(t, err, "dimension is required")
+	})
+}
+
+func TestHandleSensorStatusDiff(t *testing.T) {
+	mockDB := new(MockDB)
+	handler := NewToolHandler(mockDB, &MockConfig{}, newTestLogger())
+
+	previous := &types.Sensor{ID: 123, Name: "Ping", Status: types.StatusUp, StatusText: "Up", Message: "OK"}
+	current := &types.Sensor{ID: 123, Name: "Ping", Status: types.StatusWarning, StatusText: "Warning", Message: "Packet loss 20%"}
+
+	mockDB.On("GetSensorByID", mock.Anything, 123, (*int)(nil)).Return(previous, nil).Once()
+	mockDB.On("GetSensorByID", mock.Anything, 123, (*int)(nil)).Return(current, nil).Once()
+
+	// The text returned by prtg_get_sensor_status is accepted as the previous snapshot
+	statusResult, err := handler.handleGetSensorStatus(context.Background(), createTestRequest(map[string]interface{}{
+		"sensor_id": float64(123),
+	}))
+	require.NoError(t, err)
+
+	result, err := handler.handleSensorStatusDiff(context.Background(), createTestRequest(map[string]interface{}{
+		"sensor_id": float64(123),
+		"previous":  statusResult.Content[0].(mcp.TextContent).Text,
+	}))
+	require.NoError(t, err)
+
+	text := result.Content[0].(mcp.TextContent).Text
+	assert.Contains(t, text, "🔄 **Changed** since the previous snapshot.")
+	assert.Contains(t, text, "| status | Up | Warning |")
+	assert.Contains(t, text, "| message | OK | Packet loss 20% |")
+
+	mockDB.AssertExpectations(t)
+
+	t.Run("previous or since required", func(t *testing.T) {
+		_, err := handler.handleSensorStatusDiff(context.Background(), createTestRequest(map[string]interface{}{
+			"sensor_id": float64(123),
+		}))
+		assert.EqualError(t, err, "previous or since is required")
+	})
+
+	t.Run("previous for another sensor", func(t *testing.T) {
+		_, err := handler.handleSensorStatusDiff(context.Background(), createTestRequest(map[string]interface{}{
+			"sensor_id": float64(123),
+			"previous":  map[string]interface{}{"id": float64(456), "status": float64(3)},
+		}))
+		assert.EqualError(t, err, "previous status is for sensor 456, not sensor 123")
+	})
+
+	t.Run("invalid since", func(t *testing.T) {
+		_, err := handler.handleSensorStatusDiff(context.Background(), createTestRequest(map[string]interface{}{
+			"sensor_id": float64(123),
+			"since":     "yesterday",
+		}))
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "since must be an RFC 3339 timestamp")
+	})
+
+	t.Run("previous of a prefixed server", func(t *testing.T) {
+		mockDB := new(MockDB)
+		handler := NewToolHandler(mockDB, &MockConfig{idOffsets: types.IDOffsets{"eu": 1000000}}, newTestLogger())
+
+		mockDB.On("GetSensorByID", mock.Anything, 1000123, (*int)(nil)).
+			Return(&types.Sensor{ID: 1000123, Name: "Ping", Status: types.StatusUp, StatusText: "Up"}, nil).Once()
+		mockDB.On("GetSensorByID", mock.Anything, 1000123, (*int)(nil)).
+			Return(&types.Sensor{ID: 1000123, Name: "Ping", Status: types.StatusDown, StatusText: "Down"}, nil).Once()
+
+		statusResult, err := handler.handleGetSensorStatus(context.Background(), createTestRequest(map[string]interface{}{
+			"sensor_id": float64(123),
+			"id_prefix": "eu",
+		}))
+		require.NoError(t, err)
+		assert.Contains(t, statusResult.Content[0].(mcp.TextContent).Text, `"id": 123,`)
+
+		// The previous status carries the UI ID, as does the current one it is compared with
+		result, err := handler.handleSensorStatusDiff(context.Background(), createTestRequest(map[string]interface{}{
+			"sensor_id": float64(123),
+			"id_prefix": "eu",
+			"previous":  statusResult.Content[0].(mcp.TextContent).Text,
+		}))
+		require.NoError(t, err)
+
+		text := result.Content[0].(mcp.TextContent).Text
+		assert.Contains(t, text, "| status | Up | Down |")
+		assert.NotContains(t, text, "1000123")
+
+		mockDB.AssertExpectations(t)
+	})
+}
+
+func TestHandleListSensorTypes(t *testing.T) {
+	mockDB := new(MockDB)
+	logger := zerolog.Nop()
+	handler := NewToolHandler(mockDB, &MockConfig{}, &logger)
+
+	mockDB.On("GetSensorTypes", mock.Anything, 101).Return([]types.SensorTypeCount{
+		{Type: "ping", Count: 120},
+		{Type: "HTTP Advanced", Count: 35},
+	}, nil)
+
+	request := mcp.CallToolRequest{}
+	request.Params.Arguments = map[string]interface{}{}
+
+	result, err := handler.handleListSensorTypes(context.Background(), request)
+	require.NoError(t, err)
+
+	text := result.Content[0].(mcp.TextContent).Text
+	assert.Contains(t, text, "Found **2 sensor type(s)**")
+	assert.Contains(t, text, "| 1 | `ping` | 120 |")
+	assert.Contains(t, text, "| 2 | `HTTP Advanced` | 35 |")
+	assert.Contains(t, text, `"type": "HTTP Advanced"`)
+
+	mockDB.AssertExpectations(t)
+}
+
+func TestHandleExplainOutage(t *testing.T) {
+	mockDB := new(MockDB)
+	logger := zerolog.Nop()
+	handler := NewToolHandler(mockDB, &MockConfig{}, &logger)
+
+	filter := types.SensorFilter{DeviceName: "web01"}
+	mockDB.On("GetSensorsExtended", mock.Anything, filter, "device", explainOutageSensorLimit+1).Return([]types.Sensor{
+		{ID: 1, Name: "HTTP", SensorType: "http", DeviceID: 10, DeviceName: "web01", Status: types.StatusDown, StatusText: "Down", Message: "Connection refused"},
+		{ID: 2, Name: "Ping", SensorType: "ping", DeviceID: 10, DeviceName: "web01", Status: types.StatusDown, StatusText: "Down", Message: "Destination unreachable"},
+	}, nil)
+
+	request := mcp.CallToolRequest{}
+	request.Params.Arguments = map[string]interface{}{"device_name": "web01"}
+
+	result, err := handler.handleExplainOutage(context.Background(), request)
+	require.NoError(t, err)
+
+	text := result.Content[0].(mcp.TextContent).Text
+	assert.Contains(t, text, "## 🔎 Outage Explanation: device web01")
+	assert.Contains(t, text, "### 🔴 Probable device outage: web01")
+	assert.Contains(t, text, "Destination unreachable")
+	assert.Contains(t, text, `"cause": "device_outage"`)
+
+	mockDB.AssertExpectations(t)
+
+	// A scope is required
+	request.Params.Arguments = map[string]interface{}{}
+	_, err = handler.handleExplainOutage(context.Background(), request)
+	assert.EqualError(t, err, "device_name or group_name is required")
+}
@@ -0,0 +1,188 @@
+// Package handlers implements opt-in MCP tools that modify PRTG objects via API v2.
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+
+	"github.com/matthieu/mcp-server-prtg/internal/prtg"
+)
+
+// PRTGWriteClient interface for PRTG API operations that modify objects.
+type PRTGWriteClient interface {
+	PauseSensor(ctx context.Context, sensorID, minutes int, message string) error
+	ResumeSensor(ctx context.Context, sensorID int) error
+}
+
+// WriteToolHandler handles MCP tool requests that change PRTG state.
+type WriteToolHandler struct {
+	prtgClient  PRTGWriteClient
+	handler     *ToolHandler // Reference to main handler for config, logger and validator
+	idempotency idempotencyCache
+}
+
+// NewWriteToolHandler creates a new write tool handler.
+func NewWriteToolHandler(prtgClient PRTGWriteClient, mainHandler *ToolHandler) *WriteToolHandler {
+	return &WriteToolHandler{
+		prtgClient: prtgClient,
+		handler:    mainHandler,
+	}
+}
+
+// RegisterWriteTools registers the PRTG write tools and returns how many were registered.
+// Nothing is registered unless allow_write_operations is enabled, so read-only deployments
+// never expose these tools to clients.
+func (h *WriteToolHandler) RegisterWriteTools(s *server.MCPServer) int {
+	if !h.handler.config.AllowWriteOperations() {
+		return 0
+	}
+
+	// Tool 1: prtg_pause_sensor
+	addTool(s, h.handler.validator, mcp.Tool{
+		Name: "prtg_pause_sensor",
+		Description: "**MODIFIES PRTG:** pause a sensor so it stops monitoring and raising alerts. " +
+			"Use for planned maintenance or known issues. " +
+			"With minutes > 0 the sensor resumes automatically, otherwise it stays paused until prtg_resume_sensor is called. " +
+			"Requires a PRTG API token with write access.",
+		InputSchema: mcp.ToolInputSchema{
+			Type: "object",
+			Properties: map[string]interface{}{
+				"sensor_id": map[string]interface{}{
+					"type":        "integer",
+					"description": "PRTG sensor ID to pause",
+				},
+				"minutes": map[string]interface{}{
+					"type":        "integer",
+					"minimum":     0,
+					"description": "Pause duration in minutes (default: 0 = until resumed)",
+				},
+				"message": map[string]interface{}{
+					"type":        "string",
+					"description": "Reason shown in PRTG as the pause message (e.g. 'Firmware upgrade')",
+				},
+				"idempotency_key": idempotencyKeyProperty,
+			},
+			Required: []string{"sensor_id"},
+		},
+	}, h.idempotent("prtg_pause_sensor", h.handlePauseSensor))
+
+	// Tool 2: prtg_resume_sensor
+	addTool(s, h.handler.validator, mcp.Tool{
+		Name: "prtg_resume_sensor",
+		Description: "**MODIFIES PRTG:** resume a paused sensor so it starts monitoring again. " +
+			"Requires a PRTG API token with write access.",
+		InputSchema: mcp.ToolInputSchema{
+			Type: "object",
+			Properties: map[string]interface{}{
+				"sensor_id": map[string]interface{}{
+					"type":        "integer",
+					"description": "PRTG sensor ID to resume",
+				},
+				"idempotency_key": idempotencyKeyProperty,
+			},
+			Required: []string{"sensor_id"},
+		},
+	}, h.idempotent("prtg_resume_sensor", h.handleResumeSensor))
+
+	return 2
+}
+
+// handlePauseSensor handles prtg_pause_sensor tool requests.
+func (h *WriteToolHandler) handlePauseSensor(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	if result := h.checkWriteAllowed(); result != nil {
+		return result, nil
+	}
+
+	var params struct {
+		SensorID int    `json:"sensor_id"`
+		Minutes  int    `json:"minutes"`
+		Message  string `json:"message"`
+	}
+
+	if err := parseArguments(request.Params.Arguments, &params); err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Invalid parameters: %v", err)), nil
+	}
+
+	if params.Minutes < 0 {
+		return mcp.NewToolResultError("minutes must not be negative"), nil
+	}
+
+	h.handler.logger.Info().
+		Int("sensor_id", params.SensorID).
+		Int("minutes", params.Minutes).
+		Str("message", params.Message).
+		Msg("Pausing sensor via PRTG API")
+
+	if err := h.prtgClient.PauseSensor(ctx, params.SensorID, params.Minutes, params.Message); err != nil {
+		h.handler.logger.Error().
+			Err(err).
+			Int("sensor_id", params.SensorID).
+			Msg("Failed to pause sensor via PRTG API")
+		return mcp.NewToolResultError(writeErrorMessage("pause", params.SensorID, err)), nil
+	}
+
+	if params.Minutes > 0 {
+		return mcp.NewToolResultText(fmt.Sprintf("⏸️ Sensor %d paused for %d minutes", params.SensorID, params.Minutes)), nil
+	}
+
+	return mcp.NewToolResultText(fmt.Sprintf("⏸️ Sensor %d paused until resumed", params.SensorID)), nil
+}
+
+// handleResumeSensor handles prtg_resume_sensor tool requests.
+func (h *WriteToolHandler) handleResumeSensor(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	if result := h.checkWriteAllowed(); result != nil {
+		return result, nil
+	}
+
+	var params struct {
+		SensorID int `json:"sensor_id"`
+	}
+
+	if err := parseArguments(request.Params.Arguments, &params); err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Invalid parameters: %v", err)), nil
+	}
+
+	h.handler.logger.Info().
+		Int("sensor_id", params.SensorID).
+		Msg("Resuming sensor via PRTG API")
+
+	if err := h.prtgClient.ResumeSensor(ctx, params.SensorID); err != nil {
+		h.handler.logger.Error().
+			Err(err).
+			Int("sensor_id", params.SensorID).
+			Msg("Failed to resume sensor via PRTG API")
+		return mcp.NewToolResultError(writeErrorMessage("resume", params.SensorID, err)), nil
+	}
+
+	return mcp.NewToolResultText(fmt.Sprintf("▶️ Sensor %d resumed", params.SensorID)), nil
+}
+
+// checkWriteAllowed refuses the call when write operations have been disabled since registration.
+func (h *WriteToolHandler) checkWriteAllowed() *mcp.CallToolResult {
+	if h.handler.config.AllowWriteOperations() {
+		return nil
+	}
+
+	h.handler.logger.Warn().Msg("PRTG write operations are disabled in configuration (allow_write_operations: false)")
+	return mcp.NewToolResultError("PRTG write operations are disabled. " +
+		"Set 'allow_write_operations: true' in config.yaml to enable")
+}
+
+// writeErrorMessage turns a PRTG API error into a message the caller can act on.
+func writeErrorMessage(action string, sensorID int, err error) string {
+	switch {
+	case errors.Is(err, prtg.ErrForbidden):
+		return fmt.Sprintf("Cannot %s sensor %d: the PRTG API token does not have write access to this sensor. "+
+			"Use a token of a PRTG user with write permission on the object (%v)", action, sensorID, err)
+	case errors.Is(err, prtg.ErrInvalidObjectID):
+		return fmt.Sprintf("Cannot %s sensor %d: sensor_id must be greater than 0", action, sensorID)
+	case errors.Is(err, prtg.ErrNotFound):
+		return fmt.Sprintf("Cannot %s sensor %d: sensor not found in PRTG", action, sensorID)
+	default:
+		return fmt.Sprintf("Failed to %s sensor %d: %v", action, sensorID, err)
+	}
+}
@@ -0,0 +1,147 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	"github.com/matthieu/mcp-server-prtg/internal/prtg"
+)
+
+// MockPRTGWriteClient is a mock implementation of PRTGWriteClient interface
+type MockPRTGWriteClient struct {
+	mock.Mock
+}
+
+func (m *MockPRTGWriteClient) PauseSensor(ctx context.Context, sensorID, minutes int, message string) error {
+	args := m.Called(ctx, sensorID, minutes, message)
+	return args.Error(0)
+}
+
+func (m *MockPRTGWriteClient) ResumeSensor(ctx context.Context, sensorID int) error {
+	args := m.Called(ctx, sensorID)
+	return args.Error(0)
+}
+
+// Helper to create a write handler backed by mocks
+func newTestWriteHandler(config *MockConfig, mockClient *MockPRTGWriteClient) *WriteToolHandler {
+	handler := NewToolHandler(new(MockDB), config, newTestLogger())
+	return NewWriteToolHandler(mockClient, handler)
+}
+
+func TestRegisterWriteTools(t *testing.T) {
+	for _, enabled := range []bool{false, true} {
+		t.Run(fmt.Sprintf("allow_write_operations=%v", enabled), func(t *testing.T) {
+			handler := newTestWriteHandler(&MockConfig{allowWriteOperations: enabled}, new(MockPRTGWriteClient))
+			s := server.NewMCPServer("test", "1.0.0", server.WithToolCapabilities(false))
+
+			count := handler.RegisterWriteTools(s)
+			tools := s.ListTools()
+
+			if enabled {
+				assert.Equal(t, 2, count)
+				assert.Contains(t, tools, "prtg_pause_sensor")
+				assert.Contains(t, tools, "prtg_resume_sensor")
+			} else {
+				assert.Equal(t, 0, count)
+				assert.NotContains(t, tools, "prtg_pause_sensor")
+				assert.NotContains(t, tools, "prtg_resume_sensor")
+			}
+		})
+	}
+}
+
+func TestHandlePauseSensor(t *testing.T) {
+	t.Run("Timed pause", func(t *testing.T) {
+		mockClient := new(MockPRTGWriteClient)
+		handler := newTestWriteHandler(&MockConfig{allowWriteOperations: true}, mockClient)
+
+		mockClient.On("PauseSensor", mock.Anything, 1234, 30, "Firmware upgrade").Return(nil)
+
+		request := mcp.CallToolRequest{}
+		request.Params.Arguments = map[string]interface{}{
+			"sensor_id": float64(1234),
+			"minutes":   float64(30),
+			"message":   "Firmware upgrade",
+		}
+
+		result, err := handler.handlePauseSensor(context.Background(), request)
+
+		assert.NoError(t, err)
+		assert.False(t, result.IsError)
+		assert.Contains(t, resultText(t, result), "Sensor 1234 paused for 30 minutes")
+		mockClient.AssertExpectations(t)
+	})
+
+	t.Run("Token without write access", func(t *testing.T) {
+		mockClient := new(MockPRTGWriteClient)
+		handler := newTestWriteHandler(&MockConfig{allowWriteOperations: true}, mockClient)
+
+		mockClient.On("PauseSensor", mock.Anything, 1234, 0, "").
+			Return(fmt.Errorf("%w: read-only user", prtg.ErrForbidden))
+
+		request := mcp.CallToolRequest{}
+		request.Params.Arguments = map[string]interface{}{"sensor_id": float64(1234)}
+
+		result, err := handler.handlePauseSensor(context.Background(), request)
+
+		assert.NoError(t, err)
+		assert.True(t, result.IsError)
+		assert.Contains(t, resultText(t, result), "does not have write access")
+	})
+
+	t.Run("Disabled at call time", func(t *testing.T) {
+		mockClient := new(MockPRTGWriteClient)
+		handler := newTestWriteHandler(&MockConfig{allowWriteOperations: false}, mockClient)
+
+		request := mcp.CallToolRequest{}
+		request.Params.Arguments = map[string]interface{}{"sensor_id": float64(1234)}
+
+		result, err := handler.handlePauseSensor(context.Background(), request)
+
+		assert.NoError(t, err)
+		assert.True(t, result.IsError)
+		assert.Contains(t, resultText(t, result), "allow_write_operations: true")
+		mockClient.AssertNotCalled(t, "PauseSensor", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	})
+}
+
+func TestHandleResumeSensor(t *testing.T) {
+	t.Run("Resumed", func(t *testing.T) {
+		mockClient := new(MockPRTGWriteClient)
+		handler := newTestWriteHandler(&MockConfig{allowWriteOperations: true}, mockClient)
+
+		mockClient.On("ResumeSensor", mock.Anything, 1234).Return(nil)
+
+		request := mcp.CallToolRequest{}
+		request.Params.Arguments = map[string]interface{}{"sensor_id": float64(1234)}
+
+		result, err := handler.handleResumeSensor(context.Background(), request)
+
+		assert.NoError(t, err)
+		assert.False(t, result.IsError)
+		assert.Contains(t, resultText(t, result), "Sensor 1234 resumed")
+	})
+
+	t.Run("API failure", func(t *testing.T) {
+		mockClient := new(MockPRTGWriteClient)
+		handler := newTestWriteHandler(&MockConfig{allowWriteOperations: true}, mockClient)
+
+		mockClient.On("ResumeSensor", mock.Anything, 1234).Return(errors.New("connection reset"))
+
+		request := mcp.CallToolRequest{}
+		request.Params.Arguments = map[string]interface{}{"sensor_id": float64(1234)}
+
+		result, err := handler.handleResumeSensor(context.Background(), request)
+
+		assert.NoError(t, err)
+		assert.True(t, result.IsError)
+		assert.Contains(t, resultText(t, result), "Failed to resume sensor 1234: connection reset")
+	})
+}
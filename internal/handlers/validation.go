@@ -0,0 +1,270 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// ArgumentValidator checks tool call arguments against the InputSchema declared by each tool,
+// so that bad calls fail with a precise message before the handler runs.
+// Supported keywords: required, type (including type lists), enum, minimum and maximum.
+// Properties not declared in the schema are ignored.
+type ArgumentValidator struct {
+	mu      sync.RWMutex
+	schemas map[string]mcp.ToolInputSchema
+}
+
+// NewArgumentValidator creates an empty validator.
+func NewArgumentValidator() *ArgumentValidator {
+	return &ArgumentValidator{schemas: make(map[string]mcp.ToolInputSchema)}
+}
+
+// Register records the input schema of a tool.
+func (v *ArgumentValidator) Register(tool mcp.Tool) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	v.schemas[tool.Name] = tool.InputSchema
+}
+
+// Validate checks arguments against the schema registered for the tool.
+// Tools without a registered schema are not validated. All violations are reported, sorted by property.
+func (v *ArgumentValidator) Validate(toolName string, arguments map[string]interface{}) error {
+	v.mu.RLock()
+	schema, ok := v.schemas[toolName]
+	v.mu.RUnlock()
+
+	if !ok {
+		return nil
+	}
+
+	var problems []string
+
+	for _, name := range schema.Required {
+		if value, present := arguments[name]; !present || value == nil {
+			problems = append(problems, fmt.Sprintf("%s is required", name))
+		}
+	}
+
+	for name, value := range arguments {
+		property, declared := schema.Properties[name]
+		if !declared || value == nil {
+			continue
+		}
+
+		if err := validateProperty(name, toPropertySchema(property), value); err != nil {
+			problems = append(problems, err.Error())
+		}
+	}
+
+	if len(problems) == 0 {
+		return nil
+	}
+
+	sort.Strings(problems)
+
+	return errors.New(strings.Join(problems, "; "))
+}
+
+// Wrap returns a handler that validates the arguments of the named tool before calling handler.
+func (v *ArgumentValidator) Wrap(toolName string, handler server.ToolHandlerFunc) server.ToolHandlerFunc {
+	return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		if err := v.Validate(toolName, request.GetArguments()); err != nil {
+			return nil, fmt.Errorf("invalid arguments: %w", err)
+		}
+
+		return handler(ctx, request)
+	}
+}
+
+// addTool registers a tool with the server, validating its arguments against its InputSchema.
+func addTool(s *server.MCPServer, validator *ArgumentValidator, tool mcp.Tool, handler server.ToolHandlerFunc) {
+	validator.Register(tool)
+	s.AddTool(tool, validator.Wrap(tool.Name, handler))
+}
+
+// toPropertySchema normalizes a property schema declared as map[string]string or map[string]interface{}.
+func toPropertySchema(property interface{}) map[string]interface{} {
+	switch p := property.(type) {
+	case map[string]interface{}:
+		return p
+	case map[string]string:
+		schema := make(map[string]interface{}, len(p))
+		for key, value := range p {
+			schema[key] = value
+		}
+
+		return schema
+	default:
+		return nil
+	}
+}
+
+// validateProperty checks one argument value against its property schema.
+func validateProperty(name string, schema map[string]interface{}, value interface{}) error {
+	if allowedTypes := schemaTypes(schema["type"]); len(allowedTypes) > 0 {
+		matched := false
+
+		for _, t := range allowedTypes {
+			if matchesType(t, value) {
+				matched = true
+				break
+			}
+		}
+
+		if !matched {
+			return fmt.Errorf("%s must be %s, got %s", name, describeTypes(allowedTypes), jsonTypeName(value))
+		}
+	}
+
+	if enum := toSlice(schema["enum"]); len(enum) > 0 {
+		allowed := false
+
+		for _, candidate := range enum {
+			if fmt.Sprint(candidate) == fmt.Sprint(value) {
+				allowed = true
+				break
+			}
+		}
+
+		if !allowed {
+			return fmt.Errorf("%s must be one of %s, got %q", name, formatEnum(enum), fmt.Sprint(value))
+		}
+	}
+
+	if number, ok := value.(float64); ok {
+		if minimum, ok := toFloat(schema["minimum"]); ok && number < minimum {
+			return fmt.Errorf("%s must be at least %g, got %g", name, minimum, number)
+		}
+
+		if maximum, ok := toFloat(schema["maximum"]); ok && number > maximum {
+			return fmt.Errorf("%s must be at most %g, got %g", name, maximum, number)
+		}
+	}
+
+	return nil
+}
+
+// schemaTypes returns the JSON types of a "type" keyword (a string or a list of strings).
+func schemaTypes(value interface{}) []string {
+	if t, ok := value.(string); ok {
+		return []string{t}
+	}
+
+	var allowedTypes []string
+	for _, t := range toSlice(value) {
+		allowedTypes = append(allowedTypes, fmt.Sprint(t))
+	}
+
+	return allowedTypes
+}
+
+// matchesType reports whether a decoded JSON value is of the given JSON Schema type.
+func matchesType(schemaType string, value interface{}) bool {
+	switch schemaType {
+	case "string":
+		_, ok := value.(string)
+		return ok
+	case "integer":
+		number, ok := value.(float64)
+		return ok && number == math.Trunc(number)
+	case "number":
+		_, ok := value.(float64)
+		return ok
+	case "boolean":
+		_, ok := value.(bool)
+		return ok
+	case "object":
+		_, ok := value.(map[string]interface{})
+		return ok
+	case "array":
+		_, ok := value.([]interface{})
+		return ok
+	default:
+		return true
+	}
+}
+
+// jsonTypeName names the JSON type of a decoded value for error messages.
+func jsonTypeName(value interface{}) string {
+	switch v := value.(type) {
+	case string:
+		return "string"
+	case float64:
+		if v != math.Trunc(v) {
+			return fmt.Sprintf("number %g", v)
+		}
+
+		return "integer"
+	case bool:
+		return "boolean"
+	case map[string]interface{}:
+		return "object"
+	case []interface{}:
+		return "array"
+	default:
+		return fmt.Sprintf("%T", value)
+	}
+}
+
+// describeTypes formats the allowed types with an article ("an integer", "an object or a string").
+func describeTypes(allowedTypes []string) string {
+	described := make([]string, len(allowedTypes))
+
+	for i, t := range allowedTypes {
+		article := "a"
+		if t != "" && strings.ContainsAny(t[:1], "aeiou") {
+			article = "an"
+		}
+
+		described[i] = article + " " + t
+	}
+
+	return strings.Join(described, " or ")
+}
+
+// formatEnum formats the allowed enum values as [a, b, c].
+func formatEnum(enum []interface{}) string {
+	values := make([]string, len(enum))
+	for i, value := range enum {
+		values[i] = fmt.Sprint(value)
+	}
+
+	return "[" + strings.Join(values, ", ") + "]"
+}
+
+// toSlice converts any slice ([]string, []int, []interface{}, ...) to []interface{}.
+func toSlice(value interface{}) []interface{} {
+	rv := reflect.ValueOf(value)
+	if rv.Kind() != reflect.Slice {
+		return nil
+	}
+
+	items := make([]interface{}, rv.Len())
+	for i := range items {
+		items[i] = rv.Index(i).Interface()
+	}
+
+	return items
+}
+
+// toFloat converts a numeric schema keyword (int or float) to float64.
+func toFloat(value interface{}) (float64, bool) {
+	switch v := value.(type) {
+	case int:
+		return float64(v), true
+	case float64:
+		return v, true
+	default:
+		return 0, false
+	}
+}
@@ -0,0 +1,84 @@
+package handlers
+
+import (
+	"context"
+	"testing"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newRegisteredHandler returns a handler whose tools are registered, so the validator knows their schemas.
+func newRegisteredHandler(db *MockDB) *ToolHandler {
+	handler := NewToolHandler(db, &MockConfig{}, newTestLogger())
+	handler.RegisterTools(server.NewMCPServer("test", "1.0.0"))
+
+	return handler
+}
+
+func TestArgumentValidator_Validate(t *testing.T) {
+	validator := newRegisteredHandler(new(MockDB)).validator
+
+	tests := []struct {
+		name      string
+		tool      string
+		arguments map[string]interface{}
+		wantErr   string
+	}{
+		{"valid", "prtg_get_sensors", map[string]interface{}{"order_by": "status", "limit": float64(10)}, ""},
+		{"missing required field", "prtg_get_sensor_status", map[string]interface{}{}, "sensor_id is required"},
+		{"bad enum", "prtg_get_sensors", map[string]interface{}{"order_by": "foo"},
+			`order_by must be one of [name, status, priority, device, device_status, type, last_check, host], got "foo"`},
+		{"type mismatch", "prtg_get_sensors", map[string]interface{}{"limit": "50"}, "limit must be an integer, got string"},
+		{"fractional integer", "prtg_get_sensors", map[string]interface{}{"limit": 2.5}, "limit must be an integer, got number 2.5"},
+		{"below minimum", "prtg_get_sensors", map[string]interface{}{"min_priority": float64(0)}, "min_priority must be at least 1, got 0"},
+		{"type list", "prtg_sensor_status_diff", map[string]interface{}{"sensor_id": float64(1), "previous": true},
+			"previous must be an object or a string, got boolean"},
+		{"null treated as absent", "prtg_get_sensors", map[string]interface{}{"status": nil}, ""},
+		{"undeclared properties ignored", "prtg_get_sensors", map[string]interface{}{"extra": "x"}, ""},
+		{"unknown tool not validated", "other_tool", map[string]interface{}{"limit": "x"}, ""},
+		{"all errors reported", "prtg_group_counts", map[string]interface{}{"limit": "x"},
+			"dimension is required; limit must be an integer, got string"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validator.Validate(tt.tool, tt.arguments)
+			if tt.wantErr == "" {
+				assert.NoError(t, err)
+				return
+			}
+
+			assert.EqualError(t, err, tt.wantErr)
+		})
+	}
+}
+
+func TestArgumentValidator_Wrap(t *testing.T) {
+	validator := NewArgumentValidator()
+	validator.Register(mcp.Tool{
+		Name: "test_tool",
+		InputSchema: mcp.ToolInputSchema{
+			Type:       "object",
+			Properties: map[string]interface{}{"sensor_id": map[string]interface{}{"type": "integer"}},
+			Required:   []string{"sensor_id"},
+		},
+	})
+
+	called := false
+	handler := validator.Wrap("test_tool", func(_ context.Context, _ mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		called = true
+		return mcp.NewToolResultText("ok"), nil
+	})
+
+	_, err := handler(context.Background(), createTestRequest(map[string]interface{}{}))
+	assert.EqualError(t, err, "invalid arguments: sensor_id is required")
+	assert.False(t, called, "the handler must not run on invalid arguments")
+
+	result, err := handler(context.Background(), createTestRequest(map[string]interface{}{"sensor_id": float64(1)}))
+	require.NoError(t, err)
+	assert.NotNil(t, result)
+	assert.True(t, called)
+}
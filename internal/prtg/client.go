@@ -1,6 +1,7 @@
 package prtg
 
 import (
+	"bytes"
 	"context"
 	"crypto/tls"
 	"encoding/json"
@@ -8,6 +9,7 @@ import (
 	"io"
 	"net/http"
 	"net/url"
+	"strconv"
 	"strings"
 	"time"
 
@@ -79,6 +81,10 @@ func NewClient(config ClientConfig) (*Client, error) {
 // objectID: The PRTG object ID (sensor/device/group)
 // timeType: The time period type (live, short, medium, long)
 func (c *Client) GetTimeSeries(ctx context.Context, objectID int, timeType TimeSeriesType) (*TimeSeriesData, error) {
+	if err := validateObjectID(objectID); err != nil {
+		return nil, err
+	}
+
 	endpoint := fmt.Sprintf("/api/v2/experimental/timeseries/%d/%s", objectID, timeType)
 
 	// PRTG API returns array of arrays directly [[timestamp, val1, val2, ...], ...]
@@ -102,6 +108,10 @@ func (c *Client) GetTimeSeries(ctx context.Context, objectID int, timeType TimeS
 // start: Start time (RFC3339)
 // end: End time (RFC3339)
 func (c *Client) GetTimeSeriesCustom(ctx context.Context, objectID int, start, end time.Time) (*TimeSeriesData, error) {
+	if err := validateObjectID(objectID); err != nil {
+		return nil, err
+	}
+
 	endpoint := fmt.Sprintf("/api/v2/experimental/timeseries/%d", objectID)
 
 	// Add query parameters for custom time range
@@ -151,12 +161,83 @@ func (c *Client) GetChannels(ctx context.Context, filters map[string]string) ([]
 
 // GetChannelsBySensor retrieves all channels for a specific sensor.
 func (c *Client) GetChannelsBySensor(ctx context.Context, sensorID int) ([]Channel, error) {
+	if err := validateObjectID(sensorID); err != nil {
+		return nil, err
+	}
+
 	filters := map[string]string{
 		"filter_objid": fmt.Sprintf("%d", sensorID),
 	}
 	return c.GetChannels(ctx, filters)
 }
 
+// GetBusinessProcessSources retrieves the source objects aggregated by a Business Process sensor.
+// Returns the raw source entries as reported by the PRTG API.
+func (c *Client) GetBusinessProcessSources(ctx context.Context, sensorID int) ([]BusinessProcessSource, error) {
+	if err := validateObjectID(sensorID); err != nil {
+		return nil, err
+	}
+
+	endpoint := fmt.Sprintf("/api/v2/experimental/sensors/%d/sources", sensorID)
+
+	// PRTG API returns array directly, not wrapped in object
+	var sources []BusinessProcessSource
+	if err := c.doRequest(ctx, "GET", endpoint, nil, &sources); err != nil {
+		return nil, err
+	}
+
+	return sources, nil
+}
+
+// PauseSensor pauses a sensor. With minutes > 0 the sensor resumes automatically after that duration,
+// otherwise it stays paused until resumed. The message is shown in PRTG as the pause reason.
+// Requires an API token with write access to the sensor.
+func (c *Client) PauseSensor(ctx context.Context, sensorID, minutes int, message string) error {
+	if err := validateObjectID(sensorID); err != nil {
+		return err
+	}
+
+	endpoint := fmt.Sprintf("/api/v2/experimental/objects/%d/pause", sensorID)
+
+	payload := pauseRequest{Message: message}
+	if minutes > 0 {
+		payload.Duration = minutes
+	}
+
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("failed to encode request: %w", err)
+	}
+
+	return c.doRequest(ctx, http.MethodPost, endpoint, bytes.NewReader(body), nil)
+}
+
+// ResumeSensor resumes a paused sensor.
+// Requires an API token with write access to the sensor.
+func (c *Client) ResumeSensor(ctx context.Context, sensorID int) error {
+	if err := validateObjectID(sensorID); err != nil {
+		return err
+	}
+
+	endpoint := fmt.Sprintf("/api/v2/experimental/objects/%d/resume", sensorID)
+
+	return c.doRequest(ctx, http.MethodPost, endpoint, nil, nil)
+}
+
+// ParseObjectID parses a PRTG API v2 object ID (e.g., "2045" or "2045.0") into its numeric object ID.
+func ParseObjectID(id string) (int, error) {
+	if idx := strings.Index(id, "."); idx != -1 {
+		id = id[:idx]
+	}
+
+	objectID, err := strconv.Atoi(strings.TrimSpace(id))
+	if err != nil {
+		return 0, fmt.Errorf("invalid object ID %q: %w", id, err)
+	}
+
+	return objectID, nil
+}
+
 // parseRawTimeSeriesData parses raw time series data from PRTG API.
 // rawData: [[timestamp, val1, val2, ...], ...]
 // channels: Channel info to get names (optional, will use generic names if nil)
@@ -182,10 +263,15 @@ func (c *Client) parseRawTimeSeriesData(
 	headers := []string{"timestamp"}
 	numChannels := len(rawData[0]) - 1 // First column is timestamp
 
-	// Try to get channel names from channels info
+	var units map[string]ChannelBasic
+
+	// Try to get channel names and units from channels info
 	if channels != nil && len(channels) >= numChannels {
+		units = make(map[string]ChannelBasic, numChannels)
+
 		for i := 0; i < numChannels; i++ {
 			headers = append(headers, channels[i].Name)
+			units[channels[i].Name] = channels[i].Basic
 		}
 	} else {
 		// Use generic names if we don't have channel info
@@ -231,6 +317,7 @@ func (c *Client) parseRawTimeSeriesData(
 		StartTime:  start,
 		EndTime:    end,
 		Headers:    headers,
+		Units:      units,
 		DataPoints: dataPoints,
 	}, nil
 }
@@ -340,6 +427,8 @@ func (c *Client) handleHTTPError(statusCode int, endpoint string, body []byte) e
 	switch statusCode {
 	case http.StatusUnauthorized:
 		return fmt.Errorf("%w: %s", ErrUnauthorized, message)
+	case http.StatusForbidden:
+		return fmt.Errorf("%w: %s", ErrForbidden, message)
 	case http.StatusNotFound:
 		return fmt.Errorf("%w: %s", ErrNotFound, message)
 	case http.StatusTooManyRequests:
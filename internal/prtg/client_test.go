@@ -3,6 +3,8 @@ package prtg
 import (
 	"context"
 	"encoding/json"
+	"errors"
+	"io"
 	"net/http"
 	"net/http/httptest"
 	"strings"
@@ -88,6 +90,34 @@ func TestNewClient(t *testing.T) {
 	}
 }
 
+func TestNewClient_HTTPSettings(t *testing.T) {
+	logger := zerolog.Nop()
+
+	client, err := NewClient(ClientConfig{
+		BaseURL:   "https://prtg.example.com",
+		Token:     "valid-token",
+		Timeout:   90 * time.Second,
+		VerifySSL: false,
+		Logger:    &logger,
+	})
+	if err != nil {
+		t.Fatalf("NewClient() error = %v", err)
+	}
+
+	if client.httpClient.Timeout != 90*time.Second {
+		t.Errorf("httpClient.Timeout = %v, want 90s", client.httpClient.Timeout)
+	}
+
+	transport, ok := client.httpClient.Transport.(*http.Transport)
+	if !ok {
+		t.Fatalf("httpClient.Transport is %T, want *http.Transport", client.httpClient.Transport)
+	}
+
+	if !transport.TLSClientConfig.InsecureSkipVerify {
+		t.Error("InsecureSkipVerify = false, want true when VerifySSL is false")
+	}
+}
+
 func TestClient_GetTimeSeries(t *testing.T) {
 	// Mock time series data - API returns array of arrays directly
 	mockTimeSeriesData := [][]interface{}{
@@ -173,6 +203,11 @@ func TestClient_GetTimeSeries(t *testing.T) {
 	if data.Headers[2] != "Memory Usage" {
 		t.Errorf("Headers[2] = %s, want Memory Usage", data.Headers[2])
 	}
+
+	// Units are kept per channel for display
+	if unit := data.Units["Memory Usage"]; unit.UnitType != "BYTES_MEMORY" || unit.DisplayUnit != "MB" {
+		t.Errorf("Units[Memory Usage] = %+v, want BYTES_MEMORY in MB", unit)
+	}
 }
 
 func TestClient_GetTimeSeriesCustom(t *testing.T) {
@@ -327,6 +362,238 @@ func TestClient_GetChannelsBySensor(t *testing.T) {
 	}
 }
 
+func TestClient_GetBusinessProcessSources(t *testing.T) {
+	mockResponse := []BusinessProcessSource{
+		{ID: "2045", Name: "Web Ping", Channel: "Frontend"},
+		{ID: "2046", Name: "DB Port", Channel: "Backend"},
+	}
+
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/v2/experimental/sensors/5000/sources" {
+			t.Errorf("Unexpected path: %s", r.URL.Path)
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(mockResponse); err != nil {
+			t.Fatalf("Failed to encode response: %v", err)
+		}
+	}
+
+	client, server := setupTestClient(t, handler)
+	defer server.Close()
+
+	ctx := context.Background()
+	sources, err := client.GetBusinessProcessSources(ctx, 5000)
+	if err != nil {
+		t.Fatalf("GetBusinessProcessSources() error = %v", err)
+	}
+
+	if len(sources) != 2 {
+		t.Fatalf("len(sources) = %d, want 2", len(sources))
+	}
+
+	if sources[1].Channel != "Backend" {
+		t.Errorf("sources[1].Channel = %s, want Backend", sources[1].Channel)
+	}
+}
+
+// TestClient_GetBusinessProcessSources_NotAvailable validates that a PRTG version without the
+// experimental sources endpoint (404) reports ErrNotFound, so callers can explain it.
+func TestClient_GetBusinessProcessSources_NotAvailable(t *testing.T) {
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+		_, _ = w.Write([]byte("Not Found"))
+	}
+
+	client, server := setupTestClient(t, handler)
+	defer server.Close()
+
+	_, err := client.GetBusinessProcessSources(context.Background(), 5000)
+	if !errors.Is(err, ErrNotFound) {
+		t.Errorf("GetBusinessProcessSources() error = %v, want ErrNotFound", err)
+	}
+}
+
+func TestClient_PauseSensor(t *testing.T) {
+	tests := []struct {
+		name     string
+		minutes  int
+		message  string
+		wantBody map[string]interface{}
+	}{
+		{
+			name:     "timed pause",
+			minutes:  60,
+			message:  "maintenance window",
+			wantBody: map[string]interface{}{"message": "maintenance window", "duration": float64(60)},
+		},
+		{
+			name:     "indefinite pause",
+			minutes:  0,
+			message:  "decommissioning",
+			wantBody: map[string]interface{}{"message": "decommissioning"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := func(w http.ResponseWriter, r *http.Request) {
+				if r.Method != http.MethodPost {
+					t.Errorf("Expected POST, got %s", r.Method)
+				}
+				if r.URL.Path != "/api/v2/experimental/objects/1234/pause" {
+					t.Errorf("Unexpected path: %s", r.URL.Path)
+				}
+				if ct := r.Header.Get("Content-Type"); ct != "application/json" {
+					t.Errorf("Expected JSON content type, got %q", ct)
+				}
+
+				var body map[string]interface{}
+				if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+					t.Fatalf("Failed to decode request body: %v", err)
+				}
+				if len(body) != len(tt.wantBody) {
+					t.Errorf("Expected body %v, got %v", tt.wantBody, body)
+				}
+				for k, v := range tt.wantBody {
+					if body[k] != v {
+						t.Errorf("Expected %s=%v, got %v", k, v, body[k])
+					}
+				}
+
+				w.WriteHeader(http.StatusNoContent)
+			}
+
+			client, server := setupTestClient(t, handler)
+			defer server.Close()
+
+			if err := client.PauseSensor(context.Background(), 1234, tt.minutes, tt.message); err != nil {
+				t.Fatalf("PauseSensor() error = %v", err)
+			}
+		})
+	}
+}
+
+func TestClient_ResumeSensor(t *testing.T) {
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			t.Errorf("Expected POST, got %s", r.Method)
+		}
+		if r.URL.Path != "/api/v2/experimental/objects/1234/resume" {
+			t.Errorf("Unexpected path: %s", r.URL.Path)
+		}
+
+		body, _ := io.ReadAll(r.Body)
+		if len(body) != 0 {
+			t.Errorf("Expected empty body, got %q", body)
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	}
+
+	client, server := setupTestClient(t, handler)
+	defer server.Close()
+
+	if err := client.ResumeSensor(context.Background(), 1234); err != nil {
+		t.Fatalf("ResumeSensor() error = %v", err)
+	}
+}
+
+func TestClient_PauseSensor_Forbidden(t *testing.T) {
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusForbidden)
+		_, _ = w.Write([]byte("read-only token"))
+	}
+
+	client, server := setupTestClient(t, handler)
+	defer server.Close()
+
+	err := client.PauseSensor(context.Background(), 1234, 10, "")
+	if !errors.Is(err, ErrForbidden) {
+		t.Errorf("Expected ErrForbidden, got %v", err)
+	}
+}
+
+func TestClient_InvalidObjectID(t *testing.T) {
+	requests := 0
+	handler := func(w http.ResponseWriter, _ *http.Request) {
+		requests++
+		w.WriteHeader(http.StatusBadRequest)
+	}
+
+	client, server := setupTestClient(t, handler)
+	defer server.Close()
+
+	ctx := context.Background()
+	calls := map[string]func(id int) error{
+		"GetTimeSeries": func(id int) error {
+			_, err := client.GetTimeSeries(ctx, id, TimeSeriesLive)
+			return err
+		},
+		"GetTimeSeriesCustom": func(id int) error {
+			_, err := client.GetTimeSeriesCustom(ctx, id, time.Now().Add(-time.Hour), time.Now())
+			return err
+		},
+		"GetChannelsBySensor": func(id int) error {
+			_, err := client.GetChannelsBySensor(ctx, id)
+			return err
+		},
+		"GetBusinessProcessSources": func(id int) error {
+			_, err := client.GetBusinessProcessSources(ctx, id)
+			return err
+		},
+		"PauseSensor": func(id int) error {
+			return client.PauseSensor(ctx, id, 10, "")
+		},
+		"ResumeSensor": func(id int) error {
+			return client.ResumeSensor(ctx, id)
+		},
+	}
+
+	for name, call := range calls {
+		for _, id := range []int{0, -1} {
+			err := call(id)
+			if !errors.Is(err, ErrInvalidObjectID) {
+				t.Errorf("%s(%d) error = %v, want ErrInvalidObjectID", name, id, err)
+			}
+		}
+	}
+
+	if requests != 0 {
+		t.Errorf("Expected no HTTP request for invalid IDs, got %d", requests)
+	}
+
+	if err := calls["GetChannelsBySensor"](-1); err.Error() != "invalid PRTG object ID -1: must be greater than 0" {
+		t.Errorf("Unexpected error message: %v", err)
+	}
+}
+
+func TestParseObjectID(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		want    int
+		wantErr bool
+	}{
+		{name: "plain ID", input: "2045", want: 2045},
+		{name: "ID with suffix", input: "2045.0", want: 2045},
+		{name: "empty", input: "", wantErr: true},
+		{name: "not a number", input: "abc", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseObjectID(tt.input)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseObjectID(%q) error = %v, wantErr %v", tt.input, err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("ParseObjectID(%q) = %d, want %d", tt.input, got, tt.want)
+			}
+		})
+	}
+}
+
 func TestClient_HandleHTTPErrors(t *testing.T) {
 	tests := []struct {
 		name       string
@@ -338,6 +605,11 @@ func TestClient_HandleHTTPErrors(t *testing.T) {
 			statusCode: http.StatusUnauthorized,
 			wantErr:    ErrUnauthorized,
 		},
+		{
+			name:       "forbidden",
+			statusCode: http.StatusForbidden,
+			wantErr:    ErrForbidden,
+		},
 		{
 			name:       "not found",
 			statusCode: http.StatusNotFound,
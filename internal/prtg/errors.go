@@ -22,6 +22,9 @@ var (
 	// ErrUnauthorized is returned when authentication fails (401).
 	ErrUnauthorized = errors.New("PRTG API authentication failed - check API token")
 
+	// ErrForbidden is returned when the API token lacks the required permission (403).
+	ErrForbidden = errors.New("PRTG API permission denied - the API token needs write access to this object")
+
 	// ErrNotFound is returned when a resource is not found (404).
 	ErrNotFound = errors.New("PRTG resource not found")
 
@@ -30,8 +33,21 @@ var (
 
 	// ErrServerError is returned when PRTG server returns 5xx error.
 	ErrServerError = errors.New("PRTG server error")
+
+	// ErrInvalidObjectID is returned, without calling the API, for object IDs that are not positive.
+	ErrInvalidObjectID = errors.New("invalid PRTG object ID")
 )
 
+// validateObjectID checks that an object (sensor, device, group) ID can exist in PRTG, so that
+// bad IDs fail before a round-trip that PRTG would answer with a less helpful error.
+func validateObjectID(objectID int) error {
+	if objectID <= 0 {
+		return fmt.Errorf("%w %d: must be greater than 0", ErrInvalidObjectID, objectID)
+	}
+
+	return nil
+}
+
 // APIError represents an error from the PRTG API.
 type APIError struct {
 	StatusCode int
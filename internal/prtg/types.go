@@ -25,12 +25,14 @@ type TimeSeriesResponse struct {
 
 // TimeSeriesData represents parsed time series data with typed values.
 type TimeSeriesData struct {
-	ObjectID   int                   `json:"object_id"`
-	TimeType   TimeSeriesType        `json:"time_type,omitempty"`
-	StartTime  *time.Time            `json:"start_time,omitempty"`
-	EndTime    *time.Time            `json:"end_time,omitempty"`
-	Headers    []string              `json:"headers"`
-	DataPoints []TimeSeriesDataPoint `json:"data_points"`
+	ObjectID   int                     `json:"object_id"`
+	TimeType   TimeSeriesType          `json:"time_type,omitempty"`
+	StartTime  *time.Time              `json:"start_time,omitempty"`
+	EndTime    *time.Time              `json:"end_time,omitempty"`
+	Headers    []string                `json:"headers"`
+	Units      map[string]ChannelBasic `json:"units,omitempty"` // Channel name -> unit, when channel info is known
+	DataPoints []TimeSeriesDataPoint   `json:"data_points"`
+	Truncated  bool                    `json:"truncated,omitempty"` // A row cap was reached: the oldest data points are missing
 }
 
 // TimeSeriesDataPoint represents a single data point in time series.
@@ -89,3 +91,17 @@ type ChannelData struct {
 type ChannelsResponse struct {
 	Channels []Channel `json:"channels"`
 }
+
+// BusinessProcessSource represents a source object referenced by a Business Process sensor.
+// Each Business Process channel aggregates the status of one or more source objects.
+type BusinessProcessSource struct {
+	ID      string `json:"id"`      // Source object ID (e.g., "2045")
+	Name    string `json:"name"`    // Source object name
+	Channel string `json:"channel"` // Business Process channel the source contributes to
+}
+
+// pauseRequest is the body of a pause request.
+type pauseRequest struct {
+	Message  string `json:"message,omitempty"`
+	Duration int    `json:"duration,omitempty"` // Minutes until the object resumes (omitted = indefinitely)
+}
@@ -0,0 +1,162 @@
+// Package selftest runs the installation checks of the selftest command and renders
+// their outcome as a report that can be pasted into a bug report.
+package selftest
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io"
+	"text/tabwriter"
+	"time"
+
+	"github.com/matthieu/mcp-server-prtg/internal/services/logger"
+)
+
+// DefaultCheckTimeout bounds a single check, so an unreachable host cannot hang the report.
+const DefaultCheckTimeout = 15 * time.Second
+
+// Outcomes of a check.
+const (
+	StatusPass = "PASS"
+	StatusFail = "FAIL"
+	StatusSkip = "SKIP"
+)
+
+// Check is one item of the self-test. Run returns a short description of what was
+// verified, or an error; errors created with Skip mark the check as skipped.
+type Check struct {
+	Name string
+	Run  func(ctx context.Context) (string, error)
+}
+
+// Result is the outcome of one check.
+type Result struct {
+	Name   string
+	Status string
+	Detail string
+}
+
+// Report is the outcome of all checks, in the order they were run.
+type Report struct {
+	Results []Result
+}
+
+// skipError marks a check that does not apply to the current configuration.
+type skipError struct {
+	reason string
+}
+
+func (e *skipError) Error() string {
+	return e.reason
+}
+
+// Skip returns an error marking a check as skipped for the given reason.
+func Skip(reason string) error {
+	return &skipError{reason: reason}
+}
+
+// Run executes the checks one after the other, each with its own timeout.
+func Run(ctx context.Context, checks []Check, timeout time.Duration) Report {
+	if timeout <= 0 {
+		timeout = DefaultCheckTimeout
+	}
+
+	report := Report{Results: make([]Result, 0, len(checks))}
+
+	for _, check := range checks {
+		report.Results = append(report.Results, runCheck(ctx, check, timeout))
+	}
+
+	return report
+}
+
+// runCheck executes a single check and classifies its outcome.
+func runCheck(ctx context.Context, check Check, timeout time.Duration) Result {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	detail, err := check.Run(ctx)
+
+	var skip *skipError
+
+	switch {
+	case errors.As(err, &skip):
+		return Result{Name: check.Name, Status: StatusSkip, Detail: skip.reason}
+	case err != nil:
+		// Errors may echo connection settings, keep secrets out of the report
+		return Result{Name: check.Name, Status: StatusFail, Detail: logger.MaskSensitiveData(err.Error())}
+	default:
+		return Result{Name: check.Name, Status: StatusPass, Detail: detail}
+	}
+}
+
+// Failed returns the number of failed checks.
+func (r Report) Failed() int {
+	failed := 0
+
+	for _, result := range r.Results {
+		if result.Status == StatusFail {
+			failed++
+		}
+	}
+
+	return failed
+}
+
+// Passed reports whether no check failed. Skipped checks do not count as failures.
+func (r Report) Passed() bool {
+	return r.Failed() == 0
+}
+
+// Write renders the report as an aligned table followed by a summary line.
+func (r Report) Write(w io.Writer) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+
+	for _, result := range r.Results {
+		if _, err := fmt.Fprintf(tw, "  [%s]\t%s\t%s\n", result.Status, result.Name, result.Detail); err != nil {
+			return err
+		}
+	}
+
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+
+	summary := fmt.Sprintf("\nResult: all %d checks passed\n", len(r.Results))
+	if failed := r.Failed(); failed > 0 {
+		summary = fmt.Sprintf("\nResult: %d of %d checks failed\n", failed, len(r.Results))
+	}
+
+	_, err := io.WriteString(w, summary)
+
+	return err
+}
+
+// CheckCertificate loads a TLS certificate and key pair and verifies that the certificate
+// is valid at the given time. It returns the subject and expiry date of the certificate.
+func CheckCertificate(certFile, keyFile string, now time.Time) (string, error) {
+	pair, err := tls.LoadX509KeyPair(certFile, keyFile)
+	if err != nil {
+		return "", fmt.Errorf("failed to load certificate: %w", err)
+	}
+
+	cert, err := x509.ParseCertificate(pair.Certificate[0])
+	if err != nil {
+		return "", fmt.Errorf("failed to parse certificate: %w", err)
+	}
+
+	switch {
+	case now.Before(cert.NotBefore):
+		return "", fmt.Errorf("certificate %s is not valid before %s", certFile, cert.NotBefore.UTC().Format(time.RFC3339))
+	case now.After(cert.NotAfter):
+		return "", fmt.Errorf("certificate %s expired on %s", certFile, cert.NotAfter.UTC().Format(time.RFC3339))
+	}
+
+	days := int(cert.NotAfter.Sub(now).Hours() / 24)
+
+	return fmt.Sprintf("%s, CN=%s, expires %s (in %d days)",
+		certFile, cert.Subject.CommonName, cert.NotAfter.UTC().Format("2006-01-02"), days), nil
+}
@@ -0,0 +1,184 @@
+package selftest
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func passing(detail string) func(context.Context) (string, error) {
+	return func(context.Context) (string, error) { return detail, nil }
+}
+
+func failing(err error) func(context.Context) (string, error) {
+	return func(context.Context) (string, error) { return "", err }
+}
+
+func TestRun_Aggregation(t *testing.T) {
+	tests := []struct {
+		name       string
+		checks     []Check
+		wantStatus []string
+		wantFailed int
+	}{
+		{
+			name: "all pass",
+			checks: []Check{
+				{Name: "version", Run: passing("v1.0.0")},
+				{Name: "database", Run: passing("connected")},
+			},
+			wantStatus: []string{StatusPass, StatusPass},
+		},
+		{
+			name: "skipped checks do not fail the report",
+			checks: []Check{
+				{Name: "version", Run: passing("v1.0.0")},
+				{Name: "prtg_api", Run: failing(Skip("disabled"))},
+			},
+			wantStatus: []string{StatusPass, StatusSkip},
+		},
+		{
+			name: "failures are counted",
+			checks: []Check{
+				{Name: "database", Run: failing(errors.New("connection refused"))},
+				{Name: "prtg_api", Run: failing(errors.New("status 401"))},
+				{Name: "tls_certificate", Run: passing("valid")},
+			},
+			wantStatus: []string{StatusFail, StatusFail, StatusPass},
+			wantFailed: 2,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			report := Run(context.Background(), tt.checks, time.Second)
+
+			require.Len(t, report.Results, len(tt.checks))
+
+			for i, result := range report.Results {
+				assert.Equal(t, tt.checks[i].Name, result.Name)
+				assert.Equal(t, tt.wantStatus[i], result.Status)
+			}
+
+			assert.Equal(t, tt.wantFailed, report.Failed())
+			assert.Equal(t, tt.wantFailed == 0, report.Passed())
+		})
+	}
+}
+
+func TestRun_Timeout(t *testing.T) {
+	slow := func(ctx context.Context) (string, error) {
+		<-ctx.Done()
+		return "", ctx.Err()
+	}
+
+	report := Run(context.Background(), []Check{{Name: "database", Run: slow}}, 10*time.Millisecond)
+
+	assert.Equal(t, StatusFail, report.Results[0].Status)
+	assert.Contains(t, report.Results[0].Detail, "deadline exceeded")
+}
+
+func TestRun_MasksSecrets(t *testing.T) {
+	report := Run(context.Background(), []Check{
+		{Name: "database", Run: failing(errors.New("cannot connect with password=hunter2"))},
+	}, time.Second)
+
+	assert.NotContains(t, report.Results[0].Detail, "hunter2")
+}
+
+func TestReport_Write(t *testing.T) {
+	report := Report{Results: []Result{
+		{Name: "version", Status: StatusPass, Detail: "v1.0.0"},
+		{Name: "database", Status: StatusFail, Detail: "connection refused"},
+		{Name: "prtg_api", Status: StatusSkip, Detail: "disabled"},
+	}}
+
+	var buf bytes.Buffer
+	require.NoError(t, report.Write(&buf))
+
+	out := buf.String()
+	assert.Contains(t, out, "[PASS]  version   v1.0.0")
+	assert.Contains(t, out, "[FAIL]  database  connection refused")
+	assert.Contains(t, out, "[SKIP]  prtg_api  disabled")
+	assert.Contains(t, out, "Result: 1 of 3 checks failed")
+
+	buf.Reset()
+	require.NoError(t, Report{Results: report.Results[:1]}.Write(&buf))
+	assert.Contains(t, buf.String(), "Result: all 1 checks passed")
+}
+
+// writeCertificate writes a self-signed certificate valid between notBefore and notAfter.
+func writeCertificate(t *testing.T, notBefore, notAfter time.Time) (string, string) {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "prtg-mcp.example.com"},
+		NotBefore:    notBefore,
+		NotAfter:     notAfter,
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	require.NoError(t, err)
+
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	require.NoError(t, err)
+
+	dir := t.TempDir()
+	certFile := filepath.Join(dir, "server.crt")
+	keyFile := filepath.Join(dir, "server.key")
+
+	require.NoError(t, os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0600))
+	require.NoError(t, os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0600))
+
+	return certFile, keyFile
+}
+
+func TestCheckCertificate(t *testing.T) {
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	t.Run("valid", func(t *testing.T) {
+		certFile, keyFile := writeCertificate(t, now.AddDate(0, -1, 0), now.AddDate(0, 0, 30))
+
+		detail, err := CheckCertificate(certFile, keyFile, now)
+		require.NoError(t, err)
+		assert.Contains(t, detail, "CN=prtg-mcp.example.com")
+		assert.Contains(t, detail, "expires 2026-01-31 (in 30 days)")
+	})
+
+	t.Run("expired", func(t *testing.T) {
+		certFile, keyFile := writeCertificate(t, now.AddDate(-1, 0, 0), now.AddDate(0, 0, -1))
+
+		_, err := CheckCertificate(certFile, keyFile, now)
+		assert.ErrorContains(t, err, "expired on 2025-12-31")
+	})
+
+	t.Run("not yet valid", func(t *testing.T) {
+		certFile, keyFile := writeCertificate(t, now.AddDate(0, 0, 1), now.AddDate(1, 0, 0))
+
+		_, err := CheckCertificate(certFile, keyFile, now)
+		assert.ErrorContains(t, err, "not valid before")
+	})
+
+	t.Run("missing files", func(t *testing.T) {
+		_, err := CheckCertificate(filepath.Join(t.TempDir(), "none.crt"), "none.key", now)
+		assert.ErrorContains(t, err, "failed to load certificate")
+	})
+}
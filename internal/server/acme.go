@@ -0,0 +1,64 @@
+package server
+
+import (
+	"crypto/tls"
+	"net/http"
+	"time"
+
+	"golang.org/x/crypto/acme"
+	"golang.org/x/crypto/acme/autocert"
+
+	"github.com/matthieu/mcp-server-prtg/internal/services/configuration"
+)
+
+// acmeHTTPDisabled disables the HTTP-01 challenge listener (TLS-ALPN-01 only).
+const acmeHTTPDisabled = "-"
+
+// newACMEManager creates an autocert manager that obtains and renews certificates
+// for the configured domains, caching them on disk across restarts.
+func newACMEManager(cfg configuration.ACMEConfig) *autocert.Manager {
+	manager := &autocert.Manager{
+		Prompt:     autocert.AcceptTOS,
+		HostPolicy: autocert.HostWhitelist(cfg.Domains...),
+		Cache:      autocert.DirCache(cfg.CacheDir),
+		Email:      cfg.Email,
+	}
+
+	if cfg.DirectoryURL != "" {
+		manager.Client = &acme.Client{DirectoryURL: cfg.DirectoryURL}
+	}
+
+	return manager
+}
+
+// newACMETLSConfig returns a TLS config serving certificates from the ACME manager.
+// The ACME ALPN protocol is advertised so TLS-ALPN-01 challenges work on the main port.
+func newACMETLSConfig(manager *autocert.Manager) *tls.Config {
+	tlsConfig := manager.TLSConfig()
+	tlsConfig.MinVersion = tls.VersionTLS12
+
+	return tlsConfig
+}
+
+// startACMEChallengeServer serves HTTP-01 challenges on the given address.
+// Other plain HTTP requests are redirected to HTTPS.
+func (s *StreamableHTTPServer) startACMEChallengeServer(manager *autocert.Manager, address string) {
+	if address == acmeHTTPDisabled {
+		s.logger.Info().Msg("ACME HTTP-01 challenge listener disabled, relying on TLS-ALPN-01")
+		return
+	}
+
+	s.acmeHTTPServer = &http.Server{
+		Addr:              address,
+		Handler:           manager.HTTPHandler(nil),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	go func() {
+		if err := s.acmeHTTPServer.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			s.logger.Error().Err(err).Str("address", address).Msg("ACME challenge server error")
+		}
+	}()
+
+	s.logger.Info().Str("address", address).Msg("ACME HTTP-01 challenge listener started")
+}
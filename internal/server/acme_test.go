@@ -0,0 +1,86 @@
+package server
+
+import (
+	"context"
+	"crypto/tls"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/acme"
+
+	"github.com/matthieu/mcp-server-prtg/internal/cliargs"
+	"github.com/matthieu/mcp-server-prtg/internal/services/configuration"
+	"github.com/matthieu/mcp-server-prtg/internal/services/logger"
+)
+
+// newTestTLSServer creates a server from the given YAML configuration without starting it.
+func newTestTLSServer(t *testing.T, configYAML string) *StreamableHTTPServer {
+	t.Helper()
+
+	configPath := filepath.Join(t.TempDir(), "config.yaml")
+	require.NoError(t, os.WriteFile(configPath, []byte(configYAML), 0o600))
+
+	config, err := configuration.NewConfiguration(&cliargs.ParsedArgs{ConfigPath: configPath}, logger.NewSilentLogger())
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = config.Shutdown(context.Background()) })
+
+	s := NewStreamableHTTPServer(nil, nil, config, nil, logger.NewSilentLogger())
+	s.httpServer = &http.Server{}
+
+	return s
+}
+
+func TestConfigureTLS_ACMEUsesAutocertCertificates(t *testing.T) {
+	cacheDir := t.TempDir()
+	s := newTestTLSServer(t, `server:
+  api_key: "test-key"
+  enable_tls: true
+  tls:
+    acme:
+      enabled: true
+      domains: ["prtg-mcp.example.com"]
+      email: "ops@example.com"
+      cache_dir: "`+cacheDir+`"
+`)
+
+	certFile, keyFile, err := s.configureTLS()
+	require.NoError(t, err)
+
+	// ListenAndServeTLS must not load certificate files
+	assert.Empty(t, certFile)
+	assert.Empty(t, keyFile)
+
+	require.NotNil(t, s.acmeManager)
+	tlsConfig := s.httpServer.TLSConfig
+	require.NotNil(t, tlsConfig)
+	require.NotNil(t, tlsConfig.GetCertificate)
+	assert.Contains(t, tlsConfig.NextProtos, acme.ALPNProto, "TLS-ALPN-01 challenge must be supported")
+	assert.Equal(t, "ops@example.com", s.acmeManager.Email)
+
+	// The callback is the autocert one: hosts outside the whitelist are refused before any ACME request
+	_, err = tlsConfig.GetCertificate(&tls.ClientHelloInfo{ServerName: "other.example.com"})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "other.example.com")
+}
+
+func TestConfigureTLS_CertificateFiles(t *testing.T) {
+	s := newTestTLSServer(t, `server:
+  api_key: "test-key"
+  enable_tls: true
+  cert_file: "/etc/prtg/server.crt"
+  key_file: "/etc/prtg/server.key"
+`)
+
+	certFile, keyFile, err := s.configureTLS()
+	require.NoError(t, err)
+
+	assert.Equal(t, "/etc/prtg/server.crt", certFile)
+	assert.Equal(t, "/etc/prtg/server.key", keyFile)
+	assert.Nil(t, s.acmeManager)
+	require.NotNil(t, s.httpServer.TLSConfig)
+	assert.Nil(t, s.httpServer.TLSConfig.GetCertificate)
+}
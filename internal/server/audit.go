@@ -0,0 +1,206 @@
+package server
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	server "github.com/mark3labs/mcp-go/server"
+	"github.com/rs/zerolog"
+	"gopkg.in/natefinch/lumberjack.v2"
+
+	"github.com/matthieu/mcp-server-prtg/internal/services/configuration"
+	"github.com/matthieu/mcp-server-prtg/internal/services/logger"
+)
+
+// queryArguments are tool arguments carrying raw SQL, hashed when queries are redacted.
+var queryArguments = map[string]bool{
+	"query": true,
+}
+
+// AuditLog writes one JSON entry per MCP tool call: timestamp, client IP, tool name,
+// arguments, duration, result size and outcome. Entries go through the log masking
+// writer, so secrets matching the masking patterns never reach the file.
+type AuditLog struct {
+	logger        zerolog.Logger
+	closer        io.Closer
+	redactQueries bool
+}
+
+// NewAuditLog opens the rotated audit log file described by cfg.
+func NewAuditLog(cfg configuration.AuditLogConfig) (*AuditLog, error) {
+	if err := os.MkdirAll(filepath.Dir(cfg.File), 0750); err != nil {
+		return nil, fmt.Errorf("failed to create audit log directory: %w", err)
+	}
+
+	rotator := &lumberjack.Logger{
+		Filename:   cfg.File,
+		MaxSize:    cfg.MaxSizeMB,
+		MaxBackups: cfg.MaxBackups,
+		MaxAge:     cfg.MaxAgeDays,
+		Compress:   cfg.Compress,
+	}
+
+	audit := newAuditLog(rotator, cfg.RedactQueries)
+	audit.closer = rotator
+
+	return audit, nil
+}
+
+// newAuditLog creates an audit log writing to w.
+func newAuditLog(w io.Writer, redactQueries bool) *AuditLog {
+	return &AuditLog{
+		logger:        zerolog.New(logger.NewMaskingWriter(w)).With().Timestamp().Logger(),
+		redactQueries: redactQueries,
+	}
+}
+
+// Middleware returns a tool handler middleware that records each tool call.
+// Register it with server.WithToolHandlerMiddleware when creating the MCP server.
+func (a *AuditLog) Middleware(next server.ToolHandlerFunc) server.ToolHandlerFunc {
+	return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		start := time.Now()
+		result, err := next(ctx, request)
+
+		// Log() has no level, so entries are written regardless of the global log level
+		event := a.logger.Log().
+			Str("client_ip", clientIPFromContext(ctx))
+
+		if cn := clientCNFromContext(ctx); cn != "" {
+			event = event.Str("client_cn", cn)
+		}
+
+		if name := apiKeyNameFromContext(ctx); name != "" {
+			event = event.Str("api_key_name", name)
+		}
+
+		event = event.
+			Str("tool", request.Params.Name).
+			Interface("arguments", a.sanitizeArguments(request.GetArguments())).
+			Int64("duration_ms", time.Since(start).Milliseconds()).
+			Int("result_bytes", resultSize(result))
+
+		switch {
+		case err != nil:
+			event = event.Bool("success", false).Str("error", err.Error())
+		case result != nil && result.IsError:
+			event = event.Bool("success", false).Str("error", resultText(result))
+		default:
+			event = event.Bool("success", true)
+		}
+
+		event.Send()
+
+		return result, err
+	}
+}
+
+// Close closes the underlying audit log file.
+func (a *AuditLog) Close() error {
+	if a.closer == nil {
+		return nil
+	}
+
+	return a.closer.Close()
+}
+
+// sanitizeArguments returns a copy of the arguments with SQL queries replaced by their
+// SHA-256 when query redaction is enabled.
+func (a *AuditLog) sanitizeArguments(arguments map[string]any) map[string]any {
+	sanitized := make(map[string]any, len(arguments))
+
+	for key, value := range arguments {
+		if text, ok := value.(string); ok && a.redactQueries && queryArguments[key] {
+			sum := sha256.Sum256([]byte(text))
+			value = "sha256:" + hex.EncodeToString(sum[:])
+		}
+
+		sanitized[key] = value
+	}
+
+	return sanitized
+}
+
+// resultSize returns the total size in bytes of the text content of a tool result.
+func resultSize(result *mcp.CallToolResult) int {
+	if result == nil {
+		return 0
+	}
+
+	size := 0
+
+	for _, content := range result.Content {
+		if text, ok := content.(mcp.TextContent); ok {
+			size += len(text.Text)
+		}
+	}
+
+	return size
+}
+
+// resultText returns the concatenated text content of a tool result.
+func resultText(result *mcp.CallToolResult) string {
+	text := ""
+
+	for _, content := range result.Content {
+		if c, ok := content.(mcp.TextContent); ok {
+			text += c.Text
+		}
+	}
+
+	return text
+}
+
+// clientIPKey is the context key holding the IP address of the MCP client.
+type clientIPKey struct{}
+
+// apiKeyNameKey is the context key holding the name of the API key that authenticated the request.
+type apiKeyNameKey struct{}
+
+// contextWithClientInfo stores the client IP of r, the CN of its verified client certificate
+// and the name of its API key, if any, in ctx for the audit log.
+func contextWithClientInfo(ctx context.Context, r *http.Request) context.Context {
+	ctx = context.WithValue(ctx, clientIPKey{}, requestClientIP(r))
+
+	if cn := clientCNFromContext(r.Context()); cn != "" {
+		ctx = context.WithValue(ctx, clientCNKey{}, cn)
+	}
+
+	if name := apiKeyNameFromContext(r.Context()); name != "" {
+		ctx = context.WithValue(ctx, apiKeyNameKey{}, name)
+	}
+
+	return ctx
+}
+
+// apiKeyNameFromContext returns the name of the API key that authenticated the request, or ""
+// (e.g. when a client certificate was enough).
+func apiKeyNameFromContext(ctx context.Context) string {
+	name, _ := ctx.Value(apiKeyNameKey{}).(string)
+
+	return name
+}
+
+// requestClientIP returns the client IP resolved by the auth middleware, falling back
+// to the direct peer address when the request did not go through it.
+func requestClientIP(r *http.Request) string {
+	if ip := clientIPFromContext(r.Context()); ip != "" {
+		return ip
+	}
+
+	return getClientIP(r, nil)
+}
+
+// clientIPFromContext returns the client IP stored by contextWithClientInfo, or "" if unknown.
+func clientIPFromContext(ctx context.Context) string {
+	ip, _ := ctx.Value(clientIPKey{}).(string)
+
+	return ip
+}
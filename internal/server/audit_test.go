@@ -0,0 +1,182 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	server "github.com/mark3labs/mcp-go/server"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/matthieu/mcp-server-prtg/internal/services/configuration"
+)
+
+// auditEntries decodes the JSON lines written to an audit log buffer.
+func auditEntries(t *testing.T, buf *bytes.Buffer) []map[string]interface{} {
+	t.Helper()
+
+	var entries []map[string]interface{}
+
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		var entry map[string]interface{}
+		require.NoError(t, json.Unmarshal([]byte(line), &entry), line)
+
+		entries = append(entries, entry)
+	}
+
+	return entries
+}
+
+// auditCall runs handler through the audit middleware with a client IP in the context.
+func auditCall(audit *AuditLog, handler server.ToolHandlerFunc, name string, args map[string]interface{}) {
+	request := mcp.CallToolRequest{}
+	request.Params.Name = name
+	request.Params.Arguments = args
+
+	r := httptest.NewRequest("POST", "/mcp", nil)
+	r.RemoteAddr = "192.0.2.10:51234"
+
+	_, _ = audit.Middleware(handler)(contextWithClientInfo(context.Background(), r), request)
+}
+
+func TestAuditLog_Entry(t *testing.T) {
+	var buf bytes.Buffer
+	audit := newAuditLog(&buf, false)
+
+	ok := func(context.Context, mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		return mcp.NewToolResultText("12345"), nil
+	}
+
+	auditCall(audit, ok, "prtg_get_sensors", map[string]interface{}{"device_name": "core", "limit": 10})
+
+	entries := auditEntries(t, &buf)
+	require.Len(t, entries, 1)
+
+	entry := entries[0]
+	assert.Equal(t, "192.0.2.10", entry["client_ip"])
+	assert.Equal(t, "prtg_get_sensors", entry["tool"])
+	assert.Equal(t, map[string]interface{}{"device_name": "core", "limit": float64(10)}, entry["arguments"])
+	assert.Equal(t, true, entry["success"])
+	assert.Equal(t, float64(5), entry["result_bytes"])
+	assert.Contains(t, entry, "duration_ms")
+	assert.Contains(t, entry, "time")
+	assert.NotContains(t, entry, "error")
+}
+
+func TestAuditLog_APIKeyName(t *testing.T) {
+	var buf bytes.Buffer
+	audit := newAuditLog(&buf, false)
+
+	ok := func(context.Context, mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		return mcp.NewToolResultText("ok"), nil
+	}
+
+	// The auth middleware stores the key name in the request context
+	r := httptest.NewRequest("POST", "/mcp", nil)
+	r = r.WithContext(context.WithValue(r.Context(), apiKeyNameKey{}, "grafana"))
+
+	request := mcp.CallToolRequest{}
+	request.Params.Name = "prtg_get_alerts"
+
+	_, _ = audit.Middleware(ok)(contextWithClientInfo(context.Background(), r), request)
+	auditCall(audit, ok, "prtg_get_alerts", nil)
+
+	entries := auditEntries(t, &buf)
+	require.Len(t, entries, 2)
+	assert.Equal(t, "grafana", entries[0]["api_key_name"])
+	assert.NotContains(t, entries[1], "api_key_name")
+}
+
+func TestAuditLog_Failures(t *testing.T) {
+	var buf bytes.Buffer
+	audit := newAuditLog(&buf, false)
+
+	failing := func(context.Context, mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		return nil, errors.New("failed to get sensors: connection refused")
+	}
+	toolError := func(context.Context, mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		return mcp.NewToolResultError("Sensor not found"), nil
+	}
+
+	auditCall(audit, failing, "prtg_get_sensors", nil)
+	auditCall(audit, toolError, "prtg_get_channel_current_values", map[string]interface{}{"sensor_id": 42})
+
+	entries := auditEntries(t, &buf)
+	require.Len(t, entries, 2)
+
+	assert.Equal(t, false, entries[0]["success"])
+	assert.Equal(t, "failed to get sensors: connection refused", entries[0]["error"])
+	assert.Equal(t, float64(0), entries[0]["result_bytes"])
+
+	assert.Equal(t, false, entries[1]["success"])
+	assert.Equal(t, "Sensor not found", entries[1]["error"])
+}
+
+func TestAuditLog_RedactQueries(t *testing.T) {
+	ok := func(context.Context, mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		return mcp.NewToolResultText("[]"), nil
+	}
+	args := map[string]interface{}{"query": "SELECT name FROM prtg_sensor", "limit": 5}
+
+	// Without redaction the query is stored as-is
+	var plain bytes.Buffer
+	auditCall(newAuditLog(&plain, false), ok, "prtg_custom_query", args)
+	assert.Equal(t, "SELECT name FROM prtg_sensor", auditEntries(t, &plain)[0]["arguments"].(map[string]interface{})["query"])
+
+	// With redaction only a hash is kept, and the caller's arguments are untouched
+	var redacted bytes.Buffer
+	auditCall(newAuditLog(&redacted, true), ok, "prtg_custom_query", args)
+
+	sum := sha256.Sum256([]byte("SELECT name FROM prtg_sensor"))
+
+	logged := auditEntries(t, &redacted)[0]["arguments"].(map[string]interface{})
+	assert.Equal(t, "sha256:"+hex.EncodeToString(sum[:]), logged["query"])
+	assert.NotContains(t, redacted.String(), "prtg_sensor")
+	assert.Equal(t, float64(5), logged["limit"])
+	assert.Equal(t, "SELECT name FROM prtg_sensor", args["query"])
+}
+
+func TestAuditLog_MasksSecrets(t *testing.T) {
+	var buf bytes.Buffer
+	audit := newAuditLog(&buf, false)
+
+	ok := func(context.Context, mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		return mcp.NewToolResultText("ok"), nil
+	}
+
+	auditCall(audit, ok, "prtg_custom_query", map[string]interface{}{"query": "SELECT 1 -- password=hunter2"})
+
+	assert.NotContains(t, buf.String(), "hunter2")
+}
+
+func TestNewAuditLog_File(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "audit", "tools.log")
+
+	audit, err := NewAuditLog(configuration.AuditLogConfig{File: file, MaxSizeMB: 1, MaxBackups: 1, MaxAgeDays: 1})
+	require.NoError(t, err)
+
+	mcpServer := server.NewMCPServer("test", "1.0.0", server.WithToolHandlerMiddleware(audit.Middleware))
+	mcpServer.AddTool(mcp.Tool{Name: "echo", InputSchema: mcp.ToolInputSchema{Type: "object"}},
+		func(context.Context, mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+			return mcp.NewToolResultText("echo"), nil
+		})
+
+	message := `{"jsonrpc":"2.0","id":1,"method":"tools/call","params":{"name":"echo","arguments":{"value":"x"}}}`
+	mcpServer.HandleMessage(context.Background(), json.RawMessage(message))
+	require.NoError(t, audit.Close())
+
+	content, err := os.ReadFile(file)
+	require.NoError(t, err)
+	assert.Contains(t, string(content), `"tool":"echo"`)
+	assert.Contains(t, string(content), `"success":true`)
+}
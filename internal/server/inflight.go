@@ -0,0 +1,112 @@
+package server
+
+import (
+	"context"
+	"sync"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	server "github.com/mark3labs/mcp-go/server"
+)
+
+// InFlightTracker tracks active MCP tool calls so shutdown can drain them.
+// Once draining starts, new tool calls are rejected while active ones are
+// allowed to finish within the shutdown grace period.
+type InFlightTracker struct {
+	mu       sync.Mutex
+	wg       sync.WaitGroup
+	active   int
+	draining bool
+
+	// Cancelled when the grace period expires to abort remaining handlers
+	forceCtx    context.Context
+	forceCancel context.CancelFunc
+}
+
+// NewInFlightTracker creates a new in-flight tool call tracker.
+func NewInFlightTracker() *InFlightTracker {
+	forceCtx, forceCancel := context.WithCancel(context.Background())
+
+	return &InFlightTracker{
+		forceCtx:    forceCtx,
+		forceCancel: forceCancel,
+	}
+}
+
+// Middleware returns a tool handler middleware that records each tool call.
+// Register it with server.WithToolHandlerMiddleware when creating the MCP server.
+func (t *InFlightTracker) Middleware(next server.ToolHandlerFunc) server.ToolHandlerFunc {
+	return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		if !t.begin() {
+			return mcp.NewToolResultError("Server is shutting down, please retry later"), nil
+		}
+		defer t.end()
+
+		// Abort the handler if it is forcibly terminated at the end of the grace period
+		ctx, cancel := context.WithCancel(ctx)
+		defer cancel()
+
+		stop := context.AfterFunc(t.forceCtx, cancel)
+		defer stop()
+
+		return next(ctx, request)
+	}
+}
+
+// Active returns the number of tool calls currently in progress.
+func (t *InFlightTracker) Active() int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	return t.active
+}
+
+// Drain stops accepting new tool calls and waits for active ones to finish until ctx is done.
+// Returns the number of calls that completed (drained) and that were still running (terminated).
+// Remaining calls are cancelled when the context expires.
+func (t *InFlightTracker) Drain(ctx context.Context) (drained, terminated int) {
+	t.mu.Lock()
+	t.draining = true
+	pending := t.active
+	t.mu.Unlock()
+
+	done := make(chan struct{})
+
+	go func() {
+		t.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return pending, 0
+	case <-ctx.Done():
+		remaining := t.Active()
+		t.forceCancel()
+
+		return pending - remaining, remaining
+	}
+}
+
+// begin records the start of a tool call. Returns false if the tracker is draining.
+func (t *InFlightTracker) begin() bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.draining {
+		return false
+	}
+
+	t.active++
+	t.wg.Add(1)
+
+	return true
+}
+
+// end records the completion of a tool call.
+func (t *InFlightTracker) end() {
+	t.mu.Lock()
+	t.active--
+	t.mu.Unlock()
+
+	t.wg.Done()
+}
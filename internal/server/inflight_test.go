@@ -0,0 +1,152 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	server "github.com/mark3labs/mcp-go/server"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/matthieu/mcp-server-prtg/internal/services/logger"
+)
+
+// newTestServer creates a StreamableHTTPServer serving on a random local port.
+func newTestServer(t *testing.T, mcpServer *server.MCPServer, inFlight *InFlightTracker) *StreamableHTTPServer {
+	t.Helper()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	s := &StreamableHTTPServer{
+		mcpServer:  mcpServer,
+		logger:     logger.NewModuleLogger(logger.NewSilentLogger(), logger.ModuleServer),
+		inFlight:   inFlight,
+		shutdownCh: make(chan struct{}),
+		httpServer: &http.Server{
+			Handler:           server.NewStreamableHTTPServer(mcpServer),
+			ReadHeaderTimeout: 10 * time.Second,
+		},
+	}
+
+	go func() {
+		_ = s.httpServer.Serve(listener)
+	}()
+
+	return s
+}
+
+// newSlowMCPServer creates an MCP server with a single tool that sleeps for the given duration.
+func newSlowMCPServer(inFlight *InFlightTracker, delay time.Duration) *server.MCPServer {
+	mcpServer := server.NewMCPServer("test", "1.0.0", server.WithToolHandlerMiddleware(inFlight.Middleware))
+
+	mcpServer.AddTool(mcp.Tool{
+		Name:        "slow_tool",
+		InputSchema: mcp.ToolInputSchema{Type: "object"},
+	}, func(ctx context.Context, _ mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		select {
+		case <-time.After(delay):
+			return mcp.NewToolResultText("done"), nil
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	})
+
+	return mcpServer
+}
+
+// callTool invokes a tool through the MCP server and returns the JSON-RPC response.
+func callTool(mcpServer *server.MCPServer, name string) mcp.JSONRPCMessage {
+	message := json.RawMessage(`{"jsonrpc":"2.0","id":1,"method":"tools/call","params":{"name":"` + name + `"}}`)
+	return mcpServer.HandleMessage(context.Background(), message)
+}
+
+// waitForActive waits until the tracker reports the expected number of active calls.
+func waitForActive(t *testing.T, inFlight *InFlightTracker, expected int) {
+	t.Helper()
+
+	require.Eventually(t, func() bool {
+		return inFlight.Active() == expected
+	}, 2*time.Second, 5*time.Millisecond)
+}
+
+func TestShutdown_DrainsSlowToolCall(t *testing.T) {
+	inFlight := NewInFlightTracker()
+	mcpServer := newSlowMCPServer(inFlight, 200*time.Millisecond)
+	s := newTestServer(t, mcpServer, inFlight)
+
+	responseCh := make(chan mcp.JSONRPCMessage, 1)
+
+	go func() {
+		responseCh <- callTool(mcpServer, "slow_tool")
+	}()
+
+	waitForActive(t, inFlight, 1)
+
+	// Grace period is longer than the handler, so it must be allowed to complete
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+
+	require.NoError(t, s.Shutdown(ctx))
+
+	select {
+	case response := <-responseCh:
+		result, ok := response.(mcp.JSONRPCResponse)
+		require.True(t, ok, "expected a successful response, got %T", response)
+
+		toolResult, ok := result.Result.(mcp.CallToolResult)
+		require.True(t, ok)
+		assert.False(t, toolResult.IsError)
+	default:
+		t.Fatal("slow tool call did not complete before shutdown returned")
+	}
+
+	assert.Equal(t, 0, inFlight.Active())
+}
+
+func TestShutdown_RejectsNewToolCalls(t *testing.T) {
+	inFlight := NewInFlightTracker()
+	mcpServer := newSlowMCPServer(inFlight, time.Millisecond)
+	s := newTestServer(t, mcpServer, inFlight)
+
+	require.NoError(t, s.Shutdown(context.Background()))
+
+	response, ok := callTool(mcpServer, "slow_tool").(mcp.JSONRPCResponse)
+	require.True(t, ok)
+
+	toolResult, ok := response.Result.(mcp.CallToolResult)
+	require.True(t, ok)
+	assert.True(t, toolResult.IsError)
+}
+
+func TestInFlightTracker_TerminatesAfterGracePeriod(t *testing.T) {
+	inFlight := NewInFlightTracker()
+	mcpServer := newSlowMCPServer(inFlight, time.Minute)
+
+	responseCh := make(chan mcp.JSONRPCMessage, 1)
+
+	go func() {
+		responseCh <- callTool(mcpServer, "slow_tool")
+	}()
+
+	waitForActive(t, inFlight, 1)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	drained, terminated := inFlight.Drain(ctx)
+	assert.Equal(t, 0, drained)
+	assert.Equal(t, 1, terminated)
+
+	// Forcibly terminated handler must observe the cancellation
+	select {
+	case <-responseCh:
+	case <-time.After(2 * time.Second):
+		t.Fatal("terminated tool call was not cancelled")
+	}
+}
@@ -0,0 +1,88 @@
+package server
+
+import (
+	"context"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	server "github.com/mark3labs/mcp-go/server"
+)
+
+// DefaultConcurrencyWait is how long a tool call over the limit waits for a free slot
+// before it is rejected.
+const DefaultConcurrencyWait = 2 * time.Second
+
+// ConcurrencyLimiter bounds the number of tool calls executing at the same time, so a burst
+// of clients cannot exhaust the database connection pool with long-running queries.
+// Calls over the limit wait briefly for a slot and are rejected if none frees up.
+type ConcurrencyLimiter struct {
+	slots chan struct{}
+	wait  time.Duration
+}
+
+// NewConcurrencyLimiter creates a limiter allowing limit concurrent tool calls, where calls
+// over the limit wait up to wait for a slot (0 = reject immediately).
+func NewConcurrencyLimiter(limit int, wait time.Duration) *ConcurrencyLimiter {
+	if limit <= 0 {
+		limit = 1
+	}
+
+	return &ConcurrencyLimiter{
+		slots: make(chan struct{}, limit),
+		wait:  wait,
+	}
+}
+
+// Middleware returns a tool handler middleware that enforces the concurrency limit.
+// Register it with server.WithToolHandlerMiddleware when creating the MCP server.
+func (l *ConcurrencyLimiter) Middleware(next server.ToolHandlerFunc) server.ToolHandlerFunc {
+	return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		acquired, err := l.acquire(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		if !acquired {
+			return mcp.NewToolResultError("Server busy: too many concurrent tool calls, please retry in a few seconds"), nil
+		}
+		defer l.release()
+
+		return next(ctx, request)
+	}
+}
+
+// Active returns the number of tool calls currently holding a slot.
+func (l *ConcurrencyLimiter) Active() int {
+	return len(l.slots)
+}
+
+// acquire takes a slot, waiting up to the configured duration. Returns false when no slot
+// freed up in time, and the context error if the caller went away while waiting.
+func (l *ConcurrencyLimiter) acquire(ctx context.Context) (bool, error) {
+	select {
+	case l.slots <- struct{}{}:
+		return true, nil
+	default:
+	}
+
+	if l.wait <= 0 {
+		return false, nil
+	}
+
+	timer := time.NewTimer(l.wait)
+	defer timer.Stop()
+
+	select {
+	case l.slots <- struct{}{}:
+		return true, nil
+	case <-timer.C:
+		return false, nil
+	case <-ctx.Done():
+		return false, ctx.Err()
+	}
+}
+
+// release frees the slot taken by acquire.
+func (l *ConcurrencyLimiter) release() {
+	<-l.slots
+}
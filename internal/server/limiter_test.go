@@ -0,0 +1,136 @@
+package server
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// recordMax raises highest to current if it is higher.
+func recordMax(highest *atomic.Int32, current int32) {
+	for {
+		previous := highest.Load()
+		if current <= previous || highest.CompareAndSwap(previous, current) {
+			return
+		}
+	}
+}
+
+func TestConcurrencyLimiter_RejectsExcessCalls(t *testing.T) {
+	limiter := NewConcurrencyLimiter(3, 20*time.Millisecond)
+
+	release := make(chan struct{})
+
+	var running, maxRunning atomic.Int32
+
+	blocking := limiter.Middleware(func(context.Context, mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		recordMax(&maxRunning, running.Add(1))
+		defer running.Add(-1)
+
+		<-release
+
+		return mcp.NewToolResultText("done"), nil
+	})
+
+	var wg sync.WaitGroup
+
+	results := make([]*mcp.CallToolResult, 10)
+
+	for i := range results {
+		wg.Add(1)
+
+		go func(i int) {
+			defer wg.Done()
+
+			result, err := blocking(context.Background(), mcp.CallToolRequest{})
+			assert.NoError(t, err)
+
+			results[i] = result
+		}(i)
+	}
+
+	// The calls over the limit give up after the short wait while the first ones still run
+	require.Eventually(t, func() bool {
+		return limiter.Active() == 3
+	}, 2*time.Second, 5*time.Millisecond)
+	time.Sleep(100 * time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	busy := 0
+
+	for _, result := range results {
+		if result.IsError {
+			busy++
+			assert.Contains(t, result.Content[0].(mcp.TextContent).Text, "Server busy")
+		}
+	}
+
+	assert.Equal(t, 7, busy)
+	assert.Equal(t, int32(3), maxRunning.Load())
+	assert.Equal(t, 0, limiter.Active())
+}
+
+func TestConcurrencyLimiter_QueuesUntilSlotFrees(t *testing.T) {
+	limiter := NewConcurrencyLimiter(1, 2*time.Second)
+
+	var running, maxRunning atomic.Int32
+
+	short := limiter.Middleware(func(context.Context, mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		recordMax(&maxRunning, running.Add(1))
+		defer running.Add(-1)
+
+		time.Sleep(20 * time.Millisecond)
+
+		return mcp.NewToolResultText("done"), nil
+	})
+
+	var wg sync.WaitGroup
+
+	for range 4 {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			result, err := short(context.Background(), mcp.CallToolRequest{})
+			require.NoError(t, err)
+			assert.False(t, result.IsError)
+		}()
+	}
+
+	wg.Wait()
+
+	// All calls completed, one at a time
+	assert.Equal(t, int32(1), maxRunning.Load())
+}
+
+func TestConcurrencyLimiter_CallerGoesAway(t *testing.T) {
+	limiter := NewConcurrencyLimiter(1, time.Minute)
+
+	release := make(chan struct{})
+	defer close(release)
+
+	blocking := limiter.Middleware(func(context.Context, mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		<-release
+		return mcp.NewToolResultText("done"), nil
+	})
+
+	go func() { _, _ = blocking(context.Background(), mcp.CallToolRequest{}) }()
+
+	require.Eventually(t, func() bool {
+		return limiter.Active() == 1
+	}, 2*time.Second, 5*time.Millisecond)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	_, err := blocking(ctx, mcp.CallToolRequest{})
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+}
@@ -0,0 +1,35 @@
+package server
+
+import (
+	"context"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	server "github.com/mark3labs/mcp-go/server"
+)
+
+// MaintenanceMessage is the result of every tool call while maintenance mode is on.
+const MaintenanceMessage = "The PRTG MCP server is under maintenance: tools are temporarily unavailable, please retry later"
+
+// MaintenanceGate answers tool calls with MaintenanceMessage while maintenance mode is on,
+// without running the tool, so database maintenance doesn't surface as confusing query errors.
+// The mode is checked on every call, so it follows configuration reloads.
+type MaintenanceGate struct {
+	enabled func() bool
+}
+
+// NewMaintenanceGate creates a gate that is closed whenever enabled returns true.
+func NewMaintenanceGate(enabled func() bool) *MaintenanceGate {
+	return &MaintenanceGate{enabled: enabled}
+}
+
+// Middleware returns a tool handler middleware that short-circuits calls during maintenance.
+// Register it with server.WithToolHandlerMiddleware when creating the MCP server.
+func (g *MaintenanceGate) Middleware(next server.ToolHandlerFunc) server.ToolHandlerFunc {
+	return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		if g.enabled() {
+			return mcp.NewToolResultError(MaintenanceMessage), nil
+		}
+
+		return next(ctx, request)
+	}
+}
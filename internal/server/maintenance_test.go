@@ -0,0 +1,106 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	server "github.com/mark3labs/mcp-go/server"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/matthieu/mcp-server-prtg/internal/cliargs"
+	"github.com/matthieu/mcp-server-prtg/internal/services/configuration"
+	"github.com/matthieu/mcp-server-prtg/internal/services/logger"
+)
+
+// maintenanceConfigYAML returns a minimal valid configuration with the given maintenance mode.
+func maintenanceConfigYAML(maintenance bool) string {
+	return fmt.Sprintf(`server:
+  api_key: "test-key"
+  port: 8443
+  maintenance_mode: %t
+database:
+  host: "db.example.com"
+  port: 5432
+  name: "prtg_data_exporter"
+  user: "prtg_reader"
+`, maintenance)
+}
+
+func TestMaintenanceGate_FollowsConfigReload(t *testing.T) {
+	configPath := filepath.Join(t.TempDir(), "config.yaml")
+	require.NoError(t, os.WriteFile(configPath, []byte(maintenanceConfigYAML(false)), 0o600))
+
+	config, err := configuration.NewConfiguration(&cliargs.ParsedArgs{ConfigPath: configPath}, logger.NewSilentLogger())
+	require.NoError(t, err)
+
+	t.Cleanup(func() { _ = config.Shutdown(context.Background()) })
+
+	reloaded := make(chan struct{}, 10)
+	config.OnConfigChanged(func() { reloaded <- struct{}{} })
+
+	// The tool stands for any handler querying the database
+	var queries atomic.Int32
+
+	gate := NewMaintenanceGate(config.MaintenanceMode)
+	mcpServer := server.NewMCPServer("test", "1.0.0", server.WithToolHandlerMiddleware(gate.Middleware))
+	mcpServer.AddTool(mcp.Tool{
+		Name:        "prtg_get_sensors",
+		InputSchema: mcp.ToolInputSchema{Type: "object"},
+	}, func(context.Context, mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		queries.Add(1)
+		return mcp.NewToolResultText("sensors"), nil
+	})
+
+	call := func() mcp.CallToolResult {
+		t.Helper()
+
+		response, ok := callTool(mcpServer, "prtg_get_sensors").(mcp.JSONRPCResponse)
+		require.True(t, ok)
+
+		data, err := json.Marshal(response.Result)
+		require.NoError(t, err)
+
+		var result mcp.CallToolResult
+		require.NoError(t, json.Unmarshal(data, &result))
+
+		return result
+	}
+
+	reload := func(maintenance bool) {
+		t.Helper()
+
+		require.NoError(t, os.WriteFile(configPath, []byte(maintenanceConfigYAML(maintenance)), 0o600))
+
+		select {
+		case <-reloaded:
+		case <-time.After(2 * time.Second):
+			t.Fatal("configuration was not reloaded")
+		}
+	}
+
+	result := call()
+	assert.False(t, result.IsError)
+	assert.Equal(t, int32(1), queries.Load())
+
+	reload(true)
+
+	result = call()
+	assert.True(t, result.IsError)
+	require.Len(t, result.Content, 1)
+	assert.Equal(t, MaintenanceMessage, result.Content[0].(mcp.TextContent).Text)
+	assert.Equal(t, int32(1), queries.Load(), "the tool does not run during maintenance")
+
+	reload(false)
+
+	result = call()
+	assert.False(t, result.IsError)
+	assert.Equal(t, int32(2), queries.Load())
+}
@@ -0,0 +1,58 @@
+package server
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net/http"
+	"os"
+)
+
+// clientCNKey is the context key holding the common name of the verified client certificate.
+type clientCNKey struct{}
+
+// configureClientAuth enables mutual TLS on tlsConfig when a client CA file is configured.
+// Unless require is set, clients may still connect without a certificate and authenticate
+// with the API key; a certificate that is presented must be signed by one of the CAs.
+func configureClientAuth(tlsConfig *tls.Config, caFile string, require bool) error {
+	if caFile == "" {
+		return nil
+	}
+
+	data, err := os.ReadFile(caFile)
+	if err != nil {
+		return fmt.Errorf("failed to read client CA file: %w", err)
+	}
+
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(data) {
+		return fmt.Errorf("no PEM certificate found in client CA file %s", caFile)
+	}
+
+	tlsConfig.ClientCAs = pool
+	tlsConfig.ClientAuth = tls.VerifyClientCertIfGiven
+
+	if require {
+		tlsConfig.ClientAuth = tls.RequireAndVerifyClientCert
+	}
+
+	return nil
+}
+
+// verifiedClientCN returns the common name of the client certificate verified during the
+// TLS handshake of r, or "" when the client presented none.
+func verifiedClientCN(r *http.Request) string {
+	if r.TLS == nil || len(r.TLS.VerifiedChains) == 0 || len(r.TLS.VerifiedChains[0]) == 0 {
+		return ""
+	}
+
+	return r.TLS.VerifiedChains[0][0].Subject.CommonName
+}
+
+// clientCNFromContext returns the verified client certificate CN stored by the auth middleware, or "".
+func clientCNFromContext(ctx context.Context) string {
+	cn, _ := ctx.Value(clientCNKey{}).(string)
+
+	return cn
+}
@@ -0,0 +1,211 @@
+package server
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	server "github.com/mark3labs/mcp-go/server"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testCA is a throwaway certificate authority issuing client certificates.
+type testCA struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+}
+
+func newTestCA(t *testing.T, name string) *testCA {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: name},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	require.NoError(t, err)
+
+	cert, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+
+	return &testCA{cert: cert, key: key}
+}
+
+// writePEM writes the CA certificate to a temporary file and returns its path.
+func (ca *testCA) writePEM(t *testing.T) string {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "client-ca.pem")
+	data := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: ca.cert.Raw})
+	require.NoError(t, os.WriteFile(path, data, 0o600))
+
+	return path
+}
+
+// issueClientCert returns a client certificate for commonName signed by the CA.
+func (ca *testCA) issueClientCert(t *testing.T, commonName string) tls.Certificate {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(2),
+		Subject:      pkix.Name{CommonName: commonName},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, ca.cert, &key.PublicKey, ca.key)
+	require.NoError(t, err)
+
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key}
+}
+
+// newTestMTLSServer starts an HTTPS test server using the TLS configuration built by
+// configureTLS, and returns its URL and a client trusting its certificate.
+func newTestMTLSServer(t *testing.T, caFile, extraYAML string) (string, *http.Client) {
+	t.Helper()
+
+	s := newTestTLSServer(t, `server:
+  api_key: "`+testAPIKey+`"
+  enable_tls: true
+  cert_file: "/etc/prtg/server.crt"
+  key_file: "/etc/prtg/server.key"
+  tls:
+    client_ca_file: "`+caFile+`"
+`+extraYAML)
+
+	_, _, err := s.configureTLS()
+	require.NoError(t, err)
+
+	mcpServer := server.NewMCPServer("test", "1.0.0")
+	s.streamableHTTP = server.NewStreamableHTTPServer(mcpServer, server.WithEndpointPath(s.route("/mcp")))
+
+	ts := httptest.NewUnstartedServer(s.newMux())
+	ts.TLS = s.httpServer.TLSConfig
+	ts.StartTLS()
+	t.Cleanup(ts.Close)
+
+	return ts.URL, ts.Client()
+}
+
+// withClientCert makes client present cert during TLS handshakes.
+func withClientCert(client *http.Client, cert tls.Certificate) *http.Client {
+	transport := client.Transport.(*http.Transport).Clone()
+	transport.TLSClientConfig.Certificates = []tls.Certificate{cert}
+
+	return &http.Client{Transport: transport}
+}
+
+// mtlsStatus requests /status with client, sending the API key when withKey is set.
+func mtlsStatus(t *testing.T, client *http.Client, baseURL string, withKey bool) (int, error) {
+	t.Helper()
+
+	req, err := http.NewRequest(http.MethodGet, baseURL+"/status", nil)
+	require.NoError(t, err)
+
+	if withKey {
+		req.Header.Set("Authorization", "Bearer "+testAPIKey)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	_ = resp.Body.Close()
+
+	return resp.StatusCode, nil
+}
+
+func TestMTLS_RequireClientCert(t *testing.T) {
+	ca := newTestCA(t, "Test Client CA")
+	untrusted := newTestCA(t, "Untrusted CA")
+
+	baseURL, client := newTestMTLSServer(t, ca.writePEM(t), "    require_client_cert: true\n")
+
+	// Valid client certificate plus API key
+	status, err := mtlsStatus(t, withClientCert(client, ca.issueClientCert(t, "ops-workstation")), baseURL, true)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, status)
+
+	// Certificate signed by another CA is rejected during the handshake
+	_, err = mtlsStatus(t, withClientCert(client, untrusted.issueClientCert(t, "intruder")), baseURL, true)
+	require.Error(t, err)
+
+	// No certificate at all is rejected too, even with the API key
+	_, err = mtlsStatus(t, client, baseURL, true)
+	require.Error(t, err)
+
+	// Without auth.allow_mtls the API key is still required
+	status, err = mtlsStatus(t, withClientCert(client, ca.issueClientCert(t, "ops-workstation")), baseURL, false)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusUnauthorized, status)
+}
+
+func TestMTLS_AllowMTLSAuth(t *testing.T) {
+	ca := newTestCA(t, "Test Client CA")
+
+	baseURL, client := newTestMTLSServer(t, ca.writePEM(t), "  auth:\n    allow_mtls: true\n")
+
+	// A verified certificate is enough to authenticate
+	status, err := mtlsStatus(t, withClientCert(client, ca.issueClientCert(t, "ops-workstation")), baseURL, false)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, status)
+
+	// The certificate is optional: clients without one fall back to the API key
+	status, err = mtlsStatus(t, client, baseURL, true)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, status)
+
+	status, err = mtlsStatus(t, client, baseURL, false)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusUnauthorized, status)
+}
+
+func TestConfigureClientAuth_InvalidCAFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "ca.pem")
+	require.NoError(t, os.WriteFile(path, []byte("not a certificate"), 0o600))
+
+	err := configureClientAuth(&tls.Config{}, path, true)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "no PEM certificate")
+
+	err = configureClientAuth(&tls.Config{}, filepath.Join(t.TempDir(), "missing.pem"), false)
+	require.Error(t, err)
+}
+
+func TestVerifiedClientCN(t *testing.T) {
+	r := httptest.NewRequest(http.MethodGet, "/mcp", nil)
+	assert.Empty(t, verifiedClientCN(r))
+
+	ca := newTestCA(t, "Test Client CA")
+	cert, err := x509.ParseCertificate(ca.issueClientCert(t, "ops-workstation").Certificate[0])
+	require.NoError(t, err)
+
+	r.TLS = &tls.ConnectionState{VerifiedChains: [][]*x509.Certificate{{cert, ca.cert}}}
+	assert.Equal(t, "ops-workstation", verifiedClientCN(r))
+}
@@ -0,0 +1,219 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/matthieu/mcp-server-prtg/internal/services/configuration"
+	"github.com/matthieu/mcp-server-prtg/internal/services/logger"
+	"github.com/matthieu/mcp-server-prtg/internal/types"
+)
+
+const (
+	// sensorMetricsContentType is the content type of the Prometheus text exposition format.
+	sensorMetricsContentType = "text/plain; version=0.0.4; charset=utf-8"
+
+	// sensorMetricsChannelWindow is the age beyond which a channel value is considered stale
+	// and left out of the scrape.
+	sensorMetricsChannelWindow = time.Hour
+
+	// sensorMetricsTimeout bounds the database queries of one refresh.
+	sensorMetricsTimeout = 30 * time.Second
+)
+
+// sensorMetricsSource is the part of the database read by the /prtg-metrics endpoint.
+type sensorMetricsSource interface {
+	GetSensorsExtended(ctx context.Context, filter types.SensorFilter, orderBy string, limit int) ([]types.Sensor, error)
+	GetLatestChannelValues(ctx context.Context, since time.Time, limit int) ([]types.ChannelValue, error)
+}
+
+// sensorMetricsHandler serves PRTG sensor statuses, and the latest channel values when a history
+// table is configured, as Prometheus gauges. The output is rendered at most once per ttl: scrapes
+// in between get the cached body, so frequent or concurrent scrapes don't load the database.
+// At most maxSeries series are exposed, sensor statuses first.
+type sensorMetricsHandler struct {
+	source    sensorMetricsSource
+	channels  bool // Read channel values from the history table
+	maxSeries int
+	ttl       time.Duration
+	logger    *logger.ModuleLogger
+	now       func() time.Time // Overridden in tests
+
+	mu        sync.Mutex // Held during a refresh, so concurrent scrapes wait for its result
+	body      []byte
+	generated time.Time
+}
+
+// newSensorMetricsHandler creates the /prtg-metrics handler. Channel values are only exposed
+// when channels is set, i.e. a history table is configured.
+func newSensorMetricsHandler(source sensorMetricsSource, config configuration.SensorMetricsConfig, channels bool,
+	logger *logger.ModuleLogger) *sensorMetricsHandler {
+	return &sensorMetricsHandler{
+		source:    source,
+		channels:  channels,
+		maxSeries: config.MaxSeries,
+		ttl:       time.Duration(config.CacheSeconds) * time.Second,
+		logger:    logger,
+	}
+}
+
+// ServeHTTP writes the sensor metrics in the Prometheus text format.
+func (h *sensorMetricsHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, err := h.get(r.Context())
+	if err != nil {
+		// The database error may name hosts or users: it is logged, never returned
+		h.logger.Error().Err(err).Msg("Failed to collect sensor metrics")
+		http.Error(w, "Failed to collect sensor metrics", http.StatusServiceUnavailable)
+
+		return
+	}
+
+	w.Header().Set("Content-Type", sensorMetricsContentType)
+	w.WriteHeader(http.StatusOK)
+
+	if _, err := w.Write(body); err != nil {
+		h.logger.Error().Err(err).Msg("Failed to write sensor metrics response")
+	}
+}
+
+// get returns the rendered metrics, refreshing them when older than the ttl.
+func (h *sensorMetricsHandler) get(ctx context.Context) ([]byte, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	now := h.clock()
+	if h.body != nil && now.Sub(h.generated) < h.ttl {
+		return h.body, nil
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, sensorMetricsTimeout)
+	defer cancel()
+
+	// One extra row tells whether the series were truncated
+	sensors, err := h.source.GetSensorsExtended(ctx, types.SensorFilter{}, "name", h.maxSeries+1)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get sensors: %w", err)
+	}
+
+	var values []types.ChannelValue
+
+	if budget := h.maxSeries - len(sensors); h.channels && budget > 0 {
+		values, err = h.source.GetLatestChannelValues(ctx, now.Add(-sensorMetricsChannelWindow), budget+1)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get channel values: %w", err)
+		}
+	}
+
+	var buf bytes.Buffer
+
+	if dropped := writeSensorMetrics(&buf, sensors, values, h.maxSeries); dropped {
+		h.logger.Warn().
+			Int("max_series", h.maxSeries).
+			Msg("Sensor metrics truncated, raise server.sensor_metrics.max_series to expose every sensor")
+	}
+
+	h.body = buf.Bytes()
+	h.generated = now
+
+	return h.body, nil
+}
+
+// clock returns the current time.
+func (h *sensorMetricsHandler) clock() time.Time {
+	if h.now != nil {
+		return h.now()
+	}
+
+	return time.Now()
+}
+
+// sensorKey identifies a sensor: sensor IDs are only unique within one PRTG server.
+type sensorKey struct {
+	serverID int
+	sensorID int
+}
+
+// writeSensorMetrics renders a prtg_sensor_status gauge per sensor and a prtg_channel_value gauge
+// per channel value of those sensors, up to maxSeries series. Reports whether series were dropped.
+// The history table has no server column, so channel values of a sensor ID that exists on several
+// servers can't be attributed and are left out.
+func writeSensorMetrics(buf *bytes.Buffer, sensors []types.Sensor, values []types.ChannelValue, maxSeries int) bool {
+	dropped := false
+
+	if len(sensors) > maxSeries {
+		sensors = sensors[:maxSeries]
+		dropped = true
+	}
+
+	buf.WriteString("# HELP prtg_sensor_status PRTG status code of the sensor (1-14, 3 = Up, 4 = Warning, 5 = Down).\n")
+	buf.WriteString("# TYPE prtg_sensor_status gauge\n")
+
+	labels := make(map[sensorKey]string, len(sensors))
+	servers := make(map[int][]int, len(sensors)) // Servers of each sensor ID
+
+	for _, sensor := range sensors {
+		key := sensorKey{serverID: sensor.ServerID, sensorID: sensor.ID}
+		labels[key] = fmt.Sprintf(`id="%d",server="%d",name="%s",device="%s"`, sensor.ID, sensor.ServerID,
+			escapeLabelValue(sensor.Name), escapeLabelValue(sensor.DeviceName))
+		servers[sensor.ID] = append(servers[sensor.ID], sensor.ServerID)
+
+		fmt.Fprintf(buf, "prtg_sensor_status{%s} %d\n", labels[key], sensor.Status)
+	}
+
+	if len(values) == 0 {
+		return dropped
+	}
+
+	buf.WriteString("# HELP prtg_channel_value Latest value of a sensor channel, in the channel's unit.\n")
+	buf.WriteString("# TYPE prtg_channel_value gauge\n")
+
+	series := len(sensors)
+
+	for _, value := range values {
+		if len(servers[value.SensorID]) != 1 {
+			continue
+		}
+
+		sensorLabels := labels[sensorKey{serverID: servers[value.SensorID][0], sensorID: value.SensorID}]
+
+		if series == maxSeries {
+			return true
+		}
+
+		fmt.Fprintf(buf, "prtg_channel_value{%s,channel=\"%s\"} %s\n", sensorLabels,
+			escapeLabelValue(value.Channel), formatSampleValue(value.Value))
+
+		series++
+	}
+
+	return dropped
+}
+
+// labelValueEscaper escapes label values as required by the Prometheus text format.
+var labelValueEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+// escapeLabelValue escapes a label value for the Prometheus text format.
+func escapeLabelValue(value string) string {
+	return labelValueEscaper.Replace(value)
+}
+
+// formatSampleValue formats a sample value for the Prometheus text format.
+func formatSampleValue(value float64) string {
+	switch {
+	case math.IsNaN(value):
+		return "NaN"
+	case math.IsInf(value, 1):
+		return "+Inf"
+	case math.IsInf(value, -1):
+		return "-Inf"
+	}
+
+	return strconv.FormatFloat(value, 'g', -1, 64)
+}
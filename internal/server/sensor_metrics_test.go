@@ -0,0 +1,172 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/matthieu/mcp-server-prtg/internal/services/configuration"
+	"github.com/matthieu/mcp-server-prtg/internal/services/logger"
+	"github.com/matthieu/mcp-server-prtg/internal/types"
+)
+
+// fakeMetricsSource returns fixed sensors and channel values and counts the queries.
+type fakeMetricsSource struct {
+	sensors      []types.Sensor
+	values       []types.ChannelValue
+	err          error
+	sensorCalls  int
+	channelCalls int
+}
+
+func (f *fakeMetricsSource) GetSensorsExtended(_ context.Context, _ types.SensorFilter, _ string, limit int) ([]types.Sensor, error) {
+	f.sensorCalls++
+
+	return f.sensors[:min(limit, len(f.sensors))], f.err
+}
+
+func (f *fakeMetricsSource) GetLatestChannelValues(_ context.Context, _ time.Time, limit int) ([]types.ChannelValue, error) {
+	f.channelCalls++
+
+	return f.values[:min(limit, len(f.values))], nil
+}
+
+// promLine matches a comment or a sample line of the Prometheus text format, with labels.
+var promLine = regexp.MustCompile(`^(# (HELP|TYPE) [a-zA-Z_:][a-zA-Z0-9_:]* .+|` +
+	`[a-zA-Z_:][a-zA-Z0-9_:]*\{[a-zA-Z_][a-zA-Z0-9_]*="(?:[^"\\\n]|\\.)*"(?:,[a-zA-Z_][a-zA-Z0-9_]*="(?:[^"\\\n]|\\.)*")*\} ` +
+	`(NaN|[+-]Inf|[-+]?[0-9.]+(e[-+]?[0-9]+)?))$`)
+
+func newTestMetricsHandler(source sensorMetricsSource, maxSeries int) *sensorMetricsHandler {
+	config := configuration.SensorMetricsConfig{Enabled: true, MaxSeries: maxSeries, CacheSeconds: 60}
+
+	return newSensorMetricsHandler(source, config, true, logger.NewModuleLogger(logger.NewSilentLogger(), logger.ModuleServer))
+}
+
+func scrape(t *testing.T, handler http.Handler) (int, string) {
+	t.Helper()
+
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/prtg-metrics", nil))
+
+	return recorder.Code, recorder.Body.String()
+}
+
+func TestSensorMetrics_PrometheusTextFormat(t *testing.T) {
+	source := &fakeMetricsSource{
+		sensors: []types.Sensor{
+			{ID: 1001, ServerID: 1, Name: "Ping", DeviceName: "web01", Status: types.StatusUp},
+			{ID: 1002, ServerID: 1, Name: `Disk "C:\"`, DeviceName: "file\nserver", Status: types.StatusDown},
+		},
+		values: []types.ChannelValue{
+			{SensorID: 1001, Channel: "Response Time", Value: 12.5},
+			{SensorID: 1002, Channel: "Free Space", Value: 1.5e10},
+			{SensorID: 1002, Channel: "Ratio", Value: math.NaN()},
+			{SensorID: 9999, Channel: "Unknown sensor", Value: 1},
+		},
+	}
+
+	recorder := httptest.NewRecorder()
+	newTestMetricsHandler(source, 100).ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/prtg-metrics", nil))
+
+	require.Equal(t, http.StatusOK, recorder.Code)
+	assert.Equal(t, "text/plain; version=0.0.4; charset=utf-8", recorder.Header().Get("Content-Type"))
+
+	body := recorder.Body.String()
+	require.True(t, strings.HasSuffix(body, "\n"), "the exposition ends with a newline")
+
+	for _, line := range strings.Split(strings.TrimSuffix(body, "\n"), "\n") {
+		assert.Regexp(t, promLine, line)
+	}
+
+	assert.Contains(t, body, "# TYPE prtg_sensor_status gauge\n")
+	assert.Contains(t, body, `prtg_sensor_status{id="1001",server="1",name="Ping",device="web01"} 3`+"\n")
+	assert.Contains(t, body, `prtg_sensor_status{id="1002",server="1",name="Disk \"C:\\\"",device="file\nserver"} 5`+"\n")
+	assert.Contains(t, body, "# TYPE prtg_channel_value gauge\n")
+	assert.Contains(t, body, `prtg_channel_value{id="1001",server="1",name="Ping",device="web01",channel="Response Time"} 12.5`+"\n")
+	assert.Contains(t, body, `channel="Free Space"} 1.5e+10`+"\n")
+	assert.Contains(t, body, `channel="Ratio"} NaN`+"\n")
+	assert.NotContains(t, body, "Unknown sensor", "channels of sensors without a status series are skipped")
+}
+
+func TestSensorMetrics_SeveralServers(t *testing.T) {
+	source := &fakeMetricsSource{
+		sensors: []types.Sensor{
+			{ID: 1001, ServerID: 1, Name: "Ping", DeviceName: "web01", Status: types.StatusUp},
+			{ID: 1001, ServerID: 2, Name: "HTTP", DeviceName: "web02", Status: types.StatusDown},
+			{ID: 1002, ServerID: 2, Name: "Disk", DeviceName: "web02", Status: types.StatusUp},
+		},
+		values: []types.ChannelValue{
+			{SensorID: 1001, Channel: "Response Time", Value: 12.5},
+			{SensorID: 1002, Channel: "Free Space", Value: 40},
+		},
+	}
+
+	_, body := scrape(t, newTestMetricsHandler(source, 100))
+
+	// The same sensor ID on two servers gives two series
+	assert.Contains(t, body, `prtg_sensor_status{id="1001",server="1",name="Ping",device="web01"} 3`+"\n")
+	assert.Contains(t, body, `prtg_sensor_status{id="1001",server="2",name="HTTP",device="web02"} 5`+"\n")
+
+	// Channel values are only attributed to sensor IDs found on a single server
+	assert.Contains(t, body, `prtg_channel_value{id="1002",server="2",name="Disk",device="web02",channel="Free Space"} 40`+"\n")
+	assert.NotContains(t, body, "Response Time")
+}
+
+func TestSensorMetrics_MaxSeries(t *testing.T) {
+	source := &fakeMetricsSource{
+		sensors: []types.Sensor{
+			{ID: 1, Name: "A", Status: types.StatusUp},
+			{ID: 2, Name: "B", Status: types.StatusUp},
+		},
+		values: []types.ChannelValue{
+			{SensorID: 1, Channel: "X", Value: 1},
+			{SensorID: 2, Channel: "Y", Value: 2},
+		},
+	}
+
+	// Statuses come first, channel values fill the remaining budget
+	_, body := scrape(t, newTestMetricsHandler(source, 3))
+	assert.Equal(t, 3, strings.Count(body, "\nprtg_"))
+	assert.Contains(t, body, `channel="X"`)
+	assert.NotContains(t, body, `channel="Y"`)
+
+	// No budget left for channels: they are not queried
+	source.channelCalls = 0
+	_, body = scrape(t, newTestMetricsHandler(source, 1))
+	assert.Equal(t, 1, strings.Count(body, "\nprtg_"))
+	assert.NotContains(t, body, "prtg_channel_value")
+	assert.Zero(t, source.channelCalls)
+}
+
+func TestSensorMetrics_Cache(t *testing.T) {
+	source := &fakeMetricsSource{sensors: []types.Sensor{{ID: 1, Name: "A", Status: types.StatusUp}}}
+	handler := newTestMetricsHandler(source, 100)
+
+	now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	handler.now = func() time.Time { return now }
+
+	scrape(t, handler)
+	scrape(t, handler)
+	assert.Equal(t, 1, source.sensorCalls, "scrapes within cache_seconds reuse the output")
+
+	now = now.Add(time.Minute)
+	scrape(t, handler)
+	assert.Equal(t, 2, source.sensorCalls)
+}
+
+func TestSensorMetrics_DatabaseError(t *testing.T) {
+	source := &fakeMetricsSource{err: errors.New(`dial tcp db.internal:5432: connection refused`)}
+
+	code, body := scrape(t, newTestMetricsHandler(source, 100))
+	assert.Equal(t, http.StatusServiceUnavailable, code)
+	assert.NotContains(t, body, "db.internal")
+}
@@ -0,0 +1,200 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/matthieu/mcp-server-prtg/internal/services/logger"
+)
+
+// sseSessions caps the number of open SSE streams and closes the streams whose client sent no
+// message for idleTimeout, so that a client leaking connections cannot exhaust file descriptors.
+// Activity is a POST to the message endpoint of the session, including answers to keepalive pings.
+type sseSessions struct {
+	max         int
+	idleTimeout time.Duration // 0 = streams are never closed for inactivity
+	logger      *logger.ModuleLogger
+
+	mu     sync.Mutex
+	active int
+	timers map[string]*time.Timer // Idle timer by session ID, once the endpoint event is sent
+}
+
+// newSSESessions creates the SSE stream limits: at most max open streams, closed after
+// idleTimeout without client message (0 = never).
+func newSSESessions(maxStreams int, idleTimeout time.Duration, logger *logger.ModuleLogger) *sseSessions {
+	return &sseSessions{
+		max:         maxStreams,
+		idleTimeout: idleTimeout,
+		logger:      logger,
+		timers:      make(map[string]*time.Timer),
+	}
+}
+
+// streamHandler wraps the SSE stream handler: streams beyond the limit are rejected with 503,
+// and the stream of an idle session is ended by cancelling its request context.
+func (l *sseSessions) streamHandler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		if !l.acquire() {
+			l.logger.Warn().
+				Int("max_sse_connections", l.max).
+				Str("remote_addr", r.RemoteAddr).
+				Msg("SSE connection rejected, too many open streams")
+
+			w.Header().Set("Retry-After", "30")
+			http.Error(w, "Too many SSE connections", http.StatusServiceUnavailable)
+
+			return
+		}
+		defer l.release()
+
+		if l.idleTimeout <= 0 {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		ctx, cancel := context.WithCancel(r.Context())
+		defer cancel()
+
+		writer := &sseSessionWriter{ResponseWriter: w}
+
+		timer := time.AfterFunc(l.idleTimeout, func() {
+			l.logger.Info().
+				Str("remote_addr", r.RemoteAddr).
+				Dur("idle_timeout", l.idleTimeout).
+				Msg("Closing idle SSE session")
+			cancel()
+		})
+		defer timer.Stop()
+
+		writer.onSession = func(sessionID string) { l.register(sessionID, timer) }
+		defer func() { l.unregister(writer.sessionID) }()
+
+		next.ServeHTTP(writer, r.WithContext(ctx))
+	})
+}
+
+// messageHandler wraps the SSE message handler to record the activity of the session.
+// It must be placed after authentication, so that only authenticated clients keep a session open.
+func (l *sseSessions) messageHandler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		l.touch(r.URL.Query().Get("sessionId"))
+		next.ServeHTTP(w, r)
+	})
+}
+
+// acquire reserves a stream, or reports false when max streams are already open.
+func (l *sseSessions) acquire() bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.active >= l.max {
+		return false
+	}
+
+	l.active++
+
+	return true
+}
+
+// release frees a stream reserved with acquire.
+func (l *sseSessions) release() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.active--
+}
+
+// open returns the number of open streams.
+func (l *sseSessions) open() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	return l.active
+}
+
+// register associates a session ID with the idle timer of its stream.
+func (l *sseSessions) register(sessionID string, timer *time.Timer) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.timers[sessionID] = timer
+}
+
+// unregister forgets a session whose stream ended.
+func (l *sseSessions) unregister(sessionID string) {
+	if sessionID == "" {
+		return
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	delete(l.timers, sessionID)
+}
+
+// touch restarts the idle timer of a session. Unknown sessions are ignored.
+func (l *sseSessions) touch(sessionID string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if timer, ok := l.timers[sessionID]; ok {
+		timer.Reset(l.idleTimeout)
+	}
+}
+
+// sseSessionWriter passes the SSE stream through and reads the session ID from the endpoint
+// event, the first event of the stream ("event: endpoint\ndata: /message?sessionId=...").
+type sseSessionWriter struct {
+	http.ResponseWriter
+	sessionID string
+	onSession func(sessionID string)
+}
+
+// Write forwards the stream, extracting the session ID from the first endpoint event.
+func (w *sseSessionWriter) Write(p []byte) (int, error) {
+	if w.sessionID == "" && bytes.HasPrefix(p, []byte("event: endpoint\n")) {
+		if sessionID := endpointSessionID(string(p)); sessionID != "" {
+			w.sessionID = sessionID
+			w.onSession(sessionID)
+		}
+	}
+
+	return w.ResponseWriter.Write(p)
+}
+
+// Flush flushes the stream; the SSE handler requires an http.Flusher.
+func (w *sseSessionWriter) Flush() {
+	if flusher, ok := w.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// endpointSessionID returns the sessionId query parameter of the endpoint event, or "".
+func endpointSessionID(event string) string {
+	for _, line := range strings.Split(event, "\n") {
+		data, ok := strings.CutPrefix(strings.TrimSpace(line), "data:")
+		if !ok {
+			continue
+		}
+
+		endpoint, err := url.Parse(strings.TrimSpace(data))
+		if err != nil {
+			return ""
+		}
+
+		return endpoint.Query().Get("sessionId")
+	}
+
+	return ""
+}
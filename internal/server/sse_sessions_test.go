@@ -0,0 +1,147 @@
+package server
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/matthieu/mcp-server-prtg/internal/services/logger"
+)
+
+// fakeSSEStream sends the endpoint event of session ?id= like the mcp-go SSE handler, then
+// keeps the stream open until the request context ends.
+func fakeSSEStream(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/event-stream")
+	fmt.Fprintf(w, "event: endpoint\ndata: /message?sessionId=%s\r\n\r\n", r.URL.Query().Get("id"))
+	w.(http.Flusher).Flush()
+
+	<-r.Context().Done()
+}
+
+// newSSESessionsServer serves fakeSSEStream on /sse and an empty message endpoint on /message.
+func newSSESessionsServer(t *testing.T, sessions *sseSessions) *httptest.Server {
+	t.Helper()
+
+	mux := http.NewServeMux()
+	mux.Handle("/sse", sessions.streamHandler(http.HandlerFunc(fakeSSEStream)))
+	mux.Handle("/message", sessions.messageHandler(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusAccepted)
+	})))
+
+	ts := httptest.NewServer(mux)
+	t.Cleanup(ts.Close)
+
+	return ts
+}
+
+// openSSEStream opens a stream for session id. Once accepted, the endpoint event has been received.
+// The returned function closes the stream.
+func openSSEStream(t *testing.T, ts *httptest.Server, id string) (*http.Response, func()) {
+	t.Helper()
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, ts.URL+"/sse?id="+id, nil)
+	require.NoError(t, err)
+
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+
+	closeStream := func() {
+		cancel()
+		_ = resp.Body.Close()
+	}
+	t.Cleanup(closeStream)
+
+	if resp.StatusCode == http.StatusOK {
+		event, err := bufio.NewReader(resp.Body).ReadString('\n')
+		require.NoError(t, err)
+		require.Equal(t, "event: endpoint\n", event)
+	}
+
+	return resp, closeStream
+}
+
+func newTestSSESessions(maxStreams int, idleTimeout time.Duration) *sseSessions {
+	return newSSESessions(maxStreams, idleTimeout, logger.NewModuleLogger(logger.NewSilentLogger(), logger.ModuleServer))
+}
+
+func TestSSESessions_MaxConnections(t *testing.T) {
+	sessions := newTestSSESessions(2, 0)
+	ts := newSSESessionsServer(t, sessions)
+
+	_, closeFirst := openSSEStream(t, ts, "a")
+	resp, _ := openSSEStream(t, ts, "b")
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, 2, sessions.open())
+
+	// Beyond the limit
+	resp, _ = openSSEStream(t, ts, "c")
+	assert.Equal(t, http.StatusServiceUnavailable, resp.StatusCode)
+	assert.Equal(t, "30", resp.Header.Get("Retry-After"))
+	assert.Equal(t, 2, sessions.open(), "a rejected stream is not counted")
+
+	// A closed stream frees its slot
+	closeFirst()
+	assert.Eventually(t, func() bool { return sessions.open() == 1 }, time.Second, 10*time.Millisecond)
+
+	resp, _ = openSSEStream(t, ts, "d")
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, 2, sessions.open())
+}
+
+func TestSSESessions_IdleTimeout(t *testing.T) {
+	sessions := newTestSSESessions(10, 150*time.Millisecond)
+	ts := newSSESessionsServer(t, sessions)
+
+	idle, _ := openSSEStream(t, ts, "idle")
+	active, _ := openSSEStream(t, ts, "active")
+
+	// Messages of the active session restart its idle timer
+	for range 6 {
+		time.Sleep(50 * time.Millisecond)
+
+		resp, err := http.Post(ts.URL+"/message?sessionId=active", "application/json", nil)
+		require.NoError(t, err)
+		_ = resp.Body.Close()
+	}
+
+	// The idle stream was closed by the server, the active one is still open
+	_, err := io.ReadAll(idle.Body)
+	require.NoError(t, err, "the idle stream ends normally")
+	assert.Equal(t, 1, sessions.open())
+
+	// Without messages the active session ends too, and is forgotten
+	_, err = io.ReadAll(active.Body)
+	require.NoError(t, err)
+	assert.Eventually(t, func() bool { return sessions.open() == 0 }, time.Second, 10*time.Millisecond)
+
+	sessions.mu.Lock()
+	assert.Empty(t, sessions.timers)
+	sessions.mu.Unlock()
+}
+
+func TestEndpointSessionID(t *testing.T) {
+	tests := []struct {
+		event string
+		want  string
+	}{
+		{"event: endpoint\ndata: /message?sessionId=3f2a\r\n\r\n", "3f2a"},
+		{"event: endpoint\ndata: /prtg/message?sessionId=3f2a&token=secret\r\n\r\n", "3f2a"},
+		{"event: endpoint\ndata: http://localhost:8443/message?sessionId=3f2a\r\n\r\n", "3f2a"},
+		{"event: endpoint\ndata: /message\r\n\r\n", ""},
+		{"event: endpoint\n", ""},
+	}
+
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, endpointSessionID(tt.event), tt.event)
+	}
+}
@@ -2,15 +2,20 @@ package server
 
 import (
 	"context"
+	"crypto/subtle"
 	"crypto/tls"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"net"
 	"net/http"
+	"net/netip"
 	"strings"
 	"sync"
 	"time"
 
 	server "github.com/mark3labs/mcp-go/server"
+	"golang.org/x/crypto/acme/autocert"
 
 	"github.com/matthieu/mcp-server-prtg/internal/database"
 	"github.com/matthieu/mcp-server-prtg/internal/services/configuration"
@@ -108,24 +113,45 @@ func (rl *authRateLimiter) cleanup() {
 	}
 }
 
-// StreamableHTTPServer implements MCP server using Streamable HTTP transport.
+// StreamableHTTPServer implements MCP server using Streamable HTTP (or SSE, or WebSocket) transport.
 type StreamableHTTPServer struct {
 	mcpServer      *server.MCPServer
 	streamableHTTP http.Handler
+	sseServer      *server.SSEServer
+	wsHandler      *webSocketHandler
+	acmeManager    *autocert.Manager // Set when certificates are obtained via ACME
+	acmeHTTPServer *http.Server      // HTTP-01 challenge listener (ACME only)
+	acmeShared     bool              // acmeManager is shared with another listener, which serves HTTP-01 challenges
 	httpServer     *http.Server
+	listener       net.Listener // Closed first on shutdown, so no connection is accepted while tool calls drain
 	config         *configuration.Configuration
 	logger         *logger.ModuleLogger
 	db             *database.DB
 	rateLimiter    *authRateLimiter
+	sseSessions    *sseSessions   // Open stream limit and idle timeout of the SSE transport
+	trustedProxies []netip.Prefix // Peers allowed to set X-Forwarded-For / X-Real-IP
+	heartbeat      time.Duration  // Keepalive interval of Streamable HTTP streams
+	inFlight       *InFlightTracker
+	transport      string
 	address        string
+	basePath       string        // Prefix of all endpoints (e.g. "/prtg"), empty = none
 	shutdownCh     chan struct{} // Channel for graceful shutdown of background tasks
+	stopOnce       sync.Once     // Guards stopAccepting
+
+	// Parent context of all requests, cancelled on shutdown once tool calls are drained
+	// so that open streams (SSE, Streamable HTTP GET) end instead of holding the server
+	requestCtx     context.Context
+	cancelRequests context.CancelFunc
 }
 
 // NewStreamableHTTPServer creates a new Streamable HTTP-based MCP server.
+// The inFlight tracker must be registered as tool middleware on mcpServer so that
+// Shutdown can drain active tool calls.
 func NewStreamableHTTPServer(
 	mcpServer *server.MCPServer,
 	db *database.DB,
 	config *configuration.Configuration,
+	inFlight *InFlightTracker,
 	baseLogger *logger.Logger,
 ) *StreamableHTTPServer {
 	logger := logger.NewModuleLogger(baseLogger, logger.ModuleServer)
@@ -133,14 +159,24 @@ func NewStreamableHTTPServer(
 	// Get server address for binding
 	address := config.GetServerAddress()
 
+	requestCtx, cancelRequests := context.WithCancel(context.Background())
+
 	return &StreamableHTTPServer{
-		mcpServer:   mcpServer,
-		config:      config,
-		logger:      logger,
-		db:          db,
-		rateLimiter: newAuthRateLimiter(),
-		address:     address,
-		shutdownCh:  make(chan struct{}),
+		mcpServer:      mcpServer,
+		config:         config,
+		logger:         logger,
+		db:             db,
+		rateLimiter:    newAuthRateLimiter(),
+		sseSessions:    newSSESessions(config.GetMaxSSEConnections(), config.GetSSEIdleTimeout(), logger),
+		trustedProxies: config.GetTrustedProxies(),
+		heartbeat:      config.GetHeartbeatInterval(),
+		inFlight:       inFlight,
+		transport:      config.GetTransport(),
+		address:        address,
+		basePath:       config.GetBasePath(),
+		shutdownCh:     make(chan struct{}),
+		requestCtx:     requestCtx,
+		cancelRequests: cancelRequests,
 	}
 }
 
@@ -149,13 +185,23 @@ func (s *StreamableHTTPServer) Start(_ context.Context) error {
 	s.logger.Info().
 		Str("address", s.address).
 		Bool("tls", s.config.IsTLSEnabled()).
-		Msg("Starting MCP Server with Streamable HTTP transport")
-
-	// Create Streamable HTTP server with heartbeat support
-	// Default heartbeat interval is 30 seconds, can be configured
-	heartbeatInterval := 30 * time.Second
-	heartbeatOption := server.WithHeartbeatInterval(heartbeatInterval)
-	s.streamableHTTP = server.NewStreamableHTTPServer(s.mcpServer, heartbeatOption)
+		Str("transport", s.transport).
+		Msg("Starting MCP Server")
+
+	switch s.transport {
+	case configuration.TransportWebSocket:
+		// WebSocket transport with ping/pong keepalives
+		// Bounded like tool calls (server.max_concurrent_requests), per connection
+		s.wsHandler = newWebSocketHandler(s.mcpServer, s.logger, s.config.GetMaxConcurrentRequests())
+	case configuration.TransportSSE:
+		s.sseServer = newSSEServer(s.mcpServer, s.basePath, s.heartbeat)
+	default:
+		// Create Streamable HTTP server with heartbeat support (server.heartbeat_interval_seconds)
+		heartbeatOption := server.WithHeartbeatInterval(s.heartbeat)
+		s.streamableHTTP = server.NewStreamableHTTPServer(s.mcpServer, heartbeatOption,
+			server.WithEndpointPath(s.route("/mcp")),
+			server.WithHTTPContextFunc(contextWithClientInfo))
+	}
 
 	// Start rate limiter cleanup goroutine
 	go s.cleanupRateLimiterPeriodically()
@@ -171,27 +217,13 @@ func (s *StreamableHTTPServer) Start(_ context.Context) error {
 	return nil
 }
 
-// startHTTPServer starts the HTTP server with all endpoints.
-//
-//nolint:unparam // error return kept for consistency and future-proofing
+// startHTTPServer starts the HTTP server with all endpoints. The port is bound before
+// returning, so an address already in use fails the startup.
 func (s *StreamableHTTPServer) startHTTPServer() error {
-	// Create mux with all endpoints
-	mux := http.NewServeMux()
-
-	// MCP endpoint with authentication middleware
-	mux.Handle("/mcp", s.createAuthMiddleware(s.streamableHTTP))
-
-	// Health check endpoint (no auth)
-	mux.HandleFunc("/health", s.handleHealth)
-
-	// Status endpoint (auth required)
-	statusHandler := s.createAuthMiddleware(http.HandlerFunc(s.handleStatus))
-	mux.Handle("/status", statusHandler)
-
 	// Create HTTP server with optimized timeouts
 	s.httpServer = &http.Server{
 		Addr:              s.address,
-		Handler:           mux,
+		Handler:           s.newMux(),
 		ReadTimeout:       0,                // No read timeout for streaming connections
 		WriteTimeout:      0,                // No write timeout for streaming connections
 		IdleTimeout:       60 * time.Minute, // Close inactive connections after 1 hour
@@ -199,26 +231,36 @@ func (s *StreamableHTTPServer) startHTTPServer() error {
 		MaxHeaderBytes:    1 << 20,          // 1MB max header size
 	}
 
+	if s.requestCtx != nil {
+		s.httpServer.BaseContext = func(net.Listener) context.Context { return s.requestCtx }
+	}
+
 	// Configure TLS if enabled
+	var certFile, keyFile string
 	if s.config.IsTLSEnabled() {
-		certFile := s.config.GetTLSCertFile()
-		keyFile := s.config.GetTLSKeyFile()
-
-		tlsConfig := &tls.Config{
-			MinVersion:               tls.VersionTLS12,
-			PreferServerCipherSuites: true,
+		var err error
+		if certFile, keyFile, err = s.configureTLS(); err != nil {
+			return err
 		}
+	}
 
-		s.httpServer.TLSConfig = tlsConfig
+	listener, err := net.Listen("tcp", s.address)
+	if err != nil {
+		return fmt.Errorf("failed to listen on %s: %w", s.address, err)
+	}
 
-		s.logger.Info().
-			Str("cert", certFile).
-			Str("key", keyFile).
-			Msg("Starting HTTPS server")
+	// Closed by stopAccepting, then again by http.Server.Shutdown
+	listener = &closeOnceListener{Listener: listener}
+	s.listener = listener
+
+	if s.config.IsTLSEnabled() {
+		if s.acmeManager != nil && !s.acmeShared {
+			s.startACMEChallengeServer(s.acmeManager, s.config.GetACMEConfig().HTTPAddress)
+		}
 
 		// Start server in background
 		go func() {
-			if err := s.httpServer.ListenAndServeTLS(certFile, keyFile); err != nil && err != http.ErrServerClosed {
+			if err := s.httpServer.ServeTLS(listener, certFile, keyFile); err != nil && !isServerStopped(err) {
 				s.logger.Error().Err(err).Msg("HTTPS server error")
 			}
 		}()
@@ -227,7 +269,7 @@ func (s *StreamableHTTPServer) startHTTPServer() error {
 
 		// Start server in background
 		go func() {
-			if err := s.httpServer.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			if err := s.httpServer.Serve(listener); err != nil && !isServerStopped(err) {
 				s.logger.Error().Err(err).Msg("HTTP server error")
 			}
 		}()
@@ -236,39 +278,198 @@ func (s *StreamableHTTPServer) startHTTPServer() error {
 	return nil
 }
 
-// Handles X-Forwarded-For and X-Real-IP headers for proxy situations.
-func getClientIP(r *http.Request) string {
-	// Try X-Real-IP first (single IP from trusted proxy)
-	if ip := r.Header.Get("X-Real-IP"); ip != "" {
-		return ip
+// closeOnceListener is a net.Listener that can be closed several times, the later calls
+// returning the result of the first one.
+type closeOnceListener struct {
+	net.Listener
+
+	once sync.Once
+	err  error
+}
+
+// Close closes the listener on the first call.
+func (l *closeOnceListener) Close() error {
+	l.once.Do(func() { l.err = l.Listener.Close() })
+
+	return l.err
+}
+
+// isServerStopped reports whether a Serve error comes from shutdown: the server was shut down,
+// or its listener was closed by stopAccepting.
+func isServerStopped(err error) bool {
+	return errors.Is(err, http.ErrServerClosed) || errors.Is(err, net.ErrClosed)
+}
+
+// configureTLS sets the TLS config of the HTTP server. With ACME enabled, certificates are
+// obtained automatically and empty cert/key file paths are returned; otherwise the configured
+// (or self-signed) certificate files are used.
+func (s *StreamableHTTPServer) configureTLS() (certFile, keyFile string, err error) {
+	if s.config.IsACMEEnabled() {
+		acmeConfig := s.config.GetACMEConfig()
+
+		if s.acmeManager == nil {
+			s.acmeManager = newACMEManager(acmeConfig)
+		}
+
+		s.httpServer.TLSConfig = newACMETLSConfig(s.acmeManager)
+
+		s.logger.Info().
+			Strs("domains", acmeConfig.Domains).
+			Str("cache_dir", acmeConfig.CacheDir).
+			Msg("Starting HTTPS server with ACME certificates")
+	} else {
+		certFile = s.config.GetTLSCertFile()
+		keyFile = s.config.GetTLSKeyFile()
+
+		s.httpServer.TLSConfig = &tls.Config{
+			MinVersion:               tls.VersionTLS12,
+			PreferServerCipherSuites: true,
+		}
+
+		s.logger.Info().
+			Str("cert", certFile).
+			Str("key", keyFile).
+			Msg("Starting HTTPS server")
+	}
+
+	// Optional mutual TLS
+	caFile := s.config.GetTLSClientCAFile()
+	if err := configureClientAuth(s.httpServer.TLSConfig, caFile, s.config.IsTLSClientCertRequired()); err != nil {
+		return "", "", err
+	}
+
+	if caFile != "" {
+		s.logger.Info().
+			Str("client_ca_file", caFile).
+			Bool("require_client_cert", s.config.IsTLSClientCertRequired()).
+			Bool("allow_mtls_auth", s.config.GetAuthConfig().AllowMTLS).
+			Msg("Client certificate verification enabled")
+	}
+
+	return certFile, keyFile, nil
+}
+
+// newMux creates the router with the MCP endpoint for the configured transport.
+// All endpoints are registered under the configured base path.
+func (s *StreamableHTTPServer) newMux() *http.ServeMux {
+	mux := http.NewServeMux()
+
+	// MCP endpoint with authentication middleware (applied before the WebSocket upgrade)
+	switch {
+	case s.wsHandler != nil:
+		mux.Handle(s.route("/ws"), s.createAuthMiddleware(s.wsHandler))
+	case s.sseServer != nil:
+		mux.Handle(s.route("/sse"), s.createAuthMiddleware(s.sseSessions.streamHandler(s.sseServer.SSEHandler())))
+		mux.Handle(s.route("/message"), s.createAuthMiddleware(s.sseSessions.messageHandler(s.sseServer.MessageHandler())))
+	default:
+		mux.Handle(s.route("/mcp"), s.createAuthMiddleware(s.streamableHTTP))
+	}
+
+	// Health check endpoint (no auth)
+	mux.HandleFunc(s.route("/health"), s.handleHealth)
+
+	// Status endpoint (auth required)
+	statusHandler := s.createAuthMiddleware(http.HandlerFunc(s.handleStatus))
+	mux.Handle(s.route("/status"), statusHandler)
+
+	// Sensor metrics for Prometheus (auth required, opt-in with server.sensor_metrics.enabled)
+	if metrics := s.config.GetSensorMetricsConfig(); metrics.Enabled && s.db != nil {
+		handler := newSensorMetricsHandler(s.db, metrics, s.config.HistoryTable() != "", s.logger)
+		mux.Handle(s.route("/prtg-metrics"), s.createAuthMiddleware(handler))
+	}
+
+	return mux
+}
+
+// route prefixes an endpoint path with the configured base path (e.g. "/mcp" -> "/prtg/mcp").
+func (s *StreamableHTTPServer) route(path string) string {
+	return s.basePath + path
+}
+
+// endpointURL returns the public URL of an endpoint, as advertised in the startup logs.
+func (s *StreamableHTTPServer) endpointURL(protocol, path string) string {
+	return fmt.Sprintf("%s://%s%s", protocol, s.address, s.route(path))
+}
+
+// getClientIP returns the IP address of the client that sent r.
+// X-Real-IP and X-Forwarded-For are only honoured when the direct peer is one of the
+// trusted proxies, otherwise any client could spoof them to evade rate limiting.
+// X-Forwarded-For is read right to left, skipping trusted proxies, so the first
+// untrusted hop is returned rather than the client-controlled leftmost entry.
+func getClientIP(r *http.Request, trustedProxies []netip.Prefix) string {
+	peer, ok := parseIP(r.RemoteAddr)
+	if !ok {
+		return r.RemoteAddr
+	}
+
+	if !isTrustedProxy(peer, trustedProxies) {
+		return peer.String()
+	}
+
+	if ip, ok := parseIP(r.Header.Get("X-Real-IP")); ok {
+		return ip.String()
 	}
 
-	// Try X-Forwarded-For (can be a list)
-	if forwarded := r.Header.Get("X-Forwarded-For"); forwarded != "" {
-		// Take the first IP (client IP)
-		if idx := strings.Index(forwarded, ","); idx != -1 {
-			return strings.TrimSpace(forwarded[:idx])
+	if forwarded := r.Header.Values("X-Forwarded-For"); len(forwarded) > 0 {
+		hops := strings.Split(strings.Join(forwarded, ","), ",")
+
+		client := peer
+		for i := len(hops) - 1; i >= 0; i-- {
+			ip, ok := parseIP(hops[i])
+			if !ok {
+				break
+			}
+
+			client = ip
+			if !isTrustedProxy(ip, trustedProxies) {
+				break
+			}
 		}
 
-		return strings.TrimSpace(forwarded)
+		return client.String()
+	}
+
+	return peer.String()
+}
+
+// parseIP parses an IP address with an optional port, as found in RemoteAddr and
+// forwarding headers: "192.0.2.1", "192.0.2.1:8080", "2001:db8::1", "[2001:db8::1]:8080".
+// IPv4-mapped IPv6 addresses are returned as IPv4 and zones are dropped.
+func parseIP(value string) (netip.Addr, bool) {
+	value = strings.TrimSpace(value)
+
+	if host, _, err := net.SplitHostPort(value); err == nil {
+		value = host
 	}
 
-	// Fall back to RemoteAddr
-	// RemoteAddr is in format "IP:port", extract just the IP
-	if host, _, err := net.SplitHostPort(r.RemoteAddr); err == nil {
-		return host
+	addr, err := netip.ParseAddr(strings.TrimSuffix(strings.TrimPrefix(value, "["), "]"))
+	if err != nil {
+		return netip.Addr{}, false
 	}
 
-	return r.RemoteAddr
+	return addr.Unmap().WithZone(""), true
 }
 
-// createAuthMiddleware creates authentication middleware using Bearer token with rate limiting.
+// isTrustedProxy reports whether ip belongs to one of the trusted proxy ranges.
+func isTrustedProxy(ip netip.Addr, trustedProxies []netip.Prefix) bool {
+	for _, prefix := range trustedProxies {
+		if prefix.Contains(ip) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// createAuthMiddleware creates authentication middleware with rate limiting.
+// The API key is read from the sources configured in server.auth (default: Bearer token or ?token=)
+// and checked against every configured key, re-read on each request so reloads revoke keys at once.
 func (s *StreamableHTTPServer) createAuthMiddleware(next http.Handler) http.Handler {
-	expectedToken := s.config.GetAPIKey()
+	auth := s.config.GetAuthConfig()
 
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		// Extract client IP for rate limiting
-		clientIP := getClientIP(r)
+		clientIP := getClientIP(r, s.trustedProxies)
 
 		// Check rate limit BEFORE validating token (prevent brute-force)
 		if !s.rateLimiter.checkAndRecord(clientIP, false) {
@@ -283,28 +484,17 @@ func (s *StreamableHTTPServer) createAuthMiddleware(next http.Handler) http.Hand
 			return
 		}
 
-		// Extract Bearer token from Authorization header
-		authHeader := r.Header.Get("Authorization")
+		authHeader := r.Header.Get(auth.HeaderName)
+		providedToken := extractToken(r, auth)
+		clientCN := verifiedClientCN(r)
 
-		var providedToken string
+		keyName, keyValid := matchAPIKey(providedToken, s.config.GetAPIKeys())
 
-		if authHeader != "" {
-			// Check for "Bearer <token>" format
-			const bearerPrefix = "Bearer "
-			if len(authHeader) > len(bearerPrefix) && authHeader[:len(bearerPrefix)] == bearerPrefix {
-				providedToken = authHeader[len(bearerPrefix):]
-			}
-		}
-
-		// Fallback: check query parameter (for compatibility)
-		if providedToken == "" {
-			providedToken = r.URL.Query().Get("token")
-		}
-
-		// Validate token
-		if providedToken != expectedToken {
+		// Validate token, unless a verified client certificate is enough (auth.allow_mtls)
+		if !(auth.AllowMTLS && clientCN != "") && !keyValid {
 			s.logger.Warn().
 				Str("client_ip", clientIP).
+				Str("client_cn", clientCN).
 				Str("remote_addr", r.RemoteAddr).
 				Str("path", r.URL.Path).
 				Str("method", r.Method).
@@ -322,15 +512,70 @@ func (s *StreamableHTTPServer) createAuthMiddleware(next http.Handler) http.Hand
 		// Log successful authentication
 		s.logger.Debug().
 			Str("client_ip", clientIP).
+			Str("client_cn", clientCN).
+			Str("api_key_name", keyName).
 			Str("path", r.URL.Path).
 			Str("method", r.Method).
 			Msg("Authenticated request")
 
-		// Call next handler
-		next.ServeHTTP(w, r)
+		// Call next handler, with the resolved client identity for the audit log and WebSocket logs
+		ctx := context.WithValue(r.Context(), clientIPKey{}, clientIP)
+		if clientCN != "" {
+			ctx = context.WithValue(ctx, clientCNKey{}, clientCN)
+		}
+
+		if keyValid {
+			ctx = context.WithValue(ctx, apiKeyNameKey{}, keyName)
+		}
+
+		next.ServeHTTP(w, r.WithContext(ctx))
 	})
 }
 
+// extractToken returns the API key sent by the client, from the configured header
+// ("<scheme> <key>", or the bare key without scheme) or, when allowed, the ?token= query parameter.
+func extractToken(r *http.Request, auth configuration.AuthConfig) string {
+	if value := r.Header.Get(auth.HeaderName); value != "" {
+		if auth.Scheme == "" {
+			return value
+		}
+
+		// Scheme names are case-insensitive (RFC 7235)
+		prefix := auth.Scheme + " "
+		if len(value) > len(prefix) && strings.EqualFold(value[:len(prefix)], prefix) {
+			return value[len(prefix):]
+		}
+	}
+
+	if auth.AllowQueryParam != nil && *auth.AllowQueryParam {
+		return r.URL.Query().Get("token")
+	}
+
+	return ""
+}
+
+// matchAPIKey returns the name of the configured key equal to provided. Every key is compared
+// in constant time, without stopping at the first match, so timing does not reveal which key matched.
+func matchAPIKey(provided string, keys []configuration.APIKey) (string, bool) {
+	if provided == "" {
+		return "", false
+	}
+
+	name := ""
+	found := 0
+
+	for _, key := range keys {
+		match := subtle.ConstantTimeCompare([]byte(provided), []byte(key.Key))
+		if match == 1 {
+			name = key.Name
+		}
+
+		found |= match
+	}
+
+	return name, found == 1
+}
+
 // cleanupRateLimiterPeriodically runs periodic cleanup of rate limiter entries.
 func (s *StreamableHTTPServer) cleanupRateLimiterPeriodically() {
 	ticker := time.NewTicker(10 * time.Minute)
@@ -360,31 +605,59 @@ func (s *StreamableHTTPServer) handleHealth(w http.ResponseWriter, _ *http.Reque
 
 // handleStatus handles status requests (requires authentication).
 func (s *StreamableHTTPServer) handleStatus(w http.ResponseWriter, r *http.Request) {
-	status := map[string]interface{}{
-		"version":   version.Get(),
-		"transport": "streamable-http",
-		"protocol":  "2025-03-26",
-		"uptime":    time.Since(startTime).String(),
+	status := statusResponse{
+		Version:   version.Get(),
+		Transport: s.transport,
+		Protocol:  "2025-03-26",
+		Uptime:    time.Since(startTime).String(),
 	}
 
-	// Check database connection
+	// Check database connection (the health check reconnects a lost connection)
 	if s.db != nil {
 		if err := s.db.Health(r.Context()); err != nil {
-			status["database"] = "error"
-			status["database_error"] = err.Error()
+			status.Database = "error"
+			status.DatabaseError = databaseErrorReason(err)
+
+			// The driver error may name hosts or users: it is logged, never returned
+			s.logger.Warn().Err(err).Str("reason", status.DatabaseError).Msg("Database health check failed")
 		} else {
-			status["database"] = "connected"
+			status.Database = "connected"
 		}
+
+		status.DatabaseState, _ = s.db.State()
 	} else {
-		status["database"] = "not_configured"
+		status.Database = "not_configured"
+		status.DatabaseState = database.StateDisconnected
 	}
 
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
 
-	// Simple JSON encoding
-	fmt.Fprintf(w, `{"version":"%s","transport":"streamable-http","protocol":"2025-03-26","uptime":"%s","database":"%s"}`,
-		status["version"], status["uptime"], status["database"])
+	if err := json.NewEncoder(w).Encode(status); err != nil {
+		s.logger.Error().Err(err).Msg("Failed to write status response")
+	}
+}
+
+// statusResponse is the JSON body of the /status endpoint.
+type statusResponse struct {
+	Version       string `json:"version"`
+	Transport     string `json:"transport"`
+	Protocol      string `json:"protocol"`
+	Uptime        string `json:"uptime"`
+	Database      string `json:"database"`
+	DatabaseState string `json:"database_state"`
+	DatabaseError string `json:"database_error,omitempty"` // Failure reason, see database.HealthError
+}
+
+// databaseErrorReason returns the reason of a failed database health check that is safe to
+// expose: one of the database.Health* reasons, never the driver error.
+func databaseErrorReason(err error) string {
+	var healthErr *database.HealthError
+	if errors.As(err, &healthErr) {
+		return healthErr.Reason
+	}
+
+	return database.HealthUnavailable
 }
 
 // logStartupInfo logs startup information.
@@ -394,34 +667,145 @@ func (s *StreamableHTTPServer) logStartupInfo() {
 		protocol = "https"
 	}
 
+	if metrics := s.config.GetSensorMetricsConfig(); metrics.Enabled && s.db != nil {
+		s.logger.Info().
+			Str("url", s.endpointURL(protocol, "/prtg-metrics")).
+			Int("max_series", metrics.MaxSeries).
+			Int("cache_seconds", metrics.CacheSeconds).
+			Msg("Sensor metrics endpoint enabled")
+	}
+
+	if s.wsHandler != nil {
+		wsProtocol := "ws"
+		if s.config.IsTLSEnabled() {
+			wsProtocol = "wss"
+		}
+
+		s.logger.Info().
+			Str("url", s.endpointURL(wsProtocol, "/ws")).
+			Str("health_check", s.endpointURL(protocol, "/health")).
+			Str("status", s.endpointURL(protocol, "/status")).
+			Str("version", version.Get()).
+			Str("protocol", "2025-03-26").
+			Msg("MCP Server ready (WebSocket transport, Bearer token or ?token= query parameter)")
+
+		return
+	}
+
+	if s.sseServer != nil {
+		s.logger.Info().
+			Str("url", s.endpointURL(protocol, "/sse")).
+			Str("message_endpoint", s.endpointURL(protocol, "/message")).
+			Str("health_check", s.endpointURL(protocol, "/health")).
+			Str("status", s.endpointURL(protocol, "/status")).
+			Str("version", version.Get()).
+			Dur("keepalive_interval", s.heartbeat).
+			Int("max_sse_connections", s.sseSessions.max).
+			Dur("sse_idle_timeout", s.sseSessions.idleTimeout).
+			Msg("MCP Server ready (SSE transport)")
+
+		return
+	}
+
 	s.logger.Info().
-		Str("url", fmt.Sprintf("%s://%s/mcp", protocol, s.address)).
-		Str("health_check", fmt.Sprintf("%s://%s/health", protocol, s.address)).
-		Str("status", fmt.Sprintf("%s://%s/status", protocol, s.address)).
+		Str("url", s.endpointURL(protocol, "/mcp")).
+		Str("health_check", s.endpointURL(protocol, "/health")).
+		Str("status", s.endpointURL(protocol, "/status")).
 		Str("version", version.Get()).
 		Str("protocol", "2025-03-26").
+		Dur("heartbeat_interval", s.heartbeat).
 		Msg("MCP Server ready")
 
 	s.logger.Info().Msg("Configure Claude Desktop with:")
 	s.logger.Info().Msgf(`  "mcpServers": {`)
 	s.logger.Info().Msgf(`    "prtg": {`)
-	s.logger.Info().Msgf(`      "url": "%s://%s/mcp",`, protocol, s.address)
+	s.logger.Info().Msgf(`      "url": "%s",`, s.endpointURL(protocol, "/mcp"))
 	s.logger.Info().Msgf(`      "headers": {`)
-	s.logger.Info().Msgf(`        "Authorization": "Bearer YOUR_API_KEY"`)
+	auth := s.config.GetAuthConfig()
+	s.logger.Info().Msgf(`        "%s": "%s"`, auth.HeaderName, strings.TrimSpace(auth.Scheme+" YOUR_API_KEY"))
 	s.logger.Info().Msgf(`      }`)
 	s.logger.Info().Msgf(`    }`)
 	s.logger.Info().Msgf(`  }`)
 }
 
-// Shutdown gracefully shuts down the server.
+// Shutdown gracefully shuts down the server. Listeners sharing an in-flight tracker are shut
+// down together by TransportServers.Shutdown, which drains the tool calls once.
 func (s *StreamableHTTPServer) Shutdown(ctx context.Context) error {
-	s.logger.Info().Msg("Shutting down Streamable HTTP server")
+	s.stopAccepting()
+	drainToolCalls(ctx, s.inFlight, s.logger)
+
+	return s.closeConnections(ctx)
+}
+
+// stopAccepting signals background tasks to stop and closes the listener. Open connections
+// stay up, so that tool calls in progress can still answer on them.
+func (s *StreamableHTTPServer) stopAccepting() {
+	s.stopOnce.Do(func() {
+		s.logger.Info().Str("transport", s.transport).Str("address", s.address).Msg("Shutting down MCP server")
+
+		close(s.shutdownCh)
+
+		if s.listener != nil {
+			if err := s.listener.Close(); err != nil {
+				s.logger.Warn().Err(err).Str("address", s.address).Msg("Failed to close listener")
+			}
+		}
+	})
+}
+
+// drainToolCalls lets the active tool calls of inFlight finish within the grace period of ctx.
+// New tool calls are rejected from then on.
+func drainToolCalls(ctx context.Context, inFlight *InFlightTracker, moduleLogger *logger.ModuleLogger) {
+	if inFlight == nil {
+		return
+	}
+
+	active := inFlight.Active()
+	if active > 0 {
+		moduleLogger.Info().
+			Int("in_flight", active).
+			Msg("Waiting for in-flight tool calls to complete")
+	}
 
-	// Signal background tasks to stop
-	close(s.shutdownCh)
+	drained, terminated := inFlight.Drain(ctx)
+
+	logEvent := moduleLogger.Info()
+	if terminated > 0 {
+		logEvent = moduleLogger.Warn()
+	}
+
+	logEvent.
+		Int("drained", drained).
+		Int("terminated", terminated).
+		Msg("In-flight tool calls drained")
+}
+
+// closeConnections ends the open streams and connections once tool calls are drained, and
+// stops the HTTP server.
+func (s *StreamableHTTPServer) closeConnections(ctx context.Context) error {
+	// End open streams: tool calls are drained, nothing is left to send on them
+	if s.cancelRequests != nil {
+		s.cancelRequests()
+	}
+
+	// Hijacked WebSocket connections are not tracked by http.Server - close them explicitly
+	if s.wsHandler != nil {
+		s.wsHandler.Close(ctx)
+	}
+
+	if s.acmeHTTPServer != nil {
+		if err := s.acmeHTTPServer.Shutdown(ctx); err != nil {
+			s.logger.Warn().Err(err).Msg("Failed to shutdown ACME challenge server")
+		}
+	}
 
 	// Shutdown HTTP server
 	if err := s.httpServer.Shutdown(ctx); err != nil {
+		// Grace period exhausted (e.g. open streaming connections) - force close
+		if closeErr := s.httpServer.Close(); closeErr != nil {
+			s.logger.Error().Err(closeErr).Msg("Failed to force close HTTP server")
+		}
+
 		return fmt.Errorf("failed to shutdown HTTP server: %w", err)
 	}
 
@@ -430,5 +814,18 @@ func (s *StreamableHTTPServer) Shutdown(ctx context.Context) error {
 	return nil
 }
 
+// newSSEServer creates the handlers of the SSE transport: a GET stream on /sse and a POST
+// endpoint on /message. The message endpoint is advertised as a path, resolved by clients
+// against the URL they connected to, so it stays valid behind a reverse proxy. Query
+// parameters of the stream are kept on it for clients authenticating with ?token=.
+func newSSEServer(mcpServer *server.MCPServer, basePath string, keepAlive time.Duration) *server.SSEServer {
+	return server.NewSSEServer(mcpServer,
+		server.WithStaticBasePath(basePath),
+		server.WithUseFullURLForMessageEndpoint(false),
+		server.WithAppendQueryToMessageEndpoint(),
+		server.WithKeepAliveInterval(keepAlive),
+		server.WithSSEContextFunc(contextWithClientInfo))
+}
+
 //nolint:gochecknoglobals // Server start time is package-level constant set once at initialization.
 var startTime = time.Now()
@@ -0,0 +1,291 @@
+package server
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/lib/pq"
+	server "github.com/mark3labs/mcp-go/server"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/matthieu/mcp-server-prtg/internal/cliargs"
+	"github.com/matthieu/mcp-server-prtg/internal/database"
+	"github.com/matthieu/mcp-server-prtg/internal/services/configuration"
+	"github.com/matthieu/mcp-server-prtg/internal/services/logger"
+)
+
+// newTestHTTPServer starts a Streamable HTTP transport server. serverYAML holds extra
+// indented lines of the server section. It returns the URL of the test server root.
+func newTestHTTPServer(t *testing.T, serverYAML string) string {
+	t.Helper()
+
+	configPath := filepath.Join(t.TempDir(), "config.yaml")
+	configYAML := "server:\n  api_key: " + testAPIKey + "\n" + serverYAML
+	require.NoError(t, os.WriteFile(configPath, []byte(configYAML), 0o600))
+
+	return newTestHTTPServerFromFile(t, configPath)
+}
+
+// newTestHTTPServerFromFile starts a Streamable HTTP transport server for the configuration
+// file at configPath, which is watched for changes. It returns the URL of the test server root.
+func newTestHTTPServerFromFile(t *testing.T, configPath string) string {
+	t.Helper()
+
+	config, err := configuration.NewConfiguration(&cliargs.ParsedArgs{ConfigPath: configPath}, logger.NewSilentLogger())
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = config.Shutdown(context.Background()) })
+
+	inFlight := NewInFlightTracker()
+	mcpServer := server.NewMCPServer("test", "1.0.0", server.WithToolHandlerMiddleware(inFlight.Middleware))
+
+	s := NewStreamableHTTPServer(mcpServer, nil, config, inFlight, logger.NewSilentLogger())
+	s.streamableHTTP = server.NewStreamableHTTPServer(mcpServer, server.WithEndpointPath(s.route("/mcp")))
+
+	httpServer := httptest.NewServer(s.newMux())
+	t.Cleanup(httpServer.Close)
+
+	return httpServer.URL
+}
+
+// getStatusCode sends an authenticated GET request and returns the response status code.
+func getStatusCode(t *testing.T, url string) int {
+	t.Helper()
+
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	require.NoError(t, err)
+	req.Header.Set("Authorization", "Bearer "+testAPIKey)
+
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	_ = resp.Body.Close()
+
+	return resp.StatusCode
+}
+
+func TestNewMux_BasePath(t *testing.T) {
+	baseURL := newTestHTTPServer(t, "  base_path: prtg/\n")
+
+	for _, path := range []string{"/health", "/status"} {
+		assert.Equal(t, http.StatusOK, getStatusCode(t, baseURL+"/prtg"+path), "%s must respond under the prefix", path)
+		assert.Equal(t, http.StatusNotFound, getStatusCode(t, baseURL+path), "%s must not respond without the prefix", path)
+	}
+
+	// The MCP endpoint is routed under the prefix too (a GET without a session is not a 404)
+	assert.NotEqual(t, http.StatusNotFound, getStatusCode(t, baseURL+"/prtg/mcp"))
+	assert.Equal(t, http.StatusNotFound, getStatusCode(t, baseURL+"/mcp"))
+}
+
+func TestNewMux_NoBasePath(t *testing.T) {
+	baseURL := newTestHTTPServer(t, "")
+
+	assert.Equal(t, http.StatusOK, getStatusCode(t, baseURL+"/health"))
+	assert.Equal(t, http.StatusOK, getStatusCode(t, baseURL+"/status"))
+}
+
+// statusCodeWith sends a GET request to /status with the given header (skipped when empty) and query.
+func statusCodeWith(t *testing.T, baseURL, header, value, query string) int {
+	t.Helper()
+
+	req, err := http.NewRequest(http.MethodGet, baseURL+"/status"+query, nil)
+	require.NoError(t, err)
+	if header != "" {
+		req.Header.Set(header, value)
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	_ = resp.Body.Close()
+
+	return resp.StatusCode
+}
+
+func TestAuth_DefaultSources(t *testing.T) {
+	baseURL := newTestHTTPServer(t, "")
+
+	assert.Equal(t, http.StatusOK, statusCodeWith(t, baseURL, "Authorization", "Bearer "+testAPIKey, ""))
+	assert.Equal(t, http.StatusOK, statusCodeWith(t, baseURL, "Authorization", "bearer "+testAPIKey, ""), "scheme is case-insensitive")
+	assert.Equal(t, http.StatusOK, statusCodeWith(t, baseURL, "", "", "?token="+testAPIKey))
+	assert.Equal(t, http.StatusUnauthorized, statusCodeWith(t, baseURL, "Authorization", testAPIKey, ""), "scheme is required")
+	assert.Equal(t, http.StatusUnauthorized, statusCodeWith(t, baseURL, "X-API-Key", testAPIKey, ""))
+	assert.Equal(t, http.StatusUnauthorized, statusCodeWith(t, baseURL, "Authorization", "Bearer wrong-key", ""))
+}
+
+func TestAuth_CustomHeader(t *testing.T) {
+	baseURL := newTestHTTPServer(t, "  auth:\n    header_name: X-API-Key\n")
+
+	assert.Equal(t, http.StatusOK, statusCodeWith(t, baseURL, "X-API-Key", testAPIKey, ""))
+	assert.Equal(t, http.StatusUnauthorized, statusCodeWith(t, baseURL, "X-API-Key", "wrong-key", ""))
+	assert.Equal(t, http.StatusUnauthorized, statusCodeWith(t, baseURL, "Authorization", "Bearer "+testAPIKey, ""),
+		"only the configured header is accepted")
+	assert.Equal(t, http.StatusOK, statusCodeWith(t, baseURL, "", "", "?token="+testAPIKey), "query param allowed by default")
+}
+
+func TestAuth_QueryParamDisabled(t *testing.T) {
+	baseURL := newTestHTTPServer(t, "  auth:\n    allow_query_param: false\n")
+
+	assert.Equal(t, http.StatusUnauthorized, statusCodeWith(t, baseURL, "", "", "?token="+testAPIKey))
+	assert.Equal(t, http.StatusOK, statusCodeWith(t, baseURL, "Authorization", "Bearer "+testAPIKey, ""))
+}
+
+// namedKeysConfig returns a complete configuration (valid for hot reload) accepting testAPIKey
+// and the given api_keys entries.
+func namedKeysConfig(apiKeys string) string {
+	return "server:\n  api_key: " + testAPIKey + "\n  port: 8443\n  api_keys:\n" + apiKeys +
+		"database:\n  host: localhost\n  port: 5432\n  name: prtg\n  user: reader\n"
+}
+
+func TestAuth_NamedAPIKeys(t *testing.T) {
+	configPath := filepath.Join(t.TempDir(), "config.yaml")
+	keys := "    - name: grafana\n      key: grafana-key\n    - name: n8n\n      key: n8n-key\n"
+	require.NoError(t, os.WriteFile(configPath, []byte(namedKeysConfig(keys)), 0o600))
+
+	baseURL := newTestHTTPServerFromFile(t, configPath)
+
+	for _, key := range []string{testAPIKey, "grafana-key", "n8n-key"} {
+		assert.Equal(t, http.StatusOK, statusCodeWith(t, baseURL, "Authorization", "Bearer "+key, ""), key)
+	}
+	assert.Equal(t, http.StatusUnauthorized, statusCodeWith(t, baseURL, "Authorization", "Bearer grafana", ""))
+
+	// Revoking one key by removing its entry takes effect on reload, the others keep working
+	keys = "    - name: n8n\n      key: n8n-key\n"
+	require.NoError(t, os.WriteFile(configPath, []byte(namedKeysConfig(keys)), 0o600))
+
+	require.Eventually(t, func() bool {
+		return statusCodeWith(t, baseURL, "Authorization", "Bearer grafana-key", "") == http.StatusUnauthorized
+	}, 5*time.Second, 50*time.Millisecond, "revoked key must be rejected after reload")
+
+	assert.Equal(t, http.StatusOK, statusCodeWith(t, baseURL, "Authorization", "Bearer n8n-key", ""))
+	assert.Equal(t, http.StatusOK, statusCodeWith(t, baseURL, "Authorization", "Bearer "+testAPIKey, ""))
+}
+
+func TestMatchAPIKey(t *testing.T) {
+	keys := []configuration.APIKey{
+		{Name: configuration.DefaultAPIKeyName, Key: "main-key"},
+		{Name: "grafana", Key: "grafana-key"},
+	}
+
+	name, ok := matchAPIKey("grafana-key", keys)
+	assert.True(t, ok)
+	assert.Equal(t, "grafana", name)
+
+	name, ok = matchAPIKey("main-key", keys)
+	assert.True(t, ok)
+	assert.Equal(t, configuration.DefaultAPIKeyName, name)
+
+	for _, provided := range []string{"", "grafana-key2", "grafana"} {
+		_, ok = matchAPIKey(provided, keys)
+		assert.False(t, ok, provided)
+	}
+
+	_, ok = matchAPIKey("", []configuration.APIKey{{Name: "empty"}})
+	assert.False(t, ok, "an empty key never matches")
+}
+
+func TestGetClientIP(t *testing.T) {
+	trusted := []netip.Prefix{netip.MustParsePrefix("10.0.0.0/8"), netip.MustParsePrefix("fd00::/8")}
+
+	tests := []struct {
+		name       string
+		remoteAddr string
+		headers    map[string]string
+		want       string
+	}{
+		{"ipv4 peer", "192.0.2.10:51234", nil, "192.0.2.10"},
+		{"ipv6 peer with port", "[2001:db8::1]:51234", nil, "2001:db8::1"},
+		{"bare ipv6 peer", "2001:db8::1", nil, "2001:db8::1"},
+		{"ipv6 peer with zone", "[fe80::1%eth0]:51234", nil, "fe80::1"},
+		{"ipv4-mapped peer", "[::ffff:192.0.2.10]:51234", nil, "192.0.2.10"},
+		{"unparseable peer", "@", nil, "@"},
+		{"spoofed forwarded-for from untrusted peer", "192.0.2.10:51234",
+			map[string]string{"X-Forwarded-For": "203.0.113.7"}, "192.0.2.10"},
+		{"spoofed real-ip from untrusted peer", "192.0.2.10:51234",
+			map[string]string{"X-Real-IP": "203.0.113.7"}, "192.0.2.10"},
+		{"forwarded-for from trusted proxy", "10.0.0.5:443",
+			map[string]string{"X-Forwarded-For": "203.0.113.7"}, "203.0.113.7"},
+		{"forwarded-for skips trusted hops only", "10.0.0.5:443",
+			map[string]string{"X-Forwarded-For": "198.51.100.1, 203.0.113.7, 10.0.0.9"}, "203.0.113.7"},
+		{"forwarded-for ipv6 from trusted ipv6 proxy", "[fd00::5]:443",
+			map[string]string{"X-Forwarded-For": "[2001:db8::7]:1234"}, "2001:db8::7"},
+		{"real-ip from trusted proxy", "10.0.0.5:443",
+			map[string]string{"X-Real-IP": "2001:db8::7", "X-Forwarded-For": "203.0.113.7"}, "2001:db8::7"},
+		{"garbage forwarded-for keeps proxy", "10.0.0.5:443",
+			map[string]string{"X-Forwarded-For": "unknown"}, "10.0.0.5"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/mcp", nil)
+			r.RemoteAddr = tt.remoteAddr
+			for name, value := range tt.headers {
+				r.Header.Set(name, value)
+			}
+
+			assert.Equal(t, tt.want, getClientIP(r, trusted))
+		})
+	}
+}
+
+func TestNewStreamableHTTPServer_HeartbeatInterval(t *testing.T) {
+	for _, tt := range []struct {
+		name       string
+		serverYAML string
+		want       time.Duration
+	}{
+		{"default", "", 30 * time.Second},
+		{"configured", "  heartbeat_interval_seconds: 5\n", 5 * time.Second},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			configPath := filepath.Join(t.TempDir(), "config.yaml")
+			configYAML := "server:\n  api_key: " + testAPIKey + "\n" + tt.serverYAML
+			require.NoError(t, os.WriteFile(configPath, []byte(configYAML), 0o600))
+
+			config, err := configuration.NewConfiguration(&cliargs.ParsedArgs{ConfigPath: configPath}, logger.NewSilentLogger())
+			require.NoError(t, err)
+			t.Cleanup(func() { _ = config.Shutdown(context.Background()) })
+
+			assert.Equal(t, tt.want, config.GetHeartbeatInterval())
+
+			s := NewStreamableHTTPServer(server.NewMCPServer("test", "1.0.0"), nil, config, NewInFlightTracker(), logger.NewSilentLogger())
+			assert.Equal(t, tt.want, s.heartbeat)
+		})
+	}
+}
+
+func TestHandleStatus_DatabaseErrorIsSanitized(t *testing.T) {
+	driverErr := &pq.Error{
+		Code:    "28P01",
+		Message: "password authentication failed for user \"prtg_reader\"\nhost=db.internal password=hunter2",
+	}
+	failing := func(context.Context) (*sql.DB, error) { return nil, driverErr }
+
+	db, _ := database.Connect(context.Background(), failing, database.RetryPolicy{Attempts: 1}, logger.NewSilentLogger())
+
+	s := &StreamableHTTPServer{
+		db:        db,
+		transport: "streamable-http",
+		logger:    logger.NewModuleLogger(logger.NewSilentLogger(), logger.ModuleServer),
+	}
+
+	recorder := httptest.NewRecorder()
+	s.handleStatus(recorder, httptest.NewRequest(http.MethodGet, "/status", nil))
+
+	var status map[string]interface{}
+	require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &status), recorder.Body.String())
+
+	assert.Equal(t, "error", status["database"])
+	assert.Equal(t, database.HealthAuthFailed, status["database_error"])
+	assert.Equal(t, database.StateDisconnected, status["database_state"])
+
+	for _, secret := range []string{"prtg_reader", "db.internal", "hunter2"} {
+		assert.NotContains(t, recorder.Body.String(), secret)
+	}
+}
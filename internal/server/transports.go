@@ -0,0 +1,116 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	server "github.com/mark3labs/mcp-go/server"
+
+	"github.com/matthieu/mcp-server-prtg/internal/database"
+	"github.com/matthieu/mcp-server-prtg/internal/services/configuration"
+	"github.com/matthieu/mcp-server-prtg/internal/services/logger"
+)
+
+// TransportServers serves the configured MCP transports (server.transports), each on its own
+// port. All listeners share the MCP server, the database, the API keys, the authentication
+// rate limiter and the SSE stream limit, so a client cannot multiply its attempts or streams
+// by switching ports.
+type TransportServers struct {
+	servers  []*StreamableHTTPServer
+	inFlight *InFlightTracker
+	logger   *logger.ModuleLogger
+}
+
+// NewTransportServers creates a listener per configured transport. The inFlight tracker must
+// be registered as tool middleware on mcpServer so that Shutdown can drain active tool calls.
+func NewTransportServers(
+	mcpServer *server.MCPServer,
+	db *database.DB,
+	config *configuration.Configuration,
+	inFlight *InFlightTracker,
+	baseLogger *logger.Logger,
+) *TransportServers {
+	transports := config.GetTransports()
+	rateLimiter := newAuthRateLimiter()
+	sseSessions := newSSESessions(config.GetMaxSSEConnections(), config.GetSSEIdleTimeout(),
+		logger.NewModuleLogger(baseLogger, logger.ModuleServer))
+
+	servers := make([]*StreamableHTTPServer, len(transports))
+
+	for i, transport := range transports {
+		s := NewStreamableHTTPServer(mcpServer, db, config, inFlight, baseLogger)
+		s.transport = transport.Type
+		s.address = config.GetListenAddress(transport.Port)
+		s.rateLimiter = rateLimiter
+		s.sseSessions = sseSessions
+
+		servers[i] = s
+	}
+
+	// One ACME manager for all listeners: certificates are obtained once, and only the
+	// first listener serves HTTP-01 challenges
+	if config.IsACMEEnabled() {
+		manager := newACMEManager(config.GetACMEConfig())
+
+		for i, s := range servers {
+			s.acmeManager = manager
+			s.acmeShared = i > 0
+		}
+	}
+
+	return &TransportServers{
+		servers:  servers,
+		inFlight: inFlight,
+		logger:   logger.NewModuleLogger(baseLogger, logger.ModuleServer),
+	}
+}
+
+// Start starts every listener. If one fails to start, those already started are shut down.
+func (t *TransportServers) Start(ctx context.Context) error {
+	for i, s := range t.servers {
+		if err := s.Start(ctx); err != nil {
+			for _, started := range t.servers[:i] {
+				if shutdownErr := started.Shutdown(ctx); shutdownErr != nil {
+					t.logger.Warn().Err(shutdownErr).Str("address", started.address).Msg("Failed to stop listener")
+				}
+			}
+
+			return fmt.Errorf("%s transport on %s: %w", s.transport, s.address, err)
+		}
+	}
+
+	return nil
+}
+
+// Shutdown shuts down every listener within the same grace period. All listeners stop accepting
+// connections first, then the tool calls they share are drained once, and the connections of
+// every listener are closed concurrently.
+func (t *TransportServers) Shutdown(ctx context.Context) error {
+	for _, s := range t.servers {
+		s.stopAccepting()
+	}
+
+	drainToolCalls(ctx, t.inFlight, t.logger)
+
+	errs := make([]error, len(t.servers))
+
+	var wg sync.WaitGroup
+
+	for i, s := range t.servers {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			if err := s.closeConnections(ctx); err != nil {
+				errs[i] = fmt.Errorf("%s transport on %s: %w", s.transport, s.address, err)
+			}
+		}()
+	}
+
+	wg.Wait()
+
+	return errors.Join(errs...)
+}
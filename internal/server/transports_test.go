@@ -0,0 +1,238 @@
+package server
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	server "github.com/mark3labs/mcp-go/server"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/matthieu/mcp-server-prtg/internal/cliargs"
+	"github.com/matthieu/mcp-server-prtg/internal/services/configuration"
+	"github.com/matthieu/mcp-server-prtg/internal/services/logger"
+)
+
+// freePort returns a local TCP port that was free when checked.
+func freePort(t *testing.T) int {
+	t.Helper()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer listener.Close()
+
+	return listener.Addr().(*net.TCPAddr).Port
+}
+
+// canListen reports whether a local TCP port can be bound.
+func canListen(port int) bool {
+	listener, err := net.Listen("tcp", fmt.Sprintf("127.0.0.1:%d", port))
+	if err != nil {
+		return false
+	}
+
+	_ = listener.Close()
+
+	return true
+}
+
+// authenticatedRequest sends a request with the test API key, or without key when apiKey is empty.
+func authenticatedRequest(t *testing.T, method, url, body, apiKey string) *http.Response {
+	t.Helper()
+
+	req, err := http.NewRequest(method, url, strings.NewReader(body))
+	require.NoError(t, err)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json, text/event-stream")
+	if apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+apiKey)
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+
+	return resp
+}
+
+func TestTransportServers_SSEAndStreamableHTTP(t *testing.T) {
+	ssePort, httpPort := freePort(t), freePort(t)
+
+	configPath := filepath.Join(t.TempDir(), "config.yaml")
+	configYAML := fmt.Sprintf(`server:
+  api_key: %s
+  bind_address: 127.0.0.1
+  transports:
+    - type: sse
+      port: %d
+    - type: streamable-http
+      port: %d
+`, testAPIKey, ssePort, httpPort)
+	require.NoError(t, os.WriteFile(configPath, []byte(configYAML), 0o600))
+
+	config, err := configuration.NewConfiguration(&cliargs.ParsedArgs{ConfigPath: configPath}, logger.NewSilentLogger())
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = config.Shutdown(context.Background()) })
+
+	inFlight := NewInFlightTracker()
+	mcpServer := server.NewMCPServer("test", "1.0.0", server.WithToolHandlerMiddleware(inFlight.Middleware))
+
+	transports := NewTransportServers(mcpServer, nil, config, inFlight, logger.NewSilentLogger())
+	require.NoError(t, transports.Start(context.Background()))
+
+	// SSE: the stream opens with the endpoint event naming the message endpoint
+	sseURL := fmt.Sprintf("http://127.0.0.1:%d/sse", ssePort)
+
+	resp := authenticatedRequest(t, http.MethodGet, sseURL, "", "")
+	_ = resp.Body.Close()
+	assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+
+	stream := authenticatedRequest(t, http.MethodGet, sseURL, "", testAPIKey)
+	defer stream.Body.Close()
+	require.Equal(t, http.StatusOK, stream.StatusCode)
+
+	reader := bufio.NewReader(stream.Body)
+	event, err := reader.ReadString('\n')
+	require.NoError(t, err)
+	assert.Equal(t, "event: endpoint\n", event)
+
+	data, err := reader.ReadString('\n')
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(data, "data: /message?sessionId="), "got %q", data)
+
+	// Streamable HTTP on the other port, same API key
+	mcpURL := fmt.Sprintf("http://127.0.0.1:%d/mcp", httpPort)
+	initialize := `{"jsonrpc":"2.0","id":1,"method":"initialize","params":{"protocolVersion":"2025-03-26",` +
+		`"capabilities":{},"clientInfo":{"name":"test","version":"1.0.0"}}}`
+
+	resp = authenticatedRequest(t, http.MethodPost, mcpURL, initialize, "")
+	_ = resp.Body.Close()
+	assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+
+	resp = authenticatedRequest(t, http.MethodPost, mcpURL, initialize, testAPIKey)
+	_ = resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	// Pooled client connections that never sent a request would hold Shutdown for 5 seconds
+	http.DefaultClient.CloseIdleConnections()
+
+	// Shutdown ends the open SSE stream instead of waiting for the grace period
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	require.NoError(t, transports.Shutdown(ctx))
+
+	for _, port := range []int{ssePort, httpPort} {
+		assert.Eventually(t, func() bool { return canListen(port) }, time.Second, 10*time.Millisecond,
+			"port %d must be released", port)
+	}
+}
+
+func TestTransportServers_StartFailureStopsStartedListeners(t *testing.T) {
+	freeListenerPort := freePort(t)
+
+	busy, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer busy.Close()
+
+	busyPort := busy.Addr().(*net.TCPAddr).Port
+
+	configPath := filepath.Join(t.TempDir(), "config.yaml")
+	configYAML := fmt.Sprintf(`server:
+  api_key: %s
+  bind_address: 127.0.0.1
+  transports:
+    - type: streamable-http
+      port: %d
+    - type: sse
+      port: %d
+`, testAPIKey, freeListenerPort, busyPort)
+	require.NoError(t, os.WriteFile(configPath, []byte(configYAML), 0o600))
+
+	config, err := configuration.NewConfiguration(&cliargs.ParsedArgs{ConfigPath: configPath}, logger.NewSilentLogger())
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = config.Shutdown(context.Background()) })
+
+	inFlight := NewInFlightTracker()
+	mcpServer := server.NewMCPServer("test", "1.0.0", server.WithToolHandlerMiddleware(inFlight.Middleware))
+
+	err = NewTransportServers(mcpServer, nil, config, inFlight, logger.NewSilentLogger()).Start(context.Background())
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), fmt.Sprintf("sse transport on 127.0.0.1:%d", busyPort))
+
+	// The first listener was stopped: its port can be bound again
+	assert.Eventually(t, func() bool { return canListen(freeListenerPort) }, time.Second, 10*time.Millisecond)
+}
+
+func TestTransportServers_ShutdownStopsListenersBeforeDraining(t *testing.T) {
+	firstPort, secondPort := freePort(t), freePort(t)
+
+	configPath := filepath.Join(t.TempDir(), "config.yaml")
+	configYAML := fmt.Sprintf(`server:
+  api_key: %s
+  bind_address: 127.0.0.1
+  transports:
+    - type: streamable-http
+      port: %d
+    - type: sse
+      port: %d
+`, testAPIKey, firstPort, secondPort)
+	require.NoError(t, os.WriteFile(configPath, []byte(configYAML), 0o600))
+
+	config, err := configuration.NewConfiguration(&cliargs.ParsedArgs{ConfigPath: configPath}, logger.NewSilentLogger())
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = config.Shutdown(context.Background()) })
+
+	inFlight := NewInFlightTracker()
+	mcpServer := newSlowMCPServer(inFlight, 500*time.Millisecond)
+
+	transports := NewTransportServers(mcpServer, nil, config, inFlight, logger.NewSilentLogger())
+	require.NoError(t, transports.Start(context.Background()))
+
+	responseCh := make(chan mcp.JSONRPCMessage, 1)
+
+	go func() {
+		responseCh <- callTool(mcpServer, "slow_tool")
+	}()
+
+	waitForActive(t, inFlight, 1)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	shutdownErr := make(chan error, 1)
+
+	go func() {
+		shutdownErr <- transports.Shutdown(ctx)
+	}()
+
+	// Both listeners refuse connections while the tool call is still running
+	for _, port := range []int{firstPort, secondPort} {
+		assert.Eventually(t, func() bool { return canListen(port) }, time.Second, 5*time.Millisecond,
+			"port %d must be released before the tool call ends", port)
+	}
+
+	assert.Equal(t, 1, inFlight.Active())
+
+	require.NoError(t, <-shutdownErr)
+
+	select {
+	case response := <-responseCh:
+		result, ok := response.(mcp.JSONRPCResponse)
+		require.True(t, ok, "expected a successful response, got %T", response)
+
+		toolResult, ok := result.Result.(mcp.CallToolResult)
+		require.True(t, ok)
+		assert.False(t, toolResult.IsError)
+	default:
+		t.Fatal("slow tool call did not complete before shutdown returned")
+	}
+}
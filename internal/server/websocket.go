@@ -0,0 +1,299 @@
+package server
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/mark3labs/mcp-go/mcp"
+	server "github.com/mark3labs/mcp-go/server"
+
+	"github.com/matthieu/mcp-server-prtg/internal/services/logger"
+)
+
+const (
+	wsWriteWait      = 10 * time.Second // Time allowed to write a message to the peer
+	wsPongWait       = 60 * time.Second // Time allowed to read the next pong from the peer
+	wsPingPeriod     = 30 * time.Second // Send pings at this interval (must be less than wsPongWait)
+	wsMaxMessageSize = 10 << 20         // 10MB max inbound message size
+)
+
+// webSocketSession is an MCP client session bound to a single WebSocket connection.
+type webSocketSession struct {
+	id            string
+	notifications chan mcp.JSONRPCNotification
+	initialized   atomic.Bool
+}
+
+// newWebSocketSession creates a new session with a random identifier.
+func newWebSocketSession() *webSocketSession {
+	id := make([]byte, 16)
+	_, _ = rand.Read(id)
+
+	return &webSocketSession{
+		id:            "ws-" + hex.EncodeToString(id),
+		notifications: make(chan mcp.JSONRPCNotification, 100),
+	}
+}
+
+// SessionID returns the unique session identifier.
+func (s *webSocketSession) SessionID() string {
+	return s.id
+}
+
+// NotificationChannel returns the channel used to push notifications to the client.
+func (s *webSocketSession) NotificationChannel() chan<- mcp.JSONRPCNotification {
+	return s.notifications
+}
+
+// Initialize marks the session as ready to receive notifications.
+func (s *webSocketSession) Initialize() {
+	s.initialized.Store(true)
+}
+
+// Initialized returns whether the session is ready to receive notifications.
+func (s *webSocketSession) Initialized() bool {
+	return s.initialized.Load()
+}
+
+var _ server.ClientSession = (*webSocketSession)(nil)
+
+// webSocketHandler bridges MCP JSON-RPC messages over WebSocket connections.
+// Authentication and rate limiting are applied by the wrapping middleware before the upgrade.
+type webSocketHandler struct {
+	mcpServer   *server.MCPServer
+	logger      *logger.ModuleLogger
+	upgrader    websocket.Upgrader
+	pingPeriod  time.Duration
+	pongWait    time.Duration
+	writeWait   time.Duration
+	maxInFlight int // Messages handled at the same time per connection
+
+	// Cancelled on server shutdown to close all connections
+	closeCtx    context.Context
+	closeCancel context.CancelFunc
+	mu          sync.Mutex
+	closed      bool
+	wg          sync.WaitGroup
+}
+
+// newWebSocketHandler creates a WebSocket handler serving the given MCP server, handling at
+// most maxInFlight messages of each connection at the same time.
+func newWebSocketHandler(mcpServer *server.MCPServer, moduleLogger *logger.ModuleLogger, maxInFlight int) *webSocketHandler {
+	closeCtx, closeCancel := context.WithCancel(context.Background())
+
+	if maxInFlight <= 0 {
+		maxInFlight = 1
+	}
+
+	return &webSocketHandler{
+		mcpServer: mcpServer,
+		logger:    moduleLogger,
+		upgrader: websocket.Upgrader{
+			ReadBufferSize:  4096,
+			WriteBufferSize: 4096,
+			// MCP clients are not browsers - access is controlled by Bearer token, not Origin
+			CheckOrigin: func(_ *http.Request) bool { return true },
+		},
+		pingPeriod:  wsPingPeriod,
+		pongWait:    wsPongWait,
+		writeWait:   wsWriteWait,
+		maxInFlight: maxInFlight,
+		closeCtx:    closeCtx,
+		closeCancel: closeCancel,
+	}
+}
+
+// ServeHTTP upgrades the connection and serves MCP messages until the peer disconnects.
+func (h *webSocketHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	h.mu.Lock()
+	if h.closed {
+		h.mu.Unlock()
+		http.Error(w, "Server is shutting down", http.StatusServiceUnavailable)
+
+		return
+	}
+
+	h.wg.Add(1)
+	h.mu.Unlock()
+
+	defer h.wg.Done()
+
+	conn, err := h.upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		// Upgrade already replied with an HTTP error
+		h.logger.Warn().Err(err).Str("client_ip", requestClientIP(r)).Msg("WebSocket upgrade failed")
+		return
+	}
+
+	session := newWebSocketSession()
+
+	ctx, cancel := context.WithCancel(h.closeCtx)
+	defer cancel()
+
+	if err := h.mcpServer.RegisterSession(ctx, session); err != nil {
+		h.logger.Error().Err(err).Msg("Failed to register WebSocket session")
+		_ = conn.Close()
+
+		return
+	}
+	defer h.mcpServer.UnregisterSession(ctx, session.SessionID())
+
+	ctx = h.mcpServer.WithContext(contextWithClientInfo(ctx, r), session)
+
+	h.logger.Info().
+		Str("session_id", session.SessionID()).
+		Str("client_ip", requestClientIP(r)).
+		Msg("WebSocket session opened")
+
+	outbound := make(chan mcp.JSONRPCMessage, 16)
+	writerDone := make(chan struct{})
+
+	go func() {
+		defer close(writerDone)
+
+		// A write error ends the session: without a writer nobody drains outbound, and the
+		// handlers and readLoop waiting on it must stop rather than hold their slots forever
+		defer cancel()
+
+		h.writeLoop(ctx, conn, session, outbound)
+	}()
+
+	h.readLoop(ctx, conn, outbound)
+
+	// Reader stopped (peer closed or error) - stop the writer and wait for a clean close
+	cancel()
+	<-writerDone
+
+	h.logger.Info().Str("session_id", session.SessionID()).Msg("WebSocket session closed")
+}
+
+// readLoop reads JSON-RPC messages and dispatches them to the MCP server.
+// Messages are handled concurrently so long-running tool calls don't block the connection, at
+// most maxInFlight at a time: beyond that, reading waits for a message to complete, so a client
+// flooding the socket cannot start unbounded goroutines.
+func (h *webSocketHandler) readLoop(ctx context.Context, conn *websocket.Conn, outbound chan<- mcp.JSONRPCMessage) {
+	slots := make(chan struct{}, h.maxInFlight)
+
+	conn.SetReadLimit(wsMaxMessageSize)
+	_ = conn.SetReadDeadline(time.Now().Add(h.pongWait))
+	conn.SetPongHandler(func(string) error {
+		return conn.SetReadDeadline(time.Now().Add(h.pongWait))
+	})
+
+	for {
+		messageType, data, err := conn.ReadMessage()
+		if err != nil {
+			if websocket.IsUnexpectedCloseError(err, websocket.CloseNormalClosure, websocket.CloseGoingAway) {
+				h.logger.Warn().Err(err).Msg("WebSocket read error")
+			}
+
+			return
+		}
+
+		if messageType != websocket.TextMessage {
+			continue
+		}
+
+		select {
+		case slots <- struct{}{}:
+		case <-ctx.Done():
+			return
+		}
+
+		// The wait was on this server, not the peer: don't count it against the pong deadline
+		_ = conn.SetReadDeadline(time.Now().Add(h.pongWait))
+
+		go func(message json.RawMessage) {
+			defer func() { <-slots }()
+
+			response := h.mcpServer.HandleMessage(ctx, message)
+			if response == nil {
+				return // Notification - no response expected
+			}
+
+			select {
+			case outbound <- response:
+			case <-ctx.Done():
+			}
+		}(data)
+	}
+}
+
+// writeLoop is the single writer of the connection: responses, notifications and keepalive pings.
+// On context cancellation it sends a close frame and closes the connection.
+func (h *webSocketHandler) writeLoop(ctx context.Context, conn *websocket.Conn, session *webSocketSession, outbound <-chan mcp.JSONRPCMessage) {
+	ticker := time.NewTicker(h.pingPeriod)
+	defer ticker.Stop()
+	defer conn.Close()
+
+	for {
+		select {
+		case message := <-outbound:
+			if err := h.writeJSON(conn, message); err != nil {
+				h.logger.Warn().Err(err).Msg("Failed to write WebSocket response")
+				return
+			}
+
+		case notification := <-session.notifications:
+			if err := h.writeJSON(conn, notification); err != nil {
+				h.logger.Warn().Err(err).Msg("Failed to write WebSocket notification")
+				return
+			}
+
+		case <-ticker.C:
+			if err := conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(h.writeWait)); err != nil {
+				h.logger.Debug().Err(err).Msg("WebSocket ping failed")
+				return
+			}
+
+		case <-ctx.Done():
+			closeCode := websocket.CloseNormalClosure
+			if h.closeCtx.Err() != nil {
+				closeCode = websocket.CloseGoingAway // Server shutting down
+			}
+
+			_ = conn.WriteControl(websocket.CloseMessage,
+				websocket.FormatCloseMessage(closeCode, ""),
+				time.Now().Add(h.writeWait))
+
+			return
+		}
+	}
+}
+
+// writeJSON writes a JSON message with a write deadline.
+func (h *webSocketHandler) writeJSON(conn *websocket.Conn, message interface{}) error {
+	if err := conn.SetWriteDeadline(time.Now().Add(h.writeWait)); err != nil {
+		return err
+	}
+
+	return conn.WriteJSON(message)
+}
+
+// Close closes all open WebSocket connections and waits for their sessions to end.
+func (h *webSocketHandler) Close(ctx context.Context) {
+	h.mu.Lock()
+	h.closed = true
+	h.mu.Unlock()
+
+	h.closeCancel()
+
+	done := make(chan struct{})
+
+	go func() {
+		h.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-ctx.Done():
+	}
+}
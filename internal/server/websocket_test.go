@@ -0,0 +1,277 @@
+package server
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/matthieu/mcp-server-prtg/internal/cliargs"
+	"github.com/matthieu/mcp-server-prtg/internal/services/configuration"
+	"github.com/matthieu/mcp-server-prtg/internal/services/logger"
+)
+
+const testAPIKey = "test-api-key"
+
+// newTestWebSocketServer starts a WebSocket transport server behind the real auth middleware.
+// It returns the server and the ws:// URL of the /ws endpoint.
+func newTestWebSocketServer(t *testing.T, pingPeriod time.Duration) (*StreamableHTTPServer, string) {
+	t.Helper()
+
+	configPath := filepath.Join(t.TempDir(), "config.yaml")
+	configYAML := "server:\n  api_key: " + testAPIKey + "\n  transport: websocket\n"
+	require.NoError(t, os.WriteFile(configPath, []byte(configYAML), 0o600))
+
+	config, err := configuration.NewConfiguration(&cliargs.ParsedArgs{ConfigPath: configPath}, logger.NewSilentLogger())
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = config.Shutdown(context.Background()) })
+
+	inFlight := NewInFlightTracker()
+	mcpServer := newSlowMCPServer(inFlight, time.Millisecond)
+
+	s := NewStreamableHTTPServer(mcpServer, nil, config, inFlight, logger.NewSilentLogger())
+	require.Equal(t, configuration.TransportWebSocket, s.transport)
+
+	s.wsHandler = newWebSocketHandler(mcpServer, s.logger, configuration.DefaultMaxConcurrentRequests)
+	s.wsHandler.pingPeriod = pingPeriod
+
+	httpServer := httptest.NewServer(s.newMux())
+	s.httpServer = httpServer.Config
+
+	t.Cleanup(func() {
+		_ = s.Shutdown(context.Background())
+		httpServer.Close()
+	})
+
+	return s, "ws" + strings.TrimPrefix(httpServer.URL, "http") + "/ws"
+}
+
+func TestWebSocket_HandshakeWithValidToken(t *testing.T) {
+	_, url := newTestWebSocketServer(t, wsPingPeriod)
+
+	header := http.Header{}
+	header.Set("Authorization", "Bearer "+testAPIKey)
+
+	conn, resp, err := websocket.DefaultDialer.Dial(url, header)
+	require.NoError(t, err)
+
+	defer conn.Close()
+	defer resp.Body.Close()
+
+	assert.Equal(t, http.StatusSwitchingProtocols, resp.StatusCode)
+
+	// MCP initialize round-trip over the socket
+	require.NoError(t, conn.WriteMessage(websocket.TextMessage, []byte(
+		`{"jsonrpc":"2.0","id":1,"method":"initialize","params":{"protocolVersion":"2025-03-26","capabilities":{},"clientInfo":{"name":"test","version":"1.0.0"}}}`,
+	)))
+
+	_ = conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+
+	var response struct {
+		ID     int                  `json:"id"`
+		Result mcp.InitializeResult `json:"result"`
+	}
+	require.NoError(t, conn.ReadJSON(&response))
+	assert.Equal(t, 1, response.ID)
+	assert.Equal(t, "test", response.Result.ServerInfo.Name)
+}
+
+func TestWebSocket_QueryTokenFallback(t *testing.T) {
+	_, url := newTestWebSocketServer(t, wsPingPeriod)
+
+	conn, resp, err := websocket.DefaultDialer.Dial(url+"?token="+testAPIKey, nil)
+	require.NoError(t, err)
+
+	defer conn.Close()
+	defer resp.Body.Close()
+
+	assert.Equal(t, http.StatusSwitchingProtocols, resp.StatusCode)
+}
+
+func TestWebSocket_HandshakeWithInvalidToken(t *testing.T) {
+	_, url := newTestWebSocketServer(t, wsPingPeriod)
+
+	header := http.Header{}
+	header.Set("Authorization", "Bearer wrong-key")
+
+	conn, resp, err := websocket.DefaultDialer.Dial(url, header)
+	if conn != nil {
+		conn.Close()
+	}
+
+	require.ErrorIs(t, err, websocket.ErrBadHandshake)
+	require.NotNil(t, resp)
+
+	defer resp.Body.Close()
+
+	assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+}
+
+func TestWebSocket_PingKeepalive(t *testing.T) {
+	_, url := newTestWebSocketServer(t, 20*time.Millisecond)
+
+	conn, resp, err := websocket.DefaultDialer.Dial(url+"?token="+testAPIKey, nil)
+	require.NoError(t, err)
+
+	defer conn.Close()
+	defer resp.Body.Close()
+
+	pinged := make(chan struct{}, 1)
+
+	conn.SetPingHandler(func(string) error {
+		select {
+		case pinged <- struct{}{}:
+		default:
+		}
+
+		return nil
+	})
+
+	// Control frames are only processed while reading
+	go func() {
+		for {
+			if _, _, err := conn.ReadMessage(); err != nil {
+				return
+			}
+		}
+	}()
+
+	select {
+	case <-pinged:
+	case <-time.After(2 * time.Second):
+		t.Fatal("server did not send a keepalive ping")
+	}
+}
+
+func TestWebSocket_ClosesOnShutdown(t *testing.T) {
+	s, url := newTestWebSocketServer(t, wsPingPeriod)
+
+	conn, resp, err := websocket.DefaultDialer.Dial(url+"?token="+testAPIKey, nil)
+	require.NoError(t, err)
+
+	defer conn.Close()
+	defer resp.Body.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+
+	s.wsHandler.Close(ctx)
+
+	_ = conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	_, _, err = conn.ReadMessage()
+	assert.True(t, websocket.IsCloseError(err, websocket.CloseGoingAway), "expected going-away close frame, got %v", err)
+}
+
+func TestWebSocket_BoundsInFlightMessages(t *testing.T) {
+	inFlight := NewInFlightTracker()
+	mcpServer := newSlowMCPServer(inFlight, 300*time.Millisecond)
+
+	handler := newWebSocketHandler(mcpServer, logger.NewModuleLogger(logger.NewSilentLogger(), logger.ModuleServer), 2)
+	httpServer := httptest.NewServer(handler)
+
+	t.Cleanup(func() {
+		handler.Close(context.Background())
+		httpServer.Close()
+	})
+
+	conn, resp, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(httpServer.URL, "http"), nil)
+	require.NoError(t, err)
+
+	defer conn.Close()
+	defer resp.Body.Close()
+
+	const calls = 5
+	for i := 1; i <= calls; i++ {
+		require.NoError(t, conn.WriteMessage(websocket.TextMessage, []byte(
+			`{"jsonrpc":"2.0","id":`+strconv.Itoa(i)+`,"method":"tools/call","params":{"name":"slow_tool"}}`,
+		)))
+	}
+
+	// Only 2 messages of the connection run at once, the others wait to be read
+	waitForActive(t, inFlight, 2)
+	time.Sleep(100 * time.Millisecond)
+	assert.Equal(t, 2, inFlight.Active())
+
+	// Queued messages are still served once slots free up
+	_ = conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+
+	for i := 0; i < calls; i++ {
+		var response struct {
+			ID     int                `json:"id"`
+			Result mcp.CallToolResult `json:"result"`
+		}
+		require.NoError(t, conn.ReadJSON(&response))
+		assert.False(t, response.Result.IsError)
+	}
+}
+
+func TestWebSocket_WriteErrorReleasesHandlers(t *testing.T) {
+	inFlight := NewInFlightTracker()
+	mcpServer := newSlowMCPServer(inFlight, time.Millisecond)
+
+	handler := newWebSocketHandler(mcpServer, logger.NewModuleLogger(logger.NewSilentLogger(), logger.ModuleServer), 1)
+	handler.writeWait = 200 * time.Millisecond
+
+	// Small socket buffers, so responses to a client that doesn't read block the writer
+	httpServer := httptest.NewUnstartedServer(handler)
+	httpServer.Config.ConnState = func(conn net.Conn, state http.ConnState) {
+		if tcpConn, ok := conn.(*net.TCPConn); state == http.StateNew && ok {
+			_ = tcpConn.SetWriteBuffer(4096)
+		}
+	}
+	httpServer.Start()
+	t.Cleanup(httpServer.Close)
+
+	dialer := websocket.Dialer{NetDial: func(network, addr string) (net.Conn, error) {
+		conn, err := net.Dial(network, addr)
+		if tcpConn, ok := conn.(*net.TCPConn); ok {
+			_ = tcpConn.SetReadBuffer(4096)
+		}
+
+		return conn, err
+	}}
+
+	conn, resp, err := dialer.Dial("ws"+strings.TrimPrefix(httpServer.URL, "http"), nil)
+	require.NoError(t, err)
+
+	defer conn.Close()
+	defer resp.Body.Close()
+
+	// The client keeps sending calls but never reads: the writer hits its deadline while
+	// responses are queued and every slot is held by a call waiting to queue its response
+	go func() {
+		for i := 1; i <= 2000; i++ {
+			message := `{"jsonrpc":"2.0","id":` + strconv.Itoa(i) + `,"method":"tools/call","params":{"name":"slow_tool"}}`
+			if conn.WriteMessage(websocket.TextMessage, []byte(message)) != nil {
+				return
+			}
+		}
+	}()
+
+	// The session ends on its own, without a server shutdown, and no call stays blocked
+	done := make(chan struct{})
+
+	go func() {
+		handler.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(10 * time.Second):
+		t.Fatal("WebSocket session did not end after the write to the client failed")
+	}
+
+	waitForActive(t, inFlight, 0)
+}
@@ -7,12 +7,18 @@ import (
 	"crypto/x509"
 	"crypto/x509/pkix"
 	"encoding/pem"
+	"errors"
 	"fmt"
+	"maps"
 	"math/big"
 	"net"
+	"net/netip"
 	"os"
 	"path/filepath"
+	"sort"
+	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/fsnotify/fsnotify"
@@ -20,11 +26,43 @@ import (
 
 	"github.com/matthieu/mcp-server-prtg/internal/cliargs"
 	"github.com/matthieu/mcp-server-prtg/internal/services/logger"
+	"github.com/matthieu/mcp-server-prtg/internal/types"
 )
 
 const (
-	CurrentConfigVersion = 1
-	DefaultConfigFile    = "config.yaml"
+	CurrentConfigVersion             = 1
+	DefaultConfigFile                = "config.yaml"
+	DefaultShutdownTimeoutSeconds    = 30
+	DefaultHeartbeatSeconds          = 30
+	DefaultFuzzySearchThreshold      = 0.3
+	DefaultMaxHierarchyNodes         = 5000
+	DefaultAlertsPageSize            = 100
+	DefaultSensorLimit               = 50
+	DefaultSensorMetricsMaxSeries    = 10000
+	DefaultSensorMetricsCacheSeconds = 60
+	DefaultMaxConcurrentRequests     = 25
+	DefaultMaxSSEConnections         = 100
+	DefaultDBConnectAttempts         = 5
+	DefaultDBConnectRetrySeconds     = 2
+	DefaultStatisticsCacheSeconds    = 30
+	DefaultPRTGTimeoutSeconds        = 30
+	DefaultDBApplicationName         = "mcp-server-prtg"
+	DefaultLogSampleBurst            = 20
+	DefaultLogSamplePeriodSeconds    = 10
+	DefaultACMECacheDir              = "certs/acme"
+	DefaultACMEHTTPAddress           = ":80"
+	DefaultAuthHeaderName            = "Authorization"
+	DefaultAuthScheme                = "Bearer"
+)
+
+// AuthSchemeNone configures an auth header that carries the bare API key (e.g. X-API-Key).
+const AuthSchemeNone = "none"
+
+// Supported MCP transports.
+const (
+	TransportStreamableHTTP = "streamable-http"
+	TransportSSE            = "sse"
+	TransportWebSocket      = "websocket"
 )
 
 // Configuration represents the complete server configuration.
@@ -34,8 +72,16 @@ type Configuration struct {
 	watcher    *fsnotify.Watcher
 	args       *cliargs.ParsedArgs
 
-	// Configuration data
-	data ConfigData
+	// Configuration data. dataMu is held while a reload replaces it, and by every getter
+	// so none observes a partial update. Getters don't call each other while holding it.
+	data   ConfigData
+	dataMu sync.RWMutex
+
+	// Contents of database.password_file, re-read when the file changes
+	dbPasswordFromFile string
+
+	// Location of server.display_timezone, loaded with the configuration
+	displayLocation *time.Location
 
 	// Callbacks
 	onChangeCallbacks []func()
@@ -55,15 +101,90 @@ type ConfigData struct {
 
 // ServerConfig holds HTTP server configuration.
 type ServerConfig struct {
-	APIKey             string `yaml:"api_key"`              // API Key (Bearer token)
-	BindAddress        string `yaml:"bind_address"`         // Address to bind to (e.g., 0.0.0.0)
-	Port               int    `yaml:"port"`                 // Port to listen on
-	EnableTLS          bool   `yaml:"enable_tls"`           // Enable HTTPS
-	CertFile           string `yaml:"cert_file"`            // TLS certificate file
-	KeyFile            string `yaml:"key_file"`             // TLS private key file
-	ReadTimeout        int    `yaml:"read_timeout"`         // Read timeout in seconds
-	WriteTimeout       int    `yaml:"write_timeout"`        // Write timeout in seconds
-	AllowCustomQueries bool   `yaml:"allow_custom_queries"` // Allow custom SQL queries - DISABLE in production
+	APIKey                string            `yaml:"api_key"`                     // API Key (Bearer token)
+	APIKeys               []APIKey          `yaml:"api_keys"`                    // Additional named API keys, accepted alongside api_key
+	BindAddress           string            `yaml:"bind_address"`                // Address to bind to (e.g., 0.0.0.0)
+	Port                  int               `yaml:"port"`                        // Port to listen on
+	EnableTLS             bool              `yaml:"enable_tls"`                  // Enable HTTPS
+	RequireTLS            bool              `yaml:"require_tls"`                 // Refuse to start without TLS, unless bound to loopback
+	CertFile              string            `yaml:"cert_file"`                   // TLS certificate file
+	KeyFile               string            `yaml:"key_file"`                    // TLS private key file
+	ReadTimeout           int               `yaml:"read_timeout"`                // Read timeout in seconds
+	WriteTimeout          int               `yaml:"write_timeout"`               // Write timeout in seconds
+	AllowCustomQueries    bool              `yaml:"allow_custom_queries"`        // Allow custom SQL queries - DISABLE in production
+	CustomQueryTables     []string          `yaml:"custom_query_allowed_tables"` // Tables custom queries may read (empty = any)
+	AllowWriteOperations  bool              `yaml:"allow_write_operations"`      // Register PRTG API tools that modify objects (pause/resume)
+	MaintenanceMode       bool              `yaml:"maintenance_mode"`            // Answer every tool call with a maintenance message
+	ShutdownTimeout       int               `yaml:"shutdown_timeout_seconds"`    // Grace period for in-flight requests on shutdown
+	HeartbeatInterval     int               `yaml:"heartbeat_interval_seconds"`  // Streamable HTTP keepalive interval (0 = default)
+	MaxSSEConnections     int               `yaml:"max_sse_connections"`         // Open SSE streams across SSE listeners (0 = default)
+	SSEIdleTimeout        int               `yaml:"sse_idle_timeout_seconds"`    // Close SSE sessions without client message (0 = never)
+	FuzzySearchThreshold  float64           `yaml:"fuzzy_search_threshold"`      // Minimum trigram similarity (0-1) for fuzzy search
+	MaxHierarchyNodes     int               `yaml:"max_hierarchy_nodes"`         // Node budget of prtg_get_hierarchy (0 = default)
+	AlertsPageSize        int               `yaml:"alerts_page_size"`            // Alerts per prtg_get_alerts page (0 = default)
+	DefaultSensorLimit    int               `yaml:"default_sensor_limit"`        // prtg_get_sensors results when no limit is given (0 = default)
+	MaxConcurrentRequests int               `yaml:"max_concurrent_requests"`     // Tool calls executing at the same time (0 = default)
+	IncludeJSONPayload    *bool             `yaml:"include_json_payload"`        // End tool responses with their data as JSON (default: true)
+	Transport             string            `yaml:"transport"`                   // MCP transport: streamable-http (default), sse or websocket
+	Transports            []TransportConfig `yaml:"transports"`                  // Transports served at once, each on its own port (overrides transport and port)
+	BasePath              string            `yaml:"base_path"`                   // Path prefix for all endpoints (e.g. /prtg), empty = none
+	TLS                   TLSConfig         `yaml:"tls"`                         // Additional TLS settings (ACME)
+	Auth                  AuthConfig        `yaml:"auth"`                        // Where clients may send the API key
+	TrustedProxies        []string          `yaml:"trusted_proxies"`             // Reverse proxies (IPs or CIDRs) whose X-Forwarded-For / X-Real-IP are honoured
+
+	SensorMetrics SensorMetricsConfig `yaml:"sensor_metrics"` // Prometheus endpoint exposing sensor statuses
+
+	DisplayTimezone string `yaml:"display_timezone"` // IANA timezone of timestamps in tool responses (empty = UTC)
+}
+
+// SensorMetricsConfig holds settings of the /prtg-metrics endpoint, which exposes sensor statuses
+// and latest channel values as Prometheus gauges for external scraping.
+type SensorMetricsConfig struct {
+	Enabled      bool `yaml:"enabled"`       // Serve /prtg-metrics (authenticated like /mcp)
+	MaxSeries    int  `yaml:"max_series"`    // Series per scrape, sensor statuses first (0 = default)
+	CacheSeconds int  `yaml:"cache_seconds"` // How long a scrape result is reused (0 = default)
+}
+
+// APIKey is a named API key, so that each client can be given, and revoked, its own key.
+type APIKey struct {
+	Name string `yaml:"name"` // Label logged when the key authenticates a request
+	Key  string `yaml:"key"`
+}
+
+// DefaultAPIKeyName is the name logged for the single server.api_key.
+const DefaultAPIKeyName = "default"
+
+// TransportConfig is an MCP transport served on its own port, see ServerConfig.Transports.
+type TransportConfig struct {
+	Type string `yaml:"type"` // streamable-http, sse or websocket
+	Port int    `yaml:"port"` // Port to listen on, with server.bind_address
+}
+
+// AuthConfig holds the accepted sources of the API key.
+type AuthConfig struct {
+	HeaderName      string `yaml:"header_name"`       // Header carrying the key (empty = Authorization)
+	Scheme          string `yaml:"scheme"`            // Prefix before the key in the header (empty = Bearer for Authorization, none otherwise)
+	AllowQueryParam *bool  `yaml:"allow_query_param"` // Accept ?token=<key> (default: true)
+	AllowMTLS       bool   `yaml:"allow_mtls"`        // A verified client certificate authenticates without the API key
+}
+
+// TLSConfig holds additional TLS settings.
+type TLSConfig struct {
+	ACME ACMEConfig `yaml:"acme"` // Obtain certificates automatically (e.g. Let's Encrypt) instead of cert/key files
+
+	// Mutual TLS: client certificates signed by a CA of ClientCAFile are verified
+	ClientCAFile      string `yaml:"client_ca_file"`      // PEM bundle of trusted client CAs (empty = no client certificates)
+	RequireClientCert bool   `yaml:"require_client_cert"` // Refuse the handshake without a valid client certificate
+}
+
+// ACMEConfig holds settings for automatic certificate management via ACME.
+type ACMEConfig struct {
+	Enabled      bool     `yaml:"enabled"`       // Use ACME instead of cert_file/key_file
+	Domains      []string `yaml:"domains"`       // Domain names to obtain certificates for
+	Email        string   `yaml:"email"`         // Contact email for the ACME account (expiry notices)
+	CacheDir     string   `yaml:"cache_dir"`     // Directory where certificates and account keys are stored
+	HTTPAddress  string   `yaml:"http_address"`  // Listen address for the HTTP-01 challenge (empty = :80, "-" = disabled)
+	DirectoryURL string   `yaml:"directory_url"` // ACME directory URL (empty = Let's Encrypt production)
 }
 
 // DatabaseConfig holds database connection settings.
@@ -74,6 +195,44 @@ type DatabaseConfig struct {
 	User     string `yaml:"user"`
 	Password string `yaml:"password"`
 	SSLMode  string `yaml:"sslmode"`
+
+	// PasswordFile holds the password (e.g. a Docker or Kubernetes secret), trimmed;
+	// it takes precedence over Password and PRTG_DB_PASSWORD
+	PasswordFile string `yaml:"password_file"`
+
+	// DSN is a complete connection string (keyword/value or postgres:// URL) used as-is,
+	// bypassing the fields above and Options
+	DSN string `yaml:"dsn"`
+
+	// Options are extra connection parameters appended to the built DSN
+	// (e.g. connect_timeout, statement_timeout, application_name)
+	Options map[string]string `yaml:"options"`
+
+	// Schema holding the exporter tables, set as the session search_path ahead of public
+	// ("" = server default, usually public)
+	Schema string `yaml:"schema"`
+
+	// Startup connection retries: attempts are spaced by an interval doubled after each failure
+	ConnectAttempts      int `yaml:"connect_attempts"`               // Total connection attempts at startup (0 = default)
+	ConnectRetryInterval int `yaml:"connect_retry_interval_seconds"` // Delay before the first retry (0 = default)
+
+	// Server-side statement_timeout for custom, hierarchy and statistics queries (0 = disabled)
+	QueryStatementTimeoutMs int `yaml:"query_statement_timeout_ms"`
+
+	// How long prtg_get_statistics results are reused before re-querying (default: 30, 0 = disabled)
+	StatisticsCacheSeconds *int `yaml:"statistics_cache_seconds"`
+
+	// How long identical prtg_get_sensors queries are reused before re-querying (0 = disabled)
+	SensorQueryCacheSeconds int `yaml:"sensor_query_cache_seconds"`
+
+	// Table of historical channel values (e.g. a TimescaleDB hypertable), read by the
+	// time series tools instead of the PRTG API ("" = PRTG API only)
+	HistoryTable string `yaml:"history_table"`
+
+	// Offset added by the exporter to the object IDs of each PRTG server, keyed by a server
+	// prefix of your choice, for databases that namespace the IDs of several servers. Tools
+	// taking an object ID accept id_prefix to translate an ID copied from that server's UI.
+	IDOffsets map[string]int `yaml:"id_offsets"`
 }
 
 // PRTGConfig holds PRTG API connection settings for accessing historical metrics data.
@@ -81,8 +240,12 @@ type PRTGConfig struct {
 	Enabled   bool   `yaml:"enabled"`    // Enable/disable PRTG API access
 	BaseURL   string `yaml:"base_url"`   // PRTG server base URL (e.g., https://prtg.example.com)
 	APIToken  string `yaml:"api_token"`  // PRTG API v2 token (Bearer authentication)
-	Timeout   int    `yaml:"timeout"`    // HTTP request timeout in seconds
+	Timeout   int    `yaml:"timeout"`    // HTTP request timeout in seconds (0 = default, 30)
 	VerifySSL bool   `yaml:"verify_ssl"` // Verify SSL certificates
+
+	// UIBaseURL is the address of the PRTG web interface, used to build links to sensors
+	// (e.g., https://prtg.example.com). Independent of Enabled, empty = no links.
+	UIBaseURL string `yaml:"ui_base_url"`
 }
 
 // LoggingConfig holds logging settings.
@@ -93,6 +256,29 @@ type LoggingConfig struct {
 	MaxBackups int    `yaml:"max_backups"`
 	MaxAgeDays int    `yaml:"max_age_days"`
 	Compress   bool   `yaml:"compress"`
+
+	// MaskPatterns are extra regular expressions masked in all log output, on top of the
+	// built-in password/token/API key patterns. The last capture group is the masked value.
+	MaskPatterns []string `yaml:"mask_patterns"`
+
+	// Warn/Error sampling: at most SampleBurst events per message and period, the rest are dropped (0 = no sampling)
+	SampleBurst         int `yaml:"sample_burst"`
+	SamplePeriodSeconds int `yaml:"sample_period_seconds"` // 0 = default period when SampleBurst is set
+
+	// Audit log of tool calls, written to a separate rotated file (empty = disabled)
+	AuditFile          string `yaml:"audit_file"`
+	AuditRedactQueries bool   `yaml:"audit_redact_queries"` // Store a SHA-256 of custom SQL queries instead of the text
+}
+
+// AuditLogConfig describes the tool call audit log and its rotation.
+// Rotation reuses the max_size_mb, max_backups, max_age_days and compress logging settings.
+type AuditLogConfig struct {
+	File          string
+	RedactQueries bool
+	MaxSizeMB     int
+	MaxBackups    int
+	MaxAgeDays    int
+	Compress      bool
 }
 
 // NewConfiguration creates a new configuration manager.
@@ -140,6 +326,19 @@ func (c *Configuration) loadConfiguration() error {
 		return fmt.Errorf("failed to parse config file: %w", err)
 	}
 
+	// An unknown transport would otherwise start the server on streamable-http
+	if err := errors.Join(validateTransports(c.data.Server)...); err != nil {
+		return fmt.Errorf("invalid configuration: %w", err)
+	}
+
+	password, err := readPasswordFile(c.data.Database.PasswordFile)
+	if err != nil {
+		return err
+	}
+
+	c.dbPasswordFromFile = password
+	c.displayLocation = c.loadDisplayLocation(c.data.Server.DisplayTimezone)
+
 	c.logger.Info().
 		Str("path", c.configPath).
 		Int("version", c.data.ConfigVersion).
@@ -148,6 +347,46 @@ func (c *Configuration) loadConfiguration() error {
 	return nil
 }
 
+// reloadConfiguration parses the config file into a temporary struct and only applies it
+// if it passes validation, so a broken edit never replaces a working configuration.
+func (c *Configuration) reloadConfiguration() error {
+	data, err := os.ReadFile(c.configPath)
+	if err != nil {
+		return fmt.Errorf("failed to read config file: %w", err)
+	}
+
+	var newData ConfigData
+	if err := yaml.Unmarshal(data, &newData); err != nil {
+		return fmt.Errorf("failed to parse config file: %w", err)
+	}
+
+	if err := ValidateConfiguration(&newData); err != nil {
+		return fmt.Errorf("invalid configuration: %w", err)
+	}
+
+	password, err := readPasswordFile(newData.Database.PasswordFile)
+	if err != nil {
+		return err
+	}
+
+	location := c.loadDisplayLocation(newData.Server.DisplayTimezone)
+
+	c.dataMu.Lock()
+	c.data = newData
+	c.dbPasswordFromFile = password
+	c.displayLocation = location
+	c.dataMu.Unlock()
+
+	c.watchPasswordFile()
+
+	c.logger.Info().
+		Str("path", c.configPath).
+		Int("version", c.data.ConfigVersion).
+		Msg("Configuration reloaded successfully")
+
+	return nil
+}
+
 // createDefaultConfiguration creates a default configuration file.
 func (c *Configuration) createDefaultConfiguration() error {
 	// Generate API key if not provided
@@ -193,15 +432,19 @@ func (c *Configuration) createDefaultConfiguration() error {
 	c.data = ConfigData{
 		ConfigVersion: CurrentConfigVersion,
 		Server: ServerConfig{
-			APIKey:             apiKey,
-			BindAddress:        getOrDefault(c.args.BindAddress, "0.0.0.0"),
-			Port:               getOrDefaultInt(c.args.Port, 8443),
-			EnableTLS:          c.args.EnableHTTPS,
-			CertFile:           getOrDefault(c.args.CertFile, defaultCertFile),
-			KeyFile:            getOrDefault(c.args.KeyFile, defaultKeyFile),
-			ReadTimeout:        0,     // No timeout for SSE connections
-			WriteTimeout:       0,     // No timeout for SSE connections
-			AllowCustomQueries: false, // SECURITY: Disable custom SQL queries by default - enable only in dev/test
+			APIKey:               apiKey,
+			BindAddress:          getOrDefault(c.args.BindAddress, "0.0.0.0"),
+			Port:                 getOrDefaultInt(c.args.Port, 8443),
+			EnableTLS:            c.args.EnableHTTPS,
+			CertFile:             getOrDefault(c.args.CertFile, defaultCertFile),
+			KeyFile:              getOrDefault(c.args.KeyFile, defaultKeyFile),
+			ReadTimeout:          0,     // No timeout for SSE connections
+			WriteTimeout:         0,     // No timeout for SSE connections
+			AllowCustomQueries:   false, // SECURITY: Disable custom SQL queries by default - enable only in dev/test
+			AllowWriteOperations: false, // SECURITY: PRTG write tools are opt-in
+			ShutdownTimeout:      DefaultShutdownTimeoutSeconds,
+			FuzzySearchThreshold: DefaultFuzzySearchThreshold,
+			Transport:            TransportStreamableHTTP,
 		},
 		Database: DatabaseConfig{
 			Host:     getOrDefault(c.args.DBHost, "localhost"),
@@ -210,13 +453,16 @@ func (c *Configuration) createDefaultConfiguration() error {
 			User:     getOrDefault(c.args.DBUser, "prtg_reader"),
 			Password: c.args.DBPassword,
 			SSLMode:  getOrDefault(c.args.DBSSLMode, "disable"),
+
+			ConnectAttempts:      DefaultDBConnectAttempts,
+			ConnectRetryInterval: DefaultDBConnectRetrySeconds,
 		},
 		PRTG: PRTGConfig{
-			Enabled:   false, // Disabled by default - opt-in for PRTG API access
-			BaseURL:   "",    // Example: https://prtg.example.com
-			APIToken:  "",    // PRTG API v2 token
-			Timeout:   30,    // 30 seconds default timeout
-			VerifySSL: true,  // Verify SSL by default for security
+			Enabled:   false,                     // Disabled by default - opt-in for PRTG API access
+			BaseURL:   "",                        // Example: https://prtg.example.com
+			APIToken:  "",                        // PRTG API v2 token
+			Timeout:   DefaultPRTGTimeoutSeconds, // 30 seconds default timeout
+			VerifySSL: true,                      // Verify SSL by default for security
 		},
 		Logging: LoggingConfig{
 			Level:      getOrDefault(c.args.LogLevel, "info"),
@@ -225,6 +471,9 @@ func (c *Configuration) createDefaultConfiguration() error {
 			MaxBackups: 5,
 			MaxAgeDays: 30,
 			Compress:   true,
+
+			SampleBurst:         DefaultLogSampleBurst,
+			SamplePeriodSeconds: DefaultLogSamplePeriodSeconds,
 		},
 	}
 
@@ -418,107 +667,789 @@ func generateUUIDKey() (string, error) {
 
 // GetAPIKey returns the API key (Bearer token).
 func (c *Configuration) GetAPIKey() string {
+	c.dataMu.RLock()
+	defer c.dataMu.RUnlock()
+
 	return c.data.Server.APIKey
 }
 
+// GetAPIKeys returns every accepted API key: server.api_key, named DefaultAPIKeyName,
+// followed by the server.api_keys entries. Keys are re-read on each call, so a key removed
+// from the file stops working as soon as the configuration is reloaded.
+func (c *Configuration) GetAPIKeys() []APIKey {
+	c.dataMu.RLock()
+	defer c.dataMu.RUnlock()
+
+	keys := make([]APIKey, 0, len(c.data.Server.APIKeys)+1)
+
+	if c.data.Server.APIKey != "" {
+		keys = append(keys, APIKey{Name: DefaultAPIKeyName, Key: c.data.Server.APIKey})
+	}
+
+	return append(keys, c.data.Server.APIKeys...)
+}
+
+// GetAuthConfig returns the accepted API key sources with defaults applied:
+// "Authorization: Bearer <key>" and the ?token= query parameter.
+// A scheme of "none" means the header carries the bare key.
+func (c *Configuration) GetAuthConfig() AuthConfig {
+	c.dataMu.RLock()
+	defer c.dataMu.RUnlock()
+
+	auth := c.data.Server.Auth
+
+	if auth.HeaderName == "" {
+		auth.HeaderName = DefaultAuthHeaderName
+	}
+
+	switch {
+	case strings.EqualFold(auth.Scheme, AuthSchemeNone):
+		auth.Scheme = ""
+	case auth.Scheme == "" && strings.EqualFold(auth.HeaderName, DefaultAuthHeaderName):
+		auth.Scheme = DefaultAuthScheme
+	}
+
+	if auth.AllowQueryParam == nil {
+		allow := true
+		auth.AllowQueryParam = &allow
+	}
+
+	return auth
+}
+
+// GetTrustedProxies returns the reverse proxies allowed to set the client IP through
+// X-Forwarded-For / X-Real-IP. Invalid entries are skipped; they are rejected by validation.
+func (c *Configuration) GetTrustedProxies() []netip.Prefix {
+	c.dataMu.RLock()
+	defer c.dataMu.RUnlock()
+
+	prefixes := make([]netip.Prefix, 0, len(c.data.Server.TrustedProxies))
+
+	for _, entry := range c.data.Server.TrustedProxies {
+		if prefix, err := parseTrustedProxy(entry); err == nil {
+			prefixes = append(prefixes, prefix)
+		}
+	}
+
+	return prefixes
+}
+
+// parseTrustedProxy parses a single IP address or CIDR range.
+func parseTrustedProxy(entry string) (netip.Prefix, error) {
+	entry = strings.TrimSpace(entry)
+
+	if strings.Contains(entry, "/") {
+		prefix, err := netip.ParsePrefix(entry)
+		if err != nil {
+			return netip.Prefix{}, err
+		}
+
+		return prefix.Masked(), nil
+	}
+
+	addr, err := netip.ParseAddr(entry)
+	if err != nil {
+		return netip.Prefix{}, err
+	}
+
+	addr = addr.Unmap().WithZone("")
+
+	return netip.PrefixFrom(addr, addr.BitLen()), nil
+}
+
 // GetServerAddress returns the full server address.
 func (c *Configuration) GetServerAddress() string {
-	return fmt.Sprintf("%s:%d", c.data.Server.BindAddress, c.data.Server.Port)
+	c.dataMu.RLock()
+	port := c.data.Server.Port
+	c.dataMu.RUnlock()
+
+	return c.GetListenAddress(port)
+}
+
+// GetListenAddress returns the address to listen on for a port, on server.bind_address.
+func (c *Configuration) GetListenAddress(port int) string {
+	c.dataMu.RLock()
+	defer c.dataMu.RUnlock()
+
+	return fmt.Sprintf("%s:%d", c.data.Server.BindAddress, port)
 }
 
 // GetDatabaseConnectionString returns the PostgreSQL connection string.
+// A configured database.dsn is returned unchanged; otherwise a keyword/value DSN is built
+// from the individual fields plus database.options, with application_name defaulting to
+// DefaultDBApplicationName so our sessions are identifiable in pg_stat_activity.
+// It contains the password: never log it, log host/database/user instead.
 func (c *Configuration) GetDatabaseConnectionString() string {
-	return fmt.Sprintf("host=%s port=%d dbname=%s user=%s password=%s sslmode=%s",
-		c.data.Database.Host,
-		c.data.Database.Port,
-		c.data.Database.Name,
-		c.data.Database.User,
-		c.data.Database.Password,
-		c.data.Database.SSLMode,
-	)
+	c.dataMu.RLock()
+	defer c.dataMu.RUnlock()
+
+	db := c.data.Database
+	if db.DSN != "" {
+		return db.DSN
+	}
+
+	parts := []string{
+		"host=" + quoteDSNValue(db.Host),
+		"port=" + strconv.Itoa(db.Port),
+		"dbname=" + quoteDSNValue(db.Name),
+		"user=" + quoteDSNValue(db.User),
+		"password=" + quoteDSNValue(c.databasePassword()),
+		"sslmode=" + quoteDSNValue(db.SSLMode),
+	}
+
+	options := make(map[string]string, len(db.Options)+2)
+	options["application_name"] = DefaultDBApplicationName
+
+	// public stays in the path for extension functions such as pg_trgm's similarity()
+	if db.Schema != "" {
+		options["search_path"] = db.Schema + ",public"
+	}
+
+	for key, value := range db.Options {
+		options[key] = value
+	}
+
+	// Sorted so the DSN is stable across reloads
+	keys := make([]string, 0, len(options))
+	for key := range options {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		parts = append(parts, key+"="+quoteDSNValue(options[key]))
+	}
+
+	return strings.Join(parts, " ")
+}
+
+// UsesDatabaseDSN reports whether the connection string comes from database.dsn
+// rather than the individual host/port/name/user fields.
+func (c *Configuration) UsesDatabaseDSN() bool {
+	c.dataMu.RLock()
+	defer c.dataMu.RUnlock()
+
+	return c.data.Database.DSN != ""
+}
+
+// quoteDSNValue quotes a libpq keyword/value DSN value when it is empty or contains
+// spaces, quotes or backslashes, escaping quotes and backslashes inside.
+func quoteDSNValue(value string) string {
+	if value != "" && !strings.ContainsAny(value, " \t\n'\\") {
+		return value
+	}
+
+	escaped := strings.NewReplacer(`\`, `\\`, `'`, `\'`).Replace(value)
+
+	return "'" + escaped + "'"
+}
+
+// databasePassword returns the database password: the contents of database.password_file
+// when set, else the --db-password flag / PRTG_DB_PASSWORD environment variable, else database.password.
+func (c *Configuration) databasePassword() string {
+	if c.data.Database.PasswordFile != "" {
+		return c.dbPasswordFromFile
+	}
+
+	if c.args != nil && c.args.DBPassword != "" {
+		return c.args.DBPassword
+	}
+
+	return c.data.Database.Password
+}
+
+// readPasswordFile returns the trimmed contents of a password file, or "" when path is empty.
+func readPasswordFile(path string) (string, error) {
+	if path == "" {
+		return "", nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to read database.password_file: %w", err)
+	}
+
+	return strings.TrimSpace(string(data)), nil
 }
 
 // GetDatabaseHost returns the database host.
 func (c *Configuration) GetDatabaseHost() string {
+	c.dataMu.RLock()
+	defer c.dataMu.RUnlock()
+
 	return c.data.Database.Host
 }
 
 // GetDatabasePort returns the database port.
 func (c *Configuration) GetDatabasePort() int {
+	c.dataMu.RLock()
+	defer c.dataMu.RUnlock()
+
 	return c.data.Database.Port
 }
 
 // GetDatabaseName returns the database name.
 func (c *Configuration) GetDatabaseName() string {
+	c.dataMu.RLock()
+	defer c.dataMu.RUnlock()
+
 	return c.data.Database.Name
 }
 
 // GetDatabaseUser returns the database user.
 func (c *Configuration) GetDatabaseUser() string {
+	c.dataMu.RLock()
+	defer c.dataMu.RUnlock()
+
 	return c.data.Database.User
 }
 
+// GetDatabaseSchema returns the schema of the exporter tables ("" = server default).
+func (c *Configuration) GetDatabaseSchema() string {
+	c.dataMu.RLock()
+	defer c.dataMu.RUnlock()
+
+	return c.data.Database.Schema
+}
+
 // GetDatabaseSSLMode returns the database SSL mode.
 func (c *Configuration) GetDatabaseSSLMode() string {
+	c.dataMu.RLock()
+	defer c.dataMu.RUnlock()
+
 	return c.data.Database.SSLMode
 }
 
+// GetDatabaseConnectAttempts returns the number of database connection attempts made at startup.
+func (c *Configuration) GetDatabaseConnectAttempts() int {
+	c.dataMu.RLock()
+	defer c.dataMu.RUnlock()
+
+	if c.data.Database.ConnectAttempts <= 0 {
+		return DefaultDBConnectAttempts
+	}
+
+	return c.data.Database.ConnectAttempts
+}
+
+// GetDatabaseConnectRetryInterval returns the delay before the first database connection retry.
+func (c *Configuration) GetDatabaseConnectRetryInterval() time.Duration {
+	c.dataMu.RLock()
+	defer c.dataMu.RUnlock()
+
+	if c.data.Database.ConnectRetryInterval <= 0 {
+		return DefaultDBConnectRetrySeconds * time.Second
+	}
+
+	return time.Duration(c.data.Database.ConnectRetryInterval) * time.Second
+}
+
+// GetDatabaseStatementTimeout returns the PostgreSQL statement_timeout applied to custom,
+// hierarchy and statistics queries (0 = disabled).
+func (c *Configuration) GetDatabaseStatementTimeout() time.Duration {
+	c.dataMu.RLock()
+	defer c.dataMu.RUnlock()
+
+	return time.Duration(c.data.Database.QueryStatementTimeoutMs) * time.Millisecond
+}
+
+// StatisticsCacheTTL returns how long server statistics are cached between queries
+// (0 = disabled).
+func (c *Configuration) StatisticsCacheTTL() time.Duration {
+	c.dataMu.RLock()
+	defer c.dataMu.RUnlock()
+
+	if c.data.Database.StatisticsCacheSeconds == nil {
+		return DefaultStatisticsCacheSeconds * time.Second
+	}
+
+	return time.Duration(*c.data.Database.StatisticsCacheSeconds) * time.Second
+}
+
+// SensorQueryCacheTTL returns how long identical prtg_get_sensors queries are served from
+// memory (0 = disabled).
+func (c *Configuration) SensorQueryCacheTTL() time.Duration {
+	c.dataMu.RLock()
+	defer c.dataMu.RUnlock()
+
+	if c.data.Database.SensorQueryCacheSeconds <= 0 {
+		return 0
+	}
+
+	return time.Duration(c.data.Database.SensorQueryCacheSeconds) * time.Second
+}
+
+// HistoryTable returns the table of historical channel values, or "" when history is
+// only available through the PRTG API.
+func (c *Configuration) HistoryTable() string {
+	c.dataMu.RLock()
+	defer c.dataMu.RUnlock()
+
+	return c.data.Database.HistoryTable
+}
+
+// IDOffsets returns the object ID offset of each server prefix (database.id_offsets).
+func (c *Configuration) IDOffsets() types.IDOffsets {
+	c.dataMu.RLock()
+	defer c.dataMu.RUnlock()
+
+	return types.IDOffsets(maps.Clone(c.data.Database.IDOffsets))
+}
+
 // IsTLSEnabled returns whether TLS is enabled.
 func (c *Configuration) IsTLSEnabled() bool {
+	c.dataMu.RLock()
+	defer c.dataMu.RUnlock()
+
 	return c.data.Server.EnableTLS
 }
 
+// CheckRequireTLS returns an error when server.require_tls is set but the server would serve
+// plaintext HTTP on a non-loopback address.
+func (c *Configuration) CheckRequireTLS() error {
+	c.dataMu.RLock()
+	defer c.dataMu.RUnlock()
+
+	return validateRequireTLS(c.data.Server)
+}
+
 // GetTLSCertFile returns the TLS certificate file path.
 func (c *Configuration) GetTLSCertFile() string {
+	c.dataMu.RLock()
+	defer c.dataMu.RUnlock()
+
 	return c.data.Server.CertFile
 }
 
 // GetTLSKeyFile returns the TLS private key file path.
 func (c *Configuration) GetTLSKeyFile() string {
+	c.dataMu.RLock()
+	defer c.dataMu.RUnlock()
+
 	return c.data.Server.KeyFile
 }
 
+// GetTLSClientCAFile returns the PEM bundle of CAs trusted to sign client certificates,
+// or "" when mutual TLS is disabled.
+func (c *Configuration) GetTLSClientCAFile() string {
+	c.dataMu.RLock()
+	defer c.dataMu.RUnlock()
+
+	return c.data.Server.TLS.ClientCAFile
+}
+
+// IsTLSClientCertRequired reports whether TLS handshakes without a valid client certificate are refused.
+func (c *Configuration) IsTLSClientCertRequired() bool {
+	c.dataMu.RLock()
+	defer c.dataMu.RUnlock()
+
+	return c.data.Server.TLS.RequireClientCert
+}
+
 // GetReadTimeout returns the server read timeout.
 func (c *Configuration) GetReadTimeout() time.Duration {
+	c.dataMu.RLock()
+	defer c.dataMu.RUnlock()
+
 	return time.Duration(c.data.Server.ReadTimeout) * time.Second
 }
 
 // GetWriteTimeout returns the server write timeout.
 func (c *Configuration) GetWriteTimeout() time.Duration {
+	c.dataMu.RLock()
+	defer c.dataMu.RUnlock()
+
 	return time.Duration(c.data.Server.WriteTimeout) * time.Second
 }
 
+// IsACMEEnabled returns whether TLS certificates are obtained automatically via ACME.
+func (c *Configuration) IsACMEEnabled() bool {
+	c.dataMu.RLock()
+	defer c.dataMu.RUnlock()
+
+	return c.data.Server.EnableTLS && c.data.Server.TLS.ACME.Enabled
+}
+
+// GetACMEConfig returns the ACME settings with defaults applied.
+func (c *Configuration) GetACMEConfig() ACMEConfig {
+	c.dataMu.RLock()
+	defer c.dataMu.RUnlock()
+
+	acme := c.data.Server.TLS.ACME
+
+	if acme.CacheDir == "" {
+		acme.CacheDir = DefaultACMECacheDir
+	}
+
+	if acme.HTTPAddress == "" {
+		acme.HTTPAddress = DefaultACMEHTTPAddress
+	}
+
+	return acme
+}
+
+// DisplayLocation returns the timezone in which tool responses render timestamps (UTC by default).
+func (c *Configuration) DisplayLocation() *time.Location {
+	c.dataMu.RLock()
+	defer c.dataMu.RUnlock()
+
+	if c.displayLocation == nil {
+		return time.UTC
+	}
+
+	return c.displayLocation
+}
+
+// loadDisplayLocation loads the IANA timezone of server.display_timezone. An unknown timezone
+// falls back to UTC with a warning rather than failing the configuration, as timestamps are
+// only rendered in it.
+func (c *Configuration) loadDisplayLocation(name string) *time.Location {
+	if name == "" {
+		return time.UTC
+	}
+
+	location, err := time.LoadLocation(name)
+	if err != nil {
+		c.logger.Warn().
+			Err(err).
+			Str("display_timezone", name).
+			Msg("Unknown server.display_timezone, timestamps are rendered in UTC")
+
+		return time.UTC
+	}
+
+	return location
+}
+
+// GetSensorMetricsConfig returns the /prtg-metrics settings with defaults applied.
+func (c *Configuration) GetSensorMetricsConfig() SensorMetricsConfig {
+	c.dataMu.RLock()
+	defer c.dataMu.RUnlock()
+
+	metrics := c.data.Server.SensorMetrics
+
+	metrics.MaxSeries = getOrDefaultInt(metrics.MaxSeries, DefaultSensorMetricsMaxSeries)
+	metrics.CacheSeconds = getOrDefaultInt(metrics.CacheSeconds, DefaultSensorMetricsCacheSeconds)
+
+	return metrics
+}
+
+// GetTransport returns the MCP transport served by the HTTP server.
+// Defaults to streamable-http when not configured.
+func (c *Configuration) GetTransport() string {
+	c.dataMu.RLock()
+	defer c.dataMu.RUnlock()
+
+	return normalizeTransport(c.data.Server.Transport)
+}
+
+// GetTransports returns the transports to serve, each on its own port. Without server.transports,
+// this is the single server.transport on server.port.
+func (c *Configuration) GetTransports() []TransportConfig {
+	c.dataMu.RLock()
+	defer c.dataMu.RUnlock()
+
+	if len(c.data.Server.Transports) == 0 {
+		return []TransportConfig{{Type: normalizeTransport(c.data.Server.Transport), Port: c.data.Server.Port}}
+	}
+
+	transports := make([]TransportConfig, len(c.data.Server.Transports))
+	for i, transport := range c.data.Server.Transports {
+		transports[i] = TransportConfig{Type: normalizeTransport(transport.Type), Port: transport.Port}
+	}
+
+	return transports
+}
+
+// normalizeTransport returns the transport named by a configured value, streamable-http
+// when it is empty or unknown.
+func normalizeTransport(transport string) string {
+	switch strings.ToLower(transport) {
+	case TransportSSE:
+		return TransportSSE
+	case TransportWebSocket:
+		return TransportWebSocket
+	default:
+		return TransportStreamableHTTP
+	}
+}
+
+// GetBasePath returns the path prefix of all HTTP endpoints, normalized to a leading slash
+// and no trailing slash (e.g. "/prtg"). Returns an empty string when no prefix is configured.
+func (c *Configuration) GetBasePath() string {
+	c.dataMu.RLock()
+	defer c.dataMu.RUnlock()
+
+	return normalizeBasePath(c.data.Server.BasePath)
+}
+
+// normalizeBasePath turns "prtg", "/prtg/" and "/prtg" into "/prtg", and "" or "/" into "".
+func normalizeBasePath(basePath string) string {
+	trimmed := strings.Trim(strings.TrimSpace(basePath), "/")
+	if trimmed == "" {
+		return ""
+	}
+
+	return "/" + trimmed
+}
+
+// GetHeartbeatInterval returns the interval between keepalive messages on Streamable HTTP streams.
+// Defaults to 30 seconds when not configured.
+func (c *Configuration) GetHeartbeatInterval() time.Duration {
+	c.dataMu.RLock()
+	defer c.dataMu.RUnlock()
+
+	if c.data.Server.HeartbeatInterval <= 0 {
+		return DefaultHeartbeatSeconds * time.Second
+	}
+
+	return time.Duration(c.data.Server.HeartbeatInterval) * time.Second
+}
+
+// GetShutdownTimeout returns the grace period given to in-flight requests on shutdown.
+// Defaults to 30 seconds when not configured.
+func (c *Configuration) GetShutdownTimeout() time.Duration {
+	c.dataMu.RLock()
+	defer c.dataMu.RUnlock()
+
+	if c.data.Server.ShutdownTimeout <= 0 {
+		return DefaultShutdownTimeoutSeconds * time.Second
+	}
+
+	return time.Duration(c.data.Server.ShutdownTimeout) * time.Second
+}
+
+// FuzzySearchThreshold returns the minimum trigram similarity (0-1) used by fuzzy search.
+// Defaults to 0.3 (pg_trgm default) when not configured or out of range.
+func (c *Configuration) FuzzySearchThreshold() float64 {
+	c.dataMu.RLock()
+	defer c.dataMu.RUnlock()
+
+	threshold := c.data.Server.FuzzySearchThreshold
+	if threshold <= 0 || threshold > 1 {
+		return DefaultFuzzySearchThreshold
+	}
+
+	return threshold
+}
+
+// AlertsPageSize returns the number of alerts returned per prtg_get_alerts call.
+func (c *Configuration) AlertsPageSize() int {
+	c.dataMu.RLock()
+	defer c.dataMu.RUnlock()
+
+	if c.data.Server.AlertsPageSize <= 0 {
+		return DefaultAlertsPageSize
+	}
+
+	return c.data.Server.AlertsPageSize
+}
+
+// DefaultSensorLimit returns the number of sensors prtg_get_sensors returns when the call has no limit.
+func (c *Configuration) DefaultSensorLimit() int {
+	c.dataMu.RLock()
+	defer c.dataMu.RUnlock()
+
+	if c.data.Server.DefaultSensorLimit <= 0 {
+		return DefaultSensorLimit
+	}
+
+	return c.data.Server.DefaultSensorLimit
+}
+
+// IncludeJSONPayload reports whether tool responses end with their complete data as a JSON
+// block by default. Tools can override it with their include_json parameter.
+func (c *Configuration) IncludeJSONPayload() bool {
+	c.dataMu.RLock()
+	defer c.dataMu.RUnlock()
+
+	if c.data.Server.IncludeJSONPayload == nil {
+		return true
+	}
+
+	return *c.data.Server.IncludeJSONPayload
+}
+
+// MaxHierarchyNodes returns the maximum number of groups, devices and sensors returned
+// by one prtg_get_hierarchy call.
+func (c *Configuration) MaxHierarchyNodes() int {
+	c.dataMu.RLock()
+	defer c.dataMu.RUnlock()
+
+	if c.data.Server.MaxHierarchyNodes <= 0 {
+		return DefaultMaxHierarchyNodes
+	}
+
+	return c.data.Server.MaxHierarchyNodes
+}
+
+// GetMaxConcurrentRequests returns the maximum number of tool calls executing at the same time.
+// Defaults to 25, half of the database connection pool, as some tools run several queries.
+func (c *Configuration) GetMaxConcurrentRequests() int {
+	c.dataMu.RLock()
+	defer c.dataMu.RUnlock()
+
+	if c.data.Server.MaxConcurrentRequests <= 0 {
+		return DefaultMaxConcurrentRequests
+	}
+
+	return c.data.Server.MaxConcurrentRequests
+}
+
+// GetMaxSSEConnections returns the maximum number of SSE streams open at the same time,
+// across all SSE listeners.
+func (c *Configuration) GetMaxSSEConnections() int {
+	c.dataMu.RLock()
+	defer c.dataMu.RUnlock()
+
+	if c.data.Server.MaxSSEConnections <= 0 {
+		return DefaultMaxSSEConnections
+	}
+
+	return c.data.Server.MaxSSEConnections
+}
+
+// GetSSEIdleTimeout returns how long an SSE session may go without client message before its
+// stream is closed (0 = never).
+func (c *Configuration) GetSSEIdleTimeout() time.Duration {
+	c.dataMu.RLock()
+	defer c.dataMu.RUnlock()
+
+	if c.data.Server.SSEIdleTimeout <= 0 {
+		return 0
+	}
+
+	return time.Duration(c.data.Server.SSEIdleTimeout) * time.Second
+}
+
 // AllowCustomQueries returns whether custom SQL queries are allowed.
 // SECURITY: This should be false in production environments to prevent SQL injection risks.
 func (c *Configuration) AllowCustomQueries() bool {
+	c.dataMu.RLock()
+	defer c.dataMu.RUnlock()
+
 	return c.data.Server.AllowCustomQueries
 }
 
+// CustomQueryAllowedTables returns the tables custom SQL queries may reference, optionally
+// schema-qualified. An empty list allows every table the database user can read.
+func (c *Configuration) CustomQueryAllowedTables() []string {
+	c.dataMu.RLock()
+	defer c.dataMu.RUnlock()
+
+	return c.data.Server.CustomQueryTables
+}
+
+// AllowWriteOperations returns whether tools that modify PRTG objects are registered.
+// SECURITY: The PRTG API token must also have write access for these tools to work.
+func (c *Configuration) AllowWriteOperations() bool {
+	c.dataMu.RLock()
+	defer c.dataMu.RUnlock()
+
+	return c.data.Server.AllowWriteOperations
+}
+
+// MaintenanceMode returns whether tool calls are answered with a maintenance message instead of
+// being run. Read on every tool call, so it can be toggled by editing the configuration file.
+func (c *Configuration) MaintenanceMode() bool {
+	c.dataMu.RLock()
+	defer c.dataMu.RUnlock()
+
+	return c.data.Server.MaintenanceMode
+}
+
 // IsPRTGEnabled returns whether PRTG API access is enabled.
 func (c *Configuration) IsPRTGEnabled() bool {
+	c.dataMu.RLock()
+	defer c.dataMu.RUnlock()
+
 	return c.data.PRTG.Enabled
 }
 
 // GetPRTGBaseURL returns the PRTG server base URL.
 func (c *Configuration) GetPRTGBaseURL() string {
+	c.dataMu.RLock()
+	defer c.dataMu.RUnlock()
+
 	return c.data.PRTG.BaseURL
 }
 
 // GetPRTGAPIToken returns the PRTG API token.
 func (c *Configuration) GetPRTGAPIToken() string {
+	c.dataMu.RLock()
+	defer c.dataMu.RUnlock()
+
 	return c.data.PRTG.APIToken
 }
 
 // GetPRTGTimeout returns the PRTG API timeout duration.
+// An unset timeout falls back to the default rather than disabling it.
 func (c *Configuration) GetPRTGTimeout() time.Duration {
+	c.dataMu.RLock()
+	defer c.dataMu.RUnlock()
+
+	if c.data.PRTG.Timeout <= 0 {
+		return DefaultPRTGTimeoutSeconds * time.Second
+	}
+
 	return time.Duration(c.data.PRTG.Timeout) * time.Second
 }
 
+// PRTGUIBaseURL returns the PRTG web interface address without trailing slash, or "" when not set.
+func (c *Configuration) PRTGUIBaseURL() string {
+	c.dataMu.RLock()
+	defer c.dataMu.RUnlock()
+
+	return strings.TrimRight(c.data.PRTG.UIBaseURL, "/")
+}
+
 // IsPRTGSSLVerifyEnabled returns whether SSL certificate verification is enabled for PRTG API.
 func (c *Configuration) IsPRTGSSLVerifyEnabled() bool {
+	c.dataMu.RLock()
+	defer c.dataMu.RUnlock()
+
 	return c.data.PRTG.VerifySSL
 }
 
+// GetLogMaskPatterns returns the extra log masking patterns.
+func (c *Configuration) GetLogMaskPatterns() []string {
+	c.dataMu.RLock()
+	defer c.dataMu.RUnlock()
+
+	return c.data.Logging.MaskPatterns
+}
+
+// GetAuditLogConfig returns the tool call audit log settings. An empty File disables the audit log.
+// Rotation limits fall back to 10 MB, 5 backups and 30 days when unset.
+func (c *Configuration) GetAuditLogConfig() AuditLogConfig {
+	c.dataMu.RLock()
+	defer c.dataMu.RUnlock()
+
+	logging := c.data.Logging
+
+	return AuditLogConfig{
+		File:          logging.AuditFile,
+		RedactQueries: logging.AuditRedactQueries,
+		MaxSizeMB:     getOrDefaultInt(logging.MaxSizeMB, 10),
+		MaxBackups:    getOrDefaultInt(logging.MaxBackups, 5),
+		MaxAgeDays:    getOrDefaultInt(logging.MaxAgeDays, 30),
+		Compress:      logging.Compress,
+	}
+}
+
+// GetLogSampling returns the Warn/Error log sampling burst and period (a burst of 0 disables sampling).
+func (c *Configuration) GetLogSampling() (int, time.Duration) {
+	c.dataMu.RLock()
+	defer c.dataMu.RUnlock()
+
+	period := c.data.Logging.SamplePeriodSeconds
+	if period <= 0 {
+		period = DefaultLogSamplePeriodSeconds
+	}
+
+	return c.data.Logging.SampleBurst, time.Duration(period) * time.Second
+}
+
 // Helper functions.
 
 func getOrDefault(value, defaultValue string) string {
@@ -554,6 +1485,7 @@ func (c *Configuration) initWatcher() error {
 
 	c.watcher = watcher
 	c.shutdownCh = make(chan struct{})
+	c.watchPasswordFile()
 
 	// Start watching in background
 	go c.watchConfigFile()
@@ -577,11 +1509,16 @@ func (c *Configuration) watchConfigFile() {
 				return
 			}
 
+			if c.isPasswordFileEvent(event) {
+				c.reloadPasswordFile()
+				continue
+			}
+
 			if event.Op&fsnotify.Write == fsnotify.Write {
 				c.logger.Info().Str("path", event.Name).Msg("Configuration file changed, reloading")
 
-				if err := c.loadConfiguration(); err != nil {
-					c.logger.Error().Err(err).Msg("Failed to reload configuration")
+				if err := c.reloadConfiguration(); err != nil {
+					c.logger.Error().Err(err).Msg("Failed to reload configuration, keeping previous configuration")
 					continue
 				}
 
@@ -601,6 +1538,69 @@ func (c *Configuration) watchConfigFile() {
 	}
 }
 
+// watchPasswordFile adds the directory of database.password_file to the watcher.
+// The directory is watched rather than the file because Kubernetes rotates secrets by
+// swapping a symlink, which replaces the file instead of writing to it.
+func (c *Configuration) watchPasswordFile() {
+	if c.watcher == nil || c.data.Database.PasswordFile == "" {
+		return
+	}
+
+	dir := filepath.Dir(c.data.Database.PasswordFile)
+	if err := c.watcher.Add(dir); err != nil {
+		c.logger.Warn().Err(err).Str("path", dir).Msg("Failed to watch database password file, rotation requires a restart")
+	}
+}
+
+// isPasswordFileEvent reports whether event concerns the directory of database.password_file
+// (and not the configuration file itself).
+func (c *Configuration) isPasswordFileEvent(event fsnotify.Event) bool {
+	if c.data.Database.PasswordFile == "" || filepath.Clean(event.Name) == filepath.Clean(c.configPath) {
+		return false
+	}
+
+	return filepath.Dir(filepath.Clean(event.Name)) == filepath.Dir(filepath.Clean(c.data.Database.PasswordFile))
+}
+
+// reloadPasswordFile re-reads database.password_file and notifies the callbacks when the
+// password changed, so the database reconnects with it.
+func (c *Configuration) reloadPasswordFile() {
+	password, err := readPasswordFile(c.data.Database.PasswordFile)
+	if err != nil {
+		c.logger.Error().Err(err).Msg("Failed to reload database password file, keeping previous password")
+		return
+	}
+
+	// An empty file is usually caught mid-write: wait for the next event
+	if password == "" {
+		return
+	}
+
+	// Only the watcher goroutine writes the configuration, but getters read it concurrently
+	c.dataMu.Lock()
+	changed := password != c.dbPasswordFromFile
+	c.dbPasswordFromFile = password
+	c.dataMu.Unlock()
+
+	if !changed {
+		return
+	}
+
+	c.logger.Info().Str("path", c.data.Database.PasswordFile).Msg("Database password file changed")
+
+	for _, callback := range c.onChangeCallbacks {
+		callback()
+	}
+}
+
+// Validate checks the loaded configuration with ValidateConfiguration.
+func (c *Configuration) Validate() error {
+	c.dataMu.RLock()
+	defer c.dataMu.RUnlock()
+
+	return ValidateConfiguration(&c.data)
+}
+
 // OnConfigChanged registers a callback for configuration changes.
 func (c *Configuration) OnConfigChanged(callback func()) {
 	c.onChangeCallbacks = append(c.onChangeCallbacks, callback)
@@ -0,0 +1,428 @@
+package configuration
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"net/netip"
+	"net/url"
+	"slices"
+	"strings"
+
+	"github.com/matthieu/mcp-server-prtg/internal/services/logger"
+)
+
+// ValidateConfiguration checks a parsed configuration for values that would break a running server.
+// All problems are reported together so a single reload attempt shows everything to fix.
+func ValidateConfiguration(data *ConfigData) error {
+	var errs []error
+
+	// Server
+	if data.Server.APIKey == "" && len(data.Server.APIKeys) == 0 {
+		errs = append(errs, errors.New("server.api_key or server.api_keys is required"))
+	}
+
+	errs = append(errs, validateAPIKeys(data.Server)...)
+
+	if !isValidPort(data.Server.Port) {
+		errs = append(errs, fmt.Errorf("server.port must be between 1 and 65535, got %d", data.Server.Port))
+	}
+
+	acme := data.Server.TLS.ACME
+	if data.Server.EnableTLS && !acme.Enabled && (data.Server.CertFile == "" || data.Server.KeyFile == "") {
+		errs = append(errs, errors.New("server.cert_file and server.key_file are required when enable_tls is true"))
+	}
+
+	if acme.Enabled {
+		if !data.Server.EnableTLS {
+			errs = append(errs, errors.New("server.enable_tls must be true when server.tls.acme.enabled is true"))
+		}
+
+		if len(acme.Domains) == 0 {
+			errs = append(errs, errors.New("server.tls.acme.domains is required when server.tls.acme.enabled is true"))
+		}
+	}
+
+	if err := validateRequireTLS(data.Server); err != nil {
+		errs = append(errs, err)
+	}
+
+	clientCA := data.Server.TLS.ClientCAFile
+	if clientCA != "" && !data.Server.EnableTLS {
+		errs = append(errs, errors.New("server.enable_tls must be true when server.tls.client_ca_file is set"))
+	}
+
+	if data.Server.TLS.RequireClientCert && clientCA == "" {
+		errs = append(errs, errors.New("server.tls.client_ca_file is required when server.tls.require_client_cert is true"))
+	}
+
+	if data.Server.Auth.AllowMTLS && clientCA == "" {
+		errs = append(errs, errors.New("server.tls.client_ca_file is required when server.auth.allow_mtls is true"))
+	}
+
+	if data.Server.HeartbeatInterval < 0 {
+		errs = append(errs, fmt.Errorf("server.heartbeat_interval_seconds must be positive, got %d", data.Server.HeartbeatInterval))
+	}
+
+	if data.Server.ShutdownTimeout < 0 {
+		errs = append(errs, fmt.Errorf("server.shutdown_timeout_seconds must not be negative, got %d", data.Server.ShutdownTimeout))
+	}
+
+	for i, table := range data.Server.CustomQueryTables {
+		if !isValidTableName(table) {
+			errs = append(errs, fmt.Errorf("server.custom_query_allowed_tables[%d] must be a table name, optionally schema-qualified (schema.table), got %q", i, table))
+		}
+	}
+
+	if data.Server.AlertsPageSize < 0 {
+		errs = append(errs, fmt.Errorf("server.alerts_page_size must not be negative, got %d", data.Server.AlertsPageSize))
+	}
+
+	if data.Server.DefaultSensorLimit < 0 {
+		errs = append(errs, fmt.Errorf("server.default_sensor_limit must not be negative, got %d", data.Server.DefaultSensorLimit))
+	}
+
+	if data.Server.SensorMetrics.MaxSeries < 0 {
+		errs = append(errs, fmt.Errorf("server.sensor_metrics.max_series must not be negative, got %d", data.Server.SensorMetrics.MaxSeries))
+	}
+
+	if data.Server.SensorMetrics.CacheSeconds < 0 {
+		errs = append(errs, fmt.Errorf("server.sensor_metrics.cache_seconds must not be negative, got %d", data.Server.SensorMetrics.CacheSeconds))
+	}
+
+	if data.Server.MaxConcurrentRequests < 0 {
+		errs = append(errs, fmt.Errorf("server.max_concurrent_requests must not be negative, got %d", data.Server.MaxConcurrentRequests))
+	}
+
+	if data.Server.MaxSSEConnections < 0 {
+		errs = append(errs, fmt.Errorf("server.max_sse_connections must not be negative, got %d", data.Server.MaxSSEConnections))
+	}
+
+	if data.Server.SSEIdleTimeout < 0 {
+		errs = append(errs, fmt.Errorf("server.sse_idle_timeout_seconds must not be negative, got %d", data.Server.SSEIdleTimeout))
+	}
+
+	if data.Server.MaxHierarchyNodes < 0 {
+		errs = append(errs, fmt.Errorf("server.max_hierarchy_nodes must not be negative, got %d", data.Server.MaxHierarchyNodes))
+	}
+
+	if data.Server.FuzzySearchThreshold < 0 || data.Server.FuzzySearchThreshold > 1 {
+		errs = append(errs, fmt.Errorf("server.fuzzy_search_threshold must be between 0 and 1, got %g", data.Server.FuzzySearchThreshold))
+	}
+
+	errs = append(errs, validateTransports(data.Server)...)
+
+	if strings.ContainsAny(data.Server.BasePath, "?#% \t") {
+		errs = append(errs, fmt.Errorf("server.base_path must be a plain URL path like /prtg, got %q", data.Server.BasePath))
+	}
+
+	if strings.ContainsAny(data.Server.Auth.HeaderName, ": \t") {
+		errs = append(errs, fmt.Errorf("server.auth.header_name must be a plain header name like X-API-Key, got %q", data.Server.Auth.HeaderName))
+	}
+
+	if strings.ContainsAny(data.Server.Auth.Scheme, " \t") {
+		errs = append(errs, fmt.Errorf("server.auth.scheme must be a single word like Bearer, got %q", data.Server.Auth.Scheme))
+	}
+
+	for _, entry := range data.Server.TrustedProxies {
+		if _, err := parseTrustedProxy(entry); err != nil {
+			errs = append(errs, fmt.Errorf("server.trusted_proxies must contain IP addresses or CIDR ranges, got %q", entry))
+		}
+	}
+
+	// Database: a full DSN replaces the individual connection fields
+	if data.Database.DSN != "" {
+		errs = append(errs, validateDSN(data.Database)...)
+	} else {
+		if data.Database.Host == "" {
+			errs = append(errs, errors.New("database.host is required"))
+		}
+
+		if !isValidPort(data.Database.Port) {
+			errs = append(errs, fmt.Errorf("database.port must be between 1 and 65535, got %d", data.Database.Port))
+		}
+
+		if data.Database.Name == "" {
+			errs = append(errs, errors.New("database.name is required"))
+		}
+
+		if data.Database.User == "" {
+			errs = append(errs, errors.New("database.user is required"))
+		}
+
+		for key := range data.Database.Options {
+			if !isValidDSNOptionKey(key) {
+				errs = append(errs, fmt.Errorf("database.options: invalid parameter name %q", key))
+			} else if reservedDSNKeys[key] {
+				errs = append(errs, fmt.Errorf("database.options: %q must be set with its own database field", key))
+			}
+		}
+
+		if _, ok := data.Database.Options["search_path"]; ok && data.Database.Schema != "" {
+			errs = append(errs, errors.New("database.options: \"search_path\" cannot be combined with database.schema"))
+		}
+	}
+
+	if schema := data.Database.Schema; schema != "" && (strings.Contains(schema, ".") || !isValidTableName(schema)) {
+		errs = append(errs, fmt.Errorf("database.schema must be a schema name (letters, digits and underscores), got %q", schema))
+	}
+
+	if data.Database.ConnectAttempts < 0 {
+		errs = append(errs, fmt.Errorf("database.connect_attempts must not be negative, got %d", data.Database.ConnectAttempts))
+	}
+
+	if data.Database.ConnectRetryInterval < 0 {
+		errs = append(errs, fmt.Errorf("database.connect_retry_interval_seconds must not be negative, got %d", data.Database.ConnectRetryInterval))
+	}
+
+	if data.Database.QueryStatementTimeoutMs < 0 {
+		errs = append(errs, fmt.Errorf("database.query_statement_timeout_ms must not be negative, got %d", data.Database.QueryStatementTimeoutMs))
+	}
+
+	if data.Database.HistoryTable != "" && !isValidTableName(data.Database.HistoryTable) {
+		errs = append(errs, fmt.Errorf("database.history_table must be a table name, optionally schema-qualified (schema.table), got %q", data.Database.HistoryTable))
+	}
+
+	for _, prefix := range slices.Sorted(maps.Keys(data.Database.IDOffsets)) {
+		if strings.TrimSpace(prefix) == "" {
+			errs = append(errs, errors.New("database.id_offsets must not have an empty server prefix"))
+		}
+
+		if offset := data.Database.IDOffsets[prefix]; offset < 0 {
+			errs = append(errs, fmt.Errorf("database.id_offsets[%s] must not be negative, got %d", prefix, offset))
+		}
+	}
+
+	if seconds := data.Database.StatisticsCacheSeconds; seconds != nil && *seconds < 0 {
+		errs = append(errs, fmt.Errorf("database.statistics_cache_seconds must not be negative, got %d", *seconds))
+	}
+
+	if data.Database.SensorQueryCacheSeconds < 0 {
+		errs = append(errs, fmt.Errorf("database.sensor_query_cache_seconds must not be negative, got %d", data.Database.SensorQueryCacheSeconds))
+	}
+
+	// Logging
+	if _, err := logger.CompileMaskPatterns(data.Logging.MaskPatterns); err != nil {
+		errs = append(errs, fmt.Errorf("logging.mask_patterns: %w", err))
+	}
+
+	if data.Logging.SampleBurst < 0 {
+		errs = append(errs, fmt.Errorf("logging.sample_burst must not be negative, got %d", data.Logging.SampleBurst))
+	}
+
+	if data.Logging.SamplePeriodSeconds < 0 {
+		errs = append(errs, fmt.Errorf("logging.sample_period_seconds must not be negative, got %d", data.Logging.SamplePeriodSeconds))
+	}
+
+	if data.PRTG.Timeout < 0 {
+		errs = append(errs, fmt.Errorf("prtg.timeout must not be negative (0 = default), got %d", data.PRTG.Timeout))
+	}
+
+	if data.PRTG.UIBaseURL != "" {
+		if u, err := url.Parse(data.PRTG.UIBaseURL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			errs = append(errs, fmt.Errorf("prtg.ui_base_url must be an http(s) URL, got %q", data.PRTG.UIBaseURL))
+		}
+	}
+
+	// PRTG API (only checked when enabled)
+	if data.PRTG.Enabled {
+		if data.PRTG.BaseURL == "" {
+			errs = append(errs, errors.New("prtg.base_url is required when prtg.enabled is true"))
+		}
+
+		if data.PRTG.APIToken == "" {
+			errs = append(errs, errors.New("prtg.api_token is required when prtg.enabled is true"))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// validateAPIKeys checks that every named API key has a unique name and a unique key.
+func validateAPIKeys(server ServerConfig) []error {
+	var errs []error
+
+	names := map[string]bool{DefaultAPIKeyName: server.APIKey != ""}
+	keys := map[string]bool{server.APIKey: server.APIKey != ""}
+
+	for i, apiKey := range server.APIKeys {
+		switch {
+		case apiKey.Name == "":
+			errs = append(errs, fmt.Errorf("server.api_keys[%d].name is required", i))
+		case names[apiKey.Name]:
+			errs = append(errs, fmt.Errorf("server.api_keys[%d].name %q is already used", i, apiKey.Name))
+		}
+
+		switch {
+		case apiKey.Key == "":
+			errs = append(errs, fmt.Errorf("server.api_keys[%d].key is required", i))
+		case keys[apiKey.Key]:
+			errs = append(errs, fmt.Errorf("server.api_keys[%d].key is already used by another key", i))
+		}
+
+		names[apiKey.Name] = true
+		keys[apiKey.Key] = true
+	}
+
+	return errs
+}
+
+// isValidPort reports whether port is a usable TCP port number.
+func isValidPort(port int) bool {
+	return port > 0 && port <= 65535
+}
+
+// validateRequireTLS rejects plaintext HTTP when server.require_tls is set, unless the server
+// is bound to a loopback address, where the API key never leaves the host.
+func validateRequireTLS(server ServerConfig) error {
+	if !server.RequireTLS || server.EnableTLS || isLoopbackAddress(server.BindAddress) {
+		return nil
+	}
+
+	return fmt.Errorf("server.require_tls is true but TLS is disabled on bind_address %q, which is not a loopback address: "+
+		"set server.enable_tls to true, or bind to 127.0.0.1 or ::1", server.BindAddress)
+}
+
+// isLoopbackAddress reports whether a bind address only accepts connections from the local host.
+// An empty address binds to all interfaces.
+func isLoopbackAddress(address string) bool {
+	if strings.EqualFold(address, "localhost") {
+		return true
+	}
+
+	ip, err := netip.ParseAddr(strings.Trim(address, "[]"))
+
+	return err == nil && ip.IsLoopback()
+}
+
+// isValidTransport reports whether transport names a supported MCP transport.
+func isValidTransport(transport string) bool {
+	switch strings.ToLower(transport) {
+	case TransportStreamableHTTP, TransportSSE, TransportWebSocket:
+		return true
+	default:
+		return false
+	}
+}
+
+// validateTransports checks server.transport and server.transports: known types, each
+// transport of the list on a valid port of its own.
+func validateTransports(server ServerConfig) []error {
+	var errs []error
+
+	if server.Transport != "" && !isValidTransport(server.Transport) {
+		errs = append(errs, fmt.Errorf("server.transport must be %q, %q or %q, got %q",
+			TransportStreamableHTTP, TransportSSE, TransportWebSocket, server.Transport))
+	}
+
+	ports := make(map[int]int, len(server.Transports))
+
+	for i, transport := range server.Transports {
+		if !isValidTransport(transport.Type) {
+			errs = append(errs, fmt.Errorf("server.transports[%d].type must be %q, %q or %q, got %q",
+				i, TransportStreamableHTTP, TransportSSE, TransportWebSocket, transport.Type))
+		}
+
+		if !isValidPort(transport.Port) {
+			errs = append(errs, fmt.Errorf("server.transports[%d].port must be between 1 and 65535, got %d", i, transport.Port))
+			continue
+		}
+
+		if first, ok := ports[transport.Port]; ok {
+			errs = append(errs, fmt.Errorf("server.transports[%d].port %d is already used by server.transports[%d]",
+				i, transport.Port, first))
+			continue
+		}
+
+		ports[transport.Port] = i
+	}
+
+	return errs
+}
+
+// reservedDSNKeys are connection parameters that have a dedicated database field.
+var reservedDSNKeys = map[string]bool{
+	"host":     true,
+	"port":     true,
+	"dbname":   true,
+	"user":     true,
+	"password": true,
+	"sslmode":  true,
+}
+
+// isValidDSNOptionKey reports whether key is a plausible libpq parameter name
+// (lowercase letters, digits and underscores).
+func isValidDSNOptionKey(key string) bool {
+	if key == "" {
+		return false
+	}
+
+	for _, r := range key {
+		if (r < 'a' || r > 'z') && (r < '0' || r > '9') && r != '_' {
+			return false
+		}
+	}
+
+	return true
+}
+
+// validateDSN checks a database.dsn passthrough: URLs must parse with a postgres scheme,
+// keyword/value strings must contain at least one key=value pair.
+func validateDSN(db DatabaseConfig) []error {
+	var errs []error
+
+	if len(db.Options) > 0 {
+		errs = append(errs, errors.New("database.options cannot be combined with database.dsn; add the parameters to the DSN"))
+	}
+
+	if db.PasswordFile != "" {
+		errs = append(errs, errors.New("database.password_file cannot be combined with database.dsn; put the password in the DSN"))
+	}
+
+	if db.Schema != "" {
+		errs = append(errs, errors.New("database.schema cannot be combined with database.dsn; add search_path to the DSN"))
+	}
+
+	if strings.Contains(db.DSN, "://") {
+		// The parse error embeds the raw URL and therefore the password: do not wrap it
+		u, err := url.Parse(db.DSN)
+		if err != nil {
+			return append(errs, errors.New("database.dsn is not a valid URL"))
+		}
+
+		if u.Scheme != "postgres" && u.Scheme != "postgresql" {
+			errs = append(errs, fmt.Errorf("database.dsn URL scheme must be postgres or postgresql, got %q", u.Scheme))
+		}
+
+		return errs
+	}
+
+	if !strings.Contains(db.DSN, "=") {
+		errs = append(errs, errors.New("database.dsn must be a postgres:// URL or a keyword=value connection string"))
+	}
+
+	return errs
+}
+
+// isValidTableName reports whether name is a plain PostgreSQL identifier, optionally
+// schema-qualified: letters, digits and underscores, not starting with a digit.
+func isValidTableName(name string) bool {
+	parts := strings.Split(name, ".")
+	if len(parts) > 2 {
+		return false
+	}
+
+	for _, part := range parts {
+		if part == "" || (part[0] >= '0' && part[0] <= '9') {
+			return false
+		}
+
+		for _, r := range part {
+			if (r < 'a' || r > 'z') && (r < 'A' || r > 'Z') && (r < '0' || r > '9') && r != '_' {
+				return false
+			}
+		}
+	}
+
+	return true
+}
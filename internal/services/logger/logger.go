@@ -59,9 +59,14 @@ func NewLogger(args *cliargs.ParsedArgs) *Logger {
 
 // buildDevelopmentLogger creates a logger for development/console mode.
 func buildDevelopmentLogger(_ *cliargs.ParsedArgs, level zerolog.Level) *Logger {
+	return newDevelopmentLogger(os.Stderr, level)
+}
+
+// newDevelopmentLogger creates a console logger writing to out.
+func newDevelopmentLogger(out io.Writer, level zerolog.Level) *Logger {
 	// Console writer with colors
 	consoleWriter := zerolog.ConsoleWriter{
-		Out:        os.Stderr,
+		Out:        out,
 		TimeFormat: "15:04:05",
 	}
 
@@ -70,6 +75,7 @@ func buildDevelopmentLogger(_ *cliargs.ParsedArgs, level zerolog.Level) *Logger
 
 	logger := zerolog.New(maskingWriter).
 		Level(level).
+		Hook(warnSampler).
 		With().
 		Timestamp().
 		Caller().
@@ -80,19 +86,26 @@ func buildDevelopmentLogger(_ *cliargs.ParsedArgs, level zerolog.Level) *Logger
 
 // buildProductionLogger creates a logger for production/service mode.
 func buildProductionLogger(args *cliargs.ParsedArgs, level zerolog.Level) *Logger {
+	return newProductionLogger(args.LogFile, level, os.Stderr)
+}
+
+// newProductionLogger creates a JSON logger writing to the rotated logFile. When the file can't
+// be written, it warns on stderr and logs there instead, rather than losing every log line.
+func newProductionLogger(logFile string, level zerolog.Level, stderr io.Writer) *Logger {
 	// Ensure log directory exists
-	logDir := filepath.Dir(args.LogFile)
+	logDir := filepath.Dir(logFile)
 	if err := os.MkdirAll(logDir, 0750); err != nil {
-		// Fallback to stderr
-		logger := zerolog.New(os.Stderr).Level(level).With().Timestamp().Logger()
-		logger.Error().Err(err).Msg("Failed to create log directory, using stderr")
+		return newStderrFallbackLogger(stderr, level, logFile, err)
+	}
 
-		return &logger
+	// lumberjack only opens the file on the first write, and then reports errors to nobody
+	if err := checkLogFileWritable(logFile); err != nil {
+		return newStderrFallbackLogger(stderr, level, logFile, err)
 	}
 
 	// Configure log rotation
 	logRotator := &lumberjack.Logger{
-		Filename:   args.LogFile,
+		Filename:   logFile,
 		MaxSize:    10,   // Megabytes
 		MaxBackups: 5,    // Number of backups
 		MaxAge:     30,   // Days
@@ -105,7 +118,7 @@ func buildProductionLogger(args *cliargs.ParsedArgs, level zerolog.Level) *Logge
 	// Add console in interactive mode
 	if service.Interactive() {
 		consoleWriter := zerolog.ConsoleWriter{
-			Out:        os.Stderr,
+			Out:        stderr,
 			TimeFormat: "15:04:05",
 		}
 		writers = append(writers, NewMaskingWriter(consoleWriter))
@@ -116,6 +129,7 @@ func buildProductionLogger(args *cliargs.ParsedArgs, level zerolog.Level) *Logge
 
 	logger := zerolog.New(logWriter).
 		Level(level).
+		Hook(warnSampler).
 		With().
 		Timestamp().
 		Logger()
@@ -123,6 +137,34 @@ func buildProductionLogger(args *cliargs.ParsedArgs, level zerolog.Level) *Logge
 	return &logger
 }
 
+// checkLogFileWritable opens the log file for appending, creating it if needed.
+func checkLogFileWritable(logFile string) error {
+	file, err := os.OpenFile(logFile, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0600) // lumberjack's mode for new files
+	if err != nil {
+		return err
+	}
+
+	return file.Close()
+}
+
+// newStderrFallbackLogger creates a JSON logger writing to stderr (still masked), after warning
+// that logFile can't be used.
+func newStderrFallbackLogger(stderr io.Writer, level zerolog.Level, logFile string, err error) *Logger {
+	logger := zerolog.New(NewMaskingWriter(stderr)).With().Timestamp().Logger()
+
+	// Written without level, so neither the configured level nor the global level set by
+	// NewLogger hides it when it is "error"
+	logger.WithLevel(zerolog.NoLevel).
+		Str(zerolog.LevelFieldName, zerolog.LevelWarnValue).
+		Err(err).
+		Str("log_file", logFile).
+		Msg("Log file is not writable, logging to stderr instead")
+
+	logger = logger.Level(level).Hook(warnSampler)
+
+	return &logger
+}
+
 // parseLogLevel converts string to zerolog.Level.
 func parseLogLevel(level string) zerolog.Level {
 	switch level {
@@ -0,0 +1,76 @@
+package logger
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestProductionLogger_UnwritableLogFile(t *testing.T) {
+	assertStderrFallback := func(t *testing.T, logFile string) {
+		t.Helper()
+
+		var stderr bytes.Buffer
+
+		logger := newProductionLogger(logFile, zerolog.ErrorLevel, &stderr)
+		logger.Error().Msg("database unreachable")
+
+		output := stderr.String()
+		assert.Contains(t, output, `"level":"warn"`)
+		assert.Contains(t, output, "Log file is not writable, logging to stderr instead")
+		assert.Contains(t, output, `"log_file":"`+logFile+`"`)
+		assert.Contains(t, output, "database unreachable", "logs go to stderr instead of being lost")
+	}
+
+	t.Run("read-only directory", func(t *testing.T) {
+		if os.Geteuid() == 0 {
+			t.Skip("root can write to read-only directories")
+		}
+
+		dir := t.TempDir()
+		require.NoError(t, os.Chmod(dir, 0500))
+		t.Cleanup(func() { _ = os.Chmod(dir, 0700) })
+
+		assertStderrFallback(t, filepath.Join(dir, "server.log"))
+	})
+
+	t.Run("path is a directory", func(t *testing.T) {
+		assertStderrFallback(t, t.TempDir())
+	})
+
+	t.Run("directory cannot be created", func(t *testing.T) {
+		parent := filepath.Join(t.TempDir(), "file")
+		require.NoError(t, os.WriteFile(parent, nil, 0600))
+
+		assertStderrFallback(t, filepath.Join(parent, "logs", "server.log"))
+	})
+
+	t.Run("global level error", func(t *testing.T) {
+		// NewLogger sets the global level before building the logger
+		previous := zerolog.GlobalLevel()
+		zerolog.SetGlobalLevel(zerolog.ErrorLevel)
+		t.Cleanup(func() { zerolog.SetGlobalLevel(previous) })
+
+		assertStderrFallback(t, t.TempDir())
+	})
+
+	t.Run("writable file", func(t *testing.T) {
+		var stderr bytes.Buffer
+
+		logFile := filepath.Join(t.TempDir(), "server.log")
+
+		logger := newProductionLogger(logFile, zerolog.InfoLevel, &stderr)
+		logger.Info().Msg("started")
+
+		assert.NotContains(t, stderr.String(), "not writable")
+
+		content, err := os.ReadFile(logFile)
+		require.NoError(t, err)
+		assert.Contains(t, string(content), "started")
+	})
+}
@@ -1,9 +1,11 @@
 package logger
 
 import (
+	"fmt"
 	"io"
 	"regexp"
 	"strings"
+	"sync"
 )
 
 // Sensitive patterns to mask in logs.
@@ -12,11 +14,11 @@ import (
 var sensitivePatterns = []*regexp.Regexp{
 	// Passwords in various formats
 	regexp.MustCompile(`(?i)"(password|passwd|pwd)"\s*:\s*"([^"]+)"`),
-	regexp.MustCompile(`(?i)(password|passwd|pwd)=([^\s&]+)`),
+	regexp.MustCompile(`(?i)(password|passwd|pwd)=([^\s&"]+)`),
 
 	// API keys and tokens
 	regexp.MustCompile(`(?i)"(token|api[-_]?key|secret|authentication[-_]?key)"\s*:\s*"([^"]+)"`),
-	regexp.MustCompile(`(?i)(api[-_]?key|token|secret)=([^\s&]+)`),
+	regexp.MustCompile(`(?i)(api[-_]?key|token|secret)=([^\s&"]+)`),
 
 	// Authorization headers
 	regexp.MustCompile(`(?i)(Authorization|X-API-Key):\s*(Bearer|Basic)?\s*([a-zA-Z0-9+/=._-]+)`),
@@ -28,14 +30,70 @@ var sensitivePatterns = []*regexp.Regexp{
 	regexp.MustCompile(`([a-f0-9]{8}-[a-f0-9]{4}-[a-f0-9]{4}-[a-f0-9]{4}-[a-f0-9]{12})`),
 }
 
+// Additional patterns from the configuration (logging.mask_patterns).
+//
+//nolint:gochecknoglobals // Shared by every MaskingWriter, updated on configuration reload.
+var (
+	customPatterns    []*regexp.Regexp
+	customPatternLock sync.RWMutex
+)
+
+// CompileMaskPatterns compiles custom masking patterns.
+// Each pattern must capture the sensitive value in its last capture group.
+func CompileMaskPatterns(patterns []string) ([]*regexp.Regexp, error) {
+	compiled := make([]*regexp.Regexp, 0, len(patterns))
+
+	for _, expr := range patterns {
+		pattern, err := regexp.Compile(expr)
+		if err != nil {
+			return nil, fmt.Errorf("invalid mask pattern %q: %w", expr, err)
+		}
+
+		if pattern.NumSubexp() == 0 {
+			return nil, fmt.Errorf("mask pattern %q must capture the value to mask in a group", expr)
+		}
+
+		compiled = append(compiled, pattern)
+	}
+
+	return compiled, nil
+}
+
+// SetMaskPatterns replaces the custom masking patterns applied on top of the built-in ones.
+// On error the previous patterns are kept.
+func SetMaskPatterns(patterns []string) error {
+	compiled, err := CompileMaskPatterns(patterns)
+	if err != nil {
+		return err
+	}
+
+	customPatternLock.Lock()
+	defer customPatternLock.Unlock()
+
+	customPatterns = compiled
+
+	return nil
+}
+
 // MaskSensitiveData masks sensitive information in log output.
 func MaskSensitiveData(input string) string {
+	// Built-in patterns capture a key and a value
+	masked := maskMatches(input, sensitivePatterns, 2)
+
+	customPatternLock.RLock()
+	defer customPatternLock.RUnlock()
+
+	return maskMatches(masked, customPatterns, 1)
+}
+
+// maskMatches masks the last capture group of each match having at least minGroups groups.
+func maskMatches(input string, patterns []*regexp.Regexp, minGroups int) string {
 	masked := input
 
-	for _, pattern := range sensitivePatterns {
+	for _, pattern := range patterns {
 		masked = pattern.ReplaceAllStringFunc(masked, func(match string) string {
 			parts := pattern.FindStringSubmatch(match)
-			if len(parts) >= 3 {
+			if len(parts) > minGroups {
 				value := parts[len(parts)-1]
 				maskedValue := maskValue(value)
 
@@ -0,0 +1,65 @@
+package logger
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/matthieu/mcp-server-prtg/internal/cliargs"
+)
+
+// logSecrets writes a log line containing a password and an API key.
+func logSecrets(logger *Logger) {
+	logger.Info().
+		Str("dsn", "host=db.example.com dbname=prtg user=reader password=s3cretPassw0rd sslmode=disable").
+		Msg("connecting with api_key=abcd1234efgh5678")
+}
+
+func assertSecretsMasked(t *testing.T, output string) {
+	t.Helper()
+
+	require.NotEmpty(t, output)
+	assert.NotContains(t, output, "s3cretPassw0rd")
+	assert.NotContains(t, output, "abcd1234efgh5678")
+	assert.Contains(t, output, "password=s3***rd")
+	assert.Contains(t, output, "api_key=ab***78")
+	assert.Contains(t, output, "host=db.example.com", "non-sensitive values must be kept")
+}
+
+func TestMasking_DevelopmentLogger(t *testing.T) {
+	var buf bytes.Buffer
+
+	logSecrets(newDevelopmentLogger(&buf, zerolog.InfoLevel))
+
+	assertSecretsMasked(t, buf.String())
+}
+
+func TestMasking_ProductionLogger(t *testing.T) {
+	logFile := filepath.Join(t.TempDir(), "logs", "server.log")
+
+	logSecrets(buildProductionLogger(&cliargs.ParsedArgs{LogFile: logFile}, zerolog.InfoLevel))
+
+	content, err := os.ReadFile(logFile)
+	require.NoError(t, err)
+
+	assertSecretsMasked(t, string(content))
+	assert.Contains(t, string(content), `"level":"info"`, "JSON output must stay parseable")
+}
+
+func TestSetMaskPatterns(t *testing.T) {
+	t.Cleanup(func() { require.NoError(t, SetMaskPatterns(nil)) })
+
+	require.NoError(t, SetMaskPatterns([]string{`(?i)community=(\w+)`}))
+	assert.Equal(t, "snmp community=pu***ng", MaskSensitiveData("snmp community=publicstring"))
+
+	t.Run("invalid pattern keeps previous ones", func(t *testing.T) {
+		assert.Error(t, SetMaskPatterns([]string{`community=(`}))
+		assert.Error(t, SetMaskPatterns([]string{`community=\w+`}), "a capture group is required")
+		assert.Equal(t, "community=pu***ng", MaskSensitiveData("community=publicstring"))
+	})
+}
@@ -0,0 +1,86 @@
+package logger
+
+import (
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+// warnSampler rate-limits repeated Warn and Error events of every logger built by this package.
+// Info and Debug events are never sampled.
+//
+//nolint:gochecknoglobals // Global state required so sampling can be reconfigured after loggers are built.
+var warnSampler = &messageSampler{}
+
+// sampleMaxMessages bounds the distinct messages counted in one sampling period. Messages
+// beyond it share a single counter, so formatted messages can't grow the counts without limit.
+const sampleMaxMessages = 1000
+
+// sampleOverflowKey is the counter shared by the messages beyond sampleMaxMessages.
+const sampleOverflowKey = "\x00overflow"
+
+// messageSampler is a zerolog.Hook dropping Warn and Error events once the same message was
+// written burst times in the current period. A zerolog.Sampler only sees the level, so one
+// flooding error would also silence every other one. Its limits can be replaced at runtime,
+// since loggers are created before the configuration is loaded.
+type messageSampler struct {
+	mu          sync.Mutex
+	burst       int // 0: no sampling
+	period      time.Duration
+	periodStart time.Time
+	counts      map[string]int // Events of each message in the current period
+}
+
+// Run implements zerolog.Hook.
+func (s *messageSampler) Run(e *zerolog.Event, level zerolog.Level, message string) {
+	if level != zerolog.WarnLevel && level != zerolog.ErrorLevel {
+		return
+	}
+
+	if !s.allow(message) {
+		e.Discard()
+	}
+}
+
+// allow counts an event of message and reports whether it is within the burst of the period.
+func (s *messageSampler) allow(message string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.burst <= 0 {
+		return true
+	}
+
+	now := time.Now()
+	if s.counts == nil || now.Sub(s.periodStart) >= s.period {
+		s.counts = make(map[string]int)
+		s.periodStart = now
+	}
+
+	key := message
+	if _, ok := s.counts[key]; !ok && len(s.counts) >= sampleMaxMessages {
+		key = sampleOverflowKey
+	}
+
+	s.counts[key]++
+
+	return s.counts[key] <= s.burst
+}
+
+// SetSampling limits each Warn and Error message to burst events per period; events over the limit
+// are dropped until the period ends. The limit applies per message, so a flood of one repeated error
+// (e.g. during a database outage) cannot rotate useful history out of the log file, nor hide other
+// errors. A burst or period of 0 disables sampling. Fatal and Panic events are never sampled.
+func SetSampling(burst int, period time.Duration) {
+	warnSampler.mu.Lock()
+	defer warnSampler.mu.Unlock()
+
+	if burst <= 0 || period <= 0 {
+		burst, period = 0, 0
+	}
+
+	warnSampler.burst = burst
+	warnSampler.period = period
+	warnSampler.counts = nil
+}
@@ -0,0 +1,64 @@
+package logger
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSetSampling(t *testing.T) {
+	t.Cleanup(func() { SetSampling(0, 0) })
+
+	SetSampling(5, time.Minute)
+
+	var buf bytes.Buffer
+
+	logger := newDevelopmentLogger(&buf, zerolog.InfoLevel)
+	for i := 0; i < 1000; i++ {
+		logger.Error().Msg("query failed: dial tcp db.example.com:5432: connection refused password=s3cretPassw0rd")
+	}
+
+	output := buf.String()
+	errorLines := strings.Count(output, "connection refused")
+	assert.Equal(t, 5, errorLines, "only the burst is written within the period")
+	assert.NotContains(t, output, "s3cretPassw0rd", "sampled events are still masked")
+
+	t.Run("per message", func(t *testing.T) {
+		buf.Reset()
+
+		// The connection error used up its burst, other errors are still written
+		logger.Error().Msg("query failed: dial tcp db.example.com:5432: connection refused password=s3cretPassw0rd")
+
+		for i := 0; i < 3; i++ {
+			logger.Warn().Msg("statistics cache refresh failed")
+		}
+
+		assert.NotContains(t, buf.String(), "connection refused")
+		assert.Equal(t, 3, strings.Count(buf.String(), "statistics cache refresh failed"))
+	})
+
+	t.Run("info is not sampled", func(t *testing.T) {
+		buf.Reset()
+
+		for i := 0; i < 100; i++ {
+			logger.Info().Msg("tool call handled")
+		}
+
+		assert.Equal(t, 100, strings.Count(buf.String(), "tool call handled"))
+	})
+
+	t.Run("disabled", func(t *testing.T) {
+		SetSampling(0, 0)
+		buf.Reset()
+
+		for i := 0; i < 100; i++ {
+			logger.Warn().Msg("slow query")
+		}
+
+		assert.Equal(t, 100, strings.Count(buf.String(), "slow query"))
+	})
+}
@@ -1,6 +1,11 @@
 package types
 
-import "time"
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
 
 // Sensor represents a PRTG sensor with its metadata and current status.
 type Sensor struct {
@@ -10,6 +15,7 @@ type Sensor struct {
 	SensorType           string     `json:"sensor_type"`
 	DeviceID             int        `json:"device_id"`
 	DeviceName           string     `json:"device_name,omitempty"`
+	DeviceHost           string     `json:"device_host,omitempty"`
 	ScanningIntervalSecs int        `json:"scanning_interval_seconds"`
 	Status               int        `json:"status"`
 	StatusText           string     `json:"status_text"`
@@ -22,6 +28,142 @@ type Sensor struct {
 	DowntimeSinceSecs    *float64   `json:"downtime_since_seconds,omitempty"`
 	FullPath             string     `json:"full_path,omitempty"`
 	Tags                 string     `json:"tags,omitempty"`
+	URL                  string     `json:"url,omitempty"`           // Link to the sensor in the PRTG web interface, when requested
+	PrimaryValue         string     `json:"primary_value,omitempty"` // Current value of the primary channel from the PRTG API, when requested
+}
+
+// Status transition directions reported by StatusChange.
+const (
+	TransitionDown = "down"
+	TransitionUp   = "up"
+)
+
+// StatusChange is a sensor that recently went down or came back up.
+type StatusChange struct {
+	Sensor
+	Transition string    `json:"transition"` // "down" or "up"
+	ChangedAt  time.Time `json:"changed_at"` // Time of the most recent transition
+}
+
+// LastTransition returns the direction and time of the sensor's most recent up/down transition,
+// from its last up/down timestamps. A down time at or after the last up time means it went down.
+func (s Sensor) LastTransition() (string, time.Time) {
+	if s.LastDownUTC != nil && !s.LastDownUTC.Before(s.LastUpUTC) {
+		return TransitionDown, *s.LastDownUTC
+	}
+
+	return TransitionUp, s.LastUpUTC
+}
+
+// FieldChange is a sensor field that differs between two snapshots, as display strings.
+type FieldChange struct {
+	Field string `json:"field"`
+	Old   string `json:"old"`
+	New   string `json:"new"`
+}
+
+// SensorDiff is the delta between a previous state of a sensor and its current state.
+// Used by the prtg_sensor_status_diff MCP tool for change detection.
+type SensorDiff struct {
+	SensorID   int           `json:"sensor_id"`
+	Changed    bool          `json:"changed"`
+	Transition string        `json:"transition,omitempty"` // TransitionDown or TransitionUp when the sensor crossed up/down
+	ChangedAt  *time.Time    `json:"changed_at,omitempty"` // Time of the transition, when known
+	Since      *time.Time    `json:"since,omitempty"`      // Set when compared against a timestamp instead of a snapshot
+	Changes    []FieldChange `json:"changes"`
+
+	// Elapsed uptime/downtime between the snapshots (nil when not comparable)
+	UptimeDeltaSecs   *float64 `json:"uptime_delta_seconds,omitempty"`
+	DowntimeDeltaSecs *float64 `json:"downtime_delta_seconds,omitempty"`
+
+	Current Sensor `json:"current"`
+}
+
+// SensorFilter holds the filters shared by sensor listing and count queries.
+// Empty fields are ignored; string filters use case-insensitive partial matching.
+type SensorFilter struct {
+	DeviceName string
+	SensorName string
+	SensorType string
+	GroupName  string
+	Status     *int
+	Tags       string
+
+	// Statuses keeps sensors in any of these statuses (e.g. Down or Warning). Exclusive with Status.
+	Statuses []int
+
+	// Priority range (1-5, inclusive). A nil bound defaults to the end of the range.
+	MinPriority *int
+	MaxPriority *int
+
+	// Scanning interval range in seconds (inclusive). A nil bound leaves that side open.
+	MinInterval *int
+	MaxInterval *int
+
+	// ExcludePaused drops sensors in any paused status (see PausedStatuses).
+	// ActiveOnly additionally drops Unknown and Collecting sensors.
+	ExcludePaused bool
+	ActiveOnly    bool
+
+	// ProblemOnly keeps only sensors in a problem status (see ProblemStatuses).
+	ProblemOnly bool
+
+	// StaleMinutes keeps only sensors not checked for more than this many minutes,
+	// including sensors never checked (0 = no filter).
+	StaleMinutes int
+
+	// PathPrefix keeps only sensors below this group or device path (e.g. "/Root/Servers"),
+	// including those of subgroups. Unlike the name filters it is matched exactly.
+	PathPrefix string
+}
+
+// PRTG sensor priority bounds.
+const (
+	MinSensorPriority = 1
+	MaxSensorPriority = 5
+)
+
+// Validate checks that the priority range is within 1-5 and that neither range is inverted.
+func (f SensorFilter) Validate() error {
+	if f.Status != nil && len(f.Statuses) > 0 {
+		return fmt.Errorf("provide either status or statuses, not both")
+	}
+
+	for _, status := range f.Statuses {
+		if !IsKnownStatus(status) {
+			return fmt.Errorf("statuses must be PRTG status codes (%d-%d), got %d", StatusUnknown, StatusDownPartial, status)
+		}
+	}
+
+	if f.MinPriority != nil && (*f.MinPriority < MinSensorPriority || *f.MinPriority > MaxSensorPriority) {
+		return fmt.Errorf("min_priority must be between %d and %d, got %d", MinSensorPriority, MaxSensorPriority, *f.MinPriority)
+	}
+
+	if f.MaxPriority != nil && (*f.MaxPriority < MinSensorPriority || *f.MaxPriority > MaxSensorPriority) {
+		return fmt.Errorf("max_priority must be between %d and %d, got %d", MinSensorPriority, MaxSensorPriority, *f.MaxPriority)
+	}
+
+	if f.MinPriority != nil && f.MaxPriority != nil && *f.MinPriority > *f.MaxPriority {
+		return fmt.Errorf("min_priority (%d) must not be greater than max_priority (%d)", *f.MinPriority, *f.MaxPriority)
+	}
+
+	if f.MinInterval != nil && *f.MinInterval < 0 {
+		return fmt.Errorf("min_interval must not be negative, got %d", *f.MinInterval)
+	}
+
+	if f.MaxInterval != nil && *f.MaxInterval < 0 {
+		return fmt.Errorf("max_interval must not be negative, got %d", *f.MaxInterval)
+	}
+
+	if f.MinInterval != nil && f.MaxInterval != nil && *f.MinInterval > *f.MaxInterval {
+		return fmt.Errorf("min_interval (%d) must not be greater than max_interval (%d)", *f.MinInterval, *f.MaxInterval)
+	}
+
+	if f.StaleMinutes < 0 {
+		return fmt.Errorf("stale_minutes must not be negative, got %d", f.StaleMinutes)
+	}
+
+	return nil
 }
 
 // Device represents a PRTG device.
@@ -37,6 +179,23 @@ type Device struct {
 	TreeDepth   int    `json:"tree_depth"`
 }
 
+// AmbiguousSensorError is returned by a sensor lookup by ID when the ID exists on several
+// PRTG servers and no server was given. Sensors holds the candidates, one per server.
+type AmbiguousSensorError struct {
+	SensorID int
+	Sensors  []Sensor
+}
+
+func (e *AmbiguousSensorError) Error() string {
+	serverIDs := make([]string, len(e.Sensors))
+	for i := range e.Sensors {
+		serverIDs[i] = strconv.Itoa(e.Sensors[i].ServerID)
+	}
+
+	return fmt.Sprintf("sensor %d exists on several PRTG servers (server_id %s), pass server_id to choose one",
+		e.SensorID, strings.Join(serverIDs, ", "))
+}
+
 // Group represents a PRTG group/probe.
 type Group struct {
 	ID          int    `json:"id"`
@@ -48,6 +207,22 @@ type Group struct {
 	TreeDepth   int    `json:"tree_depth"`
 }
 
+// Object types reported by PRTGObject.Type.
+const (
+	ObjectTypeSensor = "sensor"
+	ObjectTypeDevice = "device"
+	ObjectTypeGroup  = "group"
+)
+
+// PRTGObject is a PRTG object resolved from its ID alone; exactly one of Sensor, Device
+// and Group is set, as named by Type. Used by the prtg_get_object MCP tool.
+type PRTGObject struct {
+	Type   string  `json:"type"`
+	Sensor *Sensor `json:"sensor,omitempty"`
+	Device *Device `json:"device,omitempty"`
+	Group  *Group  `json:"group,omitempty"`
+}
+
 // DeviceOverview represents a device with its sensors and aggregated statistics.
 // Used by the prtg_device_overview MCP tool to provide a complete device status summary.
 type DeviceOverview struct {
@@ -65,20 +240,135 @@ type HierarchyNode struct {
 	Group   Group             `json:"group"`
 	Devices []HierarchyDevice `json:"devices"`
 	Groups  []*HierarchyNode  `json:"groups,omitempty"`
+
+	// Set only when the breadth limit truncated the devices or child groups of this node
+	TotalDevices int `json:"total_devices,omitempty"`
+	TotalGroups  int `json:"total_groups,omitempty"`
+
+	// Set on the root node when the node budget (HierarchyOptions.MaxNodes) stopped the traversal
+	NodeLimitReached bool `json:"node_limit_reached,omitempty"`
+}
+
+// DevicePath is a device with its ancestors, from the probe down to the device's group.
+// Used by the prtg_get_hierarchy MCP tool when it starts from a device.
+type DevicePath struct {
+	Ancestors []Group  `json:"ancestors"` // Probe first, the device's group last
+	Device    Device   `json:"device"`
+	Sensors   []Sensor `json:"sensors,omitempty"`
+
+	// Set only when the per-device sensor limit truncated the sensors
+	TotalSensors int `json:"total_sensors,omitempty"`
 }
 
 // HierarchyDevice represents a device with its sensors in the hierarchy.
 type HierarchyDevice struct {
 	Device  Device   `json:"device"`
 	Sensors []Sensor `json:"sensors,omitempty"`
+
+	// Set only when the per-device sensor limit truncated the sensors
+	TotalSensors int `json:"total_sensors,omitempty"`
+}
+
+// Default breadth limits of a hierarchy traversal.
+const (
+	DefaultHierarchyMaxChildren         = 50
+	DefaultHierarchyMaxSensorsPerDevice = 50
+	DefaultHierarchyMaxNodes            = 5000
+)
+
+// HierarchyOptions controls how much of the PRTG tree a hierarchy traversal returns.
+type HierarchyOptions struct {
+	IncludeSensors      bool
+	MaxDepth            int // 0 = unlimited
+	MaxChildren         int // Maximum devices and child groups listed per group (0 = default)
+	MaxSensorsPerDevice int // Maximum sensors listed per device (0 = default)
+	MaxNodes            int // Maximum groups, devices and sensors in the whole tree (0 = default)
 }
 
 // SearchResults represents the results of a universal search across PRTG objects.
 // Used by the prtg_search MCP tool.
 type SearchResults struct {
-	Groups  []Group  `json:"groups"`
+	Groups  []Group       `json:"groups"`
+	Devices []Device      `json:"devices"`
+	Sensors []Sensor      `json:"sensors"`
+	Errors  []SearchError `json:"errors,omitempty"` // Categories whose query failed, the others are still returned
+}
+
+// SearchError is a search category ("groups", "devices" or "sensors") whose query failed.
+type SearchError struct {
+	Category string `json:"category"`
+	Error    string `json:"error"`
+}
+
+// EmptyObjects lists devices without sensors and groups without devices or child groups.
+// Used by the prtg_find_empty_objects MCP tool to find leftovers worth cleaning up.
+type EmptyObjects struct {
 	Devices []Device `json:"devices"`
-	Sensors []Sensor `json:"sensors"`
+	Groups  []Group  `json:"groups"`
+}
+
+// TagCoverage tells how many sensors carry at least one tag, and where the untagged ones are.
+// Used by the prtg_tag_coverage MCP tool for tagging governance.
+type TagCoverage struct {
+	TotalSensors    int     `json:"total_sensors"`
+	UntaggedSensors int     `json:"untagged_sensors"`
+	CoveragePercent float64 `json:"coverage_percent"` // Tagged sensors, 100 when there are no sensors
+
+	// Devices or groups (see GroupBy) with untagged sensors, most untagged sensors first
+	GroupBy string            `json:"group_by"`
+	Objects []UntaggedSensors `json:"objects"`
+}
+
+// UntaggedSensors is the number of untagged sensors of one device or group.
+type UntaggedSensors struct {
+	ID              int    `json:"id"`
+	ServerID        int    `json:"server_id"`
+	Name            string `json:"name"`
+	FullPath        string `json:"full_path"`
+	UntaggedSensors int    `json:"untagged_sensors"`
+	TotalSensors    int    `json:"total_sensors"`
+}
+
+// GroupDiff compares the sensor inventories of two groups, matching sensors by name and type.
+// Used by the prtg_diff_groups MCP tool to verify parity during migrations.
+type GroupDiff struct {
+	GroupA    Group        `json:"group_a"`
+	GroupB    Group        `json:"group_b"`
+	OnlyInA   []Sensor     `json:"only_in_a"`
+	OnlyInB   []Sensor     `json:"only_in_b"`
+	Common    []SensorPair `json:"common"`
+	Truncated bool         `json:"truncated,omitempty"` // A group had more sensors than were compared
+}
+
+// SensorPair is a sensor of one group matched with its counterpart in another group.
+type SensorPair struct {
+	A Sensor `json:"a"`
+	B Sensor `json:"b"`
+}
+
+// BusinessProcessSources represents a Business Process sensor with the source sensors it aggregates.
+// Used by the prtg_business_process_sources MCP tool to drill down into a failing process.
+type BusinessProcessSources struct {
+	Process     Sensor   `json:"process"`
+	Sources     []Sensor `json:"sources"`
+	DownSources int      `json:"down_sources"`
+	Unresolved  []int    `json:"unresolved_source_ids,omitempty"`
+}
+
+// ScoredAlert represents an alert sensor with its computed severity score.
+// Used by the prtg_get_alerts MCP tool to rank issues deterministically.
+type ScoredAlert struct {
+	Sensor
+	SeverityScore int `json:"severity_score"` // 0-100, combines status weight and priority
+}
+
+// AlertDiff compares the current alerts with the sensor IDs in alert at a previous poll.
+// Used by the prtg_get_alerts MCP tool when a baseline is given.
+type AlertDiff struct {
+	New              []ScoredAlert `json:"new"`                // Alerts of sensors not in the baseline, most severe first
+	Cleared          []int         `json:"cleared"`            // Baseline sensors no longer in alert
+	Unchanged        int           `json:"unchanged"`          // Baseline sensors still in alert
+	CurrentSensorIDs []int         `json:"current_sensor_ids"` // Sensors in alert now, the baseline of the next poll
 }
 
 // Tag represents a PRTG tag with usage statistics.
@@ -89,6 +379,80 @@ type Tag struct {
 	SensorCount int    `json:"sensor_count"`
 }
 
+// Tag orderings.
+const (
+	TagOrderName  = "name"  // Alphabetical
+	TagOrderUsage = "usage" // Most-used tags first
+)
+
+// TagFilter holds the filters and ordering of a tags query.
+type TagFilter struct {
+	Name    string // Tag name (partial match, case-insensitive)
+	OrderBy string // TagOrderName (default) or TagOrderUsage
+
+	// Range of the number of sensors using the tag (inclusive). A nil bound leaves that side open;
+	// MaxSensorCount 0 finds unused tags.
+	MinSensorCount *int
+	MaxSensorCount *int
+}
+
+// Validate checks the ordering and that the sensor count range is neither negative nor inverted.
+func (f TagFilter) Validate() error {
+	if f.OrderBy != "" && f.OrderBy != TagOrderName && f.OrderBy != TagOrderUsage {
+		return fmt.Errorf("order_by must be %q or %q, got %q", TagOrderName, TagOrderUsage, f.OrderBy)
+	}
+
+	if f.MinSensorCount != nil && *f.MinSensorCount < 0 {
+		return fmt.Errorf("min_sensor_count must not be negative, got %d", *f.MinSensorCount)
+	}
+
+	if f.MaxSensorCount != nil && *f.MaxSensorCount < 0 {
+		return fmt.Errorf("max_sensor_count must not be negative, got %d", *f.MaxSensorCount)
+	}
+
+	if f.MinSensorCount != nil && f.MaxSensorCount != nil && *f.MinSensorCount > *f.MaxSensorCount {
+		return fmt.Errorf("min_sensor_count (%d) must not be greater than max_sensor_count (%d)", *f.MinSensorCount, *f.MaxSensorCount)
+	}
+
+	return nil
+}
+
+// AlertsLimit is the default number of sensors returned per page of an alerts query.
+const AlertsLimit = 100
+
+// SensorsLimit is the default number of sensors returned by a sensors query without a limit.
+const SensorsLimit = 50
+
+// CustomQueryLimit is the default number of rows returned by a custom SQL query.
+const CustomQueryLimit = 100
+
+// MaxCustomQueryRows is the maximum number of rows returned by a custom SQL query.
+const MaxCustomQueryRows = 1000
+
+// AlertFilter holds the filters of an alerts query.
+type AlertFilter struct {
+	Hours       int    // Only sensors checked in the last N hours (0 = all)
+	Status      *int   // Only sensors in this status
+	MinPriority *int   // Only sensors with at least this priority (1-5, nil = all)
+	DeviceName  string // Device name (partial match, case-insensitive)
+	OrderBy     string // AlertOrderPriority (default) or AlertOrderSeverity
+}
+
+// Alert orderings.
+const (
+	AlertOrderPriority = "priority" // Priority, then most critical status first
+	AlertOrderSeverity = "severity" // Severity score (see ScoredAlert), then as AlertOrderPriority
+)
+
+// Validate checks that the priority floor is within 1-5.
+func (f AlertFilter) Validate() error {
+	if f.MinPriority != nil && (*f.MinPriority < MinSensorPriority || *f.MinPriority > MaxSensorPriority) {
+		return fmt.Errorf("min_priority must be between %d and %d, got %d", MinSensorPriority, MaxSensorPriority, *f.MinPriority)
+	}
+
+	return nil
+}
+
 // SensorStatus represents PRTG sensor status values.
 // Official PRTG status codes from documentation.
 const (
@@ -108,6 +472,30 @@ const (
 	StatusDownPartial        = 14
 )
 
+// PausedStatuses lists every PRTG status code that represents a paused sensor,
+// whether paused manually, by dependency, by schedule, by license or until a time.
+var PausedStatuses = []int{
+	StatusPausedByUser,
+	StatusPausedByDependency,
+	StatusPausedBySchedule,
+	StatusPausedByLicense,
+	StatusPausedUntil,
+}
+
+// InactiveStatuses lists the paused statuses plus Unknown and Collecting,
+// i.e. sensors that are not currently producing monitoring results.
+var InactiveStatuses = append([]int{StatusUnknown, StatusCollecting}, PausedStatuses...)
+
+// ProblemStatuses lists the PRTG status codes of sensors that are not green:
+// Warning, Down, Unusual, Down (Acknowledged) and Down (Partial).
+var ProblemStatuses = []int{
+	StatusWarning,
+	StatusDown,
+	StatusUnusual,
+	StatusDownAcknowledged,
+	StatusDownPartial,
+}
+
 // Statistics represents aggregated PRTG server statistics.
 // Used by the prtg_get_statistics MCP tool to provide server-wide metrics.
 type Statistics struct {
@@ -119,6 +507,77 @@ type Statistics struct {
 	TopSensorTypes      []SensorTypeCount `json:"top_sensor_types"`
 	AvgSensorsPerDevice float64           `json:"avg_sensors_per_device"`
 	TotalProbes         int               `json:"total_probes"`
+
+	// Sensor types with the most problem sensors (down + warning), for spotting a failing class
+	ProblemSensorTypes []SensorTypeStatusCount `json:"problem_sensor_types"`
+}
+
+// RAG (red/amber/green) health statuses reported by EstateHealth.
+const (
+	HealthGreen = "green"
+	HealthAmber = "amber"
+	HealthRed   = "red"
+)
+
+// EstateHealth is a one-shot health summary of the whole PRTG estate.
+// Paused sensors are excluded from the monitored sensors and the health percentage.
+type EstateHealth struct {
+	HealthPercent       float64       `json:"health_percent"` // Up sensors / monitored sensors
+	Status              string        `json:"status"`         // HealthGreen, HealthAmber or HealthRed
+	Summary             string        `json:"summary"`
+	MonitoredSensors    int           `json:"monitored_sensors"`
+	UpSensors           int           `json:"up_sensors"`
+	WarningSensors      int           `json:"warning_sensors"` // Includes unusual
+	DownSensors         int           `json:"down_sensors"`    // Includes partial, excludes acknowledged
+	AcknowledgedSensors int           `json:"acknowledged_sensors"`
+	PausedSensors       int           `json:"paused_sensors"`
+	TotalProbes         int           `json:"total_probes"`
+	NoProbeSensors      int           `json:"no_probe_sensors"` // Sensors of disconnected probes
+	TopProblems         []ScoredAlert `json:"top_problems"`
+}
+
+// Probable root causes reported by OutageExplanation, most widespread first.
+const (
+	CauseSiteOutage       = "site_outage"       // Every monitored device of the scope is down
+	CauseDeviceOutage     = "device_outage"     // Every monitored sensor of a device is down
+	CauseMultipleServices = "multiple_services" // Several sensors of a reachable device are down
+	CauseIsolated         = "isolated"          // Single problems on otherwise healthy devices
+)
+
+// OutageCause groups the problem sensors sharing one probable root cause.
+type OutageCause struct {
+	Cause       string   `json:"cause"` // One of the Cause* constants
+	Explanation string   `json:"explanation"`
+	Devices     []string `json:"devices"`
+	Sensors     []Sensor `json:"sensors"` // Down and warning sensors, most severe first
+}
+
+// OutageExplanation is a consolidated view of the problems of a device or group,
+// grouped by probable root cause. Paused sensors are not monitored and never a cause.
+type OutageExplanation struct {
+	Scope              string        `json:"scope"` // e.g. "device web01" or "group Branch Office"
+	Summary            string        `json:"summary"`
+	MonitoredDevices   int           `json:"monitored_devices"`
+	MonitoredSensors   int           `json:"monitored_sensors"`
+	DownSensors        int           `json:"down_sensors"`    // Includes partial and acknowledged
+	WarningSensors     int           `json:"warning_sensors"` // Includes unusual
+	PausedByDependency int           `json:"paused_by_dependency"`
+	Causes             []OutageCause `json:"causes"`
+}
+
+// DimensionCount is the number of sensors sharing one value of a grouping dimension.
+type DimensionCount struct {
+	Value string `json:"value"` // Status code, sensor type, device name or group name
+	Count int    `json:"count"`
+}
+
+// MessageCount is a status message shared by sensors in a problem status, with the number of
+// sensors and devices showing it.
+type MessageCount struct {
+	Message       string   `json:"message"` // Trimmed; empty for sensors without message
+	SensorCount   int      `json:"sensor_count"`
+	DeviceCount   int      `json:"device_count"`
+	SampleSensors []string `json:"sample_sensors"` // Up to 3 "device / sensor" names, highest priority first
 }
 
 // SensorTypeCount represents a count of sensors by type.
@@ -127,6 +586,46 @@ type SensorTypeCount struct {
 	Count int    `json:"count"`
 }
 
+// SensorTypeStatusCount is a per-sensor-type breakdown of sensor status.
+// Down includes partial and acknowledged down; Warning includes unusual.
+type SensorTypeStatusCount struct {
+	Type    string `json:"type"`
+	Down    int    `json:"down"`
+	Warning int    `json:"warning"`
+	Up      int    `json:"up"`
+	Total   int    `json:"total"`
+}
+
+// ChannelValue is the latest value of a sensor channel recorded in the history table.
+type ChannelValue struct {
+	SensorID  int       `json:"sensor_id"`
+	Channel   string    `json:"channel"`
+	Value     float64   `json:"value"`
+	Timestamp time.Time `json:"timestamp"`
+}
+
+// IDOffsets maps a server prefix to the offset the exporter added to the object IDs of that
+// PRTG server, for databases that namespace the IDs of several servers. Unmapped prefixes
+// translate as the identity.
+type IDOffsets map[string]int
+
+// ToDatabaseID translates an object ID shown in the PRTG web interface of the server with the
+// given prefix into the ID stored in the database.
+func (o IDOffsets) ToDatabaseID(prefix string, uiID int) int {
+	return uiID + o[prefix]
+}
+
+// ToUIID translates an object ID stored in the database into the ID shown in the PRTG web
+// interface of the server with the given prefix.
+func (o IDOffsets) ToUIID(prefix string, databaseID int) int {
+	return databaseID - o[prefix]
+}
+
+// IsKnownStatus reports whether status is one of the 14 documented PRTG status codes.
+func IsKnownStatus(status int) bool {
+	return status >= StatusUnknown && status <= StatusDownPartial
+}
+
 // GetStatusText returns the human-readable name for a PRTG status code (1-14).
 // Returns "Unknown" for invalid status codes.
 func GetStatusText(status int) string {
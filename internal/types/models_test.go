@@ -1,8 +1,6 @@
 package types
 
-import (
-	"testing"
-)
+import "testing"
 
 // TestStatusConstants validates that all PRTG status constants match official documentation.
 // Official PRTG status codes: https://www.paessler.com/manuals/prtg/object_states
@@ -278,3 +276,61 @@ func BenchmarkGetStatusText_AllCodes(b *testing.B) {
 		}
 	}
 }
+
+// TestIsKnownStatus validates that only the documented PRTG codes (1-14) are known.
+func TestIsKnownStatus(t *testing.T) {
+	for status := StatusUnknown; status <= StatusDownPartial; status++ {
+		if !IsKnownStatus(status) {
+			t.Errorf("IsKnownStatus(%d) = false, want true", status)
+		}
+	}
+
+	for _, status := range []int{-1, 0, 15, 99} {
+		if IsKnownStatus(status) {
+			t.Errorf("IsKnownStatus(%d) = true, want false", status)
+		}
+	}
+}
+
+func TestIDOffsets(t *testing.T) {
+	offsets := IDOffsets{"eu": 1000000, "us": 2000000}
+
+	tests := []struct {
+		prefix     string
+		uiID       int
+		databaseID int
+	}{
+		{"eu", 2045, 1002045},
+		{"us", 2045, 2002045},
+		{"apac", 2045, 2045}, // Unmapped server: identity
+		{"", 2045, 2045},
+	}
+
+	for _, tt := range tests {
+		if got := offsets.ToDatabaseID(tt.prefix, tt.uiID); got != tt.databaseID {
+			t.Errorf("ToDatabaseID(%q, %d) = %d, want %d", tt.prefix, tt.uiID, got, tt.databaseID)
+		}
+
+		if got := offsets.ToUIID(tt.prefix, tt.databaseID); got != tt.uiID {
+			t.Errorf("ToUIID(%q, %d) = %d, want %d", tt.prefix, tt.databaseID, got, tt.uiID)
+		}
+
+		// Round trips in both directions
+		if got := offsets.ToUIID(tt.prefix, offsets.ToDatabaseID(tt.prefix, tt.uiID)); got != tt.uiID {
+			t.Errorf("ToUIID(ToDatabaseID(%q, %d)) = %d", tt.prefix, tt.uiID, got)
+		}
+
+		if got := offsets.ToDatabaseID(tt.prefix, offsets.ToUIID(tt.prefix, tt.databaseID)); got != tt.databaseID {
+			t.Errorf("ToDatabaseID(ToUIID(%q, %d)) = %d", tt.prefix, tt.databaseID, got)
+		}
+	}
+
+	var none IDOffsets
+	if got := none.ToDatabaseID("eu", 2045); got != 2045 {
+		t.Errorf("nil IDOffsets: ToDatabaseID = %d, want 2045", got)
+	}
+
+	if got := none.ToUIID("eu", 2045); got != 2045 {
+		t.Errorf("nil IDOffsets: ToUIID = %d, want 2045", got)
+	}
+}